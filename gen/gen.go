@@ -0,0 +1,214 @@
+// Package gen define the language neutral helpers shared by the generators,
+// the generators keep only the target language rendering
+package gen
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/gsdocker/gserrors"
+	"github.com/gsrpc/gslang"
+	"github.com/gsrpc/gslang/ast"
+	"github.com/gsrpc/gslang/lexer"
+)
+
+// Arg get the annotation argument by position or by name
+func Arg(annotation *ast.Annotation, index int, name string) (ast.Expr, bool) {
+	return arg(annotation.Args, index, name)
+}
+
+// FieldArg get the argument of table literal for the field by field position or by field name
+func FieldArg(newObj *ast.NewObj, index int, field *ast.Field) (ast.Expr, bool) {
+	return arg(newObj.Args, index, field.Name())
+}
+
+func arg(args *ast.ArgsTable, index int, name string) (ast.Expr, bool) {
+
+	if args == nil {
+		return nil, false
+	}
+
+	if args.Named {
+		return args.NamedArg(name)
+	}
+
+	if index < len(args.Arguments) {
+		return args.Arguments[index], true
+	}
+
+	return nil, false
+}
+
+// Exception get the exception type name, which always ends with Exception
+func Exception(name string) string {
+	if strings.HasSuffix(name, "Exception") {
+		return strings.Title(name)
+	}
+
+	return strings.Title(name) + "Exception"
+}
+
+// TableName get the table type name, the exception name is suffixed with Exception
+func TableName(typeDecl ast.Type) string {
+	if gslang.IsException(typeDecl) {
+		return Exception(typeDecl.Name())
+	}
+
+	return strings.Title(typeDecl.Name())
+}
+
+// IsOptional check if the field is annotated with @Optional
+func IsOptional(field *ast.Field) bool {
+	_, ok := gslang.FindAnnotation(field, "com.gsrpc.Optional")
+
+	return ok
+}
+
+// IsOneOf check if the type is @OneOf table, the type reference is resolved
+func IsOneOf(typeDecl ast.Type) bool {
+	if typeRef, ok := typeDecl.(*ast.TypeRef); ok {
+		return IsOneOf(typeRef.Ref)
+	}
+
+	if _, ok := typeDecl.(*ast.Table); !ok {
+		return false
+	}
+
+	_, ok := gslang.FindAnnotation(typeDecl, "com.gsrpc.OneOf")
+
+	return ok
+}
+
+// Variant get the union variant index of field by field index, the variant 0 is the absent value
+func Variant(index int) int {
+	return index + 1
+}
+
+// IsStrict check if the enum is annotated with @Strict
+func IsStrict(enum *ast.Enum) bool {
+	_, ok := gslang.FindAnnotation(enum, "com.gsrpc.Strict")
+
+	return ok
+}
+
+// IsBytes check if the seq is byte seq
+func IsBytes(seq *ast.Seq) bool {
+	builtinType, ok := seq.Component.(*ast.BuiltinType)
+
+	return ok && builtinType.Type == lexer.KeyByte
+}
+
+// Default get the @Default value expr of field, which is the positional or the Value argument,
+// the value is untyped and evaluated by the field type, the optional field can't declare default value
+func Default(field *ast.Field) (ast.Expr, bool) {
+
+	annotation, ok := gslang.FindAnnotation(field, "com.gsrpc.Default")
+
+	if !ok {
+		return nil, false
+	}
+
+	start, _ := gslang.Pos(field)
+
+	if IsOptional(field) {
+		gserrors.Panicf(nil, "optional field %s can't declare default value :%v", field, start)
+	}
+
+	expr, ok := Arg(annotation, 0, "Value")
+
+	if !ok {
+		gserrors.Panicf(nil, "expect @Default(expr) or @Default(Value:expr) for field %s :%v", field, start)
+	}
+
+	return expr, true
+}
+
+// SnakeName get the lower snake case name, the acronym is kept as one word, e.g. OSVersion -> os_version,DurationV2 -> duration_v2
+func SnakeName(name string) string {
+
+	runes := []rune(name)
+
+	var buff bytes.Buffer
+
+	for i, r := range runes {
+
+		if i > 0 && unicode.IsUpper(r) {
+
+			prev := runes[i-1]
+
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && i+1 < len(runes) && unicode.IsLower(runes[i+1])) {
+				buff.WriteRune('_')
+			}
+		}
+
+		buff.WriteRune(unicode.ToLower(r))
+	}
+
+	return buff.String()
+}
+
+// Evaluator evaluate the constant expr, which is the gslang compiler Eval
+type Evaluator interface {
+	EvalInt(expr ast.Expr) int64
+}
+
+// CheckUnsigned panic if the default value of unsigned builtin type is negative
+func CheckUnsigned(eval Evaluator, builtinType *ast.BuiltinType, expr ast.Expr, start lexer.Position) {
+	switch builtinType.Type {
+	case lexer.KeyByte, lexer.KeyUInt16, lexer.KeyUInt32, lexer.KeyUInt64:
+		if val := eval.EvalInt(expr); val < 0 {
+			gserrors.Panicf(nil, "unsigned type(%s) can't default to negative value(%d) :%v", builtinType, val, start)
+		}
+	}
+}
+
+// Constant get the enum constant by value
+func Constant(enum *ast.Enum, val int64) (*ast.Constant, bool) {
+	for _, constant := range enum.Constants {
+		if constant.Value == val {
+			return constant, true
+		}
+	}
+
+	return nil, false
+}
+
+// Tidy remove the blank lines left by the templates
+type Tidy struct {
+	blank       []byte
+	blankLines  *regexp.Regexp
+	blankBefore *regexp.Regexp
+	blankAfter  *regexp.Regexp
+}
+
+// NewTidy create the Tidy which keeps at most keep blank lines, removes the blank lines after the line ending
+// with open and the blank lines before the line starting with close, the empty close keeps the blank lines
+func NewTidy(keep int, open, close string) *Tidy {
+
+	tidy := &Tidy{
+		blank:      bytes.Repeat([]byte("\n"), keep+1),
+		blankLines: regexp.MustCompile(fmt.Sprintf(`\n([ \t]*\n){%d,}`, keep+1)),
+		blankAfter: regexp.MustCompile(`(?m)((?:` + open + `)\n)[ \t]*\n`),
+	}
+
+	if close != "" {
+		tidy.blankBefore = regexp.MustCompile(`\n[ \t]*\n([ \t]*(?:` + close + `))`)
+	}
+
+	return tidy
+}
+
+// Clean remove the blank lines of generated content
+func (tidy *Tidy) Clean(content []byte) []byte {
+
+	content = tidy.blankLines.ReplaceAll(content, tidy.blank)
+
+	if tidy.blankBefore != nil {
+		content = tidy.blankBefore.ReplaceAll(content, []byte("\n$1"))
+	}
+
+	return tidy.blankAfter.ReplaceAll(content, []byte("$1"))
+}
@@ -0,0 +1,1110 @@
+package gen4c
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/gsdocker/gserrors"
+	"github.com/gsdocker/gslogger"
+	"github.com/gsrpc/gslang"
+	"github.com/gsrpc/gslang/ast"
+	"github.com/gsrpc/gslang/lexer"
+	"github.com/gsrpc/gsrpc/gen"
+	"github.com/gsrpc/gsrpc/wire"
+)
+
+var builtin = map[lexer.TokenType]string{
+	lexer.KeySByte:   "int8_t",
+	lexer.KeyByte:    "uint8_t",
+	lexer.KeyInt16:   "int16_t",
+	lexer.KeyUInt16:  "uint16_t",
+	lexer.KeyInt32:   "int32_t",
+	lexer.KeyUInt32:  "uint32_t",
+	lexer.KeyInt64:   "int64_t",
+	lexer.KeyUInt64:  "uint64_t",
+	lexer.KeyFloat32: "float",
+	lexer.KeyFloat64: "double",
+	lexer.KeyBool:    "bool",
+	lexer.KeyVoid:    "void",
+}
+
+var readMapping = map[lexer.TokenType]string{
+	lexer.KeySByte:   "gsrpc_read_int8",
+	lexer.KeyByte:    "gsrpc_read_uint8",
+	lexer.KeyInt16:   "gsrpc_read_int16",
+	lexer.KeyUInt16:  "gsrpc_read_uint16",
+	lexer.KeyInt32:   "gsrpc_read_int32",
+	lexer.KeyUInt32:  "gsrpc_read_uint32",
+	lexer.KeyInt64:   "gsrpc_read_int64",
+	lexer.KeyUInt64:  "gsrpc_read_uint64",
+	lexer.KeyFloat32: "gsrpc_read_float32",
+	lexer.KeyFloat64: "gsrpc_read_float64",
+	lexer.KeyBool:    "gsrpc_read_bool",
+}
+
+var writeMapping = map[lexer.TokenType]string{
+	lexer.KeySByte:   "gsrpc_write_int8",
+	lexer.KeyByte:    "gsrpc_write_uint8",
+	lexer.KeyInt16:   "gsrpc_write_int16",
+	lexer.KeyUInt16:  "gsrpc_write_uint16",
+	lexer.KeyInt32:   "gsrpc_write_int32",
+	lexer.KeyUInt32:  "gsrpc_write_uint32",
+	lexer.KeyInt64:   "gsrpc_write_int64",
+	lexer.KeyUInt64:  "gsrpc_write_uint64",
+	lexer.KeyFloat32: "gsrpc_write_float32",
+	lexer.KeyFloat64: "gsrpc_write_float64",
+	lexer.KeyBool:    "gsrpc_write_bool",
+}
+
+// keywords the c keywords, gcc predefined macros and the service context member, which are suffixed with underscore
+// when used as member names
+var keywords = map[string]bool{
+	"auto": true, "bool": true, "break": true, "case": true, "char": true, "const": true, "continue": true,
+	"default": true, "do": true, "double": true, "else": true, "enum": true, "extern": true, "false": true,
+	"float": true, "for": true, "goto": true, "if": true, "inline": true, "int": true, "long": true,
+	"register": true, "restrict": true, "return": true, "short": true, "signed": true, "sizeof": true,
+	"static": true, "struct": true, "switch": true, "true": true, "typedef": true, "union": true,
+	"unsigned": true, "void": true, "volatile": true, "while": true,
+	"context": true, "unix": true, "linux": true,
+}
+
+// defaultCapacity the capacity macros of string/byte[] and list/map without @MaxLen, which are defined in stream.h
+const (
+	defaultString = "GSRPC_MAX_STRING"
+	defaultList   = "GSRPC_MAX_LIST"
+)
+
+var runtimeFiles = []string{"stream.h", "stream.c", "service.h", "service.c"}
+
+// _Decl the type declaration of header, which is sorted by the dependencies in the same script
+type _Decl struct {
+	name    string   // type name
+	deps    []string // the same script types used by the declaration
+	content []byte   // header content
+}
+
+type _CodeGen struct {
+	gslogger.Log                    // Log APIs
+	rootpath     string             // root path
+	files        map[string][]byte  // generated files sink, the files are written into rootpath if nil
+	script       *ast.Script        // current script
+	decls        []*_Decl           // current script header declarations
+	header       bytes.Buffer       // current script contract header content
+	source       bytes.Buffer       // current script source content
+	includes     map[string]bool    // current script header includes
+	deps         map[string]bool    // current declaration dependencies
+	math         bool               // current script source use math.h macros
+	tpl          *template.Template // code generate template
+	skips        []*regexp.Regexp   // skip lists
+	compiler     *gslang.Compiler   // current compiler
+	runtime      bool               // runtime files are generated
+}
+
+// NewCodeGen .
+func NewCodeGen(rootpath string, skips []string) (gslang.Visitor, error) {
+
+	codeGen := &_CodeGen{
+		Log:      gslogger.Get("gen4c"),
+		rootpath: rootpath,
+	}
+
+	for _, skip := range skips {
+		exp, err := regexp.Compile(skip)
+
+		if err != nil {
+			return nil, gserrors.Newf(err, "invalid skip regex string :%s", skip)
+		}
+
+		codeGen.skips = append(codeGen.skips, exp)
+	}
+
+	funcs := template.FuncMap{
+		"title":         strings.Title,
+		"snakeName":     gen.SnakeName,
+		"memberName":    memberName,
+		"doc":           doc,
+		"notVoid":       gslang.NotVoid,
+		"isPOD":         gslang.IsPOD,
+		"isAsync":       gslang.IsAsync,
+		"isStrict":      gen.IsStrict,
+		"isFlag":        isFlag,
+		"isOptional":    gen.IsOptional,
+		"isOneOf":       gen.IsOneOf,
+		"hasFlag":       hasFlag,
+		"isMap":         isMap,
+		"hasResult":     hasResult,
+		"variant":       gen.Variant,
+		"declName":      codeGen.declName,
+		"constName":     codeGen.constName,
+		"fieldDecl":     codeGen.fieldDecl,
+		"paramDecl":     codeGen.paramDecl,
+		"returnDecl":    codeGen.returnDecl,
+		"exceptionName": exceptionName,
+		"read":          codeGen.read,
+		"write":         codeGen.write,
+		"tagValue":      codeGen.tagValue,
+		"wireCases":     wire.Cases,
+		"wireNested":    wire.Nested,
+		"rpcName":       codeGen.rpcName,
+		"fieldInit":     codeGen.fieldInit,
+		"compare":       codeGen.compare,
+		"signature":     codeGen.signature,
+	}
+
+	tpl, err := template.New("t4c").Funcs(funcs).Parse(t4c)
+
+	if err != nil {
+		return nil, err
+	}
+
+	codeGen.tpl = tpl
+
+	return codeGen, nil
+}
+
+// NewCodeGenWithFiles create codegen which write generated files into files map keyed by slash separated relative path
+func NewCodeGenWithFiles(files map[string][]byte, skips []string) (gslang.Visitor, error) {
+
+	codeGen, err := NewCodeGen("", skips)
+
+	if err != nil {
+		return nil, err
+	}
+
+	codeGen.(*_CodeGen).files = files
+
+	return codeGen, nil
+}
+
+// memberName get the struct member name, c keyword and the generated member names are suffixed with underscore
+func memberName(name string) string {
+
+	name = gen.SnakeName(name)
+
+	if keywords[name] {
+		return name + "_"
+	}
+
+	return name
+}
+
+// exceptionName get the member name of method exception in the result exception union
+func exceptionName(exception *ast.Exception) string {
+	return memberName(gen.TableName(exception.Type))
+}
+
+// isFlag check if the enum is @Flag enum, which is uint32_t and the constants are macros
+func isFlag(enum *ast.Enum) bool {
+	return gslang.EnumSize(enum) == 4
+}
+
+// prefix get the symbol prefix of package, e.g. com.gsrpc.test -> com_gsrpc_test
+func prefix(packageName string) string {
+	return strings.Replace(packageName, ".", "_", -1)
+}
+
+// declName get the declared type name, the header of other script is included and the same script type is recorded as dependency
+func (codegen *_CodeGen) declName(typeDecl ast.Type) string {
+
+	if typeRef, ok := typeDecl.(*ast.TypeRef); ok {
+		return codegen.declName(typeRef.Ref)
+	}
+
+	if typeDecl.Script() != codegen.script.Name() {
+		codegen.includes[headerPath(typeDecl.Package(), typeDecl.Script())] = true
+	} else if codegen.deps != nil {
+		codegen.deps[typeDecl.FullName()] = true
+	}
+
+	if _, ok := typeDecl.(*ast.Enum); ok {
+		return prefix(typeDecl.Package()) + "_" + strings.Title(typeDecl.Name())
+	}
+
+	return prefix(typeDecl.Package()) + "_" + gen.TableName(typeDecl)
+}
+
+// constName get the enum constant name, which is prefixed with the enum name
+func (codegen *_CodeGen) constName(enum *ast.Enum, name string) string {
+	return codegen.declName(enum) + "_" + name
+}
+
+// rpcName get the name of com.gsrpc package symbol
+func (codegen *_CodeGen) rpcName(name string) string {
+	return prefix("com.gsrpc") + "_" + name
+}
+
+// tagValue get the tag sequence compound literal and length, which is passed to gsrpc_write_tags
+func (codegen *_CodeGen) tagValue(typeDecl ast.Type) string {
+
+	tags := codegen.tags(typeDecl)
+
+	return fmt.Sprintf("(const uint8_t[]){%s}, %d", strings.Join(tags, ", "), len(tags))
+}
+
+// tags get the type tag sequence by the shared tag scheme
+func (codegen *_CodeGen) tags(typeDecl ast.Type) []string {
+
+	tag := codegen.rpcName("Tag")
+
+	return wire.Format(wire.Tags(typeDecl), func(t wire.Tag) string {
+		return tag + "_" + t.String()
+	})
+}
+
+// isMap check if the table is annotated with @Map
+func isMap(table *ast.Table) bool {
+	_, ok := gslang.FindAnnotation(table, "com.gsrpc.Map")
+
+	return ok
+}
+
+// hasFlag check if the field is declared with has_ presence flag, the optional union use the none variant instead
+func hasFlag(field *ast.Field) bool {
+	return gen.IsOptional(field) && !gen.IsOneOf(field.Type)
+}
+
+// hasResult check if the method result struct is declared, which holds the return value and the raised exception
+func hasResult(method *ast.Method) bool {
+	return !gslang.IsAsync(method) && (gslang.NotVoid(method.Return) || len(method.Exceptions) != 0)
+}
+
+// maxLen get the @MaxLen capacity of string or variable length seq field/param, return 0 if not declared
+func (codegen *_CodeGen) maxLen(node ast.Node, typeDecl ast.Type) int64 {
+
+	annotation, ok := gslang.FindAnnotation(node, "com.gsrpc.MaxLen")
+
+	if !ok {
+		return 0
+	}
+
+	start, _ := gslang.Pos(node)
+
+	if typeRef, ok := typeDecl.(*ast.TypeRef); ok {
+		typeDecl = typeRef.Ref
+	}
+
+	supported := false
+
+	switch typeDecl.(type) {
+	case *ast.Seq:
+		supported = typeDecl.(*ast.Seq).Size == -1
+	case *ast.BuiltinType:
+		supported = typeDecl.(*ast.BuiltinType).Type == lexer.KeyString
+	}
+
+	if !supported {
+		gserrors.Panicf(nil, "@MaxLen only support string or variable length seq :%v", start)
+	}
+
+	expr, ok := gen.Arg(annotation, 0, "Value")
+
+	if !ok {
+		gserrors.Panicf(nil, "expect @MaxLen(n) :%v", start)
+	}
+
+	val := codegen.compiler.Eval().EvalInt(expr)
+
+	if val <= 0 || val > math.MaxUint16 {
+		gserrors.Panicf(nil, "@MaxLen(%d) out of range(1,65535) :%v", val, start)
+	}
+
+	return val
+}
+
+// capacity get the capacity expr, the default macro is used if @MaxLen is not declared
+func capacity(maxLen int64, defaultVal string) string {
+	if maxLen == 0 {
+		return defaultVal
+	}
+
+	return strconv.FormatInt(maxLen, 10)
+}
+
+// declare get the declaration of name with type, the string is NUL terminated char array and the variable
+// length seq is anonymous struct with length and fixed capacity data array
+func (codegen *_CodeGen) declare(typeDecl ast.Type, name string, maxLen int64) string {
+	switch typeDecl.(type) {
+	case *ast.BuiltinType:
+		builtinType := typeDecl.(*ast.BuiltinType)
+
+		if builtinType.Type == lexer.KeyString {
+			if maxLen == 0 {
+				return fmt.Sprintf("char %s[%s + 1]", name, defaultString)
+			}
+
+			return fmt.Sprintf("char %s[%d]", name, maxLen+1)
+		}
+
+		return fmt.Sprintf("%s %s", builtin[builtinType.Type], name)
+	case *ast.TypeRef:
+		return codegen.declare(typeDecl.(*ast.TypeRef).Ref, name, maxLen)
+
+	case *ast.Enum, *ast.Table:
+		return fmt.Sprintf("%s %s", codegen.declName(typeDecl), name)
+
+	case *ast.Seq:
+		seq := typeDecl.(*ast.Seq)
+
+		if seq.Size != -1 {
+			if gen.IsBytes(seq) {
+				return fmt.Sprintf("uint8_t %s[%d]", name, seq.Size)
+			}
+
+			return codegen.declare(seq.Component, fmt.Sprintf("%s[%d]", name, seq.Size), 0)
+		}
+
+		if gen.IsBytes(seq) {
+			return fmt.Sprintf("struct { uint16_t length; uint8_t data[%s]; } %s", capacity(maxLen, defaultString), name)
+		}
+
+		data := codegen.declare(seq.Component, fmt.Sprintf("data[%s]", capacity(maxLen, defaultList)), 0)
+
+		return fmt.Sprintf("struct { uint16_t length; %s; } %s", data, name)
+	}
+
+	gserrors.Panicf(nil, "declare  error: unsupport type(%s)", typeDecl)
+
+	return "unknown"
+}
+
+func (codegen *_CodeGen) fieldDecl(field *ast.Field) string {
+	return codegen.declare(field.Type, memberName(field.Name()), codegen.maxLen(field, field.Type))
+}
+
+func (codegen *_CodeGen) paramDecl(param *ast.Param) string {
+	return codegen.declare(param.Type, memberName(param.Name()), codegen.maxLen(param, param.Type))
+}
+
+func (codegen *_CodeGen) returnDecl(method *ast.Method) string {
+	return codegen.declare(method.Return, "ret", 0)
+}
+
+// signature get the service function params, the params and result are passed by the method params/result struct
+func (codegen *_CodeGen) signature(contract *ast.Contract, method *ast.Method) string {
+
+	name := codegen.declName(contract) + "_" + gen.SnakeName(method.Name())
+
+	args := []string{"void *context"}
+
+	if len(method.Params) != 0 {
+		args = append(args, fmt.Sprintf("const %s_params *params", name))
+	}
+
+	if hasResult(method) {
+		args = append(args, fmt.Sprintf("%s_result *result", name))
+	}
+
+	return strings.Join(args, ", ")
+}
+
+// indent get the statements block with indent, each statement is terminated with newline
+func indent(lines []string, prefix string) string {
+
+	var buff bytes.Buffer
+
+	for _, line := range lines {
+		if line == "" {
+			buff.WriteString("\n")
+		} else {
+			buff.WriteString(prefix + line + "\n")
+		}
+	}
+
+	return buff.String()
+}
+
+// nested indent the nested statements of loop or if body
+func nested(lines []string) []string {
+
+	var result []string
+
+	for _, line := range lines {
+		if line == "" {
+			result = append(result, line)
+		} else {
+			result = append(result, "    "+line)
+		}
+	}
+
+	return result
+}
+
+func (codegen *_CodeGen) read(typeDecl ast.Type, lvalue string, prefix string) string {
+	return indent(codegen.readType(typeDecl, lvalue, 0), prefix)
+}
+
+func (codegen *_CodeGen) write(typeDecl ast.Type, lvalue string, prefix string) string {
+	return indent(codegen.writeType(typeDecl, lvalue, 0), prefix)
+}
+
+// readType get the statements which read the type value into lvalue from the reader pointer, depth is used to name the loop index
+func (codegen *_CodeGen) readType(typeDecl ast.Type, lvalue string, depth int) []string {
+	switch typeDecl.(type) {
+	case *ast.BuiltinType:
+		builtinType := typeDecl.(*ast.BuiltinType)
+
+		if builtinType.Type == lexer.KeyString {
+			return []string{fmt.Sprintf("gsrpc_read_string(reader, %s, sizeof(%s));", lvalue, lvalue)}
+		}
+
+		return []string{fmt.Sprintf("%s = %s(reader);", lvalue, readMapping[builtinType.Type])}
+	case *ast.TypeRef:
+		return codegen.readType(typeDecl.(*ast.TypeRef).Ref, lvalue, depth)
+
+	case *ast.Enum, *ast.Table:
+		return []string{fmt.Sprintf("%s_read(reader, &%s);", codegen.declName(typeDecl), lvalue)}
+
+	case *ast.Seq:
+		seq := typeDecl.(*ast.Seq)
+
+		index := fmt.Sprintf("i%d", depth)
+
+		if seq.Size != -1 {
+
+			if gen.IsBytes(seq) {
+				return []string{
+					fmt.Sprintf("gsrpc_read_size(reader, %d);", seq.Size),
+					fmt.Sprintf("gsrpc_read_bytes(reader, %s, %d);", lvalue, seq.Size),
+				}
+			}
+
+			lines := []string{
+				fmt.Sprintf("gsrpc_read_size(reader, %d);", seq.Size),
+				"",
+				fmt.Sprintf("for (size_t %s = 0; %s < %d; %s++) {", index, index, seq.Size, index),
+			}
+
+			lines = append(lines, nested(codegen.readType(seq.Component, fmt.Sprintf("%s[%s]", lvalue, index), depth+1))...)
+
+			return append(lines, "}")
+		}
+
+		length := fmt.Sprintf("%s.length = gsrpc_read_length(reader, GSRPC_COUNTOF(%s.data));", lvalue, lvalue)
+
+		if gen.IsBytes(seq) {
+			return []string{length, fmt.Sprintf("gsrpc_read_bytes(reader, %s.data, %s.length);", lvalue, lvalue)}
+		}
+
+		lines := []string{length, "", fmt.Sprintf("for (uint16_t %s = 0; %s < %s.length; %s++) {", index, index, lvalue, index)}
+
+		element := fmt.Sprintf("%s.data[%s]", lvalue, index)
+
+		if entry, ok := wire.MapEntry(seq); ok {
+			lines = append(lines, nested(codegen.readType(entry.Fields[0].Type, element+"."+memberName(entry.Fields[0].Name()), depth+1))...)
+			lines = append(lines, nested(codegen.readType(entry.Fields[1].Type, element+"."+memberName(entry.Fields[1].Name()), depth+1))...)
+		} else {
+			lines = append(lines, nested(codegen.readType(seq.Component, element, depth+1))...)
+		}
+
+		return append(lines, "}")
+	}
+
+	gserrors.Panicf(nil, "readType  error: unsupport type(%s)", typeDecl)
+
+	return nil
+}
+
+// writeType get the statements which write the lvalue to the writer pointer, the map entries are written in key order
+func (codegen *_CodeGen) writeType(typeDecl ast.Type, lvalue string, depth int) []string {
+	switch typeDecl.(type) {
+	case *ast.BuiltinType:
+		builtinType := typeDecl.(*ast.BuiltinType)
+
+		if builtinType.Type == lexer.KeyString {
+			return []string{fmt.Sprintf("gsrpc_write_string(writer, %s, sizeof(%s));", lvalue, lvalue)}
+		}
+
+		return []string{fmt.Sprintf("%s(writer, %s);", writeMapping[builtinType.Type], lvalue)}
+	case *ast.TypeRef:
+		return codegen.writeType(typeDecl.(*ast.TypeRef).Ref, lvalue, depth)
+
+	case *ast.Enum, *ast.Table:
+		return []string{fmt.Sprintf("%s_write(writer, &%s);", codegen.declName(typeDecl), lvalue)}
+
+	case *ast.Seq:
+		seq := typeDecl.(*ast.Seq)
+
+		index := fmt.Sprintf("i%d", depth)
+
+		if seq.Size != -1 {
+
+			if gen.IsBytes(seq) {
+				return []string{
+					fmt.Sprintf("gsrpc_write_length(writer, %d, %d);", seq.Size, seq.Size),
+					fmt.Sprintf("gsrpc_write_bytes(writer, %s, %d);", lvalue, seq.Size),
+				}
+			}
+
+			lines := []string{
+				fmt.Sprintf("gsrpc_write_length(writer, %d, %d);", seq.Size, seq.Size),
+				"",
+				fmt.Sprintf("for (size_t %s = 0; %s < %d; %s++) {", index, index, seq.Size, index),
+			}
+
+			lines = append(lines, nested(codegen.writeType(seq.Component, fmt.Sprintf("%s[%s]", lvalue, index), depth+1))...)
+
+			return append(lines, "}")
+		}
+
+		length := fmt.Sprintf("gsrpc_write_length(writer, %s.length, GSRPC_COUNTOF(%s.data));", lvalue, lvalue)
+
+		if gen.IsBytes(seq) {
+			return []string{length, fmt.Sprintf("gsrpc_write_bytes(writer, %s.data, %s.length);", lvalue, lvalue)}
+		}
+
+		if entry, ok := wire.MapEntry(seq); ok {
+
+			order := fmt.Sprintf("order%d", depth)
+
+			count := fmt.Sprintf("length%d", depth)
+
+			element := fmt.Sprintf("%s.data[%s[%s]]", lvalue, order, index)
+
+			lines := []string{
+				"{",
+				fmt.Sprintf("    uint16_t %s[GSRPC_COUNTOF(%s.data)];", order, lvalue),
+				fmt.Sprintf("    uint16_t %s = gsrpc_write_order(writer, %s, %s.length, GSRPC_COUNTOF(%s.data), %s.data, sizeof(%s.data[0]), %s_compare);",
+					count, order, lvalue, lvalue, lvalue, lvalue, codegen.declName(entry)),
+				"",
+				fmt.Sprintf("    for (uint16_t %s = 0; %s < %s; %s++) {", index, index, count, index),
+			}
+
+			body := codegen.writeType(entry.Fields[0].Type, element+"."+memberName(entry.Fields[0].Name()), depth+1)
+
+			body = append(body, codegen.writeType(entry.Fields[1].Type, element+"."+memberName(entry.Fields[1].Name()), depth+1)...)
+
+			lines = append(lines, nested(nested(body))...)
+
+			return append(lines, "    }", "}")
+		}
+
+		lines := []string{length, "", fmt.Sprintf("for (uint16_t %s = 0; %s < %s.length && writer->err == GSRPC_OK; %s++) {", index, index, lvalue, index)}
+
+		lines = append(lines, nested(codegen.writeType(seq.Component, fmt.Sprintf("%s.data[%s]", lvalue, index), depth+1))...)
+
+		return append(lines, "}")
+	}
+
+	gserrors.Panicf(nil, "writeType  error: unsupport type(%s)", typeDecl)
+
+	return nil
+}
+
+// initType get the statements which set the type default value of lvalue, the value is zeroed by memset before
+func (codegen *_CodeGen) initType(typeDecl ast.Type, lvalue string, depth int) []string {
+	switch typeDecl.(type) {
+	case *ast.TypeRef:
+		return codegen.initType(typeDecl.(*ast.TypeRef).Ref, lvalue, depth)
+
+	case *ast.Enum:
+		enum := typeDecl.(*ast.Enum)
+
+		if len(enum.Constants) == 0 || enum.Constants[0].Value == 0 {
+			return nil
+		}
+
+		return []string{fmt.Sprintf("%s = %s;", lvalue, codegen.constName(enum, enum.Constants[0].Name()))}
+
+	case *ast.Table:
+		if gen.IsOneOf(typeDecl) {
+			return nil
+		}
+
+		return []string{fmt.Sprintf("%s_init(&%s);", codegen.declName(typeDecl), lvalue)}
+
+	case *ast.Seq:
+		seq := typeDecl.(*ast.Seq)
+
+		if seq.Size == -1 || gen.IsBytes(seq) {
+			return nil
+		}
+
+		index := fmt.Sprintf("i%d", depth)
+
+		body := codegen.initType(seq.Component, fmt.Sprintf("%s[%s]", lvalue, index), depth+1)
+
+		if len(body) == 0 {
+			return nil
+		}
+
+		lines := []string{fmt.Sprintf("for (size_t %s = 0; %s < %d; %s++) {", index, index, seq.Size, index)}
+
+		lines = append(lines, nested(body)...)
+
+		return append(lines, "}")
+	}
+
+	return nil
+}
+
+// fieldInit get the statements which set the field default value, the optional field is absent by default
+func (codegen *_CodeGen) fieldInit(field *ast.Field, prefix string) string {
+
+	lvalue := "val->" + memberName(field.Name())
+
+	expr, ok := gen.Default(field)
+
+	if !ok {
+		if gen.IsOptional(field) {
+			return ""
+		}
+
+		return indent(codegen.initType(field.Type, lvalue, 0), prefix)
+	}
+
+	start, _ := gslang.Pos(field)
+
+	return indent(codegen.defaultExpr(expr, field.Type, lvalue, start), prefix)
+}
+
+// defaultExpr get the statements which set the @Default value expr to lvalue
+func (codegen *_CodeGen) defaultExpr(expr ast.Expr, typeDecl ast.Type, lvalue string, start lexer.Position) []string {
+
+	eval := codegen.compiler.Eval()
+
+	switch typeDecl.(type) {
+	case *ast.BuiltinType:
+		builtinType := typeDecl.(*ast.BuiltinType)
+
+		gen.CheckUnsigned(eval, builtinType, expr, start)
+
+		switch builtinType.Type {
+		case lexer.KeyString:
+			return []string{fmt.Sprintf("strncpy(%s, %s, sizeof(%s) - 1);", lvalue, quote(eval.EvalString(expr)), lvalue)}
+		case lexer.KeyBool:
+			return []string{fmt.Sprintf("%s = %s;", lvalue, strconv.FormatBool(eval.EvalBool(expr)))}
+		case lexer.KeyFloat32, lexer.KeyFloat64:
+			val := eval.EvalFloat(expr)
+
+			literal := strconv.FormatFloat(val, 'g', -1, 64)
+
+			switch {
+			case math.IsNaN(val):
+				codegen.math = true
+				literal = "NAN"
+			case math.IsInf(val, 1):
+				codegen.math = true
+				literal = "INFINITY"
+			case math.IsInf(val, -1):
+				codegen.math = true
+				literal = "-INFINITY"
+			default:
+				if !strings.ContainsAny(literal, ".e") {
+					literal += ".0"
+				}
+
+				if builtinType.Type == lexer.KeyFloat32 {
+					literal += "f"
+				}
+			}
+
+			return []string{fmt.Sprintf("%s = %s;", lvalue, literal)}
+		case lexer.KeyUInt64:
+			return []string{fmt.Sprintf("%s = UINT64_C(%d);", lvalue, uint64(eval.EvalInt(expr)))}
+		case lexer.KeyInt64:
+			return []string{fmt.Sprintf("%s = INT64_C(%d);", lvalue, eval.EvalInt(expr))}
+		case lexer.KeyVoid:
+		default:
+			return []string{fmt.Sprintf("%s = %d;", lvalue, eval.EvalInt(expr))}
+		}
+
+	case *ast.TypeRef:
+		return codegen.defaultExpr(expr, typeDecl.(*ast.TypeRef).Ref, lvalue, start)
+
+	case *ast.Enum:
+		enum := typeDecl.(*ast.Enum)
+
+		val := eval.EvalInt(expr)
+
+		if constant, ok := gen.Constant(enum, val); ok {
+			return []string{fmt.Sprintf("%s = %s;", lvalue, codegen.constName(enum, constant.Name()))}
+		}
+
+		// the flag enum accept the combination of constants
+		if isFlag(enum) {
+			return []string{fmt.Sprintf("%s = UINT32_C(%d);", lvalue, val)}
+		}
+
+		gserrors.Panicf(nil, "enum %s constant(%d) not found :%v", enum, val, start)
+
+	case *ast.Table:
+		table := typeDecl.(*ast.Table)
+
+		newObj, ok := expr.(*ast.NewObj)
+
+		if !ok || gen.IsOneOf(table) {
+			break
+		}
+
+		// the fields without arg keep the values set by init function
+		lines := []string{fmt.Sprintf("%s_init(&%s);", codegen.declName(table), lvalue)}
+
+		for i, field := range table.Fields {
+
+			arg, ok := gen.FieldArg(newObj, i, field)
+
+			if !ok {
+				continue
+			}
+
+			lvalue := lvalue + "." + memberName(field.Name())
+
+			if hasFlag(field) {
+				lines = append(lines, fmt.Sprintf("%s = true;", strings.TrimSuffix(lvalue, memberName(field.Name()))+"has_"+memberName(field.Name())))
+			}
+
+			lines = append(lines, codegen.defaultExpr(arg, field.Type, lvalue, start)...)
+		}
+
+		return lines
+	}
+
+	gserrors.Panicf(nil, "unsupport default value for type(%s) :%v", typeDecl, start)
+
+	return nil
+}
+
+// quote get the c string literal
+func quote(val string) string {
+
+	var buff bytes.Buffer
+
+	buff.WriteString("\"")
+
+	for _, b := range []byte(val) {
+		switch {
+		case b == '"':
+			buff.WriteString("\\\"")
+		case b == '\\':
+			buff.WriteString("\\\\")
+		case b == '\n':
+			buff.WriteString("\\n")
+		case b == '\r':
+			buff.WriteString("\\r")
+		case b == '\t':
+			buff.WriteString("\\t")
+		case b < 0x20 || b >= 0x7f:
+			// the octal escape is not extended by the following digits like the hex escape
+			buff.WriteString(fmt.Sprintf("\\%03o", b))
+		default:
+			buff.WriteByte(b)
+		}
+	}
+
+	buff.WriteString("\"")
+
+	return buff.String()
+}
+
+// compare get the map entry key compare expr of lhs and rhs entry pointers, the strings are compared in byte order
+func (codegen *_CodeGen) compare(entry *ast.Table) string {
+
+	key := entry.Fields[0]
+
+	lhs, rhs := "lhs->"+memberName(key.Name()), "rhs->"+memberName(key.Name())
+
+	typeDecl := key.Type
+
+	if typeRef, ok := typeDecl.(*ast.TypeRef); ok {
+		typeDecl = typeRef.Ref
+	}
+
+	switch typeDecl.(type) {
+	case *ast.BuiltinType:
+		if typeDecl.(*ast.BuiltinType).Type == lexer.KeyString {
+			return fmt.Sprintf("strcmp(%s, %s)", lhs, rhs)
+		}
+
+		return fmt.Sprintf("(%s > %s) - (%s < %s)", lhs, rhs, lhs, rhs)
+	case *ast.Enum:
+		return fmt.Sprintf("(%s > %s) - (%s < %s)", lhs, rhs, lhs, rhs)
+	}
+
+	start, _ := gslang.Pos(key)
+
+	gserrors.Panicf(nil, "map entry %s key must be string, number, bool or enum :%v", entry, start)
+
+	return "unknown"
+}
+
+// headerPath get the include path of script header
+func headerPath(packageName string, script string) string {
+	return path.Join(strings.Replace(packageName, ".", "/", -1), filepath.Base(script)+".h")
+}
+
+func (codegen *_CodeGen) execute(stream *bytes.Buffer, name string, data interface{}) {
+	if err := codegen.tpl.ExecuteTemplate(stream, name, data); err != nil {
+		gserrors.Panicf(err, "exec template(%s) for %s error", name, data)
+	}
+}
+
+// declare execute the header template and record the same script types used by the declaration
+func (codegen *_CodeGen) declareType(name string, typeDecl ast.Type) {
+
+	codegen.deps = make(map[string]bool)
+
+	var content bytes.Buffer
+
+	codegen.execute(&content, name, typeDecl)
+
+	decl := &_Decl{name: typeDecl.FullName(), content: content.Bytes()}
+
+	for dep := range codegen.deps {
+		if dep != decl.name {
+			decl.deps = append(decl.deps, dep)
+		}
+	}
+
+	sort.Strings(decl.deps)
+
+	codegen.decls = append(codegen.decls, decl)
+
+	codegen.deps = nil
+}
+
+// sortDecls sort the declarations so that the types are defined before used, the struct can't contain itself by value
+func (codegen *_CodeGen) sortDecls() []*_Decl {
+
+	decls := make(map[string]*_Decl)
+
+	for _, decl := range codegen.decls {
+		decls[decl.name] = decl
+	}
+
+	var sorted []*_Decl
+
+	visited := make(map[string]bool)
+
+	visiting := make(map[string]bool)
+
+	var visit func(decl *_Decl)
+
+	visit = func(decl *_Decl) {
+
+		if visiting[decl.name] {
+			gserrors.Panicf(nil, "recursive type %s can't be generated as plain c struct", decl.name)
+		}
+
+		if visited[decl.name] {
+			return
+		}
+
+		visiting[decl.name] = true
+
+		for _, dep := range decl.deps {
+			if depDecl, ok := decls[dep]; ok {
+				visit(depDecl)
+			}
+		}
+
+		visiting[decl.name] = false
+
+		visited[decl.name] = true
+
+		sorted = append(sorted, decl)
+	}
+
+	for _, decl := range codegen.decls {
+		visit(decl)
+	}
+
+	return sorted
+}
+
+func (codegen *_CodeGen) writeFile(name string, content []byte) {
+
+	// the content buffer may be reused by caller
+	if codegen.files != nil {
+		codegen.files[filepath.ToSlash(name)] = append([]byte(nil), content...)
+		return
+	}
+
+	fullpath := filepath.Join(codegen.rootpath, name)
+
+	if err := os.MkdirAll(filepath.Dir(fullpath), 0755); err != nil {
+		gserrors.Panicf(err, "create output directory error")
+	}
+
+	codegen.D("write file :%s", fullpath)
+
+	if err := ioutil.WriteFile(fullpath, content, 0644); err != nil {
+		gserrors.Panicf(err, "write generate stub code error")
+	}
+}
+
+func (codegen *_CodeGen) BeginScript(compiler *gslang.Compiler, script *ast.Script) bool {
+
+	scriptPath := filepath.ToSlash(filepath.Clean(script.Name()))
+
+	for _, skip := range codegen.skips {
+
+		if skip.MatchString(scriptPath) {
+
+			return false
+		}
+	}
+
+	if strings.HasPrefix(script.Package, "gslang.") {
+		return false
+	}
+
+	codegen.script = script
+
+	codegen.compiler = compiler
+
+	codegen.decls = nil
+
+	codegen.header.Reset()
+
+	codegen.source.Reset()
+
+	codegen.math = false
+
+	codegen.includes = map[string]bool{
+		"com/gsrpc/stream.h": true,
+	}
+
+	return true
+}
+
+func (codegen *_CodeGen) Using(compiler *gslang.Compiler, using *ast.Using) {
+}
+
+func (codegen *_CodeGen) Table(compiler *gslang.Compiler, tableType *ast.Table) {
+
+	prefix := "table"
+
+	if gen.IsOneOf(tableType) {
+		prefix = "union"
+	}
+
+	codegen.declareType(prefix+"_header", tableType)
+
+	codegen.execute(&codegen.source, prefix+"_source", tableType)
+}
+
+func (codegen *_CodeGen) Annotation(compiler *gslang.Compiler, annotation *ast.Table) {
+}
+
+func (codegen *_CodeGen) Enum(compiler *gslang.Compiler, enum *ast.Enum) {
+
+	codegen.declareType("enum_header", enum)
+
+	codegen.execute(&codegen.source, "enum_source", enum)
+}
+
+func (codegen *_CodeGen) Contract(compiler *gslang.Compiler, contract *ast.Contract) {
+
+	codegen.includes["com/gsrpc/service.h"] = true
+
+	codegen.execute(&codegen.header, "contract_header", contract)
+
+	codegen.execute(&codegen.source, "contract_source", contract)
+}
+
+// EndScript write the script header/source pair into the package directory
+func (codegen *_CodeGen) EndScript(compiler *gslang.Compiler) {
+
+	var stream bytes.Buffer
+
+	header := headerPath(codegen.script.Package, codegen.script.Name())
+
+	guard := strings.ToUpper(regexp.MustCompile(`[^A-Za-z0-9]`).ReplaceAllString(header, "_"))
+
+	stream.WriteString("// generate by gs2c,don't modify it manually\n")
+
+	stream.WriteString(fmt.Sprintf("#ifndef %s\n#define %s\n\n", guard, guard))
+
+	var includes []string
+
+	for include := range codegen.includes {
+		if include != header {
+			includes = append(includes, include)
+		}
+	}
+
+	sort.Strings(includes)
+
+	for _, include := range includes {
+		stream.WriteString(fmt.Sprintf("#include <%s>\n", include))
+	}
+
+	stream.WriteString("\n#ifdef __cplusplus\nextern \"C\" {\n#endif\n")
+
+	for _, decl := range codegen.sortDecls() {
+		stream.Write(decl.content)
+	}
+
+	stream.Write(codegen.header.Bytes())
+
+	stream.WriteString("\n#ifdef __cplusplus\n}\n#endif\n")
+
+	stream.WriteString(fmt.Sprintf("\n#endif // %s\n", guard))
+
+	codegen.writeFile(header, tidy.Clean(stream.Bytes()))
+
+	stream.Reset()
+
+	stream.WriteString("// generate by gs2c,don't modify it manually\n")
+
+	stream.WriteString(fmt.Sprintf("#include <%s>\n", header))
+
+	if header != "com/gsrpc/gsrpc.gs.h" {
+		stream.WriteString("#include <com/gsrpc/gsrpc.gs.h>\n")
+	}
+
+	if codegen.math {
+		stream.WriteString("\n#include <math.h>\n")
+	}
+
+	stream.Write(codegen.source.Bytes())
+
+	codegen.writeFile(strings.TrimSuffix(header, ".h")+".c", tidy.Clean(stream.Bytes()))
+
+	if codegen.runtime {
+		return
+	}
+
+	for _, name := range runtimeFiles {
+
+		stream.Reset()
+
+		if err := codegen.tpl.ExecuteTemplate(&stream, name, nil); err != nil {
+			gserrors.Panicf(err, "exec template(%s) error", name)
+		}
+
+		codegen.writeFile(path.Join("com/gsrpc", name), stream.Bytes())
+	}
+
+	codegen.runtime = true
+}
+
+var tidy = gen.NewTidy(1, `[{(]`, `[})]`)
+
+// doc get the c comment from the .gs comments of node
+func doc(indent string, node ast.Node) string {
+
+	comments := gslang.Comments(node)
+
+	if len(comments) == 0 {
+		return ""
+	}
+
+	var buff bytes.Buffer
+
+	for _, line := range comments {
+		buff.WriteString(strings.TrimRight(indent+"// "+strings.TrimSpace(line), " ") + "\n")
+	}
+
+	return buff.String()
+}
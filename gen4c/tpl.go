@@ -0,0 +1,1255 @@
+package gen4c
+
+var t4c = `
+{{define "enum_header"}}{{$Enum := declName .}}
+{{doc "" .}}typedef {{if isFlag .}}uint32_t{{else}}uint8_t{{end}} {{$Enum}};
+{{if isFlag .}}
+{{range .Constants}}{{doc "" .}}#define {{constName $ .Name}} UINT32_C({{.Value}})
+{{end}}{{else if .Constants}}
+enum {
+{{range .Constants}}{{doc "    " .}}    {{constName $ .Name}} = {{.Value}},
+{{end}}};
+{{end}}{{if not (isFlag .)}}
+// {{$Enum}}_valid check if val is the declared constant
+bool {{$Enum}}_valid({{$Enum}} val);
+{{end}}
+void {{$Enum}}_read(gsrpc_reader *reader, {{$Enum}} *val);
+
+void {{$Enum}}_write(gsrpc_writer *writer, const {{$Enum}} *val);
+{{end}}
+
+{{define "enum_source"}}{{$Enum := declName .}}{{$Width := "uint8"}}{{if isFlag .}}{{$Width = "uint32"}}{{end}}
+{{if not (isFlag .)}}bool {{$Enum}}_valid({{$Enum}} val) {
+    switch (val) {
+{{range .Constants}}    case {{constName $ .Name}}:
+{{end}}        return true;
+    default:
+        return false;
+    }
+}
+{{end}}
+void {{$Enum}}_read(gsrpc_reader *reader, {{$Enum}} *val) {
+    *val = gsrpc_read_{{$Width}}(reader);
+{{if and (isStrict .) (not (isFlag .))}}
+    if (reader->err == GSRPC_OK && !{{$Enum}}_valid(*val)) {
+        reader->err = GSRPC_ERR_ENUM;
+    }
+{{end}}}
+
+void {{$Enum}}_write(gsrpc_writer *writer, const {{$Enum}} *val) {
+    gsrpc_write_{{$Width}}(writer, *val);
+}
+{{end}}
+
+{{define "table_header"}}{{$Table := declName .}}
+{{doc "" .}}typedef struct {{$Table}} {
+{{range .Fields}}{{doc "    " .}}{{if hasFlag .}}    bool has_{{memberName .Name}};
+{{end}}    {{fieldDecl .}};
+{{else}}    uint8_t unused; // the empty struct is not allowed
+{{end}}} {{$Table}};
+
+// {{$Table}}_init set the default values, the optional fields are absent
+void {{$Table}}_init({{$Table}} *val);
+
+void {{$Table}}_read(gsrpc_reader *reader, {{$Table}} *val);
+
+void {{$Table}}_write(gsrpc_writer *writer, const {{$Table}} *val);
+
+// {{$Table}}_encode write val into buff, the written length is returned by length
+int {{$Table}}_encode(const {{$Table}} *val, uint8_t *buff, size_t size, size_t *length);
+
+int {{$Table}}_decode({{$Table}} *val, const uint8_t *buff, size_t size);
+{{if isMap .}}
+// {{$Table}}_compare compare the map entries by key, which is used to write map in key order
+int {{$Table}}_compare(const void *lhs, const void *rhs);
+{{end}}{{end}}
+
+{{define "table_source"}}{{$Table := declName .}}
+void {{$Table}}_init({{$Table}} *val) {
+    memset(val, 0, sizeof(*val));
+{{range .Fields}}{{fieldInit . "    "}}{{end}}}
+{{if isPOD .}}
+void {{$Table}}_read(gsrpc_reader *reader, {{$Table}} *val) {
+    {{$Table}}_init(val);
+{{range .Fields}}{{$Name := printf "val->%s" (memberName .Name)}}
+{{if hasFlag .}}    val->has_{{memberName .Name}} = gsrpc_read_bool(reader);
+
+    if (val->has_{{memberName .Name}}) {
+{{read .Type $Name "        "}}    }
+{{else if isOptional .}}    if (gsrpc_read_bool(reader)) {
+{{read .Type $Name "        "}}    }
+{{else}}{{read .Type $Name "    "}}{{end}}{{else}}
+    (void)reader;
+{{end}}}
+
+void {{$Table}}_write(gsrpc_writer *writer, const {{$Table}} *val) {
+{{range .Fields}}{{$Name := printf "val->%s" (memberName .Name)}}
+{{if hasFlag .}}    gsrpc_write_bool(writer, val->has_{{memberName .Name}});
+
+    if (val->has_{{memberName .Name}}) {
+{{write .Type $Name "        "}}    }
+{{else if isOptional .}}    gsrpc_write_bool(writer, {{$Name}}.variant != {{declName .Type}}_None);
+
+    if ({{$Name}}.variant != {{declName .Type}}_None) {
+{{write .Type $Name "        "}}    }
+{{else}}{{write .Type $Name "    "}}{{end}}{{else}}    (void)writer;
+    (void)val;
+{{end}}}
+{{else}}
+void {{$Table}}_read(gsrpc_reader *reader, {{$Table}} *val) {
+    uint16_t fields;
+
+    {{$Table}}_init(val);
+
+    fields = gsrpc_read_uint16(reader);
+{{range .Fields}}{{$Name := printf "val->%s" (memberName .Name)}}
+    if (fields == 0) {
+        return;
+    }
+
+    if (gsrpc_read_tag(reader) != {{rpcName "Tag"}}_Skip) {
+{{if hasFlag .}}        val->has_{{memberName .Name}} = true;
+
+{{end}}{{read .Type $Name "        "}}    }
+
+    fields--;
+{{end}}
+    gsrpc_skip_fields(reader, fields);
+}
+
+void {{$Table}}_write(gsrpc_writer *writer, const {{$Table}} *val) {
+    gsrpc_write_uint16(writer, {{len .Fields}});
+{{range .Fields}}{{$Name := printf "val->%s" (memberName .Name)}}
+{{if isOptional .}}{{if hasFlag .}}    if (val->has_{{memberName .Name}}) {
+{{else}}    if ({{$Name}}.variant != {{declName .Type}}_None) {
+{{end}}        gsrpc_write_tags(writer, {{tagValue .Type}});
+{{write .Type $Name "        "}}    } else {
+        gsrpc_write_uint8(writer, {{rpcName "Tag"}}_Skip);
+    }
+{{else}}    gsrpc_write_tags(writer, {{tagValue .Type}});
+{{write .Type $Name "    "}}{{end}}{{else}}
+    (void)val;
+{{end}}}
+{{end}}
+int {{$Table}}_encode(const {{$Table}} *val, uint8_t *buff, size_t size, size_t *length) {
+    gsrpc_writer writer;
+
+    gsrpc_writer_init(&writer, buff, size);
+
+    {{$Table}}_write(&writer, val);
+
+    if (length != NULL) {
+        *length = writer.offset;
+    }
+
+    return writer.err;
+}
+
+int {{$Table}}_decode({{$Table}} *val, const uint8_t *buff, size_t size) {
+    gsrpc_reader reader;
+
+    gsrpc_reader_init(&reader, buff, size);
+
+    {{$Table}}_read(&reader, val);
+
+    return reader.err;
+}
+{{if isMap .}}
+int {{$Table}}_compare(const void *lhsEntry, const void *rhsEntry) {
+    const {{$Table}} *lhs = lhsEntry, *rhs = rhsEntry;
+
+    return {{compare .}};
+}
+{{end}}{{end}}
+
+{{define "union_header"}}{{$Union := declName .}}
+{{doc "" .}}typedef struct {{$Union}} {
+    uint8_t variant; // the set variant, {{$Union}}_None if no field is set
+    union {
+{{range .Fields}}{{doc "        " .}}        {{fieldDecl .}};
+{{else}}        uint8_t unused; // the empty union is not allowed
+{{end}}    } value;
+} {{$Union}};
+
+// the {{$Union}} variants, which is the variant index on the wire
+enum {
+    {{$Union}}_None = 0,
+{{range $index, $field := .Fields}}    {{$Union}}_{{title .Name}} = {{variant $index}},
+{{end}}};
+
+void {{$Union}}_init({{$Union}} *val);
+
+void {{$Union}}_read(gsrpc_reader *reader, {{$Union}} *val);
+
+void {{$Union}}_write(gsrpc_writer *writer, const {{$Union}} *val);
+
+int {{$Union}}_encode(const {{$Union}} *val, uint8_t *buff, size_t size, size_t *length);
+
+int {{$Union}}_decode({{$Union}} *val, const uint8_t *buff, size_t size);
+{{end}}
+
+{{define "union_source"}}{{$Union := declName .}}
+void {{$Union}}_init({{$Union}} *val) {
+    memset(val, 0, sizeof(*val));
+}
+
+// the unknown variant is skipped and read as {{$Union}}_None
+void {{$Union}}_read(gsrpc_reader *reader, {{$Union}} *val) {
+    uint8_t tags[GSRPC_MAX_TAGS];
+
+    {{$Union}}_init(val);
+
+    val->variant = gsrpc_read_uint8(reader);
+
+    if (val->variant == {{$Union}}_None) {
+        return;
+    }
+
+    gsrpc_read_tags(reader, tags, sizeof(tags));
+
+    switch (val->variant) {
+{{range .Fields}}    case {{$Union}}_{{title .Name}}:
+{{read .Type (printf "val->value.%s" (memberName .Name)) "        "}}        break;
+{{end}}    default:
+        gsrpc_skip(reader, tags);
+
+        val->variant = {{$Union}}_None;
+
+        break;
+    }
+}
+
+void {{$Union}}_write(gsrpc_writer *writer, const {{$Union}} *val) {
+    switch (val->variant) {
+{{range .Fields}}    case {{$Union}}_{{title .Name}}:
+        gsrpc_write_uint8(writer, {{$Union}}_{{title .Name}});
+        gsrpc_write_tags(writer, {{tagValue .Type}});
+{{write .Type (printf "val->value.%s" (memberName .Name)) "        "}}        break;
+{{end}}    default:
+        gsrpc_write_uint8(writer, {{$Union}}_None);
+        break;
+    }
+}
+
+int {{$Union}}_encode(const {{$Union}} *val, uint8_t *buff, size_t size, size_t *length) {
+    gsrpc_writer writer;
+
+    gsrpc_writer_init(&writer, buff, size);
+
+    {{$Union}}_write(&writer, val);
+
+    if (length != NULL) {
+        *length = writer.offset;
+    }
+
+    return writer.err;
+}
+
+int {{$Union}}_decode({{$Union}} *val, const uint8_t *buff, size_t size) {
+    gsrpc_reader reader;
+
+    gsrpc_reader_init(&reader, buff, size);
+
+    {{$Union}}_read(&reader, val);
+
+    return reader.err;
+}
+{{end}}
+
+{{define "contract_header"}}{{$Contract := declName .}}{{$Root := .}}
+#define {{$Contract}}_NAME "{{.FullName}}"
+{{range .Methods}}{{$Method := printf "%s_%s" $Contract (snakeName .Name)}}{{if .Params}}
+typedef struct {{$Method}}_params {
+{{range .Params}}    {{paramDecl .}};
+{{end}}} {{$Method}}_params;
+{{end}}{{if hasResult .}}
+// {{$Method}}_result the {{title .Name}} return value or the raised exception
+typedef struct {{$Method}}_result {
+{{if notVoid .Return}}    {{returnDecl .}};
+{{end}}{{if .Exceptions}}    union {
+{{range .Exceptions}}        {{declName .Type}} {{exceptionName .}};
+{{end}}    } exception;
+{{end}}} {{$Method}}_result;
+{{end}}{{end}}
+{{doc "" .}}typedef struct {{$Contract}} {
+    void *context; // the user data passed to the functions
+{{range .Methods}}{{doc "    " .}}    int (*{{memberName .Name}})({{signature $Root .}});
+{{end}}} {{$Contract}};
+
+// {{$Contract}}_scratch the params and result of the dispatching call, which can be reused by the calls
+typedef union {{$Contract}}_scratch {
+    uint8_t none;
+{{range .Methods}}{{$Method := printf "%s_%s" $Contract (snakeName .Name)}}{{if or .Params (hasResult .)}}    struct {
+{{if .Params}}        {{$Method}}_params params;
+{{end}}{{if hasResult .}}        {{$Method}}_result result;
+{{end}}    } {{memberName .Name}};
+{{end}}{{end}}} {{$Contract}}_scratch;
+
+// {{$Contract}}_dispatch invoke the service function and write the response into writer, the service functions
+// return GSRPC_OK, GSRPC_EXCEPTION(id) with the result exception set or the negative error code, the NULL function
+// is dispatched as unknown method and GSRPC_NO_REPLY is returned for async method
+int {{$Contract}}_dispatch(const {{$Contract}} *service, {{$Contract}}_scratch *scratch, const gsrpc_call *call, gsrpc_writer *writer);
+{{range .Methods}}{{$Method := printf "%s_%s" $Contract (snakeName .Name)}}
+// {{$Method}}_call write the {{title .Name}} request into writer
+void {{$Method}}_call(gsrpc_writer *writer, uint32_t id, uint16_t service{{if .Params}}, const {{$Method}}_params *params{{end}});
+{{if not (isAsync .)}}
+// {{$Method}}_return read the {{title .Name}} response, GSRPC_EXCEPTION(id) is returned for the declared exception,
+// GSRPC_ERR_INVALID_ARGUMENT is returned for the reserved exception -2 with the violations read into invalid if not NULL
+int {{$Method}}_return(const gsrpc_return *callReturn{{if hasResult .}}, {{$Method}}_result *result{{end}}, {{rpcName "InvalidArgumentException"}} *invalid);
+{{end}}{{end}}{{end}}
+
+{{define "contract_source"}}{{$Contract := declName .}}
+int {{$Contract}}_dispatch(const {{$Contract}} *service, {{$Contract}}_scratch *scratch, const gsrpc_call *call, gsrpc_writer *writer) {
+    gsrpc_reader params, *reader = &params;
+    size_t size, offset;
+    int ret;
+
+    gsrpc_call_params(call, reader);
+
+    switch (call->method) {
+{{range .Methods}}{{$Name := memberName .Name}}    case {{.ID}}:
+        if (call->params != {{.ParamsCount}}) {
+            return GSRPC_ERR_PARAMS;
+        }
+
+        if (service->{{$Name}} == NULL) {
+            return GSRPC_ERR_METHOD;
+        }
+{{if .Params}}
+        memset(&scratch->{{$Name}}.params, 0, sizeof(scratch->{{$Name}}.params));
+{{range .Params}}
+        size = gsrpc_read_param(reader);
+{{read .Type (printf "scratch->%s.params.%s" $Name (memberName .Name)) "        "}}        gsrpc_read_param_end(reader, size);
+{{end}}
+        if (reader->err != GSRPC_OK) {
+            return reader->err;
+        }
+{{end}}{{if hasResult .}}
+        memset(&scratch->{{$Name}}.result, 0, sizeof(scratch->{{$Name}}.result));
+{{end}}
+        ret = service->{{$Name}}(service->context{{if .Params}}, &scratch->{{$Name}}.params{{end}}{{if hasResult .}}, &scratch->{{$Name}}.result{{end}});
+{{if isAsync .}}
+        if (ret != GSRPC_OK) {
+            return ret < 0 ? ret : GSRPC_ERR_REMOTE;
+        }
+
+        return GSRPC_NO_REPLY;
+{{else}}
+        switch (ret) {
+        case GSRPC_OK:
+            offset = gsrpc_write_response(writer, call, -1);
+{{if notVoid .Return}}{{write .Return (printf "scratch->%s.result.ret" $Name) "            "}}{{end}}            gsrpc_write_response_end(writer, call, offset);
+            break;
+{{range .Exceptions}}        case GSRPC_EXCEPTION({{.ID}}):
+            offset = gsrpc_write_response(writer, call, {{.ID}});
+            {{declName .Type}}_write(writer, &scratch->{{$Name}}.result.exception.{{exceptionName .}});
+            gsrpc_write_response_end(writer, call, offset);
+            break;
+{{end}}        default:
+            return ret < 0 ? ret : GSRPC_ERR_REMOTE;
+        }
+
+        return writer->err;
+{{end}}
+{{end}}    default:
+        return GSRPC_ERR_METHOD;
+    }
+}
+{{range .Methods}}{{$Method := printf "%s_%s" $Contract (snakeName .Name)}}
+void {{$Method}}_call(gsrpc_writer *writer, uint32_t id, uint16_t service{{if .Params}}, const {{$Method}}_params *params{{end}}) {
+{{if .Params}}    size_t offset;
+
+{{end}}    gsrpc_write_request(writer, id, service, {{.ID}}, {{.ParamsCount}});
+{{range .Params}}
+    offset = gsrpc_write_param(writer);
+{{write .Type (printf "params->%s" (memberName .Name)) "    "}}    gsrpc_write_param_end(writer, offset);
+{{end}}
+    gsrpc_write_request_end(writer);
+}
+{{if not (isAsync .)}}
+int {{$Method}}_return(const gsrpc_return *callReturn{{if hasResult .}}, {{$Method}}_result *result{{end}}, {{rpcName "InvalidArgumentException"}} *invalid) {
+{{if hasResult .}}    gsrpc_reader content, *reader = &content;
+
+    gsrpc_reader_init(reader, callReturn->content, callReturn->length);
+
+    memset(result, 0, sizeof(*result));
+
+{{end}}    switch (callReturn->exception) {
+    case -1:
+{{if notVoid .Return}}{{read .Return "result->ret" "        "}}
+        return reader->err;
+{{else}}        return GSRPC_OK;
+{{end}}    case -2:
+        if (invalid != NULL) {
+            gsrpc_reader violations;
+
+            gsrpc_reader_init(&violations, callReturn->content, callReturn->length);
+
+            {{rpcName "InvalidArgumentException"}}_read(&violations, invalid);
+
+            if (violations.err != GSRPC_OK) {
+                return violations.err;
+            }
+        }
+
+        return GSRPC_ERR_INVALID_ARGUMENT;
+{{range .Exceptions}}    case {{.ID}}:
+        {{declName .Type}}_read(reader, &result->exception.{{exceptionName .}});
+
+        return reader->err != GSRPC_OK ? reader->err : GSRPC_EXCEPTION({{.ID}});
+{{end}}    default:
+        return GSRPC_ERR_REMOTE;
+    }
+}
+{{end}}{{end}}{{end}}
+
+{{define "stream.h"}}// generate by gs2c,don't modify it manually
+//
+// gsrpc runtime: the little endian reader/writer over the caller supplied buffer, the first error is kept
+// in err and the following reads/writes do nothing, so the generated code check the error once at the end
+#ifndef COM_GSRPC_STREAM_H
+#define COM_GSRPC_STREAM_H
+
+#include <stdbool.h>
+#include <stddef.h>
+#include <stdint.h>
+#include <string.h>
+
+#ifdef __cplusplus
+extern "C" {
+#endif
+
+// GSRPC_MAX_STRING the capacity of string and byte[] without @MaxLen
+#ifndef GSRPC_MAX_STRING
+#define GSRPC_MAX_STRING 64
+#endif
+
+// GSRPC_MAX_LIST the capacity of list and map without @MaxLen
+#ifndef GSRPC_MAX_LIST
+#define GSRPC_MAX_LIST 16
+#endif
+
+// GSRPC_MAX_TAGS the max length of tag sequence
+#ifndef GSRPC_MAX_TAGS
+#define GSRPC_MAX_TAGS 16
+#endif
+
+// GSRPC_MAX_DEPTH the max nested depth of skipped value
+#ifndef GSRPC_MAX_DEPTH
+#define GSRPC_MAX_DEPTH 16
+#endif
+
+#define GSRPC_COUNTOF(array) (sizeof(array) / sizeof((array)[0]))
+
+// the error codes
+enum {
+    GSRPC_OK = 0,
+    GSRPC_ERR_EOF = -1,               // read beyond the end of buffer
+    GSRPC_ERR_OVERFLOW = -2,          // write beyond the end of buffer
+    GSRPC_ERR_CAPACITY = -3,          // the string or list length exceeds the capacity
+    GSRPC_ERR_SIZE = -4,              // the fixed array length mismatch
+    GSRPC_ERR_ENUM = -5,              // the @Strict enum value is not declared
+    GSRPC_ERR_TAG = -6,               // unknown tag, too long tag sequence or too deep skipped value
+    GSRPC_ERR_METHOD = -7,            // unknown method
+    GSRPC_ERR_PARAMS = -8,            // the params count mismatch
+    GSRPC_ERR_REMOTE = -9,            // the exception not declared by method
+    GSRPC_ERR_INVALID_ARGUMENT = -10, // the reserved InvalidArgument exception -2
+};
+
+typedef struct gsrpc_reader {
+    const uint8_t *buff;
+    size_t size;
+    size_t offset;
+    int err; // the first error
+} gsrpc_reader;
+
+typedef struct gsrpc_writer {
+    uint8_t *buff;
+    size_t size;
+    size_t offset;
+    int err; // the first error
+} gsrpc_writer;
+
+void gsrpc_reader_init(gsrpc_reader *reader, const uint8_t *buff, size_t size);
+
+int8_t gsrpc_read_int8(gsrpc_reader *reader);
+uint8_t gsrpc_read_uint8(gsrpc_reader *reader);
+int16_t gsrpc_read_int16(gsrpc_reader *reader);
+uint16_t gsrpc_read_uint16(gsrpc_reader *reader);
+int32_t gsrpc_read_int32(gsrpc_reader *reader);
+uint32_t gsrpc_read_uint32(gsrpc_reader *reader);
+int64_t gsrpc_read_int64(gsrpc_reader *reader);
+uint64_t gsrpc_read_uint64(gsrpc_reader *reader);
+float gsrpc_read_float32(gsrpc_reader *reader);
+double gsrpc_read_float64(gsrpc_reader *reader);
+bool gsrpc_read_bool(gsrpc_reader *reader);
+
+// gsrpc_read_length read the length prefix, GSRPC_ERR_CAPACITY is set if the length exceeds capacity
+uint16_t gsrpc_read_length(gsrpc_reader *reader, size_t capacity);
+
+// gsrpc_read_size read the fixed array length prefix, which must be size
+void gsrpc_read_size(gsrpc_reader *reader, size_t size);
+
+void gsrpc_read_bytes(gsrpc_reader *reader, uint8_t *val, size_t length);
+
+// gsrpc_read_string read the string into the char array with size, the string is NUL terminated
+void gsrpc_read_string(gsrpc_reader *reader, char *val, size_t size);
+
+// gsrpc_read_tags read the tag sequence into tags with size and return the first tag
+uint8_t gsrpc_read_tags(gsrpc_reader *reader, uint8_t *tags, size_t size);
+
+// gsrpc_read_tag read the tag sequence and return the first tag
+uint8_t gsrpc_read_tag(gsrpc_reader *reader);
+
+void gsrpc_skip(gsrpc_reader *reader, const uint8_t *tags);
+
+void gsrpc_skip_fields(gsrpc_reader *reader, uint16_t fields);
+
+void gsrpc_skip_bytes(gsrpc_reader *reader, size_t length);
+
+void gsrpc_writer_init(gsrpc_writer *writer, uint8_t *buff, size_t size);
+
+void gsrpc_write_int8(gsrpc_writer *writer, int8_t val);
+void gsrpc_write_uint8(gsrpc_writer *writer, uint8_t val);
+void gsrpc_write_int16(gsrpc_writer *writer, int16_t val);
+void gsrpc_write_uint16(gsrpc_writer *writer, uint16_t val);
+void gsrpc_write_int32(gsrpc_writer *writer, int32_t val);
+void gsrpc_write_uint32(gsrpc_writer *writer, uint32_t val);
+void gsrpc_write_int64(gsrpc_writer *writer, int64_t val);
+void gsrpc_write_uint64(gsrpc_writer *writer, uint64_t val);
+void gsrpc_write_float32(gsrpc_writer *writer, float val);
+void gsrpc_write_float64(gsrpc_writer *writer, double val);
+void gsrpc_write_bool(gsrpc_writer *writer, bool val);
+
+// gsrpc_write_length write the length prefix, GSRPC_ERR_CAPACITY is set if the length exceeds capacity
+void gsrpc_write_length(gsrpc_writer *writer, size_t length, size_t capacity);
+
+void gsrpc_write_bytes(gsrpc_writer *writer, const uint8_t *val, size_t length);
+
+// gsrpc_write_string write the NUL terminated string in the char array with size
+void gsrpc_write_string(gsrpc_writer *writer, const char *val, size_t size);
+
+void gsrpc_write_tags(gsrpc_writer *writer, const uint8_t *tags, size_t length);
+
+// gsrpc_write_order write the map length and sort the entries index by compare into order,
+// the written length is returned
+uint16_t gsrpc_write_order(gsrpc_writer *writer, uint16_t *order, uint16_t length, size_t capacity,
+                           const void *data, size_t size, int (*compare)(const void *, const void *));
+
+#ifdef __cplusplus
+}
+#endif
+
+#endif // COM_GSRPC_STREAM_H
+{{end}}
+
+{{define "stream.c"}}// generate by gs2c,don't modify it manually
+#include <com/gsrpc/stream.h>
+#include <com/gsrpc/gsrpc.gs.h>
+
+static bool gsrpc_readable(gsrpc_reader *reader, size_t length) {
+    if (reader->err != GSRPC_OK) {
+        return false;
+    }
+
+    if (length > reader->size - reader->offset) {
+        reader->err = GSRPC_ERR_EOF;
+
+        return false;
+    }
+
+    return true;
+}
+
+static uint64_t gsrpc_decode(gsrpc_reader *reader, size_t length) {
+    uint64_t val = 0;
+
+    if (!gsrpc_readable(reader, length)) {
+        return 0;
+    }
+
+    for (size_t i = 0; i < length; i++) {
+        val |= (uint64_t)reader->buff[reader->offset + i] << (8 * i);
+    }
+
+    reader->offset += length;
+
+    return val;
+}
+
+void gsrpc_reader_init(gsrpc_reader *reader, const uint8_t *buff, size_t size) {
+    reader->buff = buff;
+    reader->size = size;
+    reader->offset = 0;
+    reader->err = GSRPC_OK;
+}
+
+int8_t gsrpc_read_int8(gsrpc_reader *reader) {
+    return (int8_t)gsrpc_read_uint8(reader);
+}
+
+uint8_t gsrpc_read_uint8(gsrpc_reader *reader) {
+    return (uint8_t)gsrpc_decode(reader, 1);
+}
+
+int16_t gsrpc_read_int16(gsrpc_reader *reader) {
+    return (int16_t)gsrpc_read_uint16(reader);
+}
+
+uint16_t gsrpc_read_uint16(gsrpc_reader *reader) {
+    return (uint16_t)gsrpc_decode(reader, 2);
+}
+
+int32_t gsrpc_read_int32(gsrpc_reader *reader) {
+    return (int32_t)gsrpc_read_uint32(reader);
+}
+
+uint32_t gsrpc_read_uint32(gsrpc_reader *reader) {
+    return (uint32_t)gsrpc_decode(reader, 4);
+}
+
+int64_t gsrpc_read_int64(gsrpc_reader *reader) {
+    return (int64_t)gsrpc_read_uint64(reader);
+}
+
+uint64_t gsrpc_read_uint64(gsrpc_reader *reader) {
+    return gsrpc_decode(reader, 8);
+}
+
+float gsrpc_read_float32(gsrpc_reader *reader) {
+    uint32_t bits = gsrpc_read_uint32(reader);
+
+    float val;
+
+    memcpy(&val, &bits, sizeof(val));
+
+    return val;
+}
+
+double gsrpc_read_float64(gsrpc_reader *reader) {
+    uint64_t bits = gsrpc_read_uint64(reader);
+
+    double val;
+
+    memcpy(&val, &bits, sizeof(val));
+
+    return val;
+}
+
+bool gsrpc_read_bool(gsrpc_reader *reader) {
+    return gsrpc_read_uint8(reader) != 0;
+}
+
+uint16_t gsrpc_read_length(gsrpc_reader *reader, size_t capacity) {
+    uint16_t length = gsrpc_read_uint16(reader);
+
+    if (length > capacity) {
+        if (reader->err == GSRPC_OK) {
+            reader->err = GSRPC_ERR_CAPACITY;
+        }
+
+        return 0;
+    }
+
+    return length;
+}
+
+void gsrpc_read_size(gsrpc_reader *reader, size_t size) {
+    uint16_t length = gsrpc_read_uint16(reader);
+
+    if (reader->err == GSRPC_OK && length != size) {
+        reader->err = GSRPC_ERR_SIZE;
+    }
+}
+
+void gsrpc_read_bytes(gsrpc_reader *reader, uint8_t *val, size_t length) {
+    if (!gsrpc_readable(reader, length)) {
+        return;
+    }
+
+    if (length > 0) {
+        memcpy(val, reader->buff + reader->offset, length);
+    }
+
+    reader->offset += length;
+}
+
+void gsrpc_read_string(gsrpc_reader *reader, char *val, size_t size) {
+    uint16_t length = gsrpc_read_length(reader, size - 1);
+
+    gsrpc_read_bytes(reader, (uint8_t *)val, length);
+
+    val[reader->err == GSRPC_OK ? length : 0] = '\0';
+}
+
+uint8_t gsrpc_read_tags(gsrpc_reader *reader, uint8_t *tags, size_t size) {
+    size_t pending = 1, length = 0;
+    bool count = false; // the next byte is the POD field count
+
+    while (pending > 0) {
+        uint8_t tag = gsrpc_read_uint8(reader);
+
+        if (reader->err == GSRPC_OK && length == size) {
+            reader->err = GSRPC_ERR_TAG;
+        }
+
+        if (reader->err != GSRPC_OK) {
+            tags[0] = {{rpcName "Tag"}}_Skip;
+
+            return tags[0];
+        }
+
+        tags[length++] = tag;
+
+        pending--;
+
+        if (count) {
+            count = false;
+
+            pending += tag;
+
+            continue;
+        }
+
+        switch (tag) {
+{{range wireNested}}{{range .Tags}}        case {{rpcName "Tag"}}_{{.}}:
+{{end}}{{if lt .Nested 0}}            pending++;
+
+            count = true;
+{{else}}            pending += {{.Nested}};
+{{end}}            break;
+{{end}}        default:
+            break;
+        }
+    }
+
+    return tags[0];
+}
+
+uint8_t gsrpc_read_tag(gsrpc_reader *reader) {
+    uint8_t tags[GSRPC_MAX_TAGS];
+
+    return gsrpc_read_tags(reader, tags, sizeof(tags));
+}
+
+// gsrpc_tags_end get the index next to the tag sequence begin with index
+static size_t gsrpc_tags_end(const uint8_t *tags, size_t index) {
+    size_t next = index + 1, nested = 0;
+
+    switch (tags[index]) {
+{{range wireNested}}{{range .Tags}}    case {{rpcName "Tag"}}_{{.}}:
+{{end}}{{if lt .Nested 0}}        next = index + 2;
+        nested = tags[index + 1];
+{{else}}        nested = {{.Nested}};
+{{end}}        break;
+{{end}}    default:
+        break;
+    }
+
+    for (size_t i = 0; i < nested; i++) {
+        next = gsrpc_tags_end(tags, next);
+    }
+
+    return next;
+}
+
+static void gsrpc_skip_table(gsrpc_reader *reader, uint16_t fields, int depth);
+
+static void gsrpc_skip_components(gsrpc_reader *reader, const uint8_t *tags, size_t index, size_t count, int depth);
+
+static void gsrpc_skip_value(gsrpc_reader *reader, const uint8_t *tags, size_t index, int depth) {
+    uint16_t length;
+
+    if (reader->err != GSRPC_OK) {
+        return;
+    }
+
+    switch (tags[index]) {
+{{range wireCases}}{{range .Tags}}    case {{rpcName "Tag"}}_{{.}}:
+{{end}}{{if eq .Kind "fixed"}}        gsrpc_skip_bytes(reader, {{.Size}});
+{{else if eq .Kind "sized"}}        gsrpc_skip_bytes(reader, gsrpc_read_uint16(reader));
+{{else if eq .Kind "fields"}}        gsrpc_skip_table(reader, gsrpc_read_uint16(reader), depth + 1);
+{{else if eq .Kind "seq"}}        length = gsrpc_read_uint16(reader);
+
+        for (uint16_t i = 0; i < length && reader->err == GSRPC_OK; i++) {
+            gsrpc_skip_components(reader, tags, index + 1, {{.Nested}}, depth);
+        }
+
+{{else if eq .Kind "record"}}        gsrpc_skip_components(reader, tags, index + 2, tags[index + 1], depth);
+{{else if eq .Kind "optional"}}        if (gsrpc_read_bool(reader)) {
+            gsrpc_skip_value(reader, tags, index + 1, depth);
+        }
+
+{{else if eq .Kind "variant"}}        // the set variant is followed by its tag sequence and value, which is skipped as one field table
+        if (gsrpc_read_uint8(reader) != 0) {
+            gsrpc_skip_table(reader, 1, depth + 1);
+        }
+
+{{end}}        break;
+{{end}}    default:
+        reader->err = GSRPC_ERR_TAG;
+        break;
+    }
+}
+
+// gsrpc_skip_components skip the values of count tag sequences begin with index
+static void gsrpc_skip_components(gsrpc_reader *reader, const uint8_t *tags, size_t index, size_t count, int depth) {
+    for (size_t i = 0; i < count && reader->err == GSRPC_OK; i++) {
+        gsrpc_skip_value(reader, tags, index, depth);
+
+        index = gsrpc_tags_end(tags, index);
+    }
+}
+
+static void gsrpc_skip_table(gsrpc_reader *reader, uint16_t fields, int depth) {
+    uint8_t tags[GSRPC_MAX_TAGS];
+
+    if (depth > GSRPC_MAX_DEPTH) {
+        if (reader->err == GSRPC_OK) {
+            reader->err = GSRPC_ERR_TAG;
+        }
+
+        return;
+    }
+
+    for (uint16_t i = 0; i < fields && reader->err == GSRPC_OK; i++) {
+        gsrpc_read_tags(reader, tags, sizeof(tags));
+
+        gsrpc_skip_value(reader, tags, 0, depth);
+    }
+}
+
+void gsrpc_skip(gsrpc_reader *reader, const uint8_t *tags) {
+    gsrpc_skip_value(reader, tags, 0, 0);
+}
+
+void gsrpc_skip_fields(gsrpc_reader *reader, uint16_t fields) {
+    gsrpc_skip_table(reader, fields, 0);
+}
+
+void gsrpc_skip_bytes(gsrpc_reader *reader, size_t length) {
+    if (gsrpc_readable(reader, length)) {
+        reader->offset += length;
+    }
+}
+
+static bool gsrpc_writable(gsrpc_writer *writer, size_t length) {
+    if (writer->err != GSRPC_OK) {
+        return false;
+    }
+
+    if (length > writer->size - writer->offset) {
+        writer->err = GSRPC_ERR_OVERFLOW;
+
+        return false;
+    }
+
+    return true;
+}
+
+static void gsrpc_encode(gsrpc_writer *writer, uint64_t val, size_t length) {
+    if (!gsrpc_writable(writer, length)) {
+        return;
+    }
+
+    for (size_t i = 0; i < length; i++) {
+        writer->buff[writer->offset + i] = (uint8_t)(val >> (8 * i));
+    }
+
+    writer->offset += length;
+}
+
+void gsrpc_writer_init(gsrpc_writer *writer, uint8_t *buff, size_t size) {
+    writer->buff = buff;
+    writer->size = size;
+    writer->offset = 0;
+    writer->err = GSRPC_OK;
+}
+
+void gsrpc_write_int8(gsrpc_writer *writer, int8_t val) {
+    gsrpc_write_uint8(writer, (uint8_t)val);
+}
+
+void gsrpc_write_uint8(gsrpc_writer *writer, uint8_t val) {
+    gsrpc_encode(writer, val, 1);
+}
+
+void gsrpc_write_int16(gsrpc_writer *writer, int16_t val) {
+    gsrpc_write_uint16(writer, (uint16_t)val);
+}
+
+void gsrpc_write_uint16(gsrpc_writer *writer, uint16_t val) {
+    gsrpc_encode(writer, val, 2);
+}
+
+void gsrpc_write_int32(gsrpc_writer *writer, int32_t val) {
+    gsrpc_write_uint32(writer, (uint32_t)val);
+}
+
+void gsrpc_write_uint32(gsrpc_writer *writer, uint32_t val) {
+    gsrpc_encode(writer, val, 4);
+}
+
+void gsrpc_write_int64(gsrpc_writer *writer, int64_t val) {
+    gsrpc_write_uint64(writer, (uint64_t)val);
+}
+
+void gsrpc_write_uint64(gsrpc_writer *writer, uint64_t val) {
+    gsrpc_encode(writer, val, 8);
+}
+
+void gsrpc_write_float32(gsrpc_writer *writer, float val) {
+    uint32_t bits;
+
+    memcpy(&bits, &val, sizeof(bits));
+
+    gsrpc_write_uint32(writer, bits);
+}
+
+void gsrpc_write_float64(gsrpc_writer *writer, double val) {
+    uint64_t bits;
+
+    memcpy(&bits, &val, sizeof(bits));
+
+    gsrpc_write_uint64(writer, bits);
+}
+
+void gsrpc_write_bool(gsrpc_writer *writer, bool val) {
+    gsrpc_write_uint8(writer, val ? 1 : 0);
+}
+
+void gsrpc_write_length(gsrpc_writer *writer, size_t length, size_t capacity) {
+    if (length > capacity || length > UINT16_MAX) {
+        if (writer->err == GSRPC_OK) {
+            writer->err = GSRPC_ERR_CAPACITY;
+        }
+
+        return;
+    }
+
+    gsrpc_write_uint16(writer, (uint16_t)length);
+}
+
+void gsrpc_write_bytes(gsrpc_writer *writer, const uint8_t *val, size_t length) {
+    if (!gsrpc_writable(writer, length)) {
+        return;
+    }
+
+    if (length > 0) {
+        memcpy(writer->buff + writer->offset, val, length);
+    }
+
+    writer->offset += length;
+}
+
+void gsrpc_write_string(gsrpc_writer *writer, const char *val, size_t size) {
+    const char *end = memchr(val, '\0', size);
+
+    size_t length = end != NULL ? (size_t)(end - val) : size;
+
+    gsrpc_write_length(writer, length, size - 1);
+
+    gsrpc_write_bytes(writer, (const uint8_t *)val, length);
+}
+
+void gsrpc_write_tags(gsrpc_writer *writer, const uint8_t *tags, size_t length) {
+    gsrpc_write_bytes(writer, tags, length);
+}
+
+uint16_t gsrpc_write_order(gsrpc_writer *writer, uint16_t *order, uint16_t length, size_t capacity,
+                           const void *data, size_t size, int (*compare)(const void *, const void *)) {
+    const uint8_t *entries = data;
+
+    gsrpc_write_length(writer, length, capacity);
+
+    if (writer->err != GSRPC_OK) {
+        return 0;
+    }
+
+    // the insertion sort keep the entries with the same key in order and need no extra memory
+    for (uint16_t i = 0; i < length; i++) {
+        uint16_t j = i;
+
+        while (j > 0 && compare(entries + (size_t)order[j - 1] * size, entries + (size_t)i * size) > 0) {
+            order[j] = order[j - 1];
+
+            j--;
+        }
+
+        order[j] = i;
+    }
+
+    return length;
+}
+{{end}}
+
+{{define "service.h"}}// generate by gs2c,don't modify it manually
+//
+// gsrpc runtime: the Message/Request/Response framing without copying content, the contract
+// dispatch and call functions are generated with the contract
+#ifndef COM_GSRPC_SERVICE_H
+#define COM_GSRPC_SERVICE_H
+
+#include <com/gsrpc/stream.h>
+
+#ifdef __cplusplus
+extern "C" {
+#endif
+
+// GSRPC_EXCEPTION the service function result of the declared exception with id
+#define GSRPC_EXCEPTION(id) ((id) + 1)
+
+// GSRPC_NO_REPLY the dispatch result of async method, which has no response
+#define GSRPC_NO_REPLY 1
+
+// gsrpc_message the message frame, the content points into the read buffer
+typedef struct gsrpc_message {
+    uint8_t code;
+    uint8_t agent;
+    const uint8_t *content;
+    uint16_t length;
+} gsrpc_message;
+
+// gsrpc_call the received request, the params are read from the read buffer by dispatch
+typedef struct gsrpc_call {
+    uint32_t id;
+    uint16_t service;
+    uint16_t method;
+    uint16_t params; // the params count
+    uint64_t trace;
+    uint32_t prev;
+    const uint8_t *buff;
+    size_t offset; // the params begin offset
+    size_t size;   // the params end offset
+} gsrpc_call;
+
+// gsrpc_return the received response, the content points into the read buffer
+typedef struct gsrpc_return {
+    uint32_t id;
+    int8_t exception; // the exception id, -1 if the content is return value
+    const uint8_t *content;
+    uint16_t length;
+    uint64_t trace;
+} gsrpc_return;
+
+int gsrpc_read_message(gsrpc_message *message, const uint8_t *buff, size_t size);
+
+// gsrpc_write_message write the message header, the content is written after and closed by gsrpc_write_message_end
+size_t gsrpc_write_message(gsrpc_writer *writer, uint8_t code, uint8_t agent);
+
+void gsrpc_write_message_end(gsrpc_writer *writer, size_t offset);
+
+int gsrpc_read_call(gsrpc_call *call, const uint8_t *buff, size_t size);
+
+// gsrpc_call_params init the reader of call params
+void gsrpc_call_params(const gsrpc_call *call, gsrpc_reader *reader);
+
+// gsrpc_read_param limit the reader to the next param, the limit is removed by gsrpc_read_param_end
+size_t gsrpc_read_param(gsrpc_reader *reader);
+
+void gsrpc_read_param_end(gsrpc_reader *reader, size_t size);
+
+void gsrpc_write_request(gsrpc_writer *writer, uint32_t id, uint16_t service, uint16_t method, uint16_t params);
+
+size_t gsrpc_write_param(gsrpc_writer *writer);
+
+void gsrpc_write_param_end(gsrpc_writer *writer, size_t offset);
+
+void gsrpc_write_request_end(gsrpc_writer *writer);
+
+int gsrpc_read_return(gsrpc_return *callReturn, const uint8_t *buff, size_t size);
+
+// gsrpc_write_response write the response header of call, exception -1 means the content is return value
+size_t gsrpc_write_response(gsrpc_writer *writer, const gsrpc_call *call, int8_t exception);
+
+void gsrpc_write_response_end(gsrpc_writer *writer, const gsrpc_call *call, size_t offset);
+
+#ifdef __cplusplus
+}
+#endif
+
+#endif // COM_GSRPC_SERVICE_H
+{{end}}
+
+{{define "service.c"}}// generate by gs2c,don't modify it manually
+#include <com/gsrpc/service.h>
+
+// gsrpc_write_begin write the length placeholder and return the offset
+static size_t gsrpc_write_begin(gsrpc_writer *writer) {
+    size_t offset = writer->offset;
+
+    gsrpc_write_uint16(writer, 0);
+
+    return offset;
+}
+
+// gsrpc_write_end patch the placeholder with the length of content written after
+static void gsrpc_write_end(gsrpc_writer *writer, size_t offset) {
+    size_t length;
+
+    if (writer->err != GSRPC_OK) {
+        return;
+    }
+
+    length = writer->offset - offset - 2;
+
+    if (length > UINT16_MAX) {
+        writer->err = GSRPC_ERR_CAPACITY;
+
+        return;
+    }
+
+    writer->buff[offset] = (uint8_t)length;
+
+    writer->buff[offset + 1] = (uint8_t)(length >> 8);
+}
+
+int gsrpc_read_message(gsrpc_message *message, const uint8_t *buff, size_t size) {
+    gsrpc_reader reader;
+
+    gsrpc_reader_init(&reader, buff, size);
+
+    message->code = gsrpc_read_uint8(&reader);
+
+    message->agent = gsrpc_read_uint8(&reader);
+
+    message->length = gsrpc_read_uint16(&reader);
+
+    message->content = buff + reader.offset;
+
+    gsrpc_skip_bytes(&reader, message->length);
+
+    return reader.err;
+}
+
+size_t gsrpc_write_message(gsrpc_writer *writer, uint8_t code, uint8_t agent) {
+    gsrpc_write_uint8(writer, code);
+
+    gsrpc_write_uint8(writer, agent);
+
+    return gsrpc_write_begin(writer);
+}
+
+void gsrpc_write_message_end(gsrpc_writer *writer, size_t offset) {
+    gsrpc_write_end(writer, offset);
+}
+
+int gsrpc_read_call(gsrpc_call *call, const uint8_t *buff, size_t size) {
+    gsrpc_reader reader;
+
+    gsrpc_reader_init(&reader, buff, size);
+
+    call->id = gsrpc_read_uint32(&reader);
+
+    call->service = gsrpc_read_uint16(&reader);
+
+    call->method = gsrpc_read_uint16(&reader);
+
+    call->params = gsrpc_read_uint16(&reader);
+
+    call->buff = buff;
+
+    call->offset = reader.offset;
+
+    for (uint16_t i = 0; i < call->params && reader.err == GSRPC_OK; i++) {
+        gsrpc_skip_bytes(&reader, gsrpc_read_uint16(&reader));
+    }
+
+    call->size = reader.offset;
+
+    call->trace = gsrpc_read_uint64(&reader);
+
+    call->prev = gsrpc_read_uint32(&reader);
+
+    return reader.err;
+}
+
+void gsrpc_call_params(const gsrpc_call *call, gsrpc_reader *reader) {
+    gsrpc_reader_init(reader, call->buff, call->size);
+
+    reader->offset = call->offset;
+}
+
+size_t gsrpc_read_param(gsrpc_reader *reader) {
+    size_t size = reader->size;
+
+    uint16_t length = gsrpc_read_uint16(reader);
+
+    if (reader->err == GSRPC_OK) {
+        if (length > reader->size - reader->offset) {
+            reader->err = GSRPC_ERR_EOF;
+        } else {
+            reader->size = reader->offset + length;
+        }
+    }
+
+    return size;
+}
+
+void gsrpc_read_param_end(gsrpc_reader *reader, size_t size) {
+    // the param content not read by the older version is skipped
+    reader->offset = reader->size;
+
+    reader->size = size;
+}
+
+void gsrpc_write_request(gsrpc_writer *writer, uint32_t id, uint16_t service, uint16_t method, uint16_t params) {
+    gsrpc_write_uint32(writer, id);
+
+    gsrpc_write_uint16(writer, service);
+
+    gsrpc_write_uint16(writer, method);
+
+    gsrpc_write_uint16(writer, params);
+}
+
+size_t gsrpc_write_param(gsrpc_writer *writer) {
+    return gsrpc_write_begin(writer);
+}
+
+void gsrpc_write_param_end(gsrpc_writer *writer, size_t offset) {
+    gsrpc_write_end(writer, offset);
+}
+
+void gsrpc_write_request_end(gsrpc_writer *writer) {
+    // trace and prev
+    gsrpc_write_uint64(writer, 0);
+
+    gsrpc_write_uint32(writer, 0);
+}
+
+int gsrpc_read_return(gsrpc_return *callReturn, const uint8_t *buff, size_t size) {
+    gsrpc_reader reader;
+
+    gsrpc_reader_init(&reader, buff, size);
+
+    callReturn->id = gsrpc_read_uint32(&reader);
+
+    callReturn->exception = gsrpc_read_int8(&reader);
+
+    callReturn->length = gsrpc_read_uint16(&reader);
+
+    callReturn->content = buff + reader.offset;
+
+    gsrpc_skip_bytes(&reader, callReturn->length);
+
+    callReturn->trace = gsrpc_read_uint64(&reader);
+
+    return reader.err;
+}
+
+size_t gsrpc_write_response(gsrpc_writer *writer, const gsrpc_call *call, int8_t exception) {
+    gsrpc_write_uint32(writer, call->id);
+
+    gsrpc_write_int8(writer, exception);
+
+    return gsrpc_write_begin(writer);
+}
+
+void gsrpc_write_response_end(gsrpc_writer *writer, const gsrpc_call *call, size_t offset) {
+    gsrpc_write_end(writer, offset);
+
+    gsrpc_write_uint64(writer, call->trace);
+}
+{{end}}
+`
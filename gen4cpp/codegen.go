@@ -0,0 +1,886 @@
+package gen4cpp
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"unicode"
+
+	"github.com/gsdocker/gserrors"
+	"github.com/gsdocker/gslogger"
+	"github.com/gsrpc/gslang"
+	"github.com/gsrpc/gslang/ast"
+	"github.com/gsrpc/gslang/lexer"
+	"github.com/gsrpc/gsrpc/gen"
+	"github.com/gsrpc/gsrpc/wire"
+)
+
+var builtin = map[lexer.TokenType]string{
+	lexer.KeySByte:   "int8_t",
+	lexer.KeyByte:    "uint8_t",
+	lexer.KeyInt16:   "int16_t",
+	lexer.KeyUInt16:  "uint16_t",
+	lexer.KeyInt32:   "int32_t",
+	lexer.KeyUInt32:  "uint32_t",
+	lexer.KeyInt64:   "int64_t",
+	lexer.KeyUInt64:  "uint64_t",
+	lexer.KeyFloat32: "float",
+	lexer.KeyFloat64: "double",
+	lexer.KeyBool:    "bool",
+	lexer.KeyString:  "std::string",
+	lexer.KeyVoid:    "void",
+}
+
+var readMapping = map[lexer.TokenType]string{
+	lexer.KeySByte:   "reader.readInt8()",
+	lexer.KeyByte:    "reader.readUInt8()",
+	lexer.KeyInt16:   "reader.readInt16()",
+	lexer.KeyUInt16:  "reader.readUInt16()",
+	lexer.KeyInt32:   "reader.readInt32()",
+	lexer.KeyUInt32:  "reader.readUInt32()",
+	lexer.KeyInt64:   "reader.readInt64()",
+	lexer.KeyUInt64:  "reader.readUInt64()",
+	lexer.KeyFloat32: "reader.readFloat32()",
+	lexer.KeyFloat64: "reader.readFloat64()",
+	lexer.KeyBool:    "reader.readBool()",
+	lexer.KeyString:  "reader.readString()",
+}
+
+var writeMapping = map[lexer.TokenType]string{
+	lexer.KeySByte:   "writer.writeInt8",
+	lexer.KeyByte:    "writer.writeUInt8",
+	lexer.KeyInt16:   "writer.writeInt16",
+	lexer.KeyUInt16:  "writer.writeUInt16",
+	lexer.KeyInt32:   "writer.writeInt32",
+	lexer.KeyUInt32:  "writer.writeUInt32",
+	lexer.KeyInt64:   "writer.writeInt64",
+	lexer.KeyUInt64:  "writer.writeUInt64",
+	lexer.KeyFloat32: "writer.writeFloat32",
+	lexer.KeyFloat64: "writer.writeFloat64",
+	lexer.KeyBool:    "writer.writeBool",
+	lexer.KeyString:  "writer.writeString",
+}
+
+// keywords the c++ keywords, which are suffixed with underscore when used as identifiers
+var keywords = map[string]bool{
+	"alignas": true, "alignof": true, "and": true, "asm": true, "auto": true, "bool": true, "break": true,
+	"case": true, "catch": true, "char": true, "class": true, "const": true, "constexpr": true, "continue": true,
+	"decltype": true, "default": true, "delete": true, "do": true, "double": true, "else": true, "enum": true,
+	"explicit": true, "export": true, "extern": true, "false": true, "float": true, "for": true, "friend": true,
+	"goto": true, "if": true, "inline": true, "int": true, "long": true, "mutable": true, "namespace": true,
+	"new": true, "noexcept": true, "not": true, "nullptr": true, "operator": true, "or": true, "private": true,
+	"protected": true, "public": true, "register": true, "return": true, "short": true, "signed": true,
+	"sizeof": true, "static": true, "struct": true, "switch": true, "template": true, "this": true,
+	"throw": true, "true": true, "try": true, "typedef": true, "typeid": true, "typename": true, "union": true,
+	"unsigned": true, "using": true, "virtual": true, "void": true, "volatile": true, "while": true, "xor": true,
+	"read": true, "write": true,
+}
+
+// runtimeFiles the runtime files generated in the com/gsrpc directory
+var runtimeFiles = []string{"stream.h", "stream.cpp", "channel.h", "channel.cpp"}
+
+// _Decl the type declaration of header, which is sorted by the dependencies in the same script
+type _Decl struct {
+	name    string   // type name
+	deps    []string // the same script types used by the declaration
+	content []byte   // header content
+}
+
+type _CodeGen struct {
+	gslogger.Log                    // Log APIs
+	rootpath     string             // root path
+	files        map[string][]byte  // generated files sink, the files are written into rootpath if nil
+	script       *ast.Script        // current script
+	decls        []*_Decl           // current script header declarations
+	header       bytes.Buffer       // current script contract header content
+	source       bytes.Buffer       // current script source content
+	includes     map[string]bool    // current script header includes
+	deps         map[string]bool    // current declaration dependencies
+	tpl          *template.Template // code generate template
+	skips        []*regexp.Regexp   // skip lists
+	compiler     *gslang.Compiler   // current compiler
+	runtime      bool               // runtime files are generated
+}
+
+// NewCodeGen .
+func NewCodeGen(rootpath string, skips []string) (gslang.Visitor, error) {
+
+	codeGen := &_CodeGen{
+		Log:      gslogger.Get("gen4cpp"),
+		rootpath: rootpath,
+	}
+
+	for _, skip := range skips {
+		exp, err := regexp.Compile(skip)
+
+		if err != nil {
+			return nil, gserrors.Newf(err, "invalid skip regex string :%s", skip)
+		}
+
+		codeGen.skips = append(codeGen.skips, exp)
+	}
+
+	funcs := template.FuncMap{
+		"title":        strings.Title,
+		"tableName":    gen.TableName,
+		"memberName":   memberName,
+		"setterName":   setterName,
+		"doc":          doc,
+		"notVoid":      gslang.NotVoid,
+		"isPOD":        gslang.IsPOD,
+		"isAsync":      gslang.IsAsync,
+		"isException":  gslang.IsException,
+		"isStrict":     gen.IsStrict,
+		"enumType":     enumType,
+		"isOptional":   gen.IsOptional,
+		"isOneOf":      gen.IsOneOf,
+		"variant":      gen.Variant,
+		"typeName":     codeGen.typeName,
+		"fieldType":    codeGen.fieldType,
+		"paramType":    codeGen.paramType,
+		"readType":     codeGen.readType,
+		"writeType":    codeGen.writeType,
+		"tagValue":     codeGen.tagValue,
+		"wireCases":    wire.Cases,
+		"wireNested":   wire.Nested,
+		"rpcName":      codeGen.rpcName,
+		"fieldDefault": codeGen.fieldDefault,
+		"params":       codeGen.params,
+		"callArgs":     callArgs,
+		"fullName":     fullName,
+	}
+
+	tpl, err := template.New("t4cpp").Funcs(funcs).Parse(t4cpp)
+
+	if err != nil {
+		return nil, err
+	}
+
+	codeGen.tpl = tpl
+
+	return codeGen, nil
+}
+
+// NewCodeGenWithFiles create codegen which write generated files into files map keyed by slash separated relative path
+func NewCodeGenWithFiles(files map[string][]byte, skips []string) (gslang.Visitor, error) {
+
+	codeGen, err := NewCodeGen("", skips)
+
+	if err != nil {
+		return nil, err
+	}
+
+	codeGen.(*_CodeGen).files = files
+
+	return codeGen, nil
+}
+
+// lowerName get the lower camel case name, the leading acronym is lowered as a whole, e.g. OSVersion -> osVersion
+func lowerName(name string) string {
+
+	runes := []rune(name)
+
+	upper := 0
+
+	for upper < len(runes) && unicode.IsUpper(runes[upper]) {
+		upper++
+	}
+
+	if upper > 1 && upper < len(runes) && unicode.IsLower(runes[upper]) {
+		upper--
+	}
+
+	for i := 0; i < upper; i++ {
+		runes[i] = unicode.ToLower(runes[i])
+	}
+
+	return string(runes)
+}
+
+// memberName get the field/method/param name, c++ keyword and the generated method names are suffixed with underscore
+func memberName(name string) string {
+
+	name = lowerName(name)
+
+	if keywords[name] {
+		return name + "_"
+	}
+
+	return name
+}
+
+// setterName get the union variant setter name
+func setterName(name string) string {
+	return "set" + strings.Title(name)
+}
+
+// enumType get the enum underlying type, the @Flag enum is uint32_t
+func enumType(enum *ast.Enum) string {
+	if gslang.EnumSize(enum) == 4 {
+		return "uint32_t"
+	}
+
+	return "uint8_t"
+}
+
+// namespace get the c++ namespace of package
+func namespace(packageName string) string {
+	return strings.Replace(packageName, ".", "::", -1)
+}
+
+// fullName get the full qualified name of symbol
+func fullName(typeDecl ast.Type) string {
+	return typeDecl.FullName()
+}
+
+// qualify get the symbol reference name, symbols of other packages are full qualified from global namespace
+func (codegen *_CodeGen) qualify(packageName string, name string) string {
+
+	if packageName == codegen.script.Package {
+		return name
+	}
+
+	return "::" + namespace(packageName) + "::" + name
+}
+
+// rpcName get the reference name of com.gsrpc package symbol, which includes the runtime symbols
+func (codegen *_CodeGen) rpcName(name string) string {
+	return codegen.qualify("com.gsrpc", name)
+}
+
+func (codegen *_CodeGen) tagValue(typeDecl ast.Type) string {
+	return strings.Join(codegen.tags(typeDecl), ", ")
+}
+
+// tags get the type tag sequence by the shared tag scheme
+func (codegen *_CodeGen) tags(typeDecl ast.Type) []string {
+
+	tag := codegen.rpcName("Tag")
+
+	tags := wire.Format(wire.Tags(typeDecl), func(t wire.Tag) string {
+		return tag + "::" + t.String()
+	})
+
+	// the POD field count is formatted as number, which is converted to Tag explicitly
+	for i, name := range tags {
+		if !strings.HasPrefix(name, tag) {
+			tags[i] = fmt.Sprintf("static_cast<%s>(%s)", tag, name)
+		}
+	}
+
+	return tags
+}
+
+// declName get the declared type name, the header of other script is included and the same script type is recorded as dependency
+func (codegen *_CodeGen) declName(typeDecl ast.Type) string {
+
+	if typeDecl.Script() != codegen.script.Name() {
+		codegen.includes[headerPath(typeDecl.Package(), typeDecl.Script())] = true
+	} else if codegen.deps != nil {
+		codegen.deps[typeDecl.FullName()] = true
+	}
+
+	if _, ok := typeDecl.(*ast.Enum); ok {
+		return codegen.qualify(typeDecl.Package(), strings.Title(typeDecl.Name()))
+	}
+
+	return codegen.qualify(typeDecl.Package(), gen.TableName(typeDecl))
+}
+
+func (codegen *_CodeGen) typeName(typeDecl ast.Type) string {
+	switch typeDecl.(type) {
+	case *ast.BuiltinType:
+		builtinType := typeDecl.(*ast.BuiltinType)
+
+		return builtin[builtinType.Type]
+	case *ast.TypeRef:
+		return codegen.typeName(typeDecl.(*ast.TypeRef).Ref)
+
+	case *ast.Enum, *ast.Table:
+		return codegen.declName(typeDecl)
+
+	case *ast.Seq:
+		seq := typeDecl.(*ast.Seq)
+
+		if entry, ok := wire.MapEntry(seq); ok {
+			return fmt.Sprintf("std::map<%s, %s>", codegen.typeName(entry.Fields[0].Type), codegen.typeName(entry.Fields[1].Type))
+		}
+
+		if seq.Size != -1 {
+			return fmt.Sprintf("std::array<%s, %d>", codegen.typeName(seq.Component), seq.Size)
+		}
+
+		return fmt.Sprintf("std::vector<%s>", codegen.typeName(seq.Component))
+	}
+
+	gserrors.Panicf(nil, "typeName  error: unsupport type(%s)", typeDecl)
+
+	return "unknown"
+}
+
+// fieldType get the field type name, optional field is std::optional except the union which has the none variant
+func (codegen *_CodeGen) fieldType(field *ast.Field) string {
+
+	if gen.IsOptional(field) && !gen.IsOneOf(field.Type) {
+		return fmt.Sprintf("std::optional<%s>", codegen.typeName(field.Type))
+	}
+
+	return codegen.typeName(field.Type)
+}
+
+// paramType get the method param type, the scalar types are passed by value and others by const reference
+func (codegen *_CodeGen) paramType(typeDecl ast.Type) string {
+
+	if typeRef, ok := typeDecl.(*ast.TypeRef); ok {
+		return codegen.paramType(typeRef.Ref)
+	}
+
+	switch typeDecl.(type) {
+	case *ast.Enum:
+		return codegen.typeName(typeDecl)
+	case *ast.BuiltinType:
+		if typeDecl.(*ast.BuiltinType).Type != lexer.KeyString {
+			return codegen.typeName(typeDecl)
+		}
+	}
+
+	return fmt.Sprintf("const %s&", codegen.typeName(typeDecl))
+}
+
+// defaultVal get the field brace initializer, the empty initializer value-initialize the field
+func (codegen *_CodeGen) defaultVal(typeDecl ast.Type) string {
+	switch typeDecl.(type) {
+	case *ast.TypeRef:
+		return codegen.defaultVal(typeDecl.(*ast.TypeRef).Ref)
+
+	case *ast.Enum:
+		enum := typeDecl.(*ast.Enum)
+
+		return codegen.declName(enum) + "::" + strings.Title(enum.Constants[0].Name())
+	}
+
+	return ""
+}
+
+// fieldDefault get the field brace initializer value, optional field without default value is std::nullopt
+func (codegen *_CodeGen) fieldDefault(field *ast.Field) string {
+
+	expr, ok := gen.Default(field)
+
+	if !ok {
+		if gen.IsOptional(field) {
+			return ""
+		}
+
+		return codegen.defaultVal(field.Type)
+	}
+
+	start, _ := gslang.Pos(field)
+
+	return codegen.defaultExpr(expr, field.Type, start)
+}
+
+func (codegen *_CodeGen) defaultExpr(expr ast.Expr, typeDecl ast.Type, start lexer.Position) string {
+
+	eval := codegen.compiler.Eval()
+
+	switch typeDecl.(type) {
+	case *ast.BuiltinType:
+		builtinType := typeDecl.(*ast.BuiltinType)
+
+		gen.CheckUnsigned(eval, builtinType, expr, start)
+
+		switch builtinType.Type {
+		case lexer.KeyString:
+			return quote(eval.EvalString(expr))
+		case lexer.KeyBool:
+			return strconv.FormatBool(eval.EvalBool(expr))
+		case lexer.KeyFloat32, lexer.KeyFloat64:
+			val := strconv.FormatFloat(eval.EvalFloat(expr), 'g', -1, 64)
+
+			if !strings.ContainsAny(val, ".eIN") {
+				val += ".0"
+			}
+
+			if builtinType.Type == lexer.KeyFloat32 {
+				val += "f"
+			}
+
+			return val
+		case lexer.KeyUInt64:
+			return fmt.Sprintf("%dull", eval.EvalInt(expr))
+		case lexer.KeyInt64:
+			return fmt.Sprintf("%dll", eval.EvalInt(expr))
+		case lexer.KeyVoid:
+		default:
+			return fmt.Sprintf("%d", eval.EvalInt(expr))
+		}
+
+	case *ast.TypeRef:
+		return codegen.defaultExpr(expr, typeDecl.(*ast.TypeRef).Ref, start)
+
+	case *ast.Enum:
+		enum := typeDecl.(*ast.Enum)
+
+		val := eval.EvalInt(expr)
+
+		if constant, ok := gen.Constant(enum, val); ok {
+			return codegen.declName(enum) + "::" + strings.Title(constant.Name())
+		}
+
+		gserrors.Panicf(nil, "enum %s constant(%d) not found :%v", enum, val, start)
+
+	case *ast.Table:
+		table := typeDecl.(*ast.Table)
+
+		newObj, ok := expr.(*ast.NewObj)
+
+		if !ok || gen.IsOneOf(table) || gslang.IsException(table) {
+			break
+		}
+
+		// the aggregate initializer list all the fields, the fields without arg keep their default values
+		var fields []string
+
+		for i, field := range table.Fields {
+
+			arg, ok := gen.FieldArg(newObj, i, field)
+
+			val := ""
+
+			if ok {
+				val = codegen.defaultExpr(arg, field.Type, start)
+			} else {
+				val = codegen.fieldDefault(field)
+			}
+
+			if val == "" {
+				val = codegen.fieldType(field) + "{}"
+			}
+
+			fields = append(fields, val)
+		}
+
+		return fmt.Sprintf("%s{%s}", codegen.declName(table), strings.Join(fields, ", "))
+	}
+
+	gserrors.Panicf(nil, "unsupport default value for type(%s) :%v", typeDecl, start)
+
+	return "unknown"
+}
+
+// quote get the c++ string literal
+func quote(val string) string {
+
+	var buff bytes.Buffer
+
+	buff.WriteString("\"")
+
+	for _, b := range []byte(val) {
+		switch {
+		case b == '"':
+			buff.WriteString("\\\"")
+		case b == '\\':
+			buff.WriteString("\\\\")
+		case b == '\n':
+			buff.WriteString("\\n")
+		case b == '\r':
+			buff.WriteString("\\r")
+		case b == '\t':
+			buff.WriteString("\\t")
+		case b < 0x20 || b >= 0x7f:
+			// the octal escape is not extended by the following digits like the hex escape
+			buff.WriteString(fmt.Sprintf("\\%03o", b))
+		default:
+			buff.WriteByte(b)
+		}
+	}
+
+	buff.WriteString("\"")
+
+	return buff.String()
+}
+
+// readType get the expr which read the type value from the reader variable
+func (codegen *_CodeGen) readType(typeDecl ast.Type) string {
+	switch typeDecl.(type) {
+	case *ast.BuiltinType:
+		builtinType := typeDecl.(*ast.BuiltinType)
+
+		return readMapping[builtinType.Type]
+	case *ast.TypeRef:
+		return codegen.readType(typeDecl.(*ast.TypeRef).Ref)
+
+	case *ast.Enum:
+		return fmt.Sprintf("%s(reader)", codegen.qualify(typeDecl.Package(), "read"+strings.Title(typeDecl.Name())))
+
+	case *ast.Table:
+		return fmt.Sprintf("%s::read(reader)", codegen.declName(typeDecl))
+
+	case *ast.Seq:
+		seq := typeDecl.(*ast.Seq)
+
+		if gen.IsBytes(seq) {
+			if seq.Size != -1 {
+				return fmt.Sprintf("reader.readFixedBytes<%d>()", seq.Size)
+			}
+
+			return "reader.readBytes()"
+		}
+
+		if entry, ok := wire.MapEntry(seq); ok {
+			return fmt.Sprintf("reader.readMap<%s, %s>([&] { return %s; }, [&] { return %s; })",
+				codegen.typeName(entry.Fields[0].Type), codegen.typeName(entry.Fields[1].Type),
+				codegen.readType(entry.Fields[0].Type), codegen.readType(entry.Fields[1].Type))
+		}
+
+		if seq.Size != -1 {
+			return fmt.Sprintf("reader.readArray<%s, %d>([&] { return %s; })", codegen.typeName(seq.Component), seq.Size, codegen.readType(seq.Component))
+		}
+
+		return fmt.Sprintf("reader.readList<%s>([&] { return %s; })", codegen.typeName(seq.Component), codegen.readType(seq.Component))
+	}
+
+	gserrors.Panicf(nil, "readType  error: unsupport type(%s)", typeDecl)
+
+	return "unknown"
+}
+
+// writeType get the statement which write the valname to the writer variable, depth is used to name the lambda args
+func (codegen *_CodeGen) writeType(typeDecl ast.Type, valname string, depth int) string {
+	switch typeDecl.(type) {
+	case *ast.BuiltinType:
+		builtinType := typeDecl.(*ast.BuiltinType)
+
+		return fmt.Sprintf("%s(%s)", writeMapping[builtinType.Type], valname)
+	case *ast.TypeRef:
+		return codegen.writeType(typeDecl.(*ast.TypeRef).Ref, valname, depth)
+
+	case *ast.Enum:
+		return fmt.Sprintf("%s(writer, %s)", codegen.qualify(typeDecl.Package(), "write"+strings.Title(typeDecl.Name())), valname)
+
+	case *ast.Table:
+		return fmt.Sprintf("%s.write(writer)", valname)
+
+	case *ast.Seq:
+		seq := typeDecl.(*ast.Seq)
+
+		if gen.IsBytes(seq) {
+			return fmt.Sprintf("writer.writeBytes(%s)", valname)
+		}
+
+		key, val := fmt.Sprintf("k%d", depth), fmt.Sprintf("v%d", depth)
+
+		if entry, ok := wire.MapEntry(seq); ok {
+			return fmt.Sprintf("writer.writeMap(%s, [&](const auto& %s) { %s; }, [&](const auto& %s) { %s; })", valname,
+				key, codegen.writeType(entry.Fields[0].Type, key, depth+1),
+				val, codegen.writeType(entry.Fields[1].Type, val, depth+1))
+		}
+
+		return fmt.Sprintf("writer.writeList(%s, [&](const auto& %s) { %s; })", valname, val, codegen.writeType(seq.Component, val, depth+1))
+	}
+
+	gserrors.Panicf(nil, "writeType  error: unsupport type(%s)", typeDecl)
+
+	return "unknown"
+}
+
+func (codegen *_CodeGen) params(params []*ast.Param) string {
+
+	var args []string
+
+	for _, param := range params {
+		args = append(args, fmt.Sprintf("%s %s", codegen.paramType(param.Type), memberName(param.Name())))
+	}
+
+	return "(" + strings.Join(args, ", ") + ")"
+}
+
+func callArgs(params []*ast.Param) string {
+
+	var args []string
+
+	for _, param := range params {
+		args = append(args, fmt.Sprintf("arg%d", param.ID))
+	}
+
+	return "(" + strings.Join(args, ", ") + ")"
+}
+
+// headerPath get the include path of script header
+func headerPath(packageName string, script string) string {
+	return path.Join(strings.Replace(packageName, ".", "/", -1), filepath.Base(script)+".h")
+}
+
+func (codegen *_CodeGen) execute(stream *bytes.Buffer, name string, data interface{}) {
+	if err := codegen.tpl.ExecuteTemplate(stream, name, data); err != nil {
+		gserrors.Panicf(err, "exec template(%s) for %s error", name, data)
+	}
+}
+
+// declare execute the header template and record the same script types used by the declaration
+func (codegen *_CodeGen) declare(name string, typeDecl ast.Type) {
+
+	codegen.deps = make(map[string]bool)
+
+	var content bytes.Buffer
+
+	codegen.execute(&content, name, typeDecl)
+
+	decl := &_Decl{name: typeDecl.FullName(), content: content.Bytes()}
+
+	for dep := range codegen.deps {
+		if dep != decl.name {
+			decl.deps = append(decl.deps, dep)
+		}
+	}
+
+	sort.Strings(decl.deps)
+
+	codegen.decls = append(codegen.decls, decl)
+
+	codegen.deps = nil
+}
+
+// sortDecls sort the declarations so that the types are defined before used by value, the declared order is kept otherwise
+func (codegen *_CodeGen) sortDecls() []*_Decl {
+
+	decls := make(map[string]*_Decl)
+
+	for _, decl := range codegen.decls {
+		decls[decl.name] = decl
+	}
+
+	var sorted []*_Decl
+
+	visited := make(map[string]bool)
+
+	var visit func(decl *_Decl)
+
+	visit = func(decl *_Decl) {
+
+		if visited[decl.name] {
+			return
+		}
+
+		visited[decl.name] = true
+
+		for _, dep := range decl.deps {
+			if depDecl, ok := decls[dep]; ok {
+				visit(depDecl)
+			}
+		}
+
+		sorted = append(sorted, decl)
+	}
+
+	for _, decl := range codegen.decls {
+		visit(decl)
+	}
+
+	return sorted
+}
+
+func (codegen *_CodeGen) writeFile(name string, content []byte) {
+
+	// the content buffer may be reused by caller
+	if codegen.files != nil {
+		codegen.files[filepath.ToSlash(name)] = append([]byte(nil), content...)
+		return
+	}
+
+	fullpath := filepath.Join(codegen.rootpath, name)
+
+	if err := os.MkdirAll(filepath.Dir(fullpath), 0755); err != nil {
+		gserrors.Panicf(err, "create output directory error")
+	}
+
+	codegen.D("write file :%s", fullpath)
+
+	if err := ioutil.WriteFile(fullpath, content, 0644); err != nil {
+		gserrors.Panicf(err, "write generate stub code error")
+	}
+}
+
+func (codegen *_CodeGen) BeginScript(compiler *gslang.Compiler, script *ast.Script) bool {
+
+	scriptPath := filepath.ToSlash(filepath.Clean(script.Name()))
+
+	for _, skip := range codegen.skips {
+
+		if skip.MatchString(scriptPath) {
+
+			return false
+		}
+	}
+
+	if strings.HasPrefix(script.Package, "gslang.") {
+		return false
+	}
+
+	codegen.script = script
+
+	codegen.compiler = compiler
+
+	codegen.decls = nil
+
+	codegen.header.Reset()
+
+	codegen.source.Reset()
+
+	codegen.includes = map[string]bool{
+		"com/gsrpc/stream.h": true,
+	}
+
+	return true
+}
+
+func (codegen *_CodeGen) Using(compiler *gslang.Compiler, using *ast.Using) {
+}
+
+func (codegen *_CodeGen) Table(compiler *gslang.Compiler, tableType *ast.Table) {
+
+	prefix := "table"
+
+	if gen.IsOneOf(tableType) {
+		prefix = "union"
+	}
+
+	codegen.declare(prefix+"_header", tableType)
+
+	codegen.execute(&codegen.source, prefix+"_source", tableType)
+}
+
+func (codegen *_CodeGen) Annotation(compiler *gslang.Compiler, annotation *ast.Table) {
+}
+
+func (codegen *_CodeGen) Enum(compiler *gslang.Compiler, enum *ast.Enum) {
+
+	codegen.declare("enum_header", enum)
+
+	codegen.execute(&codegen.source, "enum_source", enum)
+}
+
+func (codegen *_CodeGen) Contract(compiler *gslang.Compiler, contract *ast.Contract) {
+
+	codegen.includes["com/gsrpc/channel.h"] = true
+
+	codegen.execute(&codegen.header, "contract_header", contract)
+
+	codegen.execute(&codegen.source, "contract_source", contract)
+}
+
+// EndScript write the script header/source pair into the package directory
+func (codegen *_CodeGen) EndScript(compiler *gslang.Compiler) {
+
+	var stream bytes.Buffer
+
+	header := headerPath(codegen.script.Package, codegen.script.Name())
+
+	guard := strings.ToUpper(regexp.MustCompile(`[^A-Za-z0-9]`).ReplaceAllString(header, "_"))
+
+	stream.WriteString("// generate by gs2cpp,don't modify it manually\n")
+
+	stream.WriteString(fmt.Sprintf("#ifndef %s\n#define %s\n\n", guard, guard))
+
+	var includes []string
+
+	for include := range codegen.includes {
+		if include != header {
+			includes = append(includes, include)
+		}
+	}
+
+	sort.Strings(includes)
+
+	for _, include := range includes {
+		stream.WriteString(fmt.Sprintf("#include <%s>\n", include))
+	}
+
+	stream.WriteString(fmt.Sprintf("\nnamespace %s {\n", namespace(codegen.script.Package)))
+
+	for _, decl := range codegen.sortDecls() {
+		stream.Write(decl.content)
+	}
+
+	stream.Write(codegen.header.Bytes())
+
+	stream.WriteString(fmt.Sprintf("\n} // namespace %s\n\n#endif // %s\n", namespace(codegen.script.Package), guard))
+
+	codegen.writeFile(header, spaceNamespace(tidy.Clean(stream.Bytes()), codegen.script.Package))
+
+	stream.Reset()
+
+	stream.WriteString("// generate by gs2cpp,don't modify it manually\n")
+
+	stream.WriteString(fmt.Sprintf("#include <%s>\n", header))
+
+	if header != "com/gsrpc/gsrpc.gs.h" {
+		stream.WriteString("#include <com/gsrpc/gsrpc.gs.h>\n")
+	}
+
+	stream.WriteString(fmt.Sprintf("\nnamespace %s {\n", namespace(codegen.script.Package)))
+
+	stream.Write(codegen.source.Bytes())
+
+	stream.WriteString(fmt.Sprintf("\n} // namespace %s\n", namespace(codegen.script.Package)))
+
+	codegen.writeFile(strings.TrimSuffix(header, ".h")+".cpp", spaceNamespace(tidy.Clean(stream.Bytes()), codegen.script.Package))
+
+	if codegen.runtime {
+		return
+	}
+
+	for _, name := range runtimeFiles {
+
+		stream.Reset()
+
+		if err := codegen.tpl.ExecuteTemplate(&stream, name, nil); err != nil {
+			gserrors.Panicf(err, "exec template(%s) error", name)
+		}
+
+		codegen.writeFile(path.Join("com/gsrpc", name), stream.Bytes())
+	}
+
+	codegen.runtime = true
+}
+
+var tidy = gen.NewTidy(1, `[{(]`, `[})]`)
+
+// spaceNamespace restore the blank lines around the namespace body, which are removed by tidy
+func spaceNamespace(content []byte, packageName string) []byte {
+
+	begin := fmt.Sprintf("namespace %s {\n", namespace(packageName))
+
+	end := fmt.Sprintf("} // namespace %s\n", namespace(packageName))
+
+	content = bytes.Replace(content, []byte(begin), []byte(begin+"\n"), 1)
+
+	return bytes.Replace(content, []byte(end), []byte("\n"+end), 1)
+}
+
+// doc get the c++ comment from the .gs comments of node
+func doc(indent string, node ast.Node) string {
+
+	comments := gslang.Comments(node)
+
+	if len(comments) == 0 {
+		return ""
+	}
+
+	var buff bytes.Buffer
+
+	for _, line := range comments {
+		buff.WriteString(strings.TrimRight(indent+"// "+strings.TrimSpace(line), " ") + "\n")
+	}
+
+	return buff.String()
+}
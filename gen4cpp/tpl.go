@@ -0,0 +1,1168 @@
+package gen4cpp
+
+var t4cpp = `
+{{define "enum_header"}}{{$Enum := title .Name}}
+{{doc "" .}}enum class {{$Enum}} : {{enumType .}} {
+{{range .Constants}}{{doc "    " .}}    {{title .Name}} = {{.Value}},
+{{end}}};
+{{if eq (enumType .) "uint32_t"}}
+inline {{$Enum}} operator|({{$Enum}} lhs, {{$Enum}} rhs) {
+    return static_cast<{{$Enum}}>(static_cast<uint32_t>(lhs) | static_cast<uint32_t>(rhs));
+}
+
+inline {{$Enum}} operator&({{$Enum}} lhs, {{$Enum}} rhs) {
+    return static_cast<{{$Enum}}>(static_cast<uint32_t>(lhs) & static_cast<uint32_t>(rhs));
+}
+{{end}}
+{{$Enum}} read{{$Enum}}({{rpcName "Reader"}}& reader);
+
+void write{{$Enum}}({{rpcName "Writer"}}& writer, {{$Enum}} val);
+{{end}}
+
+{{define "enum_source"}}{{$Enum := title .Name}}{{$Width := "UInt8"}}{{if eq (enumType .) "uint32_t"}}{{$Width = "UInt32"}}{{end}}
+{{$Enum}} read{{$Enum}}({{rpcName "Reader"}}& reader) {
+    auto val = reader.read{{$Width}}();
+{{if isStrict .}}
+    switch (val) {
+{{range .Constants}}    case {{.Value}}:
+{{end}}        return static_cast<{{$Enum}}>(val);
+    default:
+        throw {{rpcName "CodecError"}}("gsrpc: unknown {{.FullName}} value " + std::to_string(val));
+    }
+{{else}}
+    return static_cast<{{$Enum}}>(val);
+{{end}}
+}
+
+void write{{$Enum}}({{rpcName "Writer"}}& writer, {{$Enum}} val) {
+    writer.write{{$Width}}(static_cast<{{enumType .}}>(val));
+}
+{{end}}
+
+{{define "table_header"}}{{$Table := tableName .}}
+{{doc "" .}}struct {{$Table}}{{if isException .}} : public std::exception{{end}} {
+{{range .Fields}}{{doc "    " .}}    {{fieldType .}} {{memberName .Name}}{{printf "{%s}" (fieldDefault .)}};
+{{end}}
+    bool operator==(const {{$Table}}& other) const;
+
+    bool operator!=(const {{$Table}}& other) const {
+        return !(*this == other);
+    }
+{{if isException .}}
+    const char* what() const noexcept override;
+{{end}}
+    void write({{rpcName "Writer"}}& writer) const;
+
+    static {{$Table}} read({{rpcName "Reader"}}& reader);
+};
+{{end}}
+
+{{define "table_source"}}{{$Table := tableName .}}
+bool {{$Table}}::operator==(const {{$Table}}&{{if .Fields}} other{{end}}) const {
+{{if .Fields}}    return std::tie({{range $index, $field := .Fields}}{{if $index}}, {{end}}{{memberName .Name}}{{end}}) ==
+           std::tie({{range $index, $field := .Fields}}{{if $index}}, {{end}}other.{{memberName .Name}}{{end}});
+{{else}}    return true;
+{{end}}}
+{{if isException .}}
+const char* {{$Table}}::what() const noexcept {
+    return "{{.FullName}}";
+}
+{{end}}
+{{if isPOD .}}void {{$Table}}::write({{rpcName "Writer"}}&{{if .Fields}} writer{{end}}) const {
+{{range .Fields}}{{$Name := memberName .Name}}
+{{if isOptional .}}{{if isOneOf .Type}}    writer.writeBool(!{{$Name}}.empty());
+
+    if (!{{$Name}}.empty()) {
+        {{writeType .Type $Name 0}};
+    }
+{{else}}    writer.writeBool({{$Name}}.has_value());
+
+    if ({{$Name}}.has_value()) {
+        {{writeType .Type (printf "%s.value()" $Name) 0}};
+    }
+{{end}}{{else}}    {{writeType .Type $Name 0}};
+{{end}}{{end}}}
+
+{{$Table}} {{$Table}}::read({{rpcName "Reader"}}&{{if .Fields}} reader{{end}}) {
+    {{$Table}} target;
+{{range .Fields}}
+{{if isOptional .}}    if (reader.readBool()) {
+        target.{{memberName .Name}} = {{readType .Type}};
+    }
+{{else}}    target.{{memberName .Name}} = {{readType .Type}};
+{{end}}{{end}}
+    return target;
+}
+{{else}}void {{$Table}}::write({{rpcName "Writer"}}& writer) const {
+    writer.writeUInt16({{len .Fields}});
+{{range .Fields}}{{$Name := memberName .Name}}
+{{if isOptional .}}{{if isOneOf .Type}}    if (!{{$Name}}.empty()) {
+        writer.writeTags({ {{tagValue .Type}} });
+        {{writeType .Type $Name 0}};
+{{else}}    if ({{$Name}}.has_value()) {
+        writer.writeTags({ {{tagValue .Type}} });
+        {{writeType .Type (printf "%s.value()" $Name) 0}};
+{{end}}    } else {
+        writer.writeTags({ {{rpcName "Tag"}}::Skip });
+    }
+{{else}}    writer.writeTags({ {{tagValue .Type}} });
+    {{writeType .Type $Name 0}};
+{{end}}{{end}}}
+
+{{$Table}} {{$Table}}::read({{rpcName "Reader"}}& reader) {
+    {{$Table}} target;
+
+    size_t fields = reader.readUInt16();
+{{range .Fields}}
+    if (fields == 0) {
+        return target;
+    }
+
+    if (reader.readTags()[0] != {{rpcName "Tag"}}::Skip) {
+        target.{{memberName .Name}} = {{readType .Type}};
+    }
+
+    fields--;
+{{end}}
+    reader.skipFields(fields);
+
+    return target;
+}
+{{end}}{{end}}
+
+{{define "union_header"}}{{$Union := tableName .}}
+{{doc "" .}}class {{$Union}} {
+public:
+    // Kind the variant kind, which is the variant index on the wire
+    enum class Kind : uint8_t {
+        None = 0,
+{{range $index, $field := .Fields}}        {{title .Name}} = {{variant $index}},
+{{end}}    };
+
+    Kind kind() const {
+        return static_cast<Kind>(value_.index());
+    }
+
+    bool empty() const {
+        return value_.index() == 0;
+    }
+
+    void reset() {
+        value_.emplace<0>();
+    }
+{{range $index, $field := .Fields}}
+{{doc "    " .}}    const {{typeName .Type}}* {{memberName .Name}}() const {
+        return std::get_if<{{variant $index}}>(&value_);
+    }
+
+    void {{setterName .Name}}({{typeName .Type}} value) {
+        value_.emplace<{{variant $index}}>(std::move(value));
+    }
+{{end}}
+    bool operator==(const {{$Union}}& other) const {
+        return value_ == other.value_;
+    }
+
+    bool operator!=(const {{$Union}}& other) const {
+        return !(*this == other);
+    }
+
+    void write({{rpcName "Writer"}}& writer) const;
+
+    static {{$Union}} read({{rpcName "Reader"}}& reader);
+
+private:
+    std::variant<std::monostate{{range .Fields}}, {{typeName .Type}}{{end}}> value_;
+};
+{{end}}
+
+{{define "union_source"}}{{$Union := tableName .}}
+void {{$Union}}::write({{rpcName "Writer"}}& writer) const {
+    writer.writeUInt8(static_cast<uint8_t>(value_.index()));
+
+    switch (value_.index()) {
+{{range $index, $field := .Fields}}    case {{variant $index}}:
+        writer.writeTags({ {{tagValue .Type}} });
+        {{writeType .Type (printf "std::get<%d>(value_)" (variant $index)) 0}};
+        break;
+{{end}}    }
+}
+
+// the unknown variant is skipped and read as empty union
+{{$Union}} {{$Union}}::read({{rpcName "Reader"}}& reader) {
+    {{$Union}} target;
+
+    auto variant = reader.readUInt8();
+
+    if (variant == 0) {
+        return target;
+    }
+
+    auto tags = reader.readTags();
+
+    switch (variant) {
+{{range $index, $field := .Fields}}    case {{variant $index}}:
+        target.value_.emplace<{{variant $index}}>({{readType .Type}});
+        break;
+{{end}}    default:
+        reader.skip(tags);
+        break;
+    }
+
+    return target;
+}
+{{end}}
+
+{{define "contract_header"}}{{$Contract := title .Name}}
+{{doc "" .}}class {{$Contract}} {
+public:
+    static constexpr const char* NAME = "{{.FullName}}";
+
+    virtual ~{{$Contract}}() = default;
+{{range .Methods}}
+{{doc "    " .}}    virtual {{typeName .Return}} {{memberName .Name}}{{params .Params}} = 0;
+{{end}}};
+
+// {{$Contract}}Dispatcher dispatch the remote calls to {{$Contract}} service
+class {{$Contract}}Dispatcher : public {{rpcName "Dispatcher"}} {
+public:
+    explicit {{$Contract}}Dispatcher(std::shared_ptr<{{$Contract}}> service) : service_(std::move(service)) {
+    }
+
+    std::optional<{{rpcName "Response"}}> dispatch(const {{rpcName "Request"}}& call) override;
+
+private:
+    std::shared_ptr<{{$Contract}}> service_;
+};
+
+// {{$Contract}}RPC the remote {{$Contract}} service proxy, the calls block until responded or timeout
+class {{$Contract}}RPC : public {{$Contract}} {
+public:
+    {{$Contract}}RPC(std::shared_ptr<{{rpcName "Channel"}}> channel, uint16_t serviceID)
+        : channel_(std::move(channel)), serviceID_(serviceID) {
+    }
+
+    // timeout the call timeout
+    std::chrono::milliseconds timeout{5000};
+{{range .Methods}}
+    {{typeName .Return}} {{memberName .Name}}{{params .Params}} override;
+{{end}}
+private:
+    std::shared_ptr<{{rpcName "Channel"}}> channel_;
+    uint16_t serviceID_;
+};
+{{end}}
+
+{{define "contract_source"}}{{$Contract := title .Name}}
+std::optional<{{rpcName "Response"}}> {{$Contract}}Dispatcher::dispatch(const {{rpcName "Request"}}& call) {
+    switch (call.method) {
+{{range .Methods}}{{$Method := .}}    case {{.ID}}: {
+        if (call.params.size() != {{.ParamsCount}}) {
+            throw {{rpcName "RPCError"}}("gsrpc: {{$Contract}}#{{title .Name}} expect {{.ParamsCount}} params but got " + std::to_string(call.params.size()));
+        }
+{{range .Params}}
+        auto arg{{.ID}} = {{rpcName "unmarshal"}}(call.params[{{.ID}}].content, []({{rpcName "Reader"}}& reader) {
+            return {{readType .Type}};
+        });
+{{end}}
+{{if isAsync .}}        service_->{{memberName .Name}}{{callArgs .Params}};
+
+        return std::nullopt;
+{{else if .Exceptions}}        try {
+{{if notVoid .Return}}            auto ret = service_->{{memberName .Name}}{{callArgs .Params}};
+
+            return {{rpcName "makeResponse"}}(call, -1, {{rpcName "marshal"}}([&]({{rpcName "Writer"}}& writer) {
+                {{writeType .Return "ret" 0}};
+            }));
+{{else}}            service_->{{memberName .Name}}{{callArgs .Params}};
+
+            return {{rpcName "makeResponse"}}(call, -1, {});
+{{end}}        }{{range .Exceptions}} catch (const {{typeName .Type}}& e) {
+            return {{rpcName "makeResponse"}}(call, {{.ID}}, {{rpcName "marshal"}}([&]({{rpcName "Writer"}}& writer) {
+                e.write(writer);
+            }));
+        }{{end}}
+{{else if notVoid .Return}}        auto ret = service_->{{memberName .Name}}{{callArgs .Params}};
+
+        return {{rpcName "makeResponse"}}(call, -1, {{rpcName "marshal"}}([&]({{rpcName "Writer"}}& writer) {
+            {{writeType .Return "ret" 0}};
+        }));
+{{else}}        service_->{{memberName .Name}}{{callArgs .Params}};
+
+        return {{rpcName "makeResponse"}}(call, -1, {});
+{{end}}    }
+{{end}}    default:
+        throw {{rpcName "RPCError"}}("gsrpc: unknown method {{$Contract}}#" + std::to_string(call.method));
+    }
+}
+{{range .Methods}}
+{{typeName .Return}} {{$Contract}}RPC::{{memberName .Name}}{{params .Params}} {
+    {{rpcName "Request"}} call;
+
+    call.service = serviceID_;
+
+    call.method = {{.ID}};
+{{range .Params}}
+    call.params.push_back({{rpcName "Param"}}{ {{rpcName "marshal"}}([&]({{rpcName "Writer"}}& writer) {
+        {{writeType .Type (memberName .Name) 0}};
+    }) });
+{{end}}
+{{if isAsync .}}    channel_->post(call);
+{{else}}    auto callReturn = channel_->call(std::move(call), timeout);
+
+    switch (callReturn.exception) {
+    case -1:
+{{if notVoid .Return}}        return {{rpcName "unmarshal"}}(callReturn.content, []({{rpcName "Reader"}}& reader) {
+            return {{readType .Return}};
+        });
+{{else}}        return;
+{{end}}    case -2:
+        throw {{rpcName "unmarshal"}}(callReturn.content, []({{rpcName "Reader"}}& reader) {
+            return {{rpcName "InvalidArgumentException"}}::read(reader);
+        });
+{{range .Exceptions}}    case {{.ID}}:
+        throw {{rpcName "unmarshal"}}(callReturn.content, []({{rpcName "Reader"}}& reader) {
+            return {{typeName .Type}}::read(reader);
+        });
+{{end}}    default:
+        throw {{rpcName "RPCError"}}("gsrpc: {{$Contract}}#{{title .Name}} unknown exception(" + std::to_string(callReturn.exception) + ")");
+    }
+{{end}}}
+{{end}}{{end}}
+
+{{define "stream.h"}}// generate by gs2cpp,don't modify it manually
+//
+// gsrpc runtime: the little endian Reader/Writer used by the generated read/write methods
+#ifndef COM_GSRPC_STREAM_H
+#define COM_GSRPC_STREAM_H
+
+#include <array>
+#include <cstddef>
+#include <cstdint>
+#include <exception>
+#include <initializer_list>
+#include <map>
+#include <optional>
+#include <stdexcept>
+#include <string>
+#include <tuple>
+#include <utility>
+#include <variant>
+#include <vector>
+
+namespace com::gsrpc {
+
+// Tag the tagged value type tag, which is defined in gsrpc.gs.h
+enum class Tag : uint8_t;
+
+// CodecError the error raised by read/write, including read beyond the end of input
+class CodecError : public std::runtime_error {
+public:
+    using std::runtime_error::runtime_error;
+};
+
+// Reader read values from the borrowed buffer, which must outlive the reader
+class Reader {
+public:
+    Reader(const uint8_t* data, size_t size) : data_(data), size_(size) {
+    }
+
+    explicit Reader(const std::vector<uint8_t>& content) : Reader(content.data(), content.size()) {
+    }
+
+    int8_t readInt8();
+    uint8_t readUInt8();
+    int16_t readInt16();
+    uint16_t readUInt16();
+    int32_t readInt32();
+    uint32_t readUInt32();
+    int64_t readInt64();
+    uint64_t readUInt64();
+    float readFloat32();
+    double readFloat64();
+    bool readBool();
+    std::string readString();
+    std::vector<uint8_t> readBytes();
+
+    // readFixedBytes read the byte[N], the length prefix must be N
+    template <size_t N>
+    std::array<uint8_t, N> readFixedBytes() {
+        std::array<uint8_t, N> bytes{};
+
+        readLength(N);
+
+        readRaw(bytes.data(), N);
+
+        return bytes;
+    }
+
+    template <typename T, typename F>
+    std::vector<T> readList(F read) {
+        size_t length = readUInt16();
+
+        std::vector<T> list;
+
+        list.reserve(length);
+
+        for (size_t i = 0; i < length; i++) {
+            list.push_back(read());
+        }
+
+        return list;
+    }
+
+    // readArray read the T[N], the length prefix must be N
+    template <typename T, size_t N, typename F>
+    std::array<T, N> readArray(F read) {
+        std::array<T, N> array{};
+
+        readLength(N);
+
+        for (auto& v : array) {
+            v = read();
+        }
+
+        return array;
+    }
+
+    template <typename K, typename V, typename FK, typename FV>
+    std::map<K, V> readMap(FK readKey, FV readValue) {
+        size_t length = readUInt16();
+
+        std::map<K, V> map;
+
+        for (size_t i = 0; i < length; i++) {
+            auto key = readKey();
+
+            map[std::move(key)] = readValue();
+        }
+
+        return map;
+    }
+
+    // readTags read the tag sequence of tagged value, the nested container and POD tags are followed by their component tags
+    std::vector<Tag> readTags();
+
+    // skip skip the value described by the tag sequence
+    void skip(const std::vector<Tag>& tags);
+
+    // skipFields skip the unknown fields of tagged table
+    void skipFields(size_t fields);
+
+    void readRaw(void* buff, size_t length);
+
+    size_t remaining() const {
+        return size_ - offset_;
+    }
+
+private:
+    void readLength(size_t expect);
+    void readTags(std::vector<Tag>& tags);
+    void skip(const std::vector<Tag>& tags, size_t index);
+    void skipComponents(const std::vector<Tag>& tags, size_t index, size_t count);
+    void skipBytes(size_t length);
+
+    const uint8_t* data_;
+    size_t size_;
+    size_t offset_ = 0;
+};
+
+// Writer write values into the growing buffer
+class Writer {
+public:
+    void writeInt8(int8_t val);
+    void writeUInt8(uint8_t val);
+    void writeInt16(int16_t val);
+    void writeUInt16(uint16_t val);
+    void writeInt32(int32_t val);
+    void writeUInt32(uint32_t val);
+    void writeInt64(int64_t val);
+    void writeUInt64(uint64_t val);
+    void writeFloat32(float val);
+    void writeFloat64(double val);
+    void writeBool(bool val);
+    void writeString(const std::string& val);
+
+    // writeBytes write the byte[] or byte[N]
+    template <typename C>
+    void writeBytes(const C& bytes) {
+        writeLength(bytes.size());
+
+        writeRaw(bytes.data(), bytes.size());
+    }
+
+    // writeList write the T[] or T[N]
+    template <typename C, typename F>
+    void writeList(const C& list, F write) {
+        writeLength(list.size());
+
+        for (const auto& v : list) {
+            write(v);
+        }
+    }
+
+    // writeMap write the map entries in key order, so the same map is always encoded as same bytes
+    template <typename M, typename FK, typename FV>
+    void writeMap(const M& map, FK writeKey, FV writeValue) {
+        writeLength(map.size());
+
+        for (const auto& entry : map) {
+            writeKey(entry.first);
+            writeValue(entry.second);
+        }
+    }
+
+    void writeTags(std::initializer_list<Tag> tags);
+
+    // writeLength write the length prefix of string, bytes or list, CodecError is raised if out of uint16 range
+    void writeLength(size_t length);
+
+    void writeRaw(const void* buff, size_t length);
+
+    const std::vector<uint8_t>& content() const {
+        return content_;
+    }
+
+    std::vector<uint8_t> take() {
+        return std::move(content_);
+    }
+
+private:
+    std::vector<uint8_t> content_;
+};
+
+// marshal encode value into bytes by the write function
+template <typename F>
+std::vector<uint8_t> marshal(F write) {
+    Writer writer;
+
+    write(writer);
+
+    return writer.take();
+}
+
+// unmarshal decode value from content by the read function
+template <typename F>
+auto unmarshal(const std::vector<uint8_t>& content, F read) {
+    Reader reader(content);
+
+    return read(reader);
+}
+
+} // namespace com::gsrpc
+
+#endif // COM_GSRPC_STREAM_H
+{{end}}
+
+{{define "stream.cpp"}}// generate by gs2cpp,don't modify it manually
+#include <com/gsrpc/stream.h>
+#include <com/gsrpc/gsrpc.gs.h>
+
+#include <cstring>
+
+namespace com::gsrpc {
+
+namespace {
+
+uint64_t decode(const uint8_t* buff, size_t length) {
+    uint64_t val = 0;
+
+    for (size_t i = 0; i < length; i++) {
+        val |= static_cast<uint64_t>(buff[i]) << (8 * i);
+    }
+
+    return val;
+}
+
+void encode(uint8_t* buff, size_t length, uint64_t val) {
+    for (size_t i = 0; i < length; i++) {
+        buff[i] = static_cast<uint8_t>(val >> (8 * i));
+    }
+}
+
+} // namespace
+
+void Reader::readRaw(void* buff, size_t length) {
+    if (length > remaining()) {
+        throw CodecError("gsrpc: read beyond the end of input");
+    }
+
+    if (length > 0) {
+        std::memcpy(buff, data_ + offset_, length);
+    }
+
+    offset_ += length;
+}
+
+int8_t Reader::readInt8() {
+    return static_cast<int8_t>(readUInt8());
+}
+
+uint8_t Reader::readUInt8() {
+    uint8_t buff[1];
+
+    readRaw(buff, sizeof(buff));
+
+    return buff[0];
+}
+
+int16_t Reader::readInt16() {
+    return static_cast<int16_t>(readUInt16());
+}
+
+uint16_t Reader::readUInt16() {
+    uint8_t buff[2];
+
+    readRaw(buff, sizeof(buff));
+
+    return static_cast<uint16_t>(decode(buff, sizeof(buff)));
+}
+
+int32_t Reader::readInt32() {
+    return static_cast<int32_t>(readUInt32());
+}
+
+uint32_t Reader::readUInt32() {
+    uint8_t buff[4];
+
+    readRaw(buff, sizeof(buff));
+
+    return static_cast<uint32_t>(decode(buff, sizeof(buff)));
+}
+
+int64_t Reader::readInt64() {
+    return static_cast<int64_t>(readUInt64());
+}
+
+uint64_t Reader::readUInt64() {
+    uint8_t buff[8];
+
+    readRaw(buff, sizeof(buff));
+
+    return decode(buff, sizeof(buff));
+}
+
+float Reader::readFloat32() {
+    uint32_t bits = readUInt32();
+
+    float val;
+
+    std::memcpy(&val, &bits, sizeof(val));
+
+    return val;
+}
+
+double Reader::readFloat64() {
+    uint64_t bits = readUInt64();
+
+    double val;
+
+    std::memcpy(&val, &bits, sizeof(val));
+
+    return val;
+}
+
+bool Reader::readBool() {
+    return readUInt8() != 0;
+}
+
+std::string Reader::readString() {
+    std::string val(readUInt16(), '\0');
+
+    readRaw(&val[0], val.size());
+
+    return val;
+}
+
+std::vector<uint8_t> Reader::readBytes() {
+    std::vector<uint8_t> val(readUInt16());
+
+    readRaw(val.data(), val.size());
+
+    return val;
+}
+
+void Reader::readLength(size_t expect) {
+    size_t length = readUInt16();
+
+    if (length != expect) {
+        throw CodecError("gsrpc: expect array size " + std::to_string(expect) + " but got " + std::to_string(length));
+    }
+}
+
+std::vector<Tag> Reader::readTags() {
+    std::vector<Tag> tags;
+
+    readTags(tags);
+
+    return tags;
+}
+
+void Reader::readTags(std::vector<Tag>& tags) {
+    auto tag = static_cast<Tag>(readUInt8());
+
+    tags.push_back(tag);
+
+    size_t nested = 0;
+
+    switch (tag) {
+{{range wireNested}}{{range .Tags}}    case Tag::{{.}}:
+{{end}}{{if lt .Nested 0}}        nested = readUInt8();
+        tags.push_back(static_cast<Tag>(nested));
+{{else}}        nested = {{.Nested}};
+{{end}}        break;
+{{end}}    default:
+        break;
+    }
+
+    for (size_t i = 0; i < nested; i++) {
+        readTags(tags);
+    }
+}
+
+void Reader::skip(const std::vector<Tag>& tags) {
+    skip(tags, 0);
+}
+
+void Reader::skipFields(size_t fields) {
+    for (size_t i = 0; i < fields; i++) {
+        skip(readTags());
+    }
+}
+
+void Reader::skipBytes(size_t length) {
+    if (length > remaining()) {
+        throw CodecError("gsrpc: read beyond the end of input");
+    }
+
+    offset_ += length;
+}
+
+// tagsEnd get the index next to the tag sequence begin with index
+static size_t tagsEnd(const std::vector<Tag>& tags, size_t index) {
+    size_t next = index + 1;
+
+    size_t nested = 0;
+
+    switch (tags[index]) {
+{{range wireNested}}{{range .Tags}}    case Tag::{{.}}:
+{{end}}{{if lt .Nested 0}}        next = index + 2;
+        nested = static_cast<size_t>(tags[index + 1]);
+{{else}}        nested = {{.Nested}};
+{{end}}        break;
+{{end}}    default:
+        break;
+    }
+
+    for (size_t i = 0; i < nested; i++) {
+        next = tagsEnd(tags, next);
+    }
+
+    return next;
+}
+
+void Reader::skipComponents(const std::vector<Tag>& tags, size_t index, size_t count) {
+    for (size_t i = 0; i < count; i++) {
+        skip(tags, index);
+        index = tagsEnd(tags, index);
+    }
+}
+
+void Reader::skip(const std::vector<Tag>& tags, size_t index) {
+    switch (tags[index]) {
+{{range wireCases}}{{range .Tags}}    case Tag::{{.}}:
+{{end}}{{if eq .Kind "fixed"}}        skipBytes({{.Size}});
+        break;
+{{else if eq .Kind "sized"}}        skipBytes(readUInt16());
+        break;
+{{else if eq .Kind "fields"}}        skipFields(readUInt16());
+        break;
+{{else if eq .Kind "seq"}}        for (size_t i = 0, length = readUInt16(); i < length; i++) {
+            skipComponents(tags, index + 1, {{.Nested}});
+        }
+        break;
+{{else if eq .Kind "record"}}        skipComponents(tags, index + 2, static_cast<size_t>(tags[index + 1]));
+        break;
+{{else if eq .Kind "optional"}}        if (readBool()) {
+            skip(tags, index + 1);
+        }
+        break;
+{{else if eq .Kind "variant"}}        if (readUInt8() != 0) {
+            skip(readTags());
+        }
+        break;
+{{else}}        break;
+{{end}}{{end}}    default:
+        throw CodecError("gsrpc: unknown tag " + std::to_string(static_cast<uint32_t>(tags[index])));
+    }
+}
+
+void Writer::writeRaw(const void* buff, size_t length) {
+    auto bytes = static_cast<const uint8_t*>(buff);
+
+    content_.insert(content_.end(), bytes, bytes + length);
+}
+
+void Writer::writeInt8(int8_t val) {
+    writeUInt8(static_cast<uint8_t>(val));
+}
+
+void Writer::writeUInt8(uint8_t val) {
+    content_.push_back(val);
+}
+
+void Writer::writeInt16(int16_t val) {
+    writeUInt16(static_cast<uint16_t>(val));
+}
+
+void Writer::writeUInt16(uint16_t val) {
+    uint8_t buff[2];
+
+    encode(buff, sizeof(buff), val);
+
+    writeRaw(buff, sizeof(buff));
+}
+
+void Writer::writeInt32(int32_t val) {
+    writeUInt32(static_cast<uint32_t>(val));
+}
+
+void Writer::writeUInt32(uint32_t val) {
+    uint8_t buff[4];
+
+    encode(buff, sizeof(buff), val);
+
+    writeRaw(buff, sizeof(buff));
+}
+
+void Writer::writeInt64(int64_t val) {
+    writeUInt64(static_cast<uint64_t>(val));
+}
+
+void Writer::writeUInt64(uint64_t val) {
+    uint8_t buff[8];
+
+    encode(buff, sizeof(buff), val);
+
+    writeRaw(buff, sizeof(buff));
+}
+
+void Writer::writeFloat32(float val) {
+    uint32_t bits;
+
+    std::memcpy(&bits, &val, sizeof(bits));
+
+    writeUInt32(bits);
+}
+
+void Writer::writeFloat64(double val) {
+    uint64_t bits;
+
+    std::memcpy(&bits, &val, sizeof(bits));
+
+    writeUInt64(bits);
+}
+
+void Writer::writeBool(bool val) {
+    writeUInt8(val ? 1 : 0);
+}
+
+void Writer::writeString(const std::string& val) {
+    writeLength(val.size());
+
+    writeRaw(val.data(), val.size());
+}
+
+void Writer::writeTags(std::initializer_list<Tag> tags) {
+    for (auto tag : tags) {
+        writeUInt8(static_cast<uint8_t>(tag));
+    }
+}
+
+void Writer::writeLength(size_t length) {
+    if (length > UINT16_MAX) {
+        throw CodecError("gsrpc: length " + std::to_string(length) + " out of uint16 range");
+    }
+
+    writeUInt16(static_cast<uint16_t>(length));
+}
+
+} // namespace com::gsrpc
+{{end}}
+
+{{define "channel.h"}}// generate by gs2cpp,don't modify it manually
+//
+// gsrpc runtime: the std::future based Channel, the messages are pushed in by the Transport owner
+#ifndef COM_GSRPC_CHANNEL_H
+#define COM_GSRPC_CHANNEL_H
+
+#include <com/gsrpc/stream.h>
+#include <com/gsrpc/gsrpc.gs.h>
+
+#include <chrono>
+#include <functional>
+#include <future>
+#include <memory>
+#include <mutex>
+#include <unordered_map>
+
+namespace com::gsrpc {
+
+// RPCError the channel error, including timeout, closed channel and the exception not declared by method
+class RPCError : public std::runtime_error {
+public:
+    using std::runtime_error::runtime_error;
+};
+
+// Transport the message transport of channel, one message per send, the received messages
+// are passed to Channel::received and the transport close is notified by Channel::closed
+class Transport {
+public:
+    virtual ~Transport() = default;
+
+    virtual void send(std::vector<uint8_t> data) = 0;
+
+    virtual void close() = 0;
+};
+
+// Dispatcher the service dispatcher registered into channel by service id
+class Dispatcher {
+public:
+    virtual ~Dispatcher() = default;
+
+    // dispatch invoke the service method, std::nullopt is returned for async method
+    virtual std::optional<Response> dispatch(const Request& call) = 0;
+};
+
+// makeResponse create the response of call, exception -1 means the content is return value
+Response makeResponse(const Request& call, int8_t exception, std::vector<uint8_t> content);
+
+// Channel the rpc channel which send requests to and dispatch requests from the peer
+class Channel {
+public:
+    explicit Channel(std::shared_ptr<Transport> transport) : transport_(std::move(transport)) {
+    }
+
+    void registerDispatcher(uint16_t id, std::shared_ptr<Dispatcher> dispatcher);
+
+    void unregisterDispatcher(uint16_t id);
+
+    // onError set the handler invoked when the dispatcher throw or the received message is invalid
+    void onError(std::function<void(const std::exception&)> handler);
+
+    // call send the request and wait the response, RPCError is raised after timeout
+    Response call(Request call, std::chrono::milliseconds timeout);
+
+    // post send the request without waiting response
+    void post(const Request& call);
+
+    void close();
+
+    // received handle the message received by transport, the requests are dispatched on the calling thread
+    void received(const uint8_t* data, size_t size);
+
+    // closed fail the pending calls with RPCError
+    void closed();
+
+private:
+    void send(Code code, std::vector<uint8_t> content);
+
+    void handle(const uint8_t* data, size_t size);
+
+    void error(const std::exception& err);
+
+    std::shared_ptr<Transport> transport_;
+    std::mutex mutex_;
+    uint32_t seq_ = 0;
+    std::unordered_map<uint32_t, std::promise<Response>> pending_;
+    std::unordered_map<uint16_t, std::shared_ptr<Dispatcher>> dispatchers_;
+    std::function<void(const std::exception&)> errorHandler_;
+};
+
+} // namespace com::gsrpc
+
+#endif // COM_GSRPC_CHANNEL_H
+{{end}}
+
+{{define "channel.cpp"}}// generate by gs2cpp,don't modify it manually
+#include <com/gsrpc/channel.h>
+
+namespace com::gsrpc {
+
+Response makeResponse(const Request& call, int8_t exception, std::vector<uint8_t> content) {
+    Response response;
+
+    response.id = call.id;
+
+    response.exception = exception;
+
+    response.content = std::move(content);
+
+    response.trace = call.trace;
+
+    return response;
+}
+
+void Channel::registerDispatcher(uint16_t id, std::shared_ptr<Dispatcher> dispatcher) {
+    std::lock_guard<std::mutex> lock(mutex_);
+
+    dispatchers_[id] = std::move(dispatcher);
+}
+
+void Channel::unregisterDispatcher(uint16_t id) {
+    std::lock_guard<std::mutex> lock(mutex_);
+
+    dispatchers_.erase(id);
+}
+
+void Channel::onError(std::function<void(const std::exception&)> handler) {
+    std::lock_guard<std::mutex> lock(mutex_);
+
+    errorHandler_ = std::move(handler);
+}
+
+Response Channel::call(Request call, std::chrono::milliseconds timeout) {
+    std::future<Response> future;
+
+    {
+        std::lock_guard<std::mutex> lock(mutex_);
+
+        call.id = ++seq_;
+
+        future = pending_[call.id].get_future();
+    }
+
+    auto id = call.id;
+
+    try {
+        send(Code::Request, marshal([&](Writer& writer) { call.write(writer); }));
+    } catch (...) {
+        std::lock_guard<std::mutex> lock(mutex_);
+
+        pending_.erase(id);
+
+        throw;
+    }
+
+    if (future.wait_for(timeout) != std::future_status::ready) {
+        std::lock_guard<std::mutex> lock(mutex_);
+
+        pending_.erase(id);
+
+        throw RPCError("gsrpc: call(" + std::to_string(id) + ") timeout");
+    }
+
+    return future.get();
+}
+
+void Channel::post(const Request& call) {
+    send(Code::Request, marshal([&](Writer& writer) { call.write(writer); }));
+}
+
+void Channel::close() {
+    transport_->close();
+}
+
+void Channel::received(const uint8_t* data, size_t size) {
+    try {
+        handle(data, size);
+    } catch (const std::exception& err) {
+        error(err);
+    }
+}
+
+void Channel::closed() {
+    std::unordered_map<uint32_t, std::promise<Response>> pending;
+
+    {
+        std::lock_guard<std::mutex> lock(mutex_);
+
+        pending.swap(pending_);
+    }
+
+    for (auto& entry : pending) {
+        entry.second.set_exception(std::make_exception_ptr(RPCError("gsrpc: channel closed")));
+    }
+}
+
+void Channel::send(Code code, std::vector<uint8_t> content) {
+    Message message;
+
+    message.code = code;
+
+    message.content = std::move(content);
+
+    transport_->send(marshal([&](Writer& writer) { message.write(writer); }));
+}
+
+void Channel::handle(const uint8_t* data, size_t size) {
+    Reader reader(data, size);
+
+    auto message = Message::read(reader);
+
+    switch (message.code) {
+    case Code::Response: {
+        auto response = unmarshal(message.content, [](Reader& reader) { return Response::read(reader); });
+
+        std::lock_guard<std::mutex> lock(mutex_);
+
+        auto iter = pending_.find(response.id);
+
+        if (iter != pending_.end()) {
+            iter->second.set_value(std::move(response));
+
+            pending_.erase(iter);
+        }
+
+        break;
+    }
+    case Code::Request: {
+        auto call = unmarshal(message.content, [](Reader& reader) { return Request::read(reader); });
+
+        std::shared_ptr<Dispatcher> dispatcher;
+
+        {
+            std::lock_guard<std::mutex> lock(mutex_);
+
+            auto iter = dispatchers_.find(call.service);
+
+            if (iter == dispatchers_.end()) {
+                break;
+            }
+
+            dispatcher = iter->second;
+        }
+
+        auto response = dispatcher->dispatch(call);
+
+        if (response) {
+            send(Code::Response, marshal([&](Writer& writer) { response->write(writer); }));
+        }
+
+        break;
+    }
+    case Code::Heartbeat:
+        send(Code::Heartbeat, {});
+        break;
+    default:
+        break;
+    }
+}
+
+void Channel::error(const std::exception& err) {
+    std::function<void(const std::exception&)> handler;
+
+    {
+        std::lock_guard<std::mutex> lock(mutex_);
+
+        handler = errorHandler_;
+    }
+
+    if (handler) {
+        handler(err);
+    }
+}
+
+} // namespace com::gsrpc
+{{end}}
+`
@@ -0,0 +1,753 @@
+package gen4csharp
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/gsdocker/gserrors"
+	"github.com/gsdocker/gslogger"
+	"github.com/gsrpc/gslang"
+	"github.com/gsrpc/gslang/ast"
+	"github.com/gsrpc/gslang/lexer"
+	"github.com/gsrpc/gsrpc/gen"
+	"github.com/gsrpc/gsrpc/wire"
+)
+
+var builtin = map[lexer.TokenType]string{
+	lexer.KeySByte:   "sbyte",
+	lexer.KeyByte:    "byte",
+	lexer.KeyInt16:   "short",
+	lexer.KeyUInt16:  "ushort",
+	lexer.KeyInt32:   "int",
+	lexer.KeyUInt32:  "uint",
+	lexer.KeyInt64:   "long",
+	lexer.KeyUInt64:  "ulong",
+	lexer.KeyFloat32: "float",
+	lexer.KeyFloat64: "double",
+	lexer.KeyBool:    "bool",
+	lexer.KeyString:  "string",
+	lexer.KeyVoid:    "void",
+}
+
+var readMapping = map[lexer.TokenType]string{
+	lexer.KeySByte:   "reader.ReadSByte()",
+	lexer.KeyByte:    "reader.ReadByte()",
+	lexer.KeyInt16:   "reader.ReadInt16()",
+	lexer.KeyUInt16:  "reader.ReadUInt16()",
+	lexer.KeyInt32:   "reader.ReadInt32()",
+	lexer.KeyUInt32:  "reader.ReadUInt32()",
+	lexer.KeyInt64:   "reader.ReadInt64()",
+	lexer.KeyUInt64:  "reader.ReadUInt64()",
+	lexer.KeyFloat32: "reader.ReadFloat32()",
+	lexer.KeyFloat64: "reader.ReadFloat64()",
+	lexer.KeyBool:    "reader.ReadBool()",
+	lexer.KeyString:  "reader.ReadString()",
+}
+
+var writeMapping = map[lexer.TokenType]string{
+	lexer.KeySByte:   "writer.WriteSByte",
+	lexer.KeyByte:    "writer.WriteByte",
+	lexer.KeyInt16:   "writer.WriteInt16",
+	lexer.KeyUInt16:  "writer.WriteUInt16",
+	lexer.KeyInt32:   "writer.WriteInt32",
+	lexer.KeyUInt32:  "writer.WriteUInt32",
+	lexer.KeyInt64:   "writer.WriteInt64",
+	lexer.KeyUInt64:  "writer.WriteUInt64",
+	lexer.KeyFloat32: "writer.WriteFloat32",
+	lexer.KeyFloat64: "writer.WriteFloat64",
+	lexer.KeyBool:    "writer.WriteBool",
+	lexer.KeyString:  "writer.WriteString",
+}
+
+var defaultval = map[lexer.TokenType]string{
+	lexer.KeySByte:   "0",
+	lexer.KeyByte:    "0",
+	lexer.KeyInt16:   "0",
+	lexer.KeyUInt16:  "0",
+	lexer.KeyInt32:   "0",
+	lexer.KeyUInt32:  "0",
+	lexer.KeyInt64:   "0",
+	lexer.KeyUInt64:  "0",
+	lexer.KeyFloat32: "0",
+	lexer.KeyFloat64: "0",
+	lexer.KeyBool:    "false",
+	lexer.KeyString:  "\"\"",
+}
+
+// keywords the C# reserved words, which are escaped with @ prefix when used as param names
+var keywords = map[string]bool{
+	"abstract": true, "as": true, "base": true, "bool": true, "break": true, "byte": true, "case": true, "catch": true,
+	"char": true, "checked": true, "class": true, "const": true, "continue": true, "decimal": true, "default": true,
+	"delegate": true, "do": true, "double": true, "else": true, "enum": true, "event": true, "explicit": true,
+	"extern": true, "false": true, "finally": true, "fixed": true, "float": true, "for": true, "foreach": true,
+	"goto": true, "if": true, "implicit": true, "in": true, "int": true, "interface": true, "internal": true,
+	"is": true, "lock": true, "long": true, "namespace": true, "new": true, "null": true, "object": true,
+	"operator": true, "out": true, "override": true, "params": true, "private": true, "protected": true,
+	"public": true, "readonly": true, "ref": true, "return": true, "sbyte": true, "sealed": true, "short": true,
+	"sizeof": true, "stackalloc": true, "static": true, "string": true, "struct": true, "switch": true, "this": true,
+	"throw": true, "true": true, "try": true, "typeof": true, "uint": true, "ulong": true, "unchecked": true,
+	"unsafe": true, "ushort": true, "using": true, "virtual": true, "void": true, "volatile": true, "while": true,
+}
+
+// runtimeFile the runtime source file name, which is generated in the com.gsrpc namespace directory
+const runtimeFile = "Runtime.cs"
+
+type _CodeGen struct {
+	gslogger.Log                    // Log APIs
+	rootpath     string             // root path
+	files        map[string][]byte  // generated files sink, the files are written into rootpath if nil
+	script       *ast.Script        // current script
+	content      bytes.Buffer       // current script content
+	tpl          *template.Template // code generate template
+	skips        []*regexp.Regexp   // skip lists
+	compiler     *gslang.Compiler   // current compiler
+	runtime      bool               // runtime file is generated
+}
+
+// NewCodeGen .
+func NewCodeGen(rootpath string, skips []string) (gslang.Visitor, error) {
+
+	codeGen := &_CodeGen{
+		Log:      gslogger.Get("gen4csharp"),
+		rootpath: rootpath,
+	}
+
+	for _, skip := range skips {
+		exp, err := regexp.Compile(skip)
+
+		if err != nil {
+			return nil, gserrors.Newf(err, "invalid skip regex string :%s", skip)
+		}
+
+		codeGen.skips = append(codeGen.skips, exp)
+	}
+
+	funcs := template.FuncMap{
+		"title":        strings.Title,
+		"tableName":    gen.TableName,
+		"paramName":    paramName,
+		"doc":          doc,
+		"notVoid":      gslang.NotVoid,
+		"isPOD":        gslang.IsPOD,
+		"isAsync":      gslang.IsAsync,
+		"isException":  gslang.IsException,
+		"enumSize":     gslang.EnumSize,
+		"isOptional":   gen.IsOptional,
+		"variant":      gen.Variant,
+		"typeName":     codeGen.typeName,
+		"fieldType":    codeGen.fieldType,
+		"fieldValue":   codeGen.fieldValue,
+		"readType":     codeGen.readType,
+		"writeType":    codeGen.writeType,
+		"tagValue":     codeGen.tagValue,
+		"wireCases":    wire.Cases,
+		"wireNested":   wire.Nested,
+		"rpcName":      codeGen.rpcName,
+		"fieldDefault": codeGen.fieldDefault,
+		"params":       codeGen.params,
+		"returnType":   codeGen.returnType,
+		"callArgs":     callArgs,
+	}
+
+	tpl, err := template.New("t4csharp").Funcs(funcs).Parse(t4csharp)
+
+	if err != nil {
+		return nil, err
+	}
+
+	codeGen.tpl = tpl
+
+	return codeGen, nil
+}
+
+// NewCodeGenWithFiles create codegen which write generated files into files map keyed by slash separated relative path
+func NewCodeGenWithFiles(files map[string][]byte, skips []string) (gslang.Visitor, error) {
+
+	codeGen, err := NewCodeGen("", skips)
+
+	if err != nil {
+		return nil, err
+	}
+
+	codeGen.(*_CodeGen).files = files
+
+	return codeGen, nil
+}
+
+// paramName get the method param name, C# keyword is escaped with @ prefix
+func paramName(name string) string {
+	if keywords[name] {
+		return "@" + name
+	}
+
+	return name
+}
+
+// qualify get the symbol reference name, symbols of other packages are referenced by the global namespace name
+func (codegen *_CodeGen) qualify(packageName string, name string) string {
+
+	if packageName == codegen.script.Package {
+		return name
+	}
+
+	return "global::" + packageName + "." + name
+}
+
+// globalName get the full qualified table name, which is used in expression to avoid the same name field hiding the type
+func globalName(typeDecl ast.Type) string {
+	return "global::" + typeDecl.Package() + "." + gen.TableName(typeDecl)
+}
+
+// rpcName get the reference name of com.gsrpc package symbol
+func (codegen *_CodeGen) rpcName(name string) string {
+	return codegen.qualify("com.gsrpc", name)
+}
+
+func (codegen *_CodeGen) tagValue(typeDecl ast.Type) string {
+	return strings.Join(codegen.tags(typeDecl), ", ")
+}
+
+// tags get the type tag sequence by the shared tag scheme
+func (codegen *_CodeGen) tags(typeDecl ast.Type) []string {
+
+	tag := codegen.rpcName("Tag")
+
+	tags := wire.Format(wire.Tags(typeDecl), func(t wire.Tag) string {
+		return tag + "." + t.String()
+	})
+
+	// the POD field count is formatted as number, which is converted to Tag explicitly
+	for i, name := range tags {
+		if !strings.HasPrefix(name, tag) {
+			tags[i] = fmt.Sprintf("(%s)%s", tag, name)
+		}
+	}
+
+	return tags
+}
+
+// isValueType check if the type is generated as C# value type, which is wrapped as Nullable when optional
+func isValueType(typeDecl ast.Type) bool {
+	switch typeDecl.(type) {
+	case *ast.BuiltinType:
+		return typeDecl.(*ast.BuiltinType).Type != lexer.KeyString
+	case *ast.TypeRef:
+		return isValueType(typeDecl.(*ast.TypeRef).Ref)
+	case *ast.Enum:
+		return true
+	}
+
+	return false
+}
+
+func (codegen *_CodeGen) typeName(typeDecl ast.Type) string {
+	switch typeDecl.(type) {
+	case *ast.BuiltinType:
+		builtinType := typeDecl.(*ast.BuiltinType)
+
+		return builtin[builtinType.Type]
+	case *ast.TypeRef:
+		return codegen.typeName(typeDecl.(*ast.TypeRef).Ref)
+
+	case *ast.Enum:
+		return codegen.qualify(typeDecl.Package(), strings.Title(typeDecl.Name()))
+
+	case *ast.Table:
+		return codegen.qualify(typeDecl.Package(), gen.TableName(typeDecl))
+
+	case *ast.Seq:
+		seq := typeDecl.(*ast.Seq)
+
+		if gen.IsBytes(seq) {
+			return "byte[]"
+		}
+
+		if entry, ok := wire.MapEntry(seq); ok {
+			return fmt.Sprintf("Dictionary<%s, %s>", codegen.typeName(entry.Fields[0].Type), codegen.typeName(entry.Fields[1].Type))
+		}
+
+		if seq.Size != -1 {
+			return codegen.typeName(seq.Component) + "[]"
+		}
+
+		return fmt.Sprintf("List<%s>", codegen.typeName(seq.Component))
+	}
+
+	gserrors.Panicf(nil, "typeName  error: unsupport type(%s)", typeDecl)
+
+	return "unknown"
+}
+
+// fieldType get the field type name, optional value type field is generated as Nullable
+func (codegen *_CodeGen) fieldType(field *ast.Field) string {
+
+	if gen.IsOptional(field) && isValueType(field.Type) {
+		return codegen.typeName(field.Type) + "?"
+	}
+
+	return codegen.typeName(field.Type)
+}
+
+// fieldValue get the field value expr of target variable, the Nullable field is unwrapped
+func (codegen *_CodeGen) fieldValue(field *ast.Field, target string) string {
+
+	if gen.IsOptional(field) && isValueType(field.Type) {
+		return fmt.Sprintf("%s.%s.Value", target, field.Name())
+	}
+
+	return fmt.Sprintf("%s.%s", target, field.Name())
+}
+
+func (codegen *_CodeGen) defaultVal(typeDecl ast.Type) string {
+	switch typeDecl.(type) {
+	case *ast.BuiltinType:
+		builtinType := typeDecl.(*ast.BuiltinType)
+
+		return defaultval[builtinType.Type]
+	case *ast.TypeRef:
+		return codegen.defaultVal(typeDecl.(*ast.TypeRef).Ref)
+
+	case *ast.Enum:
+		enum := typeDecl.(*ast.Enum)
+
+		return codegen.typeName(enum) + "." + strings.Title(enum.Constants[0].Name())
+
+	case *ast.Table:
+		if gen.IsOneOf(typeDecl) {
+			return "null"
+		}
+
+		return fmt.Sprintf("new %s()", codegen.typeName(typeDecl))
+
+	case *ast.Seq:
+		seq := typeDecl.(*ast.Seq)
+
+		if gen.IsBytes(seq) {
+			if seq.Size != -1 {
+				return fmt.Sprintf("new byte[%d]", seq.Size)
+			}
+
+			return "new byte[0]"
+		}
+
+		if _, ok := wire.MapEntry(seq); ok {
+			return fmt.Sprintf("new %s()", codegen.typeName(seq))
+		}
+
+		if seq.Size != -1 {
+			// the zero value array of builtin numeric and union components need not fill
+			if _, ok := seq.Component.(*ast.BuiltinType); ok && isValueType(seq.Component) || codegen.defaultVal(seq.Component) == "null" {
+				return fmt.Sprintf("new %s[%d]", codegen.typeName(seq.Component), seq.Size)
+			}
+
+			return fmt.Sprintf("%s.Fill(%d, () => %s)", codegen.rpcName("Codec"), seq.Size, codegen.defaultVal(seq.Component))
+		}
+
+		return fmt.Sprintf("new %s()", codegen.typeName(seq))
+	}
+
+	gserrors.Panicf(nil, "defaultVal  error: unsupport type(%s)", typeDecl)
+
+	return "unknown"
+}
+
+// readType get the expr which read the type value from the reader variable
+func (codegen *_CodeGen) readType(typeDecl ast.Type) string {
+	switch typeDecl.(type) {
+	case *ast.BuiltinType:
+		builtinType := typeDecl.(*ast.BuiltinType)
+
+		return readMapping[builtinType.Type]
+	case *ast.TypeRef:
+		return codegen.readType(typeDecl.(*ast.TypeRef).Ref)
+
+	case *ast.Enum:
+		if gslang.EnumSize(typeDecl) == 4 {
+			return fmt.Sprintf("(%s)reader.ReadUInt32()", codegen.typeName(typeDecl))
+		}
+
+		return fmt.Sprintf("(%s)reader.ReadByte()", codegen.typeName(typeDecl))
+
+	case *ast.Table:
+		return globalName(typeDecl) + ".Unmarshal(reader)"
+
+	case *ast.Seq:
+		seq := typeDecl.(*ast.Seq)
+
+		if gen.IsBytes(seq) {
+			if seq.Size != -1 {
+				return fmt.Sprintf("reader.ReadFixedBytes(%d)", seq.Size)
+			}
+
+			return "reader.ReadBytes()"
+		}
+
+		if entry, ok := wire.MapEntry(seq); ok {
+			return fmt.Sprintf("reader.ReadMap(() => %s, () => %s)", codegen.readType(entry.Fields[0].Type), codegen.readType(entry.Fields[1].Type))
+		}
+
+		if seq.Size != -1 {
+			return fmt.Sprintf("reader.ReadArray(%d, () => %s)", seq.Size, codegen.readType(seq.Component))
+		}
+
+		return fmt.Sprintf("reader.ReadList(() => %s)", codegen.readType(seq.Component))
+	}
+
+	gserrors.Panicf(nil, "readType  error: unsupport type(%s)", typeDecl)
+
+	return "unknown"
+}
+
+// writeType get the expr which write the valname to the writer variable, depth is used to name the lambda args
+func (codegen *_CodeGen) writeType(typeDecl ast.Type, valname string, depth int) string {
+	switch typeDecl.(type) {
+	case *ast.BuiltinType:
+		builtinType := typeDecl.(*ast.BuiltinType)
+
+		return fmt.Sprintf("%s(%s)", writeMapping[builtinType.Type], valname)
+	case *ast.TypeRef:
+		return codegen.writeType(typeDecl.(*ast.TypeRef).Ref, valname, depth)
+
+	case *ast.Enum:
+		if gslang.EnumSize(typeDecl) == 4 {
+			return fmt.Sprintf("writer.WriteUInt32((uint)%s)", valname)
+		}
+
+		return fmt.Sprintf("writer.WriteByte((byte)%s)", valname)
+
+	case *ast.Table:
+		return fmt.Sprintf("%s.Marshal(writer, %s)", globalName(typeDecl), valname)
+
+	case *ast.Seq:
+		seq := typeDecl.(*ast.Seq)
+
+		if gen.IsBytes(seq) {
+			if seq.Size != -1 {
+				return fmt.Sprintf("writer.WriteFixedBytes(%d, %s)", seq.Size, valname)
+			}
+
+			return fmt.Sprintf("writer.WriteBytes(%s)", valname)
+		}
+
+		key, val := fmt.Sprintf("k%d", depth), fmt.Sprintf("v%d", depth)
+
+		if entry, ok := wire.MapEntry(seq); ok {
+			return fmt.Sprintf("writer.WriteMap(%s, %s => %s, %s => %s)", valname,
+				key, codegen.writeType(entry.Fields[0].Type, key, depth+1),
+				val, codegen.writeType(entry.Fields[1].Type, val, depth+1))
+		}
+
+		if seq.Size != -1 {
+			return fmt.Sprintf("writer.WriteArray(%d, %s, %s => %s)", seq.Size, valname, val, codegen.writeType(seq.Component, val, depth+1))
+		}
+
+		return fmt.Sprintf("writer.WriteList(%s, %s => %s)", valname, val, codegen.writeType(seq.Component, val, depth+1))
+	}
+
+	gserrors.Panicf(nil, "writeType  error: unsupport type(%s)", typeDecl)
+
+	return "unknown"
+}
+
+// fieldDefault get the field initializer expr, optional field without default value is left null
+func (codegen *_CodeGen) fieldDefault(field *ast.Field) string {
+
+	expr, ok := gen.Default(field)
+
+	if !ok {
+		if gen.IsOptional(field) {
+			return ""
+		}
+
+		return codegen.defaultVal(field.Type)
+	}
+
+	start, _ := gslang.Pos(field)
+
+	return codegen.defaultExpr(expr, field.Type, start)
+}
+
+func (codegen *_CodeGen) defaultExpr(expr ast.Expr, typeDecl ast.Type, start lexer.Position) string {
+
+	eval := codegen.compiler.Eval()
+
+	switch typeDecl.(type) {
+	case *ast.BuiltinType:
+		builtinType := typeDecl.(*ast.BuiltinType)
+
+		gen.CheckUnsigned(eval, builtinType, expr, start)
+
+		switch builtinType.Type {
+		case lexer.KeyString:
+			return quote(eval.EvalString(expr))
+		case lexer.KeyBool:
+			return strconv.FormatBool(eval.EvalBool(expr))
+		case lexer.KeyFloat32:
+			return strconv.FormatFloat(eval.EvalFloat(expr), 'g', -1, 32) + "f"
+		case lexer.KeyFloat64:
+			return strconv.FormatFloat(eval.EvalFloat(expr), 'g', -1, 64) + "d"
+		case lexer.KeyVoid:
+		default:
+			return fmt.Sprintf("%d", eval.EvalInt(expr))
+		}
+
+	case *ast.TypeRef:
+		return codegen.defaultExpr(expr, typeDecl.(*ast.TypeRef).Ref, start)
+
+	case *ast.Enum:
+		enum := typeDecl.(*ast.Enum)
+
+		val := eval.EvalInt(expr)
+
+		if constant, ok := gen.Constant(enum, val); ok {
+			return codegen.typeName(enum) + "." + strings.Title(constant.Name())
+		}
+
+		gserrors.Panicf(nil, "enum %s constant(%d) not found :%v", enum, val, start)
+
+	case *ast.Table:
+		table := typeDecl.(*ast.Table)
+
+		newObj, ok := expr.(*ast.NewObj)
+
+		if !ok || gen.IsOneOf(table) {
+			break
+		}
+
+		// the fields without arg keep the class field initializers
+		var fields []string
+
+		for i, field := range table.Fields {
+
+			arg, ok := gen.FieldArg(newObj, i, field)
+
+			if ok {
+				fields = append(fields, fmt.Sprintf("%s = %s", field.Name(), codegen.defaultExpr(arg, field.Type, start)))
+			}
+		}
+
+		if len(fields) == 0 {
+			return fmt.Sprintf("new %s()", codegen.typeName(table))
+		}
+
+		return fmt.Sprintf("new %s { %s }", codegen.typeName(table), strings.Join(fields, ", "))
+	}
+
+	gserrors.Panicf(nil, "unsupport default value for type(%s) :%v", typeDecl, start)
+
+	return "unknown"
+}
+
+// quote get the C# string literal, the control characters are escaped as \u sequence
+func quote(val string) string {
+
+	var buff bytes.Buffer
+
+	buff.WriteString("\"")
+
+	for _, r := range val {
+		switch {
+		case r == '"':
+			buff.WriteString("\\\"")
+		case r == '\\':
+			buff.WriteString("\\\\")
+		case r == '\n':
+			buff.WriteString("\\n")
+		case r == '\r':
+			buff.WriteString("\\r")
+		case r == '\t':
+			buff.WriteString("\\t")
+		case r < 0x20 || r == 0x7f:
+			buff.WriteString(fmt.Sprintf("\\u%04x", r))
+		default:
+			buff.WriteRune(r)
+		}
+	}
+
+	buff.WriteString("\"")
+
+	return buff.String()
+}
+
+func (codegen *_CodeGen) params(params []*ast.Param) string {
+
+	var args []string
+
+	for _, param := range params {
+		args = append(args, fmt.Sprintf("%s %s", codegen.typeName(param.Type), paramName(param.Name())))
+	}
+
+	return "(" + strings.Join(args, ", ") + ")"
+}
+
+func callArgs(params []*ast.Param) string {
+
+	var args []string
+
+	for _, param := range params {
+		args = append(args, fmt.Sprintf("arg%d", param.ID))
+	}
+
+	return "(" + strings.Join(args, ", ") + ")"
+}
+
+// returnType get the method return type, async method is fire and forget
+func (codegen *_CodeGen) returnType(method *ast.Method) string {
+
+	if gslang.IsAsync(method) {
+		return "void"
+	}
+
+	if !gslang.NotVoid(method.Return) {
+		return "Task"
+	}
+
+	return fmt.Sprintf("Task<%s>", codegen.typeName(method.Return))
+}
+
+func (codegen *_CodeGen) execute(name string, data interface{}) {
+	if err := codegen.tpl.ExecuteTemplate(&codegen.content, name, data); err != nil {
+		gserrors.Panicf(err, "exec template(%s) for %s error", name, data)
+	}
+}
+
+func (codegen *_CodeGen) writeFile(name string, content []byte) {
+
+	// the content buffer may be reused by caller
+	if codegen.files != nil {
+		codegen.files[filepath.ToSlash(name)] = append([]byte(nil), content...)
+		return
+	}
+
+	fullpath := filepath.Join(codegen.rootpath, name)
+
+	if err := os.MkdirAll(filepath.Dir(fullpath), 0755); err != nil {
+		gserrors.Panicf(err, "create output directory error")
+	}
+
+	codegen.D("write file :%s", fullpath)
+
+	if err := ioutil.WriteFile(fullpath, content, 0644); err != nil {
+		gserrors.Panicf(err, "write generate stub code error")
+	}
+}
+
+// namespacePath get the namespace directory path relative to root path
+func namespacePath(packageName string) string {
+	return strings.Replace(packageName, ".", "/", -1)
+}
+
+func (codegen *_CodeGen) BeginScript(compiler *gslang.Compiler, script *ast.Script) bool {
+
+	scriptPath := filepath.ToSlash(filepath.Clean(script.Name()))
+
+	for _, skip := range codegen.skips {
+
+		if skip.MatchString(scriptPath) {
+
+			return false
+		}
+	}
+
+	if strings.HasPrefix(script.Package, "gslang.") {
+		return false
+	}
+
+	codegen.script = script
+
+	codegen.compiler = compiler
+
+	codegen.content.Reset()
+
+	return true
+}
+
+func (codegen *_CodeGen) Using(compiler *gslang.Compiler, using *ast.Using) {
+}
+
+func (codegen *_CodeGen) Table(compiler *gslang.Compiler, tableType *ast.Table) {
+
+	if gen.IsOneOf(tableType) {
+		codegen.execute("union", tableType)
+	} else {
+		codegen.execute("table", tableType)
+	}
+}
+
+func (codegen *_CodeGen) Annotation(compiler *gslang.Compiler, annotation *ast.Table) {
+}
+
+func (codegen *_CodeGen) Enum(compiler *gslang.Compiler, enum *ast.Enum) {
+	codegen.execute("enum", enum)
+}
+
+func (codegen *_CodeGen) Contract(compiler *gslang.Compiler, contract *ast.Contract) {
+	codegen.execute("contract", contract)
+}
+
+// EndScript write the script source file into the namespace directory
+func (codegen *_CodeGen) EndScript(compiler *gslang.Compiler) {
+
+	var stream bytes.Buffer
+
+	stream.WriteString("// generate by gs2csharp,don't modify it manually\n\n")
+
+	stream.WriteString("using System;\nusing System.Collections.Generic;\nusing System.Threading.Tasks;\n\n")
+
+	stream.WriteString(fmt.Sprintf("namespace %s\n{", codegen.script.Package))
+
+	stream.Write(codegen.content.Bytes())
+
+	stream.WriteString("}\n")
+
+	name := strings.TrimSuffix(filepath.Base(codegen.script.Name()), filepath.Ext(codegen.script.Name()))
+
+	codegen.writeFile(filepath.Join(namespacePath(codegen.script.Package), strings.Title(name)+".cs"), tidy.Clean(stream.Bytes()))
+
+	if codegen.runtime {
+		return
+	}
+
+	stream.Reset()
+
+	if err := codegen.tpl.ExecuteTemplate(&stream, "runtime", nil); err != nil {
+		gserrors.Panicf(err, "exec template(runtime) error")
+	}
+
+	codegen.writeFile(filepath.Join(namespacePath("com.gsrpc"), runtimeFile), stream.Bytes())
+
+	codegen.runtime = true
+}
+
+var tidy = gen.NewTidy(1, `{`, `}`)
+
+// doc get the xml doc comment from the .gs comments of node
+func doc(indent string, node ast.Node) string {
+
+	comments := gslang.Comments(node)
+
+	if len(comments) == 0 {
+		return ""
+	}
+
+	escaper := strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+	var buff bytes.Buffer
+
+	buff.WriteString(indent + "/// <summary>\n")
+
+	for _, line := range comments {
+		buff.WriteString(indent + "/// " + escaper.Replace(strings.TrimSpace(line)) + "\n")
+	}
+
+	buff.WriteString(indent + "/// </summary>\n")
+
+	return buff.String()
+}
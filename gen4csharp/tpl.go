@@ -0,0 +1,1060 @@
+package gen4csharp
+
+var t4csharp = `
+{{define "enum"}}{{$Enum := title .Name}}
+{{doc "    " .}}{{if enumSize . | eq 4}}    [Flags]
+    public enum {{$Enum}} : uint{{else}}    public enum {{$Enum}} : byte{{end}}
+    {
+{{range .Constants}}{{doc "        " .}}        {{title .Name}} = {{.Value}},
+{{end}}    }
+{{end}}
+
+{{define "table"}}{{$Table := tableName .}}
+{{doc "    " .}}    public sealed class {{$Table}}{{if isException .}} : Exception{{end}}
+    {
+{{range .Fields}}{{doc "        " .}}        public {{fieldType .}} {{.Name}}{{with fieldDefault .}} = {{.}}{{end}};
+{{end}}
+{{if isException .}}
+        public {{$Table}}() : base("{{.FullName}}")
+        {
+        }
+{{end}}
+{{if isPOD .}}
+        public static {{$Table}} Unmarshal({{rpcName "Reader"}} reader)
+        {
+            var target = new {{$Table}}();
+{{range .Fields}}
+            {{if isOptional .}}if (reader.ReadBool())
+            {
+                target.{{.Name}} = {{readType .Type}};
+            }{{else}}target.{{.Name}} = {{readType .Type}};{{end}}
+{{end}}
+            return target;
+        }
+
+        public static void Marshal({{rpcName "Writer"}} writer, {{$Table}} val)
+        {
+            if (val == null)
+            {
+                val = new {{$Table}}();
+            }
+{{range .Fields}}
+            {{if isOptional .}}writer.WriteBool(val.{{.Name}} != null);
+
+            if (val.{{.Name}} != null)
+            {
+                {{writeType .Type (fieldValue . "val") 0}};
+            }{{else}}{{writeType .Type (fieldValue . "val") 0}};{{end}}
+{{end}}
+        }
+{{else}}
+        public static {{$Table}} Unmarshal({{rpcName "Reader"}} reader)
+        {
+            var target = new {{$Table}}();
+
+            int fields = reader.ReadUInt16();
+{{range .Fields}}
+            if (fields == 0)
+            {
+                return target;
+            }
+
+            if (reader.ReadTags()[0] != {{rpcName "Tag"}}.Skip)
+            {
+                target.{{.Name}} = {{readType .Type}};
+            }
+
+            fields--;
+{{end}}
+            reader.SkipFields(fields);
+
+            return target;
+        }
+
+        public static void Marshal({{rpcName "Writer"}} writer, {{$Table}} val)
+        {
+            if (val == null)
+            {
+                val = new {{$Table}}();
+            }
+
+            writer.WriteUInt16({{len .Fields}});
+{{range .Fields}}
+            {{if isOptional .}}if (val.{{.Name}} == null)
+            {
+                writer.WriteTags({{rpcName "Tag"}}.Skip);
+            }
+            else
+            {
+                writer.WriteTags({{tagValue .Type}});
+                {{writeType .Type (fieldValue . "val") 0}};
+            }{{else}}writer.WriteTags({{tagValue .Type}});
+            {{writeType .Type (fieldValue . "val") 0}};{{end}}
+{{end}}
+        }
+{{end}}    }
+{{end}}
+
+{{define "union"}}{{$Union := tableName .}}
+{{doc "    " .}}    public sealed class {{$Union}}
+    {
+        public enum Kinds : byte
+        {
+            None = 0,
+{{range $index, $field := .Fields}}            {{title .Name}} = {{variant $index}},
+{{end}}        }
+
+        private object value;
+
+        public Kinds Kind { get; private set; }
+{{range .Fields}}
+{{doc "        " .}}        public {{typeName .Type}} {{title .Name}}
+        {
+            get { return Kind == Kinds.{{title .Name}} ? ({{typeName .Type}})value : default({{typeName .Type}}); }
+            set { Kind = Kinds.{{title .Name}}; this.value = value; }
+        }
+{{end}}
+        public static {{$Union}} Unmarshal({{rpcName "Reader"}} reader)
+        {
+            var variant = reader.ReadByte();
+
+            if (variant == 0)
+            {
+                return null;
+            }
+
+            var tags = reader.ReadTags();
+
+            var target = new {{$Union}}();
+
+            switch (variant)
+            {
+{{range $index, $field := .Fields}}            case {{variant $index}}:
+                target.{{title .Name}} = {{readType .Type}};
+                return target;
+{{end}}            default:
+                reader.Skip(tags);
+                return null;
+            }
+        }
+
+        public static void Marshal({{rpcName "Writer"}} writer, {{$Union}} val)
+        {
+            if (val == null)
+            {
+                writer.WriteByte(0);
+                return;
+            }
+
+            switch (val.Kind)
+            {
+{{range $index, $field := .Fields}}            case Kinds.{{title .Name}}:
+                writer.WriteByte({{variant $index}});
+                writer.WriteTags({{tagValue .Type}});
+                {{writeType .Type (printf "val.%s" (title .Name)) 0}};
+                break;
+{{end}}            default:
+                writer.WriteByte(0);
+                break;
+            }
+        }
+    }
+{{end}}
+
+{{define "contract"}}{{$Contract := title .Name}}
+{{doc "    " .}}    public interface I{{$Contract}}
+    {
+{{range .Methods}}{{doc "        " .}}        {{returnType .}} {{title .Name}}{{params .Params}};
+{{end}}    }
+
+    /// <summary>
+    /// {{$Contract}}Dispatcher dispatch the remote calls to I{{$Contract}} service
+    /// </summary>
+    public sealed class {{$Contract}}Dispatcher : {{rpcName "IDispatcher"}}
+    {
+        public const string ServiceName = "{{.FullName}}";
+
+        private readonly I{{$Contract}} service;
+
+        public {{$Contract}}Dispatcher(ushort id, I{{$Contract}} service)
+        {
+            ID = id;
+            this.service = service;
+        }
+
+        public ushort ID { get; private set; }
+
+        public override string ToString()
+        {
+            return ServiceName;
+        }
+
+        public async Task<{{rpcName "Response"}}> Dispatch({{rpcName "Request"}} call)
+        {
+            switch (call.Method)
+            {
+{{range .Methods}}            case {{.ID}}:
+            {
+                if (call.Params.Count != {{.ParamsCount}})
+                {
+                    throw new ArgumentException("{{$Contract}}#{{title .Name}} expect {{.ParamsCount}} params but got :" + call.Params.Count);
+                }
+{{range .Params}}
+                var arg{{.ID}} = {{rpcName "Codec"}}.Unmarshal(call.Params[{{.ID}}].Content, reader => {{readType .Type}});
+{{end}}
+{{if isAsync .}}
+                service.{{title .Name}}{{callArgs .Params}};
+
+                return null;
+{{else if .Exceptions}}
+                try
+                {
+                    {{if notVoid .Return}}var ret = {{end}}await service.{{title .Name}}{{callArgs .Params}};
+
+                    return new {{rpcName "Response"}}
+                    {
+                        ID = call.ID,
+                        Exception = -1,
+                        Content = {{if notVoid .Return}}{{rpcName "Codec"}}.Marshal(writer => {{writeType .Return "ret" 0}}){{else}}new byte[0]{{end}},
+                        Trace = call.Trace,
+                    };
+                }
+{{range .Exceptions}}                catch ({{typeName .Type}} err)
+                {
+                    return new {{rpcName "Response"}}
+                    {
+                        ID = call.ID,
+                        Exception = {{.ID}},
+                        Content = {{rpcName "Codec"}}.Marshal(writer => {{writeType .Type "err" 0}}),
+                        Trace = call.Trace,
+                    };
+                }
+{{end}}
+{{else}}
+                {{if notVoid .Return}}var ret = {{end}}await service.{{title .Name}}{{callArgs .Params}};
+
+                return new {{rpcName "Response"}}
+                {
+                    ID = call.ID,
+                    Exception = -1,
+                    Content = {{if notVoid .Return}}{{rpcName "Codec"}}.Marshal(writer => {{writeType .Return "ret" 0}}){{else}}new byte[0]{{end}},
+                    Trace = call.Trace,
+                };
+{{end}}            }
+{{end}}            }
+
+            throw new InvalidOperationException("unknown {{$Contract}}#" + call.Method + " method");
+        }
+    }
+
+    /// <summary>
+    /// {{$Contract}}RPC the remote I{{$Contract}} service proxy
+    /// </summary>
+    public sealed class {{$Contract}}RPC : I{{$Contract}}
+    {
+        private readonly {{rpcName "Channel"}} channel;
+
+        private readonly ushort serviceID;
+
+        public {{$Contract}}RPC({{rpcName "Channel"}} channel, ushort serviceID)
+        {
+            this.channel = channel;
+            this.serviceID = serviceID;
+            Timeout = TimeSpan.FromSeconds(5);
+        }
+
+        public TimeSpan Timeout { get; set; }
+{{range .Methods}}
+        public {{if not (isAsync .)}}async {{end}}{{returnType .}} {{title .Name}}{{params .Params}}
+        {
+            var call = new {{rpcName "Request"}}
+            {
+                Service = serviceID,
+                Method = {{.ID}},
+                Params = new List<{{rpcName "Param"}}>
+                {
+{{range .Params}}                    new {{rpcName "Param"}} { Content = {{rpcName "Codec"}}.Marshal(writer => {{writeType .Type (paramName .Name) 0}}) },
+{{end}}                },
+            };
+{{if isAsync .}}
+            channel.Post(call);
+{{else}}
+            var callReturn = await channel.Call(call, Timeout);
+
+            if (callReturn.Exception != -1)
+            {
+                switch (callReturn.Exception)
+                {
+                case -2:
+                    throw {{rpcName "Codec"}}.Unmarshal(callReturn.Content, reader => {{rpcName "InvalidArgumentException"}}.Unmarshal(reader));
+{{range .Exceptions}}                case {{.ID}}:
+                    throw {{rpcName "Codec"}}.Unmarshal(callReturn.Content, reader => {{readType .Type}});
+{{end}}                default:
+                    throw new {{rpcName "RemoteException"}}();
+                }
+            }
+{{if notVoid .Return}}
+            return {{rpcName "Codec"}}.Unmarshal(callReturn.Content, reader => {{readType .Return}});
+{{end}}{{end}}        }
+{{end}}    }
+{{end}}
+
+{{define "runtime"}}// generate by gs2csharp,don't modify it manually
+//
+// gsrpc runtime: the byte array Reader/Writer and the Task based Channel over ITransport
+
+using System;
+using System.Collections.Generic;
+using System.IO;
+using System.Text;
+using System.Threading;
+using System.Threading.Tasks;
+
+namespace com.gsrpc
+{
+    /// <summary>
+    /// Reader read gsrpc values from the input buffer
+    /// </summary>
+    public sealed class Reader
+    {
+        private readonly byte[] buff;
+
+        private readonly int end;
+
+        private int offset;
+
+        public Reader(byte[] buff) : this(buff, 0, buff.Length)
+        {
+        }
+
+        public Reader(byte[] buff, int offset, int count)
+        {
+            this.buff = buff;
+            this.offset = offset;
+            this.end = offset + count;
+        }
+
+        private int Next(int length)
+        {
+            if (offset + length > end)
+            {
+                throw new EndOfStreamException("gsrpc: read beyond the end of buffer");
+            }
+
+            var current = offset;
+
+            offset += length;
+
+            return current;
+        }
+
+        public byte ReadByte()
+        {
+            return buff[Next(1)];
+        }
+
+        public sbyte ReadSByte()
+        {
+            return (sbyte)buff[Next(1)];
+        }
+
+        public bool ReadBool()
+        {
+            return ReadByte() != 0;
+        }
+
+        public short ReadInt16()
+        {
+            return (short)ReadUInt16();
+        }
+
+        public ushort ReadUInt16()
+        {
+            var index = Next(2);
+
+            return (ushort)(buff[index] | buff[index + 1] << 8);
+        }
+
+        public int ReadInt32()
+        {
+            return (int)ReadUInt32();
+        }
+
+        public uint ReadUInt32()
+        {
+            var index = Next(4);
+
+            return (uint)(buff[index] | buff[index + 1] << 8 | buff[index + 2] << 16 | buff[index + 3] << 24);
+        }
+
+        public long ReadInt64()
+        {
+            return (long)ReadUInt64();
+        }
+
+        public ulong ReadUInt64()
+        {
+            ulong low = ReadUInt32();
+
+            ulong high = ReadUInt32();
+
+            return low | high << 32;
+        }
+
+        public float ReadFloat32()
+        {
+            var bytes = BitConverter.GetBytes(ReadInt32());
+
+            return BitConverter.ToSingle(bytes, 0);
+        }
+
+        public double ReadFloat64()
+        {
+            return BitConverter.Int64BitsToDouble(ReadInt64());
+        }
+
+        public byte[] ReadBytes()
+        {
+            int length = ReadUInt16();
+
+            var val = new byte[length];
+
+            Buffer.BlockCopy(buff, Next(length), val, 0, length);
+
+            return val;
+        }
+
+        public byte[] ReadFixedBytes(int size)
+        {
+            var val = ReadBytes();
+
+            if (val.Length != size)
+            {
+                throw new InvalidDataException("gsrpc: check array size failed, expect " + size + " got " + val.Length);
+            }
+
+            return val;
+        }
+
+        public string ReadString()
+        {
+            int length = ReadUInt16();
+
+            return Encoding.UTF8.GetString(buff, Next(length), length);
+        }
+
+        public List<T> ReadList<T>(Func<T> read)
+        {
+            int length = ReadUInt16();
+
+            var list = new List<T>(length);
+
+            for (var i = 0; i < length; i++)
+            {
+                list.Add(read());
+            }
+
+            return list;
+        }
+
+        public T[] ReadArray<T>(int size, Func<T> read)
+        {
+            var list = ReadList(read);
+
+            if (list.Count != size)
+            {
+                throw new InvalidDataException("gsrpc: check array size failed, expect " + size + " got " + list.Count);
+            }
+
+            return list.ToArray();
+        }
+
+        public Dictionary<K, V> ReadMap<K, V>(Func<K> readKey, Func<V> readValue)
+        {
+            int length = ReadUInt16();
+
+            var map = new Dictionary<K, V>(length);
+
+            for (var i = 0; i < length; i++)
+            {
+                var key = readKey();
+
+                map[key] = readValue();
+            }
+
+            return map;
+        }
+
+        /// <summary>
+        /// read the tag sequence of tagged value, the nested container and POD tags are followed by their component tags
+        /// </summary>
+        public Tag[] ReadTags()
+        {
+            var tags = new List<Tag>();
+
+            ReadTags(tags);
+
+            return tags.ToArray();
+        }
+
+        private void ReadTags(List<Tag> tags)
+        {
+            var tag = (Tag)ReadByte();
+
+            tags.Add(tag);
+
+            var nested = 0;
+
+            switch (tag)
+            {
+{{range wireNested}}{{range .Tags}}            case Tag.{{.}}:
+{{end}}{{if lt .Nested 0}}                nested = ReadByte();
+                tags.Add((Tag)nested);
+{{else}}                nested = {{.Nested}};
+{{end}}                break;
+{{end}}            }
+
+            for (var i = 0; i < nested; i++)
+            {
+                ReadTags(tags);
+            }
+        }
+
+        /// <summary>
+        /// skip the value described by the tag sequence
+        /// </summary>
+        public void Skip(Tag[] tags)
+        {
+            SkipTags(tags, 0);
+        }
+
+        /// <summary>
+        /// skip the unknown fields of tagged table
+        /// </summary>
+        public void SkipFields(int fields)
+        {
+            for (var i = 0; i < fields; i++)
+            {
+                Skip(ReadTags());
+            }
+        }
+
+        // SkipComponents skip the values of count tag sequences begin with index
+        private void SkipComponents(Tag[] tags, int index, int count)
+        {
+            for (var i = 0; i < count; i++)
+            {
+                SkipTags(tags, index);
+                index = TagsEnd(tags, index);
+            }
+        }
+
+        private void SkipTags(Tag[] tags, int index)
+        {
+            switch (tags[index])
+            {
+{{range wireCases}}{{range .Tags}}            case Tag.{{.}}:
+{{end}}            {
+{{if eq .Kind "fixed"}}                Next({{.Size}});
+{{else if eq .Kind "sized"}}                Next(ReadUInt16());
+{{else if eq .Kind "fields"}}                SkipFields(ReadUInt16());
+{{else if eq .Kind "seq"}}                int length = ReadUInt16();
+
+                for (var i = 0; i < length; i++)
+                {
+                    SkipComponents(tags, index + 1, {{.Nested}});
+                }
+{{else if eq .Kind "record"}}                SkipComponents(tags, index + 2, (int)tags[index + 1]);
+{{else if eq .Kind "optional"}}                if (ReadBool())
+                {
+                    SkipTags(tags, index + 1);
+                }
+{{else if eq .Kind "variant"}}                if (ReadByte() != 0)
+                {
+                    Skip(ReadTags());
+                }
+{{end}}                break;
+            }
+{{end}}            default:
+                throw new InvalidDataException("gsrpc: unknown tag " + tags[index]);
+            }
+        }
+
+        // TagsEnd get the index next to the tag sequence begin with index
+        private static int TagsEnd(Tag[] tags, int index)
+        {
+            var next = index + 1;
+
+            var nested = 0;
+
+            switch (tags[index])
+            {
+{{range wireNested}}{{range .Tags}}            case Tag.{{.}}:
+{{end}}{{if lt .Nested 0}}                next = index + 2;
+                nested = (int)tags[index + 1];
+{{else}}                nested = {{.Nested}};
+{{end}}                break;
+{{end}}            }
+
+            for (var i = 0; i < nested; i++)
+            {
+                next = TagsEnd(tags, next);
+            }
+
+            return next;
+        }
+    }
+
+    /// <summary>
+    /// Writer write gsrpc values into the growable output buffer
+    /// </summary>
+    public sealed class Writer
+    {
+        private byte[] buff = new byte[64];
+
+        private int length;
+
+        // Next reserve count bytes and return the write offset, the buffer is replaced when growing
+        private int Next(int count)
+        {
+            var offset = length;
+
+            if (offset + count > buff.Length)
+            {
+                Array.Resize(ref buff, Math.Max(buff.Length * 2, offset + count));
+            }
+
+            length += count;
+
+            return offset;
+        }
+
+        public void WriteByte(byte val)
+        {
+            var offset = Next(1);
+
+            buff[offset] = val;
+        }
+
+        public void WriteSByte(sbyte val)
+        {
+            WriteByte((byte)val);
+        }
+
+        public void WriteBool(bool val)
+        {
+            WriteByte(val ? (byte)1 : (byte)0);
+        }
+
+        public void WriteInt16(short val)
+        {
+            WriteUInt16((ushort)val);
+        }
+
+        public void WriteUInt16(ushort val)
+        {
+            var offset = Next(2);
+
+            buff[offset] = (byte)val;
+            buff[offset + 1] = (byte)(val >> 8);
+        }
+
+        public void WriteInt32(int val)
+        {
+            WriteUInt32((uint)val);
+        }
+
+        public void WriteUInt32(uint val)
+        {
+            var offset = Next(4);
+
+            buff[offset] = (byte)val;
+            buff[offset + 1] = (byte)(val >> 8);
+            buff[offset + 2] = (byte)(val >> 16);
+            buff[offset + 3] = (byte)(val >> 24);
+        }
+
+        public void WriteInt64(long val)
+        {
+            WriteUInt64((ulong)val);
+        }
+
+        public void WriteUInt64(ulong val)
+        {
+            WriteUInt32((uint)val);
+            WriteUInt32((uint)(val >> 32));
+        }
+
+        public void WriteFloat32(float val)
+        {
+            WriteInt32(BitConverter.ToInt32(BitConverter.GetBytes(val), 0));
+        }
+
+        public void WriteFloat64(double val)
+        {
+            WriteInt64(BitConverter.DoubleToInt64Bits(val));
+        }
+
+        public void WriteBytes(byte[] val)
+        {
+            if (val == null)
+            {
+                val = new byte[0];
+            }
+
+            WriteUInt16((ushort)val.Length);
+
+            var offset = Next(val.Length);
+
+            Buffer.BlockCopy(val, 0, buff, offset, val.Length);
+        }
+
+        public void WriteFixedBytes(int size, byte[] val)
+        {
+            if (val == null || val.Length != size)
+            {
+                throw new InvalidDataException("gsrpc: check array size failed, expect " + size + " got " + (val == null ? 0 : val.Length));
+            }
+
+            WriteBytes(val);
+        }
+
+        public void WriteString(string val)
+        {
+            WriteBytes(Encoding.UTF8.GetBytes(val ?? ""));
+        }
+
+        public void WriteTags(params Tag[] tags)
+        {
+            foreach (var tag in tags)
+            {
+                WriteByte((byte)tag);
+            }
+        }
+
+        public void WriteList<T>(List<T> val, Action<T> write)
+        {
+            if (val == null)
+            {
+                WriteUInt16(0);
+                return;
+            }
+
+            WriteUInt16((ushort)val.Count);
+
+            foreach (var v in val)
+            {
+                write(v);
+            }
+        }
+
+        public void WriteArray<T>(int size, T[] val, Action<T> write)
+        {
+            if (val == null || val.Length != size)
+            {
+                throw new InvalidDataException("gsrpc: check array size failed, expect " + size + " got " + (val == null ? 0 : val.Length));
+            }
+
+            WriteUInt16((ushort)val.Length);
+
+            foreach (var v in val)
+            {
+                write(v);
+            }
+        }
+
+        /// <summary>
+        /// write map entries in key order, so the same map is always encoded as same bytes
+        /// </summary>
+        public void WriteMap<K, V>(Dictionary<K, V> val, Action<K> writeKey, Action<V> writeValue)
+        {
+            if (val == null)
+            {
+                WriteUInt16(0);
+                return;
+            }
+
+            var keys = new List<K>(val.Keys);
+
+            keys.Sort(typeof(K) == typeof(string) ? (IComparer<K>)StringComparer.Ordinal : Comparer<K>.Default);
+
+            WriteUInt16((ushort)keys.Count);
+
+            foreach (var key in keys)
+            {
+                writeKey(key);
+                writeValue(val[key]);
+            }
+        }
+
+        public byte[] Content()
+        {
+            var content = new byte[length];
+
+            Buffer.BlockCopy(buff, 0, content, 0, length);
+
+            return content;
+        }
+    }
+
+    /// <summary>
+    /// Codec the helper functions of generated codes
+    /// </summary>
+    public static class Codec
+    {
+        /// <summary>
+        /// marshal encode value by the write function
+        /// </summary>
+        public static byte[] Marshal(Action<Writer> write)
+        {
+            var writer = new Writer();
+
+            write(writer);
+
+            return writer.Content();
+        }
+
+        /// <summary>
+        /// unmarshal decode value from content by the read function
+        /// </summary>
+        public static T Unmarshal<T>(byte[] content, Func<Reader, T> read)
+        {
+            return read(new Reader(content));
+        }
+
+        /// <summary>
+        /// create fixed size array with the elements created by the make function
+        /// </summary>
+        public static T[] Fill<T>(int size, Func<T> make)
+        {
+            var array = new T[size];
+
+            for (var i = 0; i < size; i++)
+            {
+                array[i] = make();
+            }
+
+            return array;
+        }
+    }
+
+    /// <summary>
+    /// ITransport the message transport of channel, one message per Send and Received event,
+    /// the events may be raised from any thread
+    /// </summary>
+    public interface ITransport
+    {
+        event Action<byte[]> Received;
+
+        event Action<Exception> Closed;
+
+        void Send(byte[] data);
+
+        void Close();
+    }
+
+    /// <summary>
+    /// IDispatcher the service dispatcher registered into channel by service id
+    /// </summary>
+    public interface IDispatcher
+    {
+        ushort ID { get; }
+
+        Task<Response> Dispatch(Request call);
+    }
+
+    /// <summary>
+    /// Channel the rpc channel which send requests to and dispatch requests from the peer
+    /// </summary>
+    public sealed class Channel
+    {
+        private readonly ITransport transport;
+
+        private readonly Dictionary<uint, TaskCompletionSource<Response>> pending = new Dictionary<uint, TaskCompletionSource<Response>>();
+
+        private readonly Dictionary<ushort, IDispatcher> dispatchers = new Dictionary<ushort, IDispatcher>();
+
+        private uint seq;
+
+        /// <summary>
+        /// Error raised when the dispatcher throw unhandled exception
+        /// </summary>
+        public event Action<Exception> Error;
+
+        public Channel(ITransport transport)
+        {
+            this.transport = transport;
+            transport.Received += OnReceived;
+            transport.Closed += OnClosed;
+        }
+
+        public void Register(IDispatcher dispatcher)
+        {
+            lock (dispatchers)
+            {
+                dispatchers[dispatcher.ID] = dispatcher;
+            }
+        }
+
+        public void Unregister(ushort id)
+        {
+            lock (dispatchers)
+            {
+                dispatchers.Remove(id);
+            }
+        }
+
+        /// <summary>
+        /// call send the request and wait the response, the task is failed with TimeoutException after timeout
+        /// </summary>
+        public Task<Response> Call(Request call, TimeSpan timeout)
+        {
+            var promise = new TaskCompletionSource<Response>(TaskCreationOptions.RunContinuationsAsynchronously);
+
+            uint id;
+
+            lock (pending)
+            {
+                id = ++seq;
+                pending[id] = promise;
+            }
+
+            call.ID = id;
+
+            var timer = new CancellationTokenSource(timeout);
+
+            timer.Token.Register(() =>
+            {
+                if (Remove(id) != null)
+                {
+                    promise.TrySetException(new TimeoutException("gsrpc: rpc call(" + id + ") timeout"));
+                }
+            });
+
+            promise.Task.ContinueWith(task => timer.Dispose(), TaskScheduler.Default);
+
+            try
+            {
+                Send(Code.Request, Codec.Marshal(writer => Request.Marshal(writer, call)));
+            }
+            catch (Exception e)
+            {
+                Remove(id);
+                promise.TrySetException(e);
+            }
+
+            return promise.Task;
+        }
+
+        /// <summary>
+        /// post send the request without waiting response
+        /// </summary>
+        public void Post(Request call)
+        {
+            Send(Code.Request, Codec.Marshal(writer => Request.Marshal(writer, call)));
+        }
+
+        public void Close()
+        {
+            transport.Close();
+        }
+
+        private TaskCompletionSource<Response> Remove(uint id)
+        {
+            lock (pending)
+            {
+                TaskCompletionSource<Response> promise;
+
+                if (pending.TryGetValue(id, out promise))
+                {
+                    pending.Remove(id);
+                }
+
+                return promise;
+            }
+        }
+
+        private void Send(Code code, byte[] content)
+        {
+            transport.Send(Codec.Marshal(writer => Message.Marshal(writer, new Message { Code = code, Agent = 0, Content = content })));
+        }
+
+        private void OnReceived(byte[] data)
+        {
+            var message = Codec.Unmarshal(data, Message.Unmarshal);
+
+            switch (message.Code)
+            {
+            case Code.Response:
+            {
+                var callReturn = Codec.Unmarshal(message.Content, Response.Unmarshal);
+
+                var promise = Remove(callReturn.ID);
+
+                if (promise != null)
+                {
+                    promise.TrySetResult(callReturn);
+                }
+
+                break;
+            }
+            case Code.Request:
+                Dispatch(Codec.Unmarshal(message.Content, Request.Unmarshal));
+                break;
+            case Code.Heartbeat:
+                Send(Code.Heartbeat, new byte[0]);
+                break;
+            }
+        }
+
+        private async void Dispatch(Request call)
+        {
+            IDispatcher dispatcher;
+
+            lock (dispatchers)
+            {
+                if (!dispatchers.TryGetValue(call.Service, out dispatcher))
+                {
+                    return;
+                }
+            }
+
+            try
+            {
+                var callReturn = await dispatcher.Dispatch(call);
+
+                if (callReturn != null)
+                {
+                    Send(Code.Response, Codec.Marshal(writer => Response.Marshal(writer, callReturn)));
+                }
+            }
+            catch (Exception e)
+            {
+                var handler = Error;
+
+                if (handler != null)
+                {
+                    handler(e);
+                }
+            }
+        }
+
+        private void OnClosed(Exception reason)
+        {
+            List<TaskCompletionSource<Response>> promises;
+
+            lock (pending)
+            {
+                promises = new List<TaskCompletionSource<Response>>(pending.Values);
+                pending.Clear();
+            }
+
+            foreach (var promise in promises)
+            {
+                promise.TrySetException(reason);
+            }
+        }
+    }
+}
+{{end}}
+`
@@ -0,0 +1,831 @@
+package gen4dart
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"unicode"
+
+	"github.com/gsdocker/gserrors"
+	"github.com/gsdocker/gslogger"
+	"github.com/gsrpc/gslang"
+	"github.com/gsrpc/gslang/ast"
+	"github.com/gsrpc/gslang/lexer"
+	"github.com/gsrpc/gsrpc/gen"
+	"github.com/gsrpc/gsrpc/wire"
+)
+
+var builtin = map[lexer.TokenType]string{
+	lexer.KeySByte:   "int",
+	lexer.KeyByte:    "int",
+	lexer.KeyInt16:   "int",
+	lexer.KeyUInt16:  "int",
+	lexer.KeyInt32:   "int",
+	lexer.KeyUInt32:  "int",
+	lexer.KeyInt64:   "int",
+	lexer.KeyUInt64:  "int",
+	lexer.KeyFloat32: "double",
+	lexer.KeyFloat64: "double",
+	lexer.KeyBool:    "bool",
+	lexer.KeyString:  "String",
+	lexer.KeyVoid:    "void",
+}
+
+var readMapping = map[lexer.TokenType]string{
+	lexer.KeySByte:   "reader.readSByte()",
+	lexer.KeyByte:    "reader.readByte()",
+	lexer.KeyInt16:   "reader.readInt16()",
+	lexer.KeyUInt16:  "reader.readUInt16()",
+	lexer.KeyInt32:   "reader.readInt32()",
+	lexer.KeyUInt32:  "reader.readUInt32()",
+	lexer.KeyInt64:   "reader.readInt64()",
+	lexer.KeyUInt64:  "reader.readUInt64()",
+	lexer.KeyFloat32: "reader.readFloat32()",
+	lexer.KeyFloat64: "reader.readFloat64()",
+	lexer.KeyBool:    "reader.readBool()",
+	lexer.KeyString:  "reader.readString()",
+}
+
+var writeMapping = map[lexer.TokenType]string{
+	lexer.KeySByte:   "writer.writeSByte",
+	lexer.KeyByte:    "writer.writeByte",
+	lexer.KeyInt16:   "writer.writeInt16",
+	lexer.KeyUInt16:  "writer.writeUInt16",
+	lexer.KeyInt32:   "writer.writeInt32",
+	lexer.KeyUInt32:  "writer.writeUInt32",
+	lexer.KeyInt64:   "writer.writeInt64",
+	lexer.KeyUInt64:  "writer.writeUInt64",
+	lexer.KeyFloat32: "writer.writeFloat32",
+	lexer.KeyFloat64: "writer.writeFloat64",
+	lexer.KeyBool:    "writer.writeBool",
+	lexer.KeyString:  "writer.writeString",
+}
+
+var defaultval = map[lexer.TokenType]string{
+	lexer.KeySByte:   "0",
+	lexer.KeyByte:    "0",
+	lexer.KeyInt16:   "0",
+	lexer.KeyUInt16:  "0",
+	lexer.KeyInt32:   "0",
+	lexer.KeyUInt32:  "0",
+	lexer.KeyInt64:   "0",
+	lexer.KeyUInt64:  "0",
+	lexer.KeyFloat32: "0.0",
+	lexer.KeyFloat64: "0.0",
+	lexer.KeyBool:    "false",
+	lexer.KeyString:  "''",
+}
+
+// keywords the dart reserved words and the generated member names, which are escaped with _ suffix
+var keywords = map[string]bool{
+	"assert": true, "break": true, "case": true, "catch": true, "class": true, "const": true, "continue": true,
+	"default": true, "do": true, "else": true, "enum": true, "extends": true, "false": true, "final": true,
+	"finally": true, "for": true, "if": true, "in": true, "is": true, "new": true, "null": true, "rethrow": true,
+	"return": true, "super": true, "switch": true, "this": true, "throw": true, "true": true, "try": true,
+	"var": true, "void": true, "while": true, "with": true, "late": true, "required": true,
+	"hashCode": true, "runtimeType": true, "toString": true, "noSuchMethod": true,
+	"copyWith": true, "readFrom": true, "writeTo": true,
+}
+
+// constKeywords the names can't be used as enum constant, which conflict with enum members or the builtin types
+var constKeywords = map[string]bool{
+	"values": true, "index": true, "name": true, "value": true, "fromValue": true,
+	"bool": true, "int": true, "double": true, "num": true,
+}
+
+// runtimeModule the runtime library name, which is generated in the root path
+const runtimeModule = "gsrpc"
+
+// _Module the generated library of one gslang package, scripts of the same package share the library
+type _Module struct {
+	imports map[string]string // import prefix -> package name
+	content bytes.Buffer      // library content
+}
+
+type _CodeGen struct {
+	gslogger.Log                     // Log APIs
+	rootpath     string              // root path
+	files        map[string][]byte   // generated files sink, the files are written into rootpath if nil
+	script       *ast.Script         // current script
+	module       *_Module            // current package library
+	modules      map[string]*_Module // generated package libraries
+	tpl          *template.Template  // code generate template
+	skips        []*regexp.Regexp    // skip lists
+	compiler     *gslang.Compiler    // current compiler
+	runtime      bool                // runtime library is generated
+}
+
+// NewCodeGen .
+func NewCodeGen(rootpath string, skips []string) (gslang.Visitor, error) {
+
+	codeGen := &_CodeGen{
+		Log:      gslogger.Get("gen4dart"),
+		rootpath: rootpath,
+		modules:  make(map[string]*_Module),
+	}
+
+	for _, skip := range skips {
+		exp, err := regexp.Compile(skip)
+
+		if err != nil {
+			return nil, gserrors.Newf(err, "invalid skip regex string :%s", skip)
+		}
+
+		codeGen.skips = append(codeGen.skips, exp)
+	}
+
+	funcs := template.FuncMap{
+		"title":        strings.Title,
+		"tableName":    gen.TableName,
+		"fieldName":    fieldName,
+		"constName":    constName,
+		"doc":          doc,
+		"notVoid":      gslang.NotVoid,
+		"isPOD":        gslang.IsPOD,
+		"isAsync":      gslang.IsAsync,
+		"isException":  gslang.IsException,
+		"enumSize":     gslang.EnumSize,
+		"isOptional":   gen.IsOptional,
+		"isNullable":   isNullable,
+		"variant":      gen.Variant,
+		"typeName":     codeGen.typeName,
+		"fieldType":    codeGen.fieldType,
+		"paramType":    codeGen.paramType,
+		"readType":     codeGen.readType,
+		"writeType":    codeGen.writeType,
+		"tagValue":     codeGen.tagValue,
+		"wireCases":    wire.Cases,
+		"wireNested":   wire.Nested,
+		"rpcName":      codeGen.rpcName,
+		"fieldDefault": codeGen.fieldDefault,
+		"initializers": codeGen.initializers,
+		"params":       codeGen.params,
+		"returnType":   codeGen.returnType,
+		"callArgs":     callArgs,
+	}
+
+	tpl, err := template.New("t4dart").Funcs(funcs).Parse(t4dart)
+
+	if err != nil {
+		return nil, err
+	}
+
+	codeGen.tpl = tpl
+
+	return codeGen, nil
+}
+
+// NewCodeGenWithFiles create codegen which write generated files into files map keyed by slash separated relative path
+func NewCodeGenWithFiles(files map[string][]byte, skips []string) (gslang.Visitor, error) {
+
+	codeGen, err := NewCodeGen("", skips)
+
+	if err != nil {
+		return nil, err
+	}
+
+	codeGen.(*_CodeGen).files = files
+
+	return codeGen, nil
+}
+
+// lowerName get the lower camel case name, the leading acronym is lowered as whole, e.g. ID -> id,OSVersion -> osVersion
+func lowerName(name string) string {
+
+	runes := []rune(name)
+
+	for i := 0; i < len(runes) && unicode.IsUpper(runes[i]); i++ {
+
+		if i > 0 && i+1 < len(runes) && unicode.IsLower(runes[i+1]) {
+			break
+		}
+
+		runes[i] = unicode.ToLower(runes[i])
+	}
+
+	return string(runes)
+}
+
+// fieldName get the field/method/param name, dart reserved word is escaped with _ suffix
+func fieldName(name string) string {
+
+	name = lowerName(name)
+
+	if keywords[name] {
+		return name + "_"
+	}
+
+	return name
+}
+
+// constName get the enum constant name
+func constName(name string) string {
+
+	name = lowerName(name)
+
+	if keywords[name] || constKeywords[name] {
+		return name + "_"
+	}
+
+	return name
+}
+
+// moduleAlias get the import prefix of package library
+func moduleAlias(packageName string) string {
+	return strings.Replace(packageName, ".", "_", -1)
+}
+
+// modulePath get the package library file path relative to root path, e.g. com.gsrpc.test -> com/gsrpc/test.dart
+func modulePath(packageName string) string {
+	return filepath.FromSlash(strings.Replace(packageName, ".", "/", -1) + ".dart")
+}
+
+// qualify get the symbol reference name, symbols of other packages are referenced by the import prefix
+func (codegen *_CodeGen) qualify(packageName string, name string) string {
+
+	if packageName == codegen.script.Package {
+		return name
+	}
+
+	alias := moduleAlias(packageName)
+
+	codegen.module.imports[alias] = packageName
+
+	return alias + "." + name
+}
+
+// rpcName get the reference name of com.gsrpc package symbol
+func (codegen *_CodeGen) rpcName(name string) string {
+	return codegen.qualify("com.gsrpc", name)
+}
+
+func (codegen *_CodeGen) tagValue(typeDecl ast.Type) string {
+	return "[" + strings.Join(codegen.tags(typeDecl), ", ") + "]"
+}
+
+// tags get the type tag sequence by the shared tag scheme
+func (codegen *_CodeGen) tags(typeDecl ast.Type) []string {
+
+	tag := codegen.rpcName("Tag")
+
+	return wire.Format(wire.Tags(typeDecl), func(t wire.Tag) string {
+		return tag + "." + constName(t.String()) + ".value"
+	})
+}
+
+// isNullable check if the field value is nullable, the optional field and union field are null if absent
+func isNullable(field *ast.Field) bool {
+	return gen.IsOptional(field) || gen.IsOneOf(field.Type)
+}
+
+func (codegen *_CodeGen) typeName(typeDecl ast.Type) string {
+	switch typeDecl.(type) {
+	case *ast.BuiltinType:
+		builtinType := typeDecl.(*ast.BuiltinType)
+
+		return builtin[builtinType.Type]
+	case *ast.TypeRef:
+		return codegen.typeName(typeDecl.(*ast.TypeRef).Ref)
+
+	case *ast.Enum:
+		return codegen.qualify(typeDecl.Package(), strings.Title(typeDecl.Name()))
+
+	case *ast.Table:
+		name := codegen.qualify(typeDecl.Package(), gen.TableName(typeDecl))
+
+		if gen.IsOneOf(typeDecl) {
+			return name + "?"
+		}
+
+		return name
+
+	case *ast.Seq:
+		seq := typeDecl.(*ast.Seq)
+
+		if gen.IsBytes(seq) {
+			return "Uint8List"
+		}
+
+		if entry, ok := wire.MapEntry(seq); ok {
+			return fmt.Sprintf("Map<%s, %s>", codegen.typeName(entry.Fields[0].Type), codegen.typeName(entry.Fields[1].Type))
+		}
+
+		return fmt.Sprintf("List<%s>", codegen.typeName(seq.Component))
+	}
+
+	gserrors.Panicf(nil, "typeName  error: unsupport type(%s)", typeDecl)
+
+	return "unknown"
+}
+
+// fieldType get the field type, optional field is nullable
+func (codegen *_CodeGen) fieldType(field *ast.Field) string {
+
+	if gen.IsOptional(field) && !gen.IsOneOf(field.Type) {
+		return codegen.typeName(field.Type) + "?"
+	}
+
+	return codegen.typeName(field.Type)
+}
+
+// paramType get the nullable constructor param type of field, the null param is replaced with field default value
+func (codegen *_CodeGen) paramType(field *ast.Field) string {
+
+	if isNullable(field) {
+		return codegen.fieldType(field)
+	}
+
+	return codegen.typeName(field.Type) + "?"
+}
+
+func (codegen *_CodeGen) defaultVal(typeDecl ast.Type) string {
+	switch typeDecl.(type) {
+	case *ast.BuiltinType:
+		builtinType := typeDecl.(*ast.BuiltinType)
+
+		return defaultval[builtinType.Type]
+	case *ast.TypeRef:
+		return codegen.defaultVal(typeDecl.(*ast.TypeRef).Ref)
+
+	case *ast.Enum:
+		enum := typeDecl.(*ast.Enum)
+
+		return codegen.typeName(enum) + "." + constName(enum.Constants[0].Name())
+
+	case *ast.Table:
+		if gen.IsOneOf(typeDecl) {
+			return "null"
+		}
+
+		return codegen.qualify(typeDecl.Package(), gen.TableName(typeDecl)) + "()"
+
+	case *ast.Seq:
+		seq := typeDecl.(*ast.Seq)
+
+		if gen.IsBytes(seq) {
+			if seq.Size != -1 {
+				return fmt.Sprintf("Uint8List(%d)", seq.Size)
+			}
+
+			return "Uint8List(0)"
+		}
+
+		if _, ok := wire.MapEntry(seq); ok {
+			return "const {}"
+		}
+
+		if seq.Size != -1 {
+			return fmt.Sprintf("List.generate(%d, (_) => %s)", seq.Size, codegen.defaultVal(seq.Component))
+		}
+
+		return "const []"
+	}
+
+	gserrors.Panicf(nil, "defaultVal  error: unsupport type(%s)", typeDecl)
+
+	return "unknown"
+}
+
+// readType get the expr which read the type value from the reader variable
+func (codegen *_CodeGen) readType(typeDecl ast.Type) string {
+	switch typeDecl.(type) {
+	case *ast.BuiltinType:
+		builtinType := typeDecl.(*ast.BuiltinType)
+
+		return readMapping[builtinType.Type]
+	case *ast.TypeRef:
+		return codegen.readType(typeDecl.(*ast.TypeRef).Ref)
+
+	case *ast.Enum:
+		return codegen.qualify(typeDecl.Package(), strings.Title(typeDecl.Name())) + ".readFrom(reader)"
+
+	case *ast.Table:
+		return codegen.qualify(typeDecl.Package(), gen.TableName(typeDecl)) + ".readFrom(reader)"
+
+	case *ast.Seq:
+		seq := typeDecl.(*ast.Seq)
+
+		if gen.IsBytes(seq) {
+			if seq.Size != -1 {
+				return fmt.Sprintf("reader.readFixedBytes(%d)", seq.Size)
+			}
+
+			return "reader.readBytes()"
+		}
+
+		if entry, ok := wire.MapEntry(seq); ok {
+			return fmt.Sprintf("reader.readMap(() => %s, () => %s)", codegen.readType(entry.Fields[0].Type), codegen.readType(entry.Fields[1].Type))
+		}
+
+		if seq.Size != -1 {
+			return fmt.Sprintf("reader.readArray(%d, () => %s)", seq.Size, codegen.readType(seq.Component))
+		}
+
+		return fmt.Sprintf("reader.readList(() => %s)", codegen.readType(seq.Component))
+	}
+
+	gserrors.Panicf(nil, "readType  error: unsupport type(%s)", typeDecl)
+
+	return "unknown"
+}
+
+// writeType get the expr which write the valname to the writer variable, depth is used to name the closure args
+func (codegen *_CodeGen) writeType(typeDecl ast.Type, valname string, depth int) string {
+	switch typeDecl.(type) {
+	case *ast.BuiltinType:
+		builtinType := typeDecl.(*ast.BuiltinType)
+
+		return fmt.Sprintf("%s(%s)", writeMapping[builtinType.Type], valname)
+	case *ast.TypeRef:
+		return codegen.writeType(typeDecl.(*ast.TypeRef).Ref, valname, depth)
+
+	case *ast.Enum:
+		return fmt.Sprintf("%s.writeTo(writer, %s)", codegen.qualify(typeDecl.Package(), strings.Title(typeDecl.Name())), valname)
+
+	case *ast.Table:
+		return fmt.Sprintf("%s.writeTo(writer, %s)", codegen.qualify(typeDecl.Package(), gen.TableName(typeDecl)), valname)
+
+	case *ast.Seq:
+		seq := typeDecl.(*ast.Seq)
+
+		if gen.IsBytes(seq) {
+			if seq.Size != -1 {
+				return fmt.Sprintf("writer.writeFixedBytes(%d, %s)", seq.Size, valname)
+			}
+
+			return fmt.Sprintf("writer.writeBytes(%s)", valname)
+		}
+
+		key, val := fmt.Sprintf("k%d", depth), fmt.Sprintf("v%d", depth)
+
+		if entry, ok := wire.MapEntry(seq); ok {
+			return fmt.Sprintf("writer.writeMap(%s, (%s) => %s, (%s) => %s)", valname,
+				key, codegen.writeType(entry.Fields[0].Type, key, depth+1),
+				val, codegen.writeType(entry.Fields[1].Type, val, depth+1))
+		}
+
+		if seq.Size != -1 {
+			return fmt.Sprintf("writer.writeArray(%d, %s, (%s) => %s)", seq.Size, valname, val, codegen.writeType(seq.Component, val, depth+1))
+		}
+
+		return fmt.Sprintf("writer.writeList(%s, (%s) => %s)", valname, val, codegen.writeType(seq.Component, val, depth+1))
+	}
+
+	gserrors.Panicf(nil, "writeType  error: unsupport type(%s)", typeDecl)
+
+	return "unknown"
+}
+
+// fieldDefault get the field initial value expr, nullable field without default value is omitted
+func (codegen *_CodeGen) fieldDefault(field *ast.Field) string {
+
+	expr, ok := gen.Default(field)
+
+	if !ok {
+		if isNullable(field) {
+			return ""
+		}
+
+		return codegen.defaultVal(field.Type)
+	}
+
+	start, _ := gslang.Pos(field)
+
+	return codegen.defaultExpr(expr, field.Type, start)
+}
+
+// initializers get the constructor initializer list, which replace the null params with field default values
+func (codegen *_CodeGen) initializers(table *ast.Table) string {
+
+	var inits []string
+
+	for _, field := range table.Fields {
+
+		val := codegen.fieldDefault(field)
+
+		if val == "" {
+			continue
+		}
+
+		name := fieldName(field.Name())
+
+		inits = append(inits, fmt.Sprintf("%s = %s ?? %s", name, name, val))
+	}
+
+	if len(inits) == 0 {
+		return ""
+	}
+
+	return "  : " + strings.Join(inits, ",\n        ")
+}
+
+func (codegen *_CodeGen) defaultExpr(expr ast.Expr, typeDecl ast.Type, start lexer.Position) string {
+
+	eval := codegen.compiler.Eval()
+
+	switch typeDecl.(type) {
+	case *ast.BuiltinType:
+		builtinType := typeDecl.(*ast.BuiltinType)
+
+		gen.CheckUnsigned(eval, builtinType, expr, start)
+
+		switch builtinType.Type {
+		case lexer.KeyString:
+			return quote(eval.EvalString(expr))
+		case lexer.KeyBool:
+			return strconv.FormatBool(eval.EvalBool(expr))
+		case lexer.KeyFloat32, lexer.KeyFloat64:
+			val := eval.EvalFloat(expr)
+
+			switch {
+			case math.IsNaN(val):
+				return "double.nan"
+			case math.IsInf(val, 1):
+				return "double.infinity"
+			case math.IsInf(val, -1):
+				return "double.negativeInfinity"
+			}
+
+			literal := strconv.FormatFloat(val, 'g', -1, 64)
+
+			if !strings.ContainsAny(literal, ".e") {
+				literal += ".0"
+			}
+
+			return literal
+		case lexer.KeyVoid:
+		default:
+			return fmt.Sprintf("%d", eval.EvalInt(expr))
+		}
+
+	case *ast.TypeRef:
+		return codegen.defaultExpr(expr, typeDecl.(*ast.TypeRef).Ref, start)
+
+	case *ast.Enum:
+		enum := typeDecl.(*ast.Enum)
+
+		val := eval.EvalInt(expr)
+
+		if constant, ok := gen.Constant(enum, val); ok {
+			return codegen.typeName(enum) + "." + constName(constant.Name())
+		}
+
+		// the flag enum accept the combination of constants
+		if gslang.EnumSize(enum) == 4 {
+			return fmt.Sprintf("%s(%d)", codegen.typeName(enum), val)
+		}
+
+		gserrors.Panicf(nil, "enum %s constant(%d) not found :%v", enum, val, start)
+
+	case *ast.Table:
+		table := typeDecl.(*ast.Table)
+
+		newObj, ok := expr.(*ast.NewObj)
+
+		if !ok || gen.IsOneOf(table) {
+			break
+		}
+
+		// the fields without arg keep the constructor defaults
+		var args []string
+
+		for i, field := range table.Fields {
+
+			arg, ok := gen.FieldArg(newObj, i, field)
+
+			if ok {
+				args = append(args, fmt.Sprintf("%s: %s", fieldName(field.Name()), codegen.defaultExpr(arg, field.Type, start)))
+			}
+		}
+
+		return fmt.Sprintf("%s(%s)", codegen.qualify(table.Package(), gen.TableName(table)), strings.Join(args, ", "))
+	}
+
+	gserrors.Panicf(nil, "unsupport default value for type(%s) :%v", typeDecl, start)
+
+	return "unknown"
+}
+
+// quote get the dart string literal, the $ and control characters are escaped
+func quote(val string) string {
+
+	var buff bytes.Buffer
+
+	buff.WriteString("'")
+
+	for _, r := range val {
+		switch {
+		case r == '\'':
+			buff.WriteString("\\'")
+		case r == '\\':
+			buff.WriteString("\\\\")
+		case r == '$':
+			buff.WriteString("\\$")
+		case r == '\n':
+			buff.WriteString("\\n")
+		case r == '\r':
+			buff.WriteString("\\r")
+		case r == '\t':
+			buff.WriteString("\\t")
+		case r < 0x20 || r == 0x7f:
+			buff.WriteString(fmt.Sprintf("\\x%02x", r))
+		default:
+			buff.WriteRune(r)
+		}
+	}
+
+	buff.WriteString("'")
+
+	return buff.String()
+}
+
+func (codegen *_CodeGen) params(params []*ast.Param) string {
+
+	var args []string
+
+	for _, param := range params {
+		args = append(args, fmt.Sprintf("%s %s", codegen.typeName(param.Type), fieldName(param.Name())))
+	}
+
+	return "(" + strings.Join(args, ", ") + ")"
+}
+
+func callArgs(params []*ast.Param) string {
+
+	var args []string
+
+	for _, param := range params {
+		args = append(args, fmt.Sprintf("arg%d", param.ID))
+	}
+
+	return "(" + strings.Join(args, ", ") + ")"
+}
+
+// returnType get the method return type, the async method future is completed after the request is posted
+func (codegen *_CodeGen) returnType(method *ast.Method) string {
+
+	if gslang.IsAsync(method) {
+		return "Future<void>"
+	}
+
+	return fmt.Sprintf("Future<%s>", codegen.typeName(method.Return))
+}
+
+func (codegen *_CodeGen) execute(name string, data interface{}) {
+	if err := codegen.tpl.ExecuteTemplate(&codegen.module.content, name, data); err != nil {
+		gserrors.Panicf(err, "exec template(%s) for %s error", name, data)
+	}
+}
+
+func (codegen *_CodeGen) writeFile(name string, content []byte) {
+
+	// the content buffer may be reused by caller
+	if codegen.files != nil {
+		codegen.files[filepath.ToSlash(name)] = append([]byte(nil), content...)
+		return
+	}
+
+	fullpath := filepath.Join(codegen.rootpath, name)
+
+	if err := os.MkdirAll(filepath.Dir(fullpath), 0755); err != nil {
+		gserrors.Panicf(err, "create output directory error")
+	}
+
+	codegen.D("write file :%s", fullpath)
+
+	if err := ioutil.WriteFile(fullpath, content, 0644); err != nil {
+		gserrors.Panicf(err, "write generate stub code error")
+	}
+}
+
+// importPath get the relative library import path from the current package library
+func (codegen *_CodeGen) importPath(name string) string {
+
+	path, err := filepath.Rel(filepath.Dir(modulePath(codegen.script.Package)), name)
+
+	if err != nil {
+		gserrors.Panicf(err, "get library %s import path error", name)
+	}
+
+	return filepath.ToSlash(path)
+}
+
+func (codegen *_CodeGen) BeginScript(compiler *gslang.Compiler, script *ast.Script) bool {
+
+	scriptPath := filepath.ToSlash(filepath.Clean(script.Name()))
+
+	for _, skip := range codegen.skips {
+
+		if skip.MatchString(scriptPath) {
+
+			return false
+		}
+	}
+
+	if strings.HasPrefix(script.Package, "gslang.") {
+		return false
+	}
+
+	codegen.script = script
+
+	codegen.compiler = compiler
+
+	module, ok := codegen.modules[script.Package]
+
+	if !ok {
+		module = &_Module{imports: make(map[string]string)}
+
+		codegen.modules[script.Package] = module
+	}
+
+	codegen.module = module
+
+	return true
+}
+
+func (codegen *_CodeGen) Using(compiler *gslang.Compiler, using *ast.Using) {
+}
+
+func (codegen *_CodeGen) Table(compiler *gslang.Compiler, tableType *ast.Table) {
+
+	if gen.IsOneOf(tableType) {
+		codegen.execute("union", tableType)
+	} else {
+		codegen.execute("table", tableType)
+	}
+}
+
+func (codegen *_CodeGen) Annotation(compiler *gslang.Compiler, annotation *ast.Table) {
+}
+
+func (codegen *_CodeGen) Enum(compiler *gslang.Compiler, enum *ast.Enum) {
+	codegen.execute("enum", enum)
+}
+
+func (codegen *_CodeGen) Contract(compiler *gslang.Compiler, contract *ast.Contract) {
+	codegen.execute("contract", contract)
+}
+
+// EndScript write the package library, which is rewritten with all the scripts of the package
+func (codegen *_CodeGen) EndScript(compiler *gslang.Compiler) {
+
+	var stream bytes.Buffer
+
+	stream.WriteString("// generate by gs2dart,don't modify it manually\n\n")
+
+	stream.WriteString("import 'dart:typed_data';\n\n")
+
+	stream.WriteString(fmt.Sprintf("import '%s' as %s;\n", codegen.importPath(runtimeModule+".dart"), runtimeModule))
+
+	var aliases []string
+
+	for alias := range codegen.module.imports {
+		aliases = append(aliases, alias)
+	}
+
+	sort.Strings(aliases)
+
+	for _, alias := range aliases {
+		stream.WriteString(fmt.Sprintf("import '%s' as %s;\n", codegen.importPath(modulePath(codegen.module.imports[alias])), alias))
+	}
+
+	stream.Write(codegen.module.content.Bytes())
+
+	codegen.writeFile(modulePath(codegen.script.Package), tidy.Clean(stream.Bytes()))
+
+	if codegen.runtime {
+		return
+	}
+
+	stream.Reset()
+
+	if err := codegen.tpl.ExecuteTemplate(&stream, "runtime", filepath.ToSlash(modulePath("com.gsrpc"))); err != nil {
+		gserrors.Panicf(err, "exec template(runtime) error")
+	}
+
+	codegen.writeFile(runtimeModule+".dart", stream.Bytes())
+
+	codegen.runtime = true
+}
+
+var tidy = gen.NewTidy(1, `[{\[]`, `[}\]]`)
+
+// doc get the /// doc comment lines from the .gs comments of node
+func doc(indent string, node ast.Node) string {
+
+	var buff bytes.Buffer
+
+	for _, line := range gslang.Comments(node) {
+		buff.WriteString(strings.TrimRight(indent+"/// "+strings.TrimSpace(line), " ") + "\n")
+	}
+
+	return buff.String()
+}
@@ -0,0 +1,868 @@
+package gen4dart
+
+var t4dart = `
+{{define "enum"}}{{$Enum := title .Name}}
+{{if enumSize . | eq 4}}
+{{doc "" .}}final class {{$Enum}} implements gsrpc.EnumValue {
+  const {{$Enum}}(this.value);
+{{range .Constants}}
+{{doc "  " .}}  static const {{constName .Name}} = {{$Enum}}({{.Value}});
+{{end}}
+  @override
+  final int value;
+
+  {{$Enum}} operator |({{$Enum}} other) => {{$Enum}}(value | other.value);
+
+  {{$Enum}} operator &({{$Enum}} other) => {{$Enum}}(value & other.value);
+
+  bool contains({{$Enum}} other) => (value & other.value) == other.value;
+
+  @override
+  bool operator ==(Object other) => other is {{$Enum}} && other.value == value;
+
+  @override
+  int get hashCode => value.hashCode;
+
+  @override
+  String toString() => '{{$Enum}}($value)';
+
+  static {{$Enum}} readFrom(gsrpc.Reader reader) => {{$Enum}}(reader.readUInt32());
+
+  static void writeTo(gsrpc.Writer writer, {{$Enum}} val) => writer.writeUInt32(val.value);
+}
+{{else}}
+{{doc "" .}}enum {{$Enum}} implements gsrpc.EnumValue {
+{{range $index, $constant := .Constants}}{{if $index}},
+{{end}}{{doc "  " .}}  {{constName .Name}}({{.Value}}){{end}};
+
+  const {{$Enum}}(this.value);
+
+  @override
+  final int value;
+
+  /// fromValue get the constant of value, throw CodecException if the value is unknown
+  static {{$Enum}} fromValue(int value) {
+    for (final constant in values) {
+      if (constant.value == value) {
+        return constant;
+      }
+    }
+
+    throw gsrpc.CodecException('gsrpc: unknown {{$Enum}} value $value');
+  }
+
+  static {{$Enum}} readFrom(gsrpc.Reader reader) => fromValue(reader.readByte());
+
+  static void writeTo(gsrpc.Writer writer, {{$Enum}} val) => writer.writeByte(val.value);
+}
+{{end}}{{end}}
+
+{{define "table"}}{{$Table := tableName .}}
+{{doc "" .}}class {{$Table}}{{if isException .}} implements Exception{{end}} {
+{{if .Fields}}  {{$Table}}({
+{{range .Fields}}{{if isNullable .}}    this.{{fieldName .Name}},
+{{else}}    {{paramType .}} {{fieldName .Name}},
+{{end}}{{end}}  }){{with initializers .}}
+    {{.}}{{end}};
+{{range .Fields}}
+{{doc "  " .}}  final {{fieldType .}} {{fieldName .Name}};
+{{end}}
+  {{$Table}} copyWith({
+{{range .Fields}}    {{paramType .}} {{fieldName .Name}},
+{{end}}  }) {
+    return {{$Table}}(
+{{range .Fields}}      {{fieldName .Name}}: {{fieldName .Name}} ?? this.{{fieldName .Name}},
+{{end}}    );
+  }
+{{else}}  {{$Table}}();
+{{end}}
+  @override
+  bool operator ==(Object other) =>
+      identical(this, other) ||
+      other is {{$Table}}{{range .Fields}} &&
+          gsrpc.deepEquals(this.{{fieldName .Name}}, other.{{fieldName .Name}}){{end}};
+
+  @override
+  int get hashCode => gsrpc.deepHash([{{range $index, $field := .Fields}}{{if $index}}, {{end}}this.{{fieldName .Name}}{{end}}]);
+{{if isException .}}
+  @override
+  String toString() => '{{.FullName}}';
+{{end}}{{if isPOD .}}
+  static {{$Table}} readFrom(gsrpc.Reader reader) {
+    return {{$Table}}(
+{{range .Fields}}      {{fieldName .Name}}: {{if isOptional .}}reader.readBool() ? {{readType .Type}} : null{{else}}{{readType .Type}}{{end}},
+{{end}}    );
+  }
+
+  static void writeTo(gsrpc.Writer writer, {{$Table}} val) {
+{{range .Fields}}{{if isOptional .}}
+    writer.writeBool(val.{{fieldName .Name}} != null);
+
+    if (val.{{fieldName .Name}} != null) {
+      {{writeType .Type (printf "val.%s!" (fieldName .Name)) 0}};
+    }
+{{else}}
+    {{writeType .Type (printf "val.%s" (fieldName .Name)) 0}};
+{{end}}{{end}}  }
+{{else}}
+  static {{$Table}} readFrom(gsrpc.Reader reader) {
+{{if .Fields}}{{range .Fields}}    {{paramType .}} ${{fieldName .Name}};
+{{end}}
+    final fields = reader.readUInt16();
+
+    for (var i = 0; i < fields; i++) {
+      final tags = reader.readTags();
+
+      if (tags[0] == {{rpcName "Tag"}}.skip.value) {
+        continue;
+      }
+
+      switch (i) {
+{{range $index, $field := .Fields}}        case {{$index}}:
+          ${{fieldName .Name}} = {{readType .Type}};
+{{end}}        default:
+          reader.skip(tags);
+      }
+    }
+
+    return {{$Table}}(
+{{range .Fields}}      {{fieldName .Name}}: ${{fieldName .Name}},
+{{end}}    );
+{{else}}    reader.skipFields(reader.readUInt16());
+
+    return {{$Table}}();
+{{end}}  }
+
+  static void writeTo(gsrpc.Writer writer, {{$Table}} val) {
+    writer.writeUInt16({{len .Fields}});
+{{range .Fields}}{{if isOptional .}}
+    if (val.{{fieldName .Name}} == null) {
+      writer.writeTags([{{rpcName "Tag"}}.skip.value]);
+    } else {
+      writer.writeTags({{tagValue .Type}});
+      {{writeType .Type (printf "val.%s!" (fieldName .Name)) 0}};
+    }
+{{else}}
+    writer.writeTags({{tagValue .Type}});
+    {{writeType .Type (printf "val.%s" (fieldName .Name)) 0}};
+{{end}}{{end}}  }
+{{end}}}
+{{end}}
+
+{{define "union"}}{{$Union := tableName .}}
+{{doc "" .}}sealed class {{$Union}} {
+  const {{$Union}}();
+
+  /// readFrom return null if none variant or unknown variant is read
+  static {{$Union}}? readFrom(gsrpc.Reader reader) {
+    final variant = reader.readByte();
+
+    if (variant == 0) {
+      return null;
+    }
+
+    final tags = reader.readTags();
+
+    switch (variant) {
+{{range $index, $field := .Fields}}      case {{variant $index}}:
+        return {{$Union}}{{title .Name}}({{readType .Type}});
+{{end}}      default:
+        reader.skip(tags);
+        return null;
+    }
+  }
+
+  static void writeTo(gsrpc.Writer writer, {{$Union}}? val) {
+    switch (val) {
+      case null:
+        writer.writeByte(0);
+{{range $index, $field := .Fields}}      case {{$Union}}{{title .Name}}(:final value):
+        writer.writeByte({{variant $index}});
+        writer.writeTags({{tagValue .Type}});
+        {{writeType .Type "value" 0}};
+{{end}}    }
+  }
+}
+{{range $index, $field := .Fields}}
+{{doc "" .}}final class {{$Union}}{{title .Name}} extends {{$Union}} {
+  const {{$Union}}{{title .Name}}(this.value);
+
+  final {{typeName .Type}} value;
+
+  @override
+  bool operator ==(Object other) => other is {{$Union}}{{title .Name}} && gsrpc.deepEquals(value, other.value);
+
+  @override
+  int get hashCode => gsrpc.deepHash([{{variant $index}}, value]);
+}
+{{end}}{{end}}
+
+{{define "contract"}}{{$Contract := title .Name}}
+{{doc "" .}}abstract interface class {{$Contract}} {
+{{range .Methods}}{{doc "  " .}}  {{returnType .}} {{fieldName .Name}}{{params .Params}};
+{{end}}}
+
+/// nameOf{{$Contract}} the {{$Contract}} contract full name
+const nameOf{{$Contract}} = '{{.FullName}}';
+
+/// {{$Contract}}Dispatcher dispatch the remote calls to {{$Contract}} service
+class {{$Contract}}Dispatcher implements gsrpc.Dispatcher {
+  {{$Contract}}Dispatcher(this.id, this.service);
+
+  @override
+  final int id;
+
+  final {{$Contract}} service;
+
+  @override
+  String toString() => nameOf{{$Contract}};
+
+  @override
+  Future<{{rpcName "Response"}}?> dispatch({{rpcName "Request"}} call) async {
+    switch (call.method) {
+{{range .Methods}}      case {{.ID}}:
+        {
+          if (call.params.length != {{.ParamsCount}}) {
+            throw ArgumentError('{{$Contract}}#{{title .Name}} expect {{.ParamsCount}} params but got :${call.params.length}');
+          }
+{{range .Params}}
+          final arg{{.ID}} = gsrpc.unmarshal(call.params[{{.ID}}].content, (reader) => {{readType .Type}});
+{{end}}
+{{if isAsync .}}
+          await service.{{fieldName .Name}}{{callArgs .Params}};
+
+          return null;
+{{else if .Exceptions}}
+          try {
+            {{if notVoid .Return}}final ret = {{end}}await service.{{fieldName .Name}}{{callArgs .Params}};
+
+            return gsrpc.response(call, -1, {{if notVoid .Return}}gsrpc.marshal((writer) => {{writeType .Return "ret" 0}}){{else}}Uint8List(0){{end}});
+          }{{range .Exceptions}} on {{typeName .Type}} catch (err) {
+            return gsrpc.response(call, {{.ID}}, gsrpc.marshal((writer) => {{writeType .Type "err" 0}}));
+          }{{end}}
+{{else}}
+          {{if notVoid .Return}}final ret = {{end}}await service.{{fieldName .Name}}{{callArgs .Params}};
+
+          return gsrpc.response(call, -1, {{if notVoid .Return}}gsrpc.marshal((writer) => {{writeType .Return "ret" 0}}){{else}}Uint8List(0){{end}});
+{{end}}        }
+{{end}}      default:
+        throw ArgumentError('unknown {{$Contract}}#${call.method} method');
+    }
+  }
+}
+
+/// {{$Contract}}RPC the remote {{$Contract}} service proxy
+class {{$Contract}}RPC implements {{$Contract}} {
+  {{$Contract}}RPC(this.channel, this.serviceID, {this.timeout = const gsrpc.Timeout(seconds: 5)});
+
+  final gsrpc.Channel channel;
+
+  final int serviceID;
+
+  final gsrpc.Timeout timeout;
+{{range .Methods}}
+  @override
+  {{returnType .}} {{fieldName .Name}}{{params .Params}} async {
+    final call = {{rpcName "Request"}}(
+      service: this.serviceID,
+      method: {{.ID}},
+      params: [{{range .Params}}
+        {{rpcName "Param"}}(content: gsrpc.marshal((writer) => {{writeType .Type (fieldName .Name) 0}})),{{end}}
+      ],
+    );
+{{if isAsync .}}
+    this.channel.post(call);
+{{else}}
+    final callReturn = await this.channel.call(call, this.timeout);
+
+    if (callReturn.exception != -1) {
+      switch (callReturn.exception) {
+        case -2:
+          throw gsrpc.unmarshal(callReturn.content, (reader) => {{rpcName "InvalidArgumentException"}}.readFrom(reader));
+{{range .Exceptions}}        case {{.ID}}:
+          throw gsrpc.unmarshal(callReturn.content, (reader) => {{readType .Type}});
+{{end}}        default:
+          throw {{rpcName "RemoteException"}}();
+      }
+    }
+{{if notVoid .Return}}
+    return gsrpc.unmarshal(callReturn.content, (reader) => {{readType .Return}});
+{{end}}{{end}}  }
+{{end}}}
+{{end}}
+
+{{define "runtime"}}// generate by gs2dart,don't modify it manually
+//
+// gsrpc runtime: the ByteData based Reader/Writer and the Future based Channel over Transport,
+// requires dart 3, the 64 bits integer codec is not supported on the web platform
+
+import 'dart:async';
+import 'dart:convert';
+import 'dart:typed_data';
+
+import '{{.}}' as rpc;
+
+/// Timeout the rpc call timeout, which is the alias of dart:core Duration
+typedef Timeout = Duration;
+
+/// CodecException the gsrpc encode/decode error
+class CodecException implements Exception {
+  CodecException(this.message);
+
+  final String message;
+
+  @override
+  String toString() => message;
+}
+
+/// EnumValue the generated enum, which is encoded as the value
+abstract interface class EnumValue {
+  int get value;
+}
+
+/// Reader read gsrpc values from the input buffer
+class Reader {
+  Reader(Uint8List buff) : _view = ByteData.sublistView(buff);
+
+  final ByteData _view;
+
+  int _offset = 0;
+
+  int _next(int length) {
+    final offset = _offset;
+
+    if (offset + length > _view.lengthInBytes) {
+      throw CodecException('gsrpc: read beyond the end of buffer');
+    }
+
+    _offset += length;
+
+    return offset;
+  }
+
+  int readByte() => _view.getUint8(_next(1));
+
+  int readSByte() => _view.getInt8(_next(1));
+
+  bool readBool() => readByte() != 0;
+
+  int readInt16() => _view.getInt16(_next(2), Endian.little);
+
+  int readUInt16() => _view.getUint16(_next(2), Endian.little);
+
+  int readInt32() => _view.getInt32(_next(4), Endian.little);
+
+  int readUInt32() => _view.getUint32(_next(4), Endian.little);
+
+  int readInt64() => _view.getInt64(_next(8), Endian.little);
+
+  /// readUInt64 the values above 2^63-1 are wrapped to negative integers
+  int readUInt64() => _view.getUint64(_next(8), Endian.little);
+
+  double readFloat32() => _view.getFloat32(_next(4), Endian.little);
+
+  double readFloat64() => _view.getFloat64(_next(8), Endian.little);
+
+  Uint8List readBytes() {
+    final length = readUInt16();
+
+    final offset = _next(length);
+
+    return Uint8List.fromList(Uint8List.sublistView(_view, offset, offset + length));
+  }
+
+  Uint8List readFixedBytes(int size) {
+    final val = readBytes();
+
+    if (val.length != size) {
+      throw CodecException('gsrpc: check array size failed, expect $size got ${val.length}');
+    }
+
+    return val;
+  }
+
+  String readString() => utf8.decode(readBytes());
+
+  List<T> readList<T>(T Function() read) {
+    final length = readUInt16();
+
+    return [for (var i = 0; i < length; i++) read()];
+  }
+
+  List<T> readArray<T>(int size, T Function() read) {
+    final val = readList(read);
+
+    if (val.length != size) {
+      throw CodecException('gsrpc: check array size failed, expect $size got ${val.length}');
+    }
+
+    return val;
+  }
+
+  Map<K, V> readMap<K, V>(K Function() readKey, V Function() readValue) {
+    final length = readUInt16();
+
+    final val = <K, V>{};
+
+    for (var i = 0; i < length; i++) {
+      final key = readKey();
+
+      val[key] = readValue();
+    }
+
+    return val;
+  }
+
+  /// readTags read the tag sequence of tagged value, the nested container and POD tags are followed by their component tags
+  List<int> readTags() {
+    final tag = readByte();
+
+    final tags = [tag];
+
+    var nested = 0;
+
+{{range $i, $case := wireNested}}    {{if $i}}} else {{end}}if ({{range $j, $tag := .Tags}}{{if $j}} || {{end}}tag == rpc.Tag.{{constName (printf "%s" $tag)}}.value{{end}}) {
+{{if lt .Nested 0}}      nested = readByte();
+      tags.add(nested);
+{{else}}      nested = {{.Nested}};
+{{end}}{{end}}    }
+
+    for (var i = 0; i < nested; i++) {
+      tags.addAll(readTags());
+    }
+
+    return tags;
+  }
+
+  /// skip skip the value described by the tag sequence
+  void skip(List<int> tags) {
+    _skipTags(tags, 0);
+  }
+
+  /// skipFields skip the unknown fields of tagged table
+  void skipFields(int fields) {
+    for (var i = 0; i < fields; i++) {
+      skip(readTags());
+    }
+  }
+
+  // _skipComponents skip the values of count tag sequences begin with index
+  void _skipComponents(List<int> tags, int index, int count) {
+    for (var i = 0; i < count; i++) {
+      _skipTags(tags, index);
+      index = _tagsEnd(tags, index);
+    }
+  }
+
+  void _skipTags(List<int> tags, int index) {
+    final tag = tags[index];
+
+{{range $i, $case := wireCases}}    {{if $i}}} else {{end}}if ({{range $j, $tag := .Tags}}{{if $j}} || {{end}}tag == rpc.Tag.{{constName (printf "%s" $tag)}}.value{{end}}) {
+{{if eq .Kind "none"}}      // no value
+{{else if eq .Kind "fixed"}}      _next({{.Size}});
+{{else if eq .Kind "sized"}}      _next(readUInt16());
+{{else if eq .Kind "fields"}}      skipFields(readUInt16());
+{{else if eq .Kind "seq"}}      final length = readUInt16();
+
+      for (var i = 0; i < length; i++) {
+        _skipComponents(tags, index + 1, {{.Nested}});
+      }
+{{else if eq .Kind "record"}}      _skipComponents(tags, index + 2, tags[index + 1]);
+{{else if eq .Kind "optional"}}      if (readBool()) {
+        _skipTags(tags, index + 1);
+      }
+{{else if eq .Kind "variant"}}      if (readByte() != 0) {
+        skip(readTags());
+      }
+{{end}}{{end}}    } else {
+      throw CodecException('gsrpc: unknown tag $tag');
+    }
+  }
+}
+
+/// _tagsEnd get the index next to the tag sequence begin with index
+int _tagsEnd(List<int> tags, int index) {
+  final tag = tags[index];
+
+  var next = index + 1;
+
+  var nested = 0;
+
+{{range $i, $case := wireNested}}  {{if $i}}} else {{end}}if ({{range $j, $tag := .Tags}}{{if $j}} || {{end}}tag == rpc.Tag.{{constName (printf "%s" $tag)}}.value{{end}}) {
+{{if lt .Nested 0}}    next = index + 2;
+    nested = tags[index + 1];
+{{else}}    nested = {{.Nested}};
+{{end}}{{end}}  }
+
+  for (var i = 0; i < nested; i++) {
+    next = _tagsEnd(tags, next);
+  }
+
+  return next;
+}
+
+/// Writer write gsrpc values into the growable output buffer
+class Writer {
+  Uint8List _buff = Uint8List(64);
+
+  late ByteData _view = ByteData.sublistView(_buff);
+
+  int _length = 0;
+
+  int _next(int length) {
+    final offset = _length;
+
+    if (offset + length > _buff.length) {
+      var capacity = _buff.length * 2;
+
+      while (capacity < offset + length) {
+        capacity *= 2;
+      }
+
+      _buff = Uint8List(capacity)..setRange(0, offset, _buff);
+
+      _view = ByteData.sublistView(_buff);
+    }
+
+    _length += length;
+
+    return offset;
+  }
+
+  void writeByte(int val) => _view.setUint8(_next(1), val);
+
+  void writeSByte(int val) => _view.setInt8(_next(1), val);
+
+  void writeBool(bool val) => writeByte(val ? 1 : 0);
+
+  void writeInt16(int val) => _view.setInt16(_next(2), val, Endian.little);
+
+  void writeUInt16(int val) => _view.setUint16(_next(2), val, Endian.little);
+
+  void writeInt32(int val) => _view.setInt32(_next(4), val, Endian.little);
+
+  void writeUInt32(int val) => _view.setUint32(_next(4), val, Endian.little);
+
+  void writeInt64(int val) => _view.setInt64(_next(8), val, Endian.little);
+
+  void writeUInt64(int val) => _view.setUint64(_next(8), val, Endian.little);
+
+  void writeFloat32(double val) => _view.setFloat32(_next(4), val, Endian.little);
+
+  void writeFloat64(double val) => _view.setFloat64(_next(8), val, Endian.little);
+
+  void writeBytes(List<int> val) {
+    writeUInt16(val.length);
+
+    final offset = _next(val.length);
+
+    _buff.setRange(offset, offset + val.length, val);
+  }
+
+  void writeFixedBytes(int size, List<int> val) {
+    if (val.length != size) {
+      throw CodecException('gsrpc: check array size failed, expect $size got ${val.length}');
+    }
+
+    writeBytes(val);
+  }
+
+  void writeString(String val) => writeBytes(utf8.encode(val));
+
+  void writeTags(List<int> tags) {
+    for (final tag in tags) {
+      writeByte(tag);
+    }
+  }
+
+  void writeList<T>(List<T> val, void Function(T) write) {
+    writeUInt16(val.length);
+
+    for (final v in val) {
+      write(v);
+    }
+  }
+
+  void writeArray<T>(int size, List<T> val, void Function(T) write) {
+    if (val.length != size) {
+      throw CodecException('gsrpc: check array size failed, expect $size got ${val.length}');
+    }
+
+    writeList(val, write);
+  }
+
+  /// writeMap write map entries in key order, so the same map is always encoded as same bytes
+  void writeMap<K, V>(Map<K, V> val, void Function(K) writeKey, void Function(V) writeValue) {
+    final keys = val.keys.toList()..sort(_compareKeys);
+
+    writeUInt16(keys.length);
+
+    for (final key in keys) {
+      writeKey(key);
+      writeValue(val[key] as V);
+    }
+  }
+
+  Uint8List content() => Uint8List.fromList(Uint8List.sublistView(_buff, 0, _length));
+}
+
+/// _compareKeys compare map keys as the go implementation, the strings are compared by the utf8 bytes order
+int _compareKeys(Object? a, Object? b) {
+  if (a is String && b is String) {
+    final x = a.runes.iterator, y = b.runes.iterator;
+
+    while (true) {
+      final hasX = x.moveNext(), hasY = y.moveNext();
+
+      if (!hasX || !hasY) {
+        return (hasX ? 1 : 0) - (hasY ? 1 : 0);
+      }
+
+      if (x.current != y.current) {
+        return x.current - y.current;
+      }
+    }
+  }
+
+  if (a is EnumValue && b is EnumValue) {
+    return a.value.compareTo(b.value);
+  }
+
+  if (a is bool && b is bool) {
+    return (a ? 1 : 0) - (b ? 1 : 0);
+  }
+
+  return (a as Comparable).compareTo(b);
+}
+
+/// marshal encode value by the write function
+Uint8List marshal(void Function(Writer writer) write) {
+  final writer = Writer();
+
+  write(writer);
+
+  return writer.content();
+}
+
+/// unmarshal decode value from content by the read function
+T unmarshal<T>(Uint8List content, T Function(Reader reader) read) => read(Reader(content));
+
+/// deepEquals compare the values, the lists and maps are compared by elements
+bool deepEquals(Object? a, Object? b) {
+  if (a is List && b is List) {
+    if (a.length != b.length) {
+      return false;
+    }
+
+    for (var i = 0; i < a.length; i++) {
+      if (!deepEquals(a[i], b[i])) {
+        return false;
+      }
+    }
+
+    return true;
+  }
+
+  if (a is Map && b is Map) {
+    if (a.length != b.length) {
+      return false;
+    }
+
+    for (final key in a.keys) {
+      if (!b.containsKey(key) || !deepEquals(a[key], b[key])) {
+        return false;
+      }
+    }
+
+    return true;
+  }
+
+  return a == b;
+}
+
+/// deepHash get the hash code consistent with deepEquals
+int deepHash(Object? val) {
+  if (val is List) {
+    return Object.hashAll(val.map(deepHash));
+  }
+
+  if (val is Map) {
+    return Object.hashAllUnordered(val.entries.map((entry) => Object.hash(deepHash(entry.key), deepHash(entry.value))));
+  }
+
+  return val.hashCode;
+}
+
+/// response create the response of call
+rpc.Response response(rpc.Request call, int exception, Uint8List content) {
+  return rpc.Response(id: call.id, exception: exception, content: content, trace: call.trace);
+}
+
+/// Transport the message transport of channel, one message per send and onMessage callback
+abstract class Transport {
+  void Function(Uint8List data)? onMessage;
+
+  void Function(Object reason)? onClose;
+
+  void send(Uint8List data);
+
+  void close();
+}
+
+/// StreamTransport the transport over the binary message stream and sink, e.g. the dart:io WebSocket
+/// or the web_socket_channel stream and sink, each binary message carries one gsrpc message
+class StreamTransport extends Transport {
+  StreamTransport(Stream<dynamic> stream, this._sink) {
+    _subscription = stream.listen(
+      (data) {
+        if (data is List<int>) {
+          onMessage?.call(data is Uint8List ? data : Uint8List.fromList(data));
+        }
+      },
+      onError: (Object err) => onClose?.call(err),
+      onDone: () => onClose?.call(StateError('gsrpc: transport closed')),
+    );
+  }
+
+  final StreamSink<dynamic> _sink;
+
+  late final StreamSubscription<dynamic> _subscription;
+
+  @override
+  void send(Uint8List data) => _sink.add(data);
+
+  @override
+  void close() {
+    _subscription.cancel();
+    _sink.close();
+  }
+}
+
+/// Dispatcher the service dispatcher registered into channel by service id
+abstract interface class Dispatcher {
+  int get id;
+
+  Future<rpc.Response?> dispatch(rpc.Request call);
+}
+
+/// Channel the rpc channel which send requests to and dispatch requests from the peer
+class Channel {
+  Channel(this._transport) {
+    _transport.onMessage = _onMessage;
+    _transport.onClose = _onClose;
+  }
+
+  final Transport _transport;
+
+  int _seq = 0;
+
+  final _pending = <int, Completer<rpc.Response>>{};
+
+  final _dispatchers = <int, Dispatcher>{};
+
+  void Function(Object err, StackTrace stackTrace) _errorHandler = (err, stackTrace) {
+    Zone.current.handleUncaughtError(err, stackTrace);
+  };
+
+  void register(Dispatcher dispatcher) {
+    _dispatchers[dispatcher.id] = dispatcher;
+  }
+
+  void unregister(int id) {
+    _dispatchers.remove(id);
+  }
+
+  /// onError set the handler of dispatch errors
+  void onError(void Function(Object err, StackTrace stackTrace) handler) {
+    _errorHandler = handler;
+  }
+
+  /// call send the request and wait the response, the future is completed with TimeoutException after timeout
+  Future<rpc.Response> call(rpc.Request call, Timeout timeout) async {
+    _seq = (_seq + 1) & 0xFFFFFFFF;
+
+    final id = _seq;
+
+    final completer = Completer<rpc.Response>();
+
+    _pending[id] = completer;
+
+    try {
+      _send(rpc.Code.request, marshal((writer) => rpc.Request.writeTo(writer, call.copyWith(id: id))));
+
+      return await completer.future.timeout(timeout, onTimeout: () {
+        throw TimeoutException('gsrpc: rpc call($id) timeout', timeout);
+      });
+    } finally {
+      _pending.remove(id);
+    }
+  }
+
+  /// post send the request without waiting response
+  void post(rpc.Request call) {
+    _send(rpc.Code.request, marshal((writer) => rpc.Request.writeTo(writer, call)));
+  }
+
+  void close() {
+    _transport.close();
+  }
+
+  void _send(rpc.Code code, Uint8List content) {
+    final message = rpc.Message(code: code, agent: 0, content: content);
+
+    _transport.send(marshal((writer) => rpc.Message.writeTo(writer, message)));
+  }
+
+  void _onMessage(Uint8List data) {
+    final message = unmarshal(data, rpc.Message.readFrom);
+
+    switch (message.code) {
+      case rpc.Code.response:
+        final callReturn = unmarshal(message.content, rpc.Response.readFrom);
+
+        final completer = _pending.remove(callReturn.id);
+
+        if (completer != null && !completer.isCompleted) {
+          completer.complete(callReturn);
+        }
+      case rpc.Code.request:
+        _dispatch(unmarshal(message.content, rpc.Request.readFrom));
+      case rpc.Code.heartbeat:
+        _send(rpc.Code.heartbeat, Uint8List(0));
+      default:
+        break;
+    }
+  }
+
+  Future<void> _dispatch(rpc.Request call) async {
+    final dispatcher = _dispatchers[call.service];
+
+    if (dispatcher == null) {
+      return;
+    }
+
+    try {
+      final callReturn = await dispatcher.dispatch(call);
+
+      if (callReturn != null) {
+        _send(rpc.Code.response, marshal((writer) => rpc.Response.writeTo(writer, callReturn)));
+      }
+    } catch (err, stackTrace) {
+      _errorHandler(err, stackTrace);
+    }
+  }
+
+  void _onClose(Object reason) {
+    final pending = _pending.values.toList();
+
+    _pending.clear();
+
+    for (final completer in pending) {
+      if (!completer.isCompleted) {
+        completer.completeError(reason);
+      }
+    }
+  }
+}
+{{end}}
+`
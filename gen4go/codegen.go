@@ -7,6 +7,8 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 	"text/template"
 
@@ -18,6 +20,8 @@ import (
 	"github.com/gsrpc/gslang"
 	"github.com/gsrpc/gslang/ast"
 	"github.com/gsrpc/gslang/lexer"
+	"github.com/gsrpc/gsrpc/gen"
+	"github.com/gsrpc/gsrpc/wire"
 )
 
 var builtin = map[lexer.TokenType]string{
@@ -85,12 +89,18 @@ var imports = map[string]string{
 	"trace.":    "github.com/gsrpc/gorpc/trace",
 	"fmt.":      "fmt",
 	"bytes.":    "bytes",
+	"sort.":     "sort",
+	"json.":     "encoding/json",
+	"strconv.":  "strconv",
+	"strings.":  "strings",
+	"regexp.":   "regexp",
 	"gserrors.": "github.com/gsdocker/gserrors",
 }
 
 type _CodeGen struct {
 	gslogger.Log                    // Log APIs
 	rootpath     string             // root path
+	files        map[string][]byte  // generated files sink, the files are written into rootpath if nil
 	script       *ast.Script        // current script
 	header       bytes.Buffer       // header writer
 	content      bytes.Buffer       // content writer
@@ -99,14 +109,35 @@ type _CodeGen struct {
 	packageName  string             // package name
 	scriptPath   string             // script path
 	skips        []*regexp.Regexp   // skip lists
+	compiler     *gslang.Compiler   // current compiler
+	goImports    map[string]string  // import path to alias of @GoType custom types and codecs
 }
 
 // NewCodeGen .
 func NewCodeGen(rootpath string, skips []string) (gslang.Visitor, error) {
+	return NewCodeGenWithTemplates(rootpath, skips, "")
+}
+
+// NewCodeGenWithTemplates create golang codegen with user template files(*.tpl) in templateDir,
+// which can override or extend the builtin define blocks. The user template named
+// {enum|table|contract}_file:{suffix} is executed for each type of the kind and
+// written as extra file {lower type name}{suffix} into the package directory
+func NewCodeGenWithTemplates(rootpath string, skips []string, templateDir string) (gslang.Visitor, error) {
+	return newCodeGen(rootpath, nil, skips, templateDir)
+}
+
+// NewCodeGenWithFiles create golang codegen which write generated files into files map keyed by slash separated relative path,
+// the user templates in templateDir are loaded as NewCodeGenWithTemplates if templateDir is not empty
+func NewCodeGenWithFiles(files map[string][]byte, skips []string, templateDir string) (gslang.Visitor, error) {
+	return newCodeGen("", files, skips, templateDir)
+}
+
+func newCodeGen(rootpath string, files map[string][]byte, skips []string, templateDir string) (gslang.Visitor, error) {
 
 	codeGen := &_CodeGen{
 		Log:      gslogger.Get("gen4go"),
 		rootpath: rootpath,
+		files:    files,
 	}
 
 	for _, skip := range skips {
@@ -134,11 +165,48 @@ func NewCodeGen(rootpath string, skips []string) (gslang.Visitor, error) {
 		"defaultVal":  codeGen.defaultVal,
 		"readType":    codeGen.readType,
 		"writeType":   codeGen.writeType,
-		"params":      codeGen.params,
-		"returnParam": codeGen.returnParam,
-		"callArgs":    codeGen.callArgs,
-		"returnArgs":  codeGen.returnArgs,
-		"tagValue":    codeGen.tagValue,
+		"equalType":   codeGen.equalType,
+		"cloneType":   codeGen.cloneType,
+		"isScalar":    isScalar,
+		"jsonSafe":    jsonSafe,
+		"jsonEncode":  codeGen.jsonEncode,
+		"jsonDecode":  codeGen.jsonDecode,
+		"jsonTag":     codeGen.jsonTag,
+		"goTag":       codeGen.goTag,
+		"doc":         doc,
+		"isFlag":      isFlag,
+		"isStrict":    isStrict,
+		"isOneOf":     isOneOf,
+		"customType": func(table *ast.Table) string {
+			name, _ := codeGen.customType(table)
+			return name
+		},
+		"customCodec": func(table *ast.Table) string {
+			_, codec := codeGen.customType(table)
+			return codec
+		},
+		"tableName":   codeGen.tableName,
+		"annotations": codeGen.annotations,
+		"packageName": func() string {
+			return filepath.Base(strings.Replace(codeGen.packageName, ".", "/", -1))
+		},
+		"unionFunc":      codeGen.unionFunc,
+		"validateType":   codeGen.validateType,
+		"validateField":  codeGen.validateField,
+		"validateParams": codeGen.validateParams,
+		"patternDecl":    codeGen.patternDecl,
+		"params":         codeGen.params,
+		"returnParam":    codeGen.returnParam,
+		"callArgs":       codeGen.callArgs,
+		"returnArgs":     codeGen.returnArgs,
+		"tagValue":       codeGen.tagValue,
+		"mapKey":         mapKey,
+		"mapValue":       mapValue,
+		"isOptional":     isOptional,
+		"isPointer":      isPointer,
+		"fieldType":      codeGen.fieldType,
+		"variant":        variant,
+		"fieldDefault":   codeGen.fieldDefault,
 	}
 
 	tpl, err := template.New("gen4go").Funcs(funcs).Parse(tpl4go)
@@ -147,58 +215,248 @@ func NewCodeGen(rootpath string, skips []string) (gslang.Visitor, error) {
 		return nil, err
 	}
 
+	if templateDir != "" {
+
+		files, err := filepath.Glob(filepath.Join(templateDir, "*.tpl"))
+
+		if err != nil {
+			return nil, gserrors.Newf(err, "invalid template dir :%s", templateDir)
+		}
+
+		if len(files) == 0 {
+			return nil, gserrors.Newf(nil, "template file(*.tpl) not found in :%s", templateDir)
+		}
+
+		tpl, err = tpl.ParseFiles(files...)
+
+		if err != nil {
+			return nil, gserrors.Newf(err, "parse user template files error")
+		}
+	}
+
 	codeGen.tpl = tpl
 
 	return codeGen, nil
 }
 
 func (codegen *_CodeGen) tagValue(typeDecl ast.Type) string {
-	switch typeDecl.(type) {
-	case *ast.BuiltinType:
-		builtinType := typeDecl.(*ast.BuiltinType)
+	return strings.Join(codegen.tags(typeDecl), ",")
+}
 
-		switch builtinType.Type {
-		case lexer.KeySByte, lexer.KeyByte, lexer.KeyBool:
-			return "gorpc.TagI8"
-		case lexer.KeyInt16, lexer.KeyUInt16:
-			return "gorpc.TagI16"
-		case lexer.KeyInt32, lexer.KeyUInt32, lexer.KeyFloat32:
-			return "gorpc.TagI32"
-		case lexer.KeyInt64, lexer.KeyUInt64, lexer.KeyFloat64:
-			return "gorpc.TagI64"
-		case lexer.KeyString:
-			return "gorpc.TagString"
-		}
+// tags get the type tag sequence by the shared tag scheme
+func (codegen *_CodeGen) tags(typeDecl ast.Type) []string {
+	return wire.Format(wire.Tags(typeDecl), func(tag wire.Tag) string {
+		return fmt.Sprintf("byte(gorpc.Tag%s)", tag)
+	})
+}
 
-	case *ast.TypeRef:
-		return codegen.tagValue(typeDecl.(*ast.TypeRef).Ref)
-	case *ast.Enum:
+func mapKey(seq *ast.Seq) ast.Type {
+	entry, _ := wire.MapEntry(seq)
 
-		if gslang.EnumSize(typeDecl) == 4 {
-			return "gorpc.TagI32"
-		}
+	return entry.Fields[0].Type
+}
+
+func mapValue(seq *ast.Seq) ast.Type {
+	entry, _ := wire.MapEntry(seq)
+
+	return entry.Fields[1].Type
+}
+
+// isOptional check if the field is annotated with @Optional
+func isOptional(field *ast.Field) bool {
+	_, ok := gslang.FindAnnotation(field, "com.gsrpc.Optional")
+
+	return ok
+}
+
+// isPointer check if the optional field value is generated as pointer type
+func isPointer(field *ast.Field) bool {
 
-		return "gorpc.TagI8"
+	if !isOptional(field) {
+		return false
+	}
 
+	typeDecl := field.Type
+
+	if typeRef, ok := typeDecl.(*ast.TypeRef); ok {
+		typeDecl = typeRef.Ref
+	}
+
+	switch typeDecl.(type) {
+	case *ast.BuiltinType, *ast.Enum:
+		return true
 	case *ast.Table:
-		return "gorpc.TagTable"
+		// custom go type is value type
+		return isCustom(typeDecl)
 	case *ast.Seq:
+		// fixed size array is value type, and the present empty list or map must differ from absent one
+		return true
+	}
 
-		seq := typeDecl.(*ast.Seq)
+	return false
+}
+
+// isFlag check if the enum is annotated with @Flag
+func isFlag(enum *ast.Enum) bool {
+	_, ok := gslang.FindAnnotation(enum, "gslang.Flag")
 
-		component := codegen.tagValue(seq.Component)
+	return ok
+}
+
+// isStrict check if the enum is annotated with @Strict
+func isStrict(enum *ast.Enum) bool {
+	_, ok := gslang.FindAnnotation(enum, "com.gsrpc.Strict")
 
-		if component == "gorpc.TagList" {
-			start, _ := gslang.Pos(typeDecl)
-			gserrors.Panicf(nil, "list component %v can't be a list :%v", seq.Component, start)
+	return ok
+}
+
+// isCustom check if the table is annotated with @GoType, which is mapped to user supplied go type
+func isCustom(typeDecl ast.Type) bool {
+	if typeRef, ok := typeDecl.(*ast.TypeRef); ok {
+		typeDecl = typeRef.Ref
+	}
+
+	if _, ok := typeDecl.(*ast.Table); !ok {
+		return false
+	}
+
+	_, ok := gslang.FindAnnotation(typeDecl, "com.gsrpc.GoType")
+
+	return ok
+}
+
+// goImport get the qualified name of go type or value declared as full import path, e.g. github.com/google/uuid.UUID,
+// and record the import path
+func (codegen *_CodeGen) goImport(fullname string) string {
+
+	pointer := ""
+
+	if strings.HasPrefix(fullname, "*") {
+		pointer, fullname = "*", fullname[1:]
+	}
+
+	index := strings.LastIndex(fullname, ".")
+
+	if index == -1 {
+		return pointer + fullname
+	}
+
+	return pointer + codegen.importAlias(fullname[:index]) + fullname[index:]
+}
+
+var aliasRegex = regexp.MustCompile(`[^A-Za-z0-9_]`)
+
+var versionRegex = regexp.MustCompile(`^v[0-9]+$`)
+
+// importAlias get the unique alias of import path, the alias is derived from the last path element
+// and numbered if it's used by other import
+func (codegen *_CodeGen) importAlias(path string) string {
+
+	if alias, ok := codegen.goImports[path]; ok {
+		return alias
+	}
+
+	used := make(map[string]bool)
+
+	for k, v := range codegen.imports {
+		if v == path {
+			return strings.TrimSuffix(k, ".")
 		}
 
-		return fmt.Sprintf("(%s << 4)|gorpc.TagList", component)
+		used[strings.TrimSuffix(k, ".")] = true
 	}
 
-	gserrors.Panicf(nil, "typeName  error: unsupport type(%s)", typeDecl)
+	for _, alias := range codegen.goImports {
+		used[alias] = true
+	}
 
-	return ""
+	elements := strings.Split(path, "/")
+
+	name := elements[len(elements)-1]
+
+	if versionRegex.MatchString(name) && len(elements) > 1 {
+		name = elements[len(elements)-2]
+	}
+
+	name = aliasRegex.ReplaceAllString(strings.Split(name, ".")[0], "_")
+
+	if name == "" || (name[0] >= '0' && name[0] <= '9') {
+		name = "_" + name
+	}
+
+	alias := name
+
+	for i := 2; used[alias]; i++ {
+		alias = fmt.Sprintf("%s%d", name, i)
+	}
+
+	codegen.goImports[path] = alias
+
+	return alias
+}
+
+// customType get the user supplied go type and codec of @GoType annotated table,
+// the codec value must implement Encode(val T) (*Table, error) and Decode(val *Table) (T, error)
+func (codegen *_CodeGen) customType(table *ast.Table) (name string, codec string) {
+
+	annotation, _ := gslang.FindAnnotation(table, "com.gsrpc.GoType")
+
+	start, _ := gslang.Pos(table)
+
+	typeExpr, ok := gen.Arg(annotation, 0, "Type")
+
+	if !ok {
+		gserrors.Panicf(nil, "expect @GoType(Type:\"pkg.Type\",Codec:\"pkg.Codec\") for %s :%v", table, start)
+	}
+
+	codecExpr, ok := gen.Arg(annotation, 1, "Codec")
+
+	if !ok {
+		gserrors.Panicf(nil, "expect @GoType(Type:\"pkg.Type\",Codec:\"pkg.Codec\") for %s :%v", table, start)
+	}
+
+	if isOneOf(table) {
+		gserrors.Panicf(nil, "union %s can't be mapped to custom go type :%v", table, start)
+	}
+
+	eval := codegen.compiler.Eval()
+
+	return codegen.goImport(eval.EvalString(typeExpr)), codegen.goImport(eval.EvalString(codecExpr))
+}
+
+// tableName get the go type name of the generated table struct, ignore the @GoType annotation
+func (codegen *_CodeGen) tableName(table *ast.Table) string {
+	prefix, name := codegen.typeRef(table.Package(), table.FullName())
+
+	if prefix != "" {
+		name = prefix + "." + name
+	}
+
+	if isOneOf(table) {
+		return name
+	}
+
+	return "*" + name
+}
+
+// isOneOf check if the table is annotated with @OneOf
+func isOneOf(typeDecl ast.Type) bool {
+	_, ok := gslang.FindAnnotation(typeDecl, "com.gsrpc.OneOf")
+
+	return ok
+}
+
+// variant get the union variant index of field by field index
+func variant(index int) int {
+	return index + 1
+}
+
+func (codegen *_CodeGen) fieldType(field *ast.Field) string {
+	if isPointer(field) {
+		return "*" + codegen.typeName(field.Type)
+	}
+
+	return codegen.typeName(field.Type)
 }
 
 func (codegen *_CodeGen) params(params []*ast.Param) string {
@@ -262,6 +520,541 @@ func (codegen *_CodeGen) typeRef(pacakgeName, fullname string) (prefix string, n
 	return prefix, strings.Title(nodes[len(nodes)-1])
 }
 
+// isScalar check if the type is builtin or enum type, which can be compared and copied by value
+func isScalar(typeDecl ast.Type) bool {
+	if typeRef, ok := typeDecl.(*ast.TypeRef); ok {
+		typeDecl = typeRef.Ref
+	}
+
+	switch typeDecl.(type) {
+	case *ast.BuiltinType, *ast.Enum:
+		return true
+	}
+
+	return false
+}
+
+func (codegen *_CodeGen) execute(name string, data interface{}) string {
+	var buff bytes.Buffer
+
+	if err := codegen.tpl.ExecuteTemplate(&buff, name, data); err != nil {
+		gserrors.Panicf(err, "exec template(%s) for %s error", name, data)
+	}
+
+	return buff.String()
+}
+
+// equalType get the equal function expr of type, which signature is func(lhs,rhs T) bool
+func (codegen *_CodeGen) equalType(typeDecl ast.Type) string {
+	switch typeDecl.(type) {
+	case *ast.BuiltinType, *ast.Enum:
+		return codegen.execute("equalValue", typeDecl)
+
+	case *ast.TypeRef:
+		return codegen.equalType(typeDecl.(*ast.TypeRef).Ref)
+
+	case *ast.Table:
+		prefix, name := codegen.typeRef(typeDecl.Package(), typeDecl.FullName())
+
+		if prefix != "" {
+			prefix = prefix + "."
+		}
+
+		if isOneOf(typeDecl) {
+			return prefix + "Equal" + name
+		}
+
+		if isCustom(typeDecl) {
+			return codegen.execute("equalCustom", typeDecl)
+		}
+
+		return "(*" + prefix + name + ").Equal"
+
+	case *ast.Seq:
+		seq := typeDecl.(*ast.Seq)
+
+		if _, ok := wire.MapEntry(seq); ok {
+			return codegen.execute("equalMap", seq)
+		}
+
+		if builtinType, ok := seq.Component.(*ast.BuiltinType); ok && builtinType.Type == lexer.KeyByte && seq.Size == -1 {
+			return "bytes.Equal"
+		}
+
+		if isScalar(seq.Component) && seq.Size != -1 {
+			return codegen.execute("equalValue", seq)
+		}
+
+		return codegen.execute("equalList", seq)
+	}
+
+	gserrors.Panicf(nil, "equalType  error: unsupport type(%s)", typeDecl)
+
+	return "unknown"
+}
+
+// cloneType get the deep copy function expr of type, which signature is func(val T) T
+func (codegen *_CodeGen) cloneType(typeDecl ast.Type) string {
+	switch typeDecl.(type) {
+	case *ast.BuiltinType, *ast.Enum:
+		return codegen.execute("cloneValue", typeDecl)
+
+	case *ast.TypeRef:
+		return codegen.cloneType(typeDecl.(*ast.TypeRef).Ref)
+
+	case *ast.Table:
+		prefix, name := codegen.typeRef(typeDecl.Package(), typeDecl.FullName())
+
+		if prefix != "" {
+			prefix = prefix + "."
+		}
+
+		if isOneOf(typeDecl) {
+			return prefix + "Clone" + name
+		}
+
+		if isCustom(typeDecl) {
+			return codegen.execute("cloneCustom", typeDecl)
+		}
+
+		return "(*" + prefix + name + ").Clone"
+
+	case *ast.Seq:
+		seq := typeDecl.(*ast.Seq)
+
+		if _, ok := wire.MapEntry(seq); ok {
+			return codegen.execute("cloneMap", seq)
+		}
+
+		if seq.Size != -1 {
+
+			if isScalar(seq.Component) {
+				return codegen.execute("cloneValue", seq)
+			}
+
+			return codegen.execute("cloneArray", seq)
+		}
+
+		return codegen.execute("cloneList", seq)
+	}
+
+	gserrors.Panicf(nil, "cloneType  error: unsupport type(%s)", typeDecl)
+
+	return "unknown"
+}
+
+// jsonSafe check if the type value can be encoded/decoded by encoding/json directly,
+// uint64 is encoded as string, fixed byte array as base64 string, union by variant name
+// and fixed arrays are decoded with length check
+func jsonSafe(typeDecl ast.Type) bool {
+	switch typeDecl.(type) {
+	case *ast.BuiltinType:
+		return typeDecl.(*ast.BuiltinType).Type != lexer.KeyUInt64
+
+	case *ast.TypeRef:
+		return jsonSafe(typeDecl.(*ast.TypeRef).Ref)
+
+	case *ast.Enum:
+		return true
+
+	case *ast.Table:
+		return !isOneOf(typeDecl) && !isCustom(typeDecl)
+
+	case *ast.Seq:
+		seq := typeDecl.(*ast.Seq)
+
+		if entry, ok := wire.MapEntry(seq); ok {
+			return jsonSafe(entry.Fields[1].Type)
+		}
+
+		if seq.Size != -1 {
+			return false
+		}
+
+		return jsonSafe(seq.Component)
+	}
+
+	return true
+}
+
+// jsonTag get the struct field tag for json encoding, the json key is the field name as the other generators,
+// @GoTag only tags the golang struct field
+func (codegen *_CodeGen) jsonTag(field *ast.Field) string {
+
+	if isOptional(field) {
+		return fmt.Sprintf("`json:\"%s,omitempty\"`", strings.Title(field.Name()))
+	}
+
+	return fmt.Sprintf("`json:\"%s\"`", strings.Title(field.Name()))
+}
+
+// goTagValue get the struct tag declared by @GoTag annotation
+func (codegen *_CodeGen) goTagValue(field *ast.Field) (string, bool) {
+
+	annotation, ok := gslang.FindAnnotation(field, "com.gsrpc.GoTag")
+
+	if !ok {
+		return "", false
+	}
+
+	start, _ := gslang.Pos(field)
+
+	expr, ok := gen.Arg(annotation, 0, "Value")
+
+	if !ok {
+		gserrors.Panicf(nil, "expect @GoTag(\"key:\\\"value\\\"\") for field %s :%v", field, start)
+	}
+
+	tag := codegen.compiler.Eval().EvalString(expr)
+
+	if strings.Contains(tag, "`") {
+		gserrors.Panicf(nil, "@GoTag of field %s can't contain back quote :%v", field, start)
+	}
+
+	return tag, true
+}
+
+// goTag get the struct field tag declared by @GoTag annotation
+func (codegen *_CodeGen) goTag(field *ast.Field) string {
+	if tag, ok := codegen.goTagValue(field); ok {
+		return "`" + tag + "`"
+	}
+
+	return ""
+}
+
+// doc get the go doc comment lines from the .gs comments of node
+func doc(node ast.Node) string {
+	var buff bytes.Buffer
+
+	for _, line := range gslang.Comments(node) {
+		buff.WriteString("// " + strings.TrimSpace(line) + "\n")
+	}
+
+	return buff.String()
+}
+
+// jsonEncode get the json encode function expr of type, which signature is func(val T) (interface{},error)
+func (codegen *_CodeGen) jsonEncode(typeDecl ast.Type) string {
+	switch typeDecl.(type) {
+	case *ast.BuiltinType:
+		if typeDecl.(*ast.BuiltinType).Type == lexer.KeyUInt64 {
+			return codegen.execute("jsonEncodeUInt64", typeDecl)
+		}
+
+	case *ast.TypeRef:
+		return codegen.jsonEncode(typeDecl.(*ast.TypeRef).Ref)
+
+	case *ast.Table:
+		if isCustom(typeDecl) {
+			return codegen.execute("jsonEncodeCustom", typeDecl)
+		}
+
+	case *ast.Seq:
+		seq := typeDecl.(*ast.Seq)
+
+		if _, ok := wire.MapEntry(seq); ok {
+			return codegen.execute("jsonEncodeMap", seq)
+		}
+
+		if builtinType, ok := seq.Component.(*ast.BuiltinType); ok && builtinType.Type == lexer.KeyByte {
+			return codegen.execute("jsonEncodeBytes", seq)
+		}
+
+		return codegen.execute("jsonEncodeList", seq)
+	}
+
+	return codegen.execute("jsonEncodeValue", typeDecl)
+}
+
+// jsonDecode get the json decode function expr of type, which signature is func(data json.RawMessage) (T,error)
+func (codegen *_CodeGen) jsonDecode(typeDecl ast.Type) string {
+	switch typeDecl.(type) {
+	case *ast.BuiltinType:
+		if typeDecl.(*ast.BuiltinType).Type == lexer.KeyUInt64 {
+			return codegen.execute("jsonDecodeUInt64", typeDecl)
+		}
+
+	case *ast.TypeRef:
+		return codegen.jsonDecode(typeDecl.(*ast.TypeRef).Ref)
+
+	case *ast.Table:
+		if isOneOf(typeDecl) {
+			prefix, name := codegen.typeRef(typeDecl.Package(), typeDecl.FullName())
+
+			if prefix != "" {
+				return prefix + ".Unmarshal" + name + "JSON"
+			}
+
+			return "Unmarshal" + name + "JSON"
+		}
+
+		if isCustom(typeDecl) {
+			return codegen.execute("jsonDecodeCustom", typeDecl)
+		}
+
+	case *ast.Seq:
+		seq := typeDecl.(*ast.Seq)
+
+		if _, ok := wire.MapEntry(seq); ok {
+			return codegen.execute("jsonDecodeMap", seq)
+		}
+
+		if builtinType, ok := seq.Component.(*ast.BuiltinType); ok && builtinType.Type == lexer.KeyByte && seq.Size != -1 {
+			return codegen.execute("jsonDecodeBytes", seq)
+		}
+
+		return codegen.execute("jsonDecodeList", seq)
+	}
+
+	return codegen.execute("jsonDecodeValue", typeDecl)
+}
+
+// unionFunc get the union helper function name, e.g. ValidatePayload
+func (codegen *_CodeGen) unionFunc(action string, typeDecl ast.Type) string {
+	prefix, name := codegen.typeRef(typeDecl.Package(), typeDecl.FullName())
+
+	if prefix != "" {
+		return prefix + "." + action + name
+	}
+
+	return action + name
+}
+
+// validateType get the nested validate function expr of type, which signature is func(path string,val T) []string,
+// return empty string if the type value has nothing to validate
+func (codegen *_CodeGen) validateType(typeDecl ast.Type) string {
+	switch typeDecl.(type) {
+	case *ast.TypeRef:
+		return codegen.validateType(typeDecl.(*ast.TypeRef).Ref)
+
+	case *ast.Table:
+		if isCustom(typeDecl) {
+			return ""
+		}
+
+		return codegen.execute("validateTable", typeDecl)
+
+	case *ast.Seq:
+		seq := typeDecl.(*ast.Seq)
+
+		if entry, ok := wire.MapEntry(seq); ok {
+			if codegen.validateType(entry.Fields[1].Type) != "" {
+				return codegen.execute("validateMap", seq)
+			}
+
+			return ""
+		}
+
+		if codegen.validateType(seq.Component) != "" {
+			return codegen.execute("validateList", seq)
+		}
+	}
+
+	return ""
+}
+
+// patternDecl get the precompiled regexp var declaration of @Pattern annotated field or param
+func (codegen *_CodeGen) patternDecl(prefix string, node ast.Node) string {
+
+	annotation, ok := gslang.FindAnnotation(node, "com.gsrpc.Pattern")
+
+	if !ok {
+		return ""
+	}
+
+	start, _ := gslang.Pos(node)
+
+	expr, ok := gen.Arg(annotation, 0, "Value")
+
+	if !ok {
+		gserrors.Panicf(nil, "expect @Pattern(\"regex\") for %s :%v", node, start)
+	}
+
+	pattern := codegen.compiler.Eval().EvalString(expr)
+
+	if _, err := regexp.Compile(pattern); err != nil {
+		gserrors.Panicf(err, "invalid @Pattern(%s) for %s :%v", pattern, node, start)
+	}
+
+	return fmt.Sprintf("var %s = regexp.MustCompile(%s)\n", patternVar(prefix, node), strconv.Quote(pattern))
+}
+
+// patternVar get the precompiled regexp var name, the name parts are separated by '_' to avoid collision
+func patternVar(prefix string, node ast.Node) string {
+	return fmt.Sprintf("_%s_%s_Pattern", prefix, strings.Title(node.Name()))
+}
+
+// validateField get the constraint check statements of table field
+func (codegen *_CodeGen) validateField(prefix string, field *ast.Field) string {
+	return codegen.validate(prefix, field, field.Type, "val."+strings.Title(field.Name()), strings.Title(field.Name()), isPointer(field))
+}
+
+// validateParams get the constraint check statements of method params,
+// return empty string if the params have nothing to validate
+func (codegen *_CodeGen) validateParams(prefix string, method *ast.Method) string {
+
+	var buff bytes.Buffer
+
+	for _, param := range method.Params {
+		buff.WriteString(codegen.validate(prefix+"_"+strings.Title(method.Name()), param, param.Type, param.Name(), param.Name(), false))
+	}
+
+	if buff.Len() == 0 {
+		return ""
+	}
+
+	return fmt.Sprintf("var violations []string\n%s\nif len(violations) != 0 {\nerr = &gorpc.InvalidArgument{Violations:violations}\n}\n", buff.String())
+}
+
+// validate get the statements which check the value constraints declared by @Required, @Range, @MaxLen and @Pattern,
+// and append the violations with path to local var violations
+func (codegen *_CodeGen) validate(prefix string, node ast.Node, typeDecl ast.Type, value string, path string, pointer bool) string {
+
+	start, _ := gslang.Pos(node)
+
+	eval := codegen.compiler.Eval()
+
+	if typeRef, ok := typeDecl.(*ast.TypeRef); ok {
+		typeDecl = typeRef.Ref
+	}
+
+	var buff bytes.Buffer
+
+	if _, ok := gslang.FindAnnotation(node, "com.gsrpc.Required"); ok {
+
+		cond := ""
+
+		switch {
+		case pointer:
+			cond = value + " == nil"
+		default:
+			switch typeDecl.(type) {
+			case *ast.Table:
+				cond = value + " == nil"
+			case *ast.Seq:
+				if typeDecl.(*ast.Seq).Size == -1 {
+					cond = "len(" + value + ") == 0"
+				}
+			case *ast.BuiltinType:
+				if typeDecl.(*ast.BuiltinType).Type == lexer.KeyString {
+					cond = value + ` == ""`
+				}
+			}
+		}
+
+		if cond == "" {
+			gserrors.Panicf(nil, "@Required only support optional,string,table or variable length seq :%v", start)
+		}
+
+		buff.WriteString(fmt.Sprintf("if %s {\nviolations = append(violations,%s)\n}\n", cond, strconv.Quote(path+": required")))
+	}
+
+	var checks bytes.Buffer
+
+	target := value
+
+	if pointer {
+		target = "*" + value
+	}
+
+	if annotation, ok := gslang.FindAnnotation(node, "com.gsrpc.Range"); ok {
+
+		builtinType, ok := typeDecl.(*ast.BuiltinType)
+
+		if !ok || builtinType.Type == lexer.KeyString || builtinType.Type == lexer.KeyBool {
+			gserrors.Panicf(nil, "@Range only support number type :%v", start)
+		}
+
+		unsigned := false
+
+		switch builtinType.Type {
+		case lexer.KeyByte, lexer.KeyUInt16, lexer.KeyUInt32, lexer.KeyUInt64:
+			unsigned = true
+		}
+
+		bound := func(expr ast.Expr) (string, bool) {
+			switch builtinType.Type {
+			case lexer.KeyFloat32, lexer.KeyFloat64:
+				val := eval.EvalFloat(expr)
+				return strconv.FormatFloat(val, 'g', -1, 64), val > 0
+			}
+
+			val := eval.EvalInt(expr)
+
+			return strconv.FormatInt(val, 10), val > 0
+		}
+
+		if expr, ok := gen.Arg(annotation, 0, "Min"); ok {
+			if min, positive := bound(expr); positive || !unsigned {
+				checks.WriteString(fmt.Sprintf("if %s < %s {\nviolations = append(violations,fmt.Sprintf(\"%s: value %%v less than min %s\",%s))\n}\n", target, min, path, min, target))
+			}
+		}
+
+		if expr, ok := gen.Arg(annotation, 1, "Max"); ok {
+			max, positive := bound(expr)
+
+			if !positive && unsigned && max != "0" {
+				gserrors.Panicf(nil, "@Range max(%s) out of unsigned type :%v", max, start)
+			}
+
+			checks.WriteString(fmt.Sprintf("if %s > %s {\nviolations = append(violations,fmt.Sprintf(\"%s: value %%v greater than max %s\",%s))\n}\n", target, max, path, max, target))
+		}
+	}
+
+	if annotation, ok := gslang.FindAnnotation(node, "com.gsrpc.MaxLen"); ok {
+
+		supported := false
+
+		switch typeDecl.(type) {
+		case *ast.Seq:
+			supported = typeDecl.(*ast.Seq).Size == -1
+		case *ast.BuiltinType:
+			supported = typeDecl.(*ast.BuiltinType).Type == lexer.KeyString
+		}
+
+		if !supported {
+			gserrors.Panicf(nil, "@MaxLen only support string or variable length seq :%v", start)
+		}
+
+		expr, ok := gen.Arg(annotation, 0, "Value")
+
+		if !ok {
+			gserrors.Panicf(nil, "expect @MaxLen(n) :%v", start)
+		}
+
+		max := eval.EvalInt(expr)
+
+		checks.WriteString(fmt.Sprintf("if len(%s) > %d {\nviolations = append(violations,fmt.Sprintf(\"%s: length %%d exceeds max %d\",len(%s)))\n}\n", target, max, path, max, target))
+	}
+
+	if annotation, ok := gslang.FindAnnotation(node, "com.gsrpc.Pattern"); ok {
+
+		if builtinType, ok := typeDecl.(*ast.BuiltinType); !ok || builtinType.Type != lexer.KeyString {
+			gserrors.Panicf(nil, "@Pattern only support string :%v", start)
+		}
+
+		expr, _ := gen.Arg(annotation, 0, "Value")
+
+		checks.WriteString(fmt.Sprintf("if !%s.MatchString(%s) {\nviolations = append(violations,%s)\n}\n",
+			patternVar(prefix, node), target, strconv.Quote(path+": mismatch pattern "+eval.EvalString(expr))))
+	}
+
+	if nested := codegen.validateType(typeDecl); nested != "" {
+		checks.WriteString(fmt.Sprintf("violations = append(violations,%s(%s,%s)...)\n", nested, strconv.Quote(path), target))
+	}
+
+	if checks.Len() != 0 {
+		if pointer {
+			buff.WriteString(fmt.Sprintf("if %s != nil {\n%s}\n", value, checks.String()))
+		} else {
+			buff.Write(checks.Bytes())
+		}
+	}
+
+	return buff.String()
+}
+
 func (codegen *_CodeGen) writeType(typeDecl ast.Type) string {
 	switch typeDecl.(type) {
 	case *ast.BuiltinType:
@@ -285,11 +1078,19 @@ func (codegen *_CodeGen) writeType(typeDecl ast.Type) string {
 
 		prefix, name := codegen.typeRef(typeDecl.Package(), typeDecl.FullName())
 
-		if prefix != "" {
-			return "" + prefix + ".Write" + name
+		if prefix != "" {
+			name = prefix + ".Write" + name
+		} else {
+			name = "Write" + name
+		}
+
+		if isCustom(typeDecl) {
+			customName, codec := codegen.customType(typeDecl.(*ast.Table))
+
+			return fmt.Sprintf("func(writer gorpc.Writer,val %s) error {\ntarget,err := %s.Encode(val)\nif err != nil {\nreturn err\n}\nreturn %s(writer,target)\n}", customName, codec, name)
 		}
 
-		return "Write" + name
+		return name
 
 	case *ast.Seq:
 		seq := typeDecl.(*ast.Seq)
@@ -304,6 +1105,15 @@ func (codegen *_CodeGen) writeType(typeDecl ast.Type) string {
 
 		var buff bytes.Buffer
 
+		if _, ok := wire.MapEntry(seq); ok {
+
+			if err := codegen.tpl.ExecuteTemplate(&buff, "writeMap", seq); err != nil {
+				gserrors.Panicf(err, "exec template(writeMap) for %s errir", seq)
+			}
+
+			return buff.String()
+		}
+
 		if seq.Size != -1 {
 
 			if isbytes {
@@ -365,10 +1175,19 @@ func (codegen *_CodeGen) readType(typeDecl ast.Type) string {
 		prefix, name := codegen.typeRef(typeDecl.Package(), typeDecl.FullName())
 
 		if prefix != "" {
-			return "" + prefix + ".Read" + name
+			name = prefix + ".Read" + name
+		} else {
+			name = "Read" + name
 		}
 
-		return "Read" + name
+		if isCustom(typeDecl) {
+			customName, codec := codegen.customType(typeDecl.(*ast.Table))
+
+			return fmt.Sprintf("func(reader gorpc.Reader) (val %s,err error) {\nvar target %s\nif target,err = %s(reader); err != nil {\nreturn\n}\nreturn %s.Decode(target)\n}",
+				customName, codegen.tableName(typeDecl.(*ast.Table)), name, codec)
+		}
+
+		return name
 
 	case *ast.Seq:
 		seq := typeDecl.(*ast.Seq)
@@ -383,6 +1202,15 @@ func (codegen *_CodeGen) readType(typeDecl ast.Type) string {
 			isbytes = true
 		}
 
+		if _, ok := wire.MapEntry(seq); ok {
+
+			if err := codegen.tpl.ExecuteTemplate(&buff, "readMap", seq); err != nil {
+				gserrors.Panicf(err, "exec template(readMap) for %s errir", seq)
+			}
+
+			return buff.String()
+		}
+
 		if seq.Size != -1 {
 
 			if isbytes {
@@ -438,17 +1266,21 @@ func (codegen *_CodeGen) typeName(typeDecl ast.Type) string {
 
 	case *ast.Table:
 
-		prefix, name := codegen.typeRef(typeDecl.Package(), typeDecl.FullName())
+		if isCustom(typeDecl) {
+			name, _ := codegen.customType(typeDecl.(*ast.Table))
 
-		if prefix != "" {
-			return "*" + prefix + "." + name
+			return name
 		}
 
-		return "*" + name
+		return codegen.tableName(typeDecl.(*ast.Table))
 
 	case *ast.Seq:
 		seq := typeDecl.(*ast.Seq)
 
+		if entry, ok := wire.MapEntry(seq); ok {
+			return fmt.Sprintf("map[%s]%s", codegen.typeName(entry.Fields[0].Type), codegen.typeName(entry.Fields[1].Type))
+		}
+
 		if seq.Size != -1 {
 			return fmt.Sprintf("[%d]", seq.Size) + codegen.typeName(seq.Component)
 		}
@@ -486,6 +1318,14 @@ func (codegen *_CodeGen) defaultVal(typeDecl ast.Type) string {
 
 	case *ast.Table:
 
+		if isOneOf(typeDecl) {
+			return "nil"
+		}
+
+		if isCustom(typeDecl) {
+			return "*new(" + codegen.typeName(typeDecl) + ")"
+		}
+
 		prefix, name := codegen.typeRef(typeDecl.Package(), typeDecl.FullName())
 
 		if prefix != "" {
@@ -516,6 +1356,295 @@ func (codegen *_CodeGen) defaultVal(typeDecl ast.Type) string {
 	return "unknown"
 }
 
+// fieldDefault get the field default value declared by @Default annotation,
+// or the zero value of field type if the annotation not found
+func (codegen *_CodeGen) fieldDefault(field *ast.Field) string {
+
+	expr, ok := gen.Default(field)
+
+	if !ok {
+		return codegen.defaultVal(field.Type)
+	}
+
+	start, _ := gslang.Pos(field)
+
+	return codegen.defaultExpr(expr, field.Type, start)
+}
+
+func (codegen *_CodeGen) defaultExpr(expr ast.Expr, typeDecl ast.Type, start lexer.Position) string {
+
+	eval := codegen.compiler.Eval()
+
+	switch typeDecl.(type) {
+	case *ast.BuiltinType:
+		builtinType := typeDecl.(*ast.BuiltinType)
+
+		switch builtinType.Type {
+		case lexer.KeyByte, lexer.KeyUInt16, lexer.KeyUInt32, lexer.KeyUInt64:
+			if val := eval.EvalInt(expr); val < 0 {
+				gserrors.Panicf(nil, "unsigned type(%s) can't default to negative value(%d) :%v", typeDecl, val, start)
+			}
+		}
+
+		switch builtinType.Type {
+		case lexer.KeyString:
+			return strconv.Quote(eval.EvalString(expr))
+		case lexer.KeyBool:
+			return strconv.FormatBool(eval.EvalBool(expr))
+		case lexer.KeyFloat32, lexer.KeyFloat64:
+			return fmt.Sprintf("%s(%s)", builtin[builtinType.Type], strconv.FormatFloat(eval.EvalFloat(expr), 'g', -1, 64))
+		case lexer.KeyVoid:
+		default:
+			return fmt.Sprintf("%s(%d)", builtin[builtinType.Type], eval.EvalInt(expr))
+		}
+
+	case *ast.TypeRef:
+		return codegen.defaultExpr(expr, typeDecl.(*ast.TypeRef).Ref, start)
+
+	case *ast.Enum:
+		enum := typeDecl.(*ast.Enum)
+
+		val := eval.EvalInt(expr)
+
+		for _, constant := range enum.Constants {
+			if constant.Value == val {
+				return codegen.typeName(enum) + constant.Name()
+			}
+		}
+
+		gserrors.Panicf(nil, "enum %s constant(%d) not found :%v", enum, val, start)
+
+	case *ast.Table:
+		table := typeDecl.(*ast.Table)
+
+		newObj, ok := expr.(*ast.NewObj)
+
+		if !ok || isOneOf(table) || isCustom(table) {
+			break
+		}
+
+		return codegen.tableLiteral(table, newObj.Args, start)
+	}
+
+	gserrors.Panicf(nil, "unsupport default value for type(%s) :%v", typeDecl, start)
+
+	return "unknown"
+}
+
+// tableLiteral get the table object literal expr initialized by positional or named args
+func (codegen *_CodeGen) tableLiteral(table *ast.Table, args *ast.ArgsTable, start lexer.Position) string {
+
+	var buff bytes.Buffer
+
+	buff.WriteString(strings.Replace(codegen.tableName(table), "*", "&", 1) + "{")
+
+	for i, field := range table.Fields {
+
+		var arg ast.Expr
+
+		ok := false
+
+		if args != nil {
+			arg, ok = args.NamedArg(field.Name())
+
+			if !args.Named {
+				arg, ok = nil, i < len(args.Arguments)
+
+				if ok {
+					arg = args.Arguments[i]
+				}
+			}
+		}
+
+		if ok {
+			buff.WriteString(fmt.Sprintf("%s: %s, ", strings.Title(field.Name()), codegen.defaultExpr(arg, field.Type, start)))
+		} else if !isOptional(field) {
+			buff.WriteString(fmt.Sprintf("%s: %s, ", strings.Title(field.Name()), codegen.fieldDefault(field)))
+		}
+	}
+
+	buff.WriteString("}")
+
+	return buff.String()
+}
+
+// userAnnotations get the annotations declared by user which are attached to node,
+// the gslang and com.gsrpc annotations are consumed by compiler and code generator
+func userAnnotations(node ast.Node) (annotations []*ast.Annotation) {
+
+	for _, annotation := range node.Annotations() {
+
+		if _, ok := annotationTable(annotation); ok {
+			annotations = append(annotations, annotation)
+		}
+	}
+
+	return
+}
+
+// annotationTable get the declaration table of user annotation
+func annotationTable(annotation *ast.Annotation) (*ast.Table, bool) {
+
+	typeDecl := annotation.Type
+
+	if typeRef, ok := typeDecl.(*ast.TypeRef); ok {
+		typeDecl = typeRef.Ref
+	}
+
+	table, ok := typeDecl.(*ast.Table)
+
+	if !ok || table.Package() == "com.gsrpc" || strings.HasPrefix(table.Package(), "gslang") {
+		return nil, false
+	}
+
+	return table, true
+}
+
+// _AnnotationNode the annotated node and its children, e.g. contract and its methods
+type _AnnotationNode struct {
+	name     string             // field name of parent annotations struct
+	node     ast.Node           // annotated node
+	children []*_AnnotationNode // children nodes
+}
+
+// hasAnnotations check if the node or any of its children has user annotations
+func (node *_AnnotationNode) hasAnnotations() bool {
+
+	if len(userAnnotations(node.node)) != 0 {
+		return true
+	}
+
+	for _, child := range node.children {
+		if child.hasAnnotations() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// annotationStruct write the annotations struct type of node and children into decls, and return the struct value expr
+func (codegen *_CodeGen) annotationStruct(decls *bytes.Buffer, typeName string, node *_AnnotationNode) string {
+
+	start, _ := gslang.Pos(node.node)
+
+	var fields, values bytes.Buffer
+
+	names := make(map[string]bool)
+
+	checkName := func(name string) {
+		if names[name] {
+			gserrors.Panicf(nil, "duplicate annotations field %s of %s :%v", name, node.node, start)
+		}
+
+		names[name] = true
+	}
+
+	var groups [][]*ast.Annotation
+
+	indexer := make(map[string]int)
+
+	for _, annotation := range userAnnotations(node.node) {
+
+		if index, ok := indexer[annotation.FullName()]; ok {
+			groups[index] = append(groups[index], annotation)
+			continue
+		}
+
+		indexer[annotation.FullName()] = len(groups)
+
+		groups = append(groups, []*ast.Annotation{annotation})
+	}
+
+	for _, group := range groups {
+
+		table, _ := annotationTable(group[0])
+
+		name := strings.Title(table.Name())
+
+		checkName(name)
+
+		start, _ := gslang.Pos(group[0])
+
+		if len(group) == 1 {
+			fields.WriteString(fmt.Sprintf("%s %s\n", name, codegen.tableName(table)))
+			values.WriteString(fmt.Sprintf("%s: %s,\n", name, codegen.tableLiteral(table, group[0].Args, start)))
+			continue
+		}
+
+		fields.WriteString(fmt.Sprintf("%s []%s\n", name, codegen.tableName(table)))
+
+		values.WriteString(fmt.Sprintf("%s: []%s{\n", name, codegen.tableName(table)))
+
+		for _, annotation := range group {
+			values.WriteString(codegen.tableLiteral(table, annotation.Args, start) + ",\n")
+		}
+
+		values.WriteString("},\n")
+	}
+
+	for _, child := range node.children {
+
+		if !child.hasAnnotations() {
+			continue
+		}
+
+		name := strings.Title(child.name)
+
+		checkName(name)
+
+		// separate the path with '_', avoid collision between e.g. table X field YZ and table XY field Z
+		childTypeName := typeName + "_" + name
+
+		fields.WriteString(fmt.Sprintf("%s %s\n", name, childTypeName))
+		values.WriteString(fmt.Sprintf("%s: %s,\n", name, codegen.annotationStruct(decls, childTypeName, child)))
+	}
+
+	decls.WriteString(fmt.Sprintf("//%s the annotations attached to %s -- generate by gsc\ntype %s struct {\n%s}\n\n", typeName, node.node.Name(), typeName, fields.String()))
+
+	return fmt.Sprintf("%s{\n%s}", typeName, values.String())
+}
+
+// annotations get the annotations var declaration of contract(with methods and params) or table(with fields),
+// the var is named as {Name}Annotations and only contains the annotated nodes, return empty string if no user annotation attached
+func (codegen *_CodeGen) annotations(typeDecl ast.Type) string {
+
+	root := &_AnnotationNode{node: typeDecl}
+
+	switch typeDecl.(type) {
+	case *ast.Contract:
+		for _, method := range typeDecl.(*ast.Contract).Methods {
+
+			child := &_AnnotationNode{name: method.Name(), node: method}
+
+			for _, param := range method.Params {
+				child.children = append(child.children, &_AnnotationNode{name: param.Name(), node: param})
+			}
+
+			root.children = append(root.children, child)
+		}
+
+	case *ast.Table:
+		for _, field := range typeDecl.(*ast.Table).Fields {
+			root.children = append(root.children, &_AnnotationNode{name: field.Name(), node: field})
+		}
+	}
+
+	if !root.hasAnnotations() {
+		return ""
+	}
+
+	name := strings.Title(typeDecl.Name())
+
+	var decls bytes.Buffer
+
+	value := codegen.annotationStruct(&decls, "_"+name+"Annotations", root)
+
+	decls.WriteString(fmt.Sprintf("//%sAnnotations the annotations attached to %s -- generate by gsc\nvar %sAnnotations = %s\n", name, name, name, value))
+
+	return decls.String()
+}
+
 func (codegen *_CodeGen) BeginScript(compiler *gslang.Compiler, script *ast.Script) bool {
 
 	scriptPath := filepath.ToSlash(filepath.Clean(script.Name()))
@@ -537,6 +1666,8 @@ func (codegen *_CodeGen) BeginScript(compiler *gslang.Compiler, script *ast.Scri
 
 	codegen.script = script
 
+	codegen.compiler = compiler
+
 	codegen.packageName = script.Package
 	codegen.scriptPath = strings.Replace(codegen.packageName, ".", "/", -1)
 
@@ -568,6 +1699,8 @@ func (codegen *_CodeGen) BeginScript(compiler *gslang.Compiler, script *ast.Scri
 
 	codegen.imports = make(map[string]string)
 
+	codegen.goImports = make(map[string]string)
+
 	for k, v := range imports {
 		codegen.imports[k] = v
 	}
@@ -582,27 +1715,99 @@ func (codegen *_CodeGen) Using(compiler *gslang.Compiler, using *ast.Using) {
 	codegen.imports[nodes[len(nodes)-2]+"."] = strings.Join(nodes[:len(nodes)-1], ".")
 }
 
+// checkFieldNames report the field which name collides with the generated methods of table,
+// the Error method of exception and the HasXXX methods of optional fields are checked too
+func checkFieldNames(table *ast.Table, methods ...string) {
+
+	reserved := make(map[string]bool)
+
+	for _, method := range methods {
+		reserved[method] = true
+	}
+
+	if gslang.IsException(table) {
+		reserved["Error"] = true
+	}
+
+	for _, field := range table.Fields {
+		if isOptional(field) {
+			reserved["Has"+strings.Title(field.Name())] = true
+		}
+	}
+
+	for _, field := range table.Fields {
+		if name := strings.Title(field.Name()); reserved[name] {
+			start, _ := gslang.Pos(field)
+			gserrors.Panicf(nil, "field %s of %s collides with the generated method %s :%v", field, table, name, start)
+		}
+	}
+}
+
 func (codegen *_CodeGen) Table(compiler *gslang.Compiler, tableType *ast.Table) {
 
+	if isOneOf(tableType) {
+
+		checkFieldNames(tableType, "MarshalJSON")
+
+		if gslang.IsPOD(tableType) || len(tableType.Fields) == 0 || len(tableType.Fields) > 255 {
+			start, _ := gslang.Pos(tableType)
+			gserrors.Panicf(nil, "union %s must be non-POD table with 1~255 fields :%v", tableType, start)
+		}
+
+		for _, field := range tableType.Fields {
+			if _, ok := gslang.FindAnnotation(field, "com.gsrpc.GoTag"); ok {
+				start, _ := gslang.Pos(field)
+				gserrors.Panicf(nil, "union variant %s can't declare @GoTag :%v", field, start)
+			}
+		}
+
+		if err := codegen.tpl.ExecuteTemplate(&codegen.content, "union", tableType); err != nil {
+			gserrors.Panicf(err, "exec template(union) for %s errir", tableType)
+		}
+
+		codegen.extraFiles("table", tableType)
+
+		return
+	}
+
+	checkFieldNames(tableType, "Equal", "Clone", "CopyFrom", "Validate", "MarshalJSON", "UnmarshalJSON")
+
 	if err := codegen.tpl.ExecuteTemplate(&codegen.content, "table", tableType); err != nil {
 		gserrors.Panicf(err, "exec template(table) for %s errir", tableType)
 	}
 
+	codegen.extraFiles("table", tableType)
+
 }
 
 func (codegen *_CodeGen) Annotation(compiler *gslang.Compiler, annotation *ast.Table) {
+
+	// the com.gsrpc annotations are only consumed by code generator
+	if codegen.script.Package == "com.gsrpc" {
+		return
+	}
+
+	checkFieldNames(annotation, "Equal", "Clone", "CopyFrom", "Validate", "MarshalJSON", "UnmarshalJSON")
+
+	if err := codegen.tpl.ExecuteTemplate(&codegen.content, "table", annotation); err != nil {
+		gserrors.Panicf(err, "exec template(table) for %s errir", annotation)
+	}
 }
 
 func (codegen *_CodeGen) Enum(compiler *gslang.Compiler, enum *ast.Enum) {
 	if err := codegen.tpl.ExecuteTemplate(&codegen.content, "enum", enum); err != nil {
 		gserrors.Panicf(err, "exec template(enum) for %s errir", enum)
 	}
+
+	codegen.extraFiles("enum", enum)
 }
 
 func (codegen *_CodeGen) Contract(compiler *gslang.Compiler, contract *ast.Contract) {
 	if err := codegen.tpl.ExecuteTemplate(&codegen.content, "contract", contract); err != nil {
 		gserrors.Panicf(err, "exec template(contract) for %s errir", contract)
 	}
+
+	codegen.extraFiles("contract", contract)
 }
 
 // EndScript .
@@ -612,6 +1817,12 @@ func (codegen *_CodeGen) EndScript(compiler *gslang.Compiler) {
 
 	packageName := codegen.script.Package
 
+	// the tagged tables are read and written by the gorpc runtime helpers(ReadTags, SkipTags and the Unknown field),
+	// which are shipped since the wire version 2, so the generated code fails to compile with the old runtime
+	if strings.Contains(content, "gorpc.") {
+		content = "const _ = gorpc.PackageIsVersion2 // please upgrade the gorpc runtime\n\n" + content
+	}
+
 	if packageName == "com.gsrpc" {
 		content = strings.Replace(content, "gorpc.", "", -1)
 	}
@@ -622,17 +1833,87 @@ func (codegen *_CodeGen) EndScript(compiler *gslang.Compiler) {
 		}
 	}
 
+	var paths []string
+
+	for path := range codegen.goImports {
+		paths = append(paths, path)
+	}
+
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		alias := codegen.goImports[path]
+
+		if !strings.Contains(content, alias+".") {
+			continue
+		}
+
+		if alias == path[strings.LastIndex(path, "/")+1:] {
+			codegen.header.WriteString(fmt.Sprintf("import \"%s\"\n", path))
+		} else {
+			codegen.header.WriteString(fmt.Sprintf("import %s \"%s\"\n", alias, path))
+		}
+	}
+
 	codegen.header.WriteString(content)
 
-	var err error
-	var sources []byte
+	codegen.writeFile(filepath.Base(codegen.script.Name())+".go", codegen.header.Bytes())
+
+	// the Time codec is shipped with the com.gsrpc package
+	if packageName == "com.gsrpc" {
 
-	fullpath := filepath.Join(codegen.rootpath, codegen.scriptPath, filepath.Base(codegen.script.Name())+".go")
+		var buff bytes.Buffer
 
-	sources, err = format.Source(codegen.header.Bytes())
+		if err := codegen.tpl.ExecuteTemplate(&buff, "time.go", nil); err != nil {
+			gserrors.Panicf(err, "exec template(time.go) error")
+		}
 
-	if err != nil {
-		gserrors.Panicf(err, "format golang source codes error:%s", fullpath)
+		codegen.writeFile("time.go", buff.Bytes())
+	}
+}
+
+// extraFiles execute the user templates named {kind}_file:{suffix} for typeDecl,
+// and write the outputs as extra files in current package directory
+func (codegen *_CodeGen) extraFiles(kind string, typeDecl ast.Type) {
+
+	prefix := kind + "_file:"
+
+	for _, tpl := range codegen.tpl.Templates() {
+
+		if !strings.HasPrefix(tpl.Name(), prefix) {
+			continue
+		}
+
+		var buff bytes.Buffer
+
+		if err := tpl.Execute(&buff, typeDecl); err != nil {
+			gserrors.Panicf(err, "exec template(%s) for %s error", tpl.Name(), typeDecl)
+		}
+
+		codegen.writeFile(strings.ToLower(typeDecl.Name())+strings.TrimPrefix(tpl.Name(), prefix), buff.Bytes())
+	}
+}
+
+// writeFile write generated file into current package directory, the golang source is formatted
+func (codegen *_CodeGen) writeFile(name string, content []byte) {
+
+	fullpath := filepath.Join(codegen.rootpath, codegen.scriptPath, name)
+
+	if filepath.Ext(name) == ".go" {
+
+		sources, err := format.Source(content)
+
+		if err != nil {
+			gserrors.Panicf(err, "format golang source codes error:%s", fullpath)
+		}
+
+		content = sources
+	}
+
+	// the content buffer may be reused by caller
+	if codegen.files != nil {
+		codegen.files[filepath.ToSlash(filepath.Join(codegen.scriptPath, name))] = append([]byte(nil), content...)
+		return
 	}
 
 	codegen.D("generate golang file :%s", fullpath)
@@ -645,7 +1926,7 @@ func (codegen *_CodeGen) EndScript(compiler *gslang.Compiler) {
 		}
 	}
 
-	err = ioutil.WriteFile(fullpath, sources, 0644)
+	err := ioutil.WriteFile(fullpath, content, 0644)
 
 	if err != nil {
 		gserrors.Panicf(err, "write generate golang file error")
@@ -5,12 +5,12 @@ var tpl4go = `
 {{define "enum"}} {{$Enum := title .Name}}
 
 //{{$Enum}} type define -- generate by gsc
-type {{$Enum}} {{enumType .}}
+{{doc .}}type {{$Enum}} {{enumType .}}
 
 //enum {{$Enum}} constants -- generate by gsc
 const (
     {{range .Constants}}
-    {{$Enum}}{{title .Name}} {{$Enum}} = {{.Value}}
+    {{doc .}}{{$Enum}}{{title .Name}} {{$Enum}} = {{.Value}}
     {{end}}
 )
 
@@ -22,10 +22,98 @@ func Write{{$Enum}}(writer gorpc.Writer, val {{$Enum}}) error{
 //Read{{$Enum}} write enum to output stream
 func Read{{$Enum}}(reader gorpc.Reader)({{$Enum}}, error){
     val,err := {{if enumSize . | eq 4}} gorpc.ReadUInt32(reader) {{else}} gorpc.ReadByte(reader) {{end}}
+    {{if isStrict .}}
+    if err == nil && !{{$Enum}}(val).IsValid() {
+        return {{$Enum}}(val),fmt.Errorf("invalid {{$Enum}} value(%d)",val)
+    }
+    {{end}}
     return {{$Enum}}(val),err
 }
 
-//String implement Stringer interface
+//{{$Enum}}Values get all declared {{$Enum}} constants
+func {{$Enum}}Values() []{{$Enum}} {
+    return []{{$Enum}}{ {{range .Constants}}{{$Enum}}{{title .Name}},{{end}} }
+}
+
+//Parse{{$Enum}} parse {{$Enum}} from constant name with or without "{{$Enum}}." prefix{{if isFlag .}},
+//combined flags are separated by '|', the undeclared bits are in hex form "0x%x" and no flag set is "0"{{else if isStrict . | not}},
+//the undeclared value is in String form "enum(Unknown(%d))"{{end}}
+func Parse{{$Enum}}(name string) ({{$Enum}}, error) {
+    switch name {
+        {{range .Constants}}
+        case "{{$Enum}}.{{title .Name}}","{{title .Name}}":
+            return {{$Enum}}{{title .Name}},nil
+        {{end}}
+    }
+    {{if isFlag .}}
+    if strings.Contains(name,"|") {
+        var val {{$Enum}}
+
+        for _,part := range strings.Split(name,"|") {
+            flag,err := Parse{{$Enum}}(strings.TrimSpace(part))
+
+            if err != nil {
+                return 0,err
+            }
+
+            val |= flag
+        }
+
+        return val,nil
+    }
+
+    if name == "0" {
+        return 0,nil
+    }
+
+    if strings.HasPrefix(name,"0x") {
+        if val,err := strconv.ParseUint(name[2:],16,{{if enumSize . | eq 4}}32{{else}}8{{end}}); err == nil{{if isStrict .}} && {{$Enum}}(val).IsValid(){{end}} {
+            return {{$Enum}}(val),nil
+        }
+    }
+    {{else if isStrict . | not}}
+    if strings.HasPrefix(name,"enum(Unknown(") && strings.HasSuffix(name,"))") {
+        if val,err := strconv.ParseUint(name[len("enum(Unknown("):len(name)-len("))")],10,{{if enumSize . | eq 4}}32{{else}}8{{end}}); err == nil {
+            return {{$Enum}}(val),nil
+        }
+    }
+    {{end}}
+    return 0,fmt.Errorf("unknown {{$Enum}} constant :%s",name)
+}
+
+//IsValid check if val is {{if isFlag .}}combined by{{end}} declared {{$Enum}} constant{{if isFlag .}}s{{end}}
+func (val {{$Enum}}) IsValid() bool {
+    {{if isFlag .}}
+    return val &^ ({{range $i,$c := .Constants}}{{if $i}}|{{end}}{{$Enum}}{{title .Name}}{{end}}) == 0
+    {{else}}
+    switch val {
+        {{range .Constants}}
+        case {{$Enum}}{{title .Name}}:
+            return true
+        {{end}}
+    }
+    return false
+    {{end}}
+}
+
+{{if isFlag .}}
+//Has check if all bits of flag are set
+func (val {{$Enum}}) Has(flag {{$Enum}}) bool {
+    return val & flag == flag
+}
+
+//Set set bits of flag
+func (val *{{$Enum}}) Set(flag {{$Enum}}) {
+    *val |= flag
+}
+
+//Clear clear bits of flag
+func (val *{{$Enum}}) Clear(flag {{$Enum}}) {
+    *val &^= flag
+}
+{{end}}
+
+//String implement Stringer interface{{if isFlag .}}, combined flags are rendered as names separated by '|'{{end}}
 func (val {{$Enum}}) String() string {
     switch val {
         {{range .Constants}}
@@ -33,7 +121,57 @@ func (val {{$Enum}}) String() string {
             return "{{$Enum}}.{{title .Name}}"
         {{end}}
     }
+    {{if isFlag .}}
+    var names []string
+
+    rest := val
+
+    {{range .Constants}}{{if ne .Value 0}}
+    if val.Has({{$Enum}}{{title .Name}}) {
+        names = append(names,"{{title .Name}}")
+        rest &^= {{$Enum}}{{title .Name}}
+    }
+    {{end}}{{end}}
+
+    if rest != 0 {
+        names = append(names,fmt.Sprintf("0x%x",uint32(rest)))
+    }
+
+    if len(names) == 0 {
+        return "0"
+    }
+
+    return strings.Join(names,"|")
+    {{else}}
     return fmt.Sprintf("enum(Unknown(%d))",val)
+    {{end}}
+}
+
+//MarshalText implement encoding.TextMarshaler, render enum by constant name
+func (val {{$Enum}}) MarshalText() ([]byte, error) {
+    return []byte(val.String()), nil
+}
+
+//UnmarshalText implement encoding.TextUnmarshaler, parse enum by constant name
+func (val *{{$Enum}}) UnmarshalText(text []byte) (err error) {
+    *val,err = Parse{{$Enum}}(string(text))
+    return
+}
+
+//MarshalJSON implement json.Marshaler
+func (val {{$Enum}}) MarshalJSON() ([]byte, error) {
+    return json.Marshal(val.String())
+}
+
+//UnmarshalJSON implement json.Unmarshaler
+func (val *{{$Enum}}) UnmarshalJSON(data []byte) error {
+    var name string
+
+    if err := json.Unmarshal(data,&name); err != nil {
+        return err
+    }
+
+    return val.UnmarshalText([]byte(name))
 }
 
 {{end}}
@@ -41,9 +179,12 @@ func (val {{$Enum}}) String() string {
 {{define "table"}} {{$Table := title .Name}}
 
 //{{$Table}} -- generate by gsc
-type {{$Table}} struct {
+{{doc .}}type {{$Table}} struct {
     {{range .Fields}}
-    {{title .Name}} {{typeName .Type}}
+    {{doc .}}{{title .Name}} {{fieldType .}} {{goTag .}}
+    {{end}}
+    {{if isPOD . | not}}
+    unknown []*gorpc.Unknown // unknown fields preserved for re-encoding
     {{end}}
 }
 
@@ -57,10 +198,216 @@ func (e * {{$Table}}) Error() string {
 //New{{$Table}} create new struct object with default field val -- generate by gsc
 func New{{$Table}}() *{{$Table}} {
     return &{{$Table}}{
+        {{range .Fields}}{{if isOptional . | not}}
+        {{title .Name}}: {{fieldDefault .}},
+        {{end}}{{end}}
+    }
+}
+
+{{range .Fields}}{{if isOptional .}}
+//Has{{title .Name}} check if the optional field {{title .Name}} is present -- generate by gsc
+func (val *{{$Table}}) Has{{title .Name}}() bool {
+    return val.{{title .Name}} != nil
+}
+{{end}}{{end}}
+
+//Equal check if val deep equals other,the unknown fields are ignored -- generate by gsc
+func (val *{{$Table}}) Equal(other *{{$Table}}) bool {
+
+    if val == other {
+        return true
+    }
+
+    if val == nil || other == nil {
+        return false
+    }
+
+    {{range .Fields}}
+    {{if isPointer .}}
+    if (val.{{title .Name}} == nil) != (other.{{title .Name}} == nil) {
+        return false
+    }
+
+    {{if isScalar .Type}}
+    if val.{{title .Name}} != nil && *val.{{title .Name}} != *other.{{title .Name}} {
+        return false
+    }
+    {{else}}
+    if val.{{title .Name}} != nil && !{{equalType .Type}}(*val.{{title .Name}},*other.{{title .Name}}) {
+        return false
+    }
+    {{end}}
+    {{else if isScalar .Type}}
+    if val.{{title .Name}} != other.{{title .Name}} {
+        return false
+    }
+    {{else}}
+    if !{{equalType .Type}}(val.{{title .Name}},other.{{title .Name}}) {
+        return false
+    }
+    {{end}}
+    {{end}}
+
+    return true
+}
+
+//Clone create deep copy of {{$Table}} -- generate by gsc
+func (val *{{$Table}}) Clone() *{{$Table}} {
+
+    if val == nil {
+        return nil
+    }
+
+    target := &{{$Table}}{}
+
+    target.CopyFrom(val)
+
+    return target
+}
+
+//CopyFrom deep copy fields from source -- generate by gsc
+func (val *{{$Table}}) CopyFrom(source *{{$Table}}) {
+
+    if source == nil {
+        return
+    }
+
+    {{range .Fields}}
+    {{if isPointer .}}
+    val.{{title .Name}} = nil
+
+    if source.{{title .Name}} != nil {
+        {{if isScalar .Type}}
+        value := *source.{{title .Name}}
+        {{else}}
+        value := {{cloneType .Type}}(*source.{{title .Name}})
+        {{end}}
+        val.{{title .Name}} = &value
+    }
+    {{else if isScalar .Type}}
+    val.{{title .Name}} = source.{{title .Name}}
+    {{else}}
+    val.{{title .Name}} = {{cloneType .Type}}(source.{{title .Name}})
+    {{end}}
+    {{end}}
+
+    {{if isPOD . | not}}
+    val.unknown = nil
+
+    for _,unknown := range source.unknown {
+        val.unknown = append(val.unknown,unknown.Clone())
+    }
+    {{end}}
+}
+
+{{range .Fields}}{{patternDecl $Table .}}{{end}}
+//Validate check the field constraints and report all violations with field path as *gorpc.InvalidArgument -- generate by gsc
+func (val *{{$Table}}) Validate() error {
+
+    if val == nil {
+        return nil
+    }
+
+    var violations []string
+
+    {{range .Fields}}{{validateField $Table .}}{{end}}
+
+    if len(violations) != 0 {
+        return &gorpc.InvalidArgument{Violations:violations}
+    }
+
+    return nil
+}
+
+//MarshalJSON implement json.Marshaler -- generate by gsc
+func (val *{{$Table}}) MarshalJSON() ([]byte, error) {
+
+    if val == nil {
+        return []byte("null"), nil
+    }
+
+    content := struct {
         {{range .Fields}}
-        {{title .Name}}: {{defaultVal .Type}},
+        {{title .Name}} {{if jsonSafe .Type}}{{fieldType .}}{{else}}interface{}{{end}} {{jsonTag .}}
         {{end}}
+    }{}
+
+    {{range .Fields}}
+    {{if jsonSafe .Type}}
+    content.{{title .Name}} = val.{{title .Name}}
+    {{else if isPointer .}}
+    if val.{{title .Name}} != nil {
+        encoded,err := {{jsonEncode .Type}}(*val.{{title .Name}})
+        if err != nil {
+            return nil,err
+        }
+        content.{{title .Name}} = encoded
+    }
+    {{else}}
+    {
+        encoded,err := {{jsonEncode .Type}}(val.{{title .Name}})
+        if err != nil {
+            return nil,err
+        }
+        content.{{title .Name}} = encoded
     }
+    {{end}}
+    {{end}}
+
+    return json.Marshal(&content)
+}
+
+//UnmarshalJSON implement json.Unmarshaler,the absent fields keep their values -- generate by gsc
+func (val *{{$Table}}) UnmarshalJSON(data []byte) (err error) {
+
+    content := struct {
+        {{range .Fields}}
+        {{title .Name}} {{if jsonSafe .Type}}{{fieldType .}}{{else}}json.RawMessage{{end}} {{jsonTag .}}
+        {{end}}
+    }{}
+
+    {{range .Fields}}{{if jsonSafe .Type}}
+    content.{{title .Name}} = val.{{title .Name}}
+    {{end}}{{end}}
+
+    if err = json.Unmarshal(data,&content); err != nil {
+        return
+    }
+
+    {{range .Fields}}
+    {{if jsonSafe .Type}}
+    val.{{title .Name}} = content.{{title .Name}}
+    {{else if isPointer .}}
+    if content.{{title .Name}} != nil {
+
+        val.{{title .Name}} = nil
+
+        if string(content.{{title .Name}}) != "null" {
+
+            var value {{typeName .Type}}
+
+            value,err = {{jsonDecode .Type}}(content.{{title .Name}})
+
+            if err != nil {
+                return
+            }
+
+            val.{{title .Name}} = &value
+        }
+    }
+    {{else}}
+    if content.{{title .Name}} != nil {
+
+        val.{{title .Name}},err = {{jsonDecode .Type}}(content.{{title .Name}})
+
+        if err != nil {
+            return
+        }
+    }
+    {{end}}
+    {{end}}
+
+    return
 }
 
 {{if isPOD .}}
@@ -71,11 +418,25 @@ func Read{{$Table}}(reader gorpc.Reader) (target *{{$Table}},err error) {
     {{range .Fields}}
 
     {
+        {{if isOptional .}}
+        var present bool
+
+        present,err = gorpc.ReadBool(reader)
+
+        if err != nil {
+            return
+        }
+
+        if present {
+            {{template "readOptional" .}}
+        }
+        {{else}}
         target.{{title .Name}},err = {{readType .Type}}(reader)
 
         if err != nil {
             return
         }
+        {{end}}
     }
     {{end}}
 
@@ -86,12 +447,29 @@ func Read{{$Table}}(reader gorpc.Reader) (target *{{$Table}},err error) {
 //Write{{$Table}} write {{$Table}} to output stream -- generate by gsc
 func Write{{$Table}}(writer gorpc.Writer,val *{{$Table}}) (err error) {
 
+    if val == nil {
+        return gserrors.Newf(nil,"can't write nil {{$Table}}")
+    }
+
     {{range .Fields}}
+    {{if isOptional .}}
+    err = gorpc.WriteBool(writer,val.{{title .Name}} != nil)
+    if err != nil {
+        return
+    }
+    if val.{{title .Name}} != nil {
+        err = {{writeType .Type}}(writer,{{if isPointer .}}*{{end}}val.{{title .Name}})
+        if err != nil {
+            return
+        }
+    }
+    {{else}}
     err = {{writeType .Type}}(writer,val.{{title .Name}})
     if err != nil {
         return
     }
     {{end}}
+    {{end}}
     return nil
 }
 
@@ -100,92 +478,349 @@ func Write{{$Table}}(writer gorpc.Writer,val *{{$Table}}) (err error) {
 func Read{{$Table}}(reader gorpc.Reader) (target *{{$Table}},err error) {
     target = New{{$Table}}()
 
-    var fields byte
+    var fields uint16
+
+    fields,err = gorpc.ReadUInt16(reader)
+
+    if err != nil || fields == 0 {
+        return
+    }
+
+    {{range .Fields}}
+
+    {
+        var tag []byte
+        tag,err = gorpc.ReadTags(reader)
+
+        if err != nil {
+            return
+        }
+
+        if tag[0] != byte(gorpc.TagSkip) {
+            {{if isOptional .}}
+            {{template "readOptional" .}}
+            {{else}}
+            target.{{title .Name}},err = {{readType .Type}}(reader)
+
+            if err != nil {
+                return
+            }
+            {{end}}
+        }
+
+        fields --
+
+        if fields == 0 {
+            return
+        }
+    }
+    {{end}}
+
+    for i :=0;i < int(fields); i ++ {
+
+        var unknown *gorpc.Unknown
+
+        unknown,err = gorpc.ReadUnknown(reader)
+
+        if err != nil {
+            return
+        }
+
+        target.unknown = append(target.unknown,unknown)
+    }
+
+
+    return
+}
+
+
+//Write{{$Table}} write {{$Table}} to output stream -- generate by gsc
+func Write{{$Table}}(writer gorpc.Writer,val *{{$Table}}) (err error) {
+
+    if val == nil {
+        return gserrors.Newf(nil,"can't write nil {{$Table}}")
+    }
+
+    err = gorpc.WriteUInt16(writer,uint16({{len .Fields}} + len(val.unknown)))
+
+    if err != nil {
+        return
+    }
+
+    {{range .Fields}}
+    {{if isOptional .}}
+    if val.{{title .Name}} == nil {
+        err = gorpc.WriteByte(writer,byte(gorpc.TagSkip))
+        if err != nil {
+            return
+        }
+    } else {
+        err = gorpc.WriteBytes(writer,[]byte{ {{tagValue .Type}} })
+        if err != nil {
+            return
+        }
+        err = {{writeType .Type}}(writer,{{if isPointer .}}*{{end}}val.{{title .Name}})
+        if err != nil {
+            return
+        }
+    }
+    {{else}}
+    err = gorpc.WriteBytes(writer,[]byte{ {{tagValue .Type}} })
+    if err != nil {
+        return
+    }
+    err = {{writeType .Type}}(writer,val.{{title .Name}})
+    if err != nil {
+        return
+    }
+    {{end}}
+    {{end}}
+
+    for _,unknown := range val.unknown {
+        err = gorpc.WriteUnknown(writer,unknown)
+        if err != nil {
+            return
+        }
+    }
+
+    return nil
+}
+{{end}}
+
+
+{{annotations .}}
+{{end}}
+
+{{define "union"}} {{$Union := title .Name}}
+
+//{{$Union}} union type, the value is one of variants:{{range .Fields}} *{{$Union}}{{title .Name}}{{end}} -- generate by gsc
+{{doc .}}type {{$Union}} interface {
+    is{{$Union}}()
+}
+
+{{range .Fields}}
+//{{$Union}}{{title .Name}} {{$Union}} variant -- generate by gsc
+{{doc .}}type {{$Union}}{{title .Name}} struct {
+    {{title .Name}} {{typeName .Type}}
+}
+
+func (*{{$Union}}{{title .Name}}) is{{$Union}}() {}
+{{end}}
+
+//Read{{$Union}} read {{$Union}} from input stream,unknown variant is skipped and return nil -- generate by gsc
+func Read{{$Union}}(reader gorpc.Reader) (target {{$Union}},err error) {
+
+    var variant byte
+
+    variant,err = gorpc.ReadByte(reader)
+
+    if err != nil || variant == 0 {
+        return
+    }
+
+    switch variant {
+    {{range $index,$field := .Fields}}
+    case {{variant $index}}:
+
+        _,err = gorpc.ReadTags(reader)
+
+        if err != nil {
+            return
+        }
+
+        val := &{{$Union}}{{title .Name}}{}
+
+        val.{{title .Name}},err = {{readType .Type}}(reader)
+
+        if err != nil {
+            return
+        }
+
+        target = val
+    {{end}}
+    default:
+        _,err = gorpc.ReadUnknown(reader)
+    }
+
+    return
+}
+
+//Equal{{$Union}} check if lhs deep equals rhs -- generate by gsc
+func Equal{{$Union}}(lhs,rhs {{$Union}}) bool {
+
+    switch lhs := lhs.(type) {
+    {{range .Fields}}
+    case *{{$Union}}{{title .Name}}:
+        rhs,ok := rhs.(*{{$Union}}{{title .Name}})
+
+        if !ok || lhs == nil || rhs == nil {
+            return ok && lhs == rhs
+        }
+
+        return {{equalType .Type}}(lhs.{{title .Name}},rhs.{{title .Name}})
+    {{end}}
+    }
+
+    return rhs == nil
+}
+
+//Clone{{$Union}} create deep copy of {{$Union}} -- generate by gsc
+func Clone{{$Union}}(val {{$Union}}) {{$Union}} {
+
+    switch val := val.(type) {
+    {{range .Fields}}
+    case *{{$Union}}{{title .Name}}:
+        if val == nil {
+            return val
+        }
+
+        return &{{$Union}}{{title .Name}}{ {{title .Name}}: {{cloneType .Type}}(val.{{title .Name}}) }
+    {{end}}
+    }
+
+    return nil
+}
+
+{{range .Fields}}{{patternDecl $Union .}}{{end}}
+//Validate{{$Union}} check the variant value constraints and report all violations with variant path as *gorpc.InvalidArgument -- generate by gsc
+func Validate{{$Union}}(target {{$Union}}) error {
+
+    var violations []string
+
+    switch val := target.(type) {
+    {{range .Fields}}
+    case *{{$Union}}{{title .Name}}:
+        if val == nil {
+            return nil
+        }
+
+        {{validateField $Union .}}
+    {{end}}
+    }
+
+    if len(violations) != 0 {
+        return &gorpc.InvalidArgument{Violations:violations}
+    }
+
+    return nil
+}
+
+{{range .Fields}}
+//MarshalJSON implement json.Marshaler,the variant is encoded as {"{{title .Name}}":value} -- generate by gsc
+func (val *{{$Union}}{{title .Name}}) MarshalJSON() ([]byte, error) {
+
+    if val == nil {
+        return []byte("null"), nil
+    }
 
-    fields,err = gorpc.ReadByte(reader)
+    {{if jsonSafe .Type}}
+    return json.Marshal(struct {
+        {{title .Name}} {{typeName .Type}} {{jsonTag .}}
+    }{ val.{{title .Name}} })
+    {{else}}
+    encoded,err := {{jsonEncode .Type}}(val.{{title .Name}})
 
     if err != nil {
-        return
+        return nil,err
     }
 
-    {{range .Fields}}
+    return json.Marshal(struct {
+        {{title .Name}} interface{} {{jsonTag .}}
+    }{ encoded })
+    {{end}}
+}
+{{end}}
 
-    {
-        var tag byte
-        tag,err = gorpc.ReadByte(reader)
+//Unmarshal{{$Union}}JSON decode {{$Union}} from json object with single variant name key -- generate by gsc
+func Unmarshal{{$Union}}JSON(data []byte) (target {{$Union}},err error) {
 
-        if err != nil {
-            return
-        }
+    var content map[string]json.RawMessage
 
-        if tag != byte(gorpc.TagSkip) {
-            target.{{title .Name}},err = {{readType .Type}}(reader)
+    if err = json.Unmarshal(data,&content); err != nil || content == nil {
+        return
+    }
 
-            if err != nil {
-                return
-            }
-        }
+    if len(content) != 1 {
+        err = fmt.Errorf("expect one {{$Union}} variant, got %d",len(content))
+        return
+    }
 
-        fields --
+    for name,data := range content {
+        switch name {
+        {{range .Fields}}
+        case "{{title .Name}}":
+            val := &{{$Union}}{{title .Name}}{}
 
-        if fields == 0 {
-            return
+            {{if jsonSafe .Type}}
+            err = json.Unmarshal(data,&val.{{title .Name}})
+            {{else}}
+            val.{{title .Name}},err = {{jsonDecode .Type}}(data)
+            {{end}}
+
+            target = val
+        {{end}}
+        default:
+            err = fmt.Errorf("unknown {{$Union}} variant :%s",name)
         }
     }
-    {{end}}
 
-    for i :=0;i < int(fields); i ++ {
+    return
+}
 
-        var tag byte
+//Write{{$Union}} write {{$Union}} to output stream -- generate by gsc
+func Write{{$Union}}(writer gorpc.Writer,val {{$Union}}) (err error) {
 
-        tag,err = gorpc.ReadByte(reader)
+    switch val := val.(type) {
+    {{range $index,$field := .Fields}}
+    case *{{$Union}}{{title .Name}}:
 
+        err = gorpc.WriteByte(writer,{{variant $index}})
         if err != nil {
             return
         }
 
-        if tag == byte(gorpc.TagSkip) {
-            continue
+        err = gorpc.WriteBytes(writer,[]byte{ {{tagValue .Type}} })
+        if err != nil {
+            return
         }
 
-        gorpc.SkipRead(reader,gorpc.Tag(tag))
+        err = {{writeType .Type}}(writer,val.{{title .Name}})
+    {{end}}
+    default:
+        err = gorpc.WriteByte(writer,0)
     }
 
-
     return
 }
+{{end}}
 
+{{define "readOptional"}}
+{{if isPointer .}}
+var value {{typeName .Type}}
 
-//Write{{$Table}} write {{$Table}} to output stream -- generate by gsc
-func Write{{$Table}}(writer gorpc.Writer,val *{{$Table}}) (err error) {
+value,err = {{readType .Type}}(reader)
 
-    err = gorpc.WriteByte(writer,byte({{len .Fields}}))
+if err != nil {
+    return
+}
 
-    if err != nil {
-        return
-    }
+target.{{title .Name}} = &value
+{{else}}
+target.{{title .Name}},err = {{readType .Type}}(reader)
 
-    {{range .Fields}}
-    gorpc.WriteByte(writer,byte({{tagValue .Type}}))
-    err = {{writeType .Type}}(writer,val.{{title .Name}})
-    if err != nil {
-        return
-    }
-    {{end}}
-    return nil
+if err != nil {
+    return
 }
 {{end}}
-
-
 {{end}}
 
 
 {{define "contract"}}{{$Contract := title .Name}}
 
 //{{$Contract}} -- generate by gsc
-type {{$Contract}} interface {
+{{doc .}}type {{$Contract}} interface {
     {{range .Methods}}
-    {{title .Name}}{{params .Params}}{{returnParam .Return}}
+    {{doc .}}{{title .Name}}{{params .Params}}{{returnParam .Return}}
     {{end}}
 }
 
@@ -215,6 +850,7 @@ func (maker *_{{$Contract}}Maker) String() string {
     return "{{.FullName}}"
 }
 
+{{range .Methods}}{{$Method := title .Name}}{{range .Params}}{{patternDecl (print $Contract "_" $Method) .}}{{end}}{{end}}
 // Dispatch implement gorpc.Dispatcher
 func (maker *_{{$Contract}}Maker) Dispatch(call *gorpc.Request) (callReturn *gorpc.Response, err error) {
 
@@ -261,6 +897,33 @@ func (maker *_{{$Contract}}Maker) Dispatch(call *gorpc.Request) (callReturn *gor
         var retval {{typeName .Return}}
         {{end}}{{end}}
 
+        {{$Violations := validateParams $Contract .}}
+        {{if $Violations}}
+        {{$Violations}}
+
+        if err != nil {
+            {{if isAsync . | not}}
+            // the violations are returned as the reserved InvalidArgument exception id(-2)
+            var buff bytes.Buffer
+
+            if err = gorpc.WriteInvalidArgument(&buff,err.(*gorpc.InvalidArgument)); err != nil {
+                return
+            }
+
+            callReturn = &gorpc.Response{
+                ID : call.ID,
+                Exception:int8(-2),
+                Trace:call.Trace,
+            }
+
+            callReturn.Content = buff.Bytes()
+
+            err = nil
+            {{end}}
+            return
+        }
+        {{end}}
+
         {{returnArgs .Return}} = maker.impl.{{$Name}}{{callArgs .Params}}
 
         {{if isAsync . | not }}
@@ -416,6 +1079,17 @@ func (binder *_{{$Contract}}Binder){{$Name}}{{params .Params}}{{returnParam .Ret
 
     if callReturn.Exception != -1 {
         switch callReturn.Exception {
+        case -2:
+            var exception error
+            exception,err = gorpc.ReadInvalidArgument(bytes.NewBuffer(callReturn.Content))
+
+            if err != nil {
+                err = gserrors.Newf(err,"read {{$Contract}}#{{$Name}} return")
+            } else {
+                err = exception
+            }
+
+            return
         {{range .Exceptions}}
         case {{.ID}}:
             var exception error
@@ -450,6 +1124,7 @@ func (binder *_{{$Contract}}Binder){{$Name}}{{params .Params}}{{returnParam .Ret
 }
 {{end}}
 
+{{annotations .}}
 {{end}}
 
 
@@ -472,6 +1147,239 @@ func (binder *_{{$Contract}}Binder){{$Name}}{{params .Params}}{{returnParam .Ret
 
 
 
+{{define "equalValue"}}func(lhs,rhs {{typeName .}}) bool {
+    return lhs == rhs
+}{{end}}
+
+{{define "equalList"}}func(lhs,rhs {{typeName .}}) bool {
+    if len(lhs) != len(rhs) {
+        return false
+    }
+    for i := range lhs {
+        if !{{equalType .Component}}(lhs[i],rhs[i]) {
+            return false
+        }
+    }
+    return true
+}{{end}}
+
+{{define "equalMap"}}func(lhs,rhs {{typeName .}}) bool {
+    if len(lhs) != len(rhs) {
+        return false
+    }
+    for key,val := range lhs {
+        other,ok := rhs[key]
+        if !ok || !{{mapValue . | equalType}}(val,other) {
+            return false
+        }
+    }
+    return true
+}{{end}}
+
+{{define "equalCustom"}}func(lhs,rhs {{customType .}}) bool {
+    lhsTarget,err := {{customCodec .}}.Encode(lhs)
+    if err != nil {
+        gserrors.Panicf(err,"encode {{customType .}} error")
+    }
+    rhsTarget,err := {{customCodec .}}.Encode(rhs)
+    if err != nil {
+        gserrors.Panicf(err,"encode {{customType .}} error")
+    }
+    return lhsTarget.Equal(rhsTarget)
+}{{end}}
+
+{{define "cloneCustom"}}func(val {{customType .}}) {{customType .}} {
+    target,err := {{customCodec .}}.Encode(val)
+    if err != nil {
+        gserrors.Panicf(err,"encode {{customType .}} error")
+    }
+    clone,err := {{customCodec .}}.Decode(target)
+    if err != nil {
+        gserrors.Panicf(err,"decode {{customType .}} error")
+    }
+    return clone
+}{{end}}
+
+{{define "cloneValue"}}func(val {{typeName .}}) {{typeName .}} {
+    return val
+}{{end}}
+
+{{define "cloneList"}}func(val {{typeName .}}) {{typeName .}} {
+    if val == nil {
+        return nil
+    }
+    buff := make({{typeName .}},len(val))
+    {{if isScalar .Component}}
+    copy(buff,val)
+    {{else}}
+    for i := range val {
+        buff[i] = {{cloneType .Component}}(val[i])
+    }
+    {{end}}
+    return buff
+}{{end}}
+
+{{define "cloneArray"}}func(val {{typeName .}}) (buff {{typeName .}}) {
+    for i := range val {
+        buff[i] = {{cloneType .Component}}(val[i])
+    }
+    return
+}{{end}}
+
+{{define "cloneMap"}}func(val {{typeName .}}) {{typeName .}} {
+    if val == nil {
+        return nil
+    }
+    buff := make({{typeName .}},len(val))
+    for key,v := range val {
+        buff[key] = {{mapValue . | cloneType}}(v)
+    }
+    return buff
+}{{end}}
+
+{{define "validateTable"}}func(path string,val {{typeName .}}) (violations []string) {
+    if err,ok := {{if isOneOf .}}{{unionFunc "Validate" .}}(val){{else}}val.Validate(){{end}}.(*gorpc.InvalidArgument); ok {
+        for _,violation := range err.Violations {
+            violations = append(violations,path + "." + violation)
+        }
+    }
+    return
+}{{end}}
+
+{{define "validateList"}}func(path string,val {{typeName .}}) (violations []string) {
+    for i,item := range val {
+        violations = append(violations,{{validateType .Component}}(fmt.Sprintf("%s[%d]",path,i),item)...)
+    }
+    return
+}{{end}}
+
+{{define "validateMap"}}func(path string,val {{typeName .}}) (violations []string) {
+    for key,item := range val {
+        violations = append(violations,{{mapValue . | validateType}}(fmt.Sprintf("%s[%v]",path,key),item)...)
+    }
+    return
+}{{end}}
+
+{{define "jsonEncodeValue"}}func(val {{typeName .}}) (interface{},error) {
+    return val,nil
+}{{end}}
+
+{{define "jsonEncodeCustom"}}func(val {{customType .}}) (interface{},error) {
+    target,err := {{customCodec .}}.Encode(val)
+    if err != nil {
+        return nil,gserrors.Newf(err,"encode {{customType .}} error")
+    }
+    return target,nil
+}{{end}}
+
+{{define "jsonEncodeUInt64"}}func(val uint64) (interface{},error) {
+    return strconv.FormatUint(val,10),nil
+}{{end}}
+
+{{define "jsonEncodeBytes"}}func(val {{typeName .}}) (interface{},error) {
+    return val[:],nil
+}{{end}}
+
+{{define "jsonEncodeList"}}func(val {{typeName .}}) (interface{},error) {
+    {{if eq .Size -1}}
+    if val == nil {
+        return nil,nil
+    }
+    {{end}}
+    buff := make([]interface{},len(val))
+    for i := range val {
+        var err error
+        if buff[i],err = {{jsonEncode .Component}}(val[i]); err != nil {
+            return nil,err
+        }
+    }
+    return buff,nil
+}{{end}}
+
+{{define "jsonEncodeMap"}}func(val {{typeName .}}) (interface{},error) {
+    if val == nil {
+        return nil,nil
+    }
+    buff := make(map[{{mapKey . | typeName}}]interface{},len(val))
+    for key,v := range val {
+        var err error
+        if buff[key],err = {{mapValue . | jsonEncode}}(v); err != nil {
+            return nil,err
+        }
+    }
+    return buff,nil
+}{{end}}
+
+{{define "jsonDecodeValue"}}func(data json.RawMessage) (val {{typeName .}},err error) {
+    err = json.Unmarshal(data,&val)
+    return
+}{{end}}
+
+{{define "jsonDecodeCustom"}}func(data json.RawMessage) (val {{customType .}},err error) {
+    var target {{tableName .}}
+    if err = json.Unmarshal(data,&target); err != nil || target == nil {
+        return
+    }
+    return {{customCodec .}}.Decode(target)
+}{{end}}
+
+{{define "jsonDecodeUInt64"}}func(data json.RawMessage) (uint64,error) {
+    if string(data) == "null" {
+        return 0,nil
+    }
+    return strconv.ParseUint(strings.Trim(string(data),"\""),10,64)
+}{{end}}
+
+{{define "jsonDecodeBytes"}}func(data json.RawMessage) (buff {{typeName .}},err error) {
+    var content []byte
+    if err = json.Unmarshal(data,&content); err != nil || content == nil {
+        return
+    }
+    if len(content) != {{.Size}} {
+        err = gserrors.Newf(nil,"check array size failed, expect {{.Size}} bytes got %d",len(content))
+        return
+    }
+    copy(buff[:],content)
+    return
+}{{end}}
+
+{{define "jsonDecodeList"}}func(data json.RawMessage) (buff {{typeName .}},err error) {
+    var content []json.RawMessage
+    if err = json.Unmarshal(data,&content); err != nil || content == nil {
+        return
+    }
+    {{if eq .Size -1}}
+    buff = make({{typeName .}},len(content))
+    {{else}}
+    if len(content) != {{.Size}} {
+        err = gserrors.Newf(nil,"check array size failed, expect {{.Size}} elements got %d",len(content))
+        return
+    }
+    {{end}}
+    for i := range content {
+        buff[i],err = {{jsonDecode .Component}}(content[i])
+        if err != nil {
+            return
+        }
+    }
+    return
+}{{end}}
+
+{{define "jsonDecodeMap"}}func(data json.RawMessage) (buff {{typeName .}},err error) {
+    var content map[{{mapKey . | typeName}}]json.RawMessage
+    if err = json.Unmarshal(data,&content); err != nil || content == nil {
+        return
+    }
+    buff = make({{typeName .}},len(content))
+    for key,v := range content {
+        buff[key],err = {{mapValue . | jsonDecode}}(v)
+        if err != nil {
+            return
+        }
+    }
+    return
+}{{end}}
+
 {{define "readList"}}func(reader gorpc.Reader)({{typeName .}},error) {
     length ,err := gorpc.ReadUInt16(reader)
     if err != nil {
@@ -544,6 +1452,49 @@ func (binder *_{{$Contract}}Binder){{$Name}}{{params .Params}}{{returnParam .Ret
 }{{end}}
 
 
+{{define "readMap"}}func(reader gorpc.Reader)({{typeName .}},error) {
+    length ,err := gorpc.ReadUInt16(reader)
+    if err != nil {
+        return nil,err
+    }
+    buff := make({{typeName .}},length)
+    for i := uint16(0); i < length; i ++ {
+        var key {{mapKey . | typeName}}
+        key ,err = {{mapKey . | readType}}(reader)
+        if err != nil {
+            return buff,err
+        }
+        buff[key] ,err = {{mapValue . | readType}}(reader)
+        if err != nil {
+            return buff,err
+        }
+    }
+    return buff,nil
+}{{end}}
+
+{{define "writeMap"}}func(writer gorpc.Writer,val {{typeName .}})(error) {
+    err := gorpc.WriteUInt16(writer,uint16(len(val)))
+    if err != nil {
+        return err
+    }
+    keys := make([]{{mapKey . | typeName}},0,len(val))
+    for key := range val {
+        keys = append(keys,key)
+    }
+    sort.Slice(keys,func(i, j int) bool { return keys[i] < keys[j] })
+    for _,key := range keys {
+        err = {{mapKey . | writeType}}(writer,key)
+        if err != nil {
+            return err
+        }
+        err = {{mapValue . | writeType}}(writer,val[key])
+        if err != nil {
+            return err
+        }
+    }
+    return nil
+}{{end}}
+
 {{define "writeList"}}func(writer gorpc.Writer,val {{typeName .}})(error) {
     gorpc.WriteUInt16(writer,uint16(len(val)))
     for _,c:= range val {
@@ -588,4 +1539,50 @@ func (binder *_{{$Contract}}Binder){{$Name}}{{params .Params}}{{returnParam .Ret
     return nil
 }{{end}}
 
+{{define "time.go"}}package gorpc
+
+import (
+    "math"
+    "time"
+
+    "github.com/gsdocker/gserrors"
+)
+
+//TimeCodec the @GoType codec which maps Time to time.Time, the zero time.Time is encoded as zero Time -- generate by gsc
+var TimeCodec timeCodec
+
+type timeCodec struct{}
+
+//Encode encode time.Time as the seconds and nanoseconds since unix epoch
+func (timeCodec) Encode(val time.Time) (*Time, error) {
+
+    if val.IsZero() {
+        return &Time{}, nil
+    }
+
+    if val.Unix() < 0 {
+        return nil, gserrors.Newf(nil, "can't encode time before unix epoch :%v", val)
+    }
+
+    return &Time{Second: uint64(val.Unix()), Nano: uint64(val.Nanosecond())}, nil
+}
+
+//Decode decode time.Time in UTC from the seconds and nanoseconds since unix epoch
+func (timeCodec) Decode(val *Time) (time.Time, error) {
+
+    if val == nil {
+        return time.Time{}, gserrors.Newf(nil, "can't decode nil Time")
+    }
+
+    if val.Second == 0 && val.Nano == 0 {
+        return time.Time{}, nil
+    }
+
+    if val.Second > math.MaxInt64 || val.Nano >= uint64(time.Second) {
+        return time.Time{}, gserrors.Newf(nil, "invalid Time(%d,%d)", val.Second, val.Nano)
+    }
+
+    return time.Unix(int64(val.Second), int64(val.Nano)).UTC(), nil
+}
+{{end}}
 `
@@ -4,17 +4,22 @@ import (
 	"bytes"
 	"fmt"
 	"io/ioutil"
+	"math"
 	"os"
 	"path/filepath"
 	"regexp"
+	"strconv"
 	"strings"
 	"text/template"
+	"unicode/utf16"
 
 	"github.com/gsdocker/gserrors"
 	"github.com/gsdocker/gslogger"
 	"github.com/gsrpc/gslang"
 	"github.com/gsrpc/gslang/ast"
 	"github.com/gsrpc/gslang/lexer"
+	"github.com/gsrpc/gsrpc/gen"
+	"github.com/gsrpc/gsrpc/wire"
 )
 
 var builtin = map[lexer.TokenType]string{
@@ -103,12 +108,14 @@ var imports = map[string]string{
 type _CodeGen struct {
 	gslogger.Log                    // Log APIs
 	rootpath     string             // root path
+	files        map[string][]byte  // generated files sink, the files are written into rootpath if nil
 	script       *ast.Script        // current script
 	tpl          *template.Template // code generate template
 	imports      map[string]string  // imports
 	packageName  string             // package name
 	scriptPath   string             // script path
 	skips        []*regexp.Regexp   // skip lists
+	compiler     *gslang.Compiler   // current compiler
 }
 
 // NewCodeGen .
@@ -142,6 +149,7 @@ func NewCodeGen(rootpath string, skips []string) (gslang.Visitor, error) {
 		"methodName":  methodName,
 		"fieldName":   fieldname,
 		"enumFields":  codeGen.enumFields,
+		"doc":         doc,
 		"notVoid":     gslang.NotVoid,
 		"isPOD":       gslang.IsPOD,
 		"isAsync":     gslang.IsAsync,
@@ -150,28 +158,41 @@ func NewCodeGen(rootpath string, skips []string) (gslang.Visitor, error) {
 		"enumType": func(typeDecl ast.Type) string {
 			return builtin[gslang.EnumType(typeDecl)]
 		},
-		"builtin":         gslang.IsBuiltin,
-		"typeName":        codeGen.typeName,
-		"objTypeName":     codeGen.objTypeName,
-		"defaultVal":      codeGen.defaultVal,
-		"readType":        codeGen.readType,
-		"writeType":       codeGen.writeType,
-		"params":          codeGen.params,
-		"returnParam":     codeGen.returnParam,
-		"callArgs":        codeGen.callArgs,
-		"returnArgs":      codeGen.returnArgs,
-		"marshalField":    codeGen.marshalfield,
-		"unmarshalField":  codeGen.unmarshalfield,
-		"unmarshalParam":  codeGen.unmarshalParam,
-		"methodcall":      codeGen.methodcall,
-		"marshalParam":    codeGen.marshalParam,
-		"marshalReturn":   codeGen.marshalReturn,
-		"methodRPC":       codeGen.methodRPC,
-		"marshalParams":   codeGen.marshalParams,
-		"callback":        codeGen.callback,
-		"unmarshalReturn": codeGen.unmarshalReturn,
-		"constructor":     codeGen.constructor,
-		"tagValue":        codeGen.tagValue,
+		"builtin":          gslang.IsBuiltin,
+		"typeName":         codeGen.typeName,
+		"objTypeName":      codeGen.objTypeName,
+		"defaultVal":       codeGen.defaultVal,
+		"readType":         codeGen.readType,
+		"writeType":        codeGen.writeType,
+		"params":           codeGen.params,
+		"returnParam":      codeGen.returnParam,
+		"callArgs":         codeGen.callArgs,
+		"returnArgs":       codeGen.returnArgs,
+		"marshalField":     codeGen.marshalfield,
+		"unmarshalField":   codeGen.unmarshalfield,
+		"unmarshalParam":   codeGen.unmarshalParam,
+		"methodcall":       codeGen.methodcall,
+		"marshalParam":     codeGen.marshalParam,
+		"marshalReturn":    codeGen.marshalReturn,
+		"methodRPC":        codeGen.methodRPC,
+		"marshalParams":    codeGen.marshalParams,
+		"callback":         codeGen.callback,
+		"unmarshalReturn":  codeGen.unmarshalReturn,
+		"constructor":      codeGen.constructor,
+		"tagValue":         codeGen.tagValue,
+		"tags":             codeGen.tags,
+		"isOptional":       isOptional,
+		"fieldType":        codeGen.fieldType,
+		"fieldDefault":     codeGen.fieldDefault,
+		"boxTypeName":      codeGen.boxTypeName,
+		"variant":          variant,
+		"wireCases":        wire.Cases,
+		"wireNested":       wire.Nested,
+		"tagByte":          tagByte,
+		"marshalVariant":   codeGen.marshalVariant,
+		"unmarshalVariant": codeGen.unmarshalVariant,
+		"toJSON":           codeGen.toJSON,
+		"fromJSON":         codeGen.fromJSON,
 	}
 
 	tpl, err := template.New("t4java").Funcs(funcs).Parse(t4java)
@@ -185,6 +206,20 @@ func NewCodeGen(rootpath string, skips []string) (gslang.Visitor, error) {
 	return codeGen, nil
 }
 
+// NewCodeGenWithFiles create codegen which write generated files into files map keyed by slash separated relative path
+func NewCodeGenWithFiles(files map[string][]byte, skips []string) (gslang.Visitor, error) {
+
+	codeGen, err := NewCodeGen("", skips)
+
+	if err != nil {
+		return nil, err
+	}
+
+	codeGen.(*_CodeGen).files = files
+
+	return codeGen, nil
+}
+
 func exception(name string) string {
 	if strings.HasSuffix(name, "Exception") {
 		return strings.Title(name)
@@ -193,285 +228,1107 @@ func exception(name string) string {
 	return strings.Title(name) + "Exception"
 }
 
-func methodName(name string) string {
-	return strings.ToLower(name[:1]) + name[1:]
-}
+func methodName(name string) string {
+	return strings.ToLower(name[:1]) + name[1:]
+}
+
+func (codegen *_CodeGen) tagValue(typeDecl ast.Type) string {
+	return strings.Join(codegen.tags(typeDecl), ",")
+}
+
+// tags get the type tag sequence by the shared tag scheme
+func (codegen *_CodeGen) tags(typeDecl ast.Type) []string {
+	return wire.Format(wire.Tags(typeDecl), tagName)
+}
+
+// tagName get the java constant of tag
+func tagName(tag wire.Tag) string {
+	return fmt.Sprintf("com.gsrpc.Tag.%s.getValue()", tag)
+}
+
+// tagByte get the tag value, which is used as switch case constant
+func tagByte(tag wire.Tag) byte {
+	return byte(tag)
+}
+
+// keyOrder get the comparator of canonical map key order, numbers are compared by value with the declared signedness,
+// enums by constant value and strings by UTF-8 bytes, the empty string means the natural order
+func (codegen *_CodeGen) keyOrder(key ast.Type) string {
+
+	if typeRef, ok := key.(*ast.TypeRef); ok {
+		key = typeRef.Ref
+	}
+
+	switch key.(type) {
+	case *ast.Enum:
+		return codegen.typeName(key) + ".KEY_ORDER"
+	case *ast.BuiltinType:
+		switch key.(*ast.BuiltinType).Type {
+		case lexer.KeyByte:
+			return "com.gsrpc.MapKeys.UNSIGNED_BYTE"
+		case lexer.KeyUInt16:
+			return "com.gsrpc.MapKeys.UNSIGNED_SHORT"
+		case lexer.KeyUInt32:
+			return "com.gsrpc.MapKeys.UNSIGNED_INT"
+		case lexer.KeyUInt64:
+			return "com.gsrpc.MapKeys.UNSIGNED_LONG"
+		case lexer.KeyString:
+			return "com.gsrpc.MapKeys.UTF8"
+		}
+	}
+
+	return ""
+}
+
+func (codegen *_CodeGen) writeMap(valname string, entry *ast.Table, indent int) string {
+
+	var stream bytes.Buffer
+
+	key, val := codegen.boxTypeName(entry.Fields[0].Type), codegen.boxTypeName(entry.Fields[1].Type)
+
+	stream.WriteString(fmt.Sprintf("writer.writeUInt16((short)%s.size());\n\n", valname))
+
+	writeindent(&stream, indent-1)
+
+	stream.WriteString(fmt.Sprintf("java.util.TreeMap<%s, %s> sorted%d = new java.util.TreeMap<%s, %s>(%s);\n\n", key, val, indent, key, val, codegen.keyOrder(entry.Fields[0].Type)))
+
+	writeindent(&stream, indent-1)
+
+	stream.WriteString(fmt.Sprintf("sorted%d.putAll(%s);\n\n", indent, valname))
+
+	writeindent(&stream, indent-1)
+
+	stream.WriteString(fmt.Sprintf("for(java.util.Map.Entry<%s, %s> v%d : sorted%d.entrySet()){\n\n", key, val, indent, indent))
+
+	writeindent(&stream, indent)
+
+	stream.WriteString(fmt.Sprintf("%s k%d = v%d.getKey();\n\n", codegen.typeName(entry.Fields[0].Type), indent, indent))
+
+	writeindent(&stream, indent)
+
+	stream.WriteString(codegen.writeType(fmt.Sprintf("k%d", indent), entry.Fields[0].Type, indent+1))
+
+	stream.WriteString("\n\n")
+
+	writeindent(&stream, indent)
+
+	stream.WriteString(fmt.Sprintf("%s vv%d = v%d.getValue();\n\n", codegen.typeName(entry.Fields[1].Type), indent, indent))
+
+	writeindent(&stream, indent)
+
+	stream.WriteString(codegen.writeType(fmt.Sprintf("vv%d", indent), entry.Fields[1].Type, indent+1))
+
+	stream.WriteString("\n\n")
+
+	writeindent(&stream, indent-1)
+
+	stream.WriteRune('}')
+
+	return stream.String()
+}
+
+// boxTypeName get the generic type argument name of map key or value
+func (codegen *_CodeGen) boxTypeName(typeDecl ast.Type) string {
+	if builtinType, ok := typeDecl.(*ast.BuiltinType); ok {
+		return builtinObj[builtinType.Type]
+	}
+
+	return codegen.typeName(typeDecl)
+}
+
+func (codegen *_CodeGen) readMap(valname string, entry *ast.Table, indent int) string {
+
+	var stream bytes.Buffer
+
+	key, val := entry.Fields[0].Type, entry.Fields[1].Type
+
+	stream.WriteString(fmt.Sprintf("int max%d = reader.readUInt16() & 0xffff;\n\n", indent))
+
+	writeindent(&stream, indent-1)
+
+	stream.WriteString(fmt.Sprintf("%s = new java.util.HashMap<%s, %s>();\n\n", valname, codegen.boxTypeName(key), codegen.boxTypeName(val)))
+
+	writeindent(&stream, indent-1)
+
+	stream.WriteString(fmt.Sprintf("for(int i%d = 0; i%d < max%d; i%d ++ ){\n\n", indent, indent, indent, indent))
+
+	writeindent(&stream, indent)
+
+	stream.WriteString(fmt.Sprintf("%s k%d = %s;\n\n", codegen.typeName(key), indent, codegen.defaultVal(key)))
+
+	writeindent(&stream, indent)
+
+	stream.WriteString(codegen.readType(fmt.Sprintf("k%d", indent), key, indent+1))
+
+	stream.WriteString("\n\n")
+
+	writeindent(&stream, indent)
+
+	stream.WriteString(fmt.Sprintf("%s v%d = %s;\n\n", codegen.typeName(val), indent, codegen.defaultVal(val)))
+
+	writeindent(&stream, indent)
+
+	stream.WriteString(codegen.readType(fmt.Sprintf("v%d", indent), val, indent+1))
+
+	stream.WriteString("\n\n")
+
+	writeindent(&stream, indent)
+
+	stream.WriteString(fmt.Sprintf("%s.put(k%d, v%d);\n\n", valname, indent, indent))
+
+	writeindent(&stream, indent-1)
+
+	stream.WriteRune('}')
+
+	return stream.String()
+}
+
+func (codegen *_CodeGen) callback(method *ast.Method) string {
+
+	var buff bytes.Buffer
+
+	buff.WriteString("future.Complete(null, ")
+
+	if codegen.notVoid(method.Return) {
+		buff.WriteString("returnParam, ")
+	}
+
+	buff.WriteString(");")
+
+	return strings.Replace(buff.String(), ", );", ");", -1)
+}
+
+func (codegen *_CodeGen) constructor(fields []*ast.Field) string {
+	var buff bytes.Buffer
+
+	buff.WriteString("(")
+
+	for _, field := range fields {
+		buff.WriteString(fmt.Sprintf("%s %s, ", codegen.fieldType(field), fieldname(field.Name())))
+	}
+
+	buff.WriteString(")")
+
+	return strings.Replace(buff.String(), ", )", " )", -1)
+}
+
+func (codegen *_CodeGen) marshalParams(params []*ast.Param) string {
+
+	var buff bytes.Buffer
+
+	for _, param := range params {
+
+		buff.WriteString(codegen.marshalParam(param, fmt.Sprintf("arg%d", param.ID), 2))
+	}
+
+	return buff.String()
+}
+
+func (codegen *_CodeGen) methodRPC(method *ast.Method) string {
+
+	var buff bytes.Buffer
+
+	if codegen.notVoid(method.Return) {
+		buff.WriteString(fmt.Sprintf("com.gsrpc.Future<%s> %s(", codegen.objTypeName(method.Return), methodName(method.Name())))
+	} else {
+		if gslang.IsAsync(method) {
+			buff.WriteString(fmt.Sprintf("void %s(", methodName(method.Name())))
+		} else {
+			buff.WriteString(fmt.Sprintf("com.gsrpc.Future<Void> %s(", methodName(method.Name())))
+		}
+
+	}
+
+	for _, v := range method.Params {
+		buff.WriteString(fmt.Sprintf("%s arg%d, ", codegen.typeName(v.Type), v.ID))
+	}
+
+	if !gslang.IsAsync(method) {
+		buff.WriteString("final int timeout)")
+	} else {
+		buff.WriteString(")")
+	}
+
+	return strings.Replace(buff.String(), ", )", ")", -1)
+}
+
+func (codegen *_CodeGen) marshalReturn(typeDecl ast.Type, varname string, indent int) string {
+	var buff bytes.Buffer
+
+	writeindent(&buff, indent)
+
+	buff.WriteString("byte[] returnParam;\n\n")
+
+	writeindent(&buff, indent)
+
+	buff.WriteString("{\n\n")
+
+	writeindent(&buff, indent+1)
+
+	buff.WriteString("com.gsrpc.BufferWriter writer = new com.gsrpc.BufferWriter();\n\n")
+
+	writeindent(&buff, indent+1)
+
+	buff.WriteString(codegen.writeType(varname, typeDecl, indent+1))
+
+	buff.WriteString("\n\n")
+
+	writeindent(&buff, indent+1)
+
+	buff.WriteString("returnParam = writer.getContent();\n\n")
+
+	writeindent(&buff, indent)
+
+	buff.WriteString("}\n\n")
+	return buff.String()
+}
+
+func (codegen *_CodeGen) marshalParam(param *ast.Param, varname string, indent int) string {
+	var buff bytes.Buffer
+
+	writeindent(&buff, indent)
+
+	buff.WriteString("{\n\n")
+
+	writeindent(&buff, indent+1)
+
+	buff.WriteString("com.gsrpc.BufferWriter writer = new com.gsrpc.BufferWriter();\n\n")
+
+	writeindent(&buff, indent+1)
+
+	buff.WriteString(codegen.writeType(varname, param.Type, indent+1))
+
+	buff.WriteString("\n\n")
+
+	writeindent(&buff, indent+1)
+
+	buff.WriteString("com.gsrpc.Param param = new com.gsrpc.Param();\n\n")
+
+	writeindent(&buff, indent+1)
+
+	buff.WriteString("param.setContent(writer.getContent());\n\n")
+
+	writeindent(&buff, indent+1)
+
+	buff.WriteString(fmt.Sprintf("params[%d] = (param);\n\n", param.ID))
+
+	writeindent(&buff, indent)
+
+	buff.WriteString("}\n\n")
+	return buff.String()
+}
+
+func (codegen *_CodeGen) unmarshalReturn(typeDecl ast.Type, varname string, ident int) string {
+	var buff bytes.Buffer
+
+	writeindent(&buff, ident)
+
+	buff.WriteString(fmt.Sprintf("%s returnParam = %s;\n\n", codegen.typeName(typeDecl), codegen.defaultVal(typeDecl)))
+
+	writeindent(&buff, ident)
+
+	buff.WriteString("{\n\n")
+
+	writeindent(&buff, ident+1)
+
+	buff.WriteString(fmt.Sprintf("com.gsrpc.BufferReader reader = new com.gsrpc.BufferReader(%s.getContent());\n\n", varname))
+
+	writeindent(&buff, ident+1)
+
+	buff.WriteString(codegen.readType("returnParam", typeDecl, ident+1))
+
+	buff.WriteString("\n\n")
+
+	writeindent(&buff, ident)
+
+	buff.WriteString("}\n\n")
+
+	return buff.String()
+}
+
+func (codegen *_CodeGen) unmarshalParam(param *ast.Param, varname string, ident int) string {
+	var buff bytes.Buffer
+
+	writeindent(&buff, ident)
+
+	buff.WriteString(fmt.Sprintf("%s arg%d = %s;\n\n", codegen.typeName(param.Type), param.ID, codegen.defaultVal(param.Type)))
+
+	writeindent(&buff, ident)
+
+	buff.WriteString("{\n\n")
+
+	writeindent(&buff, ident+1)
+
+	buff.WriteString(fmt.Sprintf("com.gsrpc.BufferReader reader = new com.gsrpc.BufferReader(%s.getParams()[%d].getContent());\n\n", varname, param.ID))
+
+	writeindent(&buff, ident+1)
+
+	buff.WriteString(codegen.readType(fmt.Sprintf("arg%d", param.ID), param.Type, ident+1))
+
+	buff.WriteString("\n\n")
+
+	writeindent(&buff, ident)
+
+	buff.WriteString("}\n\n")
+
+	return buff.String()
+}
+
+func (codegen *_CodeGen) methodcall(method *ast.Method) string {
+
+	var buff bytes.Buffer
+
+	if !codegen.notVoid(method.Return) {
+		buff.WriteString(fmt.Sprintf("this.service.%s(", methodName(method.Name())))
+	} else {
+		buff.WriteString(fmt.Sprintf("%s ret = this.service.%s(", codegen.typeName(method.Return), methodName(method.Name())))
+	}
+
+	for i := range method.Params {
+		buff.WriteString(fmt.Sprintf("arg%d, ", i))
+	}
+
+	buff.WriteString(");")
+
+	return strings.Replace(buff.String(), ", );", ");", -1)
+}
+
+func fieldname(name string) string {
+	return strings.ToLower(string(name[0])) + name[1:]
+}
+
+func writeindent(stream *bytes.Buffer, indent int) {
+	for i := 0; i < indent; i++ {
+		stream.WriteRune('\t')
+	}
+}
+
+func (codegen *_CodeGen) marshalfield(field *ast.Field) string {
+	return codegen.writeType(fieldname(field.Name()), field.Type, 3)
+}
+
+func (codegen *_CodeGen) unmarshalfield(field *ast.Field) string {
+
+	if isOptional(field) && !isNullable(field.Type) {
+		// optional table or array field may be null, create it before unmarshal
+		return fmt.Sprintf("%s = %s;\n\n\t\t\t%s", fieldname(field.Name()), codegen.defaultVal(field.Type), codegen.readType(fieldname(field.Name()), field.Type, 3))
+	}
+
+	return codegen.readType(fieldname(field.Name()), field.Type, 3)
+}
+
+// isOptional check if the field is annotated with @Optional
+func isOptional(field *ast.Field) bool {
+	_, ok := gslang.FindAnnotation(field, "com.gsrpc.Optional")
+
+	return ok
+}
+
+// isNullable check if the optional field type is builtin or enum,
+// which's unmarshal code assign the field directly
+func isNullable(typeDecl ast.Type) bool {
+	switch typeDecl.(type) {
+	case *ast.TypeRef:
+		return isNullable(typeDecl.(*ast.TypeRef).Ref)
+	case *ast.BuiltinType, *ast.Enum:
+		return true
+	}
+
+	return false
+}
+
+// isOneOf check if the table is annotated with @OneOf
+func isOneOf(typeDecl ast.Type) bool {
+	_, ok := gslang.FindAnnotation(typeDecl, "com.gsrpc.OneOf")
+
+	return ok
+}
+
+// variant get the union variant index of field by field index
+func variant(index int) int {
+	return index + 1
+}
+
+func (codegen *_CodeGen) marshalVariant(field *ast.Field) string {
+	return codegen.writeType(fmt.Sprintf("((%s)value)", codegen.boxTypeName(field.Type)), field.Type, 4)
+}
+
+func (codegen *_CodeGen) unmarshalVariant(field *ast.Field) string {
+	var stream bytes.Buffer
+
+	stream.WriteString(fmt.Sprintf("%s v = %s;\n\n", codegen.typeName(field.Type), codegen.defaultVal(field.Type)))
+
+	writeindent(&stream, 3)
+
+	stream.WriteString(codegen.readType("v", field.Type, 4))
+
+	return stream.String()
+}
+
+// jsonValue get the json value expr of builtin type val,
+// unsigned integers are rendered as non-negative number and uint64 as string
+var jsonValue = map[lexer.TokenType]string{
+	lexer.KeyByte:   "%s & 0xff",
+	lexer.KeyUInt16: "%s & 0xffff",
+	lexer.KeyUInt32: "%s & 0xffffffffL",
+	lexer.KeyUInt64: "Long.toUnsignedString(%s)",
+}
+
+// jsonParse get the builtin type val expr from json value
+var jsonParse = map[lexer.TokenType]string{
+	lexer.KeySByte:   "((Number)%s).byteValue()",
+	lexer.KeyByte:    "((Number)%s).byteValue()",
+	lexer.KeyInt16:   "((Number)%s).shortValue()",
+	lexer.KeyUInt16:  "((Number)%s).shortValue()",
+	lexer.KeyInt32:   "((Number)%s).intValue()",
+	lexer.KeyUInt32:  "((Number)%s).intValue()",
+	lexer.KeyInt64:   "((Number)%s).longValue()",
+	lexer.KeyUInt64:  "Long.parseUnsignedLong(%s.toString())",
+	lexer.KeyFloat32: "((Number)%s).floatValue()",
+	lexer.KeyFloat64: "((Number)%s).doubleValue()",
+	lexer.KeyBool:    "(Boolean)%s",
+	lexer.KeyString:  "(String)%s",
+}
+
+// jsonParseKey get the map key expr from json object key string
+var jsonParseKey = map[lexer.TokenType]string{
+	lexer.KeySByte:   "(byte)Long.parseLong(%s)",
+	lexer.KeyByte:    "(byte)Long.parseLong(%s)",
+	lexer.KeyInt16:   "(short)Long.parseLong(%s)",
+	lexer.KeyUInt16:  "(short)Long.parseLong(%s)",
+	lexer.KeyInt32:   "(int)Long.parseLong(%s)",
+	lexer.KeyUInt32:  "(int)Long.parseLong(%s)",
+	lexer.KeyInt64:   "Long.parseLong(%s)",
+	lexer.KeyUInt64:  "Long.parseUnsignedLong(%s)",
+	lexer.KeyFloat32: "Float.parseFloat(%s)",
+	lexer.KeyFloat64: "Double.parseDouble(%s)",
+	lexer.KeyString:  "%s",
+}
+
+// jsonKey get the json object key of map key, enum keys are rendered by toJSON which is same as the golang MarshalText
+func jsonKey(valname string, typeDecl ast.Type) string {
+
+	if typeRef, ok := typeDecl.(*ast.TypeRef); ok {
+		typeDecl = typeRef.Ref
+	}
+
+	switch typeDecl.(type) {
+	case *ast.Enum:
+		return valname + ".toJSON()"
+	case *ast.BuiltinType:
+		if format, ok := jsonValue[typeDecl.(*ast.BuiltinType).Type]; ok {
+			return fmt.Sprintf("String.valueOf(%s)", fmt.Sprintf(format, valname))
+		}
+	}
+
+	return fmt.Sprintf("String.valueOf(%s)", valname)
+}
+
+func isBytes(seq *ast.Seq) bool {
+	builtinType, ok := seq.Component.(*ast.BuiltinType)
+
+	return ok && builtinType.Type == lexer.KeyByte
+}
+
+// toJSON generate statements which assign the json value of valname to the declared Object target
+func (codegen *_CodeGen) toJSON(target string, valname string, typeDecl ast.Type, indent int) string {
+	switch typeDecl.(type) {
+	case *ast.BuiltinType:
+		builtinType := typeDecl.(*ast.BuiltinType)
+
+		if format, ok := jsonValue[builtinType.Type]; ok {
+			return fmt.Sprintf("%s = %s;", target, fmt.Sprintf(format, valname))
+		}
+
+		return fmt.Sprintf("%s = %s;", target, valname)
+
+	case *ast.TypeRef:
+		return codegen.toJSON(target, valname, typeDecl.(*ast.TypeRef).Ref, indent)
+
+	case *ast.Enum:
+		return fmt.Sprintf("%s = %s.toJSON();", target, valname)
+
+	case *ast.Table:
+		return fmt.Sprintf("%s = %s == null ? org.json.JSONObject.NULL : %s.toJSON();", target, valname, valname)
+
+	case *ast.Seq:
+		seq := typeDecl.(*ast.Seq)
+
+		if isBytes(seq) {
+			return fmt.Sprintf("%s = java.util.Base64.getEncoder().encodeToString(%s);", target, valname)
+		}
+
+		var stream bytes.Buffer
+
+		if entry, ok := wire.MapEntry(seq); ok {
+
+			key, val := entry.Fields[0].Type, entry.Fields[1].Type
+
+			stream.WriteString(fmt.Sprintf("org.json.JSONObject object%d = new org.json.JSONObject();\n\n", indent))
+
+			writeindent(&stream, indent-1)
+
+			stream.WriteString(fmt.Sprintf("for(java.util.Map.Entry<%s, %s> v%d : %s.entrySet()){\n\n", codegen.boxTypeName(key), codegen.boxTypeName(val), indent, valname))
+
+			writeindent(&stream, indent)
+
+			stream.WriteString(fmt.Sprintf("String k%d = %s;\n\n", indent, jsonKey(fmt.Sprintf("v%d.getKey()", indent), key)))
+
+			writeindent(&stream, indent)
+
+			stream.WriteString(fmt.Sprintf("Object e%d = null;\n\n", indent))
+
+			writeindent(&stream, indent)
+
+			stream.WriteString(codegen.toJSON(fmt.Sprintf("e%d", indent), fmt.Sprintf("v%d.getValue()", indent), val, indent+1))
+
+			stream.WriteString("\n\n")
+
+			writeindent(&stream, indent)
+
+			stream.WriteString(fmt.Sprintf("object%d.put(k%d, e%d);\n\n", indent, indent, indent))
+
+			writeindent(&stream, indent-1)
+
+			stream.WriteString("}\n\n")
+
+			writeindent(&stream, indent-1)
+
+			stream.WriteString(fmt.Sprintf("%s = object%d;", target, indent))
+
+			return stream.String()
+		}
+
+		stream.WriteString(fmt.Sprintf("org.json.JSONArray array%d = new org.json.JSONArray();\n\n", indent))
+
+		writeindent(&stream, indent-1)
+
+		stream.WriteString(fmt.Sprintf("for(%s v%d : %s){\n\n", codegen.typeName(seq.Component), indent, valname))
+
+		writeindent(&stream, indent)
+
+		stream.WriteString(fmt.Sprintf("Object e%d = null;\n\n", indent))
+
+		writeindent(&stream, indent)
+
+		stream.WriteString(codegen.toJSON(fmt.Sprintf("e%d", indent), fmt.Sprintf("v%d", indent), seq.Component, indent+1))
+
+		stream.WriteString("\n\n")
+
+		writeindent(&stream, indent)
+
+		stream.WriteString(fmt.Sprintf("array%d.put(e%d);\n\n", indent, indent))
+
+		writeindent(&stream, indent-1)
+
+		stream.WriteString("}\n\n")
+
+		writeindent(&stream, indent-1)
+
+		stream.WriteString(fmt.Sprintf("%s = array%d;", target, indent))
+
+		return stream.String()
+	}
+
+	gserrors.Panicf(nil, "toJSON  error: unsupport type(%s)", typeDecl)
+
+	return "unknown"
+}
+
+// fromJSON generate statements which assign the value decoded from json value expr to valname
+func (codegen *_CodeGen) fromJSON(valname string, json string, typeDecl ast.Type, indent int) string {
+	switch typeDecl.(type) {
+	case *ast.BuiltinType:
+		builtinType := typeDecl.(*ast.BuiltinType)
+
+		return fmt.Sprintf("%s = %s;", valname, fmt.Sprintf(jsonParse[builtinType.Type], json))
+
+	case *ast.TypeRef:
+		return codegen.fromJSON(valname, json, typeDecl.(*ast.TypeRef).Ref, indent)
+
+	case *ast.Enum:
+		return fmt.Sprintf("%s = %s.fromJSON((String)%s);", valname, codegen.typeName(typeDecl), json)
+
+	case *ast.Table:
+		if isOneOf(typeDecl) {
+			return fmt.Sprintf("%s = new %s();\n\n%s%s.fromJSON(%s);", valname, codegen.typeName(typeDecl), strings.Repeat("\t", indent-1), valname, json)
+		}
+
+		return fmt.Sprintf(
+			"if(%s == org.json.JSONObject.NULL) {\n%s%s = null;\n%s} else {\n%s%s = new %s();\n%s%s.fromJSON((org.json.JSONObject)%s);\n%s}",
+			json, strings.Repeat("\t", indent), valname, strings.Repeat("\t", indent-1),
+			strings.Repeat("\t", indent), valname, codegen.typeName(typeDecl),
+			strings.Repeat("\t", indent), valname, json, strings.Repeat("\t", indent-1))
+
+	case *ast.Seq:
+		seq := typeDecl.(*ast.Seq)
+
+		if isBytes(seq) {
+			if seq.Size != -1 {
+				return fmt.Sprintf("%s = java.util.Arrays.copyOf(java.util.Base64.getDecoder().decode((String)%s), %d);", valname, json, seq.Size)
+			}
+
+			return fmt.Sprintf("%s = java.util.Base64.getDecoder().decode((String)%s);", valname, json)
+		}
+
+		var stream bytes.Buffer
+
+		if entry, ok := wire.MapEntry(seq); ok {
+
+			key, val := entry.Fields[0].Type, entry.Fields[1].Type
+
+			stream.WriteString(fmt.Sprintf("org.json.JSONObject object%d = (org.json.JSONObject)%s;\n\n", indent, json))
+
+			writeindent(&stream, indent-1)
+
+			stream.WriteString(fmt.Sprintf("%s = new java.util.HashMap<%s, %s>();\n\n", valname, codegen.boxTypeName(key), codegen.boxTypeName(val)))
+
+			writeindent(&stream, indent-1)
+
+			stream.WriteString(fmt.Sprintf("for(java.util.Iterator<String> i%d = object%d.keys(); i%d.hasNext(); ){\n\n", indent, indent, indent))
+
+			writeindent(&stream, indent)
+
+			stream.WriteString(fmt.Sprintf("String name%d = i%d.next();\n\n", indent, indent))
+
+			writeindent(&stream, indent)
+
+			if typeRef, ok := key.(*ast.TypeRef); ok {
+				key = typeRef.Ref
+			}
+
+			if builtinType, ok := key.(*ast.BuiltinType); ok {
+				stream.WriteString(fmt.Sprintf("%s k%d = %s;\n\n", codegen.typeName(key), indent, fmt.Sprintf(jsonParseKey[builtinType.Type], fmt.Sprintf("name%d", indent))))
+			} else {
+				stream.WriteString(fmt.Sprintf("%s k%d = %s.fromJSON(name%d);\n\n", codegen.typeName(key), indent, codegen.typeName(key), indent))
+			}
+
+			writeindent(&stream, indent)
+
+			stream.WriteString(fmt.Sprintf("%s v%d = %s;\n\n", codegen.typeName(val), indent, codegen.defaultVal(val)))
+
+			writeindent(&stream, indent)
+
+			stream.WriteString(codegen.fromJSON(fmt.Sprintf("v%d", indent), fmt.Sprintf("object%d.get(name%d)", indent, indent), val, indent+1))
+
+			stream.WriteString("\n\n")
+
+			writeindent(&stream, indent)
+
+			stream.WriteString(fmt.Sprintf("%s.put(k%d, v%d);\n\n", valname, indent, indent))
+
+			writeindent(&stream, indent-1)
+
+			stream.WriteRune('}')
+
+			return stream.String()
+		}
+
+		stream.WriteString(fmt.Sprintf("org.json.JSONArray array%d = (org.json.JSONArray)%s;\n\n", indent, json))
+
+		writeindent(&stream, indent-1)
+
+		length := fmt.Sprintf("array%d.length()", indent)
+
+		if seq.Size != -1 {
+			length = fmt.Sprintf("%d", seq.Size)
+		}
+
+		stream.WriteString(fmt.Sprintf("%s = new %s;\n\n", valname, strings.Replace(codegen.typeName(seq), "[]", "["+length+"]", 1)))
+
+		writeindent(&stream, indent-1)
+
+		stream.WriteString(fmt.Sprintf("for(int i%d = 0; i%d < array%d.length() && i%d < %s.length; i%d ++ ){\n\n", indent, indent, indent, indent, valname, indent))
+
+		writeindent(&stream, indent)
+
+		stream.WriteString(fmt.Sprintf("%s v%d = %s;\n\n", codegen.typeName(seq.Component), indent, codegen.defaultVal(seq.Component)))
+
+		writeindent(&stream, indent)
+
+		stream.WriteString(codegen.fromJSON(fmt.Sprintf("v%d", indent), fmt.Sprintf("array%d.get(i%d)", indent, indent), seq.Component, indent+1))
+
+		stream.WriteString("\n\n")
+
+		writeindent(&stream, indent)
+
+		stream.WriteString(fmt.Sprintf("%s[i%d] = v%d;\n\n", valname, indent, indent))
+
+		writeindent(&stream, indent-1)
+
+		stream.WriteRune('}')
+
+		return stream.String()
+	}
+
+	gserrors.Panicf(nil, "fromJSON  error: unsupport type(%s)", typeDecl)
+
+	return "unknown"
+}
+
+func (codegen *_CodeGen) fieldType(field *ast.Field) string {
+	if isOptional(field) {
+		return codegen.boxTypeName(field.Type)
+	}
+
+	return codegen.typeName(field.Type)
+}
+
+func (codegen *_CodeGen) fieldDefault(field *ast.Field) string {
+
+	expr, ok := gen.Default(field)
+
+	if !ok {
+		if isOptional(field) {
+			return "null"
+		}
+
+		return codegen.defaultVal(field.Type)
+	}
+
+	start, _ := gslang.Pos(field)
+
+	return codegen.defaultExpr(expr, field.Type, start)
+}
+
+// javaQuote get the java string literal, the control chars are escaped as octal because the unicode escapes
+// are translated before lexing, and the non ASCII chars as UTF-16 unicode escapes
+func javaQuote(val string) string {
+
+	var buff bytes.Buffer
+
+	buff.WriteRune('"')
+
+	for _, c := range utf16.Encode([]rune(val)) {
+		switch {
+		case c == '"' || c == '\\':
+			buff.WriteRune('\\')
+			buff.WriteRune(rune(c))
+		case c == '\n':
+			buff.WriteString("\\n")
+		case c == '\r':
+			buff.WriteString("\\r")
+		case c == '\t':
+			buff.WriteString("\\t")
+		case c < 0x20 || c == 0x7f:
+			buff.WriteString(fmt.Sprintf("\\%03o", c))
+		case c > 0x7f:
+			buff.WriteString(fmt.Sprintf("\\u%04x", c))
+		default:
+			buff.WriteRune(rune(c))
+		}
+	}
+
+	buff.WriteRune('"')
+
+	return buff.String()
+}
+
+func (codegen *_CodeGen) defaultExpr(expr ast.Expr, typeDecl ast.Type, start lexer.Position) string {
+
+	eval := codegen.compiler.Eval()
 
-func (codegen *_CodeGen) tagValue(typeDecl ast.Type) string {
 	switch typeDecl.(type) {
 	case *ast.BuiltinType:
 		builtinType := typeDecl.(*ast.BuiltinType)
 
 		switch builtinType.Type {
-		case lexer.KeySByte, lexer.KeyByte, lexer.KeyBool:
-			return "com.gsrpc.Tag.I8.getValue()"
-		case lexer.KeyInt16, lexer.KeyUInt16:
-			return "com.gsrpc.Tag.I16.getValue()"
-		case lexer.KeyInt32, lexer.KeyUInt32, lexer.KeyFloat32:
-			return "com.gsrpc.Tag.I32.getValue()"
-		case lexer.KeyInt64, lexer.KeyUInt64, lexer.KeyFloat64:
-			return "com.gsrpc.Tag.I64.getValue()"
+		case lexer.KeyByte, lexer.KeyUInt16, lexer.KeyUInt32, lexer.KeyUInt64:
+			if val := eval.EvalInt(expr); val < 0 {
+				gserrors.Panicf(nil, "unsigned type(%s) can't default to negative value(%d) :%v", typeDecl, val, start)
+			}
+		}
+
+		switch builtinType.Type {
 		case lexer.KeyString:
-			return "com.gsrpc.Tag.String.getValue()"
+			return javaQuote(eval.EvalString(expr))
+		case lexer.KeyBool:
+			return strconv.FormatBool(eval.EvalBool(expr))
+		case lexer.KeyFloat32:
+			return strconv.FormatFloat(eval.EvalFloat(expr), 'g', -1, 32) + "f"
+		case lexer.KeyFloat64:
+			return strconv.FormatFloat(eval.EvalFloat(expr), 'g', -1, 64) + "d"
+		case lexer.KeyInt64, lexer.KeyUInt64:
+			return fmt.Sprintf("%dL", eval.EvalInt(expr))
+		case lexer.KeyVoid:
+		default:
+			val := eval.EvalInt(expr)
+
+			if val > math.MaxInt32 || val < math.MinInt32 {
+				return fmt.Sprintf("(%s)%dL", builtin[builtinType.Type], val)
+			}
+
+			if builtin[builtinType.Type] == "int" {
+				return fmt.Sprintf("%d", val)
+			}
+
+			return fmt.Sprintf("(%s)%d", builtin[builtinType.Type], val)
 		}
 
 	case *ast.TypeRef:
-		return codegen.tagValue(typeDecl.(*ast.TypeRef).Ref)
+		return codegen.defaultExpr(expr, typeDecl.(*ast.TypeRef).Ref, start)
+
 	case *ast.Enum:
+		enum := typeDecl.(*ast.Enum)
+
+		val := eval.EvalInt(expr)
 
-		if codegen.enumSize(typeDecl) == 4 {
-			return "com.gsrpc.Tag.I32.getValue()"
+		for _, constant := range enum.Constants {
+			if constant.Value == val {
+				return codegen.typeName(enum) + "." + strings.Title(constant.Name())
+			}
 		}
 
-		return "com.gsrpc.Tag.I8.getValue()"
+		gserrors.Panicf(nil, "enum %s constant(%d) not found :%v", enum, val, start)
 
 	case *ast.Table:
-		return "com.gsrpc.Tag.Table.getValue()"
-	case *ast.Seq:
-
-		seq := typeDecl.(*ast.Seq)
+		table := typeDecl.(*ast.Table)
 
-		component := codegen.tagValue(seq.Component)
+		newObj, ok := expr.(*ast.NewObj)
 
-		if component == "com.gsrpc.Tag.List.getValue()" {
-			start, _ := gslang.Pos(typeDecl)
-			gserrors.Panicf(nil, "list component %v can't be a list :%v", seq.Component, start)
+		if !ok || isOneOf(table) {
+			break
 		}
 
-		return fmt.Sprintf("((%s << 4)|com.gsrpc.Tag.List.getValue())", component)
+		return codegen.tableLiteral(table, newObj.Args, start)
 	}
 
-	gserrors.Panicf(nil, "typeName  error: unsupport type(%s)", typeDecl)
+	gserrors.Panicf(nil, "unsupport default value for type(%s) :%v", typeDecl, start)
 
-	return ""
+	return "unknown"
 }
 
-func (codegen *_CodeGen) callback(method *ast.Method) string {
+// tableLiteral get the table object creation expr initialized by positional or named args
+func (codegen *_CodeGen) tableLiteral(table *ast.Table, args *ast.ArgsTable, start lexer.Position) string {
 
-	var buff bytes.Buffer
+	var values []string
 
-	buff.WriteString("future.Complete(null, ")
+	for i, field := range table.Fields {
 
-	if codegen.notVoid(method.Return) {
-		buff.WriteString("returnParam, ")
-	}
+		var arg ast.Expr
 
-	buff.WriteString(");")
+		ok := false
 
-	return strings.Replace(buff.String(), ", );", ");", -1)
-}
+		if args != nil {
+			arg, ok = args.NamedArg(field.Name())
 
-func (codegen *_CodeGen) constructor(fields []*ast.Field) string {
-	var buff bytes.Buffer
+			if !args.Named {
+				arg, ok = nil, i < len(args.Arguments)
 
-	buff.WriteString("(")
+				if ok {
+					arg = args.Arguments[i]
+				}
+			}
+		}
 
-	for _, field := range fields {
-		buff.WriteString(fmt.Sprintf("%s %s, ", codegen.typeName(field.Type), fieldname(field.Name())))
+		if ok {
+			values = append(values, codegen.defaultExpr(arg, field.Type, start))
+		} else {
+			values = append(values, codegen.fieldDefault(field))
+		}
 	}
 
-	buff.WriteString(")")
-
-	return strings.Replace(buff.String(), ", )", " )", -1)
+	return fmt.Sprintf("new %s(%s)", codegen.typeName(table), strings.Join(values, ", "))
 }
 
-func (codegen *_CodeGen) marshalParams(params []*ast.Param) string {
-
-	var buff bytes.Buffer
+// userAnnotations get the annotations declared by user which are attached to node,
+// the gslang and com.gsrpc annotations are consumed by compiler and code generator
+func userAnnotations(node ast.Node) (annotations []*ast.Annotation) {
 
-	for _, param := range params {
+	for _, annotation := range node.Annotations() {
 
-		buff.WriteString(codegen.marshalParam(param, fmt.Sprintf("arg%d", param.ID), 2))
+		if _, ok := annotationTable(annotation); ok {
+			annotations = append(annotations, annotation)
+		}
 	}
 
-	return buff.String()
+	return
 }
 
-func (codegen *_CodeGen) methodRPC(method *ast.Method) string {
-
-	var buff bytes.Buffer
+// annotationTable get the declaration table of user annotation
+func annotationTable(annotation *ast.Annotation) (*ast.Table, bool) {
 
-	if codegen.notVoid(method.Return) {
-		buff.WriteString(fmt.Sprintf("com.gsrpc.Future<%s> %s(", codegen.objTypeName(method.Return), methodName(method.Name())))
-	} else {
-		if gslang.IsAsync(method) {
-			buff.WriteString(fmt.Sprintf("void %s(", methodName(method.Name())))
-		} else {
-			buff.WriteString(fmt.Sprintf("com.gsrpc.Future<Void> %s(", methodName(method.Name())))
-		}
+	typeDecl := annotation.Type
 
+	if typeRef, ok := typeDecl.(*ast.TypeRef); ok {
+		typeDecl = typeRef.Ref
 	}
 
-	for _, v := range method.Params {
-		buff.WriteString(fmt.Sprintf("%s arg%d, ", codegen.typeName(v.Type), v.ID))
-	}
+	table, ok := typeDecl.(*ast.Table)
 
-	if !gslang.IsAsync(method) {
-		buff.WriteString("final int timeout)")
-	} else {
-		buff.WriteString(")")
+	if !ok || table.Package() == "com.gsrpc" || strings.HasPrefix(table.Package(), "gslang") {
+		return nil, false
 	}
 
-	return strings.Replace(buff.String(), ", )", ")", -1)
+	return table, true
 }
 
-func (codegen *_CodeGen) marshalReturn(typeDecl ast.Type, varname string, indent int) string {
-	var buff bytes.Buffer
-
-	writeindent(&buff, indent)
-
-	buff.WriteString("byte[] returnParam;\n\n")
-
-	writeindent(&buff, indent)
+// _AnnotationNode the annotated node and its children, e.g. contract and its methods
+type _AnnotationNode struct {
+	name     string             // field name of parent annotations class
+	node     ast.Node           // annotated node
+	children []*_AnnotationNode // children nodes
+}
 
-	buff.WriteString("{\n\n")
+// hasAnnotations check if the node or any of its children has user annotations
+func (node *_AnnotationNode) hasAnnotations() bool {
 
-	writeindent(&buff, indent+1)
+	if len(userAnnotations(node.node)) != 0 {
+		return true
+	}
 
-	buff.WriteString("com.gsrpc.BufferWriter writer = new com.gsrpc.BufferWriter();\n\n")
+	for _, child := range node.children {
+		if child.hasAnnotations() {
+			return true
+		}
+	}
 
-	writeindent(&buff, indent+1)
+	return false
+}
 
-	buff.WriteString(codegen.writeType(varname, typeDecl, indent+1))
+// annotationClass write the annotation fields of node and the nested classes of children,
+// the root fields are static and the nested classes are named by node path joined with '_'
+func (codegen *_CodeGen) annotationClass(buff *bytes.Buffer, className string, node *_AnnotationNode, indent int) {
 
-	buff.WriteString("\n\n")
+	start, _ := gslang.Pos(node.node)
 
-	writeindent(&buff, indent+1)
+	modifier := "public final"
 
-	buff.WriteString("returnParam = writer.getContent();\n\n")
+	if indent == 1 {
+		modifier = "public static final"
+	}
 
-	writeindent(&buff, indent)
+	names := make(map[string]bool)
 
-	buff.WriteString("}\n\n")
-	return buff.String()
-}
+	checkName := func(name string) {
+		if names[name] {
+			gserrors.Panicf(nil, "duplicate annotations field %s of %s :%v", name, node.node, start)
+		}
 
-func (codegen *_CodeGen) marshalParam(param *ast.Param, varname string, indent int) string {
-	var buff bytes.Buffer
+		names[name] = true
+	}
 
-	writeindent(&buff, indent)
+	var groups [][]*ast.Annotation
 
-	buff.WriteString("{\n\n")
+	indexer := make(map[string]int)
 
-	writeindent(&buff, indent+1)
+	for _, annotation := range userAnnotations(node.node) {
 
-	buff.WriteString("com.gsrpc.BufferWriter writer = new com.gsrpc.BufferWriter();\n\n")
+		if index, ok := indexer[annotation.FullName()]; ok {
+			groups[index] = append(groups[index], annotation)
+			continue
+		}
 
-	writeindent(&buff, indent+1)
+		indexer[annotation.FullName()] = len(groups)
 
-	buff.WriteString(codegen.writeType(varname, param.Type, indent+1))
+		groups = append(groups, []*ast.Annotation{annotation})
+	}
 
-	buff.WriteString("\n\n")
+	for _, group := range groups {
 
-	writeindent(&buff, indent+1)
+		table, _ := annotationTable(group[0])
 
-	buff.WriteString("com.gsrpc.Param param = new com.gsrpc.Param();\n\n")
+		name := strings.Title(table.Name())
 
-	writeindent(&buff, indent+1)
+		checkName(name)
 
-	buff.WriteString("param.setContent(writer.getContent());\n\n")
+		start, _ := gslang.Pos(group[0])
 
-	writeindent(&buff, indent+1)
+		writeindent(buff, indent)
 
-	buff.WriteString(fmt.Sprintf("params[%d] = (param);\n\n", param.ID))
+		if len(group) == 1 {
+			buff.WriteString(fmt.Sprintf("%s %s %s = %s;\n\n", modifier, codegen.typeName(table), name, codegen.tableLiteral(table, group[0].Args, start)))
+			continue
+		}
 
-	writeindent(&buff, indent)
+		var values []string
 
-	buff.WriteString("}\n\n")
-	return buff.String()
-}
+		for _, annotation := range group {
+			values = append(values, codegen.tableLiteral(table, annotation.Args, start))
+		}
 
-func (codegen *_CodeGen) unmarshalReturn(typeDecl ast.Type, varname string, ident int) string {
-	var buff bytes.Buffer
+		buff.WriteString(fmt.Sprintf("%s java.util.List<%s> %s = java.util.Collections.unmodifiableList(java.util.Arrays.asList(%s));\n\n",
+			modifier, codegen.typeName(table), name, strings.Join(values, ", ")))
+	}
 
-	writeindent(&buff, ident)
+	for _, child := range node.children {
 
-	buff.WriteString(fmt.Sprintf("%s returnParam = %s;\n\n", codegen.typeName(typeDecl), codegen.defaultVal(typeDecl)))
+		if !child.hasAnnotations() {
+			continue
+		}
 
-	writeindent(&buff, ident)
+		name := strings.Title(child.name)
 
-	buff.WriteString("{\n\n")
+		checkName(name)
 
-	writeindent(&buff, ident+1)
+		childClassName := strings.TrimSuffix(strings.TrimSuffix(className, "Annotations"), "_") + "_" + name + "_Annotations"
 
-	buff.WriteString(fmt.Sprintf("com.gsrpc.BufferReader reader = new com.gsrpc.BufferReader(%s.getContent());\n\n", varname))
+		writeindent(buff, indent)
 
-	writeindent(&buff, ident+1)
+		buff.WriteString(fmt.Sprintf("%s %s %s = new %s();\n\n", modifier, childClassName, name, childClassName))
 
-	buff.WriteString(codegen.readType("returnParam", typeDecl, ident+1))
+		writeindent(buff, indent)
 
-	buff.WriteString("\n\n")
+		buff.WriteString(fmt.Sprintf("public static final class %s {\n\n", childClassName))
 
-	writeindent(&buff, ident)
+		codegen.annotationClass(buff, childClassName, child, indent+1)
 
-	buff.WriteString("}\n\n")
+		writeindent(buff, indent)
 
-	return buff.String()
+		buff.WriteString("}\n\n")
+	}
 }
 
-func (codegen *_CodeGen) unmarshalParam(param *ast.Param, varname string, ident int) string {
-	var buff bytes.Buffer
-
-	writeindent(&buff, ident)
-
-	buff.WriteString(fmt.Sprintf("%s arg%d = %s;\n\n", codegen.typeName(param.Type), param.ID, codegen.defaultVal(param.Type)))
-
-	writeindent(&buff, ident)
-
-	buff.WriteString("{\n\n")
-
-	writeindent(&buff, ident+1)
+// annotations get the annotations class of contract(with methods and params) or table(with fields),
+// the class is named as {Name}Annotations and only contains the annotated nodes, return nil if no user annotation attached
+func (codegen *_CodeGen) annotations(typeDecl ast.Type) []byte {
 
-	buff.WriteString(fmt.Sprintf("com.gsrpc.BufferReader reader = new com.gsrpc.BufferReader(%s.getParams()[%d].getContent());\n\n", varname, param.ID))
+	root := &_AnnotationNode{node: typeDecl}
 
-	writeindent(&buff, ident+1)
+	switch typeDecl.(type) {
+	case *ast.Contract:
+		for _, method := range typeDecl.(*ast.Contract).Methods {
 
-	buff.WriteString(codegen.readType(fmt.Sprintf("arg%d", param.ID), param.Type, ident+1))
+			child := &_AnnotationNode{name: method.Name(), node: method}
 
-	buff.WriteString("\n\n")
+			for _, param := range method.Params {
+				child.children = append(child.children, &_AnnotationNode{name: param.Name(), node: param})
+			}
 
-	writeindent(&buff, ident)
+			root.children = append(root.children, child)
+		}
 
-	buff.WriteString("}\n\n")
+	case *ast.Table:
+		for _, field := range typeDecl.(*ast.Table).Fields {
+			root.children = append(root.children, &_AnnotationNode{name: field.Name(), node: field})
+		}
+	}
 
-	return buff.String()
-}
+	if !root.hasAnnotations() {
+		return nil
+	}
 
-func (codegen *_CodeGen) methodcall(method *ast.Method) string {
+	name := strings.Title(typeDecl.Name()) + "Annotations"
 
 	var buff bytes.Buffer
 
-	if !codegen.notVoid(method.Return) {
-		buff.WriteString(fmt.Sprintf("this.service.%s(", methodName(method.Name())))
-	} else {
-		buff.WriteString(fmt.Sprintf("%s ret = this.service.%s(", codegen.typeName(method.Return), methodName(method.Name())))
-	}
+	buff.WriteString(fmt.Sprintf("/*\n * %s generate by gs2java,don't modify it manually\n *\n * the annotations attached to %s\n */\n", name, typeDecl.Name()))
 
-	for i := range method.Params {
-		buff.WriteString(fmt.Sprintf("arg%d, ", i))
-	}
+	buff.WriteString(fmt.Sprintf("public final class %s {\n\n", name))
 
-	buff.WriteString(");")
+	writeindent(&buff, 1)
 
-	return strings.Replace(buff.String(), ", );", ");", -1)
-}
+	buff.WriteString(fmt.Sprintf("private %s() {\n", name))
 
-func fieldname(name string) string {
-	return strings.ToLower(string(name[0])) + name[1:]
-}
+	writeindent(&buff, 1)
 
-func writeindent(stream *bytes.Buffer, indent int) {
-	for i := 0; i < indent; i++ {
-		stream.WriteRune('\t')
-	}
-}
+	buff.WriteString("}\n\n")
 
-func (codegen *_CodeGen) marshalfield(field *ast.Field) string {
-	return codegen.writeType(fieldname(field.Name()), field.Type, 3)
-}
+	codegen.annotationClass(&buff, name, root, 1)
 
-func (codegen *_CodeGen) unmarshalfield(field *ast.Field) string {
-	return codegen.readType(fieldname(field.Name()), field.Type, 3)
+	buff.WriteString("}\n")
+
+	return buff.Bytes()
 }
 
 func (codegen *_CodeGen) notVoid(typeDecl ast.Type) bool {
@@ -488,6 +1345,10 @@ func (codegen *_CodeGen) enumFields(enum *ast.Enum) string {
 	var buff bytes.Buffer
 
 	for _, constant := range enum.Constants {
+		if comment := doc("\t", constant); comment != "" {
+			buff.WriteString(strings.TrimPrefix(comment, "\t") + "\t")
+		}
+
 		buff.WriteString(fmt.Sprintf("%s((%s)%d),\n\t", strings.Title(constant.Name()), codegen.enumType(enum), constant.Value))
 	}
 
@@ -512,16 +1373,6 @@ func (codegen *_CodeGen) builtin(typeDecl ast.Type) bool {
 	return ok
 }
 
-func (codegen *_CodeGen) enumSize(typeDecl ast.Type) int {
-	_, ok := gslang.FindAnnotation(typeDecl, "gslang.Flag")
-
-	if ok {
-		return 4
-	}
-
-	return 1
-}
-
 func (codegen *_CodeGen) params(params []*ast.Param) string {
 	var buff bytes.Buffer
 
@@ -603,6 +1454,10 @@ func (codegen *_CodeGen) writeType(valname string, typeDecl ast.Type, indent int
 			isbytes = true
 		}
 
+		if entry, ok := wire.MapEntry(seq); ok {
+			return codegen.writeMap(valname, entry, indent)
+		}
+
 		if seq.Size == -1 {
 
 			if isbytes {
@@ -691,6 +1546,10 @@ func (codegen *_CodeGen) readType(valname string, typeDecl ast.Type, indent int)
 			isbytes = true
 		}
 
+		if entry, ok := wire.MapEntry(seq); ok {
+			return codegen.readMap(valname, entry, indent)
+		}
+
 		if seq.Size == -1 {
 
 			if isbytes {
@@ -788,6 +1647,10 @@ func (codegen *_CodeGen) objTypeName(typeDecl ast.Type) string {
 	case *ast.Seq:
 		seq := typeDecl.(*ast.Seq)
 
+		if _, ok := wire.MapEntry(seq); ok {
+			return codegen.typeName(seq)
+		}
+
 		return fmt.Sprintf("%s[]", codegen.typeName(seq.Component))
 	}
 
@@ -819,6 +1682,10 @@ func (codegen *_CodeGen) typeName(typeDecl ast.Type) string {
 	case *ast.Seq:
 		seq := typeDecl.(*ast.Seq)
 
+		if entry, ok := wire.MapEntry(seq); ok {
+			return fmt.Sprintf("java.util.Map<%s, %s>", codegen.boxTypeName(entry.Fields[0].Type), codegen.boxTypeName(entry.Fields[1].Type))
+		}
+
 		return fmt.Sprintf("%s[]", codegen.typeName(seq.Component))
 	}
 
@@ -855,6 +1722,11 @@ func (codegen *_CodeGen) defaultVal(typeDecl ast.Type) string {
 		return "new " + codegen.typeName(typeDecl) + "()"
 
 	case *ast.Seq:
+
+		if entry, ok := wire.MapEntry(typeDecl.(*ast.Seq)); ok {
+			return fmt.Sprintf("new java.util.HashMap<%s, %s>()", codegen.boxTypeName(entry.Fields[0].Type), codegen.boxTypeName(entry.Fields[1].Type))
+		}
+
 		return fmt.Sprintf("new %s", codegen.arrayDefaultVal(typeDecl))
 	}
 
@@ -869,6 +1741,10 @@ func (codegen *_CodeGen) arrayDefaultVal(typeDecl ast.Type) string {
 	case *ast.Seq:
 		seq := typeDecl.(*ast.Seq)
 
+		if _, ok := wire.MapEntry(seq); ok {
+			return "java.util.Map"
+		}
+
 		return fmt.Sprintf("%s[0]", codegen.arrayDefaultVal(seq.Component))
 
 	default:
@@ -907,6 +1783,12 @@ func (codegen *_CodeGen) writeJavaFile(name string, expr ast.Expr, content []byt
 
 	buff.Write(content)
 
+	// the content buffer may be reused by caller
+	if codegen.files != nil {
+		codegen.files[filepath.ToSlash(filepath.Join(packagename, name+".java"))] = append([]byte(nil), buff.Bytes()...)
+		return
+	}
+
 	if err := os.MkdirAll(filepath.Dir(fullpath), 0755); err != nil {
 		gserrors.Panicf(err, "create output directory error")
 	}
@@ -938,6 +1820,8 @@ func (codegen *_CodeGen) BeginScript(compiler *gslang.Compiler, script *ast.Scri
 
 	codegen.script = script
 
+	codegen.compiler = compiler
+
 	codegen.imports = make(map[string]string)
 
 	for k, v := range imports {
@@ -966,8 +1850,14 @@ func (codegen *_CodeGen) Table(compiler *gslang.Compiler, tableType *ast.Table)
 
 	var buff bytes.Buffer
 
-	if err := codegen.tpl.ExecuteTemplate(&buff, "table", tableType); err != nil {
-		gserrors.Panicf(err, "exec template(table) for %s error", tableType)
+	tpl := "table"
+
+	if isOneOf(tableType) {
+		tpl = "union"
+	}
+
+	if err := codegen.tpl.ExecuteTemplate(&buff, tpl, tableType); err != nil {
+		gserrors.Panicf(err, "exec template(%s) for %s error", tpl, tableType)
 	}
 
 	if gslang.IsException(tableType) {
@@ -976,9 +1866,31 @@ func (codegen *_CodeGen) Table(compiler *gslang.Compiler, tableType *ast.Table)
 		codegen.writeJavaFile(tableType.Name(), tableType, buff.Bytes())
 	}
 
+	codegen.writeAnnotations(tableType)
+
 }
 
 func (codegen *_CodeGen) Annotation(compiler *gslang.Compiler, annotation *ast.Table) {
+
+	// the com.gsrpc annotations are only consumed by code generator
+	if codegen.script.Package == "com.gsrpc" {
+		return
+	}
+
+	var buff bytes.Buffer
+
+	if err := codegen.tpl.ExecuteTemplate(&buff, "table", annotation); err != nil {
+		gserrors.Panicf(err, "exec template(table) for %s error", annotation)
+	}
+
+	codegen.writeJavaFile(annotation.Name(), annotation, buff.Bytes())
+}
+
+// writeAnnotations write the annotations class of contract or table if any user annotation attached
+func (codegen *_CodeGen) writeAnnotations(typeDecl ast.Type) {
+	if content := codegen.annotations(typeDecl); content != nil {
+		codegen.writeJavaFile(strings.Title(typeDecl.Name())+"Annotations", nil, content)
+	}
 }
 
 func (codegen *_CodeGen) Enum(compiler *gslang.Compiler, enum *ast.Enum) {
@@ -1016,9 +1928,53 @@ func (codegen *_CodeGen) Contract(compiler *gslang.Compiler, contract *ast.Contr
 	}
 
 	codegen.writeJavaFile(contract.Name()+"RPC", contract, buff.Bytes())
+
+	codegen.writeAnnotations(contract)
 }
 
 // EndScript .
 func (codegen *_CodeGen) EndScript(compiler *gslang.Compiler) {
 
+	// the tagged value and map key order helpers are shipped with the com.gsrpc package
+	if codegen.script.Package != "com.gsrpc" {
+		return
+	}
+
+	var buff bytes.Buffer
+
+	if err := codegen.tpl.ExecuteTemplate(&buff, "tagged", nil); err != nil {
+		gserrors.Panicf(err, "exec template(tagged) error")
+	}
+
+	codegen.writeJavaFile("Tagged", nil, buff.Bytes())
+
+	buff.Reset()
+
+	if err := codegen.tpl.ExecuteTemplate(&buff, "mapkeys", nil); err != nil {
+		gserrors.Panicf(err, "exec template(mapkeys) error")
+	}
+
+	codegen.writeJavaFile("MapKeys", nil, buff.Bytes())
+}
+
+// doc get the doc comment from the .gs comments of node
+func doc(indent string, node ast.Node) string {
+
+	comments := gslang.Comments(node)
+
+	if len(comments) == 0 {
+		return ""
+	}
+
+	var buff bytes.Buffer
+
+	buff.WriteString(indent + "/**\n")
+
+	for _, line := range comments {
+		buff.WriteString(indent + " * " + strings.Replace(strings.TrimSpace(line), "*/", "*&#47;", -1) + "\n")
+	}
+
+	buff.WriteString(indent + " */\n")
+
+	return buff.String()
 }
@@ -5,9 +5,18 @@ var t4java = `
 /*
  * {{title .Name}} generate by gs2java,don't modify it manually
  */
-public enum {{title .Name}} {
+{{doc "" .}}public enum {{title .Name}} {
     {{enumFields .}}
     private {{enumType .}} value;
+    /**
+     * KEY_ORDER the canonical map key order, which compares the constant values as unsigned
+     */
+    public static final java.util.Comparator<{{$Enum}}> KEY_ORDER = new java.util.Comparator<{{$Enum}}>() {
+        @Override
+        public int compare({{$Enum}} lhs, {{$Enum}} rhs) {
+            return {{if enumSize . | eq 4}}Integer.compareUnsigned(lhs.getValue(), rhs.getValue()){{else}}Integer.compare(lhs.getValue() & 0xff, rhs.getValue() & 0xff){{end}};
+        }
+    };
     {{title .Name}}({{enumType .}} val){
         this.value = val;
     }
@@ -41,14 +50,43 @@ public enum {{title .Name}} {
         }
         throw new Exception("unknown enum constant :" + code);
     }
+    /**
+     * toJSON render the constant as "{{$Enum}}.Name", which is same as the golang MarshalText
+     */
+    public String toJSON()
+    {
+        switch(this.value)
+        {
+        {{range .Constants}}
+        case {{.Value}}:
+            return "{{$Enum}}.{{title .Name}}";
+        {{end}}
+        }
+        return "{{$Enum}}." + toString();
+    }
+    /**
+     * fromJSON parse the constant name with or without "{{$Enum}}." prefix
+     */
+    public static {{title .Name}} fromJSON(String name) throws Exception
+    {
+        switch(name)
+        {
+        {{range .Constants}}
+        case "{{$Enum}}.{{title .Name}}":
+        case "{{title .Name}}":
+            return {{$Enum}}.{{title .Name}};
+        {{end}}
+        }
+        throw new Exception("unknown enum constant :" + name);
+    }
 }
 {{end}}
 
 {{define "table"}}{{$Struct := tableName .}}
-public class {{$Struct}} {{if isException .}}extends Exception{{end}}
+{{doc "" .}}public class {{$Struct}} {{if isException .}}extends Exception{{end}}
 {
 {{range .Fields}}
-    private  {{typeName .Type}} {{fieldName .Name}} = {{defaultVal .Type}};
+{{doc "    " .}}    private  {{fieldType .}} {{fieldName .Name}} = {{fieldDefault .}};
 {{end}}
 
 {{if .Fields}}
@@ -64,33 +102,87 @@ public class {{$Struct}} {{if isException .}}extends Exception{{end}}
     }
 
 {{range .Fields}}
-    public {{typeName .Type}} get{{title .Name}}()
+    public {{fieldType .}} get{{title .Name}}()
     {
         return this.{{fieldName .Name}};
     }
-    public void set{{title .Name}}({{typeName .Type}} arg)
+    public void set{{title .Name}}({{fieldType .}} arg)
     {
         this.{{fieldName .Name}} = arg;
     }
+{{if isOptional .}}
+    public boolean has{{title .Name}}()
+    {
+        return this.{{fieldName .Name}} != null;
+    }
+{{end}}
+{{end}}
+
+    public org.json.JSONObject toJSON() throws Exception
+    {
+        org.json.JSONObject __json = new org.json.JSONObject();
+{{range .Fields}}
+        {{if isOptional .}}if(this.{{fieldName .Name}} != null) {{end}}{
+            Object __value = null;
+            {{toJSON "__value" (printf "this.%s" (fieldName .Name)) .Type 4}}
+            __json.put("{{title .Name}}", __value);
+        }
+{{end}}
+        return __json;
+    }
+
+    public void fromJSON(org.json.JSONObject __json) throws Exception
+    {
+{{range .Fields}}
+        if(__json.has("{{title .Name}}")) {
+            Object __value = __json.get("{{title .Name}}");
+            {{if isOptional .}}
+            if(__value == org.json.JSONObject.NULL) {
+                this.{{fieldName .Name}} = null;
+            } else {
+                {{typeName .Type}} __field = {{defaultVal .Type}};
+                {{fromJSON "__field" "__value" .Type 5}}
+                this.{{fieldName .Name}} = __field;
+            }
+            {{else}}
+            {{fromJSON (printf "this.%s" (fieldName .Name)) "__value" .Type 4}}
+            {{end}}
+        }
 {{end}}
+    }
 
 {{if isPOD . | not}}
     public void marshal(Writer writer)  throws Exception
     {
-        writer.writeByte((byte){{len .Fields}});
+        writer.writeUInt16((short){{len .Fields}});
 {{range .Fields}}
-        writer.writeByte((byte){{tagValue .Type}});
+        {{if isOptional .}}
+        if(this.{{fieldName .Name}} == null) {
+            writer.writeByte((byte)com.gsrpc.Tag.Skip.getValue());
+        } else {
+            {{range tags .Type}}writer.writeByte((byte){{.}});
+            {{end}}
+            {{marshalField .}}
+        }
+        {{else}}
+        {{range tags .Type}}writer.writeByte((byte){{.}});
+        {{end}}
         {{marshalField .}}
+        {{end}}
 {{end}}
     }
     public void unmarshal(Reader reader) throws Exception
     {
-        byte __fields = reader.readByte();
+        int __fields = reader.readUInt16() & 0xffff;
+
+        if(__fields == 0) {
+            return;
+        }
 {{range .Fields}}
         {
-            byte tag = reader.readByte();
+            byte[] tag = com.gsrpc.Tagged.readTags(reader);
 
-            if(tag != com.gsrpc.Tag.Skip.getValue()) {
+            if(tag[0] != com.gsrpc.Tag.Skip.getValue()) {
                 {{unmarshalField .}}
             }
 
@@ -101,21 +193,28 @@ public class {{$Struct}} {{if isException .}}extends Exception{{end}}
 
 {{end}}
 
-        for(int i = 0; i < (int)__fields; i ++) {
-            byte tag = reader.readByte();
+        for(int i = 0; i < __fields; i ++) {
+            byte[] tag = com.gsrpc.Tagged.readTags(reader);
 
-            if (tag == com.gsrpc.Tag.Skip.getValue()) {
+            if (tag[0] == com.gsrpc.Tag.Skip.getValue()) {
                 continue;
             }
 
-            reader.readSkip(tag);
+            com.gsrpc.Tagged.skip(reader, tag);
         }
     }
 {{else}}
     public void marshal(Writer writer)  throws Exception
     {
 {{range .Fields}}
+        {{if isOptional .}}
+        writer.writeBoolean(this.{{fieldName .Name}} != null);
+        if(this.{{fieldName .Name}} != null) {
+            {{marshalField .}}
+        }
+        {{else}}
         {{marshalField .}}
+        {{end}}
 {{end}}
     }
 
@@ -123,7 +222,13 @@ public class {{$Struct}} {{if isException .}}extends Exception{{end}}
     {
 {{range .Fields}}
         {
+            {{if isOptional .}}
+            if(reader.readBoolean()) {
+                {{unmarshalField .}}
+            }
+            {{else}}
             {{unmarshalField .}}
+            {{end}}
         }
 {{end}}
     }
@@ -133,12 +238,140 @@ public class {{$Struct}} {{if isException .}}extends Exception{{end}}
 {{end}}
 
 
+{{define "union"}}{{$Union := tableName .}}
+/*
+ * {{$Union}} union generate by gs2java,don't modify it manually
+ */
+{{doc "" .}}public final class {{$Union}}
+{
+    public enum Kind {
+        None,{{range .Fields}}{{title .Name}},{{end}}
+    }
+
+    private Kind kind = Kind.None;
+
+    private Object value = null;
+
+    public Kind getKind()
+    {
+        return this.kind;
+    }
+
+{{range .Fields}}
+    public boolean is{{title .Name}}()
+    {
+        return this.kind == Kind.{{title .Name}};
+    }
+    public {{boxTypeName .Type}} get{{title .Name}}()
+    {
+        return this.kind == Kind.{{title .Name}} ? ({{boxTypeName .Type}})this.value : null;
+    }
+    public void set{{title .Name}}({{boxTypeName .Type}} arg)
+    {
+        this.kind = arg == null ? Kind.None : Kind.{{title .Name}};
+        this.value = arg;
+    }
+{{end}}
+
+    public Object toJSON() throws Exception
+    {
+        org.json.JSONObject json = new org.json.JSONObject();
+
+        Object value = null;
+
+        switch(this.kind) {
+{{range .Fields}}
+        case {{title .Name}}:
+            {{toJSON "value" (printf "((%s)this.value)" (boxTypeName .Type)) .Type 4}}
+            json.put("{{title .Name}}", value);
+            return json;
+{{end}}
+        default:
+            return org.json.JSONObject.NULL;
+        }
+    }
+
+    public void fromJSON(Object value) throws Exception
+    {
+        this.kind = Kind.None;
+
+        this.value = null;
+
+        if(value == org.json.JSONObject.NULL) {
+            return;
+        }
+
+        org.json.JSONObject json = (org.json.JSONObject)value;
+
+        if(json.length() != 1) {
+            throw new Exception("expect one {{$Union}} variant, got " + json.length());
+        }
+{{range .Fields}}
+        if(json.has("{{title .Name}}")) {
+            {{typeName .Type}} v = {{defaultVal .Type}};
+            {{fromJSON "v" (printf "json.get(\"%s\")" (title .Name)) .Type 4}}
+            this.kind = Kind.{{title .Name}};
+            this.value = v;
+            return;
+        }
+{{end}}
+        throw new Exception("unknown {{$Union}} variant :" + json.keys().next());
+    }
+
+    public void marshal(Writer writer)  throws Exception
+    {
+        writer.writeByte((byte)this.kind.ordinal());
+
+        switch(this.kind) {
+{{range .Fields}}
+        case {{title .Name}}: {
+            {{range tags .Type}}writer.writeByte((byte){{.}});
+            {{end}}
+            {{marshalVariant .}}
+            break;
+        }
+{{end}}
+        default:
+            break;
+        }
+    }
+
+    public void unmarshal(Reader reader) throws Exception
+    {
+        this.kind = Kind.None;
+
+        this.value = null;
+
+        int variant = reader.readByte() & 0xff;
+
+        if(variant == 0) {
+            return;
+        }
+
+        byte[] tag = com.gsrpc.Tagged.readTags(reader);
+
+        switch(variant) {
+{{range $index,$field := .Fields}}
+        case {{variant $index}}: {
+            {{unmarshalVariant .}}
+            this.kind = Kind.{{title .Name}};
+            this.value = v;
+            break;
+        }
+{{end}}
+        default:
+            com.gsrpc.Tagged.skip(reader, tag);
+        }
+    }
+}
+{{end}}
+
 {{define "contract"}}{{$Contract := title .Name}}
 
-public interface {{$Contract}} {
+{{doc "" .}}public interface {{$Contract}} {
     String NAME = "{{.FullName}}";
 {{range .Methods}}
-    {{returnParam .Return}} {{methodName .Name}} {{params .Params}} throws Exception;
+{{doc "    " .}}    {{returnParam .Return}} {{methodName .Name}} {{params .Params}} throws Exception;
 {{end}}
 }
 
@@ -265,6 +498,17 @@ public final class {{$Contract}}RPC {
 
                     if(callReturn.getException() != (byte)-1) {
                         switch(callReturn.getException()) {
+                            case -2:{
+                            com.gsrpc.BufferReader reader = new com.gsrpc.BufferReader(callReturn.getContent());
+
+                            com.gsrpc.InvalidArgumentException exception = new com.gsrpc.InvalidArgumentException();
+
+                            exception.unmarshal(reader);
+
+                            Notify(exception,null);
+
+                            return;
+                        }
                             {{range .Exceptions}}
                             case {{.ID}}:{
                             com.gsrpc.BufferReader reader = new com.gsrpc.BufferReader(callReturn.getContent());
@@ -306,4 +550,209 @@ public final class {{$Contract}}RPC {
     {{end}}
 }
 {{end}}
+
+{{define "tagged"}}
+/*
+ * Tagged generate by gs2java,don't modify it manually
+ */
+public final class Tagged {
+
+    private Tagged() {
+    }
+
+    /**
+     * read the tag sequence of tagged value, the nested container and POD tags are followed by their component tags
+     */
+    public static byte[] readTags(Reader reader) throws Exception
+    {
+        java.io.ByteArrayOutputStream tags = new java.io.ByteArrayOutputStream();
+
+        readTags(reader, tags);
+
+        return tags.toByteArray();
+    }
+
+    private static void readTags(Reader reader, java.io.ByteArrayOutputStream tags) throws Exception
+    {
+        byte tag = reader.readByte();
+
+        tags.write(tag);
+
+        int nested = 0;
+
+        switch(tag) {
+{{range wireNested}}{{range .Tags}}
+        case {{tagByte .}}: // {{.}}{{end}}
+            {{if lt .Nested 0}}
+            nested = reader.readByte() & 0xff;
+            tags.write(nested);
+            {{else}}
+            nested = {{.Nested}};
+            {{end}}
+            break;
+{{end}}
+        default:
+            break;
+        }
+
+        for(int i = 0; i < nested; i ++) {
+            readTags(reader, tags);
+        }
+    }
+
+    /**
+     * skip the tagged value described by the tag sequence
+     */
+    public static void skip(Reader reader, byte[] tags) throws Exception
+    {
+        skip(reader, tags, 0);
+    }
+
+    // tagsEnd get the index next to the tag sequence begin with index
+    private static int tagsEnd(byte[] tags, int index)
+    {
+        int next = index + 1;
+
+        int nested = 0;
+
+        switch(tags[index]) {
+{{range wireNested}}{{range .Tags}}
+        case {{tagByte .}}: // {{.}}{{end}}
+            {{if lt .Nested 0}}
+            next = index + 2;
+            nested = tags[index + 1] & 0xff;
+            {{else}}
+            nested = {{.Nested}};
+            {{end}}
+            break;
+{{end}}
+        default:
+            break;
+        }
+
+        for(int i = 0; i < nested; i ++) {
+            next = tagsEnd(tags, next);
+        }
+
+        return next;
+    }
+
+    // skipComponents skip the values of count tag sequences begin with index
+    private static void skipComponents(Reader reader, byte[] tags, int index, int count) throws Exception
+    {
+        for(int i = 0; i < count; i ++) {
+            skip(reader, tags, index);
+            index = tagsEnd(tags, index);
+        }
+    }
+
+    private static void skip(Reader reader, byte[] tags, int index) throws Exception
+    {
+        switch(tags[index]) {
+{{range wireCases}}{{range .Tags}}
+        case {{tagByte .}}: // {{.}}{{end}}
+        {
+            {{if eq .Kind "fixed"}}
+            for(int i = 0; i < {{.Size}}; i ++) {
+                reader.readByte();
+            }
+            {{else if eq .Kind "sized"}}
+            reader.readBytes();
+            {{else if eq .Kind "fields"}}
+            int fields = reader.readUInt16() & 0xffff;
+
+            for(int i = 0; i < fields; i ++) {
+                skip(reader, readTags(reader));
+            }
+            {{else if eq .Kind "seq"}}
+            int length = reader.readUInt16() & 0xffff;
+
+            for(int i = 0; i < length; i ++) {
+                skipComponents(reader, tags, index + 1, {{.Nested}});
+            }
+            {{else if eq .Kind "record"}}
+            skipComponents(reader, tags, index + 2, tags[index + 1] & 0xff);
+            {{else if eq .Kind "optional"}}
+            if(reader.readBoolean()) {
+                skip(reader, tags, index + 1);
+            }
+            {{else if eq .Kind "variant"}}
+            if(reader.readByte() != 0) {
+                skip(reader, readTags(reader));
+            }
+            {{end}}
+            break;
+        }
+{{end}}
+        default:
+            throw new Exception("unknown tag :" + tags[index]);
+        }
+    }
+}
+{{end}}
+
+{{define "mapkeys"}}
+/*
+ * MapKeys generate by gs2java,don't modify it manually
+ *
+ * the comparators of canonical map key order, numbers are compared by value with the declared signedness
+ * and strings by UTF-8 bytes, the map entries are encoded in this order
+ */
+public final class MapKeys {
+
+    private MapKeys() {
+    }
+
+    public static final java.util.Comparator<Byte> UNSIGNED_BYTE = new java.util.Comparator<Byte>() {
+        @Override
+        public int compare(Byte lhs, Byte rhs) {
+            return Integer.compare(lhs & 0xff, rhs & 0xff);
+        }
+    };
+
+    public static final java.util.Comparator<Short> UNSIGNED_SHORT = new java.util.Comparator<Short>() {
+        @Override
+        public int compare(Short lhs, Short rhs) {
+            return Integer.compare(lhs & 0xffff, rhs & 0xffff);
+        }
+    };
+
+    public static final java.util.Comparator<Integer> UNSIGNED_INT = new java.util.Comparator<Integer>() {
+        @Override
+        public int compare(Integer lhs, Integer rhs) {
+            return Integer.compareUnsigned(lhs, rhs);
+        }
+    };
+
+    public static final java.util.Comparator<Long> UNSIGNED_LONG = new java.util.Comparator<Long>() {
+        @Override
+        public int compare(Long lhs, Long rhs) {
+            return Long.compareUnsigned(lhs, rhs);
+        }
+    };
+
+    /**
+     * UTF8 compare strings by code points, which is same as comparing their UTF-8 bytes
+     */
+    public static final java.util.Comparator<String> UTF8 = new java.util.Comparator<String>() {
+        @Override
+        public int compare(String lhs, String rhs) {
+            int i = 0, j = 0;
+
+            while(i < lhs.length() && j < rhs.length()) {
+                int l = lhs.codePointAt(i), r = rhs.codePointAt(j);
+
+                if(l != r) {
+                    return Integer.compare(l, r);
+                }
+
+                i += Character.charCount(l);
+                j += Character.charCount(r);
+            }
+
+            return Boolean.compare(i < lhs.length(), j < rhs.length());
+        }
+    };
+}
+{{end}}
 `
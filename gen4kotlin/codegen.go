@@ -0,0 +1,808 @@
+package gen4kotlin
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+	"unicode"
+
+	"github.com/gsdocker/gserrors"
+	"github.com/gsdocker/gslogger"
+	"github.com/gsrpc/gslang"
+	"github.com/gsrpc/gslang/ast"
+	"github.com/gsrpc/gslang/lexer"
+	"github.com/gsrpc/gsrpc/gen"
+	"github.com/gsrpc/gsrpc/wire"
+)
+
+var builtin = map[lexer.TokenType]string{
+	lexer.KeySByte:   "Byte",
+	lexer.KeyByte:    "UByte",
+	lexer.KeyInt16:   "Short",
+	lexer.KeyUInt16:  "UShort",
+	lexer.KeyInt32:   "Int",
+	lexer.KeyUInt32:  "UInt",
+	lexer.KeyInt64:   "Long",
+	lexer.KeyUInt64:  "ULong",
+	lexer.KeyFloat32: "Float",
+	lexer.KeyFloat64: "Double",
+	lexer.KeyBool:    "Boolean",
+	lexer.KeyString:  "String",
+	lexer.KeyVoid:    "Unit",
+}
+
+var readMapping = map[lexer.TokenType]string{
+	lexer.KeySByte:   "reader.readByte()",
+	lexer.KeyByte:    "reader.readUByte()",
+	lexer.KeyInt16:   "reader.readShort()",
+	lexer.KeyUInt16:  "reader.readUShort()",
+	lexer.KeyInt32:   "reader.readInt()",
+	lexer.KeyUInt32:  "reader.readUInt()",
+	lexer.KeyInt64:   "reader.readLong()",
+	lexer.KeyUInt64:  "reader.readULong()",
+	lexer.KeyFloat32: "reader.readFloat()",
+	lexer.KeyFloat64: "reader.readDouble()",
+	lexer.KeyBool:    "reader.readBoolean()",
+	lexer.KeyString:  "reader.readString()",
+}
+
+var writeMapping = map[lexer.TokenType]string{
+	lexer.KeySByte:   "writer.writeByte",
+	lexer.KeyByte:    "writer.writeUByte",
+	lexer.KeyInt16:   "writer.writeShort",
+	lexer.KeyUInt16:  "writer.writeUShort",
+	lexer.KeyInt32:   "writer.writeInt",
+	lexer.KeyUInt32:  "writer.writeUInt",
+	lexer.KeyInt64:   "writer.writeLong",
+	lexer.KeyUInt64:  "writer.writeULong",
+	lexer.KeyFloat32: "writer.writeFloat",
+	lexer.KeyFloat64: "writer.writeDouble",
+	lexer.KeyBool:    "writer.writeBoolean",
+	lexer.KeyString:  "writer.writeString",
+}
+
+var defaultval = map[lexer.TokenType]string{
+	lexer.KeySByte:   "0",
+	lexer.KeyByte:    "0u",
+	lexer.KeyInt16:   "0",
+	lexer.KeyUInt16:  "0u",
+	lexer.KeyInt32:   "0",
+	lexer.KeyUInt32:  "0u",
+	lexer.KeyInt64:   "0L",
+	lexer.KeyUInt64:  "0uL",
+	lexer.KeyFloat32: "0f",
+	lexer.KeyFloat64: "0.0",
+	lexer.KeyBool:    "false",
+	lexer.KeyString:  "\"\"",
+}
+
+// keywords the kotlin hard keywords, which are escaped with backticks when used as identifiers
+var keywords = map[string]bool{
+	"as": true, "break": true, "class": true, "continue": true, "do": true, "else": true, "false": true,
+	"for": true, "fun": true, "if": true, "in": true, "interface": true, "is": true, "null": true,
+	"object": true, "package": true, "return": true, "super": true, "this": true, "throw": true, "true": true,
+	"try": true, "typealias": true, "typeof": true, "val": true, "var": true, "when": true, "while": true,
+}
+
+// runtimeFile the runtime source file name, which is generated in the com.gsrpc package directory
+const runtimeFile = "Runtime.kt"
+
+type _CodeGen struct {
+	gslogger.Log                    // Log APIs
+	rootpath     string             // root path
+	files        map[string][]byte  // generated files sink, the files are written into rootpath if nil
+	script       *ast.Script        // current script
+	content      bytes.Buffer       // current script content
+	tpl          *template.Template // code generate template
+	skips        []*regexp.Regexp   // skip lists
+	compiler     *gslang.Compiler   // current compiler
+	union        bool               // generating union, which nested variant classes may hide the same name types
+	runtime      bool               // runtime file is generated
+}
+
+// NewCodeGen .
+func NewCodeGen(rootpath string, skips []string) (gslang.Visitor, error) {
+
+	codeGen := &_CodeGen{
+		Log:      gslogger.Get("gen4kotlin"),
+		rootpath: rootpath,
+	}
+
+	for _, skip := range skips {
+		exp, err := regexp.Compile(skip)
+
+		if err != nil {
+			return nil, gserrors.Newf(err, "invalid skip regex string :%s", skip)
+		}
+
+		codeGen.skips = append(codeGen.skips, exp)
+	}
+
+	funcs := template.FuncMap{
+		"title":        strings.Title,
+		"tableName":    gen.TableName,
+		"memberName":   memberName,
+		"constName":    constName,
+		"doc":          doc,
+		"notVoid":      gslang.NotVoid,
+		"isPOD":        gslang.IsPOD,
+		"isAsync":      gslang.IsAsync,
+		"isException":  gslang.IsException,
+		"enumSize":     gslang.EnumSize,
+		"isOptional":   gen.IsOptional,
+		"variant":      gen.Variant,
+		"bytesFields":  bytesFields,
+		"isByteArray":  isByteArray,
+		"typeName":     codeGen.typeName,
+		"fieldType":    codeGen.fieldType,
+		"readType":     codeGen.readType,
+		"writeType":    codeGen.writeType,
+		"tagValue":     codeGen.tagValue,
+		"wireCases":    wire.Cases,
+		"wireNested":   wire.Nested,
+		"rpcName":      codeGen.rpcName,
+		"fieldDefault": codeGen.fieldDefault,
+		"params":       codeGen.params,
+		"returnType":   codeGen.returnType,
+		"callArgs":     callArgs,
+	}
+
+	tpl, err := template.New("t4kotlin").Funcs(funcs).Parse(t4kotlin)
+
+	if err != nil {
+		return nil, err
+	}
+
+	codeGen.tpl = tpl
+
+	return codeGen, nil
+}
+
+// NewCodeGenWithFiles create codegen which write generated files into files map keyed by slash separated relative path
+func NewCodeGenWithFiles(files map[string][]byte, skips []string) (gslang.Visitor, error) {
+
+	codeGen, err := NewCodeGen("", skips)
+
+	if err != nil {
+		return nil, err
+	}
+
+	codeGen.(*_CodeGen).files = files
+
+	return codeGen, nil
+}
+
+// lowerName get the lower camel case name, the leading acronym is lowered as a whole, e.g. OSVersion -> osVersion
+func lowerName(name string) string {
+
+	runes := []rune(name)
+
+	upper := 0
+
+	for upper < len(runes) && unicode.IsUpper(runes[upper]) {
+		upper++
+	}
+
+	if upper > 1 && upper < len(runes) && unicode.IsLower(runes[upper]) {
+		upper--
+	}
+
+	for i := 0; i < upper; i++ {
+		runes[i] = unicode.ToLower(runes[i])
+	}
+
+	return string(runes)
+}
+
+func escape(name string) string {
+	if keywords[name] {
+		return "`" + name + "`"
+	}
+
+	return name
+}
+
+// memberName get the property/method/param name, kotlin keyword is escaped with backticks
+func memberName(name string) string {
+	return escape(lowerName(name))
+}
+
+// constName get the enum constant name
+func constName(name string) string {
+	return escape(strings.Title(name))
+}
+
+// qualify get the symbol reference name, symbols of other packages or referenced in union are full qualified
+func (codegen *_CodeGen) qualify(packageName string, name string) string {
+
+	if packageName == codegen.script.Package && !codegen.union {
+		return name
+	}
+
+	return packageName + "." + name
+}
+
+// rpcName get the reference name of com.gsrpc package symbol
+func (codegen *_CodeGen) rpcName(name string) string {
+	return codegen.qualify("com.gsrpc", name)
+}
+
+func (codegen *_CodeGen) tagValue(typeDecl ast.Type) string {
+	return strings.Join(codegen.tags(typeDecl), ", ")
+}
+
+// tags get the type tag sequence by the shared tag scheme
+func (codegen *_CodeGen) tags(typeDecl ast.Type) []string {
+
+	tag := codegen.rpcName("Tag")
+
+	tags := wire.Format(wire.Tags(typeDecl), func(t wire.Tag) string {
+		return tag + "." + t.String() + ".value"
+	})
+
+	// the POD field count is formatted as number, which is written as UByte literal
+	for i, name := range tags {
+		if !strings.HasPrefix(name, tag) {
+			tags[i] = name + "u"
+		}
+	}
+
+	return tags
+}
+
+// isByteArray check if the field is generated as ByteArray, which is compared by content
+func isByteArray(field *ast.Field) bool {
+
+	typeDecl := field.Type
+
+	if typeRef, ok := typeDecl.(*ast.TypeRef); ok {
+		typeDecl = typeRef.Ref
+	}
+
+	seq, ok := typeDecl.(*ast.Seq)
+
+	return ok && gen.IsBytes(seq)
+}
+
+// bytesFields get the ByteArray fields of table, the data class equals/hashCode are overridden to compare their contents
+func bytesFields(table *ast.Table) (fields []*ast.Field) {
+
+	for _, field := range table.Fields {
+		if isByteArray(field) {
+			fields = append(fields, field)
+		}
+	}
+
+	return
+}
+
+// declName get the declared type name, the union type name is not nullable
+func (codegen *_CodeGen) declName(typeDecl ast.Type) string {
+	if _, ok := typeDecl.(*ast.Enum); ok {
+		return codegen.qualify(typeDecl.Package(), strings.Title(typeDecl.Name()))
+	}
+
+	return codegen.qualify(typeDecl.Package(), gen.TableName(typeDecl))
+}
+
+func (codegen *_CodeGen) typeName(typeDecl ast.Type) string {
+	switch typeDecl.(type) {
+	case *ast.BuiltinType:
+		builtinType := typeDecl.(*ast.BuiltinType)
+
+		return builtin[builtinType.Type]
+	case *ast.TypeRef:
+		return codegen.typeName(typeDecl.(*ast.TypeRef).Ref)
+
+	case *ast.Enum:
+		return codegen.declName(typeDecl)
+
+	case *ast.Table:
+		// the union is null when none of the variants is set
+		if gen.IsOneOf(typeDecl) {
+			return codegen.declName(typeDecl) + "?"
+		}
+
+		return codegen.declName(typeDecl)
+
+	case *ast.Seq:
+		seq := typeDecl.(*ast.Seq)
+
+		if gen.IsBytes(seq) {
+			return "ByteArray"
+		}
+
+		if entry, ok := wire.MapEntry(seq); ok {
+			return fmt.Sprintf("Map<%s, %s>", codegen.typeName(entry.Fields[0].Type), codegen.typeName(entry.Fields[1].Type))
+		}
+
+		return fmt.Sprintf("List<%s>", codegen.typeName(seq.Component))
+	}
+
+	gserrors.Panicf(nil, "typeName  error: unsupport type(%s)", typeDecl)
+
+	return "unknown"
+}
+
+// fieldType get the field type name, optional field is nullable
+func (codegen *_CodeGen) fieldType(field *ast.Field) string {
+
+	if gen.IsOptional(field) && !gen.IsOneOf(field.Type) {
+		return codegen.typeName(field.Type) + "?"
+	}
+
+	return codegen.typeName(field.Type)
+}
+
+func (codegen *_CodeGen) defaultVal(typeDecl ast.Type) string {
+	switch typeDecl.(type) {
+	case *ast.BuiltinType:
+		builtinType := typeDecl.(*ast.BuiltinType)
+
+		return defaultval[builtinType.Type]
+	case *ast.TypeRef:
+		return codegen.defaultVal(typeDecl.(*ast.TypeRef).Ref)
+
+	case *ast.Enum:
+		enum := typeDecl.(*ast.Enum)
+
+		return codegen.declName(enum) + "." + constName(enum.Constants[0].Name())
+
+	case *ast.Table:
+		if gen.IsOneOf(typeDecl) {
+			return "null"
+		}
+
+		return codegen.declName(typeDecl) + "()"
+
+	case *ast.Seq:
+		seq := typeDecl.(*ast.Seq)
+
+		if gen.IsBytes(seq) {
+			if seq.Size != -1 {
+				return fmt.Sprintf("ByteArray(%d)", seq.Size)
+			}
+
+			return "ByteArray(0)"
+		}
+
+		if _, ok := wire.MapEntry(seq); ok {
+			return "emptyMap()"
+		}
+
+		if seq.Size != -1 {
+			return fmt.Sprintf("List(%d) { %s }", seq.Size, codegen.defaultVal(seq.Component))
+		}
+
+		return "emptyList()"
+	}
+
+	gserrors.Panicf(nil, "defaultVal  error: unsupport type(%s)", typeDecl)
+
+	return "unknown"
+}
+
+// keyOrder get the comparator expr of map keys, the keys are written in the same order as go server
+func (codegen *_CodeGen) keyOrder(typeDecl ast.Type) string {
+	switch typeDecl.(type) {
+	case *ast.TypeRef:
+		return codegen.keyOrder(typeDecl.(*ast.TypeRef).Ref)
+	case *ast.Enum:
+		return fmt.Sprintf("compareBy<%s> { it.value }", codegen.typeName(typeDecl))
+	case *ast.BuiltinType:
+		if typeDecl.(*ast.BuiltinType).Type == lexer.KeyString {
+			return codegen.rpcName("utf8Order")
+		}
+	}
+
+	return fmt.Sprintf("naturalOrder<%s>()", codegen.typeName(typeDecl))
+}
+
+// readType get the expr which read the type value from the reader variable
+func (codegen *_CodeGen) readType(typeDecl ast.Type) string {
+	switch typeDecl.(type) {
+	case *ast.BuiltinType:
+		builtinType := typeDecl.(*ast.BuiltinType)
+
+		return readMapping[builtinType.Type]
+	case *ast.TypeRef:
+		return codegen.readType(typeDecl.(*ast.TypeRef).Ref)
+
+	case *ast.Enum:
+		if gslang.EnumSize(typeDecl) == 4 {
+			return fmt.Sprintf("%s(reader.readUInt())", codegen.declName(typeDecl))
+		}
+
+		return fmt.Sprintf("%s.from(reader.readUByte())", codegen.declName(typeDecl))
+
+	case *ast.Table:
+		return fmt.Sprintf("%s.read(reader)", codegen.declName(typeDecl))
+
+	case *ast.Seq:
+		seq := typeDecl.(*ast.Seq)
+
+		if gen.IsBytes(seq) {
+			if seq.Size != -1 {
+				return fmt.Sprintf("reader.readFixedBytes(%d)", seq.Size)
+			}
+
+			return "reader.readBytes()"
+		}
+
+		if entry, ok := wire.MapEntry(seq); ok {
+			return fmt.Sprintf("reader.readMap({ %s }, { %s })", codegen.readType(entry.Fields[0].Type), codegen.readType(entry.Fields[1].Type))
+		}
+
+		if seq.Size != -1 {
+			return fmt.Sprintf("reader.readArray(%d) { %s }", seq.Size, codegen.readType(seq.Component))
+		}
+
+		return fmt.Sprintf("reader.readList { %s }", codegen.readType(seq.Component))
+	}
+
+	gserrors.Panicf(nil, "readType  error: unsupport type(%s)", typeDecl)
+
+	return "unknown"
+}
+
+// writeType get the statement which write the valname to the writer variable, depth is used to name the lambda args
+func (codegen *_CodeGen) writeType(typeDecl ast.Type, valname string, depth int) string {
+	switch typeDecl.(type) {
+	case *ast.BuiltinType:
+		builtinType := typeDecl.(*ast.BuiltinType)
+
+		return fmt.Sprintf("%s(%s)", writeMapping[builtinType.Type], valname)
+	case *ast.TypeRef:
+		return codegen.writeType(typeDecl.(*ast.TypeRef).Ref, valname, depth)
+
+	case *ast.Enum:
+		if gslang.EnumSize(typeDecl) == 4 {
+			return fmt.Sprintf("writer.writeUInt(%s.value)", valname)
+		}
+
+		return fmt.Sprintf("writer.writeUByte(%s.value)", valname)
+
+	case *ast.Table:
+		if gen.IsOneOf(typeDecl) {
+			return fmt.Sprintf("%s.write(writer, %s)", codegen.declName(typeDecl), valname)
+		}
+
+		return fmt.Sprintf("%s.write(writer)", valname)
+
+	case *ast.Seq:
+		seq := typeDecl.(*ast.Seq)
+
+		if gen.IsBytes(seq) {
+			if seq.Size != -1 {
+				return fmt.Sprintf("writer.writeFixedBytes(%d, %s)", seq.Size, valname)
+			}
+
+			return fmt.Sprintf("writer.writeBytes(%s)", valname)
+		}
+
+		key, val := fmt.Sprintf("k%d", depth), fmt.Sprintf("v%d", depth)
+
+		if entry, ok := wire.MapEntry(seq); ok {
+			return fmt.Sprintf("writer.writeMap(%s, %s, { %s -> %s }, { %s -> %s })", valname, codegen.keyOrder(entry.Fields[0].Type),
+				key, codegen.writeType(entry.Fields[0].Type, key, depth+1),
+				val, codegen.writeType(entry.Fields[1].Type, val, depth+1))
+		}
+
+		if seq.Size != -1 {
+			return fmt.Sprintf("writer.writeArray(%d, %s) { %s -> %s }", seq.Size, valname, val, codegen.writeType(seq.Component, val, depth+1))
+		}
+
+		return fmt.Sprintf("writer.writeList(%s) { %s -> %s }", valname, val, codegen.writeType(seq.Component, val, depth+1))
+	}
+
+	gserrors.Panicf(nil, "writeType  error: unsupport type(%s)", typeDecl)
+
+	return "unknown"
+}
+
+// fieldDefault get the constructor param default value expr, optional field without default value is null
+func (codegen *_CodeGen) fieldDefault(field *ast.Field) string {
+
+	expr, ok := gen.Default(field)
+
+	if !ok {
+		if gen.IsOptional(field) {
+			return "null"
+		}
+
+		return codegen.defaultVal(field.Type)
+	}
+
+	start, _ := gslang.Pos(field)
+
+	return codegen.defaultExpr(expr, field.Type, start)
+}
+
+func (codegen *_CodeGen) defaultExpr(expr ast.Expr, typeDecl ast.Type, start lexer.Position) string {
+
+	eval := codegen.compiler.Eval()
+
+	switch typeDecl.(type) {
+	case *ast.BuiltinType:
+		builtinType := typeDecl.(*ast.BuiltinType)
+
+		gen.CheckUnsigned(eval, builtinType, expr, start)
+
+		switch builtinType.Type {
+		case lexer.KeyString:
+			return quote(eval.EvalString(expr))
+		case lexer.KeyBool:
+			return strconv.FormatBool(eval.EvalBool(expr))
+		case lexer.KeyFloat32:
+			return strconv.FormatFloat(eval.EvalFloat(expr), 'g', -1, 32) + "f"
+		case lexer.KeyFloat64:
+			val := strconv.FormatFloat(eval.EvalFloat(expr), 'g', -1, 64)
+
+			// kotlin integer literal can't be converted to Double
+			if !strings.ContainsAny(val, ".eIN") {
+				val += ".0"
+			}
+
+			return val
+		case lexer.KeyByte, lexer.KeyUInt16, lexer.KeyUInt32:
+			return fmt.Sprintf("%du", eval.EvalInt(expr))
+		case lexer.KeyUInt64:
+			return fmt.Sprintf("%duL", eval.EvalInt(expr))
+		case lexer.KeyInt64:
+			return fmt.Sprintf("%dL", eval.EvalInt(expr))
+		case lexer.KeyVoid:
+		default:
+			return fmt.Sprintf("%d", eval.EvalInt(expr))
+		}
+
+	case *ast.TypeRef:
+		return codegen.defaultExpr(expr, typeDecl.(*ast.TypeRef).Ref, start)
+
+	case *ast.Enum:
+		enum := typeDecl.(*ast.Enum)
+
+		val := eval.EvalInt(expr)
+
+		if constant, ok := gen.Constant(enum, val); ok {
+			return codegen.declName(enum) + "." + constName(constant.Name())
+		}
+
+		gserrors.Panicf(nil, "enum %s constant(%d) not found :%v", enum, val, start)
+
+	case *ast.Table:
+		table := typeDecl.(*ast.Table)
+
+		newObj, ok := expr.(*ast.NewObj)
+
+		if !ok || gen.IsOneOf(table) {
+			break
+		}
+
+		// the fields without arg keep the constructor param default values
+		var fields []string
+
+		for i, field := range table.Fields {
+
+			arg, ok := gen.FieldArg(newObj, i, field)
+
+			if ok {
+				fields = append(fields, fmt.Sprintf("%s = %s", memberName(field.Name()), codegen.defaultExpr(arg, field.Type, start)))
+			}
+		}
+
+		return fmt.Sprintf("%s(%s)", codegen.declName(table), strings.Join(fields, ", "))
+	}
+
+	gserrors.Panicf(nil, "unsupport default value for type(%s) :%v", typeDecl, start)
+
+	return "unknown"
+}
+
+// quote get the kotlin string literal, the control characters and $ are escaped
+func quote(val string) string {
+
+	var buff bytes.Buffer
+
+	buff.WriteString("\"")
+
+	for _, r := range val {
+		switch {
+		case r == '"':
+			buff.WriteString("\\\"")
+		case r == '\\':
+			buff.WriteString("\\\\")
+		case r == '$':
+			buff.WriteString("\\$")
+		case r == '\n':
+			buff.WriteString("\\n")
+		case r == '\r':
+			buff.WriteString("\\r")
+		case r == '\t':
+			buff.WriteString("\\t")
+		case r < 0x20 || r == 0x7f:
+			buff.WriteString(fmt.Sprintf("\\u%04x", r))
+		default:
+			buff.WriteRune(r)
+		}
+	}
+
+	buff.WriteString("\"")
+
+	return buff.String()
+}
+
+func (codegen *_CodeGen) params(params []*ast.Param) string {
+
+	var args []string
+
+	for _, param := range params {
+		args = append(args, fmt.Sprintf("%s: %s", memberName(param.Name()), codegen.typeName(param.Type)))
+	}
+
+	return "(" + strings.Join(args, ", ") + ")"
+}
+
+func callArgs(params []*ast.Param) string {
+
+	var args []string
+
+	for _, param := range params {
+		args = append(args, fmt.Sprintf("arg%d", param.ID))
+	}
+
+	return "(" + strings.Join(args, ", ") + ")"
+}
+
+// returnType get the method return type clause, the async method is fire and forget
+func (codegen *_CodeGen) returnType(method *ast.Method) string {
+
+	if gslang.IsAsync(method) || !gslang.NotVoid(method.Return) {
+		return ""
+	}
+
+	return ": " + codegen.typeName(method.Return)
+}
+
+func (codegen *_CodeGen) execute(name string, data interface{}) {
+	if err := codegen.tpl.ExecuteTemplate(&codegen.content, name, data); err != nil {
+		gserrors.Panicf(err, "exec template(%s) for %s error", name, data)
+	}
+}
+
+func (codegen *_CodeGen) writeFile(name string, content []byte) {
+
+	// the content buffer may be reused by caller
+	if codegen.files != nil {
+		codegen.files[filepath.ToSlash(name)] = append([]byte(nil), content...)
+		return
+	}
+
+	fullpath := filepath.Join(codegen.rootpath, name)
+
+	if err := os.MkdirAll(filepath.Dir(fullpath), 0755); err != nil {
+		gserrors.Panicf(err, "create output directory error")
+	}
+
+	codegen.D("write file :%s", fullpath)
+
+	if err := ioutil.WriteFile(fullpath, content, 0644); err != nil {
+		gserrors.Panicf(err, "write generate stub code error")
+	}
+}
+
+// packagePath get the package directory path relative to root path
+func packagePath(packageName string) string {
+	return strings.Replace(packageName, ".", "/", -1)
+}
+
+func (codegen *_CodeGen) BeginScript(compiler *gslang.Compiler, script *ast.Script) bool {
+
+	scriptPath := filepath.ToSlash(filepath.Clean(script.Name()))
+
+	for _, skip := range codegen.skips {
+
+		if skip.MatchString(scriptPath) {
+
+			return false
+		}
+	}
+
+	if strings.HasPrefix(script.Package, "gslang.") {
+		return false
+	}
+
+	codegen.script = script
+
+	codegen.compiler = compiler
+
+	codegen.content.Reset()
+
+	return true
+}
+
+func (codegen *_CodeGen) Using(compiler *gslang.Compiler, using *ast.Using) {
+}
+
+func (codegen *_CodeGen) Table(compiler *gslang.Compiler, tableType *ast.Table) {
+
+	if gen.IsOneOf(tableType) {
+		codegen.union = true
+
+		codegen.execute("union", tableType)
+
+		codegen.union = false
+	} else {
+		codegen.execute("table", tableType)
+	}
+}
+
+func (codegen *_CodeGen) Annotation(compiler *gslang.Compiler, annotation *ast.Table) {
+}
+
+func (codegen *_CodeGen) Enum(compiler *gslang.Compiler, enum *ast.Enum) {
+	codegen.execute("enum", enum)
+}
+
+func (codegen *_CodeGen) Contract(compiler *gslang.Compiler, contract *ast.Contract) {
+	codegen.execute("contract", contract)
+}
+
+// EndScript write the script source file into the package directory
+func (codegen *_CodeGen) EndScript(compiler *gslang.Compiler) {
+
+	var stream bytes.Buffer
+
+	stream.WriteString("// generate by gs2kotlin,don't modify it manually\n\n")
+
+	stream.WriteString(fmt.Sprintf("package %s\n", codegen.script.Package))
+
+	stream.Write(codegen.content.Bytes())
+
+	name := strings.TrimSuffix(filepath.Base(codegen.script.Name()), filepath.Ext(codegen.script.Name()))
+
+	codegen.writeFile(filepath.Join(packagePath(codegen.script.Package), strings.Title(name)+".kt"), tidy.Clean(stream.Bytes()))
+
+	if codegen.runtime {
+		return
+	}
+
+	stream.Reset()
+
+	if err := codegen.tpl.ExecuteTemplate(&stream, "runtime", nil); err != nil {
+		gserrors.Panicf(err, "exec template(runtime) error")
+	}
+
+	codegen.writeFile(filepath.Join(packagePath("com.gsrpc"), runtimeFile), stream.Bytes())
+
+	codegen.runtime = true
+}
+
+var tidy = gen.NewTidy(1, `[{(]`, `[})]`)
+
+// doc get the kdoc comment from the .gs comments of node
+func doc(indent string, node ast.Node) string {
+
+	comments := gslang.Comments(node)
+
+	if len(comments) == 0 {
+		return ""
+	}
+
+	var buff bytes.Buffer
+
+	buff.WriteString(indent + "/**\n")
+
+	for _, line := range comments {
+		buff.WriteString(indent + " * " + strings.Replace(strings.TrimSpace(line), "*/", "*&#47;", -1) + "\n")
+	}
+
+	buff.WriteString(indent + " */\n")
+
+	return buff.String()
+}
@@ -0,0 +1,703 @@
+package gen4kotlin
+
+var t4kotlin = `
+{{define "enum"}}{{$Enum := title .Name}}
+{{doc "" .}}{{if enumSize . | eq 4}}@JvmInline
+value class {{$Enum}}(val value: UInt) {
+    infix fun or(other: {{$Enum}}) = {{$Enum}}(value or other.value)
+
+    operator fun contains(other: {{$Enum}}) = (value and other.value) == other.value
+
+    companion object {
+{{range .Constants}}{{doc "        " .}}        val {{constName .Name}} = {{$Enum}}({{.Value}}u)
+{{end}}    }
+}
+{{else}}enum class {{$Enum}}(val value: UByte) {
+{{range $index, $constant := .Constants}}{{if $index}},
+{{end}}{{doc "    " .}}    {{constName .Name}}({{.Value}}u){{end}};
+
+    companion object {
+        fun from(value: UByte): {{$Enum}} = values().firstOrNull { it.value == value }
+            ?: throw {{rpcName "CodecException"}}("gsrpc: unknown {{$Enum}} value $value")
+    }
+}
+{{end}}{{end}}
+
+{{define "table"}}{{$Table := tableName .}}
+{{doc "" .}}{{if .Fields}}data class {{$Table}}(
+{{range .Fields}}{{doc "    " .}}    var {{memberName .Name}}: {{fieldType .}} = {{fieldDefault .}},
+{{end}}){{else}}class {{$Table}}{{end}}{{if isException .}} : Exception("{{.FullName}}"){{end}} {
+{{if bytesFields .}}
+    override fun equals(other: Any?): Boolean {
+        if (this === other) return true
+
+        if (other !is {{$Table}}) return false
+
+{{range .Fields}}        if (!({{if isByteArray .}}{{memberName .Name}} contentEquals other.{{memberName .Name}}{{else}}{{memberName .Name}} == other.{{memberName .Name}}{{end}})) return false
+{{end}}
+        return true
+    }
+
+    override fun hashCode(): Int {
+        var result = 0
+
+{{range .Fields}}        result = 31 * result + {{memberName .Name}}.{{if isByteArray .}}contentHashCode(){{else}}hashCode(){{end}}
+{{end}}
+        return result
+    }
+{{end}}
+{{if isPOD .}}
+    fun write(writer: {{rpcName "Writer"}}) {
+{{range .Fields}}
+        {{if isOptional .}}when (val value = this.{{memberName .Name}}) {
+            null -> writer.writeBoolean(false)
+            else -> {
+                writer.writeBoolean(true)
+                {{writeType .Type "value" 0}}
+            }
+        }{{else}}{{writeType .Type (printf "this.%s" (memberName .Name)) 0}}{{end}}
+{{end}}    }
+
+    companion object {
+        fun read(reader: {{rpcName "Reader"}}): {{$Table}} {
+            val target = {{$Table}}()
+{{range .Fields}}
+            target.{{memberName .Name}} = {{if isOptional .}}if (reader.readBoolean()) {{readType .Type}} else null{{else}}{{readType .Type}}{{end}}
+{{end}}
+            return target
+        }
+    }
+{{else}}
+    fun write(writer: {{rpcName "Writer"}}) {
+        writer.writeUShort({{len .Fields}}u)
+{{range .Fields}}
+        {{if isOptional .}}when (val value = this.{{memberName .Name}}) {
+            null -> writer.writeTags({{rpcName "Tag"}}.Skip.value)
+            else -> {
+                writer.writeTags({{tagValue .Type}})
+                {{writeType .Type "value" 0}}
+            }
+        }{{else}}writer.writeTags({{tagValue .Type}})
+        {{writeType .Type (printf "this.%s" (memberName .Name)) 0}}{{end}}
+{{end}}    }
+
+    companion object {
+        fun read(reader: {{rpcName "Reader"}}): {{$Table}} {
+            val target = {{$Table}}()
+
+            {{if .Fields}}var{{else}}val{{end}} fields = reader.readUShort().toInt()
+{{range .Fields}}
+            if (fields == 0) return target
+
+            if (reader.readTags()[0] != {{rpcName "Tag"}}.Skip.value) {
+                target.{{memberName .Name}} = {{readType .Type}}
+            }
+
+            fields--
+{{end}}
+            reader.skipFields(fields)
+
+            return target
+        }
+    }
+{{end}}}
+{{end}}
+
+{{define "union"}}{{$Union := tableName .}}
+{{doc "" .}}sealed class {{$Union}} {
+{{range .Fields}}{{doc "    " .}}    data class {{title .Name}}(val value: {{typeName .Type}}) : {{$Union}}()
+{{end}}
+    companion object {
+        fun read(reader: {{rpcName "Reader"}}): {{$Union}}? {
+            val variant = reader.readUByte().toInt()
+
+            if (variant == 0) return null
+
+            val tags = reader.readTags()
+
+            return when (variant) {
+{{range $index, $field := .Fields}}                {{variant $index}} -> {{title .Name}}({{readType .Type}})
+{{end}}                else -> {
+                    reader.skip(tags)
+                    null
+                }
+            }
+        }
+
+        fun write(writer: {{rpcName "Writer"}}, value: {{$Union}}?) {
+            when (value) {
+                null -> writer.writeUByte(0u)
+{{range $index, $field := .Fields}}                is {{title .Name}} -> {
+                    writer.writeUByte({{variant $index}}u)
+                    writer.writeTags({{tagValue .Type}})
+                    {{writeType .Type "value.value" 0}}
+                }
+{{end}}            }
+        }
+    }
+}
+{{end}}
+
+{{define "response"}}{{rpcName "Response"}}(id = call.id, exception = {{.}}, content = content, trace = call.trace){{end}}
+
+{{define "contract"}}{{$Contract := title .Name}}
+{{doc "" .}}interface {{$Contract}} {
+{{range .Methods}}{{doc "    " .}}    {{if not (isAsync .)}}suspend {{end}}fun {{memberName .Name}}{{params .Params}}{{returnType .}}
+{{end}}}
+
+/**
+ * {{$Contract}}Dispatcher dispatch the remote calls to {{$Contract}} service
+ */
+class {{$Contract}}Dispatcher(override val id: UShort, private val service: {{$Contract}}) : {{rpcName "Dispatcher"}} {
+    companion object {
+        const val NAME = "{{.FullName}}"
+    }
+
+    override suspend fun dispatch(call: {{rpcName "Request"}}): {{rpcName "Response"}}? {
+        when (call.method.toInt()) {
+{{range .Methods}}            {{.ID}} -> {
+                if (call.params.size != {{.ParamsCount}}) {
+                    throw IllegalArgumentException("{{$Contract}}#{{title .Name}} expect {{.ParamsCount}} params but got :${call.params.size}")
+                }
+{{range .Params}}
+                val arg{{.ID}} = {{rpcName "unmarshal"}}(call.params[{{.ID}}].content) { reader -> {{readType .Type}} }
+{{end}}
+{{if isAsync .}}
+                service.{{memberName .Name}}{{callArgs .Params}}
+
+                return null
+{{else if .Exceptions}}
+                try {
+                    {{if notVoid .Return}}val ret = {{end}}service.{{memberName .Name}}{{callArgs .Params}}
+
+                    val content = {{if notVoid .Return}}{{rpcName "marshal"}} { writer -> {{writeType .Return "ret" 0}} }{{else}}ByteArray(0){{end}}
+
+                    return {{template "response" "-1"}}
+                }{{range .Exceptions}} catch (err: {{typeName .Type}}) {
+                    val content = {{rpcName "marshal"}} { writer -> err.write(writer) }
+
+                    return {{template "response" (printf "%d" .ID)}}
+                }{{end}}
+{{else}}
+                {{if notVoid .Return}}val ret = {{end}}service.{{memberName .Name}}{{callArgs .Params}}
+
+                val content = {{if notVoid .Return}}{{rpcName "marshal"}} { writer -> {{writeType .Return "ret" 0}} }{{else}}ByteArray(0){{end}}
+
+                return {{template "response" "-1"}}
+{{end}}            }
+{{end}}        }
+
+        throw IllegalStateException("{{$Contract}}#${call.method} unknown method")
+    }
+}
+
+/**
+ * {{$Contract}}RPC the remote {{$Contract}} service proxy
+ */
+class {{$Contract}}RPC(private val channel: {{rpcName "Channel"}}, private val serviceID: UShort) : {{$Contract}} {
+    /**
+     * timeout the call timeout in milliseconds
+     */
+    var timeout: Long = 5000
+{{range .Methods}}
+    override {{if not (isAsync .)}}suspend {{end}}fun {{memberName .Name}}{{params .Params}}{{returnType .}} {
+        val call = {{rpcName "Request"}}(service = serviceID, method = {{.ID}}u, params = listOf(
+{{range .Params}}            {{rpcName "Param"}}(content = {{rpcName "marshal"}} { writer -> {{writeType .Type (memberName .Name) 0}} }),
+{{end}}        ))
+{{if isAsync .}}
+        channel.post(call)
+{{else}}
+        val callReturn = channel.call(call, timeout)
+
+        if (callReturn.exception.toInt() != -1) {
+            throw when (callReturn.exception.toInt()) {
+                -2 -> {{rpcName "unmarshal"}}(callReturn.content) { reader -> {{rpcName "InvalidArgumentException"}}.read(reader) }
+{{range .Exceptions}}                {{.ID}} -> {{rpcName "unmarshal"}}(callReturn.content) { reader -> {{readType .Type}} }
+{{end}}                else -> {{rpcName "RemoteException"}}()
+            }
+        }
+{{if notVoid .Return}}
+        return {{rpcName "unmarshal"}}(callReturn.content) { reader -> {{readType .Return}} }
+{{end}}{{end}}    }
+{{end}}}
+{{end}}
+
+{{define "runtime"}}// generate by gs2kotlin,don't modify it manually
+//
+// gsrpc runtime: the ByteArray Reader/Writer and the suspending Channel over Transport,
+// the channel runs the dispatched calls in the kotlinx.coroutines scope
+
+package com.gsrpc
+
+import java.util.concurrent.ConcurrentHashMap
+import java.util.concurrent.atomic.AtomicInteger
+import kotlinx.coroutines.CancellationException
+import kotlinx.coroutines.CompletableDeferred
+import kotlinx.coroutines.CoroutineScope
+import kotlinx.coroutines.launch
+import kotlinx.coroutines.withTimeout
+
+/**
+ * CodecException the invalid encoded content exception
+ */
+class CodecException(message: String) : Exception(message)
+
+private fun checkSize(expect: Int, got: Int) {
+    if (expect != got) throw CodecException("gsrpc: expect array size $expect but got $got")
+}
+
+/**
+ * Reader read gsrpc values from the input buffer
+ */
+class Reader(private val buff: ByteArray) {
+    private var offset = 0
+
+    private fun next(length: Int): Int {
+        if (length > buff.size - offset) throw CodecException("gsrpc: read beyond the end of buffer")
+
+        val current = offset
+
+        offset += length
+
+        return current
+    }
+
+    private fun readInteger(size: Int): Long {
+        val index = next(size)
+
+        var value = 0L
+
+        for (i in 0 until size) {
+            value = value or ((buff[index + i].toLong() and 0xff) shl (8 * i))
+        }
+
+        return value
+    }
+
+    fun readByte(): Byte = buff[next(1)]
+
+    fun readUByte(): UByte = readByte().toUByte()
+
+    fun readBoolean(): Boolean = readByte() != 0.toByte()
+
+    fun readShort(): Short = readInteger(2).toShort()
+
+    fun readUShort(): UShort = readInteger(2).toUShort()
+
+    fun readInt(): Int = readInteger(4).toInt()
+
+    fun readUInt(): UInt = readInteger(4).toUInt()
+
+    fun readLong(): Long = readInteger(8)
+
+    fun readULong(): ULong = readInteger(8).toULong()
+
+    fun readFloat(): Float = Float.fromBits(readInt())
+
+    fun readDouble(): Double = Double.fromBits(readLong())
+
+    fun readBytes(): ByteArray {
+        val length = readUShort().toInt()
+
+        val index = next(length)
+
+        return buff.copyOfRange(index, index + length)
+    }
+
+    fun readFixedBytes(size: Int): ByteArray {
+        val value = readBytes()
+
+        checkSize(size, value.size)
+
+        return value
+    }
+
+    fun readString(): String = readBytes().toString(Charsets.UTF_8)
+
+    fun <T> readList(read: () -> T): List<T> = List(readUShort().toInt()) { read() }
+
+    fun <T> readArray(size: Int, read: () -> T): List<T> {
+        val value = readList(read)
+
+        checkSize(size, value.size)
+
+        return value
+    }
+
+    fun <K, V> readMap(readKey: () -> K, readValue: () -> V): Map<K, V> {
+        val length = readUShort().toInt()
+
+        val value = LinkedHashMap<K, V>(length)
+
+        repeat(length) {
+            val key = readKey()
+
+            value[key] = readValue()
+        }
+
+        return value
+    }
+
+    /**
+     * readTags read the tag sequence of tagged value, the nested container and POD tags are followed by their component tags
+     */
+    fun readTags(): List<UByte> {
+        val tags = mutableListOf<UByte>()
+
+        readTags(tags)
+
+        return tags
+    }
+
+    private fun readTags(tags: MutableList<UByte>) {
+        val tag = readUByte()
+
+        tags.add(tag)
+
+        val nested = when (tag) {
+{{range wireNested}}            {{range $j, $tag := .Tags}}{{if $j}}, {{end}}Tag.{{$tag}}.value{{end}} -> {{if lt .Nested 0}}readUByte().also { tags.add(it) }.toInt(){{else}}{{.Nested}}{{end}}
+{{end}}            else -> 0
+        }
+
+        repeat(nested) {
+            readTags(tags)
+        }
+    }
+
+    /**
+     * skip skip the value described by the tag sequence
+     */
+    fun skip(tags: List<UByte>) = skip(tags, 0)
+
+    /**
+     * skipFields skip the unknown fields of tagged table
+     */
+    fun skipFields(fields: Int) {
+        repeat(fields) {
+            skip(readTags())
+        }
+    }
+
+    // skipComponents skip the values of count tag sequences begin with index
+    private fun skipComponents(tags: List<UByte>, index: Int, count: Int) {
+        var next = index
+
+        repeat(count) {
+            skip(tags, next)
+            next = tagsEnd(tags, next)
+        }
+    }
+
+    private fun skip(tags: List<UByte>, index: Int) {
+        when (tags[index]) {
+{{range wireCases}}            {{range $j, $tag := .Tags}}{{if $j}}, {{end}}Tag.{{$tag}}.value{{end}} -> {{if eq .Kind "none"}}{}
+{{else if eq .Kind "fixed"}}next({{.Size}})
+{{else if eq .Kind "sized"}}next(readUShort().toInt())
+{{else if eq .Kind "fields"}}skipFields(readUShort().toInt())
+{{else if eq .Kind "seq"}}repeat(readUShort().toInt()) {
+                skipComponents(tags, index + 1, {{.Nested}})
+            }
+{{else if eq .Kind "record"}}skipComponents(tags, index + 2, tags[index + 1].toInt())
+{{else if eq .Kind "optional"}}if (readBoolean()) skip(tags, index + 1)
+{{else if eq .Kind "variant"}}if (readUByte().toInt() != 0) skip(readTags())
+{{end}}{{end}}            else -> throw CodecException("gsrpc: unknown tag ${tags[index]}")
+        }
+    }
+
+    // tagsEnd get the index next to the tag sequence begin with index
+    private fun tagsEnd(tags: List<UByte>, index: Int): Int {
+        var next = index + 1
+
+        val nested = when (tags[index]) {
+{{range wireNested}}            {{range $j, $tag := .Tags}}{{if $j}}, {{end}}Tag.{{$tag}}.value{{end}} -> {{if lt .Nested 0}}tags[index + 1].toInt().also { next = index + 2 }{{else}}{{.Nested}}{{end}}
+{{end}}            else -> 0
+        }
+
+        repeat(nested) {
+            next = tagsEnd(tags, next)
+        }
+
+        return next
+    }
+}
+
+/**
+ * Writer write gsrpc values into the growable output buffer
+ */
+class Writer {
+    private var buff = ByteArray(64)
+
+    private var length = 0
+
+    /**
+     * content the written bytes
+     */
+    val content: ByteArray
+        get() = buff.copyOf(length)
+
+    private fun grow(size: Int) {
+        if (length + size > buff.size) {
+            buff = buff.copyOf(maxOf(buff.size * 2, length + size))
+        }
+    }
+
+    private fun writeInteger(value: Long, size: Int) {
+        grow(size)
+
+        for (i in 0 until size) {
+            buff[length++] = (value shr (8 * i)).toByte()
+        }
+    }
+
+    private fun writeLength(length: Int) {
+        if (length > 0xffff) throw CodecException("gsrpc: length $length out of uint16 range")
+
+        writeInteger(length.toLong(), 2)
+    }
+
+    fun writeByte(value: Byte) {
+        grow(1)
+
+        buff[length++] = value
+    }
+
+    fun writeUByte(value: UByte) = writeByte(value.toByte())
+
+    fun writeBoolean(value: Boolean) = writeByte((if (value) 1 else 0).toByte())
+
+    fun writeShort(value: Short) = writeInteger(value.toLong(), 2)
+
+    fun writeUShort(value: UShort) = writeInteger(value.toLong(), 2)
+
+    fun writeInt(value: Int) = writeInteger(value.toLong(), 4)
+
+    fun writeUInt(value: UInt) = writeInteger(value.toLong(), 4)
+
+    fun writeLong(value: Long) = writeInteger(value, 8)
+
+    fun writeULong(value: ULong) = writeInteger(value.toLong(), 8)
+
+    fun writeFloat(value: Float) = writeInt(value.toRawBits())
+
+    fun writeDouble(value: Double) = writeLong(value.toRawBits())
+
+    fun writeBytes(value: ByteArray) {
+        writeLength(value.size)
+
+        grow(value.size)
+
+        value.copyInto(buff, length)
+
+        length += value.size
+    }
+
+    fun writeFixedBytes(size: Int, value: ByteArray) {
+        checkSize(size, value.size)
+
+        writeBytes(value)
+    }
+
+    fun writeString(value: String) = writeBytes(value.toByteArray(Charsets.UTF_8))
+
+    fun writeTags(vararg tags: UByte) {
+        for (tag in tags) {
+            writeUByte(tag)
+        }
+    }
+
+    fun <T> writeList(value: List<T>, write: (T) -> Unit) {
+        writeLength(value.size)
+
+        for (v in value) {
+            write(v)
+        }
+    }
+
+    fun <T> writeArray(size: Int, value: List<T>, write: (T) -> Unit) {
+        checkSize(size, value.size)
+
+        writeList(value, write)
+    }
+
+    /**
+     * writeMap write map entries in key order, so the same map is always encoded as same bytes
+     */
+    fun <K, V> writeMap(value: Map<K, V>, order: Comparator<in K>, writeKey: (K) -> Unit, writeValue: (V) -> Unit) {
+        writeLength(value.size)
+
+        for (key in value.keys.sortedWith(order)) {
+            writeKey(key)
+            writeValue(value.getValue(key))
+        }
+    }
+}
+
+/**
+ * utf8Order compare strings in utf8 bytes order, which is the same as the go server
+ */
+val utf8Order: Comparator<String> = Comparator { a, b ->
+    val x = a.toByteArray(Charsets.UTF_8)
+
+    val y = b.toByteArray(Charsets.UTF_8)
+
+    for (i in 0 until minOf(x.size, y.size)) {
+        val diff = (x[i].toInt() and 0xff) - (y[i].toInt() and 0xff)
+
+        if (diff != 0) return@Comparator diff
+    }
+
+    x.size - y.size
+}
+
+/**
+ * marshal encode value by the write lambda
+ */
+fun marshal(write: (Writer) -> Unit): ByteArray {
+    val writer = Writer()
+
+    write(writer)
+
+    return writer.content
+}
+
+/**
+ * unmarshal decode value from content by the read lambda
+ */
+fun <T> unmarshal(content: ByteArray, read: (Reader) -> T): T = read(Reader(content))
+
+/**
+ * Transport the message transport of channel, one message per send and onReceived callback,
+ * the callbacks are set by channel and may be invoked from any thread
+ */
+interface Transport {
+    var onReceived: ((ByteArray) -> Unit)?
+
+    var onClosed: ((Throwable?) -> Unit)?
+
+    fun send(data: ByteArray)
+
+    fun close()
+}
+
+/**
+ * Dispatcher the service dispatcher registered into channel by service id
+ */
+interface Dispatcher {
+    val id: UShort
+
+    suspend fun dispatch(call: Request): Response?
+}
+
+/**
+ * Channel the rpc channel which send requests to and dispatch requests from the peer,
+ * the received requests are dispatched in the scope
+ */
+class Channel(private val transport: Transport, private val scope: CoroutineScope) {
+    private val seq = AtomicInteger()
+
+    private val pending = ConcurrentHashMap<UInt, CompletableDeferred<Response>>()
+
+    private val dispatchers = ConcurrentHashMap<UShort, Dispatcher>()
+
+    /**
+     * onError invoked when the dispatcher throw unhandled exception or the received message is invalid
+     */
+    var onError: ((Throwable) -> Unit)? = null
+
+    init {
+        transport.onReceived = { data -> received(data) }
+
+        transport.onClosed = { reason -> closed(reason) }
+    }
+
+    fun register(dispatcher: Dispatcher) {
+        dispatchers[dispatcher.id] = dispatcher
+    }
+
+    fun unregister(id: UShort) {
+        dispatchers.remove(id)
+    }
+
+    /**
+     * call send the request and wait the response, TimeoutCancellationException is thrown after timeout milliseconds
+     */
+    suspend fun call(call: Request, timeout: Long): Response {
+        val request = call.copy(id = seq.incrementAndGet().toUInt())
+
+        val promise = CompletableDeferred<Response>()
+
+        pending[request.id] = promise
+
+        try {
+            send(Code.Request, marshal { writer -> request.write(writer) })
+
+            return withTimeout(timeout) { promise.await() }
+        } finally {
+            pending.remove(request.id)
+        }
+    }
+
+    /**
+     * post send the request without waiting response
+     */
+    fun post(call: Request) {
+        send(Code.Request, marshal { writer -> call.write(writer) })
+    }
+
+    fun close() {
+        transport.close()
+    }
+
+    private fun send(code: Code, content: ByteArray) {
+        val message = Message(code = code, agent = 0u, content = content)
+
+        transport.send(marshal { writer -> message.write(writer) })
+    }
+
+    private fun received(data: ByteArray) {
+        try {
+            val message = unmarshal(data) { reader -> Message.read(reader) }
+
+            when (message.code) {
+                Code.Response -> {
+                    val callReturn = unmarshal(message.content) { reader -> Response.read(reader) }
+
+                    pending.remove(callReturn.id)?.complete(callReturn)
+                }
+                Code.Request -> {
+                    val call = unmarshal(message.content) { reader -> Request.read(reader) }
+
+                    scope.launch { dispatch(call) }
+                }
+                Code.Heartbeat -> send(Code.Heartbeat, ByteArray(0))
+                else -> {}
+            }
+        } catch (e: Exception) {
+            onError?.invoke(e)
+        }
+    }
+
+    private suspend fun dispatch(call: Request) {
+        val dispatcher = dispatchers[call.service] ?: return
+
+        try {
+            val callReturn = dispatcher.dispatch(call) ?: return
+
+            send(Code.Response, marshal { writer -> callReturn.write(writer) })
+        } catch (e: CancellationException) {
+            throw e
+        } catch (e: Exception) {
+            onError?.invoke(e)
+        }
+    }
+
+    private fun closed(reason: Throwable?) {
+        val error = reason ?: CancellationException("gsrpc: channel closed")
+
+        for (id in pending.keys) {
+            pending.remove(id)?.completeExceptionally(error)
+        }
+    }
+}
+{{end}}
+`
@@ -0,0 +1,687 @@
+package gen4lua
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/gsdocker/gserrors"
+	"github.com/gsdocker/gslogger"
+	"github.com/gsrpc/gslang"
+	"github.com/gsrpc/gslang/ast"
+	"github.com/gsrpc/gslang/lexer"
+	"github.com/gsrpc/gsrpc/gen"
+	"github.com/gsrpc/gsrpc/wire"
+)
+
+var readMapping = map[lexer.TokenType]string{
+	lexer.KeySByte:   "reader:read_sbyte()",
+	lexer.KeyByte:    "reader:read_byte()",
+	lexer.KeyInt16:   "reader:read_int16()",
+	lexer.KeyUInt16:  "reader:read_uint16()",
+	lexer.KeyInt32:   "reader:read_int32()",
+	lexer.KeyUInt32:  "reader:read_uint32()",
+	lexer.KeyInt64:   "reader:read_int64()",
+	lexer.KeyUInt64:  "reader:read_uint64()",
+	lexer.KeyFloat32: "reader:read_float32()",
+	lexer.KeyFloat64: "reader:read_float64()",
+	lexer.KeyBool:    "reader:read_bool()",
+	lexer.KeyString:  "reader:read_string()",
+}
+
+var writeMapping = map[lexer.TokenType]string{
+	lexer.KeySByte:   "writer:write_sbyte",
+	lexer.KeyByte:    "writer:write_byte",
+	lexer.KeyInt16:   "writer:write_int16",
+	lexer.KeyUInt16:  "writer:write_uint16",
+	lexer.KeyInt32:   "writer:write_int32",
+	lexer.KeyUInt32:  "writer:write_uint32",
+	lexer.KeyInt64:   "writer:write_int64",
+	lexer.KeyUInt64:  "writer:write_uint64",
+	lexer.KeyFloat32: "writer:write_float32",
+	lexer.KeyFloat64: "writer:write_float64",
+	lexer.KeyBool:    "writer:write_bool",
+	lexer.KeyString:  "writer:write_string",
+}
+
+var defaultval = map[lexer.TokenType]string{
+	lexer.KeySByte:   "0",
+	lexer.KeyByte:    "0",
+	lexer.KeyInt16:   "0",
+	lexer.KeyUInt16:  "0",
+	lexer.KeyInt32:   "0",
+	lexer.KeyUInt32:  "0",
+	lexer.KeyInt64:   "0",
+	lexer.KeyUInt64:  "0",
+	lexer.KeyFloat32: "0.0",
+	lexer.KeyFloat64: "0.0",
+	lexer.KeyBool:    "false",
+	lexer.KeyString:  "\"\"",
+}
+
+// keywords the lua reserved words, which are escaped with _ suffix when used as names
+var keywords = map[string]bool{
+	"and": true, "break": true, "do": true, "else": true, "elseif": true, "end": true, "false": true,
+	"for": true, "function": true, "goto": true, "if": true, "in": true, "local": true, "nil": true,
+	"not": true, "or": true, "repeat": true, "return": true, "then": true, "true": true, "until": true,
+	"while": true, "self": true,
+}
+
+// runtimeModule the runtime module name, which is generated in the root path
+const runtimeModule = "gsrpc"
+
+// _Module the generated module of one gslang package, scripts of the same package share the module
+type _Module struct {
+	imports map[string]string // require alias -> package name
+	content bytes.Buffer      // module content
+}
+
+type _CodeGen struct {
+	gslogger.Log                     // Log APIs
+	rootpath     string              // root path
+	files        map[string][]byte   // generated files sink, the files are written into rootpath if nil
+	script       *ast.Script         // current script
+	module       *_Module            // current package module
+	modules      map[string]*_Module // generated package modules
+	tpl          *template.Template  // code generate template
+	skips        []*regexp.Regexp    // skip lists
+	compiler     *gslang.Compiler    // current compiler
+	runtime      bool                // runtime module is generated
+}
+
+// NewCodeGen .
+func NewCodeGen(rootpath string, skips []string) (gslang.Visitor, error) {
+
+	codeGen := &_CodeGen{
+		Log:      gslogger.Get("gen4lua"),
+		rootpath: rootpath,
+		modules:  make(map[string]*_Module),
+	}
+
+	for _, skip := range skips {
+		exp, err := regexp.Compile(skip)
+
+		if err != nil {
+			return nil, gserrors.Newf(err, "invalid skip regex string :%s", skip)
+		}
+
+		codeGen.skips = append(codeGen.skips, exp)
+	}
+
+	funcs := template.FuncMap{
+		"title":       strings.Title,
+		"tableName":   gen.TableName,
+		"fieldName":   fieldName,
+		"comment":     comment,
+		"notVoid":     gslang.NotVoid,
+		"isPOD":       gslang.IsPOD,
+		"isAsync":     gslang.IsAsync,
+		"isException": gslang.IsException,
+		"isStrict":    gen.IsStrict,
+		"isOptional":  gen.IsOptional,
+		"isOneOf":     gen.IsOneOf,
+		"variant":     gen.Variant,
+		"arrayIndex":  arrayIndex,
+		"qualify":     codeGen.qualify,
+		"typeName":    codeGen.typeName,
+		"readType":    codeGen.readType,
+		"writeType":   codeGen.writeType,
+		"tagValue":    codeGen.tagValue,
+		"wireCases":   wire.Cases,
+		"wireNested":  wire.Nested,
+		"rpcName":     codeGen.rpcName,
+		"fieldInit":   codeGen.fieldInit,
+		"params":      params,
+		"callArgs":    callArgs,
+		"runtime":     func() string { return runtimeModule },
+	}
+
+	tpl, err := template.New("t4lua").Funcs(funcs).Parse(t4lua)
+
+	if err != nil {
+		return nil, err
+	}
+
+	codeGen.tpl = tpl
+
+	return codeGen, nil
+}
+
+// NewCodeGenWithFiles create codegen which write generated files into files map keyed by slash separated relative path
+func NewCodeGenWithFiles(files map[string][]byte, skips []string) (gslang.Visitor, error) {
+
+	codeGen, err := NewCodeGen("", skips)
+
+	if err != nil {
+		return nil, err
+	}
+
+	codeGen.(*_CodeGen).files = files
+
+	return codeGen, nil
+}
+
+// fieldName get the snake case field/method/param name, lua keyword is escaped with _ suffix
+func fieldName(name string) string {
+
+	name = gen.SnakeName(name)
+
+	if keywords[name] {
+		return name + "_"
+	}
+
+	return name
+}
+
+// moduleAlias get the require alias of package module
+func moduleAlias(packageName string) string {
+	return strings.Replace(packageName, ".", "_", -1)
+}
+
+// modulePath get the package module file path relative to root path, which is found by require through the ?/init.lua path
+func modulePath(packageName string) string {
+	return filepath.Join(strings.Replace(packageName, ".", "/", -1), "init.lua")
+}
+
+// qualify get the symbol reference name, the symbols are fields of the package module table,
+// the symbols of other packages are referenced by the required module alias
+func (codegen *_CodeGen) qualify(packageName string, name string) string {
+
+	if packageName == codegen.script.Package {
+		return "M." + name
+	}
+
+	alias := moduleAlias(packageName)
+
+	codegen.module.imports[alias] = packageName
+
+	return alias + "." + name
+}
+
+// rpcName get the reference name of com.gsrpc package symbol
+func (codegen *_CodeGen) rpcName(name string) string {
+	return codegen.qualify("com.gsrpc", name)
+}
+
+func (codegen *_CodeGen) tagValue(typeDecl ast.Type) string {
+	return strings.Join(codegen.tags(typeDecl), ", ")
+}
+
+// tags get the type tag sequence by the shared tag scheme
+func (codegen *_CodeGen) tags(typeDecl ast.Type) []string {
+
+	tag := codegen.rpcName("Tag")
+
+	return wire.Format(wire.Tags(typeDecl), func(t wire.Tag) string {
+		return tag + "." + t.String()
+	})
+}
+
+// arrayIndex get the lua array index of the zero based id
+func arrayIndex(id int) int {
+	return id + 1
+}
+
+func (codegen *_CodeGen) defaultVal(typeDecl ast.Type) string {
+	switch typeDecl.(type) {
+	case *ast.BuiltinType:
+		builtinType := typeDecl.(*ast.BuiltinType)
+
+		return defaultval[builtinType.Type]
+	case *ast.TypeRef:
+		return codegen.defaultVal(typeDecl.(*ast.TypeRef).Ref)
+
+	case *ast.Enum:
+		enum := typeDecl.(*ast.Enum)
+
+		return codegen.qualify(enum.Package(), strings.Title(enum.Name())) + "." + strings.Title(enum.Constants[0].Name())
+
+	case *ast.Table:
+		return codegen.qualify(typeDecl.Package(), gen.TableName(typeDecl)) + ".new()"
+
+	case *ast.Seq:
+		seq := typeDecl.(*ast.Seq)
+
+		if gen.IsBytes(seq) {
+			if seq.Size != -1 {
+				return fmt.Sprintf("string.rep(\"\\0\", %d)", seq.Size)
+			}
+
+			return "\"\""
+		}
+
+		if seq.Size != -1 {
+			return fmt.Sprintf("%s.array(%d, function() return %s end)", runtimeModule, seq.Size, codegen.defaultVal(seq.Component))
+		}
+
+		return "{}"
+	}
+
+	gserrors.Panicf(nil, "defaultVal  error: unsupport type(%s)", typeDecl)
+
+	return "unknown"
+}
+
+// fieldInit get the field initializer of table constructor, optional field without default value is nil
+func (codegen *_CodeGen) fieldInit(field *ast.Field) string {
+
+	expr, ok := gen.Default(field)
+
+	if !ok {
+		if gen.IsOptional(field) {
+			return ""
+		}
+
+		return codegen.defaultVal(field.Type)
+	}
+
+	start, _ := gslang.Pos(field)
+
+	return codegen.defaultExpr(expr, field.Type, start)
+}
+
+func (codegen *_CodeGen) defaultExpr(expr ast.Expr, typeDecl ast.Type, start lexer.Position) string {
+
+	eval := codegen.compiler.Eval()
+
+	switch typeDecl.(type) {
+	case *ast.BuiltinType:
+		builtinType := typeDecl.(*ast.BuiltinType)
+
+		gen.CheckUnsigned(eval, builtinType, expr, start)
+
+		switch builtinType.Type {
+		case lexer.KeyString:
+			return quote(eval.EvalString(expr))
+		case lexer.KeyBool:
+			return strconv.FormatBool(eval.EvalBool(expr))
+		case lexer.KeyFloat32, lexer.KeyFloat64:
+			val := eval.EvalFloat(expr)
+
+			switch {
+			case math.IsNaN(val):
+				return "(0 / 0)"
+			case math.IsInf(val, 1):
+				return "math.huge"
+			case math.IsInf(val, -1):
+				return "-math.huge"
+			}
+
+			literal := strconv.FormatFloat(val, 'g', -1, 64)
+
+			if !strings.ContainsAny(literal, ".e") {
+				literal += ".0"
+			}
+
+			return literal
+		case lexer.KeyVoid:
+		default:
+			return fmt.Sprintf("%d", eval.EvalInt(expr))
+		}
+
+	case *ast.TypeRef:
+		return codegen.defaultExpr(expr, typeDecl.(*ast.TypeRef).Ref, start)
+
+	case *ast.Enum:
+		enum := typeDecl.(*ast.Enum)
+
+		val := eval.EvalInt(expr)
+
+		if constant, ok := gen.Constant(enum, val); ok {
+			return codegen.qualify(enum.Package(), strings.Title(enum.Name())) + "." + strings.Title(constant.Name())
+		}
+
+		gserrors.Panicf(nil, "enum %s constant(%d) not found :%v", enum, val, start)
+
+	case *ast.Table:
+		table := typeDecl.(*ast.Table)
+
+		newObj, ok := expr.(*ast.NewObj)
+
+		if !ok || gen.IsOneOf(table) {
+			break
+		}
+
+		// the fields without arg keep the constructor defaults
+		var args []string
+
+		for i, field := range table.Fields {
+
+			arg, ok := gen.FieldArg(newObj, i, field)
+
+			if ok {
+				args = append(args, fmt.Sprintf("%s = %s", fieldName(field.Name()), codegen.defaultExpr(arg, field.Type, start)))
+			}
+		}
+
+		return fmt.Sprintf("%s.new({ %s })", codegen.qualify(table.Package(), gen.TableName(table)), strings.Join(args, ", "))
+	}
+
+	gserrors.Panicf(nil, "unsupport default value for type(%s) :%v", typeDecl, start)
+
+	return "unknown"
+}
+
+// quote get the lua string literal, the bytes out of printable ascii are escaped with 3 digits decimal escape
+func quote(val string) string {
+
+	var buff bytes.Buffer
+
+	buff.WriteString("\"")
+
+	for _, b := range []byte(val) {
+		switch {
+		case b == '"':
+			buff.WriteString("\\\"")
+		case b == '\\':
+			buff.WriteString("\\\\")
+		case b == '\n':
+			buff.WriteString("\\n")
+		case b == '\r':
+			buff.WriteString("\\r")
+		case b == '\t':
+			buff.WriteString("\\t")
+		case b < 0x20 || b >= 0x7f:
+			buff.WriteString(fmt.Sprintf("\\%03d", b))
+		default:
+			buff.WriteByte(b)
+		}
+	}
+
+	buff.WriteString("\"")
+
+	return buff.String()
+}
+
+// typeName get the class table reference name of table type
+func (codegen *_CodeGen) typeName(typeDecl ast.Type) string {
+
+	if typeRef, ok := typeDecl.(*ast.TypeRef); ok {
+		return codegen.typeName(typeRef.Ref)
+	}
+
+	if _, ok := typeDecl.(*ast.Table); !ok {
+		gserrors.Panicf(nil, "typeName  error: unsupport type(%s)", typeDecl)
+	}
+
+	return codegen.qualify(typeDecl.Package(), gen.TableName(typeDecl))
+}
+
+// readType get the expr which read the type value from the reader variable
+func (codegen *_CodeGen) readType(typeDecl ast.Type) string {
+	switch typeDecl.(type) {
+	case *ast.BuiltinType:
+		builtinType := typeDecl.(*ast.BuiltinType)
+
+		return readMapping[builtinType.Type]
+	case *ast.TypeRef:
+		return codegen.readType(typeDecl.(*ast.TypeRef).Ref)
+
+	case *ast.Enum:
+		enum := typeDecl.(*ast.Enum)
+
+		read := "reader:read_byte()"
+
+		if gslang.EnumSize(enum) == 4 {
+			read = "reader:read_uint32()"
+		}
+
+		if gen.IsStrict(enum) {
+			return fmt.Sprintf("%s.check_enum(%s, %s, \"%s\")", runtimeModule, codegen.qualify(enum.Package(), strings.Title(enum.Name())), read, enum.FullName())
+		}
+
+		return read
+
+	case *ast.Table:
+		return codegen.qualify(typeDecl.Package(), gen.TableName(typeDecl)) + ".read(reader)"
+
+	case *ast.Seq:
+		seq := typeDecl.(*ast.Seq)
+
+		if gen.IsBytes(seq) {
+			if seq.Size != -1 {
+				return fmt.Sprintf("reader:read_fixed_bytes(%d)", seq.Size)
+			}
+
+			return "reader:read_bytes()"
+		}
+
+		if entry, ok := wire.MapEntry(seq); ok {
+			return fmt.Sprintf("reader:read_map(function() return %s end, function() return %s end)", codegen.readType(entry.Fields[0].Type), codegen.readType(entry.Fields[1].Type))
+		}
+
+		if seq.Size != -1 {
+			return fmt.Sprintf("reader:read_array(%d, function() return %s end)", seq.Size, codegen.readType(seq.Component))
+		}
+
+		return fmt.Sprintf("reader:read_list(function() return %s end)", codegen.readType(seq.Component))
+	}
+
+	gserrors.Panicf(nil, "readType  error: unsupport type(%s)", typeDecl)
+
+	return "unknown"
+}
+
+// writeType get the statement which write the valname to the writer variable, depth is used to name the function args
+func (codegen *_CodeGen) writeType(typeDecl ast.Type, valname string, depth int) string {
+	switch typeDecl.(type) {
+	case *ast.BuiltinType:
+		builtinType := typeDecl.(*ast.BuiltinType)
+
+		return fmt.Sprintf("%s(%s)", writeMapping[builtinType.Type], valname)
+	case *ast.TypeRef:
+		return codegen.writeType(typeDecl.(*ast.TypeRef).Ref, valname, depth)
+
+	case *ast.Enum:
+		if gslang.EnumSize(typeDecl) == 4 {
+			return fmt.Sprintf("writer:write_uint32(%s)", valname)
+		}
+
+		return fmt.Sprintf("writer:write_byte(%s)", valname)
+
+	case *ast.Table:
+		return fmt.Sprintf("%s.write(writer, %s)", codegen.qualify(typeDecl.Package(), gen.TableName(typeDecl)), valname)
+
+	case *ast.Seq:
+		seq := typeDecl.(*ast.Seq)
+
+		if gen.IsBytes(seq) {
+			if seq.Size != -1 {
+				return fmt.Sprintf("writer:write_fixed_bytes(%d, %s)", seq.Size, valname)
+			}
+
+			return fmt.Sprintf("writer:write_bytes(%s)", valname)
+		}
+
+		key, val := fmt.Sprintf("k%d", depth), fmt.Sprintf("v%d", depth)
+
+		if entry, ok := wire.MapEntry(seq); ok {
+			return fmt.Sprintf("writer:write_map(%s, function(%s) %s end, function(%s) %s end)", valname,
+				key, codegen.writeType(entry.Fields[0].Type, key, depth+1),
+				val, codegen.writeType(entry.Fields[1].Type, val, depth+1))
+		}
+
+		if seq.Size != -1 {
+			return fmt.Sprintf("writer:write_array(%d, %s, function(%s) %s end)", seq.Size, valname, val, codegen.writeType(seq.Component, val, depth+1))
+		}
+
+		return fmt.Sprintf("writer:write_list(%s, function(%s) %s end)", valname, val, codegen.writeType(seq.Component, val, depth+1))
+	}
+
+	gserrors.Panicf(nil, "writeType  error: unsupport type(%s)", typeDecl)
+
+	return "unknown"
+}
+
+func params(params []*ast.Param) string {
+
+	var args []string
+
+	for _, param := range params {
+		args = append(args, fieldName(param.Name()))
+	}
+
+	return "(" + strings.Join(args, ", ") + ")"
+}
+
+// callArgs get the args of service method call, the args are unmarshalled into argN locals
+func callArgs(params []*ast.Param) string {
+
+	var args []string
+
+	for _, param := range params {
+		args = append(args, fmt.Sprintf("arg%d", param.ID))
+	}
+
+	return "(" + strings.Join(args, ", ") + ")"
+}
+
+func (codegen *_CodeGen) execute(name string, data interface{}) {
+	if err := codegen.tpl.ExecuteTemplate(&codegen.module.content, name, data); err != nil {
+		gserrors.Panicf(err, "exec template(%s) for %s error", name, data)
+	}
+}
+
+func (codegen *_CodeGen) writeFile(name string, content []byte) {
+
+	// the content buffer may be reused by caller
+	if codegen.files != nil {
+		codegen.files[filepath.ToSlash(name)] = append([]byte(nil), content...)
+		return
+	}
+
+	fullpath := filepath.Join(codegen.rootpath, name)
+
+	if err := os.MkdirAll(filepath.Dir(fullpath), 0755); err != nil {
+		gserrors.Panicf(err, "create output directory error")
+	}
+
+	codegen.D("write file :%s", fullpath)
+
+	if err := ioutil.WriteFile(fullpath, content, 0644); err != nil {
+		gserrors.Panicf(err, "write generate stub code error")
+	}
+}
+
+func (codegen *_CodeGen) BeginScript(compiler *gslang.Compiler, script *ast.Script) bool {
+
+	scriptPath := filepath.ToSlash(filepath.Clean(script.Name()))
+
+	for _, skip := range codegen.skips {
+
+		if skip.MatchString(scriptPath) {
+
+			return false
+		}
+	}
+
+	if strings.HasPrefix(script.Package, "gslang.") {
+		return false
+	}
+
+	codegen.script = script
+
+	codegen.compiler = compiler
+
+	module, ok := codegen.modules[script.Package]
+
+	if !ok {
+		module = &_Module{imports: make(map[string]string)}
+
+		codegen.modules[script.Package] = module
+	}
+
+	codegen.module = module
+
+	return true
+}
+
+func (codegen *_CodeGen) Using(compiler *gslang.Compiler, using *ast.Using) {
+}
+
+func (codegen *_CodeGen) Table(compiler *gslang.Compiler, tableType *ast.Table) {
+
+	if gen.IsOneOf(tableType) {
+		codegen.execute("union", tableType)
+	} else {
+		codegen.execute("table", tableType)
+	}
+}
+
+func (codegen *_CodeGen) Annotation(compiler *gslang.Compiler, annotation *ast.Table) {
+}
+
+func (codegen *_CodeGen) Enum(compiler *gslang.Compiler, enum *ast.Enum) {
+	codegen.execute("enum", enum)
+}
+
+func (codegen *_CodeGen) Contract(compiler *gslang.Compiler, contract *ast.Contract) {
+	codegen.execute("contract", contract)
+}
+
+// EndScript write the package module, which is rewritten with all the scripts of the package
+func (codegen *_CodeGen) EndScript(compiler *gslang.Compiler) {
+
+	var stream bytes.Buffer
+
+	stream.WriteString("-- generate by gs2lua,don't modify it manually\n\n")
+
+	// the module table is registered before the requires, so the packages can require each other
+	stream.WriteString(fmt.Sprintf("local M = {}\n\npackage.loaded[\"%s\"] = M\n\n", codegen.script.Package))
+
+	stream.WriteString(fmt.Sprintf("local %s = require(\"%s\")\n", runtimeModule, runtimeModule))
+
+	var aliases []string
+
+	for alias := range codegen.module.imports {
+		aliases = append(aliases, alias)
+	}
+
+	sort.Strings(aliases)
+
+	for _, alias := range aliases {
+		stream.WriteString(fmt.Sprintf("local %s = require(\"%s\")\n", alias, codegen.module.imports[alias]))
+	}
+
+	stream.Write(codegen.module.content.Bytes())
+
+	stream.WriteString("\nreturn M\n")
+
+	codegen.writeFile(modulePath(codegen.script.Package), tidy.Clean(stream.Bytes()))
+
+	if codegen.runtime {
+		return
+	}
+
+	stream.Reset()
+
+	if err := codegen.tpl.ExecuteTemplate(&stream, "runtime", "com.gsrpc"); err != nil {
+		gserrors.Panicf(err, "exec template(runtime) error")
+	}
+
+	codegen.writeFile(runtimeModule+".lua", stream.Bytes())
+
+	codegen.runtime = true
+}
+
+var tidy = gen.NewTidy(1, `\bthen|\bdo|\belse|\{|^function .*\)`, `(?:end|else|elseif|\})\b`)
+
+// comment get the -- comment lines from the .gs comments of node
+func comment(indent string, node ast.Node) string {
+
+	var buff bytes.Buffer
+
+	for _, line := range gslang.Comments(node) {
+		buff.WriteString(strings.TrimRight(indent+"-- "+strings.TrimSpace(line), " ") + "\n")
+	}
+
+	return buff.String()
+}
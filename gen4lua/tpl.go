@@ -0,0 +1,805 @@
+package gen4lua
+
+var t4lua = `
+{{define "enum"}}{{$Enum := title .Name}}
+
+{{comment "" .}}M.{{$Enum}} = {
+{{range .Constants}}{{comment "    " .}}    {{title .Name}} = {{.Value}},
+{{end}}}
+{{end}}
+
+{{define "table"}}{{$Table := printf "M.%s" (tableName .)}}
+
+{{comment "" .}}{{$Table}} = {__name = "{{.FullName}}"}
+
+{{$Table}}.__index = {{$Table}}
+{{if isException .}}
+function {{$Table}}.__tostring(val)
+    return val.__name
+end
+{{end}}
+function {{$Table}}.new(init)
+    local val = setmetatable({
+{{range .Fields}}{{$init := fieldInit .}}{{if $init}}{{comment "        " .}}        {{fieldName .Name}} = {{$init}},
+{{end}}{{end}}    }, {{$Table}})
+
+    if init ~= nil then
+        for k, v in pairs(init) do
+            val[k] = v
+        end
+    end
+
+    return val
+end
+{{if isPOD .}}
+function {{$Table}}.read(reader)
+    local val = {{$Table}}.new()
+{{range .Fields}}{{if isOptional .}}
+    if reader:read_bool() then
+        val.{{fieldName .Name}} = {{readType .Type}}
+    end
+{{else}}
+    val.{{fieldName .Name}} = {{readType .Type}}
+{{end}}{{end}}
+    return val
+end
+
+function {{$Table}}.write(writer, val){{range .Fields}}{{if isOptional .}}
+    writer:write_bool(val.{{fieldName .Name}} ~= nil)
+
+    if val.{{fieldName .Name}} ~= nil then
+        {{writeType .Type (printf "val.%s" (fieldName .Name)) 0}}
+    end
+{{else}}
+    {{writeType .Type (printf "val.%s" (fieldName .Name)) 0}}
+{{end}}{{end}}end
+{{else}}
+function {{$Table}}.read(reader)
+    local val = {{$Table}}.new()
+
+    local fields = reader:read_uint16()
+{{range .Fields}}
+    if fields == 0 then
+        return val
+    end
+
+    if reader:read_tags()[1] ~= {{rpcName "Tag"}}.Skip then
+        val.{{fieldName .Name}} = {{readType .Type}}
+    end
+
+    fields = fields - 1
+{{end}}
+    reader:skip_fields(fields)
+
+    return val
+end
+
+function {{$Table}}.write(writer, val)
+    writer:write_uint16({{len .Fields}})
+{{range .Fields}}{{if isOptional .}}
+    if val.{{fieldName .Name}} == nil then
+        writer:write_tags({{rpcName "Tag"}}.Skip)
+    else
+        writer:write_tags({{tagValue .Type}})
+        {{writeType .Type (printf "val.%s" (fieldName .Name)) 0}}
+    end
+{{else}}
+    writer:write_tags({{tagValue .Type}})
+    {{writeType .Type (printf "val.%s" (fieldName .Name)) 0}}
+{{end}}{{end}}end
+{{end}}{{end}}
+
+{{define "union"}}{{$Union := printf "M.%s" (tableName .)}}
+
+{{comment "" .}}{{$Union}} = {__name = "{{.FullName}}"}
+
+{{$Union}}.__index = {{$Union}}
+
+{{$Union}}.Kind = {
+    None = 0,
+{{range $index, $field := .Fields}}{{comment "    " .}}    {{title .Name}} = {{variant $index}},
+{{end}}}
+
+-- new create the union holding the value of the kind variant, the union holds nothing if kind is nil
+function {{$Union}}.new(kind, value)
+    return setmetatable({kind = kind or {{$Union}}.Kind.None, value = value}, {{$Union}})
+end
+
+-- read return the none union if none variant or unknown variant is read
+function {{$Union}}.read(reader)
+    local variant = reader:read_byte()
+
+    if variant == 0 then
+        return {{$Union}}.new()
+    end
+
+    local tags = reader:read_tags()
+{{range $index, $field := .Fields}}
+    if variant == {{variant $index}} then
+        return {{$Union}}.new({{$Union}}.Kind.{{title .Name}}, {{readType .Type}})
+    end
+{{end}}
+    reader:skip(tags)
+
+    return {{$Union}}.new()
+end
+
+function {{$Union}}.write(writer, val)
+    if val == nil then
+        writer:write_byte(0)
+{{range $index, $field := .Fields}}    elseif val.kind == {{$Union}}.Kind.{{title .Name}} then
+        writer:write_byte({{variant $index}})
+        writer:write_tags({{tagValue .Type}})
+        {{writeType .Type "val.value" 0}}
+{{end}}    else
+        writer:write_byte(0)
+    end
+end
+{{end}}
+
+{{define "contract"}}{{$Contract := title .Name}}{{$Dispatcher := printf "M.%sDispatcher" $Contract}}{{$RPC := printf "M.%sRPC" $Contract}}
+
+-- {{$Contract}}Dispatcher dispatch the remote calls to the {{$Contract}} service methods, which are invoked with service:method(...)
+{{$Dispatcher}} = {NAME = "{{.FullName}}"}
+
+{{$Dispatcher}}.__index = {{$Dispatcher}}
+
+function {{$Dispatcher}}.new(id, service)
+    return setmetatable({id = id, service = service}, {{$Dispatcher}})
+end
+
+function {{$Dispatcher}}:dispatch(call){{range .Methods}}
+    if call.method == {{.ID}} then
+        if #call.params ~= {{.ParamsCount}} then
+            error(string.format("{{$Contract}}#{{title .Name}} expect {{.ParamsCount}} params but got :%d", #call.params), 0)
+        end
+{{range .Params}}
+        local arg{{.ID}} = {{runtime}}.unmarshal(call.params[{{arrayIndex .ID}}].content, function(reader) return {{readType .Type}} end)
+{{end}}{{if isAsync .}}
+        self.service:{{fieldName .Name}}{{callArgs .Params}}
+
+        return nil
+{{else}}{{if .Exceptions}}
+        local ok, ret = pcall(self.service.{{fieldName .Name}}, self.service{{range .Params}}, arg{{.ID}}{{end}})
+
+        if not ok then{{range .Exceptions}}
+            if getmetatable(ret) == {{typeName .Type}} then
+                return {{runtime}}.response(call, {{.ID}}, {{runtime}}.marshal(function(writer) {{writeType .Type "ret" 0}} end))
+            end
+{{end}}
+            error(ret, 0)
+        end
+{{else}}
+        {{if notVoid .Return}}local ret = {{end}}self.service:{{fieldName .Name}}{{callArgs .Params}}
+{{end}}
+        return {{runtime}}.response(call, -1, {{if notVoid .Return}}{{runtime}}.marshal(function(writer) {{writeType .Return "ret" 0}} end){{else}}""{{end}})
+{{end}}    end
+{{end}}
+    error(string.format("unknown {{$Contract}}#%d method", call.method), 0)
+end
+
+-- {{$Contract}}RPC the remote {{$Contract}} service proxy, the methods except async ones must be invoked in coroutine
+{{$RPC}} = {NAME = "{{.FullName}}"}
+
+{{$RPC}}.__index = {{$RPC}}
+
+function {{$RPC}}.new(channel, service_id)
+    return setmetatable({channel = channel, service_id = service_id, timeout = 5}, {{$RPC}})
+end
+{{range .Methods}}
+{{comment "" .}}function {{$RPC}}:{{fieldName .Name}}{{params .Params}}
+    local call = {{rpcName "Request"}}.new({
+        service = self.service_id,
+        method = {{.ID}},
+        params = {{"{"}}{{range .Params}}
+            {{rpcName "Param"}}.new({content = {{runtime}}.marshal(function(writer) {{writeType .Type (fieldName .Name) 0}} end)}),{{end}}
+        },
+    })
+{{if isAsync .}}
+    self.channel:post(call)
+{{else}}
+    local call_return = self.channel:call(call, self.timeout)
+
+    if call_return.exception ~= -1 then
+        if call_return.exception == -2 then
+            error({{runtime}}.unmarshal(call_return.content, function(reader) return {{rpcName "InvalidArgumentException"}}.read(reader) end), 0)
+        end
+{{range .Exceptions}}
+        if call_return.exception == {{.ID}} then
+            error({{runtime}}.unmarshal(call_return.content, function(reader) return {{readType .Type}} end), 0)
+        end
+{{end}}
+        error({{rpcName "RemoteException"}}.new(), 0)
+    end
+{{if notVoid .Return}}
+    return {{runtime}}.unmarshal(call_return.content, function(reader) return {{readType .Return}} end)
+{{end}}{{end}}end
+{{end}}{{end}}
+
+{{define "runtime"}}-- generate by gs2lua,don't modify it manually
+--
+-- gsrpc runtime: the string.pack based Reader/Writer and the coroutine based Channel over transport, requires lua 5.3+
+
+local M = {}
+
+-- rpc get the {{.}} module, which is required lazily because it requires the runtime too
+local function rpc()
+    return require("{{.}}")
+end
+
+-- Reader read gsrpc values from the input string
+local Reader = {}
+
+Reader.__index = Reader
+
+M.Reader = Reader
+
+function Reader.new(buff)
+    return setmetatable({buff = buff, offset = 1}, Reader)
+end
+
+function Reader:next(length)
+    local offset = self.offset
+
+    if offset + length - 1 > #self.buff then
+        error("gsrpc: read beyond the end of buffer", 0)
+    end
+
+    self.offset = offset + length
+
+    return offset
+end
+
+function Reader:unpack(format, size)
+    return (string.unpack(format, self.buff, self:next(size)))
+end
+
+function Reader:read_byte()
+    return self:unpack("<B", 1)
+end
+
+function Reader:read_sbyte()
+    return self:unpack("<b", 1)
+end
+
+function Reader:read_bool()
+    return self:read_byte() ~= 0
+end
+
+function Reader:read_int16()
+    return self:unpack("<i2", 2)
+end
+
+function Reader:read_uint16()
+    return self:unpack("<I2", 2)
+end
+
+function Reader:read_int32()
+    return self:unpack("<i4", 4)
+end
+
+function Reader:read_uint32()
+    return self:unpack("<I4", 4)
+end
+
+function Reader:read_int64()
+    return self:unpack("<i8", 8)
+end
+
+-- read_uint64 the values above math.maxinteger are wrapped to negative integers
+function Reader:read_uint64()
+    return self:unpack("<I8", 8)
+end
+
+function Reader:read_float32()
+    return self:unpack("<f", 4)
+end
+
+function Reader:read_float64()
+    return self:unpack("<d", 8)
+end
+
+function Reader:read_bytes()
+    local length = self:read_uint16()
+
+    local offset = self:next(length)
+
+    return string.sub(self.buff, offset, offset + length - 1)
+end
+
+function Reader:read_fixed_bytes(size)
+    local val = self:read_bytes()
+
+    if #val ~= size then
+        error(string.format("gsrpc: check array size failed, expect %d got %d", size, #val), 0)
+    end
+
+    return val
+end
+
+function Reader:read_string()
+    return self:read_bytes()
+end
+
+function Reader:read_list(read)
+    local val = {}
+
+    for i = 1, self:read_uint16() do
+        val[i] = read()
+    end
+
+    return val
+end
+
+function Reader:read_array(size, read)
+    local val = self:read_list(read)
+
+    if #val ~= size then
+        error(string.format("gsrpc: check array size failed, expect %d got %d", size, #val), 0)
+    end
+
+    return val
+end
+
+function Reader:read_map(read_key, read_value)
+    local val = {}
+
+    for _ = 1, self:read_uint16() do
+        local key = read_key()
+
+        val[key] = read_value()
+    end
+
+    return val
+end
+
+-- read_tags read the tag sequence of tagged value, the nested container and POD tags are followed by their component tags
+function Reader:read_tags()
+    local tag = self:read_byte()
+
+    local Tag = rpc().Tag
+
+    local tags = {tag}
+
+    local nested = 0
+
+{{range $i, $case := wireNested}}    {{if $i}}else{{end}}if {{range $j, $tag := .Tags}}{{if $j}} or {{end}}tag == Tag.{{$tag}}{{end}} then
+{{if lt .Nested 0}}        nested = self:read_byte()
+        tags[#tags + 1] = nested
+{{else}}        nested = {{.Nested}}
+{{end}}{{end}}    end
+
+    for _ = 1, nested do
+        for _, v in ipairs(self:read_tags()) do
+            tags[#tags + 1] = v
+        end
+    end
+
+    return tags
+end
+
+-- skip skip the value described by the tag sequence
+function Reader:skip(tags)
+    self:skip_tags(tags, 1)
+end
+
+-- skip_fields skip the unknown fields of tagged table
+function Reader:skip_fields(fields)
+    for _ = 1, fields do
+        self:skip(self:read_tags())
+    end
+end
+
+-- tags_end get the index next to the tag sequence begin with index
+local function tags_end(tags, index)
+    local Tag = rpc().Tag
+
+    local tag = tags[index]
+
+    local next, nested = index + 1, 0
+
+{{range $i, $case := wireNested}}    {{if $i}}else{{end}}if {{range $j, $tag := .Tags}}{{if $j}} or {{end}}tag == Tag.{{$tag}}{{end}} then
+{{if lt .Nested 0}}        next, nested = index + 2, tags[index + 1]
+{{else}}        nested = {{.Nested}}
+{{end}}{{end}}    end
+
+    for _ = 1, nested do
+        next = tags_end(tags, next)
+    end
+
+    return next
+end
+
+-- skip_components skip the values of count tag sequences begin with index
+function Reader:skip_components(tags, index, count)
+    for _ = 1, count do
+        self:skip_tags(tags, index)
+        index = tags_end(tags, index)
+    end
+end
+
+function Reader:skip_tags(tags, index)
+    local Tag = rpc().Tag
+
+    local tag = tags[index]
+
+{{range $i, $case := wireCases}}    {{if $i}}else{{end}}if {{range $j, $tag := .Tags}}{{if $j}} or {{end}}tag == Tag.{{$tag}}{{end}} then
+{{if eq .Kind "none"}}        -- no value
+{{else if eq .Kind "fixed"}}        self:next({{.Size}})
+{{else if eq .Kind "sized"}}        self:next(self:read_uint16())
+{{else if eq .Kind "fields"}}        self:skip_fields(self:read_uint16())
+{{else if eq .Kind "seq"}}        for _ = 1, self:read_uint16() do
+            self:skip_components(tags, index + 1, {{.Nested}})
+        end
+{{else if eq .Kind "record"}}        self:skip_components(tags, index + 2, tags[index + 1])
+{{else if eq .Kind "optional"}}        if self:read_bool() then
+            self:skip_tags(tags, index + 1)
+        end
+{{else if eq .Kind "variant"}}        if self:read_byte() ~= 0 then
+            self:skip(self:read_tags())
+        end
+{{end}}{{end}}    else
+        error(string.format("gsrpc: unknown tag %d", tag), 0)
+    end
+end
+
+-- Writer write gsrpc values into the output parts, which are concatenated by content
+local Writer = {}
+
+Writer.__index = Writer
+
+M.Writer = Writer
+
+function Writer.new()
+    return setmetatable({parts = {}}, Writer)
+end
+
+function Writer:pack(format, val)
+    self.parts[#self.parts + 1] = string.pack(format, val)
+end
+
+function Writer:write_byte(val)
+    self:pack("<B", val)
+end
+
+function Writer:write_sbyte(val)
+    self:pack("<b", val)
+end
+
+function Writer:write_bool(val)
+    self:pack("<B", val and 1 or 0)
+end
+
+function Writer:write_int16(val)
+    self:pack("<i2", val)
+end
+
+function Writer:write_uint16(val)
+    self:pack("<I2", val)
+end
+
+function Writer:write_int32(val)
+    self:pack("<i4", val)
+end
+
+function Writer:write_uint32(val)
+    self:pack("<I4", val)
+end
+
+function Writer:write_int64(val)
+    self:pack("<i8", val)
+end
+
+function Writer:write_uint64(val)
+    self:pack("<I8", val)
+end
+
+function Writer:write_float32(val)
+    self:pack("<f", val)
+end
+
+function Writer:write_float64(val)
+    self:pack("<d", val)
+end
+
+function Writer:write_bytes(val)
+    self:write_uint16(#val)
+
+    self.parts[#self.parts + 1] = val
+end
+
+function Writer:write_fixed_bytes(size, val)
+    if #val ~= size then
+        error(string.format("gsrpc: check array size failed, expect %d got %d", size, #val), 0)
+    end
+
+    self:write_bytes(val)
+end
+
+function Writer:write_string(val)
+    self:write_bytes(val)
+end
+
+function Writer:write_tags(...)
+    for i = 1, select("#", ...) do
+        self:write_byte((select(i, ...)))
+    end
+end
+
+function Writer:write_list(val, write)
+    self:write_uint16(#val)
+
+    for _, v in ipairs(val) do
+        write(v)
+    end
+end
+
+function Writer:write_array(size, val, write)
+    if #val ~= size then
+        error(string.format("gsrpc: check array size failed, expect %d got %d", size, #val), 0)
+    end
+
+    self:write_list(val, write)
+end
+
+-- write_map write map entries in key order, so the same map is always encoded as same bytes
+function Writer:write_map(val, write_key, write_value)
+    local keys = {}
+
+    for key in pairs(val) do
+        keys[#keys + 1] = key
+    end
+
+    table.sort(keys)
+
+    self:write_uint16(#keys)
+
+    for _, key in ipairs(keys) do
+        write_key(key)
+        write_value(val[key])
+    end
+end
+
+function Writer:content()
+    return table.concat(self.parts)
+end
+
+-- marshal encode value by the write function
+function M.marshal(write)
+    local writer = Writer.new()
+
+    write(writer)
+
+    return writer:content()
+end
+
+-- unmarshal decode value from content by the read function
+function M.unmarshal(content, read)
+    return read(Reader.new(content))
+end
+
+-- array create the fixed size array, the elements are created by new function
+function M.array(size, new)
+    local val = {}
+
+    for i = 1, size do
+        val[i] = new()
+    end
+
+    return val
+end
+
+-- check_enum check the value read of @Strict enum is one of the declared constants
+function M.check_enum(enum, val, name)
+    for _, v in pairs(enum) do
+        if v == val then
+            return val
+        end
+    end
+
+    error(string.format("gsrpc: invalid %s value %d", name, val), 0)
+end
+
+-- response create the response of call
+function M.response(call, exception, content)
+    return rpc().Response.new({
+        id = call.id,
+        exception = exception,
+        content = content,
+        trace = call.trace,
+    })
+end
+
+-- Channel the rpc channel which send requests to and dispatch requests from the peer.
+-- the transport implements transport:send(data) and transport:close(), and reports the received
+-- messages and close reason through the on_message(data) and on_close(reason) callbacks installed by channel.
+-- the rpc calls yield the calling coroutine until the response is received, update must be called
+-- periodically to expire the timeout calls, clock return the current seconds and defaults to os.time
+local Channel = {}
+
+Channel.__index = Channel
+
+M.Channel = Channel
+
+function Channel.new(transport, clock)
+    local channel = setmetatable({
+        transport = transport,
+        clock = clock or os.time,
+        seq = 0,
+        pending = {},
+        dispatchers = {},
+        error_handler = function(err)
+            io.stderr:write(tostring(err), "\n")
+        end,
+    }, Channel)
+
+    transport.on_message = function(data)
+        channel:on_message(data)
+    end
+
+    transport.on_close = function(reason)
+        channel:on_close(reason)
+    end
+
+    return channel
+end
+
+function Channel:register(dispatcher)
+    self.dispatchers[dispatcher.id] = dispatcher
+end
+
+function Channel:unregister(id)
+    self.dispatchers[id] = nil
+end
+
+-- on_error set the handler of dispatch errors
+function Channel:on_error(handler)
+    self.error_handler = handler
+end
+
+-- call send the request and wait the response, raise error after timeout seconds
+function Channel:call(call, timeout)
+    local co, main = coroutine.running()
+
+    if co == nil or main then
+        error("gsrpc: rpc call must be invoked in coroutine", 0)
+    end
+
+    self.seq = (self.seq + 1) & 0xFFFFFFFF
+
+    local id = self.seq
+
+    call.id = id
+
+    local pending = {co = co, deadline = self.clock() + timeout}
+
+    self.pending[id] = pending
+
+    local ok, err = pcall(self.send, self, rpc().Code.Request, M.marshal(function(writer) rpc().Request.write(writer, call) end))
+
+    -- the response may be received before the send return
+    while ok and not pending.done do
+        pending.waiting = true
+
+        coroutine.yield()
+    end
+
+    self.pending[id] = nil
+
+    if not ok then
+        error(err, 0)
+    end
+
+    if pending.err ~= nil then
+        error(pending.err, 0)
+    end
+
+    return pending.response
+end
+
+-- post send the request without waiting response
+function Channel:post(call)
+    self:send(rpc().Code.Request, M.marshal(function(writer) rpc().Request.write(writer, call) end))
+end
+
+function Channel:close()
+    self.transport:close()
+end
+
+-- update expire the timeout calls
+function Channel:update()
+    local now = self.clock()
+
+    local expired = {}
+
+    for id, pending in pairs(self.pending) do
+        if not pending.done and now >= pending.deadline then
+            expired[id] = pending
+        end
+    end
+
+    for id, pending in pairs(expired) do
+        self:complete(pending, nil, string.format("gsrpc: rpc call(%d) timeout", id))
+    end
+end
+
+function Channel:send(code, content)
+    local message = rpc().Message.new({code = code, agent = 0, content = content})
+
+    self.transport:send(M.marshal(function(writer) rpc().Message.write(writer, message) end))
+end
+
+function Channel:complete(pending, response, err)
+    pending.done, pending.response, pending.err = true, response, err
+
+    if pending.waiting then
+        pending.waiting = false
+
+        local ok, reason = coroutine.resume(pending.co)
+
+        if not ok then
+            self.error_handler(reason)
+        end
+    end
+end
+
+function Channel:on_message(data)
+    local gsrpc = rpc()
+
+    local message = M.unmarshal(data, gsrpc.Message.read)
+
+    if message.code == gsrpc.Code.Response then
+        local call_return = M.unmarshal(message.content, gsrpc.Response.read)
+
+        local pending = self.pending[call_return.id]
+
+        if pending ~= nil and not pending.done then
+            self:complete(pending, call_return)
+        end
+    elseif message.code == gsrpc.Code.Request then
+        local call = M.unmarshal(message.content, gsrpc.Request.read)
+
+        local ok, reason = coroutine.resume(coroutine.create(function() self:dispatch(call) end))
+
+        if not ok then
+            self.error_handler(reason)
+        end
+    elseif message.code == gsrpc.Code.Heartbeat then
+        self:send(gsrpc.Code.Heartbeat, "")
+    end
+end
+
+function Channel:dispatch(call)
+    local dispatcher = self.dispatchers[call.service]
+
+    if dispatcher == nil then
+        return
+    end
+
+    local ok, call_return = pcall(dispatcher.dispatch, dispatcher, call)
+
+    if not ok then
+        self.error_handler(string.format("gsrpc: dispatch %s#%d error :%s", dispatcher.NAME, call.method, tostring(call_return)))
+        return
+    end
+
+    if call_return ~= nil then
+        self:send(rpc().Code.Response, M.marshal(function(writer) rpc().Response.write(writer, call_return) end))
+    end
+end
+
+function Channel:on_close(reason)
+    local pending = self.pending
+
+    self.pending = {}
+
+    for _, v in pairs(pending) do
+        if not v.done then
+            self:complete(v, nil, reason)
+        end
+    end
+end
+
+return M
+{{end}}
+`
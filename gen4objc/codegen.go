@@ -8,6 +8,7 @@ import (
 	"path"
 	"path/filepath"
 	"regexp"
+	"strconv"
 	"strings"
 	"text/template"
 
@@ -16,6 +17,8 @@ import (
 	"github.com/gsrpc/gslang"
 	"github.com/gsrpc/gslang/ast"
 	"github.com/gsrpc/gslang/lexer"
+	"github.com/gsrpc/gsrpc/gen"
+	"github.com/gsrpc/gsrpc/wire"
 )
 
 var builtin = map[lexer.TokenType]string{
@@ -119,6 +122,7 @@ var imports = map[string]string{
 type _CodeGen struct {
 	gslogger.Log                    // Log APIs
 	rootpath     string             // root path
+	files        map[string][]byte  // generated files sink, the files are written into rootpath if nil
 	script       *ast.Script        // current script
 	tpl          *template.Template // code generate template
 	imports      map[string]string  // imports
@@ -152,7 +156,9 @@ func NewCodeGen(rootpath string, skips []string) (gslang.Visitor, error) {
 	funcs := template.FuncMap{
 		"title":           codeGen.title,
 		"title2":          strings.Title,
+		"isFlag":          isFlag,
 		"enumFields":      codeGen.enumFields,
+		"doc":             doc,
 		"typeName":        codeGen.typeName,
 		"enumRead":        codeGen.enumRead,
 		"enumWrite":       codeGen.enumWrite,
@@ -175,10 +181,25 @@ func NewCodeGen(rootpath string, skips []string) (gslang.Visitor, error) {
 		"enumType": func(typeDecl ast.Type) string {
 			return builtin[gslang.EnumType(typeDecl)]
 		},
-		"builtin":       gslang.IsBuiltin,
-		"marshalParams": codeGen.marshalParams,
-		"callback":      codeGen.callback,
-		"tagValue":      codeGen.tagValue,
+		"builtin":          gslang.IsBuiltin,
+		"marshalParams":    codeGen.marshalParams,
+		"callback":         codeGen.callback,
+		"tagValue":         codeGen.tagValue,
+		"tags":             codeGen.tags,
+		"isOptional":       isOptional,
+		"fieldDefault":     codeGen.fieldDefault,
+		"variant":          variant,
+		"wireCases":        wire.Cases,
+		"wireNested":       wire.Nested,
+		"variantGet":       codeGen.variantGet,
+		"variantDefault":   codeGen.variantDefault,
+		"variantSet":       codeGen.variantSet,
+		"marshalVariant":   codeGen.marshalVariant,
+		"unmarshalVariant": codeGen.unmarshalVariant,
+		"fieldToJSON":      codeGen.fieldToJSON,
+		"fieldFromJSON":    codeGen.fieldFromJSON,
+		"toJSON":           codeGen.toJSON,
+		"fromJSON":         codeGen.fromJSON,
 	}
 
 	tpl, err := template.New("t4objc").Funcs(funcs).Parse(t4objc)
@@ -192,6 +213,20 @@ func NewCodeGen(rootpath string, skips []string) (gslang.Visitor, error) {
 	return codeGen, nil
 }
 
+// NewCodeGenWithFiles create codegen which write generated files into files map keyed by slash separated relative path
+func NewCodeGenWithFiles(files map[string][]byte, skips []string) (gslang.Visitor, error) {
+
+	codeGen, err := NewCodeGen("", skips)
+
+	if err != nil {
+		return nil, err
+	}
+
+	codeGen.(*_CodeGen).files = files
+
+	return codeGen, nil
+}
+
 func (codegen *_CodeGen) callback(method *ast.Method) string {
 
 	var buff bytes.Buffer
@@ -204,98 +239,777 @@ func (codegen *_CodeGen) callback(method *ast.Method) string {
 
 	buff.WriteString("))block)(")
 
-	if codegen.notVoid(method.Return) {
-		buff.WriteString("callreturn")
+	if codegen.notVoid(method.Return) {
+		buff.WriteString("callreturn")
+	}
+
+	buff.WriteString(");")
+
+	return buff.String()
+}
+
+// isFlag check if the enum is annotated with @Flag
+func isFlag(typeDecl ast.Type) bool {
+	_, ok := gslang.FindAnnotation(typeDecl, "gslang.Flag")
+
+	return ok
+}
+
+func enumType(typeDecl ast.Type) string {
+	_, ok := gslang.FindAnnotation(typeDecl, "gslang.Flag")
+
+	if !ok {
+		return builtin[lexer.KeyUInt32]
+	}
+
+	return builtin[lexer.KeyByte]
+}
+
+func (codegen *_CodeGen) tagValue(typeDecl ast.Type) string {
+	return strings.Join(codegen.tags(typeDecl), ",")
+}
+
+// tags get the type tag sequence by the shared tag scheme
+func (codegen *_CodeGen) tags(typeDecl ast.Type) []string {
+	return wire.Format(wire.Tags(typeDecl), func(tag wire.Tag) string {
+		return "GSTag" + tag.String()
+	})
+}
+
+func (codegen *_CodeGen) enumRead(typeDecl ast.Type) string {
+	_, ok := gslang.FindAnnotation(typeDecl, "gslang.Flag")
+
+	if ok {
+		return fmt.Sprintf("[%s]", readMapping[lexer.KeyUInt32])
+	}
+
+	return fmt.Sprintf("[%s]", readMapping[lexer.KeyByte])
+}
+
+func (codegen *_CodeGen) enumWrite(typeDecl ast.Type) string {
+	_, ok := gslang.FindAnnotation(typeDecl, "gslang.Flag")
+
+	if ok {
+		return fmt.Sprintf("[%s:(UInt32) val]", writeMapping[lexer.KeyUInt32])
+	}
+
+	return fmt.Sprintf("[%s:(UInt8) val]", writeMapping[lexer.KeyByte])
+}
+
+func (codegen *_CodeGen) fieldDecl(field *ast.Field) string {
+	if isOptional(field) {
+		return fmt.Sprintf("@property(nonatomic, strong, nullable) %s %s;", codegen.fieldType(field), strings.Title(field.Name()))
+	}
+
+	return fmt.Sprintf("@property%s %s %s;", propertyAttr(field.Type), codegen.typeName(field.Type), strings.Title(field.Name()))
+}
+
+// isOptional check if the field is annotated with @Optional
+func isOptional(field *ast.Field) bool {
+	_, ok := gslang.FindAnnotation(field, "com.gsrpc.Optional")
+
+	return ok
+}
+
+// isBoxed check if the optional field value is boxed as NSNumber
+func isBoxed(field *ast.Field) bool {
+	return isOptional(field) && isScalar(field.Type)
+}
+
+// isScalar check if the type is builtin(not string) or enum type
+func isScalar(typeDecl ast.Type) bool {
+
+	if typeRef, ok := typeDecl.(*ast.TypeRef); ok {
+		typeDecl = typeRef.Ref
+	}
+
+	switch typeDecl.(type) {
+	case *ast.BuiltinType:
+		return typeDecl.(*ast.BuiltinType).Type != lexer.KeyString
+	case *ast.Enum:
+		return true
+	}
+
+	return false
+}
+
+// isOneOf check if the table is annotated with @OneOf
+func isOneOf(typeDecl ast.Type) bool {
+	_, ok := gslang.FindAnnotation(typeDecl, "com.gsrpc.OneOf")
+
+	return ok
+}
+
+// variant get the union variant index of field by field index
+func variant(index int) int {
+	return index + 1
+}
+
+func (codegen *_CodeGen) variantGet(field *ast.Field) string {
+	return codegen.toComponentType("_value", field.Type)
+}
+
+// variantDefault get the value returned by the getter of the unset variant
+func (codegen *_CodeGen) variantDefault(field *ast.Field) string {
+	if isScalar(field.Type) {
+		return codegen.defaultVal(field.Type)
+	}
+
+	return "nil"
+}
+
+func (codegen *_CodeGen) variantSet(field *ast.Field) string {
+	return codegen.fromComponentType("val", field.Type)
+}
+
+func (codegen *_CodeGen) marshalVariant(field *ast.Field) string {
+	var stream bytes.Buffer
+
+	stream.WriteString(fmt.Sprintf("%s val = %s;\n", codegen.typeName(field.Type), codegen.toComponentType("_value", field.Type)))
+
+	stream.WriteString(codegen.marshal("val", field.Type, 2))
+
+	return stream.String()
+}
+
+func (codegen *_CodeGen) unmarshalVariant(field *ast.Field) string {
+	var stream bytes.Buffer
+
+	stream.WriteString(fmt.Sprintf("%s val = %s;\n", codegen.typeName(field.Type), codegen.defaultVal(field.Type)))
+
+	stream.WriteString(codegen.unmarshal("val", field.Type, 2))
+
+	return stream.String()
+}
+
+// jsonParseKey get the map key expr from json object key string
+var jsonParseKey = map[lexer.TokenType]string{
+	lexer.KeySByte:   "(SInt8)[%s longLongValue]",
+	lexer.KeyByte:    "(UInt8)[%s longLongValue]",
+	lexer.KeyInt16:   "(SInt16)[%s longLongValue]",
+	lexer.KeyUInt16:  "(UInt16)[%s longLongValue]",
+	lexer.KeyInt32:   "(SInt32)[%s longLongValue]",
+	lexer.KeyUInt32:  "(UInt32)[%s longLongValue]",
+	lexer.KeyInt64:   "(SInt64)[%s longLongValue]",
+	lexer.KeyUInt64:  "(UInt64)strtoull([%s UTF8String], NULL, 10)",
+	lexer.KeyFloat32: "(Float32)[%s doubleValue]",
+	lexer.KeyFloat64: "(Float64)[%s doubleValue]",
+	lexer.KeyString:  "%s",
+}
+
+func (codegen *_CodeGen) fieldToJSON(field *ast.Field) string {
+	varname := "_" + strings.Title(field.Name())
+
+	if isBoxed(field) {
+		var stream bytes.Buffer
+
+		writeindent(&stream, 2)
+
+		stream.WriteString(fmt.Sprintf("%s val = %s;\n", codegen.typeName(field.Type), codegen.toComponentType(varname, field.Type)))
+
+		stream.WriteString(codegen.toJSON("value", "val", field.Type, 2))
+
+		return stream.String()
+	}
+
+	return codegen.toJSON("value", varname, field.Type, 2)
+}
+
+func (codegen *_CodeGen) fieldFromJSON(field *ast.Field) string {
+	varname := "_" + strings.Title(field.Name())
+
+	if !isOptional(field) {
+		return codegen.fromJSON(varname, "value", field.Type, 3)
+	}
+
+	var stream bytes.Buffer
+
+	writeindent(&stream, 3)
+
+	stream.WriteString("if(value == [NSNull null]) {\n")
+
+	writeindent(&stream, 4)
+
+	stream.WriteString(fmt.Sprintf("%s = nil;\n", varname))
+
+	writeindent(&stream, 3)
+
+	stream.WriteString("} else {\n")
+
+	writeindent(&stream, 4)
+
+	stream.WriteString(fmt.Sprintf("%s val = %s;\n", codegen.typeName(field.Type), codegen.defaultVal(field.Type)))
+
+	stream.WriteString(codegen.fromJSON("val", "value", field.Type, 4))
+
+	writeindent(&stream, 4)
+
+	if isBoxed(field) {
+		stream.WriteString(fmt.Sprintf("%s = %s;\n", varname, codegen.fromComponentType("val", field.Type)))
+	} else {
+		stream.WriteString(fmt.Sprintf("%s = val;\n", varname))
+	}
+
+	writeindent(&stream, 3)
+
+	stream.WriteString("}\n")
+
+	return stream.String()
+}
+
+// toJSON generate statements which assign the json object of varname to the declared id target,
+// uint64 is rendered as string,bytes as base64 string and enum by constant name
+func (codegen *_CodeGen) toJSON(target string, varname string, typeDecl ast.Type, indent int) string {
+	var stream bytes.Buffer
+
+	writeindent(&stream, indent)
+
+	switch typeDecl.(type) {
+	case *ast.BuiltinType:
+		builtinType := typeDecl.(*ast.BuiltinType)
+
+		switch builtinType.Type {
+		case lexer.KeyUInt64:
+			stream.WriteString(fmt.Sprintf("%s = [NSString stringWithFormat:@\"%%llu\", %s];", target, varname))
+		case lexer.KeyString:
+			stream.WriteString(fmt.Sprintf("%s = %s != nil ? %s : [NSNull null];", target, varname, varname))
+		default:
+			stream.WriteString(fmt.Sprintf("%s = @(%s);", target, varname))
+		}
+
+	case *ast.TypeRef:
+		return codegen.toJSON(target, varname, typeDecl.(*ast.TypeRef).Ref, indent)
+
+	case *ast.Enum:
+		stream.WriteString(fmt.Sprintf("%s = [%sHelper toJSON: %s];", target, codegen.typeName(typeDecl), varname))
+
+	case *ast.Table:
+		stream.WriteString(fmt.Sprintf("%s = %s != nil ? [%s toJSON] : [NSNull null];", target, varname, varname))
+
+	case *ast.Seq:
+		seq := typeDecl.(*ast.Seq)
+
+		builtinType, ok := seq.Component.(*ast.BuiltinType)
+
+		if ok && builtinType.Type == lexer.KeyByte {
+			stream.WriteString(fmt.Sprintf("%s = [%s base64EncodedStringWithOptions:0];", target, varname))
+			break
+		}
+
+		if entry, ok := wire.MapEntry(seq); ok {
+
+			key, val := entry.Fields[0].Type, entry.Fields[1].Type
+
+			stream.WriteString(fmt.Sprintf("NSMutableDictionary *object%d = [NSMutableDictionary dictionary];\n", indent))
+
+			writeindent(&stream, indent)
+
+			stream.WriteString(fmt.Sprintf("for(id k%d in %s){\n", indent, varname))
+
+			writeindent(&stream, indent+1)
+
+			stream.WriteString(fmt.Sprintf("%s kk%d = %s;\n", codegen.typeName(key), indent, codegen.toComponentType(fmt.Sprintf("k%d", indent), key)))
+
+			writeindent(&stream, indent+1)
+
+			stream.WriteString(fmt.Sprintf("id kj%d = nil;\n", indent))
+
+			stream.WriteString(codegen.toJSON(fmt.Sprintf("kj%d", indent), fmt.Sprintf("kk%d", indent), key, indent+1))
+
+			writeindent(&stream, indent+1)
+
+			stream.WriteString(fmt.Sprintf("%s vv%d = %s;\n", codegen.typeName(val), indent, codegen.toComponentType(fmt.Sprintf("%s[k%d]", varname, indent), val)))
+
+			writeindent(&stream, indent+1)
+
+			stream.WriteString(fmt.Sprintf("id vj%d = nil;\n", indent))
+
+			stream.WriteString(codegen.toJSON(fmt.Sprintf("vj%d", indent), fmt.Sprintf("vv%d", indent), val, indent+1))
+
+			writeindent(&stream, indent+1)
+
+			stream.WriteString(fmt.Sprintf("object%d[[NSString stringWithFormat:@\"%%@\", kj%d]] = vj%d;\n", indent, indent, indent))
+
+			writeindent(&stream, indent)
+
+			stream.WriteString("}\n")
+
+			writeindent(&stream, indent)
+
+			stream.WriteString(fmt.Sprintf("%s = object%d;", target, indent))
+
+			break
+		}
+
+		stream.WriteString(fmt.Sprintf("NSMutableArray *array%d = [NSMutableArray arrayWithCapacity:%s.count];\n", indent, varname))
+
+		writeindent(&stream, indent)
+
+		stream.WriteString(fmt.Sprintf("for(id v%d in %s){\n", indent, varname))
+
+		writeindent(&stream, indent+1)
+
+		stream.WriteString(fmt.Sprintf("%s vv%d = %s;\n", codegen.typeName(seq.Component), indent, codegen.toComponentType(fmt.Sprintf("v%d", indent), seq.Component)))
+
+		writeindent(&stream, indent+1)
+
+		stream.WriteString(fmt.Sprintf("id vj%d = nil;\n", indent))
+
+		stream.WriteString(codegen.toJSON(fmt.Sprintf("vj%d", indent), fmt.Sprintf("vv%d", indent), seq.Component, indent+1))
+
+		writeindent(&stream, indent+1)
+
+		stream.WriteString(fmt.Sprintf("[array%d addObject:vj%d];\n", indent, indent))
+
+		writeindent(&stream, indent)
+
+		stream.WriteString("}\n")
+
+		writeindent(&stream, indent)
+
+		stream.WriteString(fmt.Sprintf("%s = array%d;", target, indent))
+	}
+
+	stream.WriteRune('\n')
+
+	return stream.String()
+}
+
+// fromJSON generate statements which assign the value decoded from json object expr to varname
+func (codegen *_CodeGen) fromJSON(varname string, json string, typeDecl ast.Type, indent int) string {
+	var stream bytes.Buffer
+
+	writeindent(&stream, indent)
+
+	switch typeDecl.(type) {
+	case *ast.BuiltinType:
+		builtinType := typeDecl.(*ast.BuiltinType)
+
+		if builtinType.Type == lexer.KeyUInt64 {
+			stream.WriteString(fmt.Sprintf("%s = (UInt64)strtoull([[%s description] UTF8String], NULL, 10);", varname, json))
+		} else {
+			stream.WriteString(fmt.Sprintf("%s = %s;", varname, fmt.Sprintf(id2Type[builtinType.Type], json)))
+		}
+
+	case *ast.TypeRef:
+		return codegen.fromJSON(varname, json, typeDecl.(*ast.TypeRef).Ref, indent)
+
+	case *ast.Enum:
+		stream.WriteString(fmt.Sprintf("%s = [%sHelper fromJSON: (NSString*)%s];", varname, codegen.typeName(typeDecl), json))
+
+	case *ast.Table:
+		if isOneOf(typeDecl) {
+			stream.WriteString(fmt.Sprintf("%s = %s;\n", varname, codegen.defaultVal(typeDecl)))
+			writeindent(&stream, indent)
+			stream.WriteString(fmt.Sprintf("[%s fromJSON: %s];", varname, json))
+			break
+		}
+
+		stream.WriteString(fmt.Sprintf("if(%s == [NSNull null]) {\n", json))
+		writeindent(&stream, indent+1)
+		stream.WriteString(fmt.Sprintf("%s = nil;\n", varname))
+		writeindent(&stream, indent)
+		stream.WriteString("} else {\n")
+		writeindent(&stream, indent+1)
+		stream.WriteString(fmt.Sprintf("%s = %s;\n", varname, codegen.defaultVal(typeDecl)))
+		writeindent(&stream, indent+1)
+		stream.WriteString(fmt.Sprintf("[%s fromJSON: (NSDictionary*)%s];\n", varname, json))
+		writeindent(&stream, indent)
+		stream.WriteString("}")
+
+	case *ast.Seq:
+		seq := typeDecl.(*ast.Seq)
+
+		builtinType, ok := seq.Component.(*ast.BuiltinType)
+
+		if ok && builtinType.Type == lexer.KeyByte {
+			stream.WriteString(fmt.Sprintf("%s = [[NSMutableData alloc] initWithBase64EncodedString:(NSString*)%s options:0];", varname, json))
+			break
+		}
+
+		if entry, ok := wire.MapEntry(seq); ok {
+
+			key, val := entry.Fields[0].Type, entry.Fields[1].Type
+
+			stream.WriteString(fmt.Sprintf("NSDictionary *object%d = (NSDictionary*)%s;\n", indent, json))
+
+			writeindent(&stream, indent)
+
+			stream.WriteString(fmt.Sprintf("%s = [NSMutableDictionary dictionary];\n", varname))
+
+			writeindent(&stream, indent)
+
+			stream.WriteString(fmt.Sprintf("for(NSString *name%d in object%d){\n", indent, indent))
+
+			writeindent(&stream, indent+1)
+
+			if typeRef, ok := key.(*ast.TypeRef); ok {
+				key = typeRef.Ref
+			}
+
+			if builtinType, ok := key.(*ast.BuiltinType); ok {
+				stream.WriteString(fmt.Sprintf("%s k%d = %s;\n", codegen.typeName(key), indent, fmt.Sprintf(jsonParseKey[builtinType.Type], fmt.Sprintf("name%d", indent))))
+			} else {
+				stream.WriteString(fmt.Sprintf("%s k%d = [%sHelper fromJSON: name%d];\n", codegen.typeName(key), indent, codegen.typeName(key), indent))
+			}
+
+			writeindent(&stream, indent+1)
+
+			stream.WriteString(fmt.Sprintf("%s v%d = %s;\n", codegen.typeName(val), indent, codegen.defaultVal(val)))
+
+			stream.WriteString(codegen.fromJSON(fmt.Sprintf("v%d", indent), fmt.Sprintf("object%d[name%d]", indent, indent), val, indent+1))
+
+			writeindent(&stream, indent+1)
+
+			stream.WriteString(fmt.Sprintf("[%s setObject:%s forKey:%s];\n", varname, codegen.fromComponentType(fmt.Sprintf("v%d", indent), val), codegen.fromComponentType(fmt.Sprintf("k%d", indent), key)))
+
+			writeindent(&stream, indent)
+
+			stream.WriteString("}")
+
+			break
+		}
+
+		stream.WriteString(fmt.Sprintf("NSArray *array%d = (NSArray*)%s;\n", indent, json))
+
+		writeindent(&stream, indent)
+
+		stream.WriteString(fmt.Sprintf("%s = [NSMutableArray arrayWithCapacity:array%d.count];\n", varname, indent))
+
+		writeindent(&stream, indent)
+
+		stream.WriteString(fmt.Sprintf("for(id item%d in array%d){\n", indent, indent))
+
+		writeindent(&stream, indent+1)
+
+		stream.WriteString(fmt.Sprintf("%s v%d = %s;\n", codegen.typeName(seq.Component), indent, codegen.defaultVal(seq.Component)))
+
+		stream.WriteString(codegen.fromJSON(fmt.Sprintf("v%d", indent), fmt.Sprintf("item%d", indent), seq.Component, indent+1))
+
+		writeindent(&stream, indent+1)
+
+		stream.WriteString(fmt.Sprintf("[%s addObject:%s];\n", varname, codegen.fromComponentType(fmt.Sprintf("v%d", indent), seq.Component)))
+
+		writeindent(&stream, indent)
+
+		stream.WriteString("}")
+	}
+
+	stream.WriteRune('\n')
+
+	return stream.String()
+}
+
+func (codegen *_CodeGen) fieldType(field *ast.Field) string {
+	if isBoxed(field) {
+		return "NSNumber *"
 	}
 
-	buff.WriteString(");")
-
-	return buff.String()
+	return codegen.typeName(field.Type)
 }
 
-func enumType(typeDecl ast.Type) string {
-	_, ok := gslang.FindAnnotation(typeDecl, "gslang.Flag")
+func (codegen *_CodeGen) fieldDefault(field *ast.Field) string {
+
+	expr, ok := gen.Default(field)
 
 	if !ok {
-		return builtin[lexer.KeyUInt32]
+		if isOptional(field) {
+			return "nil"
+		}
+
+		return codegen.defaultVal(field.Type)
 	}
 
-	return builtin[lexer.KeyByte]
+	start, _ := gslang.Pos(field)
+
+	return codegen.defaultExpr(expr, field.Type, start)
 }
 
-func (codegen *_CodeGen) tagValue(typeDecl ast.Type) string {
+func (codegen *_CodeGen) defaultExpr(expr ast.Expr, typeDecl ast.Type, start lexer.Position) string {
+
+	eval := codegen.compiler.Eval()
+
 	switch typeDecl.(type) {
 	case *ast.BuiltinType:
 		builtinType := typeDecl.(*ast.BuiltinType)
 
 		switch builtinType.Type {
-		case lexer.KeySByte, lexer.KeyByte, lexer.KeyBool:
-			return "GSTagI8"
-		case lexer.KeyInt16, lexer.KeyUInt16:
-			return "GSTagI16"
-		case lexer.KeyInt32, lexer.KeyUInt32, lexer.KeyFloat32:
-			return "GSTagI32"
-		case lexer.KeyInt64, lexer.KeyUInt64, lexer.KeyFloat64:
-			return "GSTagI64"
+		case lexer.KeyByte, lexer.KeyUInt16, lexer.KeyUInt32, lexer.KeyUInt64:
+			if val := eval.EvalInt(expr); val < 0 {
+				gserrors.Panicf(nil, "unsigned type(%s) can't default to negative value(%d) :%v", typeDecl, val, start)
+			}
+		}
+
+		switch builtinType.Type {
 		case lexer.KeyString:
-			return "GSTagString"
+			return "@" + strconv.Quote(eval.EvalString(expr))
+		case lexer.KeyBool:
+			if eval.EvalBool(expr) {
+				return "TRUE"
+			}
+
+			return "FALSE"
+		case lexer.KeyFloat32, lexer.KeyFloat64:
+			return fmt.Sprintf("(%s)%s", builtin[builtinType.Type], strconv.FormatFloat(eval.EvalFloat(expr), 'g', -1, 64))
+		case lexer.KeyInt64, lexer.KeyUInt64:
+			return fmt.Sprintf("(%s)%dLL", builtin[builtinType.Type], eval.EvalInt(expr))
+		case lexer.KeyVoid:
+		default:
+			return fmt.Sprintf("(%s)%d", builtin[builtinType.Type], eval.EvalInt(expr))
 		}
 
 	case *ast.TypeRef:
-		return codegen.tagValue(typeDecl.(*ast.TypeRef).Ref)
+		return codegen.defaultExpr(expr, typeDecl.(*ast.TypeRef).Ref, start)
+
 	case *ast.Enum:
+		enum := typeDecl.(*ast.Enum)
 
-		_, ok := gslang.FindAnnotation(typeDecl, "gslang.Flag")
+		val := eval.EvalInt(expr)
 
-		if !ok {
-			return "GSTagI32"
+		for _, constant := range enum.Constants {
+			if constant.Value == val {
+				return codegen.title(enum) + strings.Title(constant.Name())
+			}
 		}
 
-		return "GSTagI8"
+		gserrors.Panicf(nil, "enum %s constant(%d) not found :%v", enum, val, start)
 
 	case *ast.Table:
-		return "GSTagTable"
-	case *ast.Seq:
+		table := typeDecl.(*ast.Table)
 
-		seq := typeDecl.(*ast.Seq)
+		newObj, ok := expr.(*ast.NewObj)
+
+		if !ok || isOneOf(table) {
+			break
+		}
 
-		component := codegen.tagValue(seq.Component)
+		return codegen.tableLiteral(table, newObj.Args, start)
+	}
+
+	gserrors.Panicf(nil, "unsupport default value for type(%s) :%v", typeDecl, start)
+
+	return "unknown"
+}
+
+// tableLiteral get the table object creation expr initialized by positional or named args
+func (codegen *_CodeGen) tableLiteral(table *ast.Table, args *ast.ArgsTable, start lexer.Position) string {
+
+	var stream bytes.Buffer
+
+	stream.WriteString(fmt.Sprintf("({ %s val = %s; ", codegen.typeName(table), codegen.defaultVal(table)))
+
+	for i, field := range table.Fields {
+
+		if args == nil {
+			break
+		}
+
+		arg, ok := args.NamedArg(field.Name())
+
+		if !args.Named {
+			arg, ok = nil, i < len(args.Arguments)
+
+			if ok {
+				arg = args.Arguments[i]
+			}
+		}
+
+		if !ok {
+			continue
+		}
+
+		value := codegen.defaultExpr(arg, field.Type, start)
 
-		if component == "GSTagList" {
-			start, _ := gslang.Pos(typeDecl)
-			gserrors.Panicf(nil, "list component %v can't be a list :%v", seq.Component, start)
+		if isBoxed(field) {
+			value = codegen.fromComponentType(value, field.Type)
 		}
 
-		return fmt.Sprintf("((%s << 4)|GSTagList)", component)
+		stream.WriteString(fmt.Sprintf("val.%s = %s; ", strings.Title(field.Name()), value))
 	}
 
-	gserrors.Panicf(nil, "typeName  error: unsupport type(%s)", typeDecl)
+	stream.WriteString("val; })")
 
-	return ""
+	return stream.String()
 }
 
-func (codegen *_CodeGen) enumRead(typeDecl ast.Type) string {
-	_, ok := gslang.FindAnnotation(typeDecl, "gslang.Flag")
+// userAnnotations get the annotations declared by user which are attached to node,
+// the gslang and com.gsrpc annotations are consumed by compiler and code generator
+func userAnnotations(node ast.Node) (annotations []*ast.Annotation) {
 
-	if ok {
-		return fmt.Sprintf("[%s]", readMapping[lexer.KeyUInt32])
+	for _, annotation := range node.Annotations() {
+
+		if _, ok := annotationTable(annotation); ok {
+			annotations = append(annotations, annotation)
+		}
 	}
 
-	return fmt.Sprintf("[%s]", readMapping[lexer.KeyByte])
+	return
 }
 
-func (codegen *_CodeGen) enumWrite(typeDecl ast.Type) string {
-	_, ok := gslang.FindAnnotation(typeDecl, "gslang.Flag")
+// annotationTable get the declaration table of user annotation
+func annotationTable(annotation *ast.Annotation) (*ast.Table, bool) {
 
-	if ok {
-		return fmt.Sprintf("[%s:(UInt32) val]", writeMapping[lexer.KeyUInt32])
+	typeDecl := annotation.Type
+
+	if typeRef, ok := typeDecl.(*ast.TypeRef); ok {
+		typeDecl = typeRef.Ref
 	}
 
-	return fmt.Sprintf("[%s:(UInt8) val]", writeMapping[lexer.KeyByte])
+	table, ok := typeDecl.(*ast.Table)
+
+	if !ok || table.Package() == "com.gsrpc" || strings.HasPrefix(table.Package(), "gslang") {
+		return nil, false
+	}
+
+	return table, true
 }
 
-func (codegen *_CodeGen) fieldDecl(field *ast.Field) string {
-	return fmt.Sprintf("@property%s %s %s;", propertyAttr(field.Type), codegen.typeName(field.Type), strings.Title(field.Name()))
+// _AnnotationNode the annotated node and its children, e.g. contract and its methods
+type _AnnotationNode struct {
+	name     string             // property name of parent annotations class
+	node     ast.Node           // annotated node
+	children []*_AnnotationNode // children nodes
+}
+
+// hasAnnotations check if the node or any of its children has user annotations
+func (node *_AnnotationNode) hasAnnotations() bool {
+
+	if len(userAnnotations(node.node)) != 0 {
+		return true
+	}
+
+	for _, child := range node.children {
+		if child.hasAnnotations() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// annotationClass write the annotations class interface and implementation of node and children,
+// the children classes are named by node path joined with '_'
+func (codegen *_CodeGen) annotationClass(header *bytes.Buffer, source *bytes.Buffer, className string, node *_AnnotationNode, root bool) {
+
+	start, _ := gslang.Pos(node.node)
+
+	var properties, inits bytes.Buffer
+
+	names := make(map[string]bool)
+
+	checkName := func(name string) {
+		if names[name] {
+			gserrors.Panicf(nil, "duplicate annotations property %s of %s :%v", name, node.node, start)
+		}
+
+		names[name] = true
+	}
+
+	var groups [][]*ast.Annotation
+
+	indexer := make(map[string]int)
+
+	for _, annotation := range userAnnotations(node.node) {
+
+		if index, ok := indexer[annotation.FullName()]; ok {
+			groups[index] = append(groups[index], annotation)
+			continue
+		}
+
+		indexer[annotation.FullName()] = len(groups)
+
+		groups = append(groups, []*ast.Annotation{annotation})
+	}
+
+	for _, group := range groups {
+
+		table, _ := annotationTable(group[0])
+
+		name := strings.Title(table.Name())
+
+		checkName(name)
+
+		start, _ := gslang.Pos(group[0])
+
+		if len(group) == 1 {
+			properties.WriteString(fmt.Sprintf("@property(nonatomic, strong, readonly) %s %s;\n", codegen.typeName(table), name))
+			inits.WriteString(fmt.Sprintf("\t\t_%s = %s;\n", name, codegen.tableLiteral(table, group[0].Args, start)))
+			continue
+		}
+
+		var values []string
+
+		for _, annotation := range group {
+			values = append(values, codegen.tableLiteral(table, annotation.Args, start))
+		}
+
+		properties.WriteString(fmt.Sprintf("@property(nonatomic, strong, readonly) NSArray<%s>* %s;\n", codegen.typeName(table), name))
+		inits.WriteString(fmt.Sprintf("\t\t_%s = @[%s];\n", name, strings.Join(values, ", ")))
+	}
+
+	for _, child := range node.children {
+
+		if !child.hasAnnotations() {
+			continue
+		}
+
+		name := strings.Title(child.name)
+
+		checkName(name)
+
+		childClassName := strings.TrimSuffix(strings.TrimSuffix(className, "Annotations"), "_") + "_" + name + "_Annotations"
+
+		codegen.annotationClass(header, source, childClassName, child, false)
+
+		properties.WriteString(fmt.Sprintf("@property(nonatomic, strong, readonly) %s* %s;\n", childClassName, name))
+		inits.WriteString(fmt.Sprintf("\t\t_%s = [[%s alloc] init];\n", name, childClassName))
+	}
+
+	header.WriteString(fmt.Sprintf("\n// %s the annotations attached to %s\n@interface %s : NSObject\n%s", className, node.node.Name(), className, properties.String()))
+
+	source.WriteString(fmt.Sprintf("\n@implementation %s\n", className))
+
+	if root {
+		header.WriteString("+ (instancetype) shared;\n")
+
+		source.WriteString(fmt.Sprintf("+ (instancetype) shared {\n\tstatic %s *annotations = nil;\n\tstatic dispatch_once_t once;\n\tdispatch_once(&once, ^{\n\t\tannotations = [[%s alloc] init];\n\t});\n\treturn annotations;\n}\n", className, className))
+	}
+
+	header.WriteString("@end\n")
+
+	source.WriteString(fmt.Sprintf("- (instancetype) init {\n\tif (self = [super init]) {\n%s\t}\n\treturn self;\n}\n@end\n", inits.String()))
+}
+
+// annotations write the annotations class of contract(with methods and params) or table(with fields),
+// the class is named as {Name}Annotations and only contains the annotated nodes, the shared instance holds the values
+func (codegen *_CodeGen) annotations(typeDecl ast.TypeDecl) {
+
+	root := &_AnnotationNode{node: typeDecl}
+
+	switch typeDecl.(type) {
+	case *ast.Contract:
+		for _, method := range typeDecl.(*ast.Contract).Methods {
+
+			child := &_AnnotationNode{name: method.Name(), node: method}
+
+			for _, param := range method.Params {
+				child.children = append(child.children, &_AnnotationNode{name: param.Name(), node: param})
+			}
+
+			root.children = append(root.children, child)
+		}
+
+	case *ast.Table:
+		for _, field := range typeDecl.(*ast.Table).Fields {
+			root.children = append(root.children, &_AnnotationNode{name: field.Name(), node: field})
+		}
+	}
+
+	if !root.hasAnnotations() {
+		return
+	}
+
+	codegen.annotationClass(&codegen.header, &codegen.source, codegen.title(typeDecl)+"Annotations", root, true)
 }
 
 func propertyAttr(typeDecl ast.Type) string {
@@ -370,6 +1084,10 @@ func (codegen *_CodeGen) enumFields(enum *ast.Enum) string {
 	var buff bytes.Buffer
 
 	for _, v := range enum.Constants {
+		if comment := doc("\t", v); comment != "" {
+			buff.WriteString("\n" + strings.TrimSuffix(comment, "\n"))
+		}
+
 		buff.WriteString(fmt.Sprintf("\n\t%s = %d,", codegen.title(enum)+strings.Title(v.Name()), v.Value))
 	}
 
@@ -412,6 +1130,10 @@ func (codegen *_CodeGen) typeName(typeDecl ast.Type) string {
 			return "NSMutableData *"
 		}
 
+		if _, ok := wire.MapEntry(seq); ok {
+			return "NSMutableDictionary *"
+		}
+
 		return "NSMutableArray *"
 	}
 
@@ -456,6 +1178,10 @@ func (codegen *_CodeGen) defaultVal(typeDecl ast.Type) string {
 			return "[[NSMutableData alloc] init]"
 		}
 
+		if _, ok := wire.MapEntry(seq); ok {
+			return "[NSMutableDictionary dictionary]"
+		}
+
 		return "[NSMutableArray arrayWithCapacity: 0]"
 	}
 
@@ -511,6 +1237,35 @@ func (codegen *_CodeGen) marshal(varname string, typeDecl ast.Type, indent int)
 			break
 		}
 
+		if entry, ok := wire.MapEntry(seq); ok {
+
+			key, val := entry.Fields[0].Type, entry.Fields[1].Type
+
+			stream.WriteString(fmt.Sprintf("[writer WriteUInt16:%s.count];\n", varname))
+
+			writeindent(&stream, indent)
+
+			stream.WriteString(fmt.Sprintf("for(id k%d in [GSMapKeys Sorted:%s]){\n", indent, varname))
+
+			writeindent(&stream, indent+1)
+
+			stream.WriteString(fmt.Sprintf("%s kk%d = %s;\n", codegen.typeName(key), indent, codegen.toComponentType(fmt.Sprintf("k%d", indent), key)))
+
+			stream.WriteString(codegen.marshal(fmt.Sprintf("kk%d", indent), key, indent+1))
+
+			writeindent(&stream, indent+1)
+
+			stream.WriteString(fmt.Sprintf("%s vv%d = %s;\n", codegen.typeName(val), indent, codegen.toComponentType(fmt.Sprintf("%s[k%d]", varname, indent), val)))
+
+			stream.WriteString(codegen.marshal(fmt.Sprintf("vv%d", indent), val, indent+1))
+
+			writeindent(&stream, indent)
+
+			stream.WriteRune('}')
+
+			break
+		}
+
 		if seq.Size == -1 {
 
 			stream.WriteString(fmt.Sprintf("[writer WriteUInt16:%s.count];\n", varname))
@@ -556,7 +1311,11 @@ func (codegen *_CodeGen) toComponentType(varname string, typeDecl ast.Type) stri
 	case *ast.BuiltinType:
 		builtinType := typeDecl.(*ast.BuiltinType)
 		return fmt.Sprintf(id2Type[builtinType.Type], varname)
-	case *ast.TypeRef, *ast.Table, *ast.Enum, *ast.Seq:
+	case *ast.TypeRef:
+		return codegen.toComponentType(varname, typeDecl.(*ast.TypeRef).Ref)
+	case *ast.Enum:
+		return fmt.Sprintf("(%s)((NSNumber*)%s).unsignedIntValue", codegen.typeName(typeDecl), varname)
+	case *ast.Table, *ast.Seq:
 		return fmt.Sprintf("(%s)%s", codegen.typeName(typeDecl), varname)
 	}
 
@@ -568,7 +1327,11 @@ func (codegen *_CodeGen) fromComponentType(varname string, typeDecl ast.Type) st
 	case *ast.BuiltinType:
 		builtinType := typeDecl.(*ast.BuiltinType)
 		return fmt.Sprintf(type2id[builtinType.Type], varname)
-	case *ast.TypeRef, *ast.Table, *ast.Enum, *ast.Seq:
+	case *ast.TypeRef:
+		return codegen.fromComponentType(varname, typeDecl.(*ast.TypeRef).Ref)
+	case *ast.Enum:
+		return fmt.Sprintf("[[NSNumber alloc] initWithUnsignedInt:%s]", varname)
+	case *ast.Table, *ast.Seq:
 		return varname
 	}
 
@@ -613,6 +1376,41 @@ func (codegen *_CodeGen) unmarshal(varname string, typeDecl ast.Type, indent int
 
 		writeindent(&stream, indent)
 
+		if entry, ok := wire.MapEntry(seq); ok {
+
+			key, val := entry.Fields[0].Type, entry.Fields[1].Type
+
+			stream.WriteString(fmt.Sprintf("UInt16 imax%d = [reader ReadUInt16];\n\n", indent))
+
+			writeindent(&stream, indent)
+
+			stream.WriteString(fmt.Sprintf("for(UInt16 i%d = 0; i%d < imax%d; i%d ++ ){\n\n", indent, indent, indent, indent))
+
+			writeindent(&stream, indent+1)
+
+			stream.WriteString(fmt.Sprintf("%s k%d = %s;\n\n", codegen.typeName(key), indent, codegen.defaultVal(key)))
+
+			stream.WriteString(codegen.unmarshal(fmt.Sprintf("k%d", indent), key, indent+1))
+
+			writeindent(&stream, indent+1)
+
+			stream.WriteString(fmt.Sprintf("%s v%d = %s;\n\n", codegen.typeName(val), indent, codegen.defaultVal(val)))
+
+			stream.WriteString(codegen.unmarshal(fmt.Sprintf("v%d", indent), val, indent+1))
+
+			stream.WriteRune('\n')
+
+			writeindent(&stream, indent+1)
+
+			stream.WriteString(fmt.Sprintf("[ %s setObject: %s forKey: %s];\n\n", varname, codegen.fromComponentType(fmt.Sprintf("v%d", indent), val), codegen.fromComponentType(fmt.Sprintf("k%d", indent), key)))
+
+			writeindent(&stream, indent)
+
+			stream.WriteRune('}')
+
+			break
+		}
+
 		if seq.Size == -1 {
 
 			if isbytes {
@@ -679,11 +1477,41 @@ func (codegen *_CodeGen) unmarshal(varname string, typeDecl ast.Type, indent int
 }
 
 func (codegen *_CodeGen) marshalField(field *ast.Field) string {
-	return codegen.marshal("_"+strings.Title(field.Name()), field.Type, 1)
+	varname := "_" + strings.Title(field.Name())
+
+	if isBoxed(field) {
+		var stream bytes.Buffer
+
+		stream.WriteString(fmt.Sprintf("%s val = %s;\n", codegen.typeName(field.Type), codegen.toComponentType(varname, field.Type)))
+
+		stream.WriteString(codegen.marshal("val", field.Type, 1))
+
+		return stream.String()
+	}
+
+	return codegen.marshal(varname, field.Type, 1)
 }
 
 func (codegen *_CodeGen) unmarshalField(field *ast.Field) string {
-	return codegen.unmarshal("_"+strings.Title(field.Name()), field.Type, 1)
+	varname := "_" + strings.Title(field.Name())
+
+	if isBoxed(field) {
+		var stream bytes.Buffer
+
+		stream.WriteString(fmt.Sprintf("%s val = %s;\n", codegen.typeName(field.Type), codegen.defaultVal(field.Type)))
+
+		stream.WriteString(codegen.unmarshal("val", field.Type, 1))
+
+		stream.WriteString(fmt.Sprintf("\n%s = %s;", varname, codegen.fromComponentType("val", field.Type)))
+
+		return stream.String()
+	}
+
+	if isOptional(field) {
+		return fmt.Sprintf("%s = %s;\n%s", varname, codegen.defaultVal(field.Type), codegen.unmarshal(varname, field.Type, 1))
+	}
+
+	return codegen.unmarshal(varname, field.Type, 1)
 }
 
 func (codegen *_CodeGen) methodDecl(method *ast.Method) string {
@@ -922,17 +1750,42 @@ func (codegen *_CodeGen) Table(compiler *gslang.Compiler, tableType *ast.Table)
 		gserrors.Panicf(err, "exec template(table_predecl) for %s error", tableType)
 	}
 
-	if err := codegen.tpl.ExecuteTemplate(&codegen.header, "table_header", tableType); err != nil {
+	prefix := "table"
+
+	if isOneOf(tableType) {
+		prefix = "union"
+	}
+
+	if err := codegen.tpl.ExecuteTemplate(&codegen.header, prefix+"_header", tableType); err != nil {
 
-		gserrors.Panicf(err, "exec template(table) for %s error", tableType)
+		gserrors.Panicf(err, "exec template(%s) for %s error", prefix, tableType)
 	}
 
-	if err := codegen.tpl.ExecuteTemplate(&codegen.source, "table_source", tableType); err != nil {
-		gserrors.Panicf(err, "exec template(table) for %s error", tableType)
+	if err := codegen.tpl.ExecuteTemplate(&codegen.source, prefix+"_source", tableType); err != nil {
+		gserrors.Panicf(err, "exec template(%s) for %s error", prefix, tableType)
 	}
+
+	codegen.annotations(tableType)
 }
 
 func (codegen *_CodeGen) Annotation(compiler *gslang.Compiler, annotation *ast.Table) {
+
+	// the com.gsrpc annotations are only consumed by code generator
+	if codegen.script.Package == "com.gsrpc" {
+		return
+	}
+
+	if err := codegen.tpl.ExecuteTemplate(&codegen.predecl, "table_predecl", annotation); err != nil {
+		gserrors.Panicf(err, "exec template(table_predecl) for %s error", annotation)
+	}
+
+	if err := codegen.tpl.ExecuteTemplate(&codegen.header, "table_header", annotation); err != nil {
+		gserrors.Panicf(err, "exec template(table_header) for %s error", annotation)
+	}
+
+	if err := codegen.tpl.ExecuteTemplate(&codegen.source, "table_source", annotation); err != nil {
+		gserrors.Panicf(err, "exec template(table_source) for %s error", annotation)
+	}
 }
 
 func (codegen *_CodeGen) Enum(compiler *gslang.Compiler, enum *ast.Enum) {
@@ -957,12 +1810,20 @@ func (codegen *_CodeGen) Contract(compiler *gslang.Compiler, contract *ast.Contr
 	if err := codegen.tpl.ExecuteTemplate(&codegen.source, "contract_source", contract); err != nil {
 		gserrors.Panicf(err, "exec template(Contract) for %s error", contract)
 	}
+
+	codegen.annotations(contract)
 }
 
 func (codegen *_CodeGen) writefile(bytes []byte, extend string) {
 
 	path := strings.Replace(codegen.script.Package, ".", "/", -1)
 
+	// the content buffer may be reused by caller
+	if codegen.files != nil {
+		codegen.files[filepath.ToSlash(filepath.Join(path, filepath.Base(codegen.script.Name())+extend))] = append([]byte(nil), bytes...)
+		return
+	}
+
 	fullpath := filepath.Join(codegen.rootpath, path, filepath.Base(codegen.script.Name())+extend)
 
 	if err := os.MkdirAll(filepath.Dir(fullpath), 0755); err != nil {
@@ -977,6 +1838,18 @@ func (codegen *_CodeGen) writefile(bytes []byte, extend string) {
 // EndScript .
 func (codegen *_CodeGen) EndScript(compiler *gslang.Compiler) {
 
+	// the tagged value and map key order helpers are shipped with the com.gsrpc package
+	if codegen.script.Package == "com.gsrpc" {
+
+		if err := codegen.tpl.ExecuteTemplate(&codegen.header, "tagged_header", nil); err != nil {
+			gserrors.Panicf(err, "exec template(tagged_header) error")
+		}
+
+		if err := codegen.tpl.ExecuteTemplate(&codegen.source, "tagged_source", nil); err != nil {
+			gserrors.Panicf(err, "exec template(tagged_source) error")
+		}
+	}
+
 	var stream bytes.Buffer
 
 	guard := strings.ToUpper(strings.Replace(path.Join(codegen.script.Package, filepath.Base(codegen.script.Name())), ".", "_", -1))
@@ -1016,3 +1889,25 @@ func (codegen *_CodeGen) EndScript(compiler *gslang.Compiler) {
 
 	codegen.writefile(stream.Bytes(), ".m")
 }
+
+// doc get the doc comment from the .gs comments of node
+func doc(indent string, node ast.Node) string {
+
+	comments := gslang.Comments(node)
+
+	if len(comments) == 0 {
+		return ""
+	}
+
+	var buff bytes.Buffer
+
+	buff.WriteString(indent + "/**\n")
+
+	for _, line := range comments {
+		buff.WriteString(indent + " * " + strings.Replace(strings.TrimSpace(line), "*/", "*&#47;", -1) + "\n")
+	}
+
+	buff.WriteString(indent + " */\n")
+
+	return buff.String()
+}
@@ -9,13 +9,15 @@ typedef enum {{title .}}:{{enumType .}} {{title .}};
 {{define "enum_header"}}
 
 // {{title .}} enum
-enum {{title .}}:{{enumType .}}{ {{enumFields .}} };
+{{doc "" .}}enum {{title .}}:{{enumType .}}{ {{enumFields .}} };
 
 // {{title .}} enum marshal/unmarshal helper interface
 @interface {{title .}}Helper : NSObject
 + (void) marshal:({{title .}}) val withWriter:(id<GSWriter>) writer;
 + ({{title .}}) unmarshal:(id<GSReader>) reader;
 + (NSString*) tostring :({{title .}})val;
++ (NSString*) toJSON :({{title .}})val;
++ ({{title .}}) fromJSON :(NSString*)name;
 @end
 
 {{end}}
@@ -44,6 +46,84 @@ enum {{title .}}:{{enumType .}}{ {{enumFields .}} };
    }
 }
 
++ (NSString*) toJSON:({{title .}})val {
+    switch(val)
+    {
+    {{range .Constants}}
+    case {{$Enum}}{{title2 .Name}}:
+       return @"{{title2 $.Name}}.{{title2 .Name}}";
+    {{end}}
+    default:
+       break;
+   }
+   {{if isFlag .}}
+   NSMutableArray *names = [NSMutableArray array];
+
+   UInt32 rest = (UInt32)val;
+   {{range .Constants}}{{if ne .Value 0}}
+   if((val & {{$Enum}}{{title2 .Name}}) == {{$Enum}}{{title2 .Name}}) {
+       [names addObject:@"{{title2 .Name}}"];
+       rest &= ~(UInt32){{$Enum}}{{title2 .Name}};
+   }
+   {{end}}{{end}}
+   if(rest != 0) {
+       [names addObject:[NSString stringWithFormat:@"0x%x", (unsigned int)rest]];
+   }
+
+   if(names.count == 0) {
+       return @"0";
+   }
+
+   return [names componentsJoinedByString:@"|"];
+   {{else}}
+   return [NSString stringWithFormat:@"enum(Unknown(%u))", (unsigned int)val];
+   {{end}}
+}
+
++ ({{title .}}) fromJSON:(NSString*)name {
+    {{range .Constants}}
+    if([name isEqualToString:@"{{title2 $.Name}}.{{title2 .Name}}"] || [name isEqualToString:@"{{title2 .Name}}"]) {
+        return {{$Enum}}{{title2 .Name}};
+    }
+    {{end}}
+    {{if isFlag .}}
+    if([name rangeOfString:@"|"].location != NSNotFound) {
+        UInt32 val = 0;
+
+        for(NSString *part in [name componentsSeparatedByString:@"|"]) {
+            val |= (UInt32)[{{title .}}Helper fromJSON:[part stringByTrimmingCharactersInSet:[NSCharacterSet whitespaceCharacterSet]]];
+        }
+
+        return ({{title .}})val;
+    }
+
+    if([name isEqualToString:@"0"]) {
+        return ({{title .}})0;
+    }
+
+    unsigned int bits = 0;
+
+    NSScanner *scanner = [NSScanner scannerWithString:name];
+
+    if([name hasPrefix:@"0x"] && [scanner scanHexInt:&bits] && [scanner isAtEnd]) {
+        return ({{title .}})bits;
+    }
+    {{else}}
+    if([name hasPrefix:@"enum(Unknown("] && [name hasSuffix:@"))"]) {
+
+        long long val = 0;
+
+        NSScanner *scanner = [NSScanner scannerWithString:[name substringWithRange:NSMakeRange(13, name.length - 15)]];
+
+        if([scanner scanLongLong:&val] && [scanner isAtEnd] && val >= 0 && val <= UINT32_MAX) {
+            return ({{title .}})val;
+        }
+    }
+    {{end}}
+    [NSException raise:@"GSJSONException" format:@"unknown enum {{title2 .Name}} constant :%@", name];
+    return ({{title .}})0;
+}
+
 @end
 
 {{end}}
@@ -54,13 +134,15 @@ enum {{title .}}:{{enumType .}}{ {{enumFields .}} };
 
 {{define "table_header"}}
 
-@interface {{title .}} : NSObject
+{{doc "" .}}@interface {{title .}} : NSObject
 {{range .Fields}}
-{{fieldDecl .}}
+{{doc "" .}}{{fieldDecl .}}
 {{end}}
 + (instancetype)init;
 - (void) marshal:(id<GSWriter>) writer;
 - (void) unmarshal:(id<GSReader>) reader;
+- (NSDictionary*) toJSON;
+- (void) fromJSON:(NSDictionary*) json;
 {{if isException .}}
 - (NSError*) asNSError;
 {{end}}
@@ -76,45 +158,96 @@ enum {{title .}}:{{enumType .}}{ {{enumFields .}} };
 - (instancetype)init{
     if (self = [super init]){
         {{range .Fields}}
-        _{{title2 .Name}} = {{defaultVal .Type}};
+        _{{title2 .Name}} = {{fieldDefault .}};
         {{end}}
     }
     return self;
 }
 
+- (NSDictionary*) toJSON {
+    NSMutableDictionary *json = [NSMutableDictionary dictionary];
+{{range .Fields}}
+    {{if isOptional .}}if(_{{title2 .Name}} != nil) {{end}}{
+        id value = nil;
+{{fieldToJSON .}}
+        json[@"{{title2 .Name}}"] = value;
+    }
+{{end}}
+    return json;
+}
+
+- (void) fromJSON:(NSDictionary*) json {
+{{range .Fields}}
+    {
+        id value = json[@"{{title2 .Name}}"];
+        if(value != nil) {
+{{fieldFromJSON .}}
+        }
+    }
+{{end}}
+}
+
 {{if isPOD .}}
 
 - (void) marshal:(id<GSWriter>) writer {
 {{range .Fields}}
+{{if isOptional .}}
+    [writer WriteBool :(_{{title2 .Name}} != nil)];
+    if(_{{title2 .Name}} != nil) {
+{{marshalField .}}
+    }
+{{else}}
 {{marshalField .}}
 {{end}}
+{{end}}
 }
 
 - (void) unmarshal:(id<GSReader>) reader {
 {{range .Fields}}
     {
+        {{if isOptional .}}
+        if([reader ReadBool]) {
         {{unmarshalField .}}
+        }
+        {{else}}
+        {{unmarshalField .}}
+        {{end}}
     }
 {{end}}
 }
 
 {{else}}
 - (void) marshal:(id<GSWriter>) writer {
-    [writer WriteByte :(UInt8){{len .Fields}}];
+    [writer WriteUInt16 :(UInt16){{len .Fields}}];
 {{range .Fields}}
-    [writer WriteByte :(UInt8){{tagValue .Type}}];
+{{if isOptional .}}
+    if(_{{title2 .Name}} == nil) {
+        [writer WriteByte :(UInt8)GSTagSkip];
+    } else {
+    {{range tags .Type}}[writer WriteByte :(UInt8){{.}}];
+    {{end}}
+{{marshalField .}}
+    }
+{{else}}
+    {{range tags .Type}}[writer WriteByte :(UInt8){{.}}];
+    {{end}}
 {{marshalField .}}
 {{end}}
+{{end}}
 }
 - (void) unmarshal:(id<GSReader>) reader {
 
-    UInt8 __fields = [reader ReadByte];
+    UInt16 __fields = [reader ReadUInt16];
+
+    if(__fields == 0) {
+        return;
+    }
 
 {{range .Fields}}
     {
-        UInt8 tag = [reader ReadByte];
+        NSData *tag = [GSTagged ReadTags:reader];
 
-        if(tag != GSTagSkip) {
+        if(((const UInt8*)tag.bytes)[0] != GSTagSkip) {
         {{unmarshalField .}}
         }
 
@@ -125,13 +258,13 @@ enum {{title .}}:{{enumType .}}{ {{enumFields .}} };
 {{end}}
 
     for(int i = 0; i < (int)__fields; i ++) {
-        UInt8 tag = [reader ReadByte];
+        NSData *tag = [GSTagged ReadTags:reader];
 
-        if (tag == GSTagSkip) {
+        if (((const UInt8*)tag.bytes)[0] == GSTagSkip) {
             continue;
         }
 
-        [reader ReadSkip:tag];
+        [GSTagged Skip:tag withReader:reader];
     }
 }
 {{end}}
@@ -151,12 +284,150 @@ enum {{title .}}:{{enumType .}}{ {{enumFields .}} };
 @end
 {{end}}
 
+{{define "union_header"}}{{$Union := title .}}
+
+// {{$Union}} union variants
+enum {{$Union}}Variant:UInt8{ {{$Union}}None{{range .Fields}}, {{$Union}}{{title2 .Name}}{{end}} };
+
+typedef enum {{$Union}}Variant {{$Union}}Variant;
+
+// {{$Union}} union, setting one variant property clear the others
+{{doc "" .}}@interface {{$Union}} : NSObject
+@property(readonly) {{$Union}}Variant Variant;
+{{range .Fields}}
+{{doc "" .}}{{fieldDecl .}}
+{{end}}
++ (instancetype)init;
+- (void) marshal:(id<GSWriter>) writer;
+- (void) unmarshal:(id<GSReader>) reader;
+- (id) toJSON;
+- (void) fromJSON:(id) json;
+@end
+
+{{end}}
+
+{{define "union_source"}}{{$Union := title .}}
+@implementation {{$Union}} {
+    id _value;
+}
++ (instancetype)init {
+    return [[{{$Union}} alloc] init];
+}
+- (instancetype)init{
+    if (self = [super init]){
+        _Variant = {{$Union}}None;
+        _value = nil;
+    }
+    return self;
+}
+{{range .Fields}}
+- ({{typeName .Type}}) {{title2 .Name}} {
+    if(_Variant != {{$Union}}{{title2 .Name}}) {
+        return {{variantDefault .}};
+    }
+    return {{variantGet .}};
+}
+- (void) set{{title2 .Name}}:({{typeName .Type}}) val {
+    _Variant = {{$Union}}{{title2 .Name}};
+    _value = {{variantSet .}};
+}
+{{end}}
+
+- (id) toJSON {
+    id value = nil;
+
+    switch(_Variant) {
+{{range .Fields}}
+    case {{$Union}}{{title2 .Name}}: {
+        {{typeName .Type}} val = {{variantGet .}};
+{{toJSON "value" "val" .Type 2}}
+        return @{ @"{{title2 .Name}}" : value };
+    }
+{{end}}
+    default:
+        return [NSNull null];
+    }
+}
+
+- (void) fromJSON:(id) json {
+
+    _Variant = {{$Union}}None;
+
+    _value = nil;
+
+    if(json == [NSNull null]) {
+        return;
+    }
+
+    NSDictionary *object = (NSDictionary*)json;
+
+    if(object.count != 1) {
+        [NSException raise:@"GSJSONException" format:@"expect one {{$Union}} variant, got %lu", (unsigned long)object.count];
+    }
+{{range .Fields}}
+    if(object[@"{{title2 .Name}}"] != nil) {
+        {{typeName .Type}} val = {{defaultVal .Type}};
+{{fromJSON "val" (printf "object[@\"%s\"]" (title2 .Name)) .Type 2}}
+        [self set{{title2 .Name}}:val];
+        return;
+    }
+{{end}}
+    [NSException raise:@"GSJSONException" format:@"unknown {{$Union}} variant :%@", object.allKeys.firstObject];
+}
+
+- (void) marshal:(id<GSWriter>) writer {
+    [writer WriteByte :(UInt8)_Variant];
+
+    switch(_Variant) {
+{{range .Fields}}
+    case {{$Union}}{{title2 .Name}}: {
+        {{range tags .Type}}[writer WriteByte :(UInt8){{.}}];
+        {{end}}
+{{marshalVariant .}}
+        break;
+    }
+{{end}}
+    default:
+        break;
+    }
+}
+
+- (void) unmarshal:(id<GSReader>) reader {
+
+    _Variant = {{$Union}}None;
+
+    _value = nil;
+
+    UInt8 variant = [reader ReadByte];
+
+    if(variant == 0) {
+        return;
+    }
+
+    NSData *tag = [GSTagged ReadTags:reader];
+
+    switch(variant) {
+{{range $index,$field := .Fields}}
+    case {{variant $index}}: {
+{{unmarshalVariant .}}
+        [self set{{title2 .Name}}:val];
+        break;
+    }
+{{end}}
+    default:
+        [GSTagged Skip:tag withReader:reader];
+    }
+}
+
+@end
+{{end}}
+
 {{define "contract_header"}}
 
 //{{title .}} generate by objrpc
-@protocol {{title .}}<NSObject>
+{{doc "" .}}@protocol {{title .}}<NSObject>
 {{range .Methods}}
-{{methodDecl .}};
+{{doc "" .}}{{methodDecl .}};
 {{end}}
 @end
 
@@ -252,6 +523,19 @@ enum {{title .}}:{{enumType .}}{ {{enumFields .}} };
 
         if(response.Exception != (SInt8)-1) {
             switch(response.Exception){
+                case -2:{
+					GSInvalidArgument* callreturn = [[GSInvalidArgument alloc] init];
+
+					{
+
+						GSBytesReader *reader = [GSBytesReader initWithNSData: response.Content];
+
+						[callreturn unmarshal:reader ];
+					}
+
+                    *error = [callreturn asNSError];
+                    break;
+                    }
             {{range .Exceptions}}
                 case {{.ID}}:{
 {{unmarshalReturn .Type 5}}
@@ -282,4 +566,179 @@ enum {{title .}}:{{enumType .}}{ {{enumFields .}} };
 {{end}}
 
 
+
+{{define "tagged_header"}}
+
+// GSTagged the tagged value helpers, the nested container and POD tags are followed by their component tags
+@interface GSTagged : NSObject
++ (NSData*) ReadTags:(id<GSReader>) reader;
++ (void) Skip:(NSData*) tags withReader:(id<GSReader>) reader;
+@end
+
+// GSMapKeys the canonical map key order, numbers are compared by value with the declared signedness
+// and strings by UTF-8 bytes, the map entries are encoded in this order
+@interface GSMapKeys : NSObject
++ (NSArray*) Sorted:(NSDictionary*) dict;
+@end
+
+{{end}}
+
+{{define "tagged_source"}}
+@implementation GSTagged
+
++ (void) ReadTags:(id<GSReader>) reader into:(NSMutableData*) tags {
+
+    UInt8 tag = [reader ReadByte];
+
+    [tags appendBytes:&tag length:1];
+
+    int nested = 0;
+
+    switch(tag) {
+{{range wireNested}}{{range .Tags}}
+    case GSTag{{.}}:{{end}}
+        {{if lt .Nested 0}}
+        {
+            UInt8 count = [reader ReadByte];
+
+            [tags appendBytes:&count length:1];
+
+            nested = count;
+        }
+        {{else}}
+        nested = {{.Nested}};
+        {{end}}
+        break;
+{{end}}
+    default:
+        break;
+    }
+
+    for(int i = 0; i < nested; i ++) {
+        [GSTagged ReadTags:reader into:tags];
+    }
+}
+
++ (NSData*) ReadTags:(id<GSReader>) reader {
+
+    NSMutableData *tags = [NSMutableData data];
+
+    [GSTagged ReadTags:reader into:tags];
+
+    return tags;
+}
+
+// tagsEnd get the index next to the tag sequence begin with index
++ (NSUInteger) tagsEnd:(const UInt8*) tags at:(NSUInteger) index {
+
+    NSUInteger next = index + 1;
+
+    int nested = 0;
+
+    switch(tags[index]) {
+{{range wireNested}}{{range .Tags}}
+    case GSTag{{.}}:{{end}}
+        {{if lt .Nested 0}}
+        next = index + 2;
+        nested = tags[index + 1];
+        {{else}}
+        nested = {{.Nested}};
+        {{end}}
+        break;
+{{end}}
+    default:
+        break;
+    }
+
+    for(int i = 0; i < nested; i ++) {
+        next = [GSTagged tagsEnd:tags at:next];
+    }
+
+    return next;
+}
+
+// skipComponents skip the values of count tag sequences begin with index
++ (void) skipComponents:(const UInt8*) tags at:(NSUInteger) index count:(int) count withReader:(id<GSReader>) reader {
+
+    for(int i = 0; i < count; i ++) {
+        [GSTagged skip:tags at:index withReader:reader];
+        index = [GSTagged tagsEnd:tags at:index];
+    }
+}
+
++ (void) skip:(const UInt8*) tags at:(NSUInteger) index withReader:(id<GSReader>) reader {
+
+    switch(tags[index]) {
+{{range wireCases}}{{range .Tags}}
+    case GSTag{{.}}:{{end}}
+    {
+        {{if eq .Kind "fixed"}}
+        for(int i = 0; i < {{.Size}}; i ++) {
+            [reader ReadByte];
+        }
+        {{else if eq .Kind "sized"}}
+        [reader ReadBytes];
+        {{else if eq .Kind "fields"}}
+        UInt16 fields = [reader ReadUInt16];
+
+        for(int i = 0; i < (int)fields; i ++) {
+            [GSTagged Skip:[GSTagged ReadTags:reader] withReader:reader];
+        }
+        {{else if eq .Kind "seq"}}
+        UInt16 length = [reader ReadUInt16];
+
+        for(int i = 0; i < (int)length; i ++) {
+            [GSTagged skipComponents:tags at:index + 1 count:{{.Nested}} withReader:reader];
+        }
+        {{else if eq .Kind "record"}}
+        [GSTagged skipComponents:tags at:index + 2 count:tags[index + 1] withReader:reader];
+        {{else if eq .Kind "optional"}}
+        if([reader ReadBool]) {
+            [GSTagged skip:tags at:index + 1 withReader:reader];
+        }
+        {{else if eq .Kind "variant"}}
+        if([reader ReadByte] != 0) {
+            [GSTagged Skip:[GSTagged ReadTags:reader] withReader:reader];
+        }
+        {{end}}
+        break;
+    }
+{{end}}
+    default:
+        [NSException raise:@"GSTagException" format:@"unknown tag :%d", tags[index]];
+    }
+}
+
++ (void) Skip:(NSData*) tags withReader:(id<GSReader>) reader {
+    [GSTagged skip:(const UInt8*)tags.bytes at:0 withReader:reader];
+}
+
+@end
+
+@implementation GSMapKeys
+
++ (NSArray*) Sorted:(NSDictionary*) dict {
+
+    return [[dict allKeys] sortedArrayUsingComparator:^NSComparisonResult(id lhs, id rhs) {
+
+        if(![lhs isKindOfClass:[NSString class]]) {
+            return [lhs compare:rhs];
+        }
+
+        NSData *l = [lhs dataUsingEncoding:NSUTF8StringEncoding];
+
+        NSData *r = [rhs dataUsingEncoding:NSUTF8StringEncoding];
+
+        int result = memcmp(l.bytes, r.bytes, MIN(l.length, r.length));
+
+        if(result == 0) {
+            result = l.length < r.length ? -1 : (l.length > r.length ? 1 : 0);
+        }
+
+        return result < 0 ? NSOrderedAscending : (result > 0 ? NSOrderedDescending : NSOrderedSame);
+    }];
+}
+
+@end
+{{end}}
 `
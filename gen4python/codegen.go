@@ -0,0 +1,811 @@
+package gen4python
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/gsdocker/gserrors"
+	"github.com/gsdocker/gslogger"
+	"github.com/gsrpc/gslang"
+	"github.com/gsrpc/gslang/ast"
+	"github.com/gsrpc/gslang/lexer"
+	"github.com/gsrpc/gsrpc/gen"
+	"github.com/gsrpc/gsrpc/wire"
+)
+
+var builtin = map[lexer.TokenType]string{
+	lexer.KeySByte:   "int",
+	lexer.KeyByte:    "int",
+	lexer.KeyInt16:   "int",
+	lexer.KeyUInt16:  "int",
+	lexer.KeyInt32:   "int",
+	lexer.KeyUInt32:  "int",
+	lexer.KeyInt64:   "int",
+	lexer.KeyUInt64:  "int",
+	lexer.KeyFloat32: "float",
+	lexer.KeyFloat64: "float",
+	lexer.KeyBool:    "bool",
+	lexer.KeyString:  "str",
+	lexer.KeyVoid:    "None",
+}
+
+var readMapping = map[lexer.TokenType]string{
+	lexer.KeySByte:   "reader.read_sbyte()",
+	lexer.KeyByte:    "reader.read_byte()",
+	lexer.KeyInt16:   "reader.read_int16()",
+	lexer.KeyUInt16:  "reader.read_uint16()",
+	lexer.KeyInt32:   "reader.read_int32()",
+	lexer.KeyUInt32:  "reader.read_uint32()",
+	lexer.KeyInt64:   "reader.read_int64()",
+	lexer.KeyUInt64:  "reader.read_uint64()",
+	lexer.KeyFloat32: "reader.read_float32()",
+	lexer.KeyFloat64: "reader.read_float64()",
+	lexer.KeyBool:    "reader.read_bool()",
+	lexer.KeyString:  "reader.read_string()",
+}
+
+var writeMapping = map[lexer.TokenType]string{
+	lexer.KeySByte:   "writer.write_sbyte",
+	lexer.KeyByte:    "writer.write_byte",
+	lexer.KeyInt16:   "writer.write_int16",
+	lexer.KeyUInt16:  "writer.write_uint16",
+	lexer.KeyInt32:   "writer.write_int32",
+	lexer.KeyUInt32:  "writer.write_uint32",
+	lexer.KeyInt64:   "writer.write_int64",
+	lexer.KeyUInt64:  "writer.write_uint64",
+	lexer.KeyFloat32: "writer.write_float32",
+	lexer.KeyFloat64: "writer.write_float64",
+	lexer.KeyBool:    "writer.write_bool",
+	lexer.KeyString:  "writer.write_string",
+}
+
+var defaultval = map[lexer.TokenType]string{
+	lexer.KeySByte:   "0",
+	lexer.KeyByte:    "0",
+	lexer.KeyInt16:   "0",
+	lexer.KeyUInt16:  "0",
+	lexer.KeyInt32:   "0",
+	lexer.KeyUInt32:  "0",
+	lexer.KeyInt64:   "0",
+	lexer.KeyUInt64:  "0",
+	lexer.KeyFloat32: "0.0",
+	lexer.KeyFloat64: "0.0",
+	lexer.KeyBool:    "False",
+	lexer.KeyString:  "\"\"",
+}
+
+// keywords the python reserved words, which are escaped with _ suffix when used as names
+var keywords = map[string]bool{
+	"and": true, "as": true, "assert": true, "async": true, "await": true, "break": true, "class": true,
+	"continue": true, "def": true, "del": true, "elif": true, "else": true, "except": true, "finally": true,
+	"for": true, "from": true, "global": true, "if": true, "import": true, "in": true, "is": true,
+	"lambda": true, "nonlocal": true, "not": true, "or": true, "pass": true, "raise": true, "return": true,
+	"try": true, "while": true, "with": true, "yield": true, "None": true, "True": true, "False": true,
+}
+
+// runtimeModule the runtime module name, which is generated in the root path
+const runtimeModule = "gsrpc"
+
+// _Module the generated module of one gslang package, scripts of the same package share the module
+type _Module struct {
+	imports map[string]string // import alias -> package name
+	content bytes.Buffer      // module content
+}
+
+type _CodeGen struct {
+	gslogger.Log                     // Log APIs
+	rootpath     string              // root path
+	files        map[string][]byte   // generated files sink, the files are written into rootpath if nil
+	script       *ast.Script         // current script
+	module       *_Module            // current package module
+	modules      map[string]*_Module // generated package modules
+	tpl          *template.Template  // code generate template
+	skips        []*regexp.Regexp    // skip lists
+	compiler     *gslang.Compiler    // current compiler
+	runtime      bool                // runtime module is generated
+}
+
+// NewCodeGen .
+func NewCodeGen(rootpath string, skips []string) (gslang.Visitor, error) {
+
+	codeGen := &_CodeGen{
+		Log:      gslogger.Get("gen4python"),
+		rootpath: rootpath,
+		modules:  make(map[string]*_Module),
+	}
+
+	for _, skip := range skips {
+		exp, err := regexp.Compile(skip)
+
+		if err != nil {
+			return nil, gserrors.Newf(err, "invalid skip regex string :%s", skip)
+		}
+
+		codeGen.skips = append(codeGen.skips, exp)
+	}
+
+	funcs := template.FuncMap{
+		"title":       strings.Title,
+		"tableName":   gen.TableName,
+		"snakeName":   gen.SnakeName,
+		"fieldName":   fieldName,
+		"constName":   constName,
+		"doc":         doc,
+		"comment":     comment,
+		"notVoid":     gslang.NotVoid,
+		"isPOD":       gslang.IsPOD,
+		"isAsync":     gslang.IsAsync,
+		"isException": gslang.IsException,
+		"enumSize":    gslang.EnumSize,
+		"isOptional":  gen.IsOptional,
+		"variant":     gen.Variant,
+		"typeName":    codeGen.typeName,
+		"fieldType":   codeGen.fieldType,
+		"readType":    codeGen.readType,
+		"writeType":   codeGen.writeType,
+		"tagValue":    codeGen.tagValue,
+		"wireCases":   wire.Cases,
+		"wireNested":  wire.Nested,
+		"rpcName":     codeGen.rpcName,
+		"fieldInit":   codeGen.fieldInit,
+		"params":      codeGen.params,
+		"returnType":  codeGen.returnType,
+		"callArgs":    callArgs,
+		"runtime":     func() string { return runtimeModule },
+	}
+
+	tpl, err := template.New("t4python").Funcs(funcs).Parse(t4python)
+
+	if err != nil {
+		return nil, err
+	}
+
+	codeGen.tpl = tpl
+
+	return codeGen, nil
+}
+
+// NewCodeGenWithFiles create codegen which write generated files into files map keyed by slash separated relative path
+func NewCodeGenWithFiles(files map[string][]byte, skips []string) (gslang.Visitor, error) {
+
+	codeGen, err := NewCodeGen("", skips)
+
+	if err != nil {
+		return nil, err
+	}
+
+	codeGen.(*_CodeGen).files = files
+
+	return codeGen, nil
+}
+
+// fieldName get the snake case attribute/param name, python keyword is escaped with _ suffix
+func fieldName(name string) string {
+
+	name = gen.SnakeName(name)
+
+	if keywords[name] {
+		return name + "_"
+	}
+
+	return name
+}
+
+// constName get the enum constant name, python keyword is escaped with _ suffix, e.g. None -> None_
+func constName(name string) string {
+
+	name = strings.Title(name)
+
+	if keywords[name] {
+		return name + "_"
+	}
+
+	return name
+}
+
+// moduleAlias get the import alias of package module
+func moduleAlias(packageName string) string {
+	return strings.Replace(packageName, ".", "_", -1)
+}
+
+// modulePath get the package module file path relative to root path
+func modulePath(packageName string) string {
+	return filepath.Join(strings.Replace(packageName, ".", "/", -1), "__init__.py")
+}
+
+// qualify get the symbol reference name, symbols of other packages are referenced by the package module alias
+func (codegen *_CodeGen) qualify(packageName string, name string) string {
+
+	if packageName == codegen.script.Package {
+		return name
+	}
+
+	alias := moduleAlias(packageName)
+
+	codegen.module.imports[alias] = packageName
+
+	return alias + "." + name
+}
+
+// rpcName get the reference name of com.gsrpc package symbol
+func (codegen *_CodeGen) rpcName(name string) string {
+	return codegen.qualify("com.gsrpc", name)
+}
+
+func (codegen *_CodeGen) tagValue(typeDecl ast.Type) string {
+	return strings.Join(codegen.tags(typeDecl), ", ")
+}
+
+// tags get the type tag sequence by the shared tag scheme
+func (codegen *_CodeGen) tags(typeDecl ast.Type) []string {
+
+	tag := codegen.rpcName("Tag")
+
+	return wire.Format(wire.Tags(typeDecl), func(t wire.Tag) string {
+		return tag + "." + t.String()
+	})
+}
+
+func (codegen *_CodeGen) typeName(typeDecl ast.Type) string {
+	switch typeDecl.(type) {
+	case *ast.BuiltinType:
+		builtinType := typeDecl.(*ast.BuiltinType)
+
+		return builtin[builtinType.Type]
+	case *ast.TypeRef:
+		return codegen.typeName(typeDecl.(*ast.TypeRef).Ref)
+
+	case *ast.Enum:
+		return codegen.qualify(typeDecl.Package(), strings.Title(typeDecl.Name()))
+
+	case *ast.Table:
+		name := codegen.qualify(typeDecl.Package(), gen.TableName(typeDecl))
+
+		if gen.IsOneOf(typeDecl) {
+			return fmt.Sprintf("Optional[%s]", name)
+		}
+
+		return name
+
+	case *ast.Seq:
+		seq := typeDecl.(*ast.Seq)
+
+		if gen.IsBytes(seq) {
+			return "bytes"
+		}
+
+		if entry, ok := wire.MapEntry(seq); ok {
+			return fmt.Sprintf("Dict[%s, %s]", codegen.typeName(entry.Fields[0].Type), codegen.typeName(entry.Fields[1].Type))
+		}
+
+		return fmt.Sprintf("List[%s]", codegen.typeName(seq.Component))
+	}
+
+	gserrors.Panicf(nil, "typeName  error: unsupport type(%s)", typeDecl)
+
+	return "unknown"
+}
+
+// fieldType get the field type hint, optional field is Optional
+func (codegen *_CodeGen) fieldType(field *ast.Field) string {
+
+	name := codegen.typeName(field.Type)
+
+	if gen.IsOptional(field) && !strings.HasPrefix(name, "Optional[") {
+		return fmt.Sprintf("Optional[%s]", name)
+	}
+
+	return name
+}
+
+func (codegen *_CodeGen) defaultVal(typeDecl ast.Type) string {
+	switch typeDecl.(type) {
+	case *ast.BuiltinType:
+		builtinType := typeDecl.(*ast.BuiltinType)
+
+		return defaultval[builtinType.Type]
+	case *ast.TypeRef:
+		return codegen.defaultVal(typeDecl.(*ast.TypeRef).Ref)
+
+	case *ast.Enum:
+		enum := typeDecl.(*ast.Enum)
+
+		return codegen.typeName(enum) + "." + constName(enum.Constants[0].Name())
+
+	case *ast.Table:
+		if gen.IsOneOf(typeDecl) {
+			return "None"
+		}
+
+		return codegen.typeName(typeDecl) + "()"
+
+	case *ast.Seq:
+		seq := typeDecl.(*ast.Seq)
+
+		if gen.IsBytes(seq) {
+			if seq.Size != -1 {
+				return fmt.Sprintf("bytes(%d)", seq.Size)
+			}
+
+			return "b\"\""
+		}
+
+		if _, ok := wire.MapEntry(seq); ok {
+			return "{}"
+		}
+
+		if seq.Size != -1 {
+			return fmt.Sprintf("[%s for _ in range(%d)]", codegen.defaultVal(seq.Component), seq.Size)
+		}
+
+		return "[]"
+	}
+
+	gserrors.Panicf(nil, "defaultVal  error: unsupport type(%s)", typeDecl)
+
+	return "unknown"
+}
+
+// immutable check if the type value can be used as dataclass field default directly
+func immutable(typeDecl ast.Type) bool {
+	switch typeDecl.(type) {
+	case *ast.BuiltinType, *ast.Enum:
+		return true
+	case *ast.TypeRef:
+		return immutable(typeDecl.(*ast.TypeRef).Ref)
+	case *ast.Table:
+		return gen.IsOneOf(typeDecl)
+	case *ast.Seq:
+		return gen.IsBytes(typeDecl.(*ast.Seq))
+	}
+
+	return false
+}
+
+// fieldInit get the dataclass field initializer, the mutable default value is created by default_factory
+func (codegen *_CodeGen) fieldInit(field *ast.Field) string {
+
+	val := codegen.fieldDefault(field)
+
+	if val == "" {
+		return "None"
+	}
+
+	if immutable(field.Type) {
+		return val
+	}
+
+	return fmt.Sprintf("dataclasses.field(default_factory=lambda: %s)", val)
+}
+
+// readType get the expr which read the type value from the reader variable
+func (codegen *_CodeGen) readType(typeDecl ast.Type) string {
+	switch typeDecl.(type) {
+	case *ast.BuiltinType:
+		builtinType := typeDecl.(*ast.BuiltinType)
+
+		return readMapping[builtinType.Type]
+	case *ast.TypeRef:
+		return codegen.readType(typeDecl.(*ast.TypeRef).Ref)
+
+	case *ast.Enum:
+		if gslang.EnumSize(typeDecl) == 4 {
+			return fmt.Sprintf("%s(reader.read_uint32())", codegen.typeName(typeDecl))
+		}
+
+		return fmt.Sprintf("%s(reader.read_byte())", codegen.typeName(typeDecl))
+
+	case *ast.Table:
+		return codegen.qualify(typeDecl.Package(), "read_"+gen.SnakeName(gen.TableName(typeDecl))) + "(reader)"
+
+	case *ast.Seq:
+		seq := typeDecl.(*ast.Seq)
+
+		if gen.IsBytes(seq) {
+			if seq.Size != -1 {
+				return fmt.Sprintf("reader.read_fixed_bytes(%d)", seq.Size)
+			}
+
+			return "reader.read_bytes()"
+		}
+
+		if entry, ok := wire.MapEntry(seq); ok {
+			return fmt.Sprintf("reader.read_map(lambda: %s, lambda: %s)", codegen.readType(entry.Fields[0].Type), codegen.readType(entry.Fields[1].Type))
+		}
+
+		if seq.Size != -1 {
+			return fmt.Sprintf("reader.read_array(%d, lambda: %s)", seq.Size, codegen.readType(seq.Component))
+		}
+
+		return fmt.Sprintf("reader.read_list(lambda: %s)", codegen.readType(seq.Component))
+	}
+
+	gserrors.Panicf(nil, "readType  error: unsupport type(%s)", typeDecl)
+
+	return "unknown"
+}
+
+// writeType get the expr which write the valname to the writer variable, depth is used to name the lambda args
+func (codegen *_CodeGen) writeType(typeDecl ast.Type, valname string, depth int) string {
+	switch typeDecl.(type) {
+	case *ast.BuiltinType:
+		builtinType := typeDecl.(*ast.BuiltinType)
+
+		return fmt.Sprintf("%s(%s)", writeMapping[builtinType.Type], valname)
+	case *ast.TypeRef:
+		return codegen.writeType(typeDecl.(*ast.TypeRef).Ref, valname, depth)
+
+	case *ast.Enum:
+		if gslang.EnumSize(typeDecl) == 4 {
+			return fmt.Sprintf("writer.write_uint32(%s)", valname)
+		}
+
+		return fmt.Sprintf("writer.write_byte(%s)", valname)
+
+	case *ast.Table:
+		return fmt.Sprintf("%s(writer, %s)", codegen.qualify(typeDecl.Package(), "write_"+gen.SnakeName(gen.TableName(typeDecl))), valname)
+
+	case *ast.Seq:
+		seq := typeDecl.(*ast.Seq)
+
+		if gen.IsBytes(seq) {
+			if seq.Size != -1 {
+				return fmt.Sprintf("writer.write_fixed_bytes(%d, %s)", seq.Size, valname)
+			}
+
+			return fmt.Sprintf("writer.write_bytes(%s)", valname)
+		}
+
+		key, val := fmt.Sprintf("k%d", depth), fmt.Sprintf("v%d", depth)
+
+		if entry, ok := wire.MapEntry(seq); ok {
+			return fmt.Sprintf("writer.write_map(%s, lambda %s: %s, lambda %s: %s)", valname,
+				key, codegen.writeType(entry.Fields[0].Type, key, depth+1),
+				val, codegen.writeType(entry.Fields[1].Type, val, depth+1))
+		}
+
+		if seq.Size != -1 {
+			return fmt.Sprintf("writer.write_array(%d, %s, lambda %s: %s)", seq.Size, valname, val, codegen.writeType(seq.Component, val, depth+1))
+		}
+
+		return fmt.Sprintf("writer.write_list(%s, lambda %s: %s)", valname, val, codegen.writeType(seq.Component, val, depth+1))
+	}
+
+	gserrors.Panicf(nil, "writeType  error: unsupport type(%s)", typeDecl)
+
+	return "unknown"
+}
+
+// fieldDefault get the field default value expr, optional field without default value is None
+func (codegen *_CodeGen) fieldDefault(field *ast.Field) string {
+
+	expr, ok := gen.Default(field)
+
+	if !ok {
+		if gen.IsOptional(field) {
+			return ""
+		}
+
+		return codegen.defaultVal(field.Type)
+	}
+
+	start, _ := gslang.Pos(field)
+
+	return codegen.defaultExpr(expr, field.Type, start)
+}
+
+func (codegen *_CodeGen) defaultExpr(expr ast.Expr, typeDecl ast.Type, start lexer.Position) string {
+
+	eval := codegen.compiler.Eval()
+
+	switch typeDecl.(type) {
+	case *ast.BuiltinType:
+		builtinType := typeDecl.(*ast.BuiltinType)
+
+		gen.CheckUnsigned(eval, builtinType, expr, start)
+
+		switch builtinType.Type {
+		case lexer.KeyString:
+			return strconv.Quote(eval.EvalString(expr))
+		case lexer.KeyBool:
+			if eval.EvalBool(expr) {
+				return "True"
+			}
+
+			return "False"
+		case lexer.KeyFloat32, lexer.KeyFloat64:
+			val := strconv.FormatFloat(eval.EvalFloat(expr), 'g', -1, 64)
+
+			if !strings.ContainsAny(val, ".eN") {
+				val += ".0"
+			}
+
+			return val
+		case lexer.KeyVoid:
+		default:
+			return fmt.Sprintf("%d", eval.EvalInt(expr))
+		}
+
+	case *ast.TypeRef:
+		return codegen.defaultExpr(expr, typeDecl.(*ast.TypeRef).Ref, start)
+
+	case *ast.Enum:
+		enum := typeDecl.(*ast.Enum)
+
+		val := eval.EvalInt(expr)
+
+		if constant, ok := gen.Constant(enum, val); ok {
+			return codegen.typeName(enum) + "." + constName(constant.Name())
+		}
+
+		gserrors.Panicf(nil, "enum %s constant(%d) not found :%v", enum, val, start)
+
+	case *ast.Table:
+		table := typeDecl.(*ast.Table)
+
+		newObj, ok := expr.(*ast.NewObj)
+
+		if !ok || gen.IsOneOf(table) {
+			break
+		}
+
+		// the fields without arg keep the dataclass field defaults
+		var args []string
+
+		for i, field := range table.Fields {
+
+			arg, ok := gen.FieldArg(newObj, i, field)
+
+			if ok {
+				args = append(args, fmt.Sprintf("%s=%s", fieldName(field.Name()), codegen.defaultExpr(arg, field.Type, start)))
+			}
+		}
+
+		return fmt.Sprintf("%s(%s)", codegen.typeName(table), strings.Join(args, ", "))
+	}
+
+	gserrors.Panicf(nil, "unsupport default value for type(%s) :%v", typeDecl, start)
+
+	return "unknown"
+}
+
+func (codegen *_CodeGen) params(params []*ast.Param) string {
+
+	args := []string{"self"}
+
+	for _, param := range params {
+		args = append(args, fmt.Sprintf("%s: %s", fieldName(param.Name()), codegen.typeName(param.Type)))
+	}
+
+	return "(" + strings.Join(args, ", ") + ")"
+}
+
+func callArgs(params []*ast.Param) string {
+
+	var args []string
+
+	for _, param := range params {
+		args = append(args, fmt.Sprintf("arg%d", param.ID))
+	}
+
+	return "(" + strings.Join(args, ", ") + ")"
+}
+
+// returnType get the method return type hint
+func (codegen *_CodeGen) returnType(method *ast.Method) string {
+	return codegen.typeName(method.Return)
+}
+
+func (codegen *_CodeGen) execute(name string, data interface{}) {
+	if err := codegen.tpl.ExecuteTemplate(&codegen.module.content, name, data); err != nil {
+		gserrors.Panicf(err, "exec template(%s) for %s error", name, data)
+	}
+}
+
+// exists check if the file has been generated or exists in rootpath
+func (codegen *_CodeGen) exists(name string) bool {
+
+	if codegen.files != nil {
+		_, ok := codegen.files[filepath.ToSlash(name)]
+		return ok
+	}
+
+	_, err := os.Stat(filepath.Join(codegen.rootpath, name))
+
+	return err == nil
+}
+
+func (codegen *_CodeGen) writeFile(name string, content []byte) {
+
+	// the content buffer may be reused by caller
+	if codegen.files != nil {
+		codegen.files[filepath.ToSlash(name)] = append([]byte(nil), content...)
+		return
+	}
+
+	fullpath := filepath.Join(codegen.rootpath, name)
+
+	if err := os.MkdirAll(filepath.Dir(fullpath), 0755); err != nil {
+		gserrors.Panicf(err, "create output directory error")
+	}
+
+	codegen.D("write file :%s", fullpath)
+
+	if err := ioutil.WriteFile(fullpath, content, 0644); err != nil {
+		gserrors.Panicf(err, "write generate stub code error")
+	}
+}
+
+func (codegen *_CodeGen) BeginScript(compiler *gslang.Compiler, script *ast.Script) bool {
+
+	scriptPath := filepath.ToSlash(filepath.Clean(script.Name()))
+
+	for _, skip := range codegen.skips {
+
+		if skip.MatchString(scriptPath) {
+
+			return false
+		}
+	}
+
+	if strings.HasPrefix(script.Package, "gslang.") {
+		return false
+	}
+
+	codegen.script = script
+
+	codegen.compiler = compiler
+
+	module, ok := codegen.modules[script.Package]
+
+	if !ok {
+		module = &_Module{imports: make(map[string]string)}
+
+		codegen.modules[script.Package] = module
+	}
+
+	codegen.module = module
+
+	return true
+}
+
+func (codegen *_CodeGen) Using(compiler *gslang.Compiler, using *ast.Using) {
+}
+
+func (codegen *_CodeGen) Table(compiler *gslang.Compiler, tableType *ast.Table) {
+
+	if gen.IsOneOf(tableType) {
+		codegen.execute("union", tableType)
+	} else {
+		codegen.execute("table", tableType)
+	}
+}
+
+func (codegen *_CodeGen) Annotation(compiler *gslang.Compiler, annotation *ast.Table) {
+}
+
+func (codegen *_CodeGen) Enum(compiler *gslang.Compiler, enum *ast.Enum) {
+	codegen.execute("enum", enum)
+}
+
+func (codegen *_CodeGen) Contract(compiler *gslang.Compiler, contract *ast.Contract) {
+	codegen.execute("contract", contract)
+}
+
+// EndScript write the package module, which is rewritten with all the scripts of the package
+func (codegen *_CodeGen) EndScript(compiler *gslang.Compiler) {
+
+	var stream bytes.Buffer
+
+	stream.WriteString("# generate by gs2python,don't modify it manually\n\n")
+
+	stream.WriteString("from __future__ import annotations\n\n")
+
+	stream.WriteString("import dataclasses\nimport enum\nfrom typing import Any, Dict, List, Optional\n\n")
+
+	stream.WriteString(fmt.Sprintf("import %s\n", runtimeModule))
+
+	var aliases []string
+
+	for alias := range codegen.module.imports {
+		aliases = append(aliases, alias)
+	}
+
+	sort.Strings(aliases)
+
+	for _, alias := range aliases {
+		stream.WriteString(fmt.Sprintf("import %s as %s\n", codegen.module.imports[alias], alias))
+	}
+
+	stream.Write(codegen.module.content.Bytes())
+
+	codegen.writeFile(modulePath(codegen.script.Package), tidy.Clean(stream.Bytes()))
+
+	// the parent packages without gslang scripts are generated as empty python packages
+	names := strings.Split(codegen.script.Package, ".")
+
+	for i := 1; i < len(names); i++ {
+
+		parent := strings.Join(names[:i], ".")
+
+		if _, ok := codegen.modules[parent]; ok {
+			continue
+		}
+
+		if codegen.exists(modulePath(parent)) {
+			continue
+		}
+
+		codegen.writeFile(modulePath(parent), nil)
+	}
+
+	if codegen.runtime {
+		return
+	}
+
+	stream.Reset()
+
+	if err := codegen.tpl.ExecuteTemplate(&stream, "runtime", "com.gsrpc"); err != nil {
+		gserrors.Panicf(err, "exec template(runtime) error")
+	}
+
+	codegen.writeFile(runtimeModule+".py", stream.Bytes())
+
+	codegen.runtime = true
+}
+
+var tidy = gen.NewTidy(2, `:`, ``)
+
+// doc get the docstring from the .gs comments of node
+func doc(indent string, node ast.Node) string {
+
+	comments := gslang.Comments(node)
+
+	if len(comments) == 0 {
+		return ""
+	}
+
+	escaper := strings.NewReplacer("\\", "\\\\", `"""`, `\"\"\"`)
+
+	var lines []string
+
+	for _, line := range comments {
+		lines = append(lines, escaper.Replace(strings.TrimSpace(line)))
+	}
+
+	if len(lines) == 1 {
+		return fmt.Sprintf("%s\"\"\"%s\"\"\"\n", indent, lines[0])
+	}
+
+	var buff bytes.Buffer
+
+	buff.WriteString(indent + "\"\"\"" + lines[0] + "\n")
+
+	for _, line := range lines[1:] {
+		buff.WriteString(indent + line + "\n")
+	}
+
+	buff.WriteString(indent + "\"\"\"\n")
+
+	return buff.String()
+}
+
+// comment get the # comment lines from the .gs comments of node
+func comment(indent string, node ast.Node) string {
+
+	var buff bytes.Buffer
+
+	for _, line := range gslang.Comments(node) {
+		buff.WriteString(indent + "# " + strings.TrimSpace(line) + "\n")
+	}
+
+	return buff.String()
+}
@@ -0,0 +1,603 @@
+package gen4python
+
+var t4python = `
+{{define "enum"}}{{$Enum := title .Name}}
+
+
+class {{$Enum}}({{if enumSize . | eq 4}}enum.IntFlag{{else}}enum.IntEnum{{end}}):
+{{doc "    " .}}{{range .Constants}}{{comment "    " .}}    {{constName .Name}} = {{.Value}}
+{{end}}{{if not .Constants}}    pass
+{{end}}{{end}}
+
+{{define "table"}}{{$Table := tableName .}}{{$Name := snakeName $Table}}
+
+
+{{if isException .}}@dataclasses.dataclass(eq=False)
+class {{$Table}}(Exception):{{else}}@dataclasses.dataclass
+class {{$Table}}:{{end}}
+{{doc "    " .}}{{range .Fields}}{{comment "    " .}}    {{fieldName .Name}}: {{fieldType .}} = {{fieldInit .}}
+{{end}}{{if isException .}}
+    def __str__(self) -> str:
+        return repr(self)
+{{else if not .Fields}}    pass
+{{end}}
+{{if isPOD .}}
+
+def read_{{$Name}}(reader: {{runtime}}.Reader) -> {{$Table}}:
+    target = {{$Table}}()
+{{range .Fields}}{{if isOptional .}}
+    if reader.read_bool():
+        target.{{fieldName .Name}} = {{readType .Type}}
+{{else}}
+    target.{{fieldName .Name}} = {{readType .Type}}
+{{end}}{{end}}
+    return target
+
+
+def write_{{$Name}}(writer: {{runtime}}.Writer, val: {{$Table}}) -> None:{{range .Fields}}{{if isOptional .}}
+    writer.write_bool(val.{{fieldName .Name}} is not None)
+
+    if val.{{fieldName .Name}} is not None:
+        {{writeType .Type (printf "val.%s" (fieldName .Name)) 0}}
+{{else}}
+    {{writeType .Type (printf "val.%s" (fieldName .Name)) 0}}
+{{end}}{{end}}{{if not .Fields}}
+    pass
+{{end}}
+{{else}}
+
+def read_{{$Name}}(reader: {{runtime}}.Reader) -> {{$Table}}:
+    target = {{$Table}}()
+
+    fields = reader.read_uint16()
+{{range .Fields}}
+    if fields == 0:
+        return target
+
+    if reader.read_tags()[0] != {{rpcName "Tag"}}.Skip:
+        target.{{fieldName .Name}} = {{readType .Type}}
+
+    fields -= 1
+{{end}}
+    reader.skip_fields(fields)
+
+    return target
+
+
+def write_{{$Name}}(writer: {{runtime}}.Writer, val: {{$Table}}) -> None:
+    writer.write_uint16({{len .Fields}})
+{{range .Fields}}{{if isOptional .}}
+    if val.{{fieldName .Name}} is None:
+        writer.write_tags({{rpcName "Tag"}}.Skip)
+    else:
+        writer.write_tags({{tagValue .Type}})
+        {{writeType .Type (printf "val.%s" (fieldName .Name)) 0}}
+{{else}}
+    writer.write_tags({{tagValue .Type}})
+    {{writeType .Type (printf "val.%s" (fieldName .Name)) 0}}
+{{end}}{{end}}{{end}}{{end}}
+
+{{define "union"}}{{$Union := tableName .}}{{$Name := snakeName $Union}}
+
+
+@dataclasses.dataclass
+class {{$Union}}:
+{{doc "    " .}}
+    class Kind(enum.IntEnum):
+{{range $index, $field := .Fields}}{{comment "        " .}}        {{constName .Name}} = {{variant $index}}
+{{end}}
+    kind: {{$Union}}.Kind
+    value: Any
+
+
+def read_{{$Name}}(reader: {{runtime}}.Reader) -> Optional[{{$Union}}]:
+    variant = reader.read_byte()
+
+    if variant == 0:
+        return None
+
+    tags = reader.read_tags()
+{{range $index, $field := .Fields}}
+    if variant == {{variant $index}}:
+        return {{$Union}}({{$Union}}.Kind.{{constName .Name}}, {{readType .Type}})
+{{end}}
+    reader.skip(tags)
+
+    return None
+
+
+def write_{{$Name}}(writer: {{runtime}}.Writer, val: Optional[{{$Union}}]) -> None:
+    if val is None:
+        writer.write_byte(0)
+{{range $index, $field := .Fields}}    elif val.kind == {{$Union}}.Kind.{{constName .Name}}:
+        writer.write_byte({{variant $index}})
+        writer.write_tags({{tagValue .Type}})
+        {{writeType .Type "val.value" 0}}
+{{end}}    else:
+        writer.write_byte(0)
+{{end}}
+
+{{define "contract"}}{{$Contract := title .Name}}
+
+
+class {{$Contract}}Dispatcher({{runtime}}.Dispatcher):
+    """{{$Contract}}Dispatcher the {{$Contract}} service base class, which dispatch the remote calls to the overridden methods"""
+
+    NAME = "{{.FullName}}"
+
+    def __init__(self, id: int) -> None:
+        self.id = id
+
+    def __str__(self) -> str:
+        return self.NAME
+{{range .Methods}}
+    async def {{fieldName .Name}}{{params .Params}} -> {{returnType .}}:
+{{doc "        " .}}        raise NotImplementedError("{{$Contract}}#{{title .Name}}")
+{{end}}
+    async def dispatch(self, call: {{rpcName "Request"}}) -> Optional[{{rpcName "Response"}}]:{{range .Methods}}
+        if call.method == {{.ID}}:
+            if len(call.params) != {{.ParamsCount}}:
+                raise ValueError("{{$Contract}}#{{title .Name}} expect {{.ParamsCount}} params but got :%d" % len(call.params))
+{{range .Params}}
+            arg{{.ID}} = {{runtime}}.unmarshal(call.params[{{.ID}}].content, lambda reader: {{readType .Type}})
+{{end}}{{if isAsync .}}
+            await self.{{fieldName .Name}}{{callArgs .Params}}
+
+            return None
+{{else}}{{if .Exceptions}}
+            try:
+                {{if notVoid .Return}}ret = {{end}}await self.{{fieldName .Name}}{{callArgs .Params}}{{range .Exceptions}}
+            except {{typeName .Type}} as err:
+                return {{rpcName "Response"}}(
+                    id=call.id,
+                    exception={{.ID}},
+                    content={{runtime}}.marshal(lambda writer: {{writeType .Type "err" 0}}),
+                    trace=call.trace,
+                ){{end}}
+{{else}}
+            {{if notVoid .Return}}ret = {{end}}await self.{{fieldName .Name}}{{callArgs .Params}}
+{{end}}
+            return {{rpcName "Response"}}(
+                id=call.id,
+                exception=-1,
+                content={{if notVoid .Return}}{{runtime}}.marshal(lambda writer: {{writeType .Return "ret" 0}}){{else}}b""{{end}},
+                trace=call.trace,
+            )
+{{end}}{{end}}
+        raise ValueError("unknown {{$Contract}}#%d method" % call.method)
+
+
+class {{$Contract}}RPC:
+    """{{$Contract}}RPC the remote {{$Contract}} service proxy"""
+
+    def __init__(self, channel: {{runtime}}.Channel, service_id: int, timeout: float = 5.0) -> None:
+        self._channel = channel
+        self._service_id = service_id
+        self.timeout = timeout
+{{range .Methods}}
+    {{if not (isAsync .)}}async {{end}}def {{fieldName .Name}}{{params .Params}} -> {{if isAsync .}}None{{else}}{{returnType .}}{{end}}:
+{{doc "        " .}}        call = {{rpcName "Request"}}(
+            service=self._service_id,
+            method={{.ID}},
+            params=[{{range .Params}}
+                {{rpcName "Param"}}(content={{runtime}}.marshal(lambda writer: {{writeType .Type (fieldName .Name) 0}})),{{end}}
+            ],
+        )
+{{if isAsync .}}
+        self._channel.post(call)
+{{else}}
+        call_return = await self._channel.call(call, self.timeout)
+
+        if call_return.exception != -1:
+            if call_return.exception == -2:
+                raise {{runtime}}.unmarshal(call_return.content, lambda reader: {{rpcName "read_invalid_argument_exception"}}(reader))
+{{range .Exceptions}}
+            if call_return.exception == {{.ID}}:
+                raise {{runtime}}.unmarshal(call_return.content, lambda reader: {{readType .Type}})
+{{end}}
+            raise {{rpcName "RemoteException"}}()
+{{if notVoid .Return}}
+        return {{runtime}}.unmarshal(call_return.content, lambda reader: {{readType .Return}})
+{{end}}{{end}}{{end}}{{end}}
+
+{{define "runtime"}}# generate by gs2python,don't modify it manually
+#
+# gsrpc runtime: the struct based Reader/Writer and the asyncio Channel over Transport
+
+from __future__ import annotations
+
+import asyncio
+import logging
+import struct
+from typing import Callable, Dict, List, Optional, Set, TypeVar
+
+import {{.}} as rpc
+
+T = TypeVar("T")
+K = TypeVar("K")
+V = TypeVar("V")
+
+_INT8 = struct.Struct("<b")
+_UINT8 = struct.Struct("<B")
+_INT16 = struct.Struct("<h")
+_UINT16 = struct.Struct("<H")
+_INT32 = struct.Struct("<i")
+_UINT32 = struct.Struct("<I")
+_INT64 = struct.Struct("<q")
+_UINT64 = struct.Struct("<Q")
+_FLOAT32 = struct.Struct("<f")
+_FLOAT64 = struct.Struct("<d")
+
+_log = logging.getLogger("gsrpc")
+
+
+class Reader:
+    """Reader read gsrpc values from the input buffer"""
+
+    def __init__(self, buff: bytes) -> None:
+        self._buff = bytes(buff)
+        self._offset = 0
+
+    def _next(self, length: int) -> int:
+        offset = self._offset
+
+        if offset + length > len(self._buff):
+            raise EOFError("gsrpc: read beyond the end of buffer")
+
+        self._offset += length
+
+        return offset
+
+    def _unpack(self, codec: struct.Struct):
+        return codec.unpack_from(self._buff, self._next(codec.size))[0]
+
+    def read_byte(self) -> int:
+        return self._unpack(_UINT8)
+
+    def read_sbyte(self) -> int:
+        return self._unpack(_INT8)
+
+    def read_bool(self) -> bool:
+        return self.read_byte() != 0
+
+    def read_int16(self) -> int:
+        return self._unpack(_INT16)
+
+    def read_uint16(self) -> int:
+        return self._unpack(_UINT16)
+
+    def read_int32(self) -> int:
+        return self._unpack(_INT32)
+
+    def read_uint32(self) -> int:
+        return self._unpack(_UINT32)
+
+    def read_int64(self) -> int:
+        return self._unpack(_INT64)
+
+    def read_uint64(self) -> int:
+        return self._unpack(_UINT64)
+
+    def read_float32(self) -> float:
+        return self._unpack(_FLOAT32)
+
+    def read_float64(self) -> float:
+        return self._unpack(_FLOAT64)
+
+    def read_bytes(self) -> bytes:
+        length = self.read_uint16()
+
+        offset = self._next(length)
+
+        return self._buff[offset:offset + length]
+
+    def read_fixed_bytes(self, size: int) -> bytes:
+        val = self.read_bytes()
+
+        if len(val) != size:
+            raise ValueError("gsrpc: check array size failed, expect %d got %d" % (size, len(val)))
+
+        return val
+
+    def read_string(self) -> str:
+        return self.read_bytes().decode("utf-8")
+
+    def read_list(self, read: Callable[[], T]) -> List[T]:
+        return [read() for _ in range(self.read_uint16())]
+
+    def read_array(self, size: int, read: Callable[[], T]) -> List[T]:
+        val = self.read_list(read)
+
+        if len(val) != size:
+            raise ValueError("gsrpc: check array size failed, expect %d got %d" % (size, len(val)))
+
+        return val
+
+    def read_map(self, read_key: Callable[[], K], read_value: Callable[[], V]) -> Dict[K, V]:
+        val = {}
+
+        for _ in range(self.read_uint16()):
+            key = read_key()
+
+            val[key] = read_value()
+
+        return val
+
+    def read_tags(self) -> List[int]:
+        """read the tag sequence of tagged value, the nested container and POD tags are followed by their component tags"""
+        tag = self.read_byte()
+
+        tags = [tag]
+
+        nested = 0
+
+{{range $i, $case := wireNested}}        {{if $i}}el{{end}}if {{if eq (len .Tags) 1}}tag == rpc.Tag.{{index .Tags 0}}{{else}}tag in ({{range $j, $tag := .Tags}}{{if $j}}, {{end}}rpc.Tag.{{$tag}}{{end}}){{end}}:
+{{if lt .Nested 0}}            nested = self.read_byte()
+            tags.append(nested)
+{{else}}            nested = {{.Nested}}
+{{end}}{{end}}
+        for _ in range(nested):
+            tags += self.read_tags()
+
+        return tags
+
+    def skip(self, tags: List[int]) -> None:
+        """skip the value described by the tag sequence"""
+        self._skip_tags(tags, 0)
+
+    def skip_fields(self, fields: int) -> None:
+        """skip the unknown fields of tagged table"""
+        for _ in range(fields):
+            self.skip(self.read_tags())
+
+    def _skip_components(self, tags: List[int], index: int, count: int) -> None:
+        """skip the values of count tag sequences begin with index"""
+        for _ in range(count):
+            self._skip_tags(tags, index)
+            index = _tags_end(tags, index)
+
+    def _skip_tags(self, tags: List[int], index: int) -> None:
+        tag = tags[index]
+
+{{range $i, $case := wireCases}}        {{if $i}}el{{end}}if {{if eq (len .Tags) 1}}tag == rpc.Tag.{{index .Tags 0}}{{else}}tag in ({{range $j, $tag := .Tags}}{{if $j}}, {{end}}rpc.Tag.{{$tag}}{{end}}){{end}}:
+{{if eq .Kind "none"}}            pass
+{{else if eq .Kind "fixed"}}            self._next({{.Size}})
+{{else if eq .Kind "sized"}}            self._next(self.read_uint16())
+{{else if eq .Kind "fields"}}            self.skip_fields(self.read_uint16())
+{{else if eq .Kind "seq"}}            for _ in range(self.read_uint16()):
+                self._skip_components(tags, index + 1, {{.Nested}})
+{{else if eq .Kind "record"}}            self._skip_components(tags, index + 2, tags[index + 1])
+{{else if eq .Kind "optional"}}            if self.read_bool():
+                self._skip_tags(tags, index + 1)
+{{else if eq .Kind "variant"}}            if self.read_byte() != 0:
+                self.skip(self.read_tags())
+{{end}}{{end}}
+        else:
+            raise ValueError("gsrpc: unknown tag %d" % tag)
+
+
+def _tags_end(tags: List[int], index: int) -> int:
+    """get the index next to the tag sequence begin with index"""
+    tag = tags[index]
+
+    next_index, nested = index + 1, 0
+
+{{range $i, $case := wireNested}}    {{if $i}}el{{end}}if {{if eq (len .Tags) 1}}tag == rpc.Tag.{{index .Tags 0}}{{else}}tag in ({{range $j, $tag := .Tags}}{{if $j}}, {{end}}rpc.Tag.{{$tag}}{{end}}){{end}}:
+{{if lt .Nested 0}}        next_index, nested = index + 2, tags[index + 1]
+{{else}}        nested = {{.Nested}}
+{{end}}{{end}}
+    for _ in range(nested):
+        next_index = _tags_end(tags, next_index)
+
+    return next_index
+
+
+class Writer:
+    """Writer write gsrpc values into the growable output buffer"""
+
+    def __init__(self) -> None:
+        self._buff = bytearray()
+
+    def write_byte(self, val: int) -> None:
+        self._buff += _UINT8.pack(val)
+
+    def write_sbyte(self, val: int) -> None:
+        self._buff += _INT8.pack(val)
+
+    def write_bool(self, val: bool) -> None:
+        self.write_byte(1 if val else 0)
+
+    def write_int16(self, val: int) -> None:
+        self._buff += _INT16.pack(val)
+
+    def write_uint16(self, val: int) -> None:
+        self._buff += _UINT16.pack(val)
+
+    def write_int32(self, val: int) -> None:
+        self._buff += _INT32.pack(val)
+
+    def write_uint32(self, val: int) -> None:
+        self._buff += _UINT32.pack(val)
+
+    def write_int64(self, val: int) -> None:
+        self._buff += _INT64.pack(val)
+
+    def write_uint64(self, val: int) -> None:
+        self._buff += _UINT64.pack(val)
+
+    def write_float32(self, val: float) -> None:
+        self._buff += _FLOAT32.pack(val)
+
+    def write_float64(self, val: float) -> None:
+        self._buff += _FLOAT64.pack(val)
+
+    def write_bytes(self, val: bytes) -> None:
+        self.write_uint16(len(val))
+
+        self._buff += val
+
+    def write_fixed_bytes(self, size: int, val: bytes) -> None:
+        if len(val) != size:
+            raise ValueError("gsrpc: check array size failed, expect %d got %d" % (size, len(val)))
+
+        self.write_bytes(val)
+
+    def write_string(self, val: str) -> None:
+        self.write_bytes(val.encode("utf-8"))
+
+    def write_tags(self, *tags: int) -> None:
+        for tag in tags:
+            self.write_byte(tag)
+
+    def write_list(self, val: List[T], write: Callable[[T], None]) -> None:
+        self.write_uint16(len(val))
+
+        for v in val:
+            write(v)
+
+    def write_array(self, size: int, val: List[T], write: Callable[[T], None]) -> None:
+        if len(val) != size:
+            raise ValueError("gsrpc: check array size failed, expect %d got %d" % (size, len(val)))
+
+        self.write_list(val, write)
+
+    def write_map(self, val: Dict[K, V], write_key: Callable[[K], None], write_value: Callable[[V], None]) -> None:
+        """write map entries in key order, so the same map is always encoded as same bytes"""
+        self.write_uint16(len(val))
+
+        for key in sorted(val):
+            write_key(key)
+            write_value(val[key])
+
+    def content(self) -> bytes:
+        return bytes(self._buff)
+
+
+def marshal(write: Callable[[Writer], None]) -> bytes:
+    """encode value by the write function"""
+    writer = Writer()
+
+    write(writer)
+
+    return writer.content()
+
+
+def unmarshal(content: bytes, read: Callable[[Reader], T]) -> T:
+    """decode value from content by the read function"""
+    return read(Reader(content))
+
+
+class Transport:
+    """Transport the message transport of channel, one message per send and on_message callback"""
+
+    on_message: Optional[Callable[[bytes], None]] = None
+
+    on_close: Optional[Callable[[Exception], None]] = None
+
+    def send(self, data: bytes) -> None:
+        raise NotImplementedError
+
+    def close(self) -> None:
+        raise NotImplementedError
+
+
+class Dispatcher:
+    """Dispatcher the service dispatcher registered into channel by service id"""
+
+    id: int
+
+    async def dispatch(self, call: rpc.Request) -> Optional[rpc.Response]:
+        raise NotImplementedError
+
+
+class Channel:
+    """Channel the rpc channel which send requests to and dispatch requests from the peer, it must be used in the event loop thread"""
+
+    def __init__(self, transport: Transport) -> None:
+        self._transport = transport
+        self._seq = 0
+        self._pending: Dict[int, asyncio.Future] = {}
+        self._dispatchers: Dict[int, Dispatcher] = {}
+        self._tasks: Set[asyncio.Task] = set()
+        transport.on_message = self._on_message
+        transport.on_close = self._on_close
+
+    def register(self, dispatcher: Dispatcher) -> None:
+        self._dispatchers[dispatcher.id] = dispatcher
+
+    def unregister(self, id: int) -> None:
+        self._dispatchers.pop(id, None)
+
+    async def call(self, call: rpc.Request, timeout: float) -> rpc.Response:
+        """send the request and wait the response, raise TimeoutError after timeout seconds"""
+        self._seq = (self._seq + 1) & 0xFFFFFFFF
+
+        id = call.id = self._seq
+
+        future = asyncio.get_running_loop().create_future()
+
+        self._pending[id] = future
+
+        try:
+            self._send(rpc.Code.Request, marshal(lambda writer: rpc.write_request(writer, call)))
+
+            return await asyncio.wait_for(future, timeout)
+        except asyncio.TimeoutError:
+            raise TimeoutError("gsrpc: rpc call(%d) timeout" % id) from None
+        finally:
+            self._pending.pop(id, None)
+
+    def post(self, call: rpc.Request) -> None:
+        """send the request without waiting response"""
+        self._send(rpc.Code.Request, marshal(lambda writer: rpc.write_request(writer, call)))
+
+    def close(self) -> None:
+        self._transport.close()
+
+    def _send(self, code: rpc.Code, content: bytes) -> None:
+        message = rpc.Message(code=code, agent=0, content=content)
+
+        self._transport.send(marshal(lambda writer: rpc.write_message(writer, message)))
+
+    def _on_message(self, data: bytes) -> None:
+        message = unmarshal(data, rpc.read_message)
+
+        if message.code == rpc.Code.Response:
+            call_return = unmarshal(message.content, rpc.read_response)
+
+            future = self._pending.get(call_return.id)
+
+            if future is not None and not future.done():
+                future.set_result(call_return)
+        elif message.code == rpc.Code.Request:
+            task = asyncio.ensure_future(self._dispatch(unmarshal(message.content, rpc.read_request)))
+
+            self._tasks.add(task)
+
+            task.add_done_callback(self._tasks.discard)
+        elif message.code == rpc.Code.Heartbeat:
+            self._send(rpc.Code.Heartbeat, b"")
+
+    async def _dispatch(self, call: rpc.Request) -> None:
+        dispatcher = self._dispatchers.get(call.service)
+
+        if dispatcher is None:
+            return
+
+        try:
+            call_return = await dispatcher.dispatch(call)
+        except Exception:
+            _log.exception("gsrpc: dispatch %s#%d error", dispatcher, call.method)
+            return
+
+        if call_return is not None:
+            self._send(rpc.Code.Response, marshal(lambda writer: rpc.write_response(writer, call_return)))
+
+    def _on_close(self, reason: Exception) -> None:
+        for future in self._pending.values():
+            if not future.done():
+                future.set_exception(reason)
+
+        self._pending.clear()
+{{end}}
+`
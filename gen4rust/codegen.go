@@ -0,0 +1,715 @@
+package gen4rust
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/gsdocker/gserrors"
+	"github.com/gsdocker/gslogger"
+	"github.com/gsrpc/gslang"
+	"github.com/gsrpc/gslang/ast"
+	"github.com/gsrpc/gslang/lexer"
+	"github.com/gsrpc/gsrpc/gen"
+	"github.com/gsrpc/gsrpc/wire"
+)
+
+var builtin = map[lexer.TokenType]string{
+	lexer.KeySByte:   "i8",
+	lexer.KeyByte:    "u8",
+	lexer.KeyInt16:   "i16",
+	lexer.KeyUInt16:  "u16",
+	lexer.KeyInt32:   "i32",
+	lexer.KeyUInt32:  "u32",
+	lexer.KeyInt64:   "i64",
+	lexer.KeyUInt64:  "u64",
+	lexer.KeyFloat32: "f32",
+	lexer.KeyFloat64: "f64",
+	lexer.KeyBool:    "bool",
+	lexer.KeyString:  "String",
+	lexer.KeyVoid:    "()",
+}
+
+var defaultval = map[lexer.TokenType]string{
+	lexer.KeySByte:   "0",
+	lexer.KeyByte:    "0",
+	lexer.KeyInt16:   "0",
+	lexer.KeyUInt16:  "0",
+	lexer.KeyInt32:   "0",
+	lexer.KeyUInt32:  "0",
+	lexer.KeyInt64:   "0",
+	lexer.KeyUInt64:  "0",
+	lexer.KeyFloat32: "0.0",
+	lexer.KeyFloat64: "0.0",
+	lexer.KeyBool:    "false",
+	lexer.KeyString:  "String::new()",
+}
+
+// keywords the rust keywords, which are used as raw identifiers
+var keywords = map[string]bool{
+	"as": true, "async": true, "await": true, "break": true, "const": true, "continue": true, "dyn": true,
+	"else": true, "enum": true, "extern": true, "false": true, "fn": true, "for": true, "if": true,
+	"impl": true, "in": true, "let": true, "loop": true, "match": true, "mod": true, "move": true,
+	"mut": true, "pub": true, "ref": true, "return": true, "static": true, "struct": true, "trait": true,
+	"true": true, "type": true, "unsafe": true, "use": true, "where": true, "while": true, "abstract": true,
+	"become": true, "box": true, "do": true, "final": true, "macro": true, "override": true, "priv": true,
+	"typeof": true, "unsized": true, "virtual": true, "yield": true, "try": true, "gen": true,
+}
+
+// rpcPackage the package of gsrpc builtin types, the runtime module is re-exported by the package module
+const rpcPackage = "com.gsrpc"
+
+type _Package struct {
+	scripts  map[string][]byte // generated script contents of package
+	children map[string]bool   // child package module names
+}
+
+type _CodeGen struct {
+	gslogger.Log                      // Log APIs
+	rootpath     string               // root path
+	files        map[string][]byte    // generated files sink, the files are written into rootpath if nil
+	script       *ast.Script          // current script
+	content      bytes.Buffer         // current script content
+	tpl          *template.Template   // code generate template
+	skips        []*regexp.Regexp     // skip lists
+	compiler     *gslang.Compiler     // current compiler
+	packages     map[string]*_Package // generated package modules
+	runtime      bool                 // runtime file is generated
+}
+
+// NewCodeGen .
+func NewCodeGen(rootpath string, skips []string) (gslang.Visitor, error) {
+
+	codeGen := &_CodeGen{
+		Log:      gslogger.Get("gen4rust"),
+		rootpath: rootpath,
+		packages: make(map[string]*_Package),
+	}
+
+	for _, skip := range skips {
+		exp, err := regexp.Compile(skip)
+
+		if err != nil {
+			return nil, gserrors.Newf(err, "invalid skip regex string :%s", skip)
+		}
+
+		codeGen.skips = append(codeGen.skips, exp)
+	}
+
+	funcs := template.FuncMap{
+		"title":         strings.Title,
+		"tableName":     gen.TableName,
+		"memberName":    memberName,
+		"constName":     constName,
+		"doc":           doc,
+		"notVoid":       gslang.NotVoid,
+		"isPOD":         gslang.IsPOD,
+		"isAsync":       gslang.IsAsync,
+		"isException":   gslang.IsException,
+		"enumSize":      gslang.EnumSize,
+		"isOptional":    gen.IsOptional,
+		"isOneOf":       gen.IsOneOf,
+		"variant":       gen.Variant,
+		"exceptions":    exceptions,
+		"exceptionName": exceptionName,
+		"typeName":      codeGen.typeName,
+		"fieldType":     codeGen.fieldType,
+		"tagValue":      codeGen.tagValue,
+		"wireCases":     wire.Cases,
+		"wireNested":    wire.Nested,
+		"rpcName":       codeGen.rpcName,
+		"fieldDefault":  codeGen.fieldDefault,
+		"params":        codeGen.params,
+		"returnType":    codeGen.returnType,
+		"callArgs":      callArgs,
+	}
+
+	tpl, err := template.New("t4rust").Funcs(funcs).Parse(t4rust)
+
+	if err != nil {
+		return nil, err
+	}
+
+	codeGen.tpl = tpl
+
+	return codeGen, nil
+}
+
+// NewCodeGenWithFiles create codegen which write generated files into files map keyed by slash separated relative path
+func NewCodeGenWithFiles(files map[string][]byte, skips []string) (gslang.Visitor, error) {
+
+	codeGen, err := NewCodeGen("", skips)
+
+	if err != nil {
+		return nil, err
+	}
+
+	codeGen.(*_CodeGen).files = files
+
+	return codeGen, nil
+}
+
+func escape(name string) string {
+	if keywords[name] {
+		return "r#" + name
+	}
+
+	return name
+}
+
+// memberName get the field/method/param name, rust keyword is used as raw identifier
+func memberName(name string) string {
+	return escape(gen.SnakeName(name))
+}
+
+// constName get the flag enum associated const name
+func constName(name string) string {
+	return strings.ToUpper(gen.SnakeName(name))
+}
+
+// qualify get the symbol path relative to current package module, the package modules are mounted as the same tree of package names
+func (codegen *_CodeGen) qualify(packageName string, name string) string {
+
+	current := strings.Split(codegen.script.Package, ".")
+
+	target := strings.Split(packageName, ".")
+
+	common := 0
+
+	for common < len(current) && common < len(target) && current[common] == target[common] {
+		common++
+	}
+
+	var path []string
+
+	for i := common; i < len(current); i++ {
+		path = append(path, "super")
+	}
+
+	for _, module := range target[common:] {
+		path = append(path, escape(module))
+	}
+
+	return strings.Join(append(path, name), "::")
+}
+
+// rpcName get the path of com.gsrpc package symbol, which includes the runtime symbols
+func (codegen *_CodeGen) rpcName(name string) string {
+	return codegen.qualify(rpcPackage, name)
+}
+
+func (codegen *_CodeGen) tagValue(typeDecl ast.Type) string {
+	return strings.Join(codegen.tags(typeDecl), ", ")
+}
+
+// tags get the type tag sequence by the shared tag scheme
+func (codegen *_CodeGen) tags(typeDecl ast.Type) []string {
+
+	tag := codegen.rpcName("Tag")
+
+	return wire.Format(wire.Tags(typeDecl), func(t wire.Tag) string {
+		return tag + "::" + t.String() + " as u8"
+	})
+}
+
+// exceptionName get the contract error variant name of exception type
+func exceptionName(typeDecl ast.Type) string {
+	if typeRef, ok := typeDecl.(*ast.TypeRef); ok {
+		return exceptionName(typeRef.Ref)
+	}
+
+	return gen.TableName(typeDecl)
+}
+
+// exceptions get the exception types declared by the contract methods, which are the variants of contract error enum
+func exceptions(contract *ast.Contract) (types []ast.Type) {
+
+	declared := make(map[string]bool)
+
+	for _, method := range contract.Methods {
+		for _, exception := range method.Exceptions {
+
+			name := exceptionName(exception.Type)
+
+			if declared[name] {
+				continue
+			}
+
+			declared[name] = true
+
+			types = append(types, exception.Type)
+		}
+	}
+
+	return
+}
+
+func (codegen *_CodeGen) declName(typeDecl ast.Type) string {
+	if _, ok := typeDecl.(*ast.Enum); ok {
+		return codegen.qualify(typeDecl.Package(), strings.Title(typeDecl.Name()))
+	}
+
+	return codegen.qualify(typeDecl.Package(), gen.TableName(typeDecl))
+}
+
+func (codegen *_CodeGen) typeName(typeDecl ast.Type) string {
+	switch typeDecl.(type) {
+	case *ast.BuiltinType:
+		builtinType := typeDecl.(*ast.BuiltinType)
+
+		return builtin[builtinType.Type]
+	case *ast.TypeRef:
+		return codegen.typeName(typeDecl.(*ast.TypeRef).Ref)
+
+	case *ast.Enum:
+		return codegen.declName(typeDecl)
+
+	case *ast.Table:
+		// the union is None when none of the variants is set
+		if gen.IsOneOf(typeDecl) {
+			return fmt.Sprintf("Option<%s>", codegen.declName(typeDecl))
+		}
+
+		return codegen.declName(typeDecl)
+
+	case *ast.Seq:
+		seq := typeDecl.(*ast.Seq)
+
+		if entry, ok := wire.MapEntry(seq); ok {
+			return fmt.Sprintf("std::collections::BTreeMap<%s, %s>", codegen.typeName(entry.Fields[0].Type), codegen.typeName(entry.Fields[1].Type))
+		}
+
+		if seq.Size != -1 {
+			return fmt.Sprintf("[%s; %d]", codegen.typeName(seq.Component), seq.Size)
+		}
+
+		return fmt.Sprintf("Vec<%s>", codegen.typeName(seq.Component))
+	}
+
+	gserrors.Panicf(nil, "typeName  error: unsupport type(%s)", typeDecl)
+
+	return "unknown"
+}
+
+// fieldType get the field type name, optional field is Option
+func (codegen *_CodeGen) fieldType(field *ast.Field) string {
+
+	if gen.IsOptional(field) && !gen.IsOneOf(field.Type) {
+		return fmt.Sprintf("Option<%s>", codegen.typeName(field.Type))
+	}
+
+	return codegen.typeName(field.Type)
+}
+
+func (codegen *_CodeGen) defaultVal(typeDecl ast.Type) string {
+	switch typeDecl.(type) {
+	case *ast.BuiltinType:
+		builtinType := typeDecl.(*ast.BuiltinType)
+
+		return defaultval[builtinType.Type]
+	case *ast.TypeRef:
+		return codegen.defaultVal(typeDecl.(*ast.TypeRef).Ref)
+
+	case *ast.Enum:
+		return codegen.constant(typeDecl.(*ast.Enum), typeDecl.(*ast.Enum).Constants[0])
+
+	case *ast.Table:
+		if gen.IsOneOf(typeDecl) {
+			return "None"
+		}
+
+		return codegen.declName(typeDecl) + "::default()"
+
+	case *ast.Seq:
+		seq := typeDecl.(*ast.Seq)
+
+		if _, ok := wire.MapEntry(seq); ok {
+			return "std::collections::BTreeMap::new()"
+		}
+
+		if seq.Size != -1 {
+			return fmt.Sprintf("std::array::from_fn(|_| %s)", codegen.defaultVal(seq.Component))
+		}
+
+		return "Vec::new()"
+	}
+
+	gserrors.Panicf(nil, "defaultVal  error: unsupport type(%s)", typeDecl)
+
+	return "unknown"
+}
+
+// constant get the enum constant path, flag enum constants are associated consts
+func (codegen *_CodeGen) constant(enum *ast.Enum, constant *ast.Constant) string {
+	if gslang.EnumSize(enum) == 4 {
+		return codegen.declName(enum) + "::" + constName(constant.Name())
+	}
+
+	return codegen.declName(enum) + "::" + strings.Title(constant.Name())
+}
+
+// fieldDefault get the Default impl field value expr, optional field without default value is None
+func (codegen *_CodeGen) fieldDefault(field *ast.Field) string {
+
+	expr, ok := gen.Default(field)
+
+	if !ok {
+		if gen.IsOptional(field) {
+			return "None"
+		}
+
+		return codegen.defaultVal(field.Type)
+	}
+
+	start, _ := gslang.Pos(field)
+
+	return codegen.defaultExpr(expr, field.Type, start)
+}
+
+func (codegen *_CodeGen) defaultExpr(expr ast.Expr, typeDecl ast.Type, start lexer.Position) string {
+
+	eval := codegen.compiler.Eval()
+
+	switch typeDecl.(type) {
+	case *ast.BuiltinType:
+		builtinType := typeDecl.(*ast.BuiltinType)
+
+		gen.CheckUnsigned(eval, builtinType, expr, start)
+
+		switch builtinType.Type {
+		case lexer.KeyString:
+			return fmt.Sprintf("String::from(%s)", quote(eval.EvalString(expr)))
+		case lexer.KeyBool:
+			return strconv.FormatBool(eval.EvalBool(expr))
+		case lexer.KeyFloat32, lexer.KeyFloat64:
+			val := strconv.FormatFloat(eval.EvalFloat(expr), 'g', -1, 64)
+
+			// rust integer literal can't be inferred as float
+			if !strings.ContainsAny(val, ".eIN") {
+				val += ".0"
+			}
+
+			return val
+		case lexer.KeyVoid:
+		default:
+			return fmt.Sprintf("%d", eval.EvalInt(expr))
+		}
+
+	case *ast.TypeRef:
+		return codegen.defaultExpr(expr, typeDecl.(*ast.TypeRef).Ref, start)
+
+	case *ast.Enum:
+		enum := typeDecl.(*ast.Enum)
+
+		val := eval.EvalInt(expr)
+
+		if constant, ok := gen.Constant(enum, val); ok {
+			return codegen.constant(enum, constant)
+		}
+
+		gserrors.Panicf(nil, "enum %s constant(%d) not found :%v", enum, val, start)
+
+	case *ast.Table:
+		table := typeDecl.(*ast.Table)
+
+		newObj, ok := expr.(*ast.NewObj)
+
+		if !ok || gen.IsOneOf(table) {
+			break
+		}
+
+		// the fields without arg keep the Default impl values
+		var fields []string
+
+		for i, field := range table.Fields {
+
+			arg, ok := gen.FieldArg(newObj, i, field)
+
+			if ok {
+				fields = append(fields, fmt.Sprintf("%s: %s", memberName(field.Name()), codegen.defaultExpr(arg, field.Type, start)))
+			}
+		}
+
+		return fmt.Sprintf("%s { %s, ..Default::default() }", codegen.declName(table), strings.Join(fields, ", "))
+	}
+
+	gserrors.Panicf(nil, "unsupport default value for type(%s) :%v", typeDecl, start)
+
+	return "unknown"
+}
+
+// quote get the rust string literal
+func quote(val string) string {
+
+	var buff bytes.Buffer
+
+	buff.WriteString("\"")
+
+	for _, r := range val {
+		switch {
+		case r == '"':
+			buff.WriteString("\\\"")
+		case r == '\\':
+			buff.WriteString("\\\\")
+		case r == '\n':
+			buff.WriteString("\\n")
+		case r == '\r':
+			buff.WriteString("\\r")
+		case r == '\t':
+			buff.WriteString("\\t")
+		case r < 0x20 || r == 0x7f:
+			buff.WriteString(fmt.Sprintf("\\u{%x}", r))
+		default:
+			buff.WriteRune(r)
+		}
+	}
+
+	buff.WriteString("\"")
+
+	return buff.String()
+}
+
+func (codegen *_CodeGen) params(params []*ast.Param) string {
+
+	args := []string{"&self"}
+
+	for _, param := range params {
+		args = append(args, fmt.Sprintf("%s: %s", memberName(param.Name()), codegen.typeName(param.Type)))
+	}
+
+	return "(" + strings.Join(args, ", ") + ")"
+}
+
+func callArgs(params []*ast.Param) string {
+
+	var args []string
+
+	for _, param := range params {
+		args = append(args, fmt.Sprintf("arg%d", param.ID))
+	}
+
+	return "(" + strings.Join(args, ", ") + ")"
+}
+
+// returnType get the method result value type
+func (codegen *_CodeGen) returnType(method *ast.Method) string {
+
+	if gslang.IsAsync(method) || !gslang.NotVoid(method.Return) {
+		return "()"
+	}
+
+	return codegen.typeName(method.Return)
+}
+
+func (codegen *_CodeGen) execute(name string, data interface{}) {
+	if err := codegen.tpl.ExecuteTemplate(&codegen.content, name, data); err != nil {
+		gserrors.Panicf(err, "exec template(%s) for %s error", name, data)
+	}
+}
+
+func (codegen *_CodeGen) writeFile(name string, content []byte) {
+
+	// the content buffer may be reused by caller
+	if codegen.files != nil {
+		codegen.files[filepath.ToSlash(name)] = append([]byte(nil), content...)
+		return
+	}
+
+	fullpath := filepath.Join(codegen.rootpath, name)
+
+	if err := os.MkdirAll(filepath.Dir(fullpath), 0755); err != nil {
+		gserrors.Panicf(err, "create output directory error")
+	}
+
+	codegen.D("write file :%s", fullpath)
+
+	if err := ioutil.WriteFile(fullpath, content, 0644); err != nil {
+		gserrors.Panicf(err, "write generate stub code error")
+	}
+}
+
+// packagePath get the package directory path relative to root path
+func packagePath(packageName string) string {
+	return strings.Replace(packageName, ".", "/", -1)
+}
+
+// module get the package module, the parent modules are created to declare the child module
+func (codegen *_CodeGen) module(packageName string) *_Package {
+
+	pkg, ok := codegen.packages[packageName]
+
+	if ok {
+		return pkg
+	}
+
+	pkg = &_Package{
+		scripts:  make(map[string][]byte),
+		children: make(map[string]bool),
+	}
+
+	codegen.packages[packageName] = pkg
+
+	if index := strings.LastIndex(packageName, "."); index != -1 {
+		codegen.module(packageName[:index]).children[packageName[index+1:]] = true
+	}
+
+	return pkg
+}
+
+// writeModule write the package mod.rs, which contains all the generated scripts of the package
+func (codegen *_CodeGen) writeModule(packageName string) {
+
+	pkg := codegen.packages[packageName]
+
+	var stream bytes.Buffer
+
+	stream.WriteString("// generate by gs2rust,don't modify it manually\n")
+
+	var children []string
+
+	for child := range pkg.children {
+		children = append(children, child)
+	}
+
+	sort.Strings(children)
+
+	if len(children) != 0 {
+		stream.WriteString("\n")
+	}
+
+	for _, child := range children {
+		stream.WriteString(fmt.Sprintf("pub mod %s;\n", escape(child)))
+	}
+
+	if len(pkg.scripts) != 0 {
+
+		if packageName == rpcPackage {
+			stream.WriteString("\nmod runtime;\n\npub use self::runtime::*;\n")
+		} else {
+			stream.WriteString(fmt.Sprintf("\n#[allow(unused_imports)]\nuse %s;\n", codegen.rpcName("Codec")))
+		}
+	}
+
+	var scripts []string
+
+	for script := range pkg.scripts {
+		scripts = append(scripts, script)
+	}
+
+	sort.Strings(scripts)
+
+	for _, script := range scripts {
+		stream.Write(pkg.scripts[script])
+	}
+
+	codegen.writeFile(filepath.Join(packagePath(packageName), "mod.rs"), tidy.Clean(stream.Bytes()))
+}
+
+func (codegen *_CodeGen) BeginScript(compiler *gslang.Compiler, script *ast.Script) bool {
+
+	scriptPath := filepath.ToSlash(filepath.Clean(script.Name()))
+
+	for _, skip := range codegen.skips {
+
+		if skip.MatchString(scriptPath) {
+
+			return false
+		}
+	}
+
+	if strings.HasPrefix(script.Package, "gslang.") {
+		return false
+	}
+
+	codegen.script = script
+
+	codegen.compiler = compiler
+
+	codegen.content.Reset()
+
+	return true
+}
+
+func (codegen *_CodeGen) Using(compiler *gslang.Compiler, using *ast.Using) {
+}
+
+func (codegen *_CodeGen) Table(compiler *gslang.Compiler, tableType *ast.Table) {
+
+	if gen.IsOneOf(tableType) {
+		codegen.execute("union", tableType)
+	} else {
+		codegen.execute("table", tableType)
+	}
+}
+
+func (codegen *_CodeGen) Annotation(compiler *gslang.Compiler, annotation *ast.Table) {
+}
+
+func (codegen *_CodeGen) Enum(compiler *gslang.Compiler, enum *ast.Enum) {
+	codegen.execute("enum", enum)
+}
+
+func (codegen *_CodeGen) Contract(compiler *gslang.Compiler, contract *ast.Contract) {
+	codegen.execute("contract", contract)
+}
+
+// EndScript write the script content into the package module and rewrite the module files up to root package
+func (codegen *_CodeGen) EndScript(compiler *gslang.Compiler) {
+
+	packageName := codegen.script.Package
+
+	codegen.module(packageName).scripts[codegen.script.Name()] = append([]byte(nil), codegen.content.Bytes()...)
+
+	for {
+		codegen.writeModule(packageName)
+
+		index := strings.LastIndex(packageName, ".")
+
+		if index == -1 {
+			break
+		}
+
+		packageName = packageName[:index]
+	}
+
+	if codegen.runtime || codegen.script.Package != rpcPackage {
+		return
+	}
+
+	var stream bytes.Buffer
+
+	if err := codegen.tpl.ExecuteTemplate(&stream, "runtime", nil); err != nil {
+		gserrors.Panicf(err, "exec template(runtime) error")
+	}
+
+	codegen.writeFile(filepath.Join(packagePath(rpcPackage), "runtime.rs"), stream.Bytes())
+
+	codegen.runtime = true
+}
+
+var tidy = gen.NewTidy(1, `[{(\[]`, `[})\]]`)
+
+// doc get the rust doc comment from the .gs comments of node
+func doc(indent string, node ast.Node) string {
+
+	comments := gslang.Comments(node)
+
+	if len(comments) == 0 {
+		return ""
+	}
+
+	var buff bytes.Buffer
+
+	for _, line := range comments {
+		buff.WriteString(strings.TrimRight(indent+"/// "+strings.TrimSpace(line), " ") + "\n")
+	}
+
+	return buff.String()
+}
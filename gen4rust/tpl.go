@@ -0,0 +1,875 @@
+package gen4rust
+
+var t4rust = `
+{{define "enum"}}{{$Enum := title .Name}}
+{{doc "" .}}{{if enumSize . | eq 4}}#[derive(Debug, Clone, Copy, PartialEq, Eq, Hash, PartialOrd, Ord, Default)]
+pub struct {{$Enum}}(pub u32);
+
+impl {{$Enum}} {
+{{range .Constants}}{{doc "    " .}}    pub const {{constName .Name}}: {{$Enum}} = {{$Enum}}({{.Value}});
+{{end}}
+    /// contains check if all the bits of other are set
+    pub fn contains(&self, other: {{$Enum}}) -> bool {
+        self.0 & other.0 == other.0
+    }
+}
+
+impl std::ops::BitOr for {{$Enum}} {
+    type Output = {{$Enum}};
+
+    fn bitor(self, other: {{$Enum}}) -> {{$Enum}} {
+        {{$Enum}}(self.0 | other.0)
+    }
+}
+
+impl Codec for {{$Enum}} {
+    fn read_from<R: std::io::Read>(reader: &mut R) -> {{rpcName "Result"}}<Self> {
+        Ok({{$Enum}}(u32::read_from(reader)?))
+    }
+
+    fn write_to<W: std::io::Write>(&self, writer: &mut W) -> {{rpcName "Result"}}<()> {
+        self.0.write_to(writer)
+    }
+}
+{{else}}#[derive(Debug, Clone, Copy, PartialEq, Eq, Hash, Default)]
+#[repr(u8)]
+pub enum {{$Enum}} {
+{{range $index, $constant := .Constants}}{{doc "    " .}}{{if not $index}}    #[default]
+{{end}}    {{title .Name}} = {{.Value}},
+{{end}}}
+
+// the enum values are ordered by value, so the map keys are written in the same order as go server
+impl PartialOrd for {{$Enum}} {
+    fn partial_cmp(&self, other: &Self) -> Option<std::cmp::Ordering> {
+        Some(self.cmp(other))
+    }
+}
+
+impl Ord for {{$Enum}} {
+    fn cmp(&self, other: &Self) -> std::cmp::Ordering {
+        (*self as u8).cmp(&(*other as u8))
+    }
+}
+
+impl Codec for {{$Enum}} {
+    fn read_from<R: std::io::Read>(reader: &mut R) -> {{rpcName "Result"}}<Self> {
+        match u8::read_from(reader)? {
+{{range .Constants}}            {{.Value}} => Ok({{$Enum}}::{{title .Name}}),
+{{end}}            value => Err({{rpcName "Error"}}::UnknownEnum("{{.FullName}}", value as u32)),
+        }
+    }
+
+    fn write_to<W: std::io::Write>(&self, writer: &mut W) -> {{rpcName "Result"}}<()> {
+        (*self as u8).write_to(writer)
+    }
+}
+{{end}}{{end}}
+
+{{define "table"}}{{$Table := tableName .}}
+{{doc "" .}}{{if .Fields}}#[derive(Debug, Clone, PartialEq)]
+pub struct {{$Table}} {
+{{range .Fields}}{{doc "    " .}}    pub {{memberName .Name}}: {{fieldType .}},
+{{end}}}
+
+impl Default for {{$Table}} {
+    fn default() -> Self {
+        {{$Table}} {
+{{range .Fields}}            {{memberName .Name}}: {{fieldDefault .}},
+{{end}}        }
+    }
+}
+{{else}}#[derive(Debug, Clone, PartialEq, Default)]
+pub struct {{$Table}} {}
+{{end}}
+{{if isException .}}
+impl std::fmt::Display for {{$Table}} {
+    fn fmt(&self, f: &mut std::fmt::Formatter<'_>) -> std::fmt::Result {
+        f.write_str("{{.FullName}}")
+    }
+}
+
+impl std::error::Error for {{$Table}} {}
+{{end}}
+impl Codec for {{$Table}} {
+{{if isPOD .}}    fn read_from<R: std::io::Read>({{if .Fields}}reader{{else}}_reader{{end}}: &mut R) -> {{rpcName "Result"}}<Self> {
+        {{if .Fields}}let mut{{else}}let{{end}} target = {{$Table}}::default();
+{{range .Fields}}
+        target.{{memberName .Name}} = {{if isOptional .}}if bool::read_from(reader)? {
+            {{if isOneOf .Type}}Codec::read_from(reader)?{{else}}Some(Codec::read_from(reader)?){{end}}
+        } else {
+            None
+        };{{else}}Codec::read_from(reader)?;{{end}}
+{{end}}
+        Ok(target)
+    }
+
+    fn write_to<W: std::io::Write>(&self, {{if .Fields}}writer{{else}}_writer{{end}}: &mut W) -> {{rpcName "Result"}}<()> {
+{{range .Fields}}
+        {{if isOptional .}}match &self.{{memberName .Name}} {
+            Some({{if isOneOf .Type}}_{{else}}value{{end}}) => {
+                true.write_to(writer)?;
+                {{if isOneOf .Type}}self.{{memberName .Name}}{{else}}value{{end}}.write_to(writer)?;
+            }
+            None => false.write_to(writer)?,
+        }{{else}}self.{{memberName .Name}}.write_to(writer)?;{{end}}
+{{end}}
+        Ok(())
+    }
+{{else}}    fn read_from<R: std::io::Read>(reader: &mut R) -> {{rpcName "Result"}}<Self> {
+        {{if .Fields}}let mut{{else}}let{{end}} target = {{$Table}}::default();
+
+        {{if .Fields}}let mut{{else}}let{{end}} fields = u16::read_from(reader)? as usize;
+{{range .Fields}}
+        if fields == 0 {
+            return Ok(target);
+        }
+
+        if {{rpcName "read_tags"}}(reader)?[0] != {{rpcName "Tag"}}::Skip as u8 {
+            target.{{memberName .Name}} = {{if and (isOptional .) (not (isOneOf .Type))}}Some(Codec::read_from(reader)?){{else}}Codec::read_from(reader)?{{end}};
+        }
+
+        fields -= 1;
+{{end}}
+        {{rpcName "skip_fields"}}(reader, fields)?;
+
+        Ok(target)
+    }
+
+    fn write_to<W: std::io::Write>(&self, writer: &mut W) -> {{rpcName "Result"}}<()> {
+        {{len .Fields}}u16.write_to(writer)?;
+{{range .Fields}}
+        {{if isOptional .}}match &self.{{memberName .Name}} {
+            Some({{if isOneOf .Type}}_{{else}}value{{end}}) => {
+                {{rpcName "write_tags"}}(writer, &[{{tagValue .Type}}])?;
+                {{if isOneOf .Type}}self.{{memberName .Name}}{{else}}value{{end}}.write_to(writer)?;
+            }
+            None => {{rpcName "write_tags"}}(writer, &[{{rpcName "Tag"}}::Skip as u8])?,
+        }{{else}}{{rpcName "write_tags"}}(writer, &[{{tagValue .Type}}])?;
+        self.{{memberName .Name}}.write_to(writer)?;{{end}}
+{{end}}
+        Ok(())
+    }
+{{end}}}
+{{end}}
+
+{{define "union"}}{{$Union := tableName .}}
+{{doc "" .}}#[derive(Debug, Clone, PartialEq)]
+pub enum {{$Union}} {
+{{range .Fields}}{{doc "    " .}}    {{title .Name}}({{typeName .Type}}),
+{{end}}}
+
+// the union is encoded with the leading variant index, zero means None
+impl Codec for Option<{{$Union}}> {
+    fn read_from<R: std::io::Read>(reader: &mut R) -> {{rpcName "Result"}}<Self> {
+        let variant = u8::read_from(reader)?;
+
+        if variant == 0 {
+            return Ok(None);
+        }
+
+        let tags = {{rpcName "read_tags"}}(reader)?;
+
+        match variant {
+{{range $index, $field := .Fields}}            {{variant $index}} => Ok(Some({{$Union}}::{{title .Name}}(Codec::read_from(reader)?))),
+{{end}}            _ => {
+                {{rpcName "skip"}}(reader, &tags)?;
+
+                Ok(None)
+            }
+        }
+    }
+
+    fn write_to<W: std::io::Write>(&self, writer: &mut W) -> {{rpcName "Result"}}<()> {
+        match self {
+            None => 0u8.write_to(writer),
+{{range $index, $field := .Fields}}            Some({{$Union}}::{{title .Name}}(value)) => {
+                {{variant $index}}u8.write_to(writer)?;
+                {{rpcName "write_tags"}}(writer, &[{{tagValue .Type}}])?;
+                value.write_to(writer)
+            }
+{{end}}        }
+    }
+}
+{{end}}
+
+{{define "contract"}}{{$Contract := title .Name}}{{$Error := printf "%sError" $Contract}}
+{{doc "" .}}pub trait {{$Contract}}: Send + Sync + 'static {
+{{range .Methods}}{{doc "    " .}}    fn {{memberName .Name}}{{params .Params}} -> impl std::future::Future<Output = Result<{{returnType .}}, {{$Error}}>> + Send;
+{{end}}}
+
+/// {{$Error}} the {{$Contract}} method errors, which are the declared exceptions or the rpc error
+#[derive(Debug)]
+pub enum {{$Error}} {
+{{range exceptions .}}    {{exceptionName .}}({{typeName .}}),
+{{end}}    Rpc({{rpcName "Error"}}),
+}
+{{range exceptions .}}
+impl From<{{typeName .}}> for {{$Error}} {
+    fn from(err: {{typeName .}}) -> Self {
+        {{$Error}}::{{exceptionName .}}(err)
+    }
+}
+{{end}}
+impl From<{{rpcName "Error"}}> for {{$Error}} {
+    fn from(err: {{rpcName "Error"}}) -> Self {
+        {{$Error}}::Rpc(err)
+    }
+}
+
+// the exceptions not declared by the dispatched method are reported as remote error
+impl From<{{$Error}}> for {{rpcName "Error"}} {
+    fn from(err: {{$Error}}) -> Self {
+        match err {
+            {{$Error}}::Rpc(err) => err,
+            err => {{rpcName "Error"}}::Remote(err.to_string()),
+        }
+    }
+}
+
+impl std::fmt::Display for {{$Error}} {
+    fn fmt(&self, f: &mut std::fmt::Formatter<'_>) -> std::fmt::Result {
+        match self {
+{{range exceptions .}}            {{$Error}}::{{exceptionName .}}(err) => err.fmt(f),
+{{end}}            {{$Error}}::Rpc(err) => err.fmt(f),
+        }
+    }
+}
+
+impl std::error::Error for {{$Error}} {}
+
+/// {{$Contract}}Dispatcher dispatch the remote calls to {{$Contract}} service
+pub struct {{$Contract}}Dispatcher<S: {{$Contract}}> {
+    id: u16,
+    service: S,
+}
+
+impl<S: {{$Contract}}> {{$Contract}}Dispatcher<S> {
+    pub const NAME: &'static str = "{{.FullName}}";
+
+    pub fn new(id: u16, service: S) -> Self {
+        {{$Contract}}Dispatcher { id, service }
+    }
+}
+
+impl<S: {{$Contract}}> {{rpcName "Dispatcher"}} for {{$Contract}}Dispatcher<S> {
+    fn id(&self) -> u16 {
+        self.id
+    }
+
+    fn dispatch<'a>(&'a self, call: {{rpcName "Request"}}) -> {{rpcName "BoxFuture"}}<'a, {{rpcName "Result"}}<Option<{{rpcName "Response"}}>>> {
+        Box::pin(async move {
+            match call.method {
+{{range .Methods}}                {{.ID}} => {
+                    if call.params.len() != {{.ParamsCount}} {
+                        return Err({{rpcName "Error"}}::InvalidParams(format!("{{$Contract}}#{{title .Name}} expect {{.ParamsCount}} params but got :{}", call.params.len())));
+                    }
+{{range .Params}}
+                    let arg{{.ID}} = {{rpcName "unmarshal"}}(&call.params[{{.ID}}].content)?;
+{{end}}
+{{if isAsync .}}
+                    self.service.{{memberName .Name}}{{callArgs .Params}}.await?;
+
+                    Ok(None)
+{{else}}
+                    let (exception, content) = match self.service.{{memberName .Name}}{{callArgs .Params}}.await {
+                        Ok(ret) => (-1, {{rpcName "marshal"}}(&ret)?),
+{{range .Exceptions}}                        Err({{$Error}}::{{exceptionName .Type}}(err)) => ({{.ID}}, {{rpcName "marshal"}}(&err)?),
+{{end}}                        Err(err) => return Err(err.into()),
+                    };
+
+                    Ok(Some({{rpcName "Response"}} {
+                        id: call.id,
+                        exception,
+                        content,
+                        trace: call.trace,
+                    }))
+{{end}}                }
+{{end}}                _ => Err({{rpcName "Error"}}::UnknownMethod(format!("{{$Contract}}#{}", call.method))),
+            }
+        })
+    }
+}
+
+/// {{$Contract}}RPC the remote {{$Contract}} service proxy
+pub struct {{$Contract}}RPC {
+    channel: std::sync::Arc<{{rpcName "Channel"}}>,
+    service_id: u16,
+    /// timeout the call timeout
+    pub timeout: std::time::Duration,
+}
+
+impl {{$Contract}}RPC {
+    pub fn new(channel: std::sync::Arc<{{rpcName "Channel"}}>, service_id: u16) -> Self {
+        {{$Contract}}RPC {
+            channel,
+            service_id,
+            timeout: std::time::Duration::from_secs(5),
+        }
+    }
+}
+
+impl {{$Contract}} for {{$Contract}}RPC {
+{{range $index, $method := .Methods}}{{if $index}}
+{{end}}    async fn {{memberName .Name}}{{params .Params}} -> Result<{{returnType .}}, {{$Error}}> {
+        let call = {{rpcName "Request"}} {
+            service: self.service_id,
+            method: {{.ID}},
+            params: vec![
+{{range .Params}}                {{rpcName "Param"}} {
+                    content: {{rpcName "marshal"}}(&{{memberName .Name}})?,
+                },
+{{end}}            ],
+            ..Default::default()
+        };
+{{if isAsync .}}
+        self.channel.post(&call)?;
+
+        Ok(())
+{{else}}
+        let call_return = self.channel.call(call, self.timeout).await?;
+
+        match call_return.exception {
+            -1 => Ok({{rpcName "unmarshal"}}(&call_return.content)?),
+            -2 => Err({{rpcName "Error"}}::InvalidArgument({{rpcName "unmarshal"}}(&call_return.content)?).into()),
+{{range .Exceptions}}            {{.ID}} => Err({{$Error}}::{{exceptionName .Type}}({{rpcName "unmarshal"}}(&call_return.content)?)),
+{{end}}            id => Err({{rpcName "Error"}}::Remote(format!("{{$Contract}}#{{title .Name}} unknown exception({})", id)).into()),
+        }
+{{end}}    }
+{{end}}}
+{{end}}
+
+{{define "runtime"}}// generate by gs2rust,don't modify it manually
+//
+// gsrpc runtime: the little endian Codec over std::io::Read/Write and the tokio based Channel over Transport,
+// the package modules are mounted by "mod com;" in crate root, and the channel requires tokio with rt, sync and time features
+
+use std::collections::{BTreeMap, HashMap};
+use std::future::Future;
+use std::io::{Read, Write};
+use std::pin::Pin;
+use std::sync::atomic::{AtomicU32, Ordering};
+use std::sync::{Arc, Mutex};
+
+use tokio::sync::oneshot;
+
+use super::{Code, InvalidArgumentException, Message, Request, Response, Tag};
+
+/// Error the errors raised by runtime and generated codes
+#[derive(Debug)]
+pub enum Error {
+    /// read or write io error, including read beyond the end of input
+    Io(std::io::Error),
+    /// the string is not valid utf8
+    Utf8(std::string::FromUtf8Error),
+    /// the length of string, bytes or list out of uint16 range
+    Length(usize),
+    /// the fixed size array length mismatch
+    ArraySize { expect: usize, got: usize },
+    /// unknown tag of tagged value
+    UnknownTag(u8),
+    /// the enum value is not declared
+    UnknownEnum(&'static str, u32),
+    /// the dispatched call params count mismatch
+    InvalidParams(String),
+    /// the dispatched call method is not declared
+    UnknownMethod(String),
+    /// the remote service rejected the call params, which is the reserved exception -2
+    InvalidArgument(InvalidArgumentException),
+    /// the exception not declared by the method
+    Remote(String),
+    /// the call is not responded in time
+    Timeout(u32),
+    /// the channel is closed before the call is responded
+    Closed,
+}
+
+impl std::fmt::Display for Error {
+    fn fmt(&self, f: &mut std::fmt::Formatter<'_>) -> std::fmt::Result {
+        match self {
+            Error::Io(err) => write!(f, "gsrpc: {}", err),
+            Error::Utf8(err) => write!(f, "gsrpc: {}", err),
+            Error::Length(length) => write!(f, "gsrpc: length {} out of uint16 range", length),
+            Error::ArraySize { expect, got } => write!(f, "gsrpc: expect array size {} but got {}", expect, got),
+            Error::UnknownTag(tag) => write!(f, "gsrpc: unknown tag {}", tag),
+            Error::UnknownEnum(name, value) => write!(f, "gsrpc: unknown {} value {}", name, value),
+            Error::InvalidParams(message) => write!(f, "gsrpc: invalid params {}", message),
+            Error::UnknownMethod(message) => write!(f, "gsrpc: unknown method {}", message),
+            Error::InvalidArgument(err) => write!(f, "gsrpc: {}", err),
+            Error::Remote(message) => write!(f, "gsrpc: remote exception {}", message),
+            Error::Timeout(id) => write!(f, "gsrpc: call({}) timeout", id),
+            Error::Closed => write!(f, "gsrpc: channel closed"),
+        }
+    }
+}
+
+impl std::error::Error for Error {}
+
+impl From<std::io::Error> for Error {
+    fn from(err: std::io::Error) -> Self {
+        Error::Io(err)
+    }
+}
+
+pub type Result<T> = std::result::Result<T, Error>;
+
+/// Codec the gsrpc value encoding, which is implemented by builtin types and generated types
+pub trait Codec: Sized {
+    fn read_from<R: Read>(reader: &mut R) -> Result<Self>;
+
+    fn write_to<W: Write>(&self, writer: &mut W) -> Result<()>;
+}
+
+macro_rules! number_codec {
+    ($($t:ty),*) => {$(
+        impl Codec for $t {
+            fn read_from<R: Read>(reader: &mut R) -> Result<Self> {
+                let mut buff = [0u8; std::mem::size_of::<$t>()];
+
+                reader.read_exact(&mut buff)?;
+
+                Ok(<$t>::from_le_bytes(buff))
+            }
+
+            fn write_to<W: Write>(&self, writer: &mut W) -> Result<()> {
+                writer.write_all(&self.to_le_bytes())?;
+
+                Ok(())
+            }
+        }
+    )*};
+}
+
+number_codec!(i8, u8, i16, u16, i32, u32, i64, u64, f32, f64);
+
+impl Codec for bool {
+    fn read_from<R: Read>(reader: &mut R) -> Result<Self> {
+        Ok(u8::read_from(reader)? != 0)
+    }
+
+    fn write_to<W: Write>(&self, writer: &mut W) -> Result<()> {
+        (*self as u8).write_to(writer)
+    }
+}
+
+// the void return value
+impl Codec for () {
+    fn read_from<R: Read>(_reader: &mut R) -> Result<Self> {
+        Ok(())
+    }
+
+    fn write_to<W: Write>(&self, _writer: &mut W) -> Result<()> {
+        Ok(())
+    }
+}
+
+fn read_length<R: Read>(reader: &mut R) -> Result<usize> {
+    Ok(u16::read_from(reader)? as usize)
+}
+
+fn write_length<W: Write>(writer: &mut W, length: usize) -> Result<()> {
+    if length > u16::MAX as usize {
+        return Err(Error::Length(length));
+    }
+
+    (length as u16).write_to(writer)
+}
+
+impl Codec for String {
+    fn read_from<R: Read>(reader: &mut R) -> Result<Self> {
+        let mut buff = vec![0u8; read_length(reader)?];
+
+        reader.read_exact(&mut buff)?;
+
+        String::from_utf8(buff).map_err(Error::Utf8)
+    }
+
+    fn write_to<W: Write>(&self, writer: &mut W) -> Result<()> {
+        write_length(writer, self.len())?;
+
+        writer.write_all(self.as_bytes())?;
+
+        Ok(())
+    }
+}
+
+// the byte[] is encoded as the same as list of byte
+impl<T: Codec> Codec for Vec<T> {
+    fn read_from<R: Read>(reader: &mut R) -> Result<Self> {
+        let length = read_length(reader)?;
+
+        let mut list = Vec::with_capacity(length);
+
+        for _ in 0..length {
+            list.push(T::read_from(reader)?);
+        }
+
+        Ok(list)
+    }
+
+    fn write_to<W: Write>(&self, writer: &mut W) -> Result<()> {
+        write_length(writer, self.len())?;
+
+        for v in self {
+            v.write_to(writer)?;
+        }
+
+        Ok(())
+    }
+}
+
+impl<T: Codec, const N: usize> Codec for [T; N] {
+    fn read_from<R: Read>(reader: &mut R) -> Result<Self> {
+        Vec::<T>::read_from(reader)?
+            .try_into()
+            .map_err(|list: Vec<T>| Error::ArraySize { expect: N, got: list.len() })
+    }
+
+    fn write_to<W: Write>(&self, writer: &mut W) -> Result<()> {
+        write_length(writer, N)?;
+
+        for v in self {
+            v.write_to(writer)?;
+        }
+
+        Ok(())
+    }
+}
+
+// the map entries are written in key order, so the same map is always encoded as same bytes
+impl<K: Codec + Ord, V: Codec> Codec for BTreeMap<K, V> {
+    fn read_from<R: Read>(reader: &mut R) -> Result<Self> {
+        let length = read_length(reader)?;
+
+        let mut map = BTreeMap::new();
+
+        for _ in 0..length {
+            let key = K::read_from(reader)?;
+
+            map.insert(key, V::read_from(reader)?);
+        }
+
+        Ok(map)
+    }
+
+    fn write_to<W: Write>(&self, writer: &mut W) -> Result<()> {
+        write_length(writer, self.len())?;
+
+        for (key, value) in self {
+            key.write_to(writer)?;
+            value.write_to(writer)?;
+        }
+
+        Ok(())
+    }
+}
+
+// the com.gsrpc.Tag bytes matched by the tag sequence readers and skippers
+{{range wireCases}}{{range .Tags}}const TAG_{{constName (printf "%s" .)}}: u8 = Tag::{{.}} as u8;
+{{end}}{{end}}
+/// read_tags read the tag sequence of tagged value, the nested container and POD tags are followed by their component tags
+pub fn read_tags<R: Read>(reader: &mut R) -> Result<Vec<u8>> {
+    let mut tags = Vec::new();
+
+    read_tags_into(reader, &mut tags)?;
+
+    Ok(tags)
+}
+
+fn read_tags_into<R: Read>(reader: &mut R, tags: &mut Vec<u8>) -> Result<()> {
+    let tag = u8::read_from(reader)?;
+
+    tags.push(tag);
+
+    let nested = match tag {
+{{range wireNested}}        {{range $j, $tag := .Tags}}{{if $j}} | {{end}}TAG_{{constName (printf "%s" $tag)}}{{end}} => {{if lt .Nested 0}}{
+            let count = u8::read_from(reader)?;
+
+            tags.push(count);
+
+            count as usize
+        }{{else}}{{.Nested}}{{end}},
+{{end}}        _ => 0,
+    };
+
+    for _ in 0..nested {
+        read_tags_into(reader, tags)?;
+    }
+
+    Ok(())
+}
+
+/// write_tags write the tag sequence of tagged value
+pub fn write_tags<W: Write>(writer: &mut W, tags: &[u8]) -> Result<()> {
+    writer.write_all(tags)?;
+
+    Ok(())
+}
+
+/// skip skip the value described by the tag sequence
+pub fn skip<R: Read>(reader: &mut R, tags: &[u8]) -> Result<()> {
+    skip_tags(reader, tags, 0)
+}
+
+/// skip_fields skip the unknown fields of tagged table
+pub fn skip_fields<R: Read>(reader: &mut R, fields: usize) -> Result<()> {
+    for _ in 0..fields {
+        let tags = read_tags(reader)?;
+
+        skip(reader, &tags)?;
+    }
+
+    Ok(())
+}
+
+fn skip_bytes<R: Read>(reader: &mut R, length: usize) -> Result<()> {
+    let skipped = std::io::copy(&mut reader.take(length as u64), &mut std::io::sink())?;
+
+    if skipped != length as u64 {
+        return Err(Error::Io(std::io::ErrorKind::UnexpectedEof.into()));
+    }
+
+    Ok(())
+}
+
+// skip_components skip the values of count tag sequences begin with index
+fn skip_components<R: Read>(reader: &mut R, tags: &[u8], index: usize, count: usize) -> Result<()> {
+    let mut index = index;
+
+    for _ in 0..count {
+        skip_tags(reader, tags, index)?;
+
+        index = tags_end(tags, index);
+    }
+
+    Ok(())
+}
+
+fn skip_tags<R: Read>(reader: &mut R, tags: &[u8], index: usize) -> Result<()> {
+    match tags[index] {
+{{range wireCases}}        {{range $j, $tag := .Tags}}{{if $j}} | {{end}}TAG_{{constName (printf "%s" $tag)}}{{end}} => {{if eq .Kind "none"}}Ok(()),
+{{else if eq .Kind "fixed"}}skip_bytes(reader, {{.Size}}),
+{{else if eq .Kind "sized"}}{
+            let length = read_length(reader)?;
+
+            skip_bytes(reader, length)
+        }
+{{else if eq .Kind "fields"}}{
+            let fields = read_length(reader)?;
+
+            skip_fields(reader, fields)
+        }
+{{else if eq .Kind "seq"}}{
+            for _ in 0..read_length(reader)? {
+                skip_components(reader, tags, index + 1, {{.Nested}})?;
+            }
+
+            Ok(())
+        }
+{{else if eq .Kind "record"}}skip_components(reader, tags, index + 2, tags[index + 1] as usize),
+{{else if eq .Kind "optional"}}{
+            if bool::read_from(reader)? {
+                skip_tags(reader, tags, index + 1)?;
+            }
+
+            Ok(())
+        }
+{{else if eq .Kind "variant"}}{
+            if u8::read_from(reader)? != 0 {
+                let tags = read_tags(reader)?;
+
+                skip(reader, &tags)?;
+            }
+
+            Ok(())
+        }
+{{end}}{{end}}        tag => Err(Error::UnknownTag(tag)),
+    }
+}
+
+// tags_end get the index next to the tag sequence begin with index
+fn tags_end(tags: &[u8], index: usize) -> usize {
+    let (mut next, nested) = match tags[index] {
+{{range wireNested}}        {{range $j, $tag := .Tags}}{{if $j}} | {{end}}TAG_{{constName (printf "%s" $tag)}}{{end}} => {{if lt .Nested 0}}(index + 2, tags[index + 1] as usize){{else}}(index + 1, {{.Nested}}){{end}},
+{{end}}        _ => (index + 1, 0),
+    };
+
+    for _ in 0..nested {
+        next = tags_end(tags, next);
+    }
+
+    next
+}
+
+/// marshal encode value into bytes
+pub fn marshal<T: Codec>(value: &T) -> Result<Vec<u8>> {
+    let mut buff = Vec::new();
+
+    value.write_to(&mut buff)?;
+
+    Ok(buff)
+}
+
+/// unmarshal decode value from content
+pub fn unmarshal<T: Codec>(content: &[u8]) -> Result<T> {
+    let mut reader = content;
+
+    T::read_from(&mut reader)
+}
+
+pub type BoxFuture<'a, T> = Pin<Box<dyn Future<Output = T> + Send + 'a>>;
+
+/// Transport the message transport of channel, one message per send, the received messages
+/// are passed to Channel::received and the transport close is notified by Channel::closed
+pub trait Transport: Send + Sync + 'static {
+    fn send(&self, data: Vec<u8>) -> Result<()>;
+
+    fn close(&self);
+}
+
+/// Dispatcher the service dispatcher registered into channel by service id
+pub trait Dispatcher: Send + Sync + 'static {
+    fn id(&self) -> u16;
+
+    fn dispatch<'a>(&'a self, call: Request) -> BoxFuture<'a, Result<Option<Response>>>;
+}
+
+/// Channel the rpc channel which send requests to and dispatch requests from the peer
+pub struct Channel {
+    transport: Box<dyn Transport>,
+    seq: AtomicU32,
+    pending: Mutex<HashMap<u32, oneshot::Sender<Response>>>,
+    dispatchers: Mutex<HashMap<u16, Arc<dyn Dispatcher>>>,
+    on_error: Mutex<Option<Arc<dyn Fn(Error) + Send + Sync>>>,
+}
+
+impl Channel {
+    pub fn new<T: Transport>(transport: T) -> Arc<Channel> {
+        Arc::new(Channel {
+            transport: Box::new(transport),
+            seq: AtomicU32::new(0),
+            pending: Mutex::new(HashMap::new()),
+            dispatchers: Mutex::new(HashMap::new()),
+            on_error: Mutex::new(None),
+        })
+    }
+
+    pub fn register<D: Dispatcher>(&self, dispatcher: D) {
+        self.dispatchers.lock().unwrap().insert(dispatcher.id(), Arc::new(dispatcher));
+    }
+
+    pub fn unregister(&self, id: u16) {
+        self.dispatchers.lock().unwrap().remove(&id);
+    }
+
+    /// on_error set the handler invoked when the dispatcher return error or the received message is invalid
+    pub fn on_error<F: Fn(Error) + Send + Sync + 'static>(&self, handler: F) {
+        *self.on_error.lock().unwrap() = Some(Arc::new(handler));
+    }
+
+    /// call send the request and wait the response, Error::Timeout is returned after timeout
+    pub async fn call(&self, mut call: Request, timeout: std::time::Duration) -> Result<Response> {
+        call.id = self.seq.fetch_add(1, Ordering::Relaxed).wrapping_add(1);
+
+        let id = call.id;
+
+        let (sender, receiver) = oneshot::channel();
+
+        self.pending.lock().unwrap().insert(id, sender);
+
+        if let Err(err) = marshal(&call).and_then(|content| self.send(Code::Request, content)) {
+            self.pending.lock().unwrap().remove(&id);
+
+            return Err(err);
+        }
+
+        match tokio::time::timeout(timeout, receiver).await {
+            Ok(Ok(call_return)) => Ok(call_return),
+            Ok(Err(_)) => Err(Error::Closed),
+            Err(_) => {
+                self.pending.lock().unwrap().remove(&id);
+
+                Err(Error::Timeout(id))
+            }
+        }
+    }
+
+    /// post send the request without waiting response
+    pub fn post(&self, call: &Request) -> Result<()> {
+        self.send(Code::Request, marshal(call)?)
+    }
+
+    pub fn close(&self) {
+        self.transport.close();
+    }
+
+    /// received handle the message received by transport, the requests are dispatched in spawned tokio tasks
+    pub fn received(self: &Arc<Self>, data: &[u8]) {
+        if let Err(err) = self.handle(data) {
+            self.error(err);
+        }
+    }
+
+    /// closed fail the pending calls with Error::Closed
+    pub fn closed(&self) {
+        self.pending.lock().unwrap().clear();
+    }
+
+    fn send(&self, code: Code, content: Vec<u8>) -> Result<()> {
+        let message = Message { code, agent: 0, content };
+
+        self.transport.send(marshal(&message)?)
+    }
+
+    fn handle(self: &Arc<Self>, data: &[u8]) -> Result<()> {
+        let message: Message = unmarshal(data)?;
+
+        match message.code {
+            Code::Response => {
+                let call_return: Response = unmarshal(&message.content)?;
+
+                if let Some(sender) = self.pending.lock().unwrap().remove(&call_return.id) {
+                    let _ = sender.send(call_return);
+                }
+            }
+            Code::Request => {
+                let call: Request = unmarshal(&message.content)?;
+
+                let channel = self.clone();
+
+                tokio::spawn(async move { channel.dispatch(call).await });
+            }
+            Code::Heartbeat => self.send(Code::Heartbeat, Vec::new())?,
+            _ => {}
+        }
+
+        Ok(())
+    }
+
+    async fn dispatch(&self, call: Request) {
+        let dispatcher = self.dispatchers.lock().unwrap().get(&call.service).cloned();
+
+        let Some(dispatcher) = dispatcher else {
+            return;
+        };
+
+        let result = match dispatcher.dispatch(call).await {
+            Ok(Some(call_return)) => marshal(&call_return).and_then(|content| self.send(Code::Response, content)),
+            Ok(None) => Ok(()),
+            Err(err) => Err(err),
+        };
+
+        if let Err(err) = result {
+            self.error(err);
+        }
+    }
+
+    fn error(&self, err: Error) {
+        let handler = self.on_error.lock().unwrap().clone();
+
+        if let Some(handler) = handler {
+            handler(err);
+        }
+    }
+}
+{{end}}
+`
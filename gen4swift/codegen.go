@@ -0,0 +1,760 @@
+package gen4swift
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+	"unicode"
+
+	"github.com/gsdocker/gserrors"
+	"github.com/gsdocker/gslogger"
+	"github.com/gsrpc/gslang"
+	"github.com/gsrpc/gslang/ast"
+	"github.com/gsrpc/gslang/lexer"
+	"github.com/gsrpc/gsrpc/gen"
+	"github.com/gsrpc/gsrpc/wire"
+)
+
+var builtin = map[lexer.TokenType]string{
+	lexer.KeySByte:   "Int8",
+	lexer.KeyByte:    "UInt8",
+	lexer.KeyInt16:   "Int16",
+	lexer.KeyUInt16:  "UInt16",
+	lexer.KeyInt32:   "Int32",
+	lexer.KeyUInt32:  "UInt32",
+	lexer.KeyInt64:   "Int64",
+	lexer.KeyUInt64:  "UInt64",
+	lexer.KeyFloat32: "Float",
+	lexer.KeyFloat64: "Double",
+	lexer.KeyBool:    "Bool",
+	lexer.KeyString:  "String",
+	lexer.KeyVoid:    "Void",
+}
+
+var readMapping = map[lexer.TokenType]string{
+	lexer.KeySByte:   "try reader.readInt8()",
+	lexer.KeyByte:    "try reader.readUInt8()",
+	lexer.KeyInt16:   "try reader.readInt16()",
+	lexer.KeyUInt16:  "try reader.readUInt16()",
+	lexer.KeyInt32:   "try reader.readInt32()",
+	lexer.KeyUInt32:  "try reader.readUInt32()",
+	lexer.KeyInt64:   "try reader.readInt64()",
+	lexer.KeyUInt64:  "try reader.readUInt64()",
+	lexer.KeyFloat32: "try reader.readFloat32()",
+	lexer.KeyFloat64: "try reader.readFloat64()",
+	lexer.KeyBool:    "try reader.readBool()",
+	lexer.KeyString:  "try reader.readString()",
+}
+
+var writeMapping = map[lexer.TokenType]string{
+	lexer.KeySByte:   "writer.writeInt8",
+	lexer.KeyByte:    "writer.writeUInt8",
+	lexer.KeyInt16:   "writer.writeInt16",
+	lexer.KeyUInt16:  "writer.writeUInt16",
+	lexer.KeyInt32:   "writer.writeInt32",
+	lexer.KeyUInt32:  "writer.writeUInt32",
+	lexer.KeyInt64:   "writer.writeInt64",
+	lexer.KeyUInt64:  "writer.writeUInt64",
+	lexer.KeyFloat32: "writer.writeFloat32",
+	lexer.KeyFloat64: "writer.writeFloat64",
+	lexer.KeyBool:    "writer.writeBool",
+	lexer.KeyString:  "writer.writeString",
+}
+
+var defaultval = map[lexer.TokenType]string{
+	lexer.KeySByte:   "0",
+	lexer.KeyByte:    "0",
+	lexer.KeyInt16:   "0",
+	lexer.KeyUInt16:  "0",
+	lexer.KeyInt32:   "0",
+	lexer.KeyUInt32:  "0",
+	lexer.KeyInt64:   "0",
+	lexer.KeyUInt64:  "0",
+	lexer.KeyFloat32: "0",
+	lexer.KeyFloat64: "0",
+	lexer.KeyBool:    "false",
+	lexer.KeyString:  "\"\"",
+}
+
+// keywords the swift reserved words, which are escaped with backticks when used as identifiers
+var keywords = map[string]bool{
+	"associatedtype": true, "class": true, "deinit": true, "enum": true, "extension": true, "fileprivate": true,
+	"func": true, "import": true, "init": true, "inout": true, "internal": true, "let": true, "open": true,
+	"operator": true, "private": true, "protocol": true, "public": true, "rethrows": true, "static": true,
+	"struct": true, "subscript": true, "typealias": true, "var": true, "break": true, "case": true,
+	"continue": true, "default": true, "defer": true, "do": true, "else": true, "fallthrough": true, "for": true,
+	"guard": true, "if": true, "in": true, "repeat": true, "return": true, "switch": true, "where": true,
+	"while": true, "as": true, "catch": true, "false": true, "is": true, "nil": true, "super": true, "self": true,
+	"throw": true, "throws": true, "true": true, "try": true, "await": true, "async": true,
+}
+
+// runtimeFile the runtime source file name, which is generated in the com.gsrpc package directory
+const runtimeFile = "Runtime"
+
+type _CodeGen struct {
+	gslogger.Log                    // Log APIs
+	rootpath     string             // root path
+	files        map[string][]byte  // generated files sink, the files are written into rootpath if nil
+	script       *ast.Script        // current script
+	content      bytes.Buffer       // current script content
+	tpl          *template.Template // code generate template
+	skips        []*regexp.Regexp   // skip lists
+	compiler     *gslang.Compiler   // current compiler
+	namespaces   map[string]bool    // declared package namespaces
+	runtime      bool               // runtime file is generated
+}
+
+// NewCodeGen .
+func NewCodeGen(rootpath string, skips []string) (gslang.Visitor, error) {
+
+	codeGen := &_CodeGen{
+		Log:        gslogger.Get("gen4swift"),
+		rootpath:   rootpath,
+		namespaces: make(map[string]bool),
+	}
+
+	for _, skip := range skips {
+		exp, err := regexp.Compile(skip)
+
+		if err != nil {
+			return nil, gserrors.Newf(err, "invalid skip regex string :%s", skip)
+		}
+
+		codeGen.skips = append(codeGen.skips, exp)
+	}
+
+	funcs := template.FuncMap{
+		"title":        strings.Title,
+		"tableName":    gen.TableName,
+		"memberName":   memberName,
+		"doc":          doc,
+		"notVoid":      gslang.NotVoid,
+		"isPOD":        gslang.IsPOD,
+		"isAsync":      gslang.IsAsync,
+		"isException":  gslang.IsException,
+		"enumSize":     gslang.EnumSize,
+		"isOptional":   gen.IsOptional,
+		"variant":      gen.Variant,
+		"typeName":     codeGen.typeName,
+		"fieldType":    codeGen.fieldType,
+		"readType":     codeGen.readType,
+		"writeType":    codeGen.writeType,
+		"tagValue":     codeGen.tagValue,
+		"wireCases":    wire.Cases,
+		"wireNested":   wire.Nested,
+		"rpcName":      rpcName,
+		"fieldDefault": codeGen.fieldDefault,
+		"params":       codeGen.params,
+		"returnType":   codeGen.returnType,
+		"callArgs":     callArgs,
+	}
+
+	tpl, err := template.New("t4swift").Funcs(funcs).Parse(t4swift)
+
+	if err != nil {
+		return nil, err
+	}
+
+	codeGen.tpl = tpl
+
+	return codeGen, nil
+}
+
+// NewCodeGenWithFiles create codegen which write generated files into files map keyed by slash separated relative path
+func NewCodeGenWithFiles(files map[string][]byte, skips []string) (gslang.Visitor, error) {
+
+	codeGen, err := NewCodeGen("", skips)
+
+	if err != nil {
+		return nil, err
+	}
+
+	codeGen.(*_CodeGen).files = files
+
+	return codeGen, nil
+}
+
+// lowerName get the lower camel case name, the leading acronym is lowered as a whole, e.g. OSVersion -> osVersion
+func lowerName(name string) string {
+
+	runes := []rune(name)
+
+	upper := 0
+
+	for upper < len(runes) && unicode.IsUpper(runes[upper]) {
+		upper++
+	}
+
+	if upper > 1 && upper < len(runes) && unicode.IsLower(runes[upper]) {
+		upper--
+	}
+
+	for i := 0; i < upper; i++ {
+		runes[i] = unicode.ToLower(runes[i])
+	}
+
+	return string(runes)
+}
+
+// memberName get the property/case/method/param name, swift keyword is escaped with backticks
+func memberName(name string) string {
+
+	name = lowerName(name)
+
+	if keywords[name] {
+		return "`" + name + "`"
+	}
+
+	return name
+}
+
+// namespace get the namespace enum name of package, e.g. com.gsrpc.test -> ComGsrpcTest
+func namespace(packageName string) string {
+
+	var buff bytes.Buffer
+
+	for _, name := range strings.Split(packageName, ".") {
+		buff.WriteString(strings.Title(name))
+	}
+
+	return buff.String()
+}
+
+// qualify get the symbol reference name, symbols of other packages are referenced by the namespace enum
+func (codegen *_CodeGen) qualify(packageName string, name string) string {
+
+	if packageName == codegen.script.Package {
+		return name
+	}
+
+	return namespace(packageName) + "." + name
+}
+
+// rpcName get the full qualified runtime symbol name, which is always qualified to avoid nested type lookup ambiguity
+func rpcName(name string) string {
+	return namespace("com.gsrpc") + "." + name
+}
+
+func (codegen *_CodeGen) tagValue(typeDecl ast.Type) string {
+	return strings.Join(codegen.tags(typeDecl), ", ")
+}
+
+// tags get the type tag sequence by the shared tag scheme
+func (codegen *_CodeGen) tags(typeDecl ast.Type) []string {
+
+	tag := rpcName("Tag")
+
+	return wire.Format(wire.Tags(typeDecl), func(t wire.Tag) string {
+		return tag + "." + memberName(t.String()) + ".rawValue"
+	})
+}
+
+// declName get the declared type name, the union type name is not wrapped as Optional
+func (codegen *_CodeGen) declName(typeDecl ast.Type) string {
+	if _, ok := typeDecl.(*ast.Enum); ok {
+		return codegen.qualify(typeDecl.Package(), strings.Title(typeDecl.Name()))
+	}
+
+	return codegen.qualify(typeDecl.Package(), gen.TableName(typeDecl))
+}
+
+func (codegen *_CodeGen) typeName(typeDecl ast.Type) string {
+	switch typeDecl.(type) {
+	case *ast.BuiltinType:
+		builtinType := typeDecl.(*ast.BuiltinType)
+
+		return builtin[builtinType.Type]
+	case *ast.TypeRef:
+		return codegen.typeName(typeDecl.(*ast.TypeRef).Ref)
+
+	case *ast.Enum:
+		return codegen.declName(typeDecl)
+
+	case *ast.Table:
+		// the union is nil when none of the variants is set
+		if gen.IsOneOf(typeDecl) {
+			return codegen.declName(typeDecl) + "?"
+		}
+
+		return codegen.declName(typeDecl)
+
+	case *ast.Seq:
+		seq := typeDecl.(*ast.Seq)
+
+		if gen.IsBytes(seq) {
+			return "Data"
+		}
+
+		if entry, ok := wire.MapEntry(seq); ok {
+			return fmt.Sprintf("[%s: %s]", codegen.typeName(entry.Fields[0].Type), codegen.typeName(entry.Fields[1].Type))
+		}
+
+		return fmt.Sprintf("[%s]", codegen.typeName(seq.Component))
+	}
+
+	gserrors.Panicf(nil, "typeName  error: unsupport type(%s)", typeDecl)
+
+	return "unknown"
+}
+
+// fieldType get the field type name, optional field is generated as Optional
+func (codegen *_CodeGen) fieldType(field *ast.Field) string {
+
+	if gen.IsOptional(field) && !gen.IsOneOf(field.Type) {
+		return codegen.typeName(field.Type) + "?"
+	}
+
+	return codegen.typeName(field.Type)
+}
+
+func (codegen *_CodeGen) defaultVal(typeDecl ast.Type) string {
+	switch typeDecl.(type) {
+	case *ast.BuiltinType:
+		builtinType := typeDecl.(*ast.BuiltinType)
+
+		return defaultval[builtinType.Type]
+	case *ast.TypeRef:
+		return codegen.defaultVal(typeDecl.(*ast.TypeRef).Ref)
+
+	case *ast.Enum:
+		enum := typeDecl.(*ast.Enum)
+
+		return codegen.declName(enum) + "." + memberName(enum.Constants[0].Name())
+
+	case *ast.Table:
+		if gen.IsOneOf(typeDecl) {
+			return "nil"
+		}
+
+		return codegen.declName(typeDecl) + "()"
+
+	case *ast.Seq:
+		seq := typeDecl.(*ast.Seq)
+
+		if gen.IsBytes(seq) {
+			if seq.Size != -1 {
+				return fmt.Sprintf("Data(count: %d)", seq.Size)
+			}
+
+			return "Data()"
+		}
+
+		if _, ok := wire.MapEntry(seq); ok {
+			return "[:]"
+		}
+
+		if seq.Size != -1 {
+			return fmt.Sprintf("Array(repeating: %s, count: %d)", codegen.defaultVal(seq.Component), seq.Size)
+		}
+
+		return "[]"
+	}
+
+	gserrors.Panicf(nil, "defaultVal  error: unsupport type(%s)", typeDecl)
+
+	return "unknown"
+}
+
+// readType get the expr which read the type value from the reader variable
+func (codegen *_CodeGen) readType(typeDecl ast.Type) string {
+	switch typeDecl.(type) {
+	case *ast.BuiltinType:
+		builtinType := typeDecl.(*ast.BuiltinType)
+
+		return readMapping[builtinType.Type]
+	case *ast.TypeRef:
+		return codegen.readType(typeDecl.(*ast.TypeRef).Ref)
+
+	case *ast.Enum:
+		if gslang.EnumSize(typeDecl) == 4 {
+			return fmt.Sprintf("%s(rawValue: try reader.readUInt32())", codegen.declName(typeDecl))
+		}
+
+		return fmt.Sprintf("try reader.readEnum(%s.self)", codegen.declName(typeDecl))
+
+	case *ast.Table:
+		if gen.IsOneOf(typeDecl) {
+			return fmt.Sprintf("try %s.read(from: reader)", codegen.declName(typeDecl))
+		}
+
+		return fmt.Sprintf("try %s(from: reader)", codegen.declName(typeDecl))
+
+	case *ast.Seq:
+		seq := typeDecl.(*ast.Seq)
+
+		if gen.IsBytes(seq) {
+			if seq.Size != -1 {
+				return fmt.Sprintf("try reader.readFixedBytes(%d)", seq.Size)
+			}
+
+			return "try reader.readBytes()"
+		}
+
+		if entry, ok := wire.MapEntry(seq); ok {
+			return fmt.Sprintf("try reader.readMap({ %s }, { %s })", codegen.readType(entry.Fields[0].Type), codegen.readType(entry.Fields[1].Type))
+		}
+
+		if seq.Size != -1 {
+			return fmt.Sprintf("try reader.readArray(%d) { %s }", seq.Size, codegen.readType(seq.Component))
+		}
+
+		return fmt.Sprintf("try reader.readList { %s }", codegen.readType(seq.Component))
+	}
+
+	gserrors.Panicf(nil, "readType  error: unsupport type(%s)", typeDecl)
+
+	return "unknown"
+}
+
+// writeType get the statement which write the valname to the writer variable, depth is used to name the closure args,
+// only the throwing statements are prefixed with try
+func (codegen *_CodeGen) writeType(typeDecl ast.Type, valname string, depth int) string {
+	switch typeDecl.(type) {
+	case *ast.BuiltinType:
+		builtinType := typeDecl.(*ast.BuiltinType)
+
+		return fmt.Sprintf("%s(%s)", writeMapping[builtinType.Type], valname)
+	case *ast.TypeRef:
+		return codegen.writeType(typeDecl.(*ast.TypeRef).Ref, valname, depth)
+
+	case *ast.Enum:
+		if gslang.EnumSize(typeDecl) == 4 {
+			return fmt.Sprintf("writer.writeUInt32(%s.rawValue)", valname)
+		}
+
+		return fmt.Sprintf("writer.writeUInt8(%s.rawValue)", valname)
+
+	case *ast.Table:
+		if gen.IsOneOf(typeDecl) {
+			return fmt.Sprintf("try %s.write(%s, to: writer)", codegen.declName(typeDecl), valname)
+		}
+
+		return fmt.Sprintf("try %s.write(to: writer)", valname)
+
+	case *ast.Seq:
+		seq := typeDecl.(*ast.Seq)
+
+		if gen.IsBytes(seq) {
+			if seq.Size != -1 {
+				return fmt.Sprintf("try writer.writeFixedBytes(%d, %s)", seq.Size, valname)
+			}
+
+			return fmt.Sprintf("writer.writeBytes(%s)", valname)
+		}
+
+		key, val := fmt.Sprintf("k%d", depth), fmt.Sprintf("v%d", depth)
+
+		if entry, ok := wire.MapEntry(seq); ok {
+			return fmt.Sprintf("try writer.writeMap(%s, { %s in %s }, { %s in %s })", valname,
+				key, codegen.writeType(entry.Fields[0].Type, key, depth+1),
+				val, codegen.writeType(entry.Fields[1].Type, val, depth+1))
+		}
+
+		if seq.Size != -1 {
+			return fmt.Sprintf("try writer.writeArray(%d, %s) { %s in %s }", seq.Size, valname, val, codegen.writeType(seq.Component, val, depth+1))
+		}
+
+		return fmt.Sprintf("try writer.writeList(%s) { %s in %s }", valname, val, codegen.writeType(seq.Component, val, depth+1))
+	}
+
+	gserrors.Panicf(nil, "writeType  error: unsupport type(%s)", typeDecl)
+
+	return "unknown"
+}
+
+// fieldDefault get the init param default value expr, optional field without default value is nil
+func (codegen *_CodeGen) fieldDefault(field *ast.Field) string {
+
+	expr, ok := gen.Default(field)
+
+	if !ok {
+		if gen.IsOptional(field) {
+			return "nil"
+		}
+
+		return codegen.defaultVal(field.Type)
+	}
+
+	start, _ := gslang.Pos(field)
+
+	return codegen.defaultExpr(expr, field.Type, start)
+}
+
+func (codegen *_CodeGen) defaultExpr(expr ast.Expr, typeDecl ast.Type, start lexer.Position) string {
+
+	eval := codegen.compiler.Eval()
+
+	switch typeDecl.(type) {
+	case *ast.BuiltinType:
+		builtinType := typeDecl.(*ast.BuiltinType)
+
+		gen.CheckUnsigned(eval, builtinType, expr, start)
+
+		switch builtinType.Type {
+		case lexer.KeyString:
+			return quote(eval.EvalString(expr))
+		case lexer.KeyBool:
+			return strconv.FormatBool(eval.EvalBool(expr))
+		case lexer.KeyFloat32:
+			return strconv.FormatFloat(eval.EvalFloat(expr), 'g', -1, 32)
+		case lexer.KeyFloat64:
+			return strconv.FormatFloat(eval.EvalFloat(expr), 'g', -1, 64)
+		case lexer.KeyVoid:
+		default:
+			return fmt.Sprintf("%d", eval.EvalInt(expr))
+		}
+
+	case *ast.TypeRef:
+		return codegen.defaultExpr(expr, typeDecl.(*ast.TypeRef).Ref, start)
+
+	case *ast.Enum:
+		enum := typeDecl.(*ast.Enum)
+
+		val := eval.EvalInt(expr)
+
+		if constant, ok := gen.Constant(enum, val); ok {
+			return codegen.declName(enum) + "." + memberName(constant.Name())
+		}
+
+		gserrors.Panicf(nil, "enum %s constant(%d) not found :%v", enum, val, start)
+
+	case *ast.Table:
+		table := typeDecl.(*ast.Table)
+
+		newObj, ok := expr.(*ast.NewObj)
+
+		if !ok || gen.IsOneOf(table) {
+			break
+		}
+
+		// the fields without arg keep the init param default values
+		var fields []string
+
+		for i, field := range table.Fields {
+
+			arg, ok := gen.FieldArg(newObj, i, field)
+
+			if ok {
+				fields = append(fields, fmt.Sprintf("%s: %s", memberName(field.Name()), codegen.defaultExpr(arg, field.Type, start)))
+			}
+		}
+
+		return fmt.Sprintf("%s(%s)", codegen.declName(table), strings.Join(fields, ", "))
+	}
+
+	gserrors.Panicf(nil, "unsupport default value for type(%s) :%v", typeDecl, start)
+
+	return "unknown"
+}
+
+// quote get the swift string literal, the control characters are escaped as \u{} sequence
+func quote(val string) string {
+
+	var buff bytes.Buffer
+
+	buff.WriteString("\"")
+
+	for _, r := range val {
+		switch {
+		case r == '"':
+			buff.WriteString("\\\"")
+		case r == '\\':
+			buff.WriteString("\\\\")
+		case r == '\n':
+			buff.WriteString("\\n")
+		case r == '\r':
+			buff.WriteString("\\r")
+		case r == '\t':
+			buff.WriteString("\\t")
+		case r < 0x20 || r == 0x7f:
+			buff.WriteString(fmt.Sprintf("\\u{%x}", r))
+		default:
+			buff.WriteRune(r)
+		}
+	}
+
+	buff.WriteString("\"")
+
+	return buff.String()
+}
+
+func (codegen *_CodeGen) params(params []*ast.Param) string {
+
+	var args []string
+
+	for _, param := range params {
+		args = append(args, fmt.Sprintf("%s: %s", memberName(param.Name()), codegen.typeName(param.Type)))
+	}
+
+	return "(" + strings.Join(args, ", ") + ")"
+}
+
+func callArgs(params []*ast.Param) string {
+
+	var args []string
+
+	for _, param := range params {
+		args = append(args, fmt.Sprintf("%s: arg%d", memberName(param.Name()), param.ID))
+	}
+
+	return "(" + strings.Join(args, ", ") + ")"
+}
+
+// returnType get the method return clause, the async method returns after the request is posted
+func (codegen *_CodeGen) returnType(method *ast.Method) string {
+
+	if gslang.IsAsync(method) || !gslang.NotVoid(method.Return) {
+		return ""
+	}
+
+	return " -> " + codegen.typeName(method.Return)
+}
+
+func (codegen *_CodeGen) execute(name string, data interface{}) {
+	if err := codegen.tpl.ExecuteTemplate(&codegen.content, name, data); err != nil {
+		gserrors.Panicf(err, "exec template(%s) for %s error", name, data)
+	}
+}
+
+func (codegen *_CodeGen) writeFile(name string, content []byte) {
+
+	// the content buffer may be reused by caller
+	if codegen.files != nil {
+		codegen.files[filepath.ToSlash(name)] = append([]byte(nil), content...)
+		return
+	}
+
+	fullpath := filepath.Join(codegen.rootpath, name)
+
+	if err := os.MkdirAll(filepath.Dir(fullpath), 0755); err != nil {
+		gserrors.Panicf(err, "create output directory error")
+	}
+
+	codegen.D("write file :%s", fullpath)
+
+	if err := ioutil.WriteFile(fullpath, content, 0644); err != nil {
+		gserrors.Panicf(err, "write generate stub code error")
+	}
+}
+
+// sourcePath get the source file path, the file name is prefixed with namespace because swift module file names must be unique
+func sourcePath(packageName string, name string) string {
+	return filepath.Join(strings.Replace(packageName, ".", "/", -1), namespace(packageName)+"+"+strings.Title(name)+".swift")
+}
+
+func (codegen *_CodeGen) BeginScript(compiler *gslang.Compiler, script *ast.Script) bool {
+
+	scriptPath := filepath.ToSlash(filepath.Clean(script.Name()))
+
+	for _, skip := range codegen.skips {
+
+		if skip.MatchString(scriptPath) {
+
+			return false
+		}
+	}
+
+	if strings.HasPrefix(script.Package, "gslang.") {
+		return false
+	}
+
+	codegen.script = script
+
+	codegen.compiler = compiler
+
+	codegen.content.Reset()
+
+	return true
+}
+
+func (codegen *_CodeGen) Using(compiler *gslang.Compiler, using *ast.Using) {
+}
+
+func (codegen *_CodeGen) Table(compiler *gslang.Compiler, tableType *ast.Table) {
+
+	if gen.IsOneOf(tableType) {
+		codegen.execute("union", tableType)
+	} else {
+		codegen.execute("table", tableType)
+	}
+}
+
+func (codegen *_CodeGen) Annotation(compiler *gslang.Compiler, annotation *ast.Table) {
+}
+
+func (codegen *_CodeGen) Enum(compiler *gslang.Compiler, enum *ast.Enum) {
+	codegen.execute("enum", enum)
+}
+
+func (codegen *_CodeGen) Contract(compiler *gslang.Compiler, contract *ast.Contract) {
+	codegen.execute("contract", contract)
+}
+
+// EndScript write the script source file as extension of the package namespace enum,
+// the namespace enum is declared by the first script of the package
+func (codegen *_CodeGen) EndScript(compiler *gslang.Compiler) {
+
+	var stream bytes.Buffer
+
+	stream.WriteString("// generate by gs2swift,don't modify it manually\n\n")
+
+	stream.WriteString("import Foundation\n\n")
+
+	name := namespace(codegen.script.Package)
+
+	if !codegen.namespaces[name] {
+		stream.WriteString(fmt.Sprintf("/// %s the %s package namespace\npublic enum %s {}\n\n", name, codegen.script.Package, name))
+
+		codegen.namespaces[name] = true
+	}
+
+	stream.WriteString(fmt.Sprintf("extension %s {", name))
+
+	stream.Write(codegen.content.Bytes())
+
+	stream.WriteString("}\n")
+
+	scriptName := strings.TrimSuffix(filepath.Base(codegen.script.Name()), filepath.Ext(codegen.script.Name()))
+
+	codegen.writeFile(sourcePath(codegen.script.Package, scriptName), tidy.Clean(stream.Bytes()))
+
+	if codegen.runtime {
+		return
+	}
+
+	stream.Reset()
+
+	if err := codegen.tpl.ExecuteTemplate(&stream, "runtime", nil); err != nil {
+		gserrors.Panicf(err, "exec template(runtime) error")
+	}
+
+	codegen.writeFile(sourcePath("com.gsrpc", runtimeFile), stream.Bytes())
+
+	codegen.runtime = true
+}
+
+var tidy = gen.NewTidy(1, `{`, `}`)
+
+// doc get the swift doc comment from the .gs comments of node
+func doc(indent string, node ast.Node) string {
+
+	comments := gslang.Comments(node)
+
+	if len(comments) == 0 {
+		return ""
+	}
+
+	var buff bytes.Buffer
+
+	for _, line := range comments {
+		buff.WriteString(indent + "/// " + strings.TrimSpace(line) + "\n")
+	}
+
+	return buff.String()
+}
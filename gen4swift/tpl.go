@@ -0,0 +1,821 @@
+package gen4swift
+
+var t4swift = `
+{{define "enum"}}{{$Enum := title .Name}}
+{{doc "    " .}}{{if enumSize . | eq 4}}    public struct {{$Enum}}: OptionSet, Hashable {
+        public let rawValue: UInt32
+
+        public init(rawValue: UInt32) {
+            self.rawValue = rawValue
+        }
+
+{{range .Constants}}{{doc "        " .}}        public static let {{memberName .Name}} = {{$Enum}}(rawValue: {{.Value}})
+{{end}}    }
+{{else}}    public enum {{$Enum}}: UInt8, CaseIterable {
+{{range .Constants}}{{doc "        " .}}        case {{memberName .Name}} = {{.Value}}
+{{end}}    }
+{{end}}{{end}}
+
+{{define "table"}}{{$Table := tableName .}}
+{{doc "    " .}}    public struct {{$Table}}: {{if isException .}}Swift.Error, CustomStringConvertible, {{end}}Equatable {
+{{range .Fields}}{{doc "        " .}}        public var {{memberName .Name}}: {{fieldType .}}
+{{end}}
+{{if .Fields}}        public init(
+{{range $index, $field := .Fields}}{{if $index}},
+{{end}}            {{memberName .Name}}: {{fieldType .}} = {{fieldDefault .}}{{end}}
+        ) {
+{{range .Fields}}            self.{{memberName .Name}} = {{memberName .Name}}
+{{end}}        }
+{{else}}        public init() {
+        }
+{{end}}
+{{if isException .}}
+        public var description: String {
+            return "{{.FullName}}"
+        }
+{{end}}
+{{if isPOD .}}
+        public init(from reader: {{rpcName "Reader"}}) throws {
+            self.init()
+{{range .Fields}}
+            {{if isOptional .}}if try reader.readBool() {
+                self.{{memberName .Name}} = {{readType .Type}}
+            }{{else}}self.{{memberName .Name}} = {{readType .Type}}{{end}}
+{{end}}        }
+
+        public func write(to writer: {{rpcName "Writer"}}) throws {
+{{range .Fields}}
+            {{if isOptional .}}if let value = self.{{memberName .Name}} {
+                writer.writeBool(true)
+                {{writeType .Type "value" 0}}
+            } else {
+                writer.writeBool(false)
+            }{{else}}{{writeType .Type (printf "self.%s" (memberName .Name)) 0}}{{end}}
+{{end}}        }
+{{else}}
+        public init(from reader: {{rpcName "Reader"}}) throws {
+            self.init()
+
+            {{if .Fields}}var{{else}}let{{end}} fields = try Int(reader.readUInt16())
+{{range .Fields}}
+            if fields == 0 {
+                return
+            }
+
+            if try reader.readTags()[0] != {{rpcName "Tag"}}.skip.rawValue {
+                self.{{memberName .Name}} = {{readType .Type}}
+            }
+
+            fields -= 1
+{{end}}
+            try reader.skipFields(fields)
+        }
+
+        public func write(to writer: {{rpcName "Writer"}}) throws {
+            writer.writeUInt16({{len .Fields}})
+{{range .Fields}}
+            {{if isOptional .}}if let value = self.{{memberName .Name}} {
+                writer.writeTags({{tagValue .Type}})
+                {{writeType .Type "value" 0}}
+            } else {
+                writer.writeTags({{rpcName "Tag"}}.skip.rawValue)
+            }{{else}}writer.writeTags({{tagValue .Type}})
+            {{writeType .Type (printf "self.%s" (memberName .Name)) 0}}{{end}}
+{{end}}        }
+{{end}}    }
+{{end}}
+
+{{define "union"}}{{$Union := tableName .}}
+{{doc "    " .}}    public indirect enum {{$Union}}: Equatable {
+{{range .Fields}}{{doc "        " .}}        case {{memberName .Name}}({{typeName .Type}})
+{{end}}
+        public static func read(from reader: {{rpcName "Reader"}}) throws -> {{$Union}}? {
+            let variant = try reader.readUInt8()
+
+            if variant == 0 {
+                return nil
+            }
+
+            let tags = try reader.readTags()
+
+            switch variant {
+{{range $index, $field := .Fields}}            case {{variant $index}}:
+                return .{{memberName .Name}}({{readType .Type}})
+{{end}}            default:
+                try reader.skip(tags)
+                return nil
+            }
+        }
+
+        public static func write(_ val: {{$Union}}?, to writer: {{rpcName "Writer"}}) throws {
+            guard let val = val else {
+                writer.writeUInt8(0)
+                return
+            }
+
+            switch val {
+{{range $index, $field := .Fields}}            case .{{memberName .Name}}(let value):
+                writer.writeUInt8({{variant $index}})
+                writer.writeTags({{tagValue .Type}})
+                {{writeType .Type "value" 0}}
+{{end}}            }
+        }
+    }
+{{end}}
+
+{{define "response"}}{{if notVoid .Return}}try {{rpcName "Response"}}(id: call.id, exception: -1, content: {{rpcName "marshal"}} { writer in {{writeType .Return "ret" 0}} }, trace: call.trace){{else}}{{rpcName "Response"}}(id: call.id, exception: -1, content: Data(), trace: call.trace){{end}}{{end}}
+
+{{define "contract"}}{{$Contract := title .Name}}
+{{doc "    " .}}    public protocol {{$Contract}} {
+{{range .Methods}}{{doc "        " .}}        func {{memberName .Name}}{{params .Params}} async throws{{returnType .}}
+{{end}}    }
+
+    /// {{$Contract}}Dispatcher dispatch the remote calls to {{$Contract}} service
+    public final class {{$Contract}}Dispatcher: {{rpcName "Dispatcher"}} {
+        public static let serviceName = "{{.FullName}}"
+
+        public let id: UInt16
+
+        private let service: {{$Contract}}
+
+        public init(id: UInt16, service: {{$Contract}}) {
+            self.id = id
+            self.service = service
+        }
+
+        public func dispatch(_ call: {{rpcName "Request"}}) async throws -> {{rpcName "Response"}}? {
+            switch call.method {
+{{range .Methods}}            case {{.ID}}:
+                guard call.params.count == {{.ParamsCount}} else {
+                    throw {{rpcName "RPCError"}}.invalidParams("{{$Contract}}#{{title .Name}} expect {{.ParamsCount}} params but got :\(call.params.count)")
+                }
+{{range .Params}}
+                let arg{{.ID}} = try {{rpcName "unmarshal"}}(call.params[{{.ID}}].content) { reader in {{readType .Type}} }
+{{end}}
+{{if isAsync .}}
+                try await service.{{memberName .Name}}{{callArgs .Params}}
+
+                return nil
+{{else if .Exceptions}}
+                do {
+                    {{if notVoid .Return}}let ret = {{end}}try await service.{{memberName .Name}}{{callArgs .Params}}
+
+                    return {{template "response" .}}
+                }{{range .Exceptions}} catch let err as {{typeName .Type}} {
+                    return try {{rpcName "Response"}}(id: call.id, exception: {{.ID}}, content: {{rpcName "marshal"}} { writer in try err.write(to: writer) }, trace: call.trace)
+                }{{end}}
+{{else}}
+                {{if notVoid .Return}}let ret = {{end}}try await service.{{memberName .Name}}{{callArgs .Params}}
+
+                return {{template "response" .}}
+{{end}}{{end}}            default:
+                throw {{rpcName "RPCError"}}.unknownMethod("{{$Contract}}#\(call.method)")
+            }
+        }
+    }
+
+    /// {{$Contract}}RPC the remote {{$Contract}} service proxy
+    public final class {{$Contract}}RPC: {{$Contract}} {
+        private let channel: {{rpcName "Channel"}}
+
+        private let serviceID: UInt16
+
+        /// timeout the call timeout in seconds
+        public var timeout: TimeInterval = 5
+
+        public init(channel: {{rpcName "Channel"}}, serviceID: UInt16) {
+            self.channel = channel
+            self.serviceID = serviceID
+        }
+{{range .Methods}}
+        public func {{memberName .Name}}{{params .Params}} async throws{{returnType .}} {
+            let call = {{if .Params}}try {{rpcName "Request"}}(service: self.serviceID, method: {{.ID}}, params: [
+{{range .Params}}                {{rpcName "Param"}}(content: {{rpcName "marshal"}} { writer in {{writeType .Type (memberName .Name) 0}} }),
+{{end}}            ]){{else}}{{rpcName "Request"}}(service: self.serviceID, method: {{.ID}}, params: []){{end}}
+{{if isAsync .}}
+            try self.channel.post(call)
+{{else}}
+            let callReturn = try await self.channel.call(call, timeout: self.timeout)
+
+            if callReturn.exception != -1 {
+                switch callReturn.exception {
+                case -2:
+                    throw try {{rpcName "unmarshal"}}(callReturn.content) { reader in try {{rpcName "InvalidArgumentException"}}(from: reader) }
+{{range .Exceptions}}                case {{.ID}}:
+                    throw try {{rpcName "unmarshal"}}(callReturn.content) { reader in {{readType .Type}} }
+{{end}}                default:
+                    throw {{rpcName "RemoteException"}}()
+                }
+            }
+{{if notVoid .Return}}
+            return try {{rpcName "unmarshal"}}(callReturn.content) { reader in {{readType .Return}} }
+{{end}}{{end}}        }
+{{end}}    }
+{{end}}
+
+{{define "runtime"}}// generate by gs2swift,don't modify it manually
+//
+// gsrpc runtime: the byte array Reader/Writer exchanging Data and the async/await Channel over Transport,
+// the generated service protocols are nested in package namespace, which requires swift 5.10 or later
+
+import Foundation
+
+extension ComGsrpc {
+    /// RPCError the errors raised by runtime and generated codes
+    public enum RPCError: Swift.Error {
+        /// read beyond the end of buffer
+        case endOfBuffer
+        /// the fixed size array length mismatch
+        case arraySize(expect: Int, got: Int)
+        /// unknown tag of tagged value
+        case unknownTag(UInt8)
+        /// the enum value is not declared
+        case unknownEnum(String, UInt8)
+        /// the dispatched call params count mismatch
+        case invalidParams(String)
+        /// the dispatched call method is not declared
+        case unknownMethod(String)
+        /// the call is not responded in time
+        case timeout(UInt32)
+        /// the channel is closed before the call is responded
+        case closed
+    }
+
+    /// Reader read gsrpc values from the input buffer
+    public final class Reader {
+        private let buff: [UInt8]
+
+        private var offset = 0
+
+        public init(_ content: Data) {
+            buff = [UInt8](content)
+        }
+
+        @discardableResult
+        private func next(_ length: Int) throws -> Int {
+            guard length <= buff.count - offset else {
+                throw RPCError.endOfBuffer
+            }
+
+            let current = offset
+
+            offset += length
+
+            return current
+        }
+
+        private func readInteger<T: FixedWidthInteger & UnsignedInteger>(_ type: T.Type) throws -> T {
+            let index = try next(MemoryLayout<T>.size)
+
+            var val: T = 0
+
+            for i in 0 ..< MemoryLayout<T>.size {
+                val |= T(buff[index + i]) << (8 * i)
+            }
+
+            return val
+        }
+
+        public func readUInt8() throws -> UInt8 {
+            let index = try next(1)
+
+            return buff[index]
+        }
+
+        public func readInt8() throws -> Int8 {
+            return try Int8(bitPattern: readUInt8())
+        }
+
+        public func readBool() throws -> Bool {
+            return try readUInt8() != 0
+        }
+
+        public func readInt16() throws -> Int16 {
+            return try Int16(bitPattern: readUInt16())
+        }
+
+        public func readUInt16() throws -> UInt16 {
+            return try readInteger(UInt16.self)
+        }
+
+        public func readInt32() throws -> Int32 {
+            return try Int32(bitPattern: readUInt32())
+        }
+
+        public func readUInt32() throws -> UInt32 {
+            return try readInteger(UInt32.self)
+        }
+
+        public func readInt64() throws -> Int64 {
+            return try Int64(bitPattern: readUInt64())
+        }
+
+        public func readUInt64() throws -> UInt64 {
+            return try readInteger(UInt64.self)
+        }
+
+        public func readFloat32() throws -> Float {
+            return try Float(bitPattern: readUInt32())
+        }
+
+        public func readFloat64() throws -> Double {
+            return try Double(bitPattern: readUInt64())
+        }
+
+        public func readBytes() throws -> Data {
+            let length = try Int(readUInt16())
+
+            let index = try next(length)
+
+            return Data(buff[index ..< index + length])
+        }
+
+        public func readFixedBytes(_ size: Int) throws -> Data {
+            let val = try readBytes()
+
+            guard val.count == size else {
+                throw RPCError.arraySize(expect: size, got: val.count)
+            }
+
+            return val
+        }
+
+        public func readString() throws -> String {
+            let length = try Int(readUInt16())
+
+            let index = try next(length)
+
+            return String(decoding: buff[index ..< index + length], as: UTF8.self)
+        }
+
+        /// readEnum read the enum raw value, the value not declared in the enum is rejected
+        public func readEnum<T: RawRepresentable>(_ type: T.Type) throws -> T where T.RawValue == UInt8 {
+            let raw = try readUInt8()
+
+            guard let val = T(rawValue: raw) else {
+                throw RPCError.unknownEnum(String(describing: type), raw)
+            }
+
+            return val
+        }
+
+        public func readList<T>(_ read: () throws -> T) throws -> [T] {
+            let length = try Int(readUInt16())
+
+            var list = [T]()
+
+            list.reserveCapacity(length)
+
+            for _ in 0 ..< length {
+                try list.append(read())
+            }
+
+            return list
+        }
+
+        public func readArray<T>(_ size: Int, _ read: () throws -> T) throws -> [T] {
+            let list = try readList(read)
+
+            guard list.count == size else {
+                throw RPCError.arraySize(expect: size, got: list.count)
+            }
+
+            return list
+        }
+
+        public func readMap<K: Hashable, V>(_ readKey: () throws -> K, _ readValue: () throws -> V) throws -> [K: V] {
+            let length = try Int(readUInt16())
+
+            var map = [K: V](minimumCapacity: length)
+
+            for _ in 0 ..< length {
+                let key = try readKey()
+
+                map[key] = try readValue()
+            }
+
+            return map
+        }
+
+        /// readTags read the tag sequence of tagged value, the nested container and POD tags are followed by their component tags
+        public func readTags() throws -> [UInt8] {
+            var tags = [UInt8]()
+
+            try readTags(&tags)
+
+            return tags
+        }
+
+        private func readTags(_ tags: inout [UInt8]) throws {
+            let tag = try readUInt8()
+
+            tags.append(tag)
+
+            var nested = 0
+
+            switch tag {
+{{range wireNested}}            case {{range $j, $tag := .Tags}}{{if $j}}, {{end}}Tag.{{memberName (printf "%s" $tag)}}.rawValue{{end}}:
+{{if lt .Nested 0}}                let count = try readUInt8()
+
+                tags.append(count)
+
+                nested = Int(count)
+{{else}}                nested = {{.Nested}}
+{{end}}{{end}}            default:
+                break
+            }
+
+            for _ in 0 ..< nested {
+                try readTags(&tags)
+            }
+        }
+
+        /// skip skip the value described by the tag sequence
+        public func skip(_ tags: [UInt8]) throws {
+            try skipTags(tags, 0)
+        }
+
+        /// skipFields skip the unknown fields of tagged table
+        public func skipFields(_ fields: Int) throws {
+            for _ in 0 ..< fields {
+                try skip(readTags())
+            }
+        }
+
+        // skipComponents skip the values of count tag sequences begin with index
+        private func skipComponents(_ tags: [UInt8], _ index: Int, _ count: Int) throws {
+            var index = index
+
+            for _ in 0 ..< count {
+                try skipTags(tags, index)
+
+                index = Reader.tagsEnd(tags, index)
+            }
+        }
+
+        private func skipTags(_ tags: [UInt8], _ index: Int) throws {
+            switch tags[index] {
+{{range wireCases}}            case {{range $j, $tag := .Tags}}{{if $j}}, {{end}}Tag.{{memberName (printf "%s" $tag)}}.rawValue{{end}}:
+{{if eq .Kind "none"}}                break
+{{else if eq .Kind "fixed"}}                try next({{.Size}})
+{{else if eq .Kind "sized"}}                try next(Int(readUInt16()))
+{{else if eq .Kind "fields"}}                try skipFields(Int(readUInt16()))
+{{else if eq .Kind "seq"}}                let length = try Int(readUInt16())
+
+                for _ in 0 ..< length {
+                    try skipComponents(tags, index + 1, {{.Nested}})
+                }
+{{else if eq .Kind "record"}}                try skipComponents(tags, index + 2, Int(tags[index + 1]))
+{{else if eq .Kind "optional"}}                if try readBool() {
+                    try skipTags(tags, index + 1)
+                }
+{{else if eq .Kind "variant"}}                if try readUInt8() != 0 {
+                    try skip(readTags())
+                }
+{{end}}{{end}}            default:
+                throw RPCError.unknownTag(tags[index])
+            }
+        }
+
+        // tagsEnd get the index next to the tag sequence begin with index
+        private static func tagsEnd(_ tags: [UInt8], _ index: Int) -> Int {
+            var next = index + 1
+
+            var nested = 0
+
+            switch tags[index] {
+{{range wireNested}}            case {{range $j, $tag := .Tags}}{{if $j}}, {{end}}Tag.{{memberName (printf "%s" $tag)}}.rawValue{{end}}:
+{{if lt .Nested 0}}                next = index + 2
+                nested = Int(tags[index + 1])
+{{else}}                nested = {{.Nested}}
+{{end}}{{end}}            default:
+                break
+            }
+
+            for _ in 0 ..< nested {
+                next = tagsEnd(tags, next)
+            }
+
+            return next
+        }
+    }
+
+    /// Writer write gsrpc values into the growable output buffer
+    public final class Writer {
+        private var buff = [UInt8]()
+
+        public init() {
+        }
+
+        /// content the written bytes
+        public var content: Data {
+            return Data(buff)
+        }
+
+        private func writeInteger<T: FixedWidthInteger & UnsignedInteger>(_ val: T) {
+            for i in 0 ..< MemoryLayout<T>.size {
+                buff.append(UInt8(truncatingIfNeeded: val >> (8 * i)))
+            }
+        }
+
+        public func writeUInt8(_ val: UInt8) {
+            buff.append(val)
+        }
+
+        public func writeInt8(_ val: Int8) {
+            writeUInt8(UInt8(bitPattern: val))
+        }
+
+        public func writeBool(_ val: Bool) {
+            writeUInt8(val ? 1 : 0)
+        }
+
+        public func writeInt16(_ val: Int16) {
+            writeInteger(UInt16(bitPattern: val))
+        }
+
+        public func writeUInt16(_ val: UInt16) {
+            writeInteger(val)
+        }
+
+        public func writeInt32(_ val: Int32) {
+            writeInteger(UInt32(bitPattern: val))
+        }
+
+        public func writeUInt32(_ val: UInt32) {
+            writeInteger(val)
+        }
+
+        public func writeInt64(_ val: Int64) {
+            writeInteger(UInt64(bitPattern: val))
+        }
+
+        public func writeUInt64(_ val: UInt64) {
+            writeInteger(val)
+        }
+
+        public func writeFloat32(_ val: Float) {
+            writeInteger(val.bitPattern)
+        }
+
+        public func writeFloat64(_ val: Double) {
+            writeInteger(val.bitPattern)
+        }
+
+        public func writeBytes(_ val: Data) {
+            writeUInt16(UInt16(truncatingIfNeeded: val.count))
+
+            buff.append(contentsOf: val)
+        }
+
+        public func writeFixedBytes(_ size: Int, _ val: Data) throws {
+            guard val.count == size else {
+                throw RPCError.arraySize(expect: size, got: val.count)
+            }
+
+            writeBytes(val)
+        }
+
+        public func writeString(_ val: String) {
+            writeBytes(Data(val.utf8))
+        }
+
+        public func writeTags(_ tags: UInt8...) {
+            for tag in tags {
+                writeUInt8(tag)
+            }
+        }
+
+        public func writeList<T>(_ val: [T], _ write: (T) throws -> Void) throws {
+            writeUInt16(UInt16(truncatingIfNeeded: val.count))
+
+            for v in val {
+                try write(v)
+            }
+        }
+
+        public func writeArray<T>(_ size: Int, _ val: [T], _ write: (T) throws -> Void) throws {
+            guard val.count == size else {
+                throw RPCError.arraySize(expect: size, got: val.count)
+            }
+
+            try writeList(val, write)
+        }
+
+        /// writeMap write map entries in key order, so the same map is always encoded as same bytes
+        public func writeMap<K: Comparable, V>(_ val: [K: V], _ writeKey: (K) throws -> Void, _ writeValue: (V) throws -> Void) throws {
+            try writeEntries(val.sorted { $0.key < $1.key }, writeKey, writeValue)
+        }
+
+        /// writeMap write string keys in utf8 bytes order, which is the same as the go server
+        public func writeMap<V>(_ val: [String: V], _ writeKey: (String) throws -> Void, _ writeValue: (V) throws -> Void) throws {
+            try writeEntries(val.sorted { $0.key.utf8.lexicographicallyPrecedes($1.key.utf8) }, writeKey, writeValue)
+        }
+
+        /// writeMap write enum keys in raw value order
+        public func writeMap<K: RawRepresentable & Hashable, V>(_ val: [K: V], _ writeKey: (K) throws -> Void, _ writeValue: (V) throws -> Void) throws where K.RawValue: Comparable {
+            try writeEntries(val.sorted { $0.key.rawValue < $1.key.rawValue }, writeKey, writeValue)
+        }
+
+        private func writeEntries<K, V>(_ entries: [(key: K, value: V)], _ writeKey: (K) throws -> Void, _ writeValue: (V) throws -> Void) throws {
+            writeUInt16(UInt16(truncatingIfNeeded: entries.count))
+
+            for entry in entries {
+                try writeKey(entry.key)
+                try writeValue(entry.value)
+            }
+        }
+    }
+
+    /// marshal encode value by the write closure
+    public static func marshal(_ write: (Writer) throws -> Void) throws -> Data {
+        let writer = Writer()
+
+        try write(writer)
+
+        return writer.content
+    }
+
+    /// unmarshal decode value from content by the read closure
+    public static func unmarshal<T>(_ content: Data, _ read: (Reader) throws -> T) throws -> T {
+        return try read(Reader(content))
+    }
+
+    /// Transport the message transport of channel, one message per send and onReceived callback,
+    /// the callbacks are set by channel and may be invoked from any thread
+    public protocol Transport: AnyObject {
+        var onReceived: ((Data) -> Void)? { get set }
+
+        var onClosed: ((Swift.Error?) -> Void)? { get set }
+
+        func send(_ data: Data) throws
+
+        func close()
+    }
+
+    /// Dispatcher the service dispatcher registered into channel by service id
+    public protocol Dispatcher: AnyObject {
+        var id: UInt16 { get }
+
+        func dispatch(_ call: Request) async throws -> Response?
+    }
+
+    /// Channel the rpc channel which send requests to and dispatch requests from the peer
+    public final class Channel {
+        private let transport: Transport
+
+        private let lock = NSLock()
+
+        private var pending = [UInt32: CheckedContinuation<Response, Swift.Error>]()
+
+        private var dispatchers = [UInt16: Dispatcher]()
+
+        private var seq: UInt32 = 0
+
+        /// onError invoked when the dispatcher throw unhandled error or the received message is invalid
+        public var onError: ((Swift.Error) -> Void)?
+
+        public init(transport: Transport) {
+            self.transport = transport
+
+            transport.onReceived = { [weak self] data in
+                self?.received(data)
+            }
+
+            transport.onClosed = { [weak self] reason in
+                self?.closed(reason)
+            }
+        }
+
+        private func locked<T>(_ body: () throws -> T) rethrows -> T {
+            lock.lock()
+
+            defer {
+                lock.unlock()
+            }
+
+            return try body()
+        }
+
+        public func register(_ dispatcher: Dispatcher) {
+            locked {
+                dispatchers[dispatcher.id] = dispatcher
+            }
+        }
+
+        public func unregister(_ id: UInt16) {
+            locked {
+                _ = dispatchers.removeValue(forKey: id)
+            }
+        }
+
+        /// call send the request and wait the response, RPCError.timeout is thrown after timeout seconds
+        public func call(_ call: Request, timeout: TimeInterval) async throws -> Response {
+            var call = call
+
+            call.id = locked { () -> UInt32 in
+                seq = seq &+ 1
+
+                return seq
+            }
+
+            let id = call.id
+
+            let content = try ComGsrpc.marshal { writer in try call.write(to: writer) }
+
+            return try await withCheckedThrowingContinuation { (continuation: CheckedContinuation<Response, Swift.Error>) in
+                locked {
+                    pending[id] = continuation
+                }
+
+                do {
+                    try send(.request, content)
+                } catch {
+                    remove(id)?.resume(throwing: error)
+
+                    return
+                }
+
+                DispatchQueue.global().asyncAfter(deadline: .now() + timeout) { [weak self] in
+                    self?.remove(id)?.resume(throwing: RPCError.timeout(id))
+                }
+            }
+        }
+
+        /// post send the request without waiting response
+        public func post(_ call: Request) throws {
+            try send(.request, ComGsrpc.marshal { writer in try call.write(to: writer) })
+        }
+
+        public func close() {
+            transport.close()
+        }
+
+        private func remove(_ id: UInt32) -> CheckedContinuation<Response, Swift.Error>? {
+            return locked {
+                pending.removeValue(forKey: id)
+            }
+        }
+
+        private func send(_ code: Code, _ content: Data) throws {
+            let message = Message(code: code, agent: 0, content: content)
+
+            try transport.send(ComGsrpc.marshal { writer in try message.write(to: writer) })
+        }
+
+        private func received(_ data: Data) {
+            do {
+                let message = try ComGsrpc.unmarshal(data) { reader in try Message(from: reader) }
+
+                switch message.code {
+                case .response:
+                    let callReturn = try ComGsrpc.unmarshal(message.content) { reader in try Response(from: reader) }
+
+                    remove(callReturn.id)?.resume(returning: callReturn)
+                case .request:
+                    let call = try ComGsrpc.unmarshal(message.content) { reader in try Request(from: reader) }
+
+                    Task {
+                        await self.dispatch(call)
+                    }
+                case .heartbeat:
+                    try send(.heartbeat, Data())
+                default:
+                    break
+                }
+            } catch {
+                onError?(error)
+            }
+        }
+
+        private func dispatch(_ call: Request) async {
+            guard let dispatcher = locked({ dispatchers[call.service] }) else {
+                return
+            }
+
+            do {
+                if let callReturn = try await dispatcher.dispatch(call) {
+                    try send(.response, ComGsrpc.marshal { writer in try callReturn.write(to: writer) })
+                }
+            } catch {
+                onError?(error)
+            }
+        }
+
+        private func closed(_ reason: Swift.Error?) {
+            let promises = locked { () -> [CheckedContinuation<Response, Swift.Error>] in
+                let values = Array(pending.values)
+
+                pending.removeAll()
+
+                return values
+            }
+
+            for promise in promises {
+                promise.resume(throwing: reason ?? RPCError.closed)
+            }
+        }
+    }
+}
+{{end}}
+`
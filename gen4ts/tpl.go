@@ -0,0 +1,850 @@
+package gen4ts
+
+var t4ts = `
+{{define "enum"}}{{$Enum := title .Name}}
+{{doc "" .}}export enum {{$Enum}} {
+{{range .Constants}}{{doc "    " .}}    {{title .Name}} = {{.Value}},
+{{end}}}
+
+export function read{{$Enum}}(reader: gsrpc.Reader): {{$Enum}} {
+    return {{if enumSize . | eq 4}}reader.readUInt32(){{else}}reader.readByte(){{end}} as {{$Enum}};
+}
+
+export function write{{$Enum}}(writer: gsrpc.Writer, val: {{$Enum}}): void {
+    {{if enumSize . | eq 4}}writer.writeUInt32(val){{else}}writer.writeByte(val){{end}};
+}
+{{end}}
+
+{{define "table"}}{{$Table := tableName .}}
+{{if isException .}}
+{{doc "" .}}export class {{$Table}} extends Error {
+{{range .Fields}}{{doc "    " .}}    {{fieldName .Name}}{{if isOptional .}}?{{end}}: {{typeName .Type}}{{with fieldDefault .}} = {{.}}{{end}};
+{{end}}
+    constructor() {
+        super("{{.FullName}}");
+        this.name = "{{$Table}}";
+    }
+}
+
+export function new{{$Table}}(): {{$Table}} {
+    return new {{$Table}}();
+}
+{{else}}
+{{doc "" .}}export interface {{$Table}} {
+{{range .Fields}}{{doc "    " .}}    {{fieldName .Name}}{{if isOptional .}}?{{end}}: {{typeName .Type}};
+{{end}}}
+
+export function new{{$Table}}(): {{$Table}} {
+    return {
+{{range $field := .Fields}}{{with fieldDefault $field}}        {{fieldName $field.Name}}: {{.}},
+{{end}}{{end}}    };
+}
+{{end}}
+{{if isPOD .}}
+export function read{{$Table}}(reader: gsrpc.Reader): {{$Table}} {
+    const target = new{{$Table}}();
+{{range .Fields}}
+    {{if isOptional .}}if (reader.readBool()) {
+        target.{{fieldName .Name}} = {{readType .Type}};
+    }{{else}}target.{{fieldName .Name}} = {{readType .Type}};{{end}}
+{{end}}
+    return target;
+}
+
+export function write{{$Table}}(writer: gsrpc.Writer, val: {{$Table}}): void {
+{{range .Fields}}
+    {{if isOptional .}}writer.writeBool(val.{{fieldName .Name}} != null);
+    if (val.{{fieldName .Name}} != null) {
+        {{writeType .Type (printf "val.%s" (fieldName .Name)) 0}};
+    }{{else}}{{writeType .Type (printf "val.%s" (fieldName .Name)) 0}};{{end}}
+{{end}}
+}
+{{else}}
+export function read{{$Table}}(reader: gsrpc.Reader): {{$Table}} {
+    const target = new{{$Table}}();
+
+    {{if .Fields}}let{{else}}const{{end}} fields = reader.readUInt16();
+{{range .Fields}}
+    if (fields === 0) {
+        return target;
+    }
+
+    if (reader.readTags()[0] !== {{rpcName "Tag"}}.Skip) {
+        target.{{fieldName .Name}} = {{readType .Type}};
+    }
+
+    fields--;
+{{end}}
+    reader.skipFields(fields);
+
+    return target;
+}
+
+export function write{{$Table}}(writer: gsrpc.Writer, val: {{$Table}}): void {
+    writer.writeUInt16({{len .Fields}});
+{{range .Fields}}
+    {{if isOptional .}}if (val.{{fieldName .Name}} == null) {
+        writer.writeTags({{rpcName "Tag"}}.Skip);
+    } else {
+        writer.writeTags({{tagValue .Type}});
+        {{writeType .Type (printf "val.%s" (fieldName .Name)) 0}};
+    }{{else}}writer.writeTags({{tagValue .Type}});
+    {{writeType .Type (printf "val.%s" (fieldName .Name)) 0}};{{end}}
+{{end}}
+}
+{{end}}
+{{end}}
+
+{{define "union"}}{{$Union := tableName .}}
+{{doc "" .}}export type {{$Union}} ={{range .Fields}}
+    | { kind: "{{title .Name}}"; value: {{typeName .Type}} }{{end}};
+
+export function read{{$Union}}(reader: gsrpc.Reader): {{$Union}} | null {
+    const variant = reader.readByte();
+
+    if (variant === 0) {
+        return null;
+    }
+
+    const tags = reader.readTags();
+
+    switch (variant) {
+{{range $index, $field := .Fields}}    case {{variant $index}}:
+        return { kind: "{{title .Name}}", value: {{readType .Type}} };
+{{end}}    default:
+        reader.skip(tags);
+        return null;
+    }
+}
+
+export function write{{$Union}}(writer: gsrpc.Writer, val: {{$Union}} | null): void {
+    if (val == null) {
+        writer.writeByte(0);
+        return;
+    }
+
+    switch (val.kind) {
+{{range $index, $field := .Fields}}    case "{{title .Name}}":
+        writer.writeByte({{variant $index}});
+        writer.writeTags({{tagValue .Type}});
+        {{writeType .Type "val.value" 0}};
+        break;
+{{end}}    }
+}
+{{end}}
+
+{{define "contract"}}{{$Contract := title .Name}}
+{{doc "" .}}export interface {{$Contract}} {
+{{range .Methods}}{{doc "    " .}}    {{methodName .Name}}{{params .Params}}: {{returnType .}};
+{{end}}}
+
+export const NameOf{{$Contract}} = "{{.FullName}}";
+
+/**
+ * {{$Contract}}Dispatcher dispatch the remote calls to {{$Contract}} service
+ */
+export class {{$Contract}}Dispatcher implements gsrpc.Dispatcher {
+    constructor(readonly id: number, private readonly service: {{$Contract}}) {
+    }
+
+    toString(): string {
+        return NameOf{{$Contract}};
+    }
+
+    async dispatch(call: {{rpcName "Request"}}): Promise<{{rpcName "Response"}} | null> {
+        switch (call.method) {
+{{range .Methods}}        case {{.ID}}: {
+            if (call.params.length !== {{.ParamsCount}}) {
+                throw new Error("{{$Contract}}#{{title .Name}} expect {{.ParamsCount}} params but got :" + call.params.length);
+            }
+{{range .Params}}
+            const arg{{.ID}} = gsrpc.unmarshal(call.params[{{.ID}}].content, (reader) => {{readType .Type}});
+{{end}}
+{{if isAsync .}}
+            this.service.{{methodName .Name}}{{callArgs .Params}};
+
+            return null;
+{{else if .Exceptions}}
+            try {
+                {{if notVoid .Return}}const ret = {{end}}await this.service.{{methodName .Name}}{{callArgs .Params}};
+
+                return {
+                    id: call.id,
+                    exception: -1,
+                    content: {{if notVoid .Return}}gsrpc.marshal((writer) => {{writeType .Return "ret" 0}}){{else}}new Uint8Array(0){{end}},
+                    trace: call.trace,
+                };
+            } catch (err) {
+{{range .Exceptions}}
+                if (err instanceof {{typeName .Type}}) {
+                    return {
+                        id: call.id,
+                        exception: {{.ID}},
+                        content: gsrpc.marshal((writer) => {{writeType .Type "err" 0}}),
+                        trace: call.trace,
+                    };
+                }
+{{end}}
+                throw err;
+            }
+{{else}}
+            {{if notVoid .Return}}const ret = {{end}}await this.service.{{methodName .Name}}{{callArgs .Params}};
+
+            return {
+                id: call.id,
+                exception: -1,
+                content: {{if notVoid .Return}}gsrpc.marshal((writer) => {{writeType .Return "ret" 0}}){{else}}new Uint8Array(0){{end}},
+                trace: call.trace,
+            };
+{{end}}        }
+{{end}}        }
+
+        throw new Error("unknown {{$Contract}}#" + call.method + " method");
+    }
+}
+
+/**
+ * {{$Contract}}RPC the remote {{$Contract}} service proxy
+ */
+export class {{$Contract}}RPC implements {{$Contract}} {
+    constructor(private readonly channel: gsrpc.Channel, private readonly serviceID: number, public timeout: number = 5000) {
+    }
+{{range .Methods}}
+    {{methodName .Name}}{{params .Params}}: {{returnType .}} {
+        const call: {{rpcName "Request"}} = {
+            id: 0,
+            service: this.serviceID,
+            method: {{.ID}},
+            params: [{{range .Params}}
+                { content: gsrpc.marshal((writer) => {{writeType .Type .Name 0}}) },{{end}}
+            ],
+            trace: 0n,
+            prev: 0,
+        };
+{{if isAsync .}}
+        this.channel.post(call);
+{{else}}
+        return this.channel.call(call, this.timeout).then((callReturn) => {
+            if (callReturn.exception !== -1) {
+                switch (callReturn.exception) {
+                case -2:
+                    throw gsrpc.unmarshal(callReturn.content, (reader) => {{rpcName "readInvalidArgumentException"}}(reader));
+{{range .Exceptions}}                case {{.ID}}:
+                    throw gsrpc.unmarshal(callReturn.content, (reader) => {{readType .Type}});
+{{end}}                default:
+                    throw new {{rpcName "RemoteException"}}();
+                }
+            }
+{{if notVoid .Return}}
+            return gsrpc.unmarshal(callReturn.content, (reader) => {{readType .Return}});
+{{end}}        });
+{{end}}    }
+{{end}}}
+{{end}}
+
+{{define "runtime"}}// generate by gs2ts,don't modify it manually
+//
+// gsrpc runtime: DataView based Reader/Writer over Uint8Array and the promise based Channel,
+// the Transport delivers one message per send
+
+import * as rpc from "./{{.}}";
+
+const encoder = new TextEncoder();
+
+const decoder = new TextDecoder();
+
+/**
+ * Reader read gsrpc values from the input buffer
+ */
+export class Reader {
+    private readonly view: DataView;
+
+    private offset = 0;
+
+    constructor(private readonly buff: Uint8Array) {
+        this.view = new DataView(buff.buffer, buff.byteOffset, buff.byteLength);
+    }
+
+    private next(length: number): number {
+        const offset = this.offset;
+
+        if (offset + length > this.buff.byteLength) {
+            throw new RangeError("gsrpc: read beyond the end of buffer");
+        }
+
+        this.offset += length;
+
+        return offset;
+    }
+
+    readByte(): number {
+        return this.view.getUint8(this.next(1));
+    }
+
+    readSByte(): number {
+        return this.view.getInt8(this.next(1));
+    }
+
+    readBool(): boolean {
+        return this.readByte() !== 0;
+    }
+
+    readInt16(): number {
+        return this.view.getInt16(this.next(2), true);
+    }
+
+    readUInt16(): number {
+        return this.view.getUint16(this.next(2), true);
+    }
+
+    readInt32(): number {
+        return this.view.getInt32(this.next(4), true);
+    }
+
+    readUInt32(): number {
+        return this.view.getUint32(this.next(4), true);
+    }
+
+    readInt64(): bigint {
+        return this.view.getBigInt64(this.next(8), true);
+    }
+
+    readUInt64(): bigint {
+        return this.view.getBigUint64(this.next(8), true);
+    }
+
+    readFloat32(): number {
+        return this.view.getFloat32(this.next(4), true);
+    }
+
+    readFloat64(): number {
+        return this.view.getFloat64(this.next(8), true);
+    }
+
+    readBytes(): Uint8Array {
+        const length = this.readUInt16();
+
+        const offset = this.next(length);
+
+        return this.buff.slice(offset, offset + length);
+    }
+
+    readFixedBytes(size: number): Uint8Array {
+        const length = this.readUInt16();
+
+        if (length !== size) {
+            throw new RangeError("gsrpc: check array size failed, expect " + size + " got " + length);
+        }
+
+        const offset = this.next(length);
+
+        return this.buff.slice(offset, offset + length);
+    }
+
+    readString(): string {
+        return decoder.decode(this.readBytes());
+    }
+
+    readList<T>(read: () => T): T[] {
+        const length = this.readUInt16();
+
+        const list: T[] = [];
+
+        for (let i = 0; i < length; i++) {
+            list.push(read());
+        }
+
+        return list;
+    }
+
+    readArray<T>(size: number, read: () => T): T[] {
+        const list = this.readList(read);
+
+        if (list.length !== size) {
+            throw new RangeError("gsrpc: check array size failed, expect " + size + " got " + list.length);
+        }
+
+        return list;
+    }
+
+    readMap<K, V>(readKey: () => K, readValue: () => V): Map<K, V> {
+        const length = this.readUInt16();
+
+        const map = new Map<K, V>();
+
+        for (let i = 0; i < length; i++) {
+            const key = readKey();
+
+            map.set(key, readValue());
+        }
+
+        return map;
+    }
+
+    /**
+     * read the tag sequence of tagged value, the nested container and POD tags are followed by their component tags
+     */
+    readTags(): number[] {
+        const tags: number[] = [];
+
+        this.readTagsInto(tags);
+
+        return tags;
+    }
+
+    private readTagsInto(tags: number[]): void {
+        const tag = this.readByte();
+
+        tags.push(tag);
+
+        let nested = 0;
+
+        switch (tag) {
+{{range wireNested}}{{range .Tags}}        case rpc.Tag.{{.}}:
+{{end}}{{if lt .Nested 0}}            nested = this.readByte();
+            tags.push(nested);
+{{else}}            nested = {{.Nested}};
+{{end}}            break;
+{{end}}        default:
+            break;
+        }
+
+        for (let i = 0; i < nested; i++) {
+            this.readTagsInto(tags);
+        }
+    }
+
+    /**
+     * skip the value described by the tag sequence
+     */
+    skip(tags: number[]): void {
+        this.skipTags(tags, 0);
+    }
+
+    /**
+     * skip the unknown fields of tagged table
+     */
+    skipFields(fields: number): void {
+        for (let i = 0; i < fields; i++) {
+            this.skip(this.readTags());
+        }
+    }
+
+    // skipComponents skip the values of count tag sequences begin with index
+    private skipComponents(tags: number[], index: number, count: number): void {
+        for (let i = 0; i < count; i++) {
+            this.skipTags(tags, index);
+            index = tagsEnd(tags, index);
+        }
+    }
+
+    private skipTags(tags: number[], index: number): void {
+        switch (tags[index]) {
+{{range wireCases}}{{range .Tags}}        case rpc.Tag.{{.}}:
+{{end}}{{if eq .Kind "fixed"}}            this.next({{.Size}});
+{{else if eq .Kind "sized"}}            this.next(this.readUInt16());
+{{else if eq .Kind "fields"}}            this.skipFields(this.readUInt16());
+{{else if eq .Kind "seq"}}            for (let i = 0, length = this.readUInt16(); i < length; i++) {
+                this.skipComponents(tags, index + 1, {{.Nested}});
+            }
+{{else if eq .Kind "record"}}            this.skipComponents(tags, index + 2, tags[index + 1]);
+{{else if eq .Kind "optional"}}            if (this.readBool()) {
+                this.skipTags(tags, index + 1);
+            }
+{{else if eq .Kind "variant"}}            if (this.readByte() !== 0) {
+                this.skip(this.readTags());
+            }
+{{end}}            break;
+{{end}}        default:
+            throw new Error("gsrpc: unknown tag " + tags[index]);
+        }
+    }
+}
+
+// tagsEnd get the index next to the tag sequence begin with index
+function tagsEnd(tags: number[], index: number): number {
+    let next = index + 1;
+
+    let nested = 0;
+
+    switch (tags[index]) {
+{{range wireNested}}{{range .Tags}}    case rpc.Tag.{{.}}:
+{{end}}{{if lt .Nested 0}}        next = index + 2;
+        nested = tags[index + 1];
+{{else}}        nested = {{.Nested}};
+{{end}}        break;
+{{end}}    default:
+        break;
+    }
+
+    for (let i = 0; i < nested; i++) {
+        next = tagsEnd(tags, next);
+    }
+
+    return next;
+}
+
+/**
+ * Writer write gsrpc values into the growable output buffer
+ */
+export class Writer {
+    private buff = new Uint8Array(64);
+
+    private view = new DataView(this.buff.buffer);
+
+    private length = 0;
+
+    // next reserve length bytes and return the write offset, the buffer and view are replaced when growing
+    private next(length: number): number {
+        const offset = this.length;
+
+        if (offset + length > this.buff.byteLength) {
+            const buff = new Uint8Array(Math.max(this.buff.byteLength * 2, offset + length));
+
+            buff.set(this.buff.subarray(0, offset));
+
+            this.buff = buff;
+
+            this.view = new DataView(buff.buffer);
+        }
+
+        this.length += length;
+
+        return offset;
+    }
+
+    writeByte(val: number): void {
+        const offset = this.next(1);
+
+        this.view.setUint8(offset, val);
+    }
+
+    writeSByte(val: number): void {
+        const offset = this.next(1);
+
+        this.view.setInt8(offset, val);
+    }
+
+    writeBool(val: boolean): void {
+        this.writeByte(val ? 1 : 0);
+    }
+
+    writeInt16(val: number): void {
+        const offset = this.next(2);
+
+        this.view.setInt16(offset, val, true);
+    }
+
+    writeUInt16(val: number): void {
+        const offset = this.next(2);
+
+        this.view.setUint16(offset, val, true);
+    }
+
+    writeInt32(val: number): void {
+        const offset = this.next(4);
+
+        this.view.setInt32(offset, val, true);
+    }
+
+    writeUInt32(val: number): void {
+        const offset = this.next(4);
+
+        this.view.setUint32(offset, val, true);
+    }
+
+    writeInt64(val: bigint): void {
+        const offset = this.next(8);
+
+        this.view.setBigInt64(offset, val, true);
+    }
+
+    writeUInt64(val: bigint): void {
+        const offset = this.next(8);
+
+        this.view.setBigUint64(offset, val, true);
+    }
+
+    writeFloat32(val: number): void {
+        const offset = this.next(4);
+
+        this.view.setFloat32(offset, val, true);
+    }
+
+    writeFloat64(val: number): void {
+        const offset = this.next(8);
+
+        this.view.setFloat64(offset, val, true);
+    }
+
+    writeBytes(val: Uint8Array): void {
+        this.writeUInt16(val.byteLength);
+
+        const offset = this.next(val.byteLength);
+
+        this.buff.set(val, offset);
+    }
+
+    writeFixedBytes(size: number, val: Uint8Array): void {
+        if (val.byteLength !== size) {
+            throw new RangeError("gsrpc: check array size failed, expect " + size + " got " + val.byteLength);
+        }
+
+        this.writeBytes(val);
+    }
+
+    writeString(val: string): void {
+        this.writeBytes(encoder.encode(val));
+    }
+
+    writeTags(...tags: number[]): void {
+        for (const tag of tags) {
+            this.writeByte(tag);
+        }
+    }
+
+    writeList<T>(val: T[], write: (val: T) => void): void {
+        this.writeUInt16(val.length);
+
+        for (const v of val) {
+            write(v);
+        }
+    }
+
+    writeArray<T>(size: number, val: T[], write: (val: T) => void): void {
+        if (val.length !== size) {
+            throw new RangeError("gsrpc: check array size failed, expect " + size + " got " + val.length);
+        }
+
+        this.writeList(val, write);
+    }
+
+    /**
+     * write map entries in key order, so the same map is always encoded as same bytes
+     */
+    writeMap<K, V>(val: Map<K, V>, writeKey: (key: K) => void, writeValue: (val: V) => void): void {
+        const keys = Array.from(val.keys()).sort((lhs, rhs) => (lhs < rhs ? -1 : lhs > rhs ? 1 : 0));
+
+        this.writeUInt16(keys.length);
+
+        for (const key of keys) {
+            writeKey(key);
+            writeValue(val.get(key) as V);
+        }
+    }
+
+    content(): Uint8Array {
+        return this.buff.slice(0, this.length);
+    }
+}
+
+/**
+ * marshal encode value by the write function
+ */
+export function marshal(write: (writer: Writer) => void): Uint8Array {
+    const writer = new Writer();
+
+    write(writer);
+
+    return writer.content();
+}
+
+/**
+ * unmarshal decode value from content by the read function
+ */
+export function unmarshal<T>(content: Uint8Array, read: (reader: Reader) => T): T {
+    return read(new Reader(content));
+}
+
+/**
+ * Transport the message transport of channel, one message per send and onmessage callback
+ */
+export interface Transport {
+    send(data: Uint8Array): void;
+    close(): void;
+    onmessage: ((data: Uint8Array) => void) | null;
+    onclose: ((reason: Error) => void) | null;
+}
+
+/**
+ * Dispatcher the service dispatcher registered into channel by service id
+ */
+export interface Dispatcher {
+    readonly id: number;
+    dispatch(call: rpc.Request): Promise<rpc.Response | null>;
+}
+
+interface Pending {
+    resolve: (callReturn: rpc.Response) => void;
+    reject: (reason: Error) => void;
+    timer: ReturnType<typeof setTimeout>;
+}
+
+/**
+ * Channel the rpc channel which send requests to and dispatch requests from the peer
+ */
+export class Channel {
+    private seq = 0;
+
+    private readonly pending = new Map<number, Pending>();
+
+    private readonly dispatchers = new Map<number, Dispatcher>();
+
+    constructor(private readonly transport: Transport) {
+        transport.onmessage = (data) => this.onMessage(data);
+        transport.onclose = (reason) => this.onClose(reason);
+    }
+
+    register(dispatcher: Dispatcher): void {
+        this.dispatchers.set(dispatcher.id, dispatcher);
+    }
+
+    unregister(id: number): void {
+        this.dispatchers.delete(id);
+    }
+
+    /**
+     * call send the request and wait the response, the promise is rejected after timeout milliseconds
+     */
+    call(call: rpc.Request, timeout: number): Promise<rpc.Response> {
+        this.seq = (this.seq + 1) >>> 0;
+
+        const id = this.seq;
+
+        call.id = id;
+
+        return new Promise<rpc.Response>((resolve, reject) => {
+            const timer = setTimeout(() => {
+                this.pending.delete(id);
+                reject(new Error("gsrpc: rpc call(" + id + ") timeout"));
+            }, timeout);
+
+            this.pending.set(id, { resolve, reject, timer });
+
+            try {
+                this.send(rpc.Code.Request, marshal((writer) => rpc.writeRequest(writer, call)));
+            } catch (err) {
+                clearTimeout(timer);
+                this.pending.delete(id);
+                reject(err as Error);
+            }
+        });
+    }
+
+    /**
+     * post send the request without waiting response
+     */
+    post(call: rpc.Request): void {
+        this.send(rpc.Code.Request, marshal((writer) => rpc.writeRequest(writer, call)));
+    }
+
+    close(): void {
+        this.transport.close();
+    }
+
+    private send(code: rpc.Code, content: Uint8Array): void {
+        this.transport.send(marshal((writer) => rpc.writeMessage(writer, { code, agent: 0, content })));
+    }
+
+    private onMessage(data: Uint8Array): void {
+        const message = unmarshal(data, rpc.readMessage);
+
+        switch (message.code) {
+        case rpc.Code.Response: {
+            const callReturn = unmarshal(message.content, rpc.readResponse);
+
+            const pending = this.pending.get(callReturn.id);
+
+            if (pending) {
+                clearTimeout(pending.timer);
+                this.pending.delete(callReturn.id);
+                pending.resolve(callReturn);
+            }
+
+            break;
+        }
+        case rpc.Code.Request:
+            this.dispatch(unmarshal(message.content, rpc.readRequest));
+            break;
+        case rpc.Code.Heartbeat:
+            this.send(rpc.Code.Heartbeat, new Uint8Array(0));
+            break;
+        }
+    }
+
+    private async dispatch(call: rpc.Request): Promise<void> {
+        const dispatcher = this.dispatchers.get(call.service);
+
+        if (!dispatcher) {
+            return;
+        }
+
+        const callReturn = await dispatcher.dispatch(call);
+
+        if (callReturn) {
+            this.send(rpc.Code.Response, marshal((writer) => rpc.writeResponse(writer, callReturn)));
+        }
+    }
+
+    private onClose(reason: Error): void {
+        for (const pending of this.pending.values()) {
+            clearTimeout(pending.timer);
+            pending.reject(reason);
+        }
+
+        this.pending.clear();
+    }
+}
+
+/**
+ * WebSocketTransport the transport over WebSocket, each binary frame carries one message
+ */
+export class WebSocketTransport implements Transport {
+    onmessage: ((data: Uint8Array) => void) | null = null;
+
+    onclose: ((reason: Error) => void) | null = null;
+
+    constructor(private readonly socket: WebSocket) {
+        socket.binaryType = "arraybuffer";
+
+        socket.addEventListener("message", (event) => {
+            if (event.data instanceof ArrayBuffer && this.onmessage) {
+                this.onmessage(new Uint8Array(event.data));
+            }
+        });
+
+        socket.addEventListener("close", (event) => {
+            if (this.onclose) {
+                this.onclose(new Error("gsrpc: websocket closed(" + event.code + ")"));
+            }
+        });
+    }
+
+    /**
+     * connect open the WebSocket and resolve the transport after the connection is established
+     */
+    static connect(url: string, protocols?: string | string[]): Promise<WebSocketTransport> {
+        return new Promise<WebSocketTransport>((resolve, reject) => {
+            const socket = new WebSocket(url, protocols);
+
+            const onerror = () => reject(new Error("gsrpc: connect " + url + " error"));
+
+            socket.addEventListener("error", onerror);
+
+            socket.addEventListener("open", () => {
+                socket.removeEventListener("error", onerror);
+                resolve(new WebSocketTransport(socket));
+            });
+        });
+    }
+
+    send(data: Uint8Array): void {
+        this.socket.send(data);
+    }
+
+    close(): void {
+        this.socket.close();
+    }
+}
+{{end}}
+`
@@ -0,0 +1,194 @@
+// Package generate run the gsrpc compile/link/generate flow, which is shared by cmd/gsrpc and build tools
+package generate
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/gsdocker/gserrors"
+	"github.com/gsdocker/gslogger"
+	"github.com/gsrpc/gslang"
+	"github.com/gsrpc/gslang/lexer"
+	"github.com/gsrpc/gsrpc/gen4c"
+	"github.com/gsrpc/gsrpc/gen4cpp"
+	"github.com/gsrpc/gsrpc/gen4csharp"
+	"github.com/gsrpc/gsrpc/gen4dart"
+	"github.com/gsrpc/gsrpc/gen4go"
+	"github.com/gsrpc/gsrpc/gen4java"
+	"github.com/gsrpc/gsrpc/gen4kotlin"
+	"github.com/gsrpc/gsrpc/gen4lua"
+	"github.com/gsrpc/gsrpc/gen4objc"
+	"github.com/gsrpc/gsrpc/gen4python"
+	"github.com/gsrpc/gsrpc/gen4rust"
+	"github.com/gsrpc/gsrpc/gen4swift"
+	"github.com/gsrpc/gsrpc/gen4ts"
+)
+
+// langs the target generators keyed by language name, cmd/gsrpc resolves its -lang flag through Generate so new generators are registered here
+var langs = map[string]func(files map[string][]byte, skips []string) (gslang.Visitor, error){
+	"c":      gen4c.NewCodeGenWithFiles,
+	"cpp":    gen4cpp.NewCodeGenWithFiles,
+	"csharp": gen4csharp.NewCodeGenWithFiles,
+	"dart":   gen4dart.NewCodeGenWithFiles,
+	"golang": func(files map[string][]byte, skips []string) (gslang.Visitor, error) {
+		return gen4go.NewCodeGenWithFiles(files, skips, "")
+	},
+	"java":   gen4java.NewCodeGenWithFiles,
+	"kotlin": gen4kotlin.NewCodeGenWithFiles,
+	"lua":    gen4lua.NewCodeGenWithFiles,
+	"objc":   gen4objc.NewCodeGenWithFiles,
+	"python": gen4python.NewCodeGenWithFiles,
+	"rust":   gen4rust.NewCodeGenWithFiles,
+	"swift":  gen4swift.NewCodeGenWithFiles,
+	"ts":     gen4ts.NewCodeGenWithFiles,
+}
+
+// Languages get the supported target language names
+func Languages() []string {
+	var names []string
+
+	for name := range langs {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// Options the generate options
+type Options struct {
+	Lang        string   // target language, default is golang
+	Files       []string // gslang source files
+	Output      string   // output directory
+	Skips       []string // skip script regex list, default skip github.com/gsrpc/gslang
+	TemplateDir string   // user template directory, only golang support
+	InMemory    bool     // only return the generated files and not write them into output directory
+}
+
+// Diagnostic the gslang compile error
+type Diagnostic struct {
+	Start lexer.Position // error source position
+	Text  string         // error source text
+	Err   error          // original error
+}
+
+func (diagnostic Diagnostic) String() string {
+	return fmt.Sprintf("parse %s error\n\t%s", diagnostic.Start, diagnostic.Text)
+}
+
+// Generate compile and link the gslang source files, then generate target language codes.
+// The generated files are returned as map of slash separated path relative to output directory,
+// the compile errors are returned as diagnostics
+func Generate(options Options) (files map[string][]byte, diagnostics []Diagnostic, err error) {
+
+	log := gslogger.Get("gsrpc")
+
+	if options.Lang == "" {
+		options.Lang = "golang"
+	}
+
+	if options.Skips == nil {
+		options.Skips = []string{"github.com/gsrpc/gslang"}
+	}
+
+	codegenF, ok := langs[options.Lang]
+
+	if !ok {
+		return nil, nil, gserrors.Newf(nil, "unknown gsrpc object language :%s", options.Lang)
+	}
+
+	if options.TemplateDir != "" {
+
+		if options.Lang != "golang" {
+			return nil, nil, gserrors.Newf(nil, "template dir only support golang")
+		}
+
+		codegenF = func(files map[string][]byte, skips []string) (gslang.Visitor, error) {
+			return gen4go.NewCodeGenWithFiles(files, skips, options.TemplateDir)
+		}
+	}
+
+	defer func() {
+		if e := recover(); e != nil {
+
+			if e, ok := e.(error); ok {
+				err = e
+			} else {
+				err = gserrors.Newf(nil, "%v", e)
+			}
+		}
+	}()
+
+	// the codegen write generated files into memory, which are saved into output directory at last
+	generated := make(map[string][]byte)
+
+	codegen, err := codegenF(generated, options.Skips)
+
+	if err != nil {
+		return nil, nil, gserrors.Newf(err, "create language(%s) codegen error", options.Lang)
+	}
+
+	compiler := gslang.NewCompiler("gsrpc", gslang.HandleError(func(err *gslang.Error) {
+		diagnostics = append(diagnostics, Diagnostic{Start: err.Start, Text: err.Text, Err: err.Orignal})
+	}))
+
+	for _, file := range options.Files {
+		log.I("Compile gsLang File :%s", file)
+		if err := compiler.Compile(file); err != nil {
+			return nil, diagnostics, gserrors.Newf(err, "compile %s error", file)
+		}
+	}
+
+	if len(diagnostics) != 0 {
+		return nil, diagnostics, gserrors.Newf(nil, "compile gsLang files with %d errors", len(diagnostics))
+	}
+
+	log.I("Link ...")
+
+	if err := compiler.Link(); err != nil {
+		return nil, diagnostics, gserrors.Newf(err, "link error")
+	}
+
+	if len(diagnostics) != 0 {
+		return nil, diagnostics, gserrors.Newf(nil, "link gsLang files with %d errors", len(diagnostics))
+	}
+
+	if err := compiler.Visit(codegen); err != nil {
+		return nil, diagnostics, gserrors.Newf(err, "generate language codes(%s) error", options.Lang)
+	}
+
+	if options.InMemory {
+		return generated, diagnostics, nil
+	}
+
+	log.I("Output Directory :%s", options.Output)
+
+	if err := save(options.Output, generated); err != nil {
+		return nil, diagnostics, err
+	}
+
+	return generated, diagnostics, nil
+}
+
+// save write generated files into output directory
+func save(output string, files map[string][]byte) error {
+
+	for name, content := range files {
+
+		fullpath := filepath.Join(output, filepath.FromSlash(name))
+
+		if err := os.MkdirAll(filepath.Dir(fullpath), 0755); err != nil {
+			return gserrors.Newf(err, "create output directory error :%s", filepath.Dir(fullpath))
+		}
+
+		if err := ioutil.WriteFile(fullpath, content, 0644); err != nil {
+			return gserrors.Newf(err, "write generated file error :%s", fullpath)
+		}
+	}
+
+	return nil
+}
@@ -2,7 +2,8 @@ package com.gsrpc;
 
 using gslang.Package;
 using gslang.Exception;
-using gslang.Flag;
+using gslang.annotations.Usage;
+using gslang.annotations.Target;
 
 @Package(Lang:"golang",Name:"com.gsrpc",Redirect:"github.com/gsrpc/gorpc")
 
@@ -17,9 +18,88 @@ enum State{
     Disconnect,Connecting,Connected,Disconnecting,Closed
 }
 
-@Flag
+// Tagged table field type tags, List is followed by the component tag, Map by the key and value tags
+// and POD by the field count and the field tags(Optional followed by the tags of optional field),
+// so nested containers and POD tables self describe, the Union value carries the tags of the set variant
 enum Tag{
-    I8(0),I16(1),I32(2),I64(3),List(4),Table(5),String(6),Skip(7)
+    I8(0),I16(1),I32(2),I64(3),List(4),Table(5),String(6),Skip(7),Bool(8),F32(9),F64(10),Map(11),POD(12),Optional(13),Union(14)
+}
+
+// Map mark a two fields(Key,Value) table as map entry, lists of the entry table are generated as native map types.
+// gslang has no map<K,V> type syntax, so the entry table is the map type of IDL. The key must be builtin(except bool)
+// or enum type, the entries are encoded in the canonical key order: numbers by value with the declared signedness,
+// enums by constant value and strings by UTF-8 bytes
+@Usage(Target.Table)
+table Map {
+}
+
+// Optional mark a field as optional, absent fields are encoded as Skip tag
+// in tagged tables or with a leading presence bool in POD tables
+@Usage(Target.Field)
+table Optional {
+}
+
+// OneOf mark a table as union which exactly one of the fields is set,
+// the union is encoded as variant index(field index + 1, zero means none)
+// followed by the tag sequence and value of the set field
+@Usage(Target.Table)
+table OneOf {
+}
+
+// Strict mark the generated enum reader reject values not declared in the enum
+@Usage(Target.Enum)
+table Strict {
+}
+
+// Default declare the field default value by the positional or the Value argument, e.g. @Default(3)
+// or @Default(Value:Duration(-100,TimeUnit.Second)). Default is untyped: the argument can be number,
+// string, enum constant or table literal, which is type checked by the generators against the field type,
+// unsigned fields reject negative values
+@Usage(Target.Field)
+table Default {
+}
+
+// GoType map the table to user supplied go type, the Type and Codec are qualified by full import path,
+// e.g. @GoType(Type:"time.Time",Codec:"github.com/gsrpc/gorpc.TimeCodec"),
+// the Codec value converts between the type and the table by Encode(val T) (*Table, error) and
+// Decode(val *Table) (T, error), the wire format is the same as the table,
+// the codec errors are returned by the read/write and json functions, and panic in Equal, Clone and CopyFrom
+@Usage(Target.Table)
+table GoType {
+    string Type;
+    string Codec;
+}
+
+// GoTag declare the golang struct tag of field, e.g. @GoTag("json:\"agent\" db:\"agent\""),
+// the generated json encoding keeps the field name as key for all languages
+@Usage(Target.Field)
+table GoTag {
+    string Value;
+}
+
+// Required mark a field or param must be set, the optional field must be present,
+// the string, table and variable length seq must be not empty
+@Usage(Target.Field|Target.Param)
+table Required {
+}
+
+// Range declare the number field or param value range, e.g. @Range(0,100) or @Range(Max:100)
+@Usage(Target.Field|Target.Param)
+table Range {
+    float64 Min;
+    float64 Max;
+}
+
+// MaxLen declare the max length of string or variable length seq field or param, e.g. @MaxLen(256)
+@Usage(Target.Field|Target.Param)
+table MaxLen {
+    uint32 Value;
+}
+
+// Pattern declare the regular expression which the string field or param must match, e.g. @Pattern("^[a-z]+$")
+@Usage(Target.Field|Target.Param)
+table Pattern {
+    string Value;
 }
 
 // RPC message
@@ -91,13 +171,23 @@ table UnmarshalException {
 table RemoteException {
 }
 
+// InvalidArgument the validation exception which lists the violations with field path,
+// the dispatcher reject the call with it if the params violate the declared constraints,
+// it is returned as Response.Exception -2 which is reserved for it and decoded by all proxies
+@Exception
+table InvalidArgument {
+    string[] Violations;
+}
+
 @gslang.POD
 table KV {
     byte[]     Key;
     byte[]     Value;
 }
 
+// Time the seconds and nanoseconds since unix epoch, which is mapped to time.Time in golang
 @gslang.POD
+@GoType(Type:"time.Time",Codec:"github.com/gsrpc/gorpc.TimeCodec")
 table Time {
     uint64  Second;
     uint64  Nano;
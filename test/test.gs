@@ -5,6 +5,15 @@ using gslang.annotations.Target;
 using gslang.Exception;
 using gslang.Flag;
 using gslang.Package;
+using com.gsrpc.Map;
+using com.gsrpc.OneOf;
+using com.gsrpc.Default;
+using com.gsrpc.Required;
+using com.gsrpc.Range;
+using com.gsrpc.MaxLen;
+using com.gsrpc.Pattern;
+using com.gsrpc.GoType;
+using com.gsrpc.GoTag;
 
 @Package(Lang:"objc",Name:"com.gsrpc.test",Redirect:"GSTest")
 @Package(Lang:"golang",Name:"com.gsrpc.test",Redirect:"github.com/gsrpc/gorpc/test")
@@ -18,6 +27,23 @@ table Duration {
     TimeUnit Unit;
 }
 
+// Perm the permission flags, which are combined as Read|Write
+@Flag
+enum Perm {
+    None(0),Read(1),Write(2),Exec(4)
+}
+
+// Mode the flags without zero constant, no flag set is rendered as "0"
+@Flag
+enum Mode {
+    Fast(1),Safe(2)
+}
+
+table Access {
+    Perm Perm;
+    Mode Mode;
+}
+
 
 // Description define new Attribute
 @Usage(Target.Module|Target.Script)
@@ -62,9 +88,54 @@ contract RESTful {
     void SayHello(string message);
 }
 
+@Map
+table DurationEntry {
+    string Key;
+    Duration Value;
+}
+
+// Payload one of duration or text
+@OneOf
+table Payload {
+    Duration Duration;
+    string Text;
+}
+
+table Config {
+    @Default(Value:Duration(-100,TimeUnit.Second))
+    Duration Timeout;
+    @Default(3)
+    int32 Retries;
+    @Default(Value:"gsrpc")
+    string Name;
+}
+
+// Stamp is generated as time.Time in golang
+@gslang.POD
+@GoType(Type:"time.Time",Codec:"github.com/gsrpc/gorpc/test/codec.StampCodec")
+table Stamp {
+    int64 Unix;
+    int32 Nano;
+}
+
+table Account {
+    @Required
+    @MaxLen(32)
+    @Pattern("^[a-z][a-z0-9_]*$")
+    @GoTag("json:\"name\" db:\"name\"")
+    string Name; // account login name
+    @Range(0,150)
+    int32 Age;
+    @MaxLen(8)
+    string[] Tags;
+    Stamp Created;
+}
+
 table Block {
     byte[256] Content;
     KV[12][128] KV;
+    DurationEntry[] Timeouts;
+    Payload Payload;
 }
 
 // remote exception
@@ -0,0 +1,259 @@
+// Package wire define the tag scheme of tagged values(tagged table fields and union variants),
+// the generators emit the tag sequences and the runtime skippers from it, so all targets agree on the wire
+package wire
+
+import (
+	"strconv"
+
+	"github.com/gsdocker/gserrors"
+	"github.com/gsrpc/gslang"
+	"github.com/gsrpc/gslang/ast"
+	"github.com/gsrpc/gslang/lexer"
+)
+
+// Tag the type tag of tagged value, the values must keep same with com.gsrpc.Tag
+type Tag byte
+
+// The com.gsrpc.Tag constants
+const (
+	I8 Tag = iota
+	I16
+	I32
+	I64
+	List
+	Table
+	String
+	Skip
+	Bool
+	F32
+	F64
+	Map
+	POD
+	Optional
+	Union
+)
+
+var names = []string{"I8", "I16", "I32", "I64", "List", "Table", "String", "Skip", "Bool", "F32", "F64", "Map", "POD", "Optional", "Union"}
+
+func (tag Tag) String() string {
+	if int(tag) < len(names) {
+		return names[tag]
+	}
+
+	return strconv.Itoa(int(tag))
+}
+
+// Kind the value layout of tag
+type Kind string
+
+// The value layouts
+const (
+	KindNone     Kind = "none"     // no value, e.g. the absent field
+	KindFixed    Kind = "fixed"    // little endian value of Size bytes
+	KindSized    Kind = "sized"    // uint16 length followed by the bytes
+	KindFields   Kind = "fields"   // uint16 field count followed by the fields, each field is the tag sequence and the value
+	KindSeq      Kind = "seq"      // uint16 length followed by the elements, each element is the values of the Nested tag sequences
+	KindRecord   Kind = "record"   // the tag is followed by the field count and the field tag sequences, the value is the field values in order
+	KindOptional Kind = "optional" // presence bool followed by the value of the nested tag sequence if present
+	KindVariant  Kind = "variant"  // variant index, the non zero index is followed by the tag sequence and the value of variant
+)
+
+// Case the tags sharing the same value layout
+type Case struct {
+	Tags   []Tag // the tags of case
+	Kind   Kind  // the value layout
+	Size   int   // value bytes of KindFixed
+	Nested int   // count of the tag sequences following the tag, -1 means a field count byte follows
+}
+
+var cases = []Case{
+	{Tags: []Tag{Skip}, Kind: KindNone},
+	{Tags: []Tag{I8, Bool}, Kind: KindFixed, Size: 1},
+	{Tags: []Tag{I16}, Kind: KindFixed, Size: 2},
+	{Tags: []Tag{I32, F32}, Kind: KindFixed, Size: 4},
+	{Tags: []Tag{I64, F64}, Kind: KindFixed, Size: 8},
+	{Tags: []Tag{String}, Kind: KindSized},
+	{Tags: []Tag{Table}, Kind: KindFields},
+	{Tags: []Tag{List}, Kind: KindSeq, Nested: 1},
+	{Tags: []Tag{Map}, Kind: KindSeq, Nested: 2},
+	{Tags: []Tag{POD}, Kind: KindRecord, Nested: -1},
+	{Tags: []Tag{Optional}, Kind: KindOptional, Nested: 1},
+	{Tags: []Tag{Union}, Kind: KindVariant},
+}
+
+// Cases get the tag cases, the runtime skippers are generated by switch on them
+func Cases() []Case {
+	return cases
+}
+
+// Nested get the cases which tag is followed by nested tag sequences
+func Nested() []Case {
+	var nested []Case
+
+	for _, c := range cases {
+		if c.Nested != 0 {
+			nested = append(nested, c)
+		}
+	}
+
+	return nested
+}
+
+// Tags get the tag sequence of type, the field count following POD is stored as Tag too
+func Tags(typeDecl ast.Type) []Tag {
+	return tags(typeDecl, make(map[*ast.Table]bool))
+}
+
+func tags(typeDecl ast.Type, visiting map[*ast.Table]bool) []Tag {
+	switch typeDecl.(type) {
+	case *ast.BuiltinType:
+		switch typeDecl.(*ast.BuiltinType).Type {
+		case lexer.KeySByte, lexer.KeyByte:
+			return []Tag{I8}
+		case lexer.KeyBool:
+			return []Tag{Bool}
+		case lexer.KeyInt16, lexer.KeyUInt16:
+			return []Tag{I16}
+		case lexer.KeyInt32, lexer.KeyUInt32:
+			return []Tag{I32}
+		case lexer.KeyInt64, lexer.KeyUInt64:
+			return []Tag{I64}
+		case lexer.KeyFloat32:
+			return []Tag{F32}
+		case lexer.KeyFloat64:
+			return []Tag{F64}
+		case lexer.KeyString:
+			return []Tag{String}
+		}
+
+	case *ast.TypeRef:
+		return tags(typeDecl.(*ast.TypeRef).Ref, visiting)
+
+	case *ast.Enum:
+		if gslang.EnumSize(typeDecl) == 4 {
+			return []Tag{I32}
+		}
+
+		return []Tag{I8}
+
+	case *ast.Table:
+		table := typeDecl.(*ast.Table)
+
+		if _, ok := gslang.FindAnnotation(table, "com.gsrpc.OneOf"); ok {
+			return []Tag{Union}
+		}
+
+		if !gslang.IsPOD(table) {
+			return []Tag{Table}
+		}
+
+		start, _ := gslang.Pos(table)
+
+		if visiting[table] {
+			gserrors.Panicf(nil, "POD table %s can't be tagged value, it contains itself :%v", table, start)
+		}
+
+		if len(table.Fields) > 255 {
+			gserrors.Panicf(nil, "POD table %s can't be tagged value, it has more than 255 fields :%v", table, start)
+		}
+
+		visiting[table] = true
+
+		defer delete(visiting, table)
+
+		sequence := []Tag{POD, Tag(len(table.Fields))}
+
+		for _, field := range table.Fields {
+
+			if _, ok := gslang.FindAnnotation(field, "com.gsrpc.Optional"); ok {
+				sequence = append(sequence, Optional)
+			}
+
+			sequence = append(sequence, tags(field.Type, visiting)...)
+		}
+
+		return sequence
+
+	case *ast.Seq:
+		seq := typeDecl.(*ast.Seq)
+
+		if entry, ok := MapEntry(seq); ok {
+			sequence := append([]Tag{Map}, tags(entry.Fields[0].Type, visiting)...)
+
+			return append(sequence, tags(entry.Fields[1].Type, visiting)...)
+		}
+
+		return append([]Tag{List}, tags(seq.Component, visiting)...)
+	}
+
+	gserrors.Panicf(nil, "tags error: unsupport type(%s)", typeDecl)
+
+	return nil
+}
+
+// MapEntry check if the seq is a list of @Map entry tables, which is encoded as Map and generated as native map type,
+// the invalid entry table(not exactly two fields, the key is not builtin or enum type, or the key is bool) is reported
+func MapEntry(seq *ast.Seq) (*ast.Table, bool) {
+
+	if seq.Size != -1 {
+		return nil, false
+	}
+
+	component := seq.Component
+
+	if typeRef, ok := component.(*ast.TypeRef); ok {
+		component = typeRef.Ref
+	}
+
+	entry, ok := component.(*ast.Table)
+
+	if !ok {
+		return nil, false
+	}
+
+	if _, ok := gslang.FindAnnotation(entry, "com.gsrpc.Map"); !ok {
+		return nil, false
+	}
+
+	start, _ := gslang.Pos(entry)
+
+	if len(entry.Fields) != 2 {
+		gserrors.Panicf(nil, "map entry %s must have exactly two fields(Key,Value) :%v", entry, start)
+	}
+
+	key := entry.Fields[0].Type
+
+	if typeRef, ok := key.(*ast.TypeRef); ok {
+		key = typeRef.Ref
+	}
+
+	switch key.(type) {
+	case *ast.Enum:
+	case *ast.BuiltinType:
+		if key.(*ast.BuiltinType).Type == lexer.KeyBool {
+			gserrors.Panicf(nil, "map entry %s key can't be bool :%v", entry, start)
+		}
+	default:
+		gserrors.Panicf(nil, "map entry %s key must be builtin or enum type :%v", entry, start)
+	}
+
+	return entry, true
+}
+
+// Format format the tag sequence by the target language tag names, the POD field counts are formatted as number
+func Format(sequence []Tag, name func(tag Tag) string) []string {
+
+	var formatted []string
+
+	for i := 0; i < len(sequence); i++ {
+
+		formatted = append(formatted, name(sequence[i]))
+
+		if sequence[i] == POD {
+			i++
+			formatted = append(formatted, strconv.Itoa(int(sequence[i])))
+		}
+	}
+
+	return formatted
+}